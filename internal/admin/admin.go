@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/health"
+	"github.com/lorchard/feed-to-mastodon/internal/lock"
+)
+
+// defaultEntriesLimit is how many entries /api/entries returns without a
+// limit parameter.
+const defaultEntriesLimit = 50
+
+// Runner does the work requested through the API.
+type Runner interface {
+	// Fetch fetches the feed and saves its new entries.
+	Fetch(ctx context.Context) (health.RunResult, error)
+	// Post posts unposted entries, as many as a daemon run would.
+	Post(ctx context.Context) (health.RunResult, error)
+	// Entries returns the entries selected by opts, ready to encode as
+	// JSON.
+	Entries(opts database.ListOptions) (any, error)
+}
+
+// NewHandler returns an HTTP handler serving the admin API, for requests
+// with the header "Authorization: Bearer <token>":
+//   - GET /api/status: the daemon's JSON status document
+//   - POST /api/fetch: fetch the feed now
+//   - POST /api/post: post unposted entries now
+//   - GET /api/entries: list entries, with optional state, limit, and
+//     offset parameters
+func NewHandler(token string, status *health.Status, runner Runner) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, status.Document())
+	})
+
+	mux.HandleFunc("POST /api/fetch", func(w http.ResponseWriter, r *http.Request) {
+		writeRun(w, r, runner.Fetch)
+	})
+
+	mux.HandleFunc("POST /api/post", func(w http.ResponseWriter, r *http.Request) {
+		writeRun(w, r, runner.Post)
+	})
+
+	mux.HandleFunc("GET /api/entries", func(w http.ResponseWriter, r *http.Request) {
+		opts, err := listOptions(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		entries, err := runner.Entries(opts)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, entries)
+	})
+
+	return requireToken(token, mux)
+}
+
+// requireToken rejects requests without the bearer token.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="feed-to-mastodon"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid admin token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeRun does a run for the request and writes its result. Runs refused
+// because another run is using the database get 409 Conflict.
+func writeRun(w http.ResponseWriter, r *http.Request, run func(context.Context) (health.RunResult, error)) {
+	result, err := run(r.Context())
+	switch {
+	case errors.Is(err, lock.ErrLocked):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		result.Error = err.Error()
+		writeJSON(w, http.StatusInternalServerError, result)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// listOptions reads the state, limit, and offset parameters of an entries
+// request.
+func listOptions(r *http.Request) (database.ListOptions, error) {
+	opts := database.ListOptions{Limit: defaultEntriesLimit}
+	query := r.URL.Query()
+
+	switch state := query.Get("state"); state {
+	case "", database.StateUnposted, database.StatePosted, database.StateFailed, database.StateFiltered, database.StateSkipped:
+		opts.State = state
+	default:
+		return opts, errors.New("state must be one of: unposted, posted, failed, filtered, skipped")
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return opts, errors.New("limit must be a non-negative number")
+		}
+		opts.Limit = limit
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return opts, errors.New("offset must be a non-negative number")
+		}
+		opts.Offset = offset
+	}
+
+	return opts, nil
+}
+
+// writeJSON writes value as the JSON response body.
+func writeJSON(w http.ResponseWriter, code int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes err as a JSON error response.
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
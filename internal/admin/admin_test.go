@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/health"
+	"github.com/lorchard/feed-to-mastodon/internal/lock"
+)
+
+// fakeRunner records what the API asked it to do.
+type fakeRunner struct {
+	fetched int
+	posted  int
+	opts    database.ListOptions
+	err     error
+}
+
+func (f *fakeRunner) Fetch(ctx context.Context) (health.RunResult, error) {
+	f.fetched++
+	return health.RunResult{NewEntries: 2}, f.err
+}
+
+func (f *fakeRunner) Post(ctx context.Context) (health.RunResult, error) {
+	f.posted++
+	return health.RunResult{Posted: 1}, f.err
+}
+
+func (f *fakeRunner) Entries(opts database.ListOptions) (any, error) {
+	f.opts = opts
+	return []string{"entry-1"}, f.err
+}
+
+func request(handler http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("rejects missing and wrong tokens", func(t *testing.T) {
+		handler := NewHandler("secret-token", health.NewStatus(), &fakeRunner{})
+
+		for _, token := range []string{"", "wrong"} {
+			rec := request(handler, http.MethodGet, "/api/status", token)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("token %q: status = %d, want 401", token, rec.Code)
+			}
+			if rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("token %q: expected WWW-Authenticate header", token)
+			}
+		}
+	})
+
+	t.Run("status returns the status document", func(t *testing.T) {
+		status := health.NewStatus()
+		status.RecordRun(health.RunResult{Posted: 3}, 4)
+		handler := NewHandler("secret-token", status, &fakeRunner{})
+
+		rec := request(handler, http.MethodGet, "/api/status", "secret-token")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		var doc health.Document
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if doc.QueueDepth != 4 || doc.LastRun == nil || doc.LastRun.Posted != 3 {
+			t.Errorf("document = %+v", doc)
+		}
+	})
+
+	t.Run("fetch and post trigger runs", func(t *testing.T) {
+		runner := &fakeRunner{}
+		handler := NewHandler("secret-token", health.NewStatus(), runner)
+
+		rec := request(handler, http.MethodPost, "/api/fetch", "secret-token")
+		if rec.Code != http.StatusOK {
+			t.Errorf("fetch status = %d, want 200", rec.Code)
+		}
+		var result health.RunResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result.NewEntries != 2 {
+			t.Errorf("fetch result = %s", rec.Body)
+		}
+
+		rec = request(handler, http.MethodPost, "/api/post", "secret-token")
+		if rec.Code != http.StatusOK {
+			t.Errorf("post status = %d, want 200", rec.Code)
+		}
+		if runner.fetched != 1 || runner.posted != 1 {
+			t.Errorf("fetched %d and posted %d times, want 1 each", runner.fetched, runner.posted)
+		}
+	})
+
+	t.Run("runs must be POSTed", func(t *testing.T) {
+		runner := &fakeRunner{}
+		handler := NewHandler("secret-token", health.NewStatus(), runner)
+
+		rec := request(handler, http.MethodGet, "/api/post", "secret-token")
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("status = %d, want 405", rec.Code)
+		}
+		if runner.posted != 0 {
+			t.Error("Expected no post for GET")
+		}
+	})
+
+	t.Run("failed runs report the error", func(t *testing.T) {
+		handler := NewHandler("secret-token", health.NewStatus(), &fakeRunner{err: errors.New("feed is down")})
+
+		rec := request(handler, http.MethodPost, "/api/fetch", "secret-token")
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", rec.Code)
+		}
+		var result health.RunResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result.Error != "feed is down" {
+			t.Errorf("result = %s", rec.Body)
+		}
+	})
+
+	t.Run("runs conflict with a locked database", func(t *testing.T) {
+		err := &lock.LockedError{Path: "feed.db.lock", PID: 42}
+		handler := NewHandler("secret-token", health.NewStatus(), &fakeRunner{err: err})
+
+		rec := request(handler, http.MethodPost, "/api/post", "secret-token")
+		if rec.Code != http.StatusConflict {
+			t.Errorf("status = %d, want 409", rec.Code)
+		}
+	})
+
+	t.Run("entries passes the list options", func(t *testing.T) {
+		runner := &fakeRunner{}
+		handler := NewHandler("secret-token", health.NewStatus(), runner)
+
+		rec := request(handler, http.MethodGet, "/api/entries?state=failed&limit=5&offset=10", "secret-token")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		want := database.ListOptions{State: database.StateFailed, Limit: 5, Offset: 10}
+		if runner.opts != want {
+			t.Errorf("options = %+v, want %+v", runner.opts, want)
+		}
+	})
+
+	t.Run("entries rejects bad parameters", func(t *testing.T) {
+		handler := NewHandler("secret-token", health.NewStatus(), &fakeRunner{})
+
+		for _, query := range []string{"state=lost", "limit=-1", "offset=many"} {
+			rec := request(handler, http.MethodGet, "/api/entries?"+query, "secret-token")
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want 400", query, rec.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/health"
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed dashboard.html
+var dashboardFiles embed.FS
+
+var dashboardTemplate = template.Must(template.New("dashboard.html").Funcs(template.FuncMap{
+	"time": formatTime,
+}).ParseFS(dashboardFiles, "dashboard.html"))
+
+// Entry is an entry as shown on the dashboard.
+type Entry struct {
+	ID        string
+	Title     string
+	Link      string
+	FetchedAt time.Time
+	PostedAt  time.Time
+	// StatusURL is the posted status.
+	StatusURL string
+	// Preview is the post that would be sent, for entries not yet posted.
+	Preview string
+	// LastError is why the entry last failed to post.
+	LastError string
+}
+
+// Overview is what the dashboard shows.
+type Overview struct {
+	Feed *database.FeedHealth
+	// Queue holds the entries waiting to be posted, next first.
+	Queue []Entry
+	// Failed holds the entries that failed to post.
+	Failed []Entry
+	// Posted holds the most recently posted entries.
+	Posted []Entry
+}
+
+// Queue is what the dashboard inspects and changes.
+type Queue interface {
+	// Overview returns the feed health and the entries to show, giving up
+	// when ctx is done.
+	Overview(ctx context.Context) (*Overview, error)
+	// Skip keeps an entry from being posted, giving up when ctx is done.
+	Skip(ctx context.Context, id string) error
+	// Requeue returns an entry to the queue, reporting whether it was
+	// posted or held back before, giving up when ctx is done.
+	Requeue(ctx context.Context, id string) (bool, error)
+}
+
+// NewDashboard returns an HTTP handler serving the web dashboard under
+// /dashboard/, for browsers that log in with the token as the password:
+//   - GET /dashboard/: feed health, the queue, and posted history
+//   - POST /dashboard/skip: skip the entry with the form's id
+//   - POST /dashboard/requeue: requeue the entry with the form's id
+func NewDashboard(token string, status *health.Status, queue Queue) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /dashboard/{$}", func(w http.ResponseWriter, r *http.Request) {
+		overview, err := queue.Overview(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var page bytes.Buffer
+		err = dashboardTemplate.Execute(&page, struct {
+			Status health.Document
+			*Overview
+		}{status.Document(), overview})
+		if err != nil {
+			logrus.Errorf("Failed to render dashboard: %v", err)
+			http.Error(w, "failed to render dashboard", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = page.WriteTo(w)
+	})
+
+	mux.HandleFunc("POST /dashboard/skip", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PostFormValue("id")
+		if id == "" {
+			http.Error(w, "missing entry id", http.StatusBadRequest)
+			return
+		}
+		if err := queue.Skip(r.Context(), id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/dashboard/", http.StatusSeeOther)
+	})
+
+	mux.HandleFunc("POST /dashboard/requeue", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PostFormValue("id")
+		if id == "" {
+			http.Error(w, "missing entry id", http.StatusBadRequest)
+			return
+		}
+		if _, err := queue.Requeue(r.Context(), id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/dashboard/", http.StatusSeeOther)
+	})
+
+	return requirePassword(token, sameOrigin(mux))
+}
+
+// requirePassword asks browsers to log in with the token as the password,
+// with any user name.
+func requirePassword(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="feed-to-mastodon", charset="UTF-8"`)
+			http.Error(w, "log in with the admin token as the password", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin rejects changes requested by other sites, which browsers
+// would otherwise send with the saved login.
+func sameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if err := checkOrigin(r); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkOrigin returns an error if the request came from another site.
+// Requests without Sec-Fetch-Site or Origin, which aren't from browsers,
+// are allowed.
+func checkOrigin(r *http.Request) error {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return nil
+	case "":
+	default:
+		return errors.New("cross-site request rejected")
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != r.Host {
+		return errors.New("cross-site request rejected")
+	}
+	return nil
+}
+
+// formatTime formats a time for the dashboard, or "-" if it's not set.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
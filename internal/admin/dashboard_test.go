@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/health"
+)
+
+// fakeQueue records what the dashboard asked it to do.
+type fakeQueue struct {
+	overview *Overview
+	skipped  []string
+	requeued []string
+}
+
+func (f *fakeQueue) Overview(ctx context.Context) (*Overview, error) {
+	return f.overview, nil
+}
+
+func (f *fakeQueue) Skip(ctx context.Context, id string) error {
+	f.skipped = append(f.skipped, id)
+	return nil
+}
+
+func (f *fakeQueue) Requeue(ctx context.Context, id string) (bool, error) {
+	f.requeued = append(f.requeued, id)
+	return true, nil
+}
+
+func newFakeQueue() *fakeQueue {
+	lastError := "connection refused"
+	return &fakeQueue{overview: &Overview{
+		Feed: &database.FeedHealth{FeedURL: "https://example.com/feed", ConsecutiveFailures: 2, LastError: &lastError},
+		Queue: []Entry{
+			{ID: "entry-1", Title: "Next <up>", Link: "https://example.com/1", FetchedAt: time.Now(), Preview: "Next up https://example.com/1"},
+		},
+		Posted: []Entry{
+			{ID: "entry-0", Title: "Earlier", PostedAt: time.Now(), StatusURL: "https://mastodon.example/@me/1"},
+		},
+	}}
+}
+
+func dashboardRequest(handler http.Handler, req *http.Request, password string) *httptest.ResponseRecorder {
+	if password != "" {
+		req.SetBasicAuth("admin", password)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func formRequest(target, id string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(url.Values{"id": {id}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestDashboard(t *testing.T) {
+	t.Run("asks for the password", func(t *testing.T) {
+		handler := NewDashboard("secret-token", health.NewStatus(), newFakeQueue())
+
+		rec := dashboardRequest(handler, httptest.NewRequest(http.MethodGet, "/dashboard/", nil), "wrong")
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", rec.Code)
+		}
+		if !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic ") {
+			t.Errorf("WWW-Authenticate = %q, want Basic", rec.Header().Get("WWW-Authenticate"))
+		}
+	})
+
+	t.Run("shows feed health, queue, and posted entries", func(t *testing.T) {
+		handler := NewDashboard("secret-token", health.NewStatus(), newFakeQueue())
+
+		rec := dashboardRequest(handler, httptest.NewRequest(http.MethodGet, "/dashboard/", nil), "secret-token")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{
+			"https://example.com/feed",
+			"connection refused",
+			"Next &lt;up&gt;",
+			"Next up https://example.com/1",
+			`href="https://mastodon.example/@me/1"`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("dashboard doesn't contain %q", want)
+			}
+		}
+	})
+
+	t.Run("skips and requeues entries", func(t *testing.T) {
+		queue := newFakeQueue()
+		handler := NewDashboard("secret-token", health.NewStatus(), queue)
+
+		rec := dashboardRequest(handler, formRequest("/dashboard/skip", "entry-1"), "secret-token")
+		if rec.Code != http.StatusSeeOther {
+			t.Errorf("skip status = %d, want 303", rec.Code)
+		}
+		rec = dashboardRequest(handler, formRequest("/dashboard/requeue", "entry-0"), "secret-token")
+		if rec.Code != http.StatusSeeOther {
+			t.Errorf("requeue status = %d, want 303", rec.Code)
+		}
+
+		if len(queue.skipped) != 1 || queue.skipped[0] != "entry-1" {
+			t.Errorf("skipped = %v, want [entry-1]", queue.skipped)
+		}
+		if len(queue.requeued) != 1 || queue.requeued[0] != "entry-0" {
+			t.Errorf("requeued = %v, want [entry-0]", queue.requeued)
+		}
+	})
+
+	t.Run("rejects changes from other sites", func(t *testing.T) {
+		queue := newFakeQueue()
+		handler := NewDashboard("secret-token", health.NewStatus(), queue)
+
+		crossSite := formRequest("/dashboard/skip", "entry-1")
+		crossSite.Header.Set("Sec-Fetch-Site", "cross-site")
+		otherOrigin := formRequest("/dashboard/skip", "entry-1")
+		otherOrigin.Header.Set("Origin", "https://evil.example")
+
+		for _, req := range []*http.Request{crossSite, otherOrigin} {
+			rec := dashboardRequest(handler, req, "secret-token")
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403", rec.Code)
+			}
+		}
+		if len(queue.skipped) != 0 {
+			t.Errorf("skipped = %v, want none", queue.skipped)
+		}
+	})
+}
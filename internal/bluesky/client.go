@@ -0,0 +1,151 @@
+// Package bluesky posts feed entries to Bluesky over the AT Protocol.
+package bluesky
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultServer is the PDS used when none is configured.
+const DefaultServer = "https://bsky.social"
+
+// requestTimeout bounds each request to the server.
+const requestTimeout = 30 * time.Second
+
+// ErrUnauthorized indicates that the server rejected the credentials or
+// session.
+var ErrUnauthorized = errors.New("bluesky rejected the credentials")
+
+// Session is an authenticated session with a PDS.
+type Session struct {
+	DID          string `json:"did"`
+	Handle       string `json:"handle"`
+	AccessToken  string `json:"accessJwt"`
+	RefreshToken string `json:"refreshJwt"`
+}
+
+// Client makes XRPC requests to a PDS.
+type Client struct {
+	server     string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the PDS at server, or DefaultServer if
+// server is empty.
+func NewClient(server string) *Client {
+	if server == "" {
+		server = DefaultServer
+	}
+	return &Client{
+		server:     strings.TrimRight(server, "/"),
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// CreateSession logs in with a handle and an app password.
+func (c *Client) CreateSession(ctx context.Context, handle, appPassword string) (*Session, error) {
+	var session Session
+	input := map[string]string{"identifier": handle, "password": appPassword}
+	if err := c.call(ctx, "com.atproto.server.createSession", "", input, &session); err != nil {
+		return nil, fmt.Errorf("failed to log in as %s: %w", handle, err)
+	}
+	return &session, nil
+}
+
+// RefreshSession exchanges a session's refresh token for a new session.
+func (c *Client) RefreshSession(ctx context.Context, refreshToken string) (*Session, error) {
+	var session Session
+	if err := c.call(ctx, "com.atproto.server.refreshSession", refreshToken, nil, &session); err != nil {
+		return nil, fmt.Errorf("failed to refresh session: %w", err)
+	}
+	return &session, nil
+}
+
+// DeleteSession logs out, invalidating the session's refresh token.
+func (c *Client) DeleteSession(ctx context.Context, refreshToken string) error {
+	if err := c.call(ctx, "com.atproto.server.deleteSession", refreshToken, nil, nil); err != nil {
+		return fmt.Errorf("failed to log out: %w", err)
+	}
+	return nil
+}
+
+// createRecordOutput is the response to com.atproto.repo.createRecord.
+type createRecordOutput struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+// createRecord creates a record in the session's repo, returning its URI.
+func (c *Client) createRecord(ctx context.Context, session *Session, collection string, record any) (string, error) {
+	input := map[string]any{
+		"repo":       session.DID,
+		"collection": collection,
+		"record":     record,
+	}
+	var output createRecordOutput
+	if err := c.call(ctx, "com.atproto.repo.createRecord", session.AccessToken, input, &output); err != nil {
+		return "", err
+	}
+	return output.URI, nil
+}
+
+// xrpcError is the error body returned by XRPC endpoints.
+type xrpcError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// call POSTs input as JSON to an XRPC procedure, authorized with token if
+// it isn't empty, and decodes the response into output if it isn't nil.
+func (c *Client) call(ctx context.Context, method, token string, input, output any) error {
+	var body io.Reader
+	if input != nil {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.server+"/xrpc/"+method, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if input != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", c.server, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var xerr xrpcError
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&xerr)
+		message := strings.TrimSpace(xerr.Error + ": " + xerr.Message)
+		if resp.StatusCode == http.StatusUnauthorized || xerr.Error == "AuthenticationRequired" || xerr.Error == "ExpiredToken" || xerr.Error == "InvalidToken" {
+			return fmt.Errorf("%w (%s)", ErrUnauthorized, message)
+		}
+		return fmt.Errorf("%s returned %s (%s)", method, resp.Status, message)
+	}
+
+	if output == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(output); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	return nil
+}
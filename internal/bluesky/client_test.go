@@ -0,0 +1,70 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xrpc/com.atproto.server.createSession" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var input map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if input["identifier"] != "me.example" || input["password"] != "app-pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"AuthenticationRequired","message":"Invalid identifier or password"}`))
+			return
+		}
+		w.Write([]byte(`{"did":"did:plc:abc","handle":"me.example","accessJwt":"access","refreshJwt":"refresh"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL + "/")
+
+	session, err := client.CreateSession(context.Background(), "me.example", "app-pass")
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if session.DID != "did:plc:abc" || session.AccessToken != "access" || session.RefreshToken != "refresh" {
+		t.Errorf("session = %+v", session)
+	}
+
+	_, err = client.CreateSession(context.Background(), "me.example", "wrong")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("CreateSession() with a wrong password error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestRefreshSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer old-refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"ExpiredToken","message":"Token has expired"}`))
+			return
+		}
+		w.Write([]byte(`{"did":"did:plc:abc","handle":"me.example","accessJwt":"access","refreshJwt":"new-refresh"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	session, err := client.RefreshSession(context.Background(), "old-refresh")
+	if err != nil {
+		t.Fatalf("RefreshSession() error = %v", err)
+	}
+	if session.RefreshToken != "new-refresh" {
+		t.Errorf("RefreshToken = %q, want new-refresh", session.RefreshToken)
+	}
+
+	if _, err := client.RefreshSession(context.Background(), "expired"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("RefreshSession() with an expired token error = %v, want ErrUnauthorized", err)
+	}
+}
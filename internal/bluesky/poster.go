@@ -0,0 +1,135 @@
+package bluesky
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+)
+
+// MaxCharacters is the longest post Bluesky accepts. It counts graphemes,
+// which this counts as runes.
+const MaxCharacters = 300
+
+// postCollection is the collection Bluesky posts are created in.
+const postCollection = "app.bsky.feed.post"
+
+// Poster posts rendered entries to a Bluesky account.
+type Poster struct {
+	client  *Client
+	session *Session
+	now     func() time.Time
+}
+
+var _ destination.Destination = (*Poster)(nil)
+
+// NewPoster creates a poster that posts with an authenticated session.
+func NewPoster(client *Client, session *Session) *Poster {
+	return &Poster{client: client, session: session, now: time.Now}
+}
+
+// PostEntries renders and posts entries, recording each post's AT URI as
+// the entry's status ID and its bsky.app link as the status URL.
+func (p *Poster) PostEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]destination.PostResult, error) {
+	return p.PostEntriesContext(context.Background(), entries, renderer, dryRun)
+}
+
+// PostEntriesContext is like PostEntries, but gives up when ctx is done.
+func (p *Poster) PostEntriesContext(ctx context.Context, entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]destination.PostResult, error) {
+	return destination.PostEach(entries, renderer, dryRun, func(entry *database.Entry, content string) error {
+		uri, err := p.Post(ctx, content)
+		if err != nil {
+			return err
+		}
+		entry.StatusID = sql.NullString{String: uri, Valid: true}
+		entry.StatusURL = sql.NullString{String: PostURL(uri), Valid: true}
+		return nil
+	})
+}
+
+// Post creates a post with links and hashtags in text made clickable, and
+// returns its AT URI.
+func (p *Poster) Post(ctx context.Context, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if n := utf8.RuneCountInString(text); n > MaxCharacters {
+		return "", fmt.Errorf("post is %d characters, over Bluesky's limit of %d", n, MaxCharacters)
+	}
+
+	record := map[string]any{
+		"$type":     postCollection,
+		"text":      text,
+		"createdAt": p.now().UTC().Format(time.RFC3339),
+	}
+	if facets := Facets(text); len(facets) > 0 {
+		record["facets"] = facets
+	}
+
+	uri, err := p.client.createRecord(ctx, p.session, postCollection, record)
+	if err != nil {
+		return "", fmt.Errorf("failed to create post: %w", err)
+	}
+	return uri, nil
+}
+
+// PostURL returns the bsky.app link for a post's AT URI, e.g.
+// at://did:plc:abc/app.bsky.feed.post/3k2 becomes
+// https://bsky.app/profile/did:plc:abc/post/3k2.
+func PostURL(uri string) string {
+	parts := strings.Split(strings.TrimPrefix(uri, "at://"), "/")
+	if len(parts) != 3 {
+		return ""
+	}
+	return "https://bsky.app/profile/" + parts[0] + "/post/" + parts[2]
+}
+
+// Facet marks a range of a post's text as a link or hashtag. Ranges are
+// byte offsets into the UTF-8 text, as the AT Protocol requires.
+type Facet struct {
+	Index    FacetIndex       `json:"index"`
+	Features []map[string]any `json:"features"`
+}
+
+// FacetIndex is the byte range a facet applies to.
+type FacetIndex struct {
+	ByteStart int `json:"byteStart"`
+	ByteEnd   int `json:"byteEnd"`
+}
+
+var (
+	linkPattern    = regexp.MustCompile(`https?://[^\s<>"]+`)
+	hashtagPattern = regexp.MustCompile(`(?:^|\s)(#[\p{L}\p{N}_]*\p{L}[\p{L}\p{N}_]*)`)
+)
+
+// Facets finds the links and hashtags in text. Bluesky doesn't link them
+// itself.
+func Facets(text string) []Facet {
+	var facets []Facet
+	for _, match := range linkPattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		// Leave out trailing punctuation, like a sentence's period
+		end = start + len(strings.TrimRight(text[start:end], ".,;:!?)]}'"))
+		facets = append(facets, Facet{
+			Index: FacetIndex{ByteStart: start, ByteEnd: end},
+			Features: []map[string]any{
+				{"$type": "app.bsky.richtext.facet#link", "uri": text[start:end]},
+			},
+		})
+	}
+	for _, match := range hashtagPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2], match[3]
+		facets = append(facets, Facet{
+			Index: FacetIndex{ByteStart: start, ByteEnd: end},
+			Features: []map[string]any{
+				{"$type": "app.bsky.richtext.facet#tag", "tag": text[start+1 : end]},
+			},
+		})
+	}
+	return facets
+}
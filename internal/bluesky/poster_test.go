@@ -0,0 +1,112 @@
+package bluesky
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+)
+
+func TestFacets(t *testing.T) {
+	text := "Café news: https://example.com/post?id=1. #golang #2024 and url.com/#frag"
+	facets := Facets(text)
+
+	if len(facets) != 2 {
+		t.Fatalf("Facets() returned %d facets, want 2: %+v", len(facets), facets)
+	}
+
+	link := facets[0]
+	if got := text[link.Index.ByteStart:link.Index.ByteEnd]; got != "https://example.com/post?id=1" {
+		t.Errorf("link facet covers %q", got)
+	}
+	if link.Features[0]["uri"] != "https://example.com/post?id=1" {
+		t.Errorf("link feature = %v", link.Features[0])
+	}
+
+	tag := facets[1]
+	if got := text[tag.Index.ByteStart:tag.Index.ByteEnd]; got != "#golang" {
+		t.Errorf("tag facet covers %q", got)
+	}
+	if tag.Features[0]["tag"] != "golang" {
+		t.Errorf("tag feature = %v", tag.Features[0])
+	}
+}
+
+func TestPostURL(t *testing.T) {
+	if got := PostURL("at://did:plc:abc/app.bsky.feed.post/3k2"); got != "https://bsky.app/profile/did:plc:abc/post/3k2" {
+		t.Errorf("PostURL() = %q", got)
+	}
+	if got := PostURL("nonsense"); got != "" {
+		t.Errorf("PostURL(nonsense) = %q, want empty", got)
+	}
+}
+
+func TestPostEntries(t *testing.T) {
+	var records []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		var input struct {
+			Repo       string         `json:"repo"`
+			Collection string         `json:"collection"`
+			Record     map[string]any `json:"record"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if input.Repo != "did:plc:abc" || input.Collection != "app.bsky.feed.post" {
+			t.Errorf("repo = %q, collection = %q", input.Repo, input.Collection)
+		}
+		records = append(records, input.Record)
+		w.Write([]byte(`{"uri":"at://did:plc:abc/app.bsky.feed.post/3k2","cid":"bafy"}`))
+	}))
+	defer server.Close()
+
+	tmplPath := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(tmplPath, []byte("{{.Item.Title}} {{.Item.Link}}"), 0o644); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	renderer, err := template.New(tmplPath, 1000)
+	if err != nil {
+		t.Fatalf("template.New() error = %v", err)
+	}
+
+	entries := []*database.Entry{
+		{ID: "1", EntryData: []byte(`{"title":"Hello","link":"https://example.com/1"}`)},
+		{ID: "2", EntryData: []byte(`{"title":"` + strings.Repeat("long ", 70) + `","link":"https://example.com/2"}`)},
+	}
+
+	session := &Session{DID: "did:plc:abc", AccessToken: "access"}
+	results, err := NewPoster(NewClient(server.URL), session).PostEntries(entries, renderer, false)
+	if err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+
+	if results[0].Outcome != destination.OutcomePosted {
+		t.Errorf("results[0] = %s (%v), want posted", results[0].Outcome, results[0].Err)
+	}
+	if results[1].Outcome != destination.OutcomeFailed || !strings.Contains(results[1].Err.Error(), "limit of 300") {
+		t.Errorf("results[1] = %s (%v), want failed over the limit", results[1].Outcome, results[1].Err)
+	}
+	if entries[0].StatusURL.String != "https://bsky.app/profile/did:plc:abc/post/3k2" {
+		t.Errorf("StatusURL = %q", entries[0].StatusURL.String)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("server got %d posts, want 1", len(records))
+	}
+	if records[0]["text"] != "Hello https://example.com/1" {
+		t.Errorf("text = %v", records[0]["text"])
+	}
+	if facets, ok := records[0]["facets"].([]any); !ok || len(facets) != 1 {
+		t.Errorf("facets = %v, want the link", records[0]["facets"])
+	}
+}
@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/bluesky"
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// blueskyAccount names Bluesky in the per-account posted state.
+const blueskyAccount = "bluesky"
+
+// blueskySessionSetting holds the refresh token of the session stored by
+// 'bluesky login'.
+const blueskySessionSetting = "bluesky_refresh_token"
+
+var blueskyAppPassword string
+
+// NewBlueskyCmd creates the bluesky command and its subcommands.
+func NewBlueskyCmd() *cobra.Command {
+	blueskyCmd := &cobra.Command{
+		Use:   "bluesky",
+		Short: "Log in to or out of Bluesky",
+		Long: `With bluesky.handle configured, entries posted to Mastodon are posted to
+Bluesky too. Log in once with an app password (Settings > Privacy and
+security > App passwords) to store a session in the database, or set
+bluesky.app_password in config to log in on each run.`,
+	}
+
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in to Bluesky and store the session",
+		Long: `Login logs in to Bluesky as bluesky.handle with an app password and
+stores the session in the database, so the password isn't needed in
+config. The app password is read from --app-password or prompted for.`,
+		Args: cobra.NoArgs,
+		RunE: runBlueskyLogin,
+	}
+	loginCmd.Flags().StringVar(&blueskyAppPassword, "app-password", "", "Bluesky app password (prompted for if not given)")
+
+	blueskyCmd.AddCommand(loginCmd)
+	blueskyCmd.AddCommand(&cobra.Command{
+		Use:   "logout",
+		Short: "Log out of Bluesky and forget the stored session",
+		Args:  cobra.NoArgs,
+		RunE:  runBlueskyLogout,
+	})
+
+	return blueskyCmd
+}
+
+func runBlueskyLogin(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.Bluesky.Handle == "" {
+		return fmt.Errorf("bluesky.handle is required")
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	password := blueskyAppPassword
+	if password == "" {
+		fmt.Printf("App password for %s: ", cfg.Bluesky.Handle)
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read app password: %w", err)
+		}
+		password = strings.TrimSpace(answer)
+	}
+
+	session, err := bluesky.NewClient(cfg.Bluesky.Server).CreateSession(cmd.Context(), cfg.Bluesky.Handle, password)
+	if err != nil {
+		return err
+	}
+	if err := db.Settings().SetSecret(blueskySessionSetting, session.RefreshToken); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+
+	fmt.Printf("Logged in to Bluesky as @%s (%s)\n", session.Handle, session.DID)
+	return nil
+}
+
+func runBlueskyLogout(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	refreshToken, err := db.Settings().GetSecret(blueskySessionSetting)
+	if err != nil {
+		return err
+	}
+	if refreshToken == nil {
+		fmt.Println("Not logged in to Bluesky")
+		return nil
+	}
+
+	if err := bluesky.NewClient(cfg.Bluesky.Server).DeleteSession(cmd.Context(), *refreshToken); err != nil {
+		logrus.Warnf("Failed to end the session on the server: %v", err)
+	}
+	if err := db.Settings().Delete(blueskySessionSetting); err != nil {
+		return fmt.Errorf("failed to forget session: %w", err)
+	}
+
+	fmt.Println("Logged out of Bluesky")
+	return nil
+}
+
+// blueskySession logs in to Bluesky with the configured app password, or
+// else resumes the session stored by 'bluesky login'.
+func blueskySession(ctx context.Context, cfg *config.Config, db *database.DB, client *bluesky.Client) (*bluesky.Session, error) {
+	if cfg.Bluesky.AppPassword != "" {
+		return client.CreateSession(ctx, cfg.Bluesky.Handle, cfg.Bluesky.AppPassword)
+	}
+
+	refreshToken, err := db.Settings().GetSecret(blueskySessionSetting)
+	if err != nil {
+		return nil, err
+	}
+	if refreshToken == nil {
+		return nil, fmt.Errorf("not logged in - run 'feed-to-mastodon bluesky login', or set bluesky.app_password in config")
+	}
+
+	// Refresh tokens are single use, so store the new one
+	session, err := client.RefreshSession(ctx, *refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w - run 'feed-to-mastodon bluesky login' again", err)
+	}
+	if err := db.Settings().SetSecret(blueskySessionSetting, session.RefreshToken); err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+	return session, nil
+}
+
+// crossPostBluesky posts the entries not yet posted to Bluesky.
+func crossPostBluesky(ctx context.Context, cfg *config.Config, db *database.DB, dryRun bool) (int, error) {
+	entries, err := pendingCrossPosts(cfg, db, blueskyAccount, nil)
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	logrus.Infof("Found %d entries to post to Bluesky", len(entries))
+
+	// Bluesky posts are shorter, and may have their own template
+	blueskyCfg := *cfg
+	blueskyCfg.CharacterLimit = bluesky.MaxCharacters
+	if cfg.Bluesky.TemplatePath != "" {
+		blueskyCfg.TemplateFile = cfg.Bluesky.TemplatePath
+	}
+	renderer, err := newRenderer(&blueskyCfg, db)
+	if err != nil {
+		return 0, err
+	}
+	renderer.SetFinalLinks(!dryRun)
+
+	client := bluesky.NewClient(cfg.Bluesky.Server)
+	var session *bluesky.Session
+	if !dryRun {
+		if session, err = blueskySession(ctx, cfg, db, client); err != nil {
+			return 0, err
+		}
+	}
+
+	results, err := bluesky.NewPoster(client, session).PostEntriesContext(ctx, entries, renderer, dryRun)
+	if err != nil {
+		return 0, err
+	}
+	return recordCrossPosts(db, blueskyAccount, results, dryRun), nil
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/lorchard/feed-to-mastodon/internal/config"
-	"github.com/lorchard/feed-to-mastodon/internal/database"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -38,7 +37,7 @@ func runCatchup(cmd *cobra.Command, args []string) error {
 	}
 
 	// Open database
-	db, err := database.New(cfg.DatabasePath)
+	db, err := openDatabase(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -64,7 +63,7 @@ func runCatchup(cmd *cobra.Command, args []string) error {
 	} else {
 		markedCount := 0
 		for _, entry := range entries {
-			if err := db.MarkAsPosted(entry.ID); err != nil {
+			if err := db.MarkAsPosted(entry.ID, "", ""); err != nil {
 				logrus.Errorf("Failed to mark entry %s as posted: %v", entry.ID, err)
 			} else {
 				markedCount++
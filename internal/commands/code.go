@@ -1,12 +1,13 @@
 package commands
 
 import (
-	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/lorchard/feed-to-mastodon/internal/config"
-	"github.com/lorchard/feed-to-mastodon/internal/database"
-	mastodon "github.com/mattn/go-mastodon"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +20,13 @@ func NewCodeCmd() *cobra.Command {
 
 This command takes the authorization code you received after visiting the
 authorization link (from the 'link' command) and exchanges it for an access token.
-The access token is then stored in the database for future use.
+The access token is then stored in the database for future use, or in the
+OS keyring with token_storage set to keyring. A token stored before is
+replaced and revoked, so only the new one works.
+
+The scopes the server granted are checked against the features enabled in
+config, with a warning if any are missing or the token can do more than
+needed.
 
 This requires mastodon_server, mastodon_client_id, and mastodon_client_secret
 to be configured.`,
@@ -52,41 +59,49 @@ func runCode(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("mastodon_client_secret is required")
 	}
 
-	// Create Mastodon client with client credentials
-	client := mastodon.NewClient(&mastodon.Config{
-		Server:       cfg.MastodonServer,
-		ClientID:     cfg.MastodonClientID,
-		ClientSecret: cfg.MastodonClientSecret,
-	})
-
 	// Exchange authorization code for access token
 	fmt.Println("Exchanging authorization code for access token...")
-	err = client.GetUserAccessToken(context.Background(), authCode, "urn:ietf:wg:oauth:2.0:oob")
+	token, err := mastodon.ExchangeCode(cmd.Context(), cfg.MastodonServer, cfg.MastodonClientID, cfg.MastodonClientSecret, authCode, cfg.OAuthScopes)
 	if err != nil {
 		return fmt.Errorf("failed to exchange authorization code: %w", err)
 	}
 
-	// Get the access token from the client config
-	accessToken := client.Config.AccessToken
-	if accessToken == "" {
-		return fmt.Errorf("received empty access token")
-	}
-
 	// Open database to store the token
-	db, err := database.New(cfg.DatabasePath)
+	db, err := openDatabase(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
-	// Store the access token in the database
-	err = db.SetSetting("mastodon_access_token", accessToken)
+	replaced, storedIn, err := storeAccessToken(cfg, db, token)
 	if err != nil {
-		return fmt.Errorf("failed to store access token: %w", err)
+		return err
 	}
 
 	fmt.Println()
-	fmt.Println("✓ Successfully obtained and stored access token!")
+	fmt.Printf("✓ Successfully obtained access token and stored it in the %s!\n", storedIn)
+	if token.Scope != "" {
+		fmt.Printf("Granted scopes: %s\n", token.Scope)
+		warnGrantedScopes(cfg, token.Scope)
+	} else {
+		logrus.Warn("The server didn't say which scopes it granted, so they can't be checked")
+	}
+
+	// The replaced token still works until it's revoked
+	if replaced != "" {
+		if err := mastodon.RevokeToken(cmd.Context(), cfg.MastodonServer, cfg.MastodonClientID, cfg.MastodonClientSecret, replaced); err != nil {
+			logrus.Warnf("Failed to revoke the previous access token: %v", err)
+			logrus.Warn("Revoke it manually under Settings > Account > Authorized apps")
+		} else {
+			fmt.Println("Revoked the previous access token")
+		}
+	}
+
+	if tokenConfigured(cfg) {
+		fmt.Println()
+		fmt.Println("Note: mastodon_token or mastodon_token_command is set in config, and is")
+		fmt.Println("used instead of the stored token until it's removed from config.")
+	}
 	fmt.Println()
 	fmt.Println("You can now use the 'status' command to verify your account")
 	fmt.Println("and the 'post' command to post entries to Mastodon.")
@@ -94,3 +109,26 @@ func runCode(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// warnGrantedScopes warns when the granted scopes don't allow the features
+// enabled in cfg, or allow more than they and the default scopes need.
+func warnGrantedScopes(cfg *config.Config, granted string) {
+	needed := neededScopes(cfg)
+	if missing := mastodon.MissingScopes(granted, needed); len(missing) > 0 {
+		logrus.Warnf("The token lacks scopes the configured features need: %s", strings.Join(missing, " "))
+		logrus.Warn("Add them to oauth_scopes, then run 'link' and 'code' again")
+	}
+
+	// The default scopes are fine even for features that aren't enabled,
+	// so enabling them doesn't take a new token
+	wanted := strings.Fields(mastodon.DefaultScopes)
+	for _, scope := range needed {
+		if !slices.Contains(wanted, scope) {
+			wanted = append(wanted, scope)
+		}
+	}
+	if extra := mastodon.ExtraScopes(granted, wanted); len(extra) > 0 {
+		logrus.Warnf("The token has broader permissions than needed: %s", strings.Join(extra, " "))
+		logrus.Warnf("To limit what a leaked token can do, set oauth_scopes to %q, then run 'link' and 'code' again", strings.Join(wanted, " "))
+	}
+}
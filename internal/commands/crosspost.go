@@ -0,0 +1,224 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/bluesky"
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/filter"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/sirupsen/logrus"
+)
+
+// accountSincePrefix prefixes the settings holding when each account was
+// first cross-posted to. Entries posted before then aren't cross-posted,
+// so adding an account doesn't repost the whole history.
+const accountSincePrefix = "account_since:"
+
+// crossPost posts entries already posted to the main account to each of
+// the other configured accounts and to Bluesky, and returns the number
+// cross-posted. Failures are logged and retried on later runs.
+func crossPost(ctx context.Context, cfg *config.Config, db *database.DB, dryRun bool) int {
+	posted := 0
+	for _, account := range cfg.Accounts {
+		n, err := crossPostAccount(ctx, cfg, db, account, dryRun)
+		if err != nil {
+			logrus.Errorf("Failed to cross-post to %s: %v", account.Name, err)
+		}
+		posted += n
+	}
+	if cfg.Bluesky.Handle != "" {
+		n, err := crossPostBluesky(ctx, cfg, db, dryRun)
+		if err != nil {
+			logrus.Errorf("Failed to post to Bluesky: %v", err)
+		}
+		posted += n
+	}
+	for _, hook := range cfg.ChatWebhooks {
+		n, err := crossPostChat(cfg, db, hook, dryRun)
+		if err != nil {
+			logrus.Errorf("Failed to announce entries in %s: %v", hook.Name, err)
+		}
+		posted += n
+	}
+	return posted
+}
+
+// crossPostAccounts returns the names of the accounts entries are
+// cross-posted to, including Bluesky and chat webhooks.
+func crossPostAccounts(cfg *config.Config) []string {
+	var names []string
+	for _, account := range cfg.Accounts {
+		names = append(names, account.Name)
+	}
+	if cfg.Bluesky.Handle != "" {
+		names = append(names, blueskyAccount)
+	}
+	for _, hook := range cfg.ChatWebhooks {
+		names = append(names, hook.Name)
+	}
+	return names
+}
+
+// crossPostAccount posts the entries not yet posted to one account.
+func crossPostAccount(ctx context.Context, cfg *config.Config, db *database.DB, account config.Account, dryRun bool) (int, error) {
+	entries, err := pendingCrossPosts(cfg, db, account.Name, account.Categories)
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	logrus.Infof("Found %d entries to cross-post to %s", len(entries), account.Name)
+
+	// Each server has its own limits
+	accountCfg := *cfg
+	accountCfg.MastodonServer = account.Server
+	renderer, poster, err := newPoster(ctx, &accountCfg, db, account.Token)
+	if err != nil {
+		return 0, err
+	}
+
+	renderer.SetFinalLinks(!dryRun)
+	results, postErr := poster.PostEntriesContext(ctx, entries, renderer, dryRun)
+	if postErr != nil && !errors.Is(postErr, mastodon.ErrUnauthorized) && !errors.Is(postErr, mastodon.ErrServerUnavailable) &&
+		!errors.Is(postErr, mastodon.ErrRateLimited) {
+		return 0, postErr
+	}
+	posted := recordCrossPosts(db, account.Name, results, dryRun)
+
+	if errors.Is(postErr, mastodon.ErrMissingScope) {
+		return posted, fmt.Errorf("access token for %s lacks the write:statuses scope", account.Server)
+	}
+	if errors.Is(postErr, mastodon.ErrUnauthorized) {
+		return posted, fmt.Errorf("access token was rejected by %s", account.Server)
+	}
+	if errors.Is(postErr, mastodon.ErrServerUnavailable) {
+		return posted, fmt.Errorf("%s appears to be down, will retry on the next run", account.Server)
+	}
+	if errors.Is(postErr, mastodon.ErrRateLimited) {
+		return posted, fmt.Errorf("%s's rate limit is used up, will retry on the next run", account.Server)
+	}
+	return posted, nil
+}
+
+// crossPostChat announces the entries not yet announced in a Discord or
+// Slack channel.
+func crossPostChat(cfg *config.Config, db *database.DB, hook config.ChatWebhook, dryRun bool) (int, error) {
+	entries, err := pendingCrossPosts(cfg, db, hook.Name, hook.Categories)
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	logrus.Infof("Found %d entries to announce in %s", len(entries), hook.Name)
+
+	// Chat messages have their own limits, and may have their own template
+	chatCfg := *cfg
+	chatCfg.CharacterLimit = destination.DiscordMaxCharacters
+	if hook.Type == destination.ChatSlack {
+		chatCfg.CharacterLimit = destination.SlackMaxCharacters
+	}
+	if hook.TemplatePath != "" {
+		chatCfg.TemplateFile = hook.TemplatePath
+	}
+	renderer, err := newRenderer(&chatCfg, db)
+	if err != nil {
+		return 0, err
+	}
+	renderer.SetFinalLinks(!dryRun)
+
+	dest, err := destination.NewChatWebhook(hook.Type, hook.URL)
+	if err != nil {
+		return 0, err
+	}
+	results, err := dest.PostEntries(entries, renderer, dryRun)
+	if err != nil {
+		return 0, err
+	}
+	return recordCrossPosts(db, hook.Name, results, dryRun), nil
+}
+
+// pendingCrossPosts returns the entries to post to the named account:
+// those posted to the main account since it was added, with one of the
+// given categories if any, that weren't posted to it yet. They're copies,
+// so the main account's status and schedule aren't mistaken for the
+// account's.
+func pendingCrossPosts(cfg *config.Config, db *database.DB, name string, categories []string) ([]*database.Entry, error) {
+	since, err := accountSince(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := db.GetUncrossPostedEntries(name, since, cfg.MaxPostAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := filter.New("", "", categories, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*database.Entry, 0, len(pending))
+	for _, entry := range pending {
+		if ok, _, err := match.CheckEntry(entry.EntryData); err != nil || !ok {
+			continue
+		}
+		entries = append(entries, &database.Entry{ID: entry.ID, EntryData: entry.EntryData, FullContent: entry.FullContent, LeadImage: entry.LeadImage})
+	}
+	return entries, nil
+}
+
+// recordCrossPosts records the results of posting to the named account,
+// unless in dry run mode, and returns the number posted. Outages and
+// rejected credentials aren't the entry's fault and aren't counted as
+// failures.
+func recordCrossPosts(db *database.DB, name string, results []destination.PostResult, dryRun bool) int {
+	posted := 0
+	for _, result := range results {
+		entry := result.Entry
+		switch result.Outcome {
+		case destination.OutcomePosted:
+			posted++
+			if dryRun {
+				continue
+			}
+			if err := db.MarkAccountPosted(entry.ID, name, entry.StatusID.String, entry.StatusURL.String); err != nil {
+				logrus.Errorf("Failed to mark entry %s as posted to %s: %v", entry.ID, name, err)
+			}
+		case destination.OutcomeFailed:
+			if dryRun || errors.Is(result.Err, mastodon.ErrUnauthorized) || errors.Is(result.Err, mastodon.ErrServerUnavailable) ||
+				errors.Is(result.Err, mastodon.ErrRateLimited) || errors.Is(result.Err, bluesky.ErrUnauthorized) {
+				continue
+			}
+			message := "unknown error"
+			if result.Err != nil {
+				message = result.Err.Error()
+			}
+			if err := db.RecordAccountFailure(entry.ID, name, message); err != nil {
+				logrus.Errorf("Failed to record failure for entry %s: %v", entry.ID, err)
+			}
+		}
+	}
+	return posted
+}
+
+// accountSince returns when an account was first cross-posted to,
+// recording now if it's new.
+func accountSince(db *database.DB, name string) (time.Time, error) {
+	key := accountSincePrefix + name
+	stored, err := db.Settings().GetTime(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if stored != nil {
+		return *stored, nil
+	}
+
+	since := time.Now().Truncate(time.Second)
+	if err := db.Settings().SetTime(key, since); err != nil {
+		return time.Time{}, err
+	}
+	return since, nil
+}
@@ -0,0 +1,375 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/admin"
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/feed"
+	"github.com/lorchard/feed-to-mastodon/internal/health"
+	"github.com/lorchard/feed-to-mastodon/internal/systemd"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonInterval time.Duration
+	daemonListen   string
+)
+
+// NewDaemonCmd creates the daemon command.
+func NewDaemonCmd() *cobra.Command {
+	daemonCmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Fetch and post repeatedly in the foreground",
+		Long: `Daemon runs fetch and post in a loop, waiting daemon_interval between
+runs, until interrupted. This is an alternative to scheduling separate
+fetch and post commands with cron. With expire_after set, old statuses
+are deleted after each run too.
+
+The feed isn't fetched more often than its RSS ttl or Cache-Control
+max-age allow, nor during its skipHours and skipDays, and the daemon backs
+off when the feed's server answers with Retry-After, 429, or 403.
+
+When health_listen (or --listen) is set, an HTTP server is started with:
+- /healthz: liveness, always 200 while the daemon is running
+- /readyz: readiness, 200 once the latest run succeeded
+- /status: JSON document with queue depth and last-run results
+
+With admin_token set, the same server has an admin API for requests with
+"Authorization: Bearer <admin_token>":
+- GET /api/status: the status document
+- POST /api/fetch: fetch the feed now
+- POST /api/post: post unposted entries now
+- GET /api/entries: list entries (?state=, &limit=, &offset=)
+
+With dashboard also set, a web dashboard at /dashboard/ shows feed health,
+the queue with previews of its posts, and posted history, with buttons to
+skip and requeue entries. Log in with admin_token as the password.
+
+Under systemd, the daemon sends READY, STATUS, and WATCHDOG notifications
+(use Type=notify and WatchdogSec= in the unit), and serves the health
+endpoint on a socket-activated socket when one is passed.
+
+Runs are skipped while a fetch or post command is using the database.
+
+With --summary-out, a JSON summary of each run replaces the file's
+contents when the run finishes, so it always holds the latest run.`,
+		RunE: runDaemon,
+	}
+
+	daemonCmd.Flags().DurationVar(&daemonInterval, "interval", 0, "time between runs (overrides config daemon_interval)")
+	daemonCmd.Flags().StringVar(&daemonListen, "listen", "", "address for the health endpoint, e.g. :8080 (overrides config health_listen)")
+	daemonCmd.Flags().StringVar(&summaryOut, "summary-out", "", "write a JSON summary of each run to this file, replacing the previous run's")
+
+	return daemonCmd
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Validate configuration
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	interval := cfg.DaemonInterval
+	if cmd.Flags().Changed("interval") {
+		interval = daemonInterval
+	}
+	if interval <= 0 {
+		return fmt.Errorf("daemon interval must be positive")
+	}
+
+	listen := cfg.HealthListen
+	if cmd.Flags().Changed("listen") {
+		listen = daemonListen
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	// Execute cancels the command's context on SIGINT and SIGTERM
+	ctx := cmd.Context()
+
+	status := health.NewStatus()
+
+	// Runs requested through the admin API wait for the daemon's own
+	runner := &daemonRunner{cfg: cfg, db: db}
+
+	// Start the health endpoint, on a socket passed by systemd if there is one
+	listener, err := healthListener(listen)
+	if err != nil {
+		return err
+	}
+	if listener == nil && cfg.AdminToken != "" {
+		logrus.Warn("admin_token is set, but the admin API and dashboard aren't served without health_listen")
+	}
+	if listener != nil {
+		var handler http.Handler = health.NewHandler(status)
+		if cfg.AdminToken != "" {
+			mux := http.NewServeMux()
+			mux.Handle("/", handler)
+			mux.Handle("/api/", admin.NewHandler(cfg.AdminToken, status, runner))
+			if cfg.Dashboard {
+				mux.Handle("/dashboard/", admin.NewDashboard(cfg.AdminToken, status, runner))
+			}
+			handler = mux
+		}
+		server := &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			logrus.Infof("Health endpoint listening on %s", listener.Addr())
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logrus.Errorf("Health endpoint failed: %v", err)
+			}
+		}()
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+		}()
+	}
+
+	// Watchdog pings are sent while waiting between runs and after each run,
+	// so a run that hangs stops the pings and lets systemd restart us
+	watchdogInterval, err := systemd.WatchdogInterval()
+	if err != nil {
+		logrus.Warnf("Ignoring systemd watchdog: %v", err)
+	}
+	var watchdog <-chan time.Time
+	if watchdogInterval > 0 {
+		logrus.Debugf("Sending systemd watchdog notifications every %s", watchdogInterval)
+		ticker := time.NewTicker(watchdogInterval)
+		defer ticker.Stop()
+		watchdog = ticker.C
+	}
+
+	logrus.Infof("Starting daemon (interval: %s)", interval)
+	sdNotify("READY=1")
+	defer sdNotify("STOPPING=1")
+
+	for {
+		runner.mu.Lock()
+		runDaemonOnce(ctx, cfg, db, status, summaryOut)
+		runner.mu.Unlock()
+		sdNotify("WATCHDOG=1")
+		sdNotify(fmt.Sprintf("STATUS=Last run finished at %s", time.Now().Format(time.RFC3339)))
+
+		next := time.After(interval)
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				logrus.Info("Shutting down daemon")
+				return nil
+			case <-watchdog:
+				sdNotify("WATCHDOG=1")
+			case <-next:
+				break wait
+			}
+		}
+	}
+}
+
+// healthListener returns the listener for the health endpoint: the first
+// socket passed by systemd socket activation, or a new listener on the
+// given address. Returns nil if neither is available.
+func healthListener(listen string) (net.Listener, error) {
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		return nil, fmt.Errorf("failed to use systemd sockets: %w", err)
+	}
+	if len(listeners) > 0 {
+		logrus.Debug("Using socket from systemd socket activation")
+		return listeners[0], nil
+	}
+
+	if listen == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+	return listener, nil
+}
+
+// sdNotify sends a notification to systemd, logging any failure.
+func sdNotify(state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		logrus.Warnf("Failed to notify systemd: %v", err)
+	}
+}
+
+// runDaemonOnce performs a single fetch-and-post run and records the result,
+// writing its summary to summaryPath if it's set. The run is abandoned when
+// ctx is done, and skipped while a fetch or post command is using the
+// database.
+func runDaemonOnce(ctx context.Context, cfg *config.Config, db *database.DB, status *health.Status, summaryPath string) {
+	summary := newRunSummary("daemon")
+	var runErr error
+	defer func() { summary.write(summaryPath, runErr) }()
+
+	runLock, err := lockRun(ctx, cfg, false)
+	if err != nil {
+		if msg := lockedMessage(cfg, err); msg != "" {
+			logrus.Info(msg)
+			summary.Skipped = msg
+		} else {
+			logrus.Errorf("Failed to lock database: %v", err)
+			runErr = fmt.Errorf("failed to lock database: %w", err)
+		}
+		return
+	}
+	defer runLock.Release()
+
+	// Pick up settings changed by other commands since the last run, like
+	// a new token
+	db.ReloadSettings()
+
+	result := health.RunResult{StartedAt: time.Now()}
+
+	// Honor the feed's ttl, skipHours, and skipDays, and its server's
+	// caching headers and requests to back off
+	hints, err := feed.LoadPollHints(db.FeedSettings(cfg.FeedURL))
+	if err != nil {
+		logrus.Warnf("Failed to load poll hints: %v", err)
+	}
+	if next := hints.NextFetch(result.StartedAt); next.After(result.StartedAt) {
+		logrus.Infof("Not fetching the feed until %s, as asked by the feed or its server", next.Format(time.RFC3339))
+	} else {
+		fetched, err := fetchFeed(ctx, cfg, db, true)
+		if err != nil {
+			logrus.Errorf("Fetch failed: %v", err)
+			result.Error = err.Error()
+			runErr = err
+		} else {
+			result.NewEntries = fetched.NewEntries
+			summary.Fetch = &fetchSummary{NewEntries: fetched.NewEntries, Purged: fetched.Purged, CaughtUp: fetched.CaughtUp}
+		}
+	}
+
+	posted, err := postUnposted(ctx, cfg, db, daemonPostLimit(cfg), false)
+	summary.setPostResult(posted)
+	if err != nil {
+		logrus.Errorf("Post failed: %v", err)
+		if result.Error == "" {
+			result.Error = err.Error()
+			runErr = err
+		}
+	} else {
+		result.Posted = posted.Posted
+		result.Failed = posted.Failed
+	}
+
+	if cfg.ExpireAfter > 0 {
+		if _, err := expireStatuses(ctx, cfg, db, false); err != nil {
+			logrus.Errorf("Expire failed: %v", err)
+		}
+	}
+
+	result.FinishedAt = time.Now()
+
+	_, _, unposted, err := db.GetStats()
+	if err != nil {
+		logrus.Warnf("Failed to get database stats: %v", err)
+	}
+
+	status.RecordRun(result, unposted)
+}
+
+// daemonPostLimit is the most entries a daemon run posts. With
+// post_interval set, it posts one entry per run rather than blocking the
+// loop while waiting between posts.
+func daemonPostLimit(cfg *config.Config) int {
+	if cfg.PostInterval > 0 {
+		return 1
+	}
+	return cfg.MaxItems
+}
+
+// daemonRunner does the runs requested through the admin API, one at a
+// time and never during a run of the daemon loop, which holds mu.
+type daemonRunner struct {
+	mu  sync.Mutex
+	cfg *config.Config
+	db  *database.DB
+}
+
+// Fetch fetches the feed now, even if the feed or its server asked for it
+// to be fetched later.
+func (r *daemonRunner) Fetch(ctx context.Context) (health.RunResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runLock, err := lockRun(ctx, r.cfg, false)
+	if err != nil {
+		return health.RunResult{}, err
+	}
+	defer runLock.Release()
+	r.db.ReloadSettings()
+
+	result := health.RunResult{StartedAt: time.Now()}
+	fetched, err := fetchFeed(ctx, r.cfg, r.db, true)
+	result.FinishedAt = time.Now()
+	if err != nil {
+		return result, err
+	}
+	result.NewEntries = fetched.NewEntries
+	return result, nil
+}
+
+// Post posts as many unposted entries as a daemon run would.
+func (r *daemonRunner) Post(ctx context.Context) (health.RunResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runLock, err := lockRun(ctx, r.cfg, false)
+	if err != nil {
+		return health.RunResult{}, err
+	}
+	defer runLock.Release()
+	r.db.ReloadSettings()
+
+	result := health.RunResult{StartedAt: time.Now()}
+	posted, err := postUnposted(ctx, r.cfg, r.db, daemonPostLimit(r.cfg), false)
+	result.FinishedAt = time.Now()
+	if posted != nil {
+		result.Posted = posted.Posted
+		result.Failed = posted.Failed
+	}
+	return result, err
+}
+
+// Entries lists entries as list --format json does.
+func (r *daemonRunner) Entries(opts database.ListOptions) (any, error) {
+	entries, err := r.db.ListEntries(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+
+	listed := make([]listedEntry, 0, len(entries))
+	for _, entry := range entries {
+		listed = append(listed, newListedEntry(entry))
+	}
+	return listed, nil
+}
@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lorchard/feed-to-mastodon/internal/admin"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+)
+
+// Limits on the entries shown by the dashboard.
+const (
+	dashboardQueueLimit  = 50
+	dashboardFailedLimit = 20
+	dashboardPostedLimit = 20
+)
+
+// Overview returns the feed health, the queue with the posts that would be
+// sent, and the latest posted entries.
+func (r *daemonRunner) Overview(ctx context.Context) (*admin.Overview, error) {
+	feedHealth, err := r.db.GetFeedHealth(r.cfg.FeedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	queued, err := r.db.GetUnpostedEntriesOrdered(ctx, dashboardQueueLimit, queueOrder(r.cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unposted entries: %w", err)
+	}
+	failed, err := r.db.ListEntries(database.ListOptions{State: database.StateFailed, Limit: dashboardFailedLimit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed entries: %w", err)
+	}
+	posted, err := r.db.ListEntries(database.ListOptions{State: database.StatePosted, Limit: dashboardPostedLimit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posted entries: %w", err)
+	}
+
+	// Show why previews are missing rather than failing the whole page
+	renderer, rendererErr := newRenderer(r.cfg, r.db)
+	preview := func(entry *database.Entry) string {
+		if rendererErr != nil {
+			return rendererErr.Error()
+		}
+		content, err := destination.RenderEntry(renderer, entry)
+		if err != nil {
+			return fmt.Sprintf("failed to render entry: %v", err)
+		}
+		if entry.ContentWarning != "" {
+			content = "CW: " + entry.ContentWarning + "\n\n" + content
+		}
+		return content
+	}
+
+	overview := &admin.Overview{Feed: feedHealth}
+	for _, entry := range queued {
+		shown := newDashboardEntry(entry)
+		shown.Preview = preview(entry)
+		overview.Queue = append(overview.Queue, shown)
+	}
+	for _, entry := range failed {
+		shown := newDashboardEntry(entry)
+		shown.Preview = preview(entry)
+		overview.Failed = append(overview.Failed, shown)
+	}
+	for _, entry := range posted {
+		overview.Posted = append(overview.Posted, newDashboardEntry(entry))
+	}
+	return overview, nil
+}
+
+// Skip marks an entry as skipped so it is never posted. Like a run, it
+// waits for other processes using the database, so it can't skip an
+// entry they're posting.
+func (r *daemonRunner) Skip(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runLock, err := lockRun(ctx, r.cfg, true)
+	if err != nil {
+		return err
+	}
+	defer runLock.Release()
+
+	entry, err := r.db.GetEntry(id)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("entry not found: %s", id)
+	}
+	if entry.State() == database.StatePosted {
+		return fmt.Errorf("entry %s was already posted", id)
+	}
+	_, err = r.db.MarkAsSkipped(id)
+	return err
+}
+
+// Requeue returns a posted, skipped, or filtered entry to the queue,
+// waiting for other processes using the database like Skip.
+func (r *daemonRunner) Requeue(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runLock, err := lockRun(ctx, r.cfg, true)
+	if err != nil {
+		return false, err
+	}
+	defer runLock.Release()
+
+	return r.db.Requeue(id)
+}
+
+// newDashboardEntry describes an entry for the dashboard.
+func newDashboardEntry(entry *database.Entry) admin.Entry {
+	listed := newListedEntry(entry)
+	shown := admin.Entry{
+		ID:        entry.ID,
+		Title:     listed.Title,
+		Link:      listed.Link,
+		StatusURL: listed.StatusURL,
+		LastError: entry.LastError.String,
+	}
+	if listed.FetchedAt != nil {
+		shown.FetchedAt = *listed.FetchedAt
+	}
+	if listed.PostedAt != nil {
+		shown.PostedAt = *listed.PostedAt
+	}
+	return shown
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+)
+
+func TestDashboardChangesWaitForOtherRuns(t *testing.T) {
+	cfg := &config.Config{DatabasePath: filepath.Join(t.TempDir(), "feed-to-mastodon.db")}
+	db, err := database.New(cfg.DatabasePath)
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	defer db.Close()
+	if _, err := db.SaveEntry("entry-1", []byte(`{"title": "Entry 1"}`)); err != nil {
+		t.Fatalf("SaveEntry() error = %v", err)
+	}
+	runner := &daemonRunner{cfg: cfg, db: db}
+
+	// Another process, like a cron post, is using the database
+	runLock, err := lockRun(context.Background(), cfg, false)
+	if err != nil {
+		t.Fatalf("lockRun() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := runner.Skip(ctx, "entry-1"); err == nil {
+		t.Error("Skip() while locked error = nil, want error")
+	}
+	if _, err := runner.Requeue(ctx, "entry-1"); err == nil {
+		t.Error("Requeue() while locked error = nil, want error")
+	}
+	if entry, err := db.GetEntry("entry-1"); err != nil || entry.State() != database.StateUnposted {
+		t.Fatalf("entry after locked Skip() = %+v, %v; want it unposted", entry, err)
+	}
+
+	if err := runLock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := runner.Skip(context.Background(), "entry-1"); err != nil {
+		t.Fatalf("Skip() error = %v", err)
+	}
+	if ok, err := runner.Requeue(context.Background(), "entry-1"); err != nil || !ok {
+		t.Errorf("Requeue() = %v, %v; want requeued", ok, err)
+	}
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deletePostRequeue bool
+	deletePostDryRun  bool
+)
+
+// NewDeletePostCmd creates the delete-post command.
+func NewDeletePostCmd() *cobra.Command {
+	deletePostCmd := &cobra.Command{
+		Use:   "delete-post <entry-id>",
+		Short: "Delete an entry's status from Mastodon",
+		Long: `Delete-post deletes the status an entry was posted as, to quickly retract
+a bad post.
+
+By default the entry is kept as a tombstone: it stays posted in the
+database, marked as deleted, so it isn't posted again. Use --requeue to
+return it to the queue instead, e.g. to post it again after fixing the
+template.
+
+Entries purged from the database after they were posted can still have
+their status deleted, though not be requeued.
+
+Entries split into a thread have every status of the thread deleted, last
+reply first. Cross-posts to other accounts are left alone.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runDeletePost,
+	}
+
+	deletePostCmd.Flags().BoolVar(&deletePostRequeue, "requeue", false, "return the entry to the queue after deleting its status")
+	deletePostCmd.Flags().BoolVar(&deletePostDryRun, "dry-run", false, "preview without deleting the status")
+
+	return deletePostCmd
+}
+
+func runDeletePost(cmd *cobra.Command, args []string) error {
+	id := args[0]
+
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.IsMastodon() {
+		return fmt.Errorf("delete-post only works with the mastodon destination")
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	status, err := db.GetPostedStatus(id)
+	if err != nil {
+		return err
+	}
+	if deletePostRequeue && status.Purged {
+		return fmt.Errorf("entry %s was purged from the database, so it can't be requeued", id)
+	}
+
+	// Purged entries are only known by their ID
+	entry := &database.Entry{ID: id}
+	if !status.Purged {
+		if entry, err = db.GetEntry(id); err != nil {
+			return err
+		}
+	}
+
+	accessToken, err := getAccessToken(cmd.Context(), cfg, db)
+	if err != nil {
+		return fmt.Errorf("authentication required: %w", err)
+	}
+
+	// Validate configuration (but don't require access token since we got it from DB)
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	flavor := detectFlavor(cmd.Context(), cfg)
+	poster, err := mastodon.NewWithFlavor(cfg.MastodonServer, accessToken, cfg.PostVisibility, cfg.ContentWarning, flavor)
+	if err != nil {
+		return fmt.Errorf("failed to create Mastodon poster: %w", err)
+	}
+
+	err = deleteEntryStatuses(cmd.Context(), poster, db, id, status.StatusID, deletePostDryRun)
+	if errors.Is(err, mastodon.ErrUnauthorized) {
+		if err := markAccessTokenInvalid(cmd.Context(), cfg, db); err != nil {
+			logrus.Warnf("Failed to record rejected access token: %v", err)
+		}
+		return rejectedTokenError(cfg, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete status of entry %s: %w", id, err)
+	}
+
+	if deletePostDryRun {
+		statusURL := entry.StatusURL.String
+		if statusURL == "" {
+			statusURL = "status " + status.StatusID
+		}
+		fmt.Printf("DRY RUN: Would delete %s: %s\n", entryLabel(entry), statusURL)
+		if deletePostRequeue {
+			fmt.Println("DRY RUN: Would requeue the entry")
+		}
+		return nil
+	}
+
+	if err := db.RecordStatusDeleted(*status, deletePostRequeue); err != nil {
+		return err
+	}
+	if deletePostRequeue {
+		fmt.Printf("Deleted %s and requeued it to be posted on the next run\n", entryLabel(entry))
+		return nil
+	}
+	fmt.Printf("Deleted %s\n", entryLabel(entry))
+	return nil
+}
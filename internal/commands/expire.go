@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	expireOlderThan time.Duration
+	expireDryRun    bool
+)
+
+// NewExpireCmd creates the expire command.
+func NewExpireCmd() *cobra.Command {
+	expireCmd := &cobra.Command{
+		Use:   "expire",
+		Short: "Delete statuses older than expire_after from Mastodon",
+		Long: `Expire deletes the statuses of entries posted longer ago than
+expire_after (or --older-than), for accounts that shouldn't accumulate
+history. The daemon does this after each run while expire_after is set.
+
+Expired entries stay posted in the database, so they aren't posted again.
+Entries split into a thread have every status of the thread deleted, last
+reply first. Statuses that were scheduled rather than posted directly
+aren't deleted, since their IDs aren't known. Cross-posts to other accounts
+are left alone.
+
+Mastodon only allows 30 deletes every 30 minutes, so expiring a long
+history takes a while.`,
+		Args: cobra.NoArgs,
+		RunE: runExpire,
+	}
+
+	expireCmd.Flags().DurationVar(&expireOlderThan, "older-than", 0, "delete statuses posted longer ago than this (overrides config expire_after)")
+	expireCmd.Flags().BoolVar(&expireDryRun, "dry-run", false, "preview statuses without deleting them")
+
+	return expireCmd
+}
+
+func runExpire(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cmd.Flags().Changed("older-than") {
+		cfg.ExpireAfter = expireOlderThan
+	}
+	if cfg.ExpireAfter <= 0 {
+		return fmt.Errorf("set expire_after in config, or give --older-than")
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	deleted, err := expireStatuses(cmd.Context(), cfg, db, expireDryRun)
+	if err != nil {
+		return err
+	}
+
+	if expireDryRun {
+		fmt.Printf("DRY RUN: Would delete %d statuses\n", deleted)
+		return nil
+	}
+	fmt.Printf("Deleted %d statuses posted more than %s ago\n", deleted, cfg.ExpireAfter)
+	return nil
+}
+
+// expireStatuses deletes the statuses of entries posted longer ago than
+// expire_after, and returns the number deleted. Statuses that fail to
+// delete are logged and tried again on the next run. Deleting stops when
+// ctx is done.
+func expireStatuses(ctx context.Context, cfg *config.Config, db *database.DB, dryRun bool) (int, error) {
+	entries, err := db.GetExpiredEntriesContext(ctx, time.Now().Add(-cfg.ExpireAfter))
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	logrus.Infof("Found %d statuses to expire", len(entries))
+
+	accessToken, err := getAccessToken(ctx, cfg, db)
+	if err != nil {
+		return 0, fmt.Errorf("authentication required: %w", err)
+	}
+
+	// Validate configuration (but don't require access token since we got it from DB)
+	if err := cfg.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid config: %w", err)
+	}
+
+	flavor := detectFlavor(ctx, cfg)
+	poster, err := mastodon.NewWithFlavor(cfg.MastodonServer, accessToken, cfg.PostVisibility, cfg.ContentWarning, flavor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create Mastodon poster: %w", err)
+	}
+
+	deleted := 0
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		err := deleteEntryStatuses(ctx, poster, db, entry.ID, entry.StatusID.String, dryRun)
+		if errors.Is(err, mastodon.ErrUnauthorized) {
+			if err := markAccessTokenInvalid(ctx, cfg, db); err != nil {
+				logrus.Warnf("Failed to record rejected access token: %v", err)
+			}
+			return deleted, rejectedTokenError(cfg, err)
+		}
+		if errors.Is(err, mastodon.ErrServerUnavailable) {
+			return deleted, fmt.Errorf("%s appears to be down, will retry on the next run", cfg.MastodonServer)
+		}
+		var rateLimitErr *mastodon.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			return deleted, fmt.Errorf("%s's rate limit is used up until %s, will retry on the next run", cfg.MastodonServer, rateLimitErr.Reset.Format(time.RFC3339))
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return deleted, ctx.Err()
+			}
+			logrus.Errorf("Failed to expire entry %s: %v", entry.ID, err)
+			continue
+		}
+
+		deleted++
+		if dryRun {
+			fmt.Printf("DRY RUN: Would delete %s: %s\n", entryLabel(entry), entry.StatusURL.String)
+			continue
+		}
+		if err := db.MarkAsDeleted(entry.ID); err != nil {
+			logrus.Errorf("Failed to mark entry %s as deleted: %v", entry.ID, err)
+		}
+	}
+	return deleted, nil
+}
+
+// deleteEntryStatuses deletes an entry's status from Mastodon, along with
+// the replies a long entry was split into, last reply first. Each reply is
+// forgotten once it's deleted, so a delete that fails partway picks up
+// where it stopped. Recording the entry's status as deleted is up to the
+// caller.
+func deleteEntryStatuses(ctx context.Context, poster *mastodon.Poster, db *database.DB, entryID, statusID string, dryRun bool) error {
+	replies, err := db.GetThreadStatuses(entryID)
+	if err != nil {
+		return err
+	}
+	for i := len(replies) - 1; i >= 0; i-- {
+		if err := poster.DeleteStatusContext(ctx, replies[i], dryRun); err != nil {
+			return err
+		}
+		if dryRun {
+			continue
+		}
+		if err := db.RemoveThreadStatus(entryID, replies[i]); err != nil {
+			return err
+		}
+	}
+	return poster.DeleteStatusContext(ctx, statusID, dryRun)
+}
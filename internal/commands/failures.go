@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var failuresRetry bool
+
+// NewFailuresCmd creates the failures command.
+func NewFailuresCmd() *cobra.Command {
+	failuresCmd := &cobra.Command{
+		Use:   "failures [entry-id...]",
+		Short: "List entries that failed to post",
+		Long: `Failures lists unposted entries that failed to post, with the number of
+attempts, the last error, and when the entry will be retried.
+
+Failed entries are retried with exponential backoff starting at
+retry_backoff, and given up on after max_post_attempts.
+
+Use --retry to clear the failure history of the given entries (or of all
+failed entries, if none are given) so they are posted on the next run.`,
+		RunE: runFailures,
+	}
+
+	failuresCmd.Flags().BoolVar(&failuresRetry, "retry", false, "clear failure history so entries are posted on the next run")
+
+	return failuresCmd
+}
+
+func runFailures(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if failuresRetry {
+		return retryFailures(db, args)
+	}
+
+	entries, err := db.GetFailedEntries()
+	if err != nil {
+		return fmt.Errorf("failed to get failed entries: %w", err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No failed entries")
+		return nil
+	}
+
+	for i, entry := range entries {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		fmt.Println(entryLabel(entry))
+		fmt.Printf("  Attempts:   %d\n", entry.FailureCount)
+		fmt.Printf("  Last error: %s\n", entry.LastError.String)
+		if entry.FailedAt.Valid {
+			fmt.Printf("  Gave up:    %s\n", entry.FailedAt.Time)
+		} else if entry.RetryAt.Valid {
+			fmt.Printf("  Retry at:   %s\n", entry.RetryAt.Time)
+		}
+	}
+
+	fmt.Printf("\nRun 'feed-to-mastodon failures --retry [entry-id...]' to retry now\n")
+	return nil
+}
+
+// retryFailures clears the failure history of the given entries, or of all
+// failed entries if ids is empty.
+func retryFailures(db *database.DB, ids []string) error {
+	if len(ids) == 0 {
+		entries, err := db.GetFailedEntries()
+		if err != nil {
+			return fmt.Errorf("failed to get failed entries: %w", err)
+		}
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+	}
+
+	reset := 0
+	for _, id := range ids {
+		ok, err := db.ResetFailures(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Printf("Entry %s has no failures to retry\n", id)
+			continue
+		}
+		reset++
+	}
+
+	fmt.Printf("Queued %d entries to be posted on the next run\n", reset)
+	return nil
+}
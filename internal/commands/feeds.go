@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// NewFeedsCmd creates the feeds command and its subcommands.
+func NewFeedsCmd() *cobra.Command {
+	feedsCmd := &cobra.Command{
+		Use:   "feeds",
+		Short: "Inspect configured feeds",
+	}
+
+	feedsCmd.AddCommand(&cobra.Command{
+		Use:   "health",
+		Short: "Summarize recent fetch results for each feed",
+		Long: `Health summarizes the recent fetch history of each configured feed:
+- Last fetch attempt and last successful fetch
+- Consecutive failures since the last success, and the latest error
+- Average number of new entries per successful fetch
+
+The summary covers the most recent fetches recorded in the database.`,
+		Args: cobra.NoArgs,
+		RunE: runFeedsHealth,
+	})
+
+	return feedsCmd
+}
+
+func runFeedsHealth(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	health, err := db.GetFeedHealth(cfg.FeedURL)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Feed: %s\n", health.FeedURL)
+
+	if health.Fetches == 0 {
+		fmt.Println("  No fetches recorded")
+		fmt.Println("\nRun 'feed-to-mastodon fetch' to fetch entries from the feed")
+		return nil
+	}
+
+	fmt.Printf("  Fetches recorded: %d\n", health.Fetches)
+	fmt.Printf("  Last attempt: %s\n", stringOr(health.LastAttempt, "never"))
+	fmt.Printf("  Last success: %s\n", stringOr(health.LastSuccess, "never"))
+	fmt.Printf("  Average new entries per fetch: %.1f\n", health.AverageNewEntries)
+
+	if health.ConsecutiveFailures > 0 {
+		fmt.Printf("  State: failing (%d consecutive failures)\n", health.ConsecutiveFailures)
+		fmt.Printf("  Last error: %s\n", stringOr(health.LastError, "unknown"))
+	} else {
+		fmt.Println("  State: ok")
+	}
+
+	return nil
+}
+
+// stringOr returns the pointed-to string, or fallback if it's nil.
+func stringOr(s *string, fallback string) string {
+	if s == nil {
+		return fallback
+	}
+	return *s
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lorchard/feed-to-mastodon/internal/config"
@@ -10,7 +11,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var noPurge bool
+var (
+	noPurge     bool
+	waitForLock bool
+)
 
 // NewFetchCmd creates the fetch command.
 func NewFetchCmd() *cobra.Command {
@@ -22,16 +26,27 @@ them to the database. Entries that already exist (based on their ID)
 are skipped automatically.
 
 By default, entries that are no longer in the feed are purged from the
-database to clean up old entries over time.`,
+database to clean up old entries over time. The purge setting limits
+this to entries fetched long enough ago, or beyond a number of recent
+entries, or turns it off. Posted entries leave a tombstone when purged,
+so items that come back into the feed aren't posted again.
+
+If another fetch or post is already using the database, fetch exits
+without doing anything, unless --wait is given.`,
 		RunE: runFetch,
 	}
 
 	fetchCmd.Flags().BoolVar(&noPurge, "no-purge", false, "skip purging entries that are no longer in the feed")
+	fetchCmd.Flags().BoolVar(&waitForLock, "wait", false, "wait for another run using the database to finish instead of skipping this one")
+	fetchCmd.Flags().StringVar(&summaryOut, "summary-out", "", "write a JSON summary of the run to this file")
 
 	return fetchCmd
 }
 
-func runFetch(cmd *cobra.Command, args []string) error {
+func runFetch(cmd *cobra.Command, args []string) (err error) {
+	summary := newRunSummary("fetch")
+	defer func() { summary.write(summaryOut, err) }()
+
 	// Load configuration
 	cfg, err := config.LoadConfig(GetConfigFile())
 	if err != nil {
@@ -43,46 +58,164 @@ func runFetch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
+	// Keep overlapping runs, e.g. from cron, from racing on the database
+	runLock, err := lockRun(cmd.Context(), cfg, waitForLock)
+	if err != nil {
+		if msg := lockedMessage(cfg, err); msg != "" {
+			fmt.Printf("%s (use --wait to wait for it)\n", msg)
+			summary.Skipped = msg
+			return nil
+		}
+		return fmt.Errorf("failed to lock database: %w", err)
+	}
+	defer runLock.Release()
+
 	// Open database
-	db, err := database.New(cfg.DatabasePath)
+	db, err := openDatabase(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
-	// Get stats before fetch
-	totalBefore, _, _, err := db.GetStats()
+	result, err := fetchFeed(cmd.Context(), cfg, db, !noPurge)
 	if err != nil {
-		return fmt.Errorf("failed to get database stats: %w", err)
+		return err
+	}
+	summary.Fetch = &fetchSummary{NewEntries: result.NewEntries, Purged: result.Purged, CaughtUp: result.CaughtUp}
+
+	if result.NewEntries > 0 || result.Purged > 0 {
+		fmt.Println()
+		if result.NewEntries > 0 {
+			fmt.Printf("Fetched %d new entries\n", result.NewEntries)
+		}
+		if result.CaughtUp > 0 {
+			fmt.Printf("Marked %d of them as posted, as this was the first fetch of the feed (initial_mode %s)\n", result.CaughtUp, cfg.InitialMode)
+		}
+		if result.Purged > 0 {
+			fmt.Printf("Purged %d old entries\n", result.Purged)
+		}
+		if result.NewEntries > 0 {
+			fmt.Printf("Run 'feed-to-mastodon status' to see what will be posted\n")
+		}
+	} else {
+		fmt.Println("\nNo new entries found")
 	}
 
-	// Create fetcher
+	return nil
+}
+
+// fetchResult summarizes a single fetch run.
+type fetchResult struct {
+	NewEntries int
+	Purged     int
+	// CaughtUp is the number of new entries marked as posted by
+	// initial_mode.
+	CaughtUp int
+}
+
+// newFetcher creates a fetcher with the configured HTTP options.
+func newFetcher(cfg *config.Config) (*feed.Fetcher, error) {
 	fetcher := feed.New()
+	fetcher.SetMaxEntryAge(cfg.MaxEntryAge)
+	fetcher.SetRetries(cfg.FetchRetries, cfg.FetchRetryBackoff)
+	err := fetcher.SetHTTPOptions(feed.HTTPOptions{
+		Timeout:   cfg.FetchTimeout,
+		Proxy:     cfg.HTTPProxy,
+		UserAgent: cfg.UserAgent,
+		Headers:   cfg.FetchHeaders,
+		Username:  cfg.FeedUsername,
+		Password:  cfg.FeedPassword,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SourceType == "json" {
+		if err := fetcher.SetJSONMapping(feed.JSONMapping(cfg.JSONMapping)); err != nil {
+			return nil, err
+		}
+	}
+	return fetcher, nil
+}
+
+// fetchFeed fetches the configured feed, saves its entries to the database,
+// and optionally purges entries that are no longer in the feed. Fetching
+// and saving stop when ctx is done.
+func fetchFeed(ctx context.Context, cfg *config.Config, db *database.DB, purge bool) (*fetchResult, error) {
+	fetcher, err := newFetcher(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	// Fetch feed
 	logrus.Infof("Fetching feed from %s", cfg.FeedURL)
-	feedData, err := fetcher.Fetch(cfg.FeedURL)
+	feedData, err := fetcher.FetchContext(ctx, cfg.FeedURL)
+
+	// Remember when the feed and its server want it fetched again
+	if err := fetcher.StorePollHints(db.FeedSettings(cfg.FeedURL)); err != nil {
+		logrus.Warnf("Failed to store poll hints: %v", err)
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("fetch interrupted: %w", ctx.Err())
+	}
 	if err != nil {
-		return fmt.Errorf("failed to fetch feed: %w", err)
+		if logErr := db.RecordFetch(cfg.FeedURL, 0, err); logErr != nil {
+			logrus.Warnf("Failed to record fetch: %v", logErr)
+		}
+		notifyFetchFailure(ctx, cfg, db, err)
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
 	}
 
 	logrus.Infof("Feed: %s", feedData.Title)
 	logrus.Infof("Found %d entries in feed", len(feedData.Items))
 
+	first, err := isFirstFetch(db, cfg.FeedURL)
+	if err != nil {
+		return nil, err
+	}
+
 	// Save entries to database
-	saved, err := fetcher.SaveEntriesToDB(feedData, db)
+	newEntries, err := fetcher.SaveEntriesToDBContext(ctx, feedData, db)
 	if err != nil {
-		return fmt.Errorf("failed to save entries: %w", err)
+		return nil, fmt.Errorf("failed to save entries: %w", err)
+	}
+
+	// Keep the archive of a new feed from flooding the account
+	var caughtUp int
+	if first {
+		caughtUp, err = applyInitialMode(cfg, db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Extract the linked articles of new entries for feeds with only summaries
+	if cfg.ExtractContent {
+		extracted, err := fetcher.ExtractArticles(ctx, db)
+		if err != nil {
+			logrus.Warnf("Failed to extract articles: %v", err)
+		} else if extracted > 0 {
+			logrus.Infof("Extracted %d articles", extracted)
+		}
 	}
 
 	// Store feed metadata for use in templates
-	if err := fetcher.StoreFeedMetadata(feedData, db); err != nil {
+	if err := fetcher.StoreFeedMetadata(feedData, db.FeedSettings(cfg.FeedURL)); err != nil {
 		logrus.Warnf("Failed to store feed metadata: %v", err)
 	}
 
-	// Purge entries no longer in feed (unless --no-purge is set)
+	// Purge entries no longer in feed, as set by purge
 	var purged int
-	if !noPurge {
+	policy, err := cfg.PurgePolicy()
+	if err != nil {
+		return nil, err
+	}
+	if purge && !policy.Never {
+		fetcher.SetPurgeOptions(feed.PurgeOptions{
+			MinAge:     policy.MinAge,
+			Keep:       policy.Keep,
+			KeepPosted: !cfg.PurgePosted,
+		})
 		purged, err = fetcher.PurgeStaleEntries(feedData, db)
 		if err != nil {
 			logrus.Warnf("Failed to purge stale entries: %v", err)
@@ -92,32 +225,60 @@ func runFetch(cmd *cobra.Command, args []string) error {
 	// Get stats after fetch
 	totalAfter, postedAfter, unpostedAfter, err := db.GetStats()
 	if err != nil {
-		return fmt.Errorf("failed to get database stats: %w", err)
+		return nil, fmt.Errorf("failed to get database stats: %w", err)
 	}
 
-	// Calculate and display results
-	newEntries := totalAfter - totalBefore + purged
-	logrus.Infof("Saved %d new entries (skipped %d duplicates)", saved, len(feedData.Items)-saved)
+	// Log results
 	if purged > 0 {
 		logrus.Infof("Purged %d entries no longer in feed", purged)
 	}
 	logrus.Infof("Database totals: %d total, %d posted, %d unposted",
 		totalAfter, postedAfter, unpostedAfter)
 
-	if newEntries > 0 || purged > 0 {
-		fmt.Println()
-		if newEntries > 0 {
-			fmt.Printf("Fetched %d new entries\n", newEntries)
-		}
-		if purged > 0 {
-			fmt.Printf("Purged %d old entries\n", purged)
-		}
-		if newEntries > 0 {
-			fmt.Printf("Run 'feed-to-mastodon status' to see what will be posted\n")
-		}
-	} else {
-		fmt.Println("\nNo new entries found")
+	if err := db.RecordFetch(cfg.FeedURL, newEntries, nil); err != nil {
+		logrus.Warnf("Failed to record fetch: %v", err)
 	}
 
-	return nil
+	return &fetchResult{NewEntries: newEntries, Purged: purged, CaughtUp: caughtUp}, nil
+}
+
+// isFirstFetch reports whether the feed is being fetched for the first
+// time: no fetch of it succeeded before, and the database is empty. The
+// database check keeps a long outage, whose failures push the last success
+// out of the fetch log, from passing for a first fetch.
+func isFirstFetch(db *database.DB, feedURL string) (bool, error) {
+	health, err := db.GetFeedHealth(feedURL)
+	if err != nil {
+		return false, err
+	}
+	if health.LastSuccess != nil {
+		return false, nil
+	}
+	total, _, _, err := db.GetStats()
+	if err != nil {
+		return false, fmt.Errorf("failed to get database stats: %w", err)
+	}
+	return total == 0, nil
+}
+
+// applyInitialMode marks the backlog saved by the first fetch of the feed
+// as posted, as set by initial_mode. Returns the number of entries marked.
+func applyInitialMode(cfg *config.Config, db *database.DB) (int, error) {
+	var keep int
+	switch cfg.InitialMode {
+	case "catchup":
+	case "post_latest_n":
+		keep = cfg.InitialPosts
+	default:
+		return 0, nil
+	}
+
+	caughtUp, err := db.CatchUp(keep)
+	if err != nil {
+		return 0, err
+	}
+	if caughtUp > 0 {
+		logrus.Infof("First fetch of the feed: marked %d entries as posted (initial_mode %s)", caughtUp, cfg.InitialMode)
+	}
+	return caughtUp, nil
 }
@@ -1,29 +1,327 @@
 package commands
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/lorchard/feed-to-mastodon/internal/config"
 	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/lock"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/lorchard/feed-to-mastodon/internal/secret"
+	"github.com/sirupsen/logrus"
 )
 
+// invalidTokenSetting holds a fingerprint of an access token that the server
+// rejected, so we stop using it until it's replaced.
+const invalidTokenSetting = "mastodon_token_invalid"
+
+// Where the access token in effect comes from, checked in this order.
+const (
+	tokenSourceConfig   = "mastodon_token in config"
+	tokenSourceCommand  = "mastodon_token_command"
+	tokenSourceKeyring  = "OS keyring"
+	tokenSourceDatabase = "database"
+)
+
+// errNoAccessToken is returned when no access token is configured or
+// stored.
+var errNoAccessToken = errors.New("no access token found - run 'link' and 'code' commands to authenticate, or set mastodon_token in config")
+
+// secretKeyEnv is the environment variable holding the key for
+// secret_encryption: env.
+const secretKeyEnv = "FEED_TO_MASTODON_SECRET_KEY"
+
+// reauthInstructions explains how to replace a rejected access token.
+const reauthInstructions = `re-authenticate with 'feed-to-mastodon link' and 'feed-to-mastodon code <authorization-code>', or set a new mastodon_token in config`
+
 // getAccessToken retrieves the access token from config or database.
 // Priority: config token > database token
-func getAccessToken(cfg *config.Config, db *database.DB) (string, error) {
+// Tokens previously rejected by the server are refused with re-auth guidance.
+// ctx bounds running mastodon_token_command.
+func getAccessToken(ctx context.Context, cfg *config.Config, db *database.DB) (string, error) {
+	token, err := findAccessToken(ctx, cfg, db)
+	if err != nil {
+		return "", err
+	}
+
+	invalid, err := db.Settings().Get(invalidTokenSetting)
+	if err != nil {
+		return "", fmt.Errorf("failed to check access token state: %w", err)
+	}
+	if invalid != nil && *invalid == tokenFingerprint(token) {
+		// The command may print a new token once it's been replaced
+		commandToken = ""
+		return "", fmt.Errorf("access token was rejected by %s - %s", cfg.MastodonServer, reauthInstructions)
+	}
+
+	return token, nil
+}
+
+// findAccessToken looks up the configured or stored access token.
+// Priority: config token > token command > keyring > database token
+func findAccessToken(ctx context.Context, cfg *config.Config, db *database.DB) (string, error) {
+	token, _, err := lookupAccessToken(ctx, cfg, db)
+	return token, err
+}
+
+// lookupAccessToken is findAccessToken, also returning where the token
+// came from, one of the tokenSource constants.
+func lookupAccessToken(ctx context.Context, cfg *config.Config, db *database.DB) (string, string, error) {
 	// First check if access token is in config
 	if cfg.MastodonAccessToken != "" {
-		return cfg.MastodonAccessToken, nil
+		return cfg.MastodonAccessToken, tokenSourceConfig, nil
+	}
+
+	// Then ask the configured command, e.g. a password manager, once per run
+	if cfg.MastodonTokenCommand != "" {
+		if commandToken == "" {
+			token, err := secret.Command(ctx, cfg.MastodonTokenCommand)
+			if err != nil {
+				return "", "", fmt.Errorf("failed to get access token from mastodon_token_command: %w", err)
+			}
+			commandToken = token
+		}
+		return commandToken, tokenSourceCommand, nil
+	}
+
+	return storedAccessToken(cfg, db)
+}
+
+// storedAccessToken looks up the access token stored by 'code', in the OS
+// keyring or the database, returning where it was found. Returns
+// errNoAccessToken if there is none.
+func storedAccessToken(cfg *config.Config, db *database.DB) (string, string, error) {
+	if cfg.TokenStorage == "keyring" {
+		token, err := secret.Get(keyringAccount(cfg))
+		if err == nil {
+			return token, tokenSourceKeyring, nil
+		}
+		// Tokens stored before switching to the keyring are still found below
+		if !errors.Is(err, secret.ErrNotFound) {
+			return "", "", fmt.Errorf("failed to get access token: %w", err)
+		}
 	}
 
 	// Otherwise try to get it from the database
-	token, err := db.GetSetting("mastodon_access_token")
+	token, err := db.Settings().GetSecret("mastodon_access_token")
 	if err != nil {
-		return "", fmt.Errorf("failed to get access token from database: %w", err)
+		return "", "", fmt.Errorf("failed to get access token from database: %w", err)
 	}
 
 	if token == nil || *token == "" {
-		return "", fmt.Errorf("no access token found - run 'link' and 'code' commands to authenticate, or set mastodon_token in config")
+		return "", "", errNoAccessToken
+	}
+
+	return *token, tokenSourceDatabase, nil
+}
+
+// storeAccessToken stores a token from 'code' in the OS keyring or the
+// database, with its granted scopes, clearing any record of a rejected
+// token. Returns the different token it replaced, which still works until
+// it's revoked, if any, and where the token was stored.
+func storeAccessToken(cfg *config.Config, db *database.DB, token *mastodon.Token) (string, string, error) {
+	previous, _, err := storedAccessToken(cfg, db)
+	if err != nil && !errors.Is(err, errNoAccessToken) {
+		logrus.Warnf("Failed to look up the previous access token: %v", err)
+	}
+	if previous == token.AccessToken {
+		previous = ""
+	}
+
+	// Store the access token in the keyring or the database
+	storedIn := tokenSourceDatabase
+	if cfg.TokenStorage == "keyring" {
+		if err := secret.Set(keyringAccount(cfg), token.AccessToken); err != nil {
+			return "", "", fmt.Errorf("failed to store access token: %w", err)
+		}
+		// Don't leave an older token behind in the database
+		if err := db.Settings().Delete("mastodon_access_token"); err != nil {
+			return "", "", fmt.Errorf("failed to remove access token from database: %w", err)
+		}
+		storedIn = tokenSourceKeyring
+	} else if err := db.Settings().SetSecret("mastodon_access_token", token.AccessToken); err != nil {
+		return "", "", fmt.Errorf("failed to store access token: %w", err)
+	}
+
+	// Store the scopes the server actually granted, without keeping the
+	// previous token's if the server didn't say
+	if token.Scope != "" {
+		if err := db.Settings().Set("mastodon_token_scopes", token.Scope); err != nil {
+			return "", "", fmt.Errorf("failed to store granted scopes: %w", err)
+		}
+	} else if err := db.Settings().Delete("mastodon_token_scopes"); err != nil {
+		return "", "", fmt.Errorf("failed to clear granted scopes: %w", err)
+	}
+
+	// Clear any record of a previously rejected token
+	if err := db.Settings().Delete(invalidTokenSetting); err != nil {
+		return "", "", fmt.Errorf("failed to clear rejected token state: %w", err)
+	}
+	if err := db.Settings().Delete(invalidTokenSetting + "_at"); err != nil {
+		return "", "", fmt.Errorf("failed to clear rejected token state: %w", err)
+	}
+
+	return previous, storedIn, nil
+}
+
+// forgetAccessToken removes the access token stored by 'code', and what's
+// recorded about it, from the OS keyring and the database.
+func forgetAccessToken(cfg *config.Config, db *database.DB) error {
+	if cfg.TokenStorage == "keyring" {
+		if err := secret.Delete(keyringAccount(cfg)); err != nil {
+			return err
+		}
+	}
+	for _, key := range []string{"mastodon_access_token", "mastodon_token_scopes", invalidTokenSetting, invalidTokenSetting + "_at"} {
+		if err := db.Settings().Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeTokenSource says where the access token in effect comes from,
+// for status.
+func describeTokenSource(ctx context.Context, cfg *config.Config, db *database.DB) string {
+	_, source, err := lookupAccessToken(ctx, cfg, db)
+	switch {
+	case errors.Is(err, errNoAccessToken):
+		return "none"
+	case err != nil:
+		return fmt.Sprintf("unknown (%v)", err)
+	case source == tokenSourceDatabase && cfg.SecretEncryption != "" && cfg.SecretEncryption != "off":
+		return source + " (encrypted)"
+	}
+	return source
+}
+
+// commandToken caches the token printed by mastodon_token_command, so the
+// command, which may prompt to unlock a password manager, runs once. It's
+// cleared when the server rejects the token, so the daemon asks again.
+var commandToken string
+
+// keyringAccount names the access token in the OS keyring. The client ID
+// tells apart projects posting to different accounts on the same server.
+func keyringAccount(cfg *config.Config) string {
+	return strings.TrimRight(cfg.MastodonServer, "/") + "?client_id=" + cfg.MastodonClientID
+}
+
+// tokenConfigured reports whether the access token comes from config,
+// rather than from 'code', so its granted scopes aren't known.
+func tokenConfigured(cfg *config.Config) bool {
+	return cfg.MastodonAccessToken != "" || cfg.MastodonTokenCommand != ""
+}
+
+// markAccessTokenInvalid records that the current access token was rejected,
+// so later runs fail fast instead of retrying every entry with it.
+func markAccessTokenInvalid(ctx context.Context, cfg *config.Config, db *database.DB) error {
+	token, err := findAccessToken(ctx, cfg, db)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Settings().Set(invalidTokenSetting, tokenFingerprint(token)); err != nil {
+		return err
+	}
+	commandToken = ""
+
+	return db.Settings().SetTime(invalidTokenSetting+"_at", time.Now())
+}
+
+// rejectedTokenError explains why the server refused the access token in
+// err and how to replace it.
+func rejectedTokenError(cfg *config.Config, err error) error {
+	if errors.Is(err, mastodon.ErrMissingScope) {
+		return fmt.Errorf("access token for %s lacks the write:statuses scope - include write (or write:statuses) in oauth_scopes and %s", cfg.MastodonServer, reauthInstructions)
+	}
+	return fmt.Errorf("access token was rejected by %s - %s", cfg.MastodonServer, reauthInstructions)
+}
+
+// tokenFingerprint returns a hash of a token, so the token itself isn't
+// duplicated in the settings table.
+func tokenFingerprint(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// openDatabase opens the configured database with its SQLite options, and
+// the key to encrypt secrets stored in it with, if secret_encryption is set.
+func openDatabase(cfg *config.Config) (*database.DB, error) {
+	db, err := database.NewWithOptions(cfg.DatabasePath, database.Options{
+		BusyTimeout: cfg.SQLiteBusyTimeout,
+		Synchronous: cfg.SQLiteSynchronous,
+		CacheSize:   cfg.SQLiteCacheSize,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return *token, nil
+	key, err := secretKey(cfg)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if key != "" {
+		cipher, err := secret.NewCipher(key)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		db.SetSecretCipher(cipher)
+	}
+	return db, nil
+}
+
+// secretKey returns the key that secrets in the database are encrypted
+// with, or "" if they aren't. With secret_encryption: keyring, a key is
+// generated for the database the first time.
+func secretKey(cfg *config.Config) (string, error) {
+	switch cfg.SecretEncryption {
+	case "env":
+		key := os.Getenv(secretKeyEnv)
+		if key == "" {
+			return "", fmt.Errorf("secret_encryption is env, but %s isn't set", secretKeyEnv)
+		}
+		return key, nil
+	case "keyring":
+		path, err := filepath.Abs(cfg.DatabasePath)
+		if err != nil {
+			return "", err
+		}
+		key, err := secret.KeyringKey("secret_key?database=" + path)
+		if err != nil {
+			return "", fmt.Errorf("failed to get secret key: %w", err)
+		}
+		return key, nil
+	}
+	return "", nil
+}
+
+// lockRun takes the lock that keeps runs against the configured database,
+// e.g. overlapping cron jobs, from racing each other. With wait set, it
+// waits for the other run to finish; otherwise, if the database is locked,
+// it returns an error matching lock.ErrLocked.
+func lockRun(ctx context.Context, cfg *config.Config, wait bool) (*lock.Lock, error) {
+	return lock.Acquire(ctx, cfg.DatabasePath+".lock", wait)
+}
+
+// lockedMessage explains that a run was skipped because another run holds
+// the lock, or returns "" for other errors.
+func lockedMessage(cfg *config.Config, err error) string {
+	var lockedErr *lock.LockedError
+	if !errors.As(err, &lockedErr) {
+		return ""
+	}
+	if lockedErr.PID > 0 {
+		return fmt.Sprintf("Another run (pid %d) is using %s, skipping this one", lockedErr.PID, cfg.DatabasePath)
+	}
+	return fmt.Sprintf("Another run is using %s, skipping this one", cfg.DatabasePath)
 }
@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/lorchard/feed-to-mastodon/internal/secret"
+	"github.com/zalando/go-keyring"
+)
+
+// newTokenTest returns a config storing tokens as set by storage, and an
+// empty database.
+func newTokenTest(t *testing.T, storage string) (*config.Config, *database.DB) {
+	t.Helper()
+	keyring.MockInit()
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &config.Config{
+		MastodonServer:   "https://mastodon.example",
+		MastodonClientID: "client-id",
+		TokenStorage:     storage,
+	}
+	return cfg, db
+}
+
+func TestStoreAccessToken(t *testing.T) {
+	for _, storage := range []string{"database", "keyring"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg, db := newTokenTest(t, storage)
+
+			replaced, _, err := storeAccessToken(cfg, db, &mastodon.Token{AccessToken: "token-1", Scope: "write:statuses"})
+			if err != nil || replaced != "" {
+				t.Fatalf("storeAccessToken() = %q, %v; want nothing replaced", replaced, err)
+			}
+
+			// Authorizing again can return the same token, which mustn't be
+			// revoked
+			replaced, _, err = storeAccessToken(cfg, db, &mastodon.Token{AccessToken: "token-1"})
+			if err != nil || replaced != "" {
+				t.Errorf("storeAccessToken() of same token = %q, %v; want nothing replaced", replaced, err)
+			}
+			if scopes, err := db.Settings().Get("mastodon_token_scopes"); err != nil || scopes != nil {
+				t.Errorf("scopes = %v, %v; want cleared when the server doesn't say", scopes, err)
+			}
+
+			replaced, _, err = storeAccessToken(cfg, db, &mastodon.Token{AccessToken: "token-2"})
+			if err != nil || replaced != "token-1" {
+				t.Errorf("storeAccessToken() of new token = %q, %v; want token-1 replaced", replaced, err)
+			}
+			if token, err := getAccessToken(context.Background(), cfg, db); err != nil || token != "token-2" {
+				t.Errorf("getAccessToken() = %q, %v; want token-2", token, err)
+			}
+		})
+	}
+
+	t.Run("clears a rejected token", func(t *testing.T) {
+		cfg, db := newTokenTest(t, "database")
+		if _, _, err := storeAccessToken(cfg, db, &mastodon.Token{AccessToken: "token-1"}); err != nil {
+			t.Fatalf("storeAccessToken() error = %v", err)
+		}
+		if err := markAccessTokenInvalid(context.Background(), cfg, db); err != nil {
+			t.Fatalf("markAccessTokenInvalid() error = %v", err)
+		}
+		if _, err := getAccessToken(context.Background(), cfg, db); err == nil {
+			t.Fatal("getAccessToken() of rejected token error = nil, want error")
+		}
+
+		if _, _, err := storeAccessToken(cfg, db, &mastodon.Token{AccessToken: "token-1"}); err != nil {
+			t.Fatalf("storeAccessToken() error = %v", err)
+		}
+		if token, err := getAccessToken(context.Background(), cfg, db); err != nil || token != "token-1" {
+			t.Errorf("getAccessToken() after code = %q, %v; want token-1", token, err)
+		}
+	})
+}
+
+func TestForgetAccessToken(t *testing.T) {
+	for _, storage := range []string{"database", "keyring"} {
+		t.Run(storage, func(t *testing.T) {
+			cfg, db := newTokenTest(t, storage)
+			if _, _, err := storeAccessToken(cfg, db, &mastodon.Token{AccessToken: "token-1", Scope: "write:statuses"}); err != nil {
+				t.Fatalf("storeAccessToken() error = %v", err)
+			}
+			if err := markAccessTokenInvalid(context.Background(), cfg, db); err != nil {
+				t.Fatalf("markAccessTokenInvalid() error = %v", err)
+			}
+
+			if err := forgetAccessToken(cfg, db); err != nil {
+				t.Fatalf("forgetAccessToken() error = %v", err)
+			}
+
+			for _, key := range []string{"mastodon_access_token", "mastodon_token_scopes", invalidTokenSetting, invalidTokenSetting + "_at"} {
+				if value, err := db.Settings().Get(key); err != nil || value != nil {
+					t.Errorf("setting %s = %v, %v; want removed", key, value, err)
+				}
+			}
+			if _, err := secret.Get(keyringAccount(cfg)); !errors.Is(err, secret.ErrNotFound) {
+				t.Errorf("keyring token error = %v, want ErrNotFound", err)
+			}
+			if _, _, err := storedAccessToken(cfg, db); !errors.Is(err, errNoAccessToken) {
+				t.Errorf("storedAccessToken() error = %v, want errNoAccessToken", err)
+			}
+		})
+	}
+}
+
+func TestDatabaseTokenWithKeyringStorage(t *testing.T) {
+	cfg, db := newTokenTest(t, "database")
+	if _, _, err := storeAccessToken(cfg, db, &mastodon.Token{AccessToken: "token-1"}); err != nil {
+		t.Fatalf("storeAccessToken() error = %v", err)
+	}
+
+	// Switching to the keyring keeps the token stored before working
+	cfg.TokenStorage = "keyring"
+	token, source, err := storedAccessToken(cfg, db)
+	if err != nil || token != "token-1" || source != tokenSourceDatabase {
+		t.Fatalf("storedAccessToken() = %q, %q, %v; want token-1 from the database", token, source, err)
+	}
+
+	t.Run("is forgotten on logout", func(t *testing.T) {
+		if err := forgetAccessToken(cfg, db); err != nil {
+			t.Fatalf("forgetAccessToken() error = %v", err)
+		}
+		if _, _, err := storedAccessToken(cfg, db); !errors.Is(err, errNoAccessToken) {
+			t.Errorf("storedAccessToken() after forget error = %v, want errNoAccessToken", err)
+		}
+	})
+
+	t.Run("is replaced by code", func(t *testing.T) {
+		cfg.TokenStorage = "database"
+		if _, _, err := storeAccessToken(cfg, db, &mastodon.Token{AccessToken: "token-1"}); err != nil {
+			t.Fatalf("storeAccessToken() error = %v", err)
+		}
+		cfg.TokenStorage = "keyring"
+
+		replaced, storedIn, err := storeAccessToken(cfg, db, &mastodon.Token{AccessToken: "token-2"})
+		if err != nil || replaced != "token-1" || storedIn != tokenSourceKeyring {
+			t.Fatalf("storeAccessToken() = %q, %q, %v; want token-1 replaced in the keyring", replaced, storedIn, err)
+		}
+		if value, err := db.Settings().Get("mastodon_access_token"); err != nil || value != nil {
+			t.Errorf("database token = %v, %v; want removed", value, err)
+		}
+	})
+}
+
+func TestTokenCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell command")
+	}
+	cfg, db := newTokenTest(t, "database")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token-1\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg.MastodonTokenCommand = "cat " + tokenFile
+	commandToken = ""
+	t.Cleanup(func() { commandToken = "" })
+
+	if token, err := getAccessToken(context.Background(), cfg, db); err != nil || token != "token-1" {
+		t.Fatalf("getAccessToken() = %q, %v; want token-1", token, err)
+	}
+
+	// A rotated token is only read again once the old one is rejected
+	if err := os.WriteFile(tokenFile, []byte("token-2\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if token, err := getAccessToken(context.Background(), cfg, db); err != nil || token != "token-1" {
+		t.Errorf("getAccessToken() = %q, %v; want cached token-1", token, err)
+	}
+	if err := markAccessTokenInvalid(context.Background(), cfg, db); err != nil {
+		t.Fatalf("markAccessTokenInvalid() error = %v", err)
+	}
+	if token, err := getAccessToken(context.Background(), cfg, db); err != nil || token != "token-2" {
+		t.Errorf("getAccessToken() after rejection = %q, %v; want token-2", token, err)
+	}
+
+	t.Run("stops when ctx is done", func(t *testing.T) {
+		commandToken = ""
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := getAccessToken(ctx, cfg, db); err == nil {
+			t.Error("getAccessToken() with done ctx error = nil, want error")
+		}
+	})
+}
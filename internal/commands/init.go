@@ -94,6 +94,26 @@ func createDefaultConfig(path string) error {
 # REQUIRED: Feed URL to fetch
 feed_url: "https://example.com/feed.xml"
 
+# OPTIONAL: What feed_url returns: feed for RSS, Atom, or JSON Feed, or
+# json for any other JSON API, whose items are read as described by
+# json_mapping. Each mapping is a path into the JSON, like .data.items or
+# .links[0].href; list alternatives with //, as in .published // .created.
+# items is the path of the array of items (empty if the response is one),
+# and one of id, link, or title is required to tell items apart. Dates may
+# be Unix timestamps or dates like 2024-01-02T15:04:05Z.
+# Default: feed
+# source_type: json
+# json_mapping:
+#   items: ".data.posts"
+#   id: ".id"
+#   title: ".title"
+#   link: ".url"
+#   date: ".published_at // .created_at"
+#   description: ".summary"
+#   content: ".body"
+#   author: ".author.name"
+#   feed_title: ".data.title"
+
 # REQUIRED: Mastodon server URL
 mastodon_server: "https://mastodon.social"
 
@@ -114,27 +134,510 @@ mastodon_server: "https://mastodon.social"
 #
 # mastodon_client_id: "your-client-id"
 # mastodon_client_secret: "your-client-secret"
+#
+# Or get the token from a command, e.g. a password manager, instead of
+# mastodon_token (the first line of its output is used):
+# mastodon_token_command: "pass show mastodon"
+#
+# Where 'code' stores the access token: database (default), or keyring for
+# the OS keyring (macOS Keychain, Secret Service, Windows Credential Manager)
+# token_storage: keyring
+#
+# Encrypt the access token and Bluesky session stored in the database, so
+# a copy of the database file alone doesn't give them away: off (default),
+# env for a key in the FEED_TO_MASTODON_SECRET_KEY environment variable
+# (e.g. from 'openssl rand -base64 32'), or keyring for a key generated
+# and kept in the OS keyring. Tokens stored before are encrypted when next
+# used. Without the key, stored tokens can't be read, and 'code' must be
+# run again.
+# secret_encryption: env
+#
+# Instead of creating the application by hand, 'feed-to-mastodon register'
+# can create one and print the client ID and secret.
+#
+# OAuth scopes requested by 'register' and 'link' (default: write:statuses
+# write:media read:accounts). 'code' warns when the granted scopes are
+# missing ones the configured features need (read:statuses for
+# dedupe_timeline, read:search for status_links), or give more than needed.
+# oauth_scopes: "write:statuses write:media read:accounts read:statuses"
 
 # OPTIONAL: Database file path (default: ./feed-to-mastodon.db)
 database_path: "feed-to-mastodon.db"
 
+# OPTIONAL: SQLite tuning. sqlite_busy_timeout is how long to wait for
+# another run to release the database (default: 5s); raise it if runs
+# overlap or the database is on network storage. sqlite_synchronous is off,
+# normal, full, or extra (default: normal); use full on storage that may
+# lose recent writes on power failure. sqlite_cache_size is the page cache
+# in KiB (default: SQLite's own, about 2MB).
+# sqlite_busy_timeout: "5s"
+# sqlite_synchronous: "normal"
+# sqlite_cache_size: 8192
+
 # OPTIONAL: Template file path (default: ./post-template.txt)
 template_path: "post-template.txt"
 
-# OPTIONAL: Character limit for posts (default: 500)
-character_limit: 500
+# OPTIONAL: Use other templates for some entries. The first rule whose
+# match_category is one of the entry's categories and whose match_feed is the
+# feed's title, link, or feed URL wins; either match may be left out.
+# Entries matching no rule use template_path.
+# templates:
+#   - match_category: "podcast"
+#     path: "podcast-template.txt"
+
+# OPTIONAL: Strings for templates to use as .Vars, like {{.Vars.handle}},
+# so they can change per deployment without editing the template. Names
+# are lowercased, so use {{.Vars.campaign}} for Campaign.
+# template_vars:
+#   handle: "@me@example.social"
+#   campaign: "#LaunchWeek"
+
+# OPTIONAL: Keep mentions (@user@host) and hashtags in feed text from
+# mentioning fediverse users or tagging the post, by inserting an invisible
+# space after the @ or #. Mentions and hashtags in the template itself,
+# including those from template_vars and the hashtag function, still work.
+# Default: true
+# escape_mentions: true
+
+# OPTIONAL: Timezone for dates formatted by template functions, such as
+# "America/Los_Angeles", or "Local" for the system timezone
+# Default: the timezone of the date in the feed
+# timezone: "America/Los_Angeles"
+
+# OPTIONAL: Hashtags for feed categories, used by the template hashtag
+# function instead of a tag derived from the category. An empty tag drops
+# the category.
+# hashtags:
+#   - category: "Node.js"
+#     tag: "NodeJS"
+#   - category: "Uncategorized"
+#     tag: ""
+
+# OPTIONAL: Character limit for posts
+# Counted like Mastodon does: URLs count as 23 characters, the domain of a
+# mention doesn't count, and the content warning counts toward the limit.
+# Default: the server's limit when detect_instance_limits is on, else 500
+# (5000 on GoToSocial, Akkoma, and Pleroma)
+# character_limit: 500
+
+# OPTIONAL: Query the server for its character limit, media size limit,
+# and supported media types before posting. Turn off for offline use.
+# Default: true
+# detect_instance_limits: true
+
+# OPTIONAL: The server software, for its differences from Mastodon:
+# mastodon, gotosocial, akkoma, pleroma, or auto to detect it from the
+# server. GoToSocial, Akkoma, and Pleroma default to a 5000 character limit,
+# Akkoma and Pleroma allow the local visibility (posts that aren't
+# federated), and posts to GoToSocial, which ignores idempotency keys,
+# aren't retried after server errors.
+# Default: auto
+# instance_flavor: auto
+
+# OPTIONAL: What to do with posts over the character limit: warn logs a
+# warning and posts them anyway, truncate shortens the entry's description
+# and content until the post fits (keeping the link and hashtags), and fail
+# refuses to post them. truncate and fail can't be used with split_long_posts.
+# Default: warn
+# character_limit_mode: warn
+
+# OPTIONAL: Post entries over the character limit as a thread of replies,
+# split at word boundaries with (1/N) markers, instead of as one post
+# Default: false
+# split_long_posts: false
 
-# OPTIONAL: Post visibility (public, unlisted, private, direct)
+# OPTIONAL: Edit the posted status when an entry's content changes in the
+# feed, instead of leaving the original post as it was. Same as post --update.
+# Default: false
+# update_edited: false
+
+# OPTIONAL: Post visibility (public, unlisted, private, direct, or local
+# on Akkoma and Pleroma)
 # Default: public
 post_visibility: "public"
 
+# OPTIONAL: Post some entries with another visibility. match_regex is
+# matched against the title, categories, description, and content, like
+# filters. The first matching rule wins. post --visibility ignores these.
+# visibility_rules:
+#   - match_category: "links"
+#     visibility: "unlisted"
+#   - match_regex: "(?i)weekly digest"
+#     visibility: "unlisted"
+
 # OPTIONAL: Content warning / spoiler text
 # Default: none
 # content_warning: "Automated post"
 
+# OPTIONAL: Content warnings for some entries, by category or by a regex
+# matched against the title. The first matching rule wins.
+# content_warnings:
+#   - match_category: "politics"
+#     text: "politics"
+#   - match_title: "(?i)spoiler"
+#     text: "spoilers"
+
+# OPTIONAL: Template for the content warning of entries no rule matches,
+# with the same data and functions as the post template. If it renders to
+# nothing, content_warning is used.
+# cw_template: "{{if .Item.Categories}}re: {{join \", \" .Item.Categories}}{{end}}"
+
 # OPTIONAL: Number of entries to post per run (0 = all)
 # Default: 0
 posts_per_run: 0
+
+# OPTIONAL: Which unposted entries to post first
+# oldest: in the order they were fetched, working through any backlog first;
+#   entries fetched together go in the order they were published
+# newest: the most recently fetched first, so today's news goes out before
+#   a backlog built up while posting was down
+# random: in a random order
+# published: in the order of the items' published dates
+# Default: oldest
+# post_order: "oldest"
+
+# OPTIONAL: How to publish entries that link to a fediverse status
+# link: post the rendered template as usual
+# quote: publish a quote post (ignored by servers without quote support)
+# reply: reply to the status, mentioning its author
+# boost: boost the status instead of posting, e.g. for the feed of another
+#   fediverse account (boosts happen right away, even with schedule_spread)
+# Default: link
+# status_links: "link"
+
+# OPTIONAL: Post every entry as a reply to this status, e.g. a pinned post
+# introducing the feed, so the feed's posts form one thread under it rather
+# than separate posts. The status ID is the number at the end of its URL.
+# Entries published as replies to a linked status (status_links: reply)
+# reply to that status instead.
+# Default: "" (post entries as separate posts)
+# thread_anchor: "109876543210987654"
+
+# OPTIONAL: Post each entry as a reply to the feed's previous post, so the
+# feed's posts form one ongoing thread, e.g. for a serialized comic. The
+# first post replies to thread_anchor if it's set. Posts scheduled with
+# schedule_spread reply to the last post published before the run.
+# Default: false
+# continue_thread: false
+
+# OPTIONAL: Upload image enclosures and media:content as attachments
+# Up to 4 images per post; larger images than media_max_bytes are skipped.
+# Images uploaded for an entry that then fails to post are reused when it's
+# retried.
+# Default: false, 8 MiB
+# media_attachments: false
+# media_max_bytes: 8388608
+
+# OPTIONAL: For entries with no image enclosures or media:content, attach
+# the first image embedded in their HTML content or description instead,
+# described by its alt text. Requires media_attachments.
+# Default: false
+# media_first_image: false
+
+# OPTIONAL: Attach an entry's audio or video enclosure or media:content,
+# like a podcast episode, in place of its images, so the post carries a
+# playable file. Files larger than media_max_audio_video_bytes, or the
+# server's video size limit with detect_instance_limits, are skipped for
+# the entry's images.
+# Posting waits up to 3 minutes for the server to process each file.
+# Requires media_attachments.
+# Default: false, 40 MiB
+# media_audio_video: false
+# media_max_audio_video_bytes: 41943040
+
+# OPTIONAL: Downscale and re-encode images larger than media_max_bytes or
+# media_max_dimension pixels on their longest side, instead of skipping
+# them. With detect_instance_limits, images are also kept within the
+# server's limit on pixels. Requires media_attachments.
+# Default: false, 0 (no dimension limit)
+# media_downscale: false
+# media_max_dimension: 2048
+
+# OPTIONAL: Remove EXIF, XMP, and text metadata, which can include where
+# and with what a photo was taken, from JPEG and PNG images before they're
+# uploaded. Photos that EXIF says are rotated are turned upright.
+# Default: false
+# media_strip_metadata: false
+
+# OPTIONAL: Focal point of uploaded images, as x,y from -1.0 to 1.0, which
+# the server keeps in view when cropping previews. 0.0,0.5 keeps the top
+# half of a picture in view, where faces tend to be.
+# Default: "" (centered)
+# media_focus: "0.0,0.5"
+
+# OPTIONAL: Mark attached media as sensitive, for every entry or for
+# entries in some categories.
+# Default: false, none
+# sensitive_media: false
+# sensitive_categories: ["nsfw", "spoilers"]
+
+# OPTIONAL: Alt text for images the feed doesn't describe with a
+# media:description or media:title, rendered from a template with the same
+# data and functions as the post template.
+# alt_text_template: "Image from {{.Item.Title}}"
+
+# OPTIONAL: Credentials for a private feed, e.g. a FreshRSS share or a
+# paywalled podcast feed, sent with HTTP basic authentication. For feeds
+# that take a token instead, set an Authorization header in fetch_headers.
+# feed_username: "reader"
+# feed_password: "your-feed-password"
+
+# OPTIONAL: How feeds are fetched. Requests time out after fetch_timeout.
+# http_proxy fetches through a proxy; by default, the HTTP_PROXY and
+# HTTPS_PROXY environment variables are used. fetch_headers are added to
+# each request, e.g. for feeds that require an API key.
+# Default: 30s timeout, "feed-to-mastodon" user agent, no proxy or headers
+# fetch_timeout: "30s"
+# http_proxy: "http://proxy.example:3128"
+# user_agent: "my-feed-bot/1.0 (+https://example.com/bot)"
+# fetch_headers:
+#   X-Api-Key: "your-api-key"
+
+# OPTIONAL: Retry fetches that fail with a network or server error, so
+# a blip doesn't skip a whole run. Retries wait about fetch_retry_backoff,
+# doubling each time, with some jitter.
+# Default: 2 retries, 5s backoff
+# fetch_retries: 2
+# fetch_retry_backoff: "5s"
+
+# OPTIONAL: For feeds that only include a summary, fetch each new entry's
+# link and extract the article's text and lead image (its og:image), for
+# templates as .Item.FullContent and .Item.LeadImage. Extraction is a
+# best guess, and pages that fail to load leave both empty.
+# Default: false
+# extract_content: true
+
+# OPTIONAL: Don't queue entries published longer ago than this, e.g. when
+# a feed includes years of archives. Entries without a date are kept.
+# Go durations only go up to hours, so use e.g. "168h" for 7 days.
+# Default: 0 (no limit)
+# max_entry_age: "168h"
+
+# OPTIONAL: What to do with the entries found by the first fetch of the
+# feed, e.g. one with a large archive
+# post_all: queue them all to be posted
+# catchup: mark them as posted without posting them, like 'catchup', so
+#   only entries published later are posted
+# post_latest_n: queue the initial_posts most recently published, and mark
+#   the rest as posted
+# Default: post_all, 1
+# initial_mode: "catchup"
+# initial_posts: 1
+
+# OPTIONAL: Which entries that are no longer in the feed fetch purges from
+# the database
+# stale: all of them
+# age:<duration>: those fetched longer ago than this, e.g. "age:720h"
+# keep:<n>: all but the n most recently fetched entries, e.g. "keep:500"
+# never: none, so the database keeps growing
+# Default: stale
+# purge: "stale"
+
+# OPTIONAL: Also purge posted entries. Purged posted entries leave a
+# tombstone, so an item that drops out of a rolling feed and comes back
+# isn't posted again; set to false to keep posted entries whole, e.g. for
+# 'show' and 'list', and purge only those that were never posted.
+# Default: true
+# purge_posted: false
+
+# OPTIONAL: Before posting, check this many of the account's latest
+# statuses for links to the entries being posted, and skip entries that
+# were already posted, e.g. by hand or by an installation that lost its
+# database. Skipped entries are marked as posted as the status that linked
+# them. Links rewritten by url_rewrites or link_shortener aren't
+# recognized.
+# Default: 0 (don't check)
+# dedupe_timeline: 80
+
+# OPTIONAL: Delete statuses posted longer ago than this, for bot accounts
+# that shouldn't accumulate history. The daemon deletes them after each
+# run; otherwise run 'feed-to-mastodon expire' from cron.
+# Default: 0 (keep statuses)
+# expire_after: "720h"
+
+# OPTIONAL: Minimum time between posts, so a backlog doesn't flood
+# timelines. 'post' waits this long between posts within a run, and posts
+# nothing until this long after the previous run's last post. The daemon
+# posts one entry per run while this is set.
+# Default: 0 (no spacing)
+# post_interval: "5m"
+
+# OPTIONAL: Post a backlog as scheduled statuses spaced this far apart,
+# instead of all at once. Mastodon publishes them at their scheduled times,
+# so nothing needs to keep running. Later runs continue after the last
+# scheduled post. Must be at least 5m. Same as post --schedule-spread.
+# Default: 0 (post immediately)
+# schedule_spread: "1h"
+
+# OPTIONAL: Only post during these hours and days, in 'timezone' or else
+# the system's local time. Outside the window 'post' posts nothing, or with
+# schedule_spread set, schedules posts for when the window opens. Windows
+# may run past midnight, e.g. "22:00-02:00". Days may be ranges.
+# Default: post at any time
+# post_window: "08:00-22:00"
+# post_days: ["Mon-Fri"]
+
+# OPTIONAL: Hold back entries instead of posting them
+# Regexes are matched against the title, categories, description, and
+# content. Category lists are case-insensitive. Filtered entries are kept
+# in the database and shown as filtered by 'show' and 'status'.
+# filters:
+#   include_regex: "(?i)golang|rust"
+#   exclude_regex: "(?i)sponsored"
+#   include_categories: ["programming"]
+#   exclude_categories: ["meta"]
+
+# OPTIONAL: Clean up links in posts. clean_urls removes tracking
+# parameters like utm_source and fbclid. Links on resolve_redirects hosts,
+# or their subdomains, are replaced by the page they redirect to, e.g. for
+# feeds that wrap every link in a click tracker. Cleanup happens before
+# url_rewrites, and the cleanURL template function does both for one link.
+# Default: false, none
+# clean_urls: true
+# resolve_redirects: ["feedproxy.google.com", "feeds.feedburner.com"]
+
+# OPTIONAL: Rewrite links in posts to alternative frontends
+# Subdomains of "from" also match. Stored entries are not modified.
+# url_rewrites:
+#   - from: "youtube.com"
+#     to: "https://yewtu.be"
+
+# OPTIONAL: Shorten the links in posts with a URL shortener's API, after
+# they're cleaned up and rewritten. The url is requested with GET, with
+# {url} replaced by the link. If the API responds with JSON, set
+# response_field to the path of the short link in it, like data.link.
+# Links that fail to shorten are posted as they are. Links are only
+# shortened when posting for real, not in dry runs, previews, or the
+# dashboard.
+# Default: off
+# link_shortener:
+#   url: "https://is.gd/create.php?format=simple&url={url}"
+#   response_field: ""
+#   headers:
+#     X-Api-Key: "your-key"
+
+# OPTIONAL: Add a link to a Wayback Machine snapshot of each entry's link
+# at the end of its post, after archive_label. With archive_save, pages
+# that have no snapshot yet are captured first, which can take a minute;
+# otherwise entries without one get no archive link. The archive link
+# counts toward the character limit. Like short links, it's only added
+# when posting for real.
+# Default: false, false, "Archived:"
+# archive_links: true
+# archive_save: true
+# archive_label: "🗄️"
+
+# OPTIONAL: Time between runs in daemon mode
+# Default: 15m
+# daemon_interval: "15m"
+
+# OPTIONAL: Address for the daemon's health endpoint (/healthz, /readyz, /status)
+# Default: disabled
+# health_listen: ":8080"
+
+# OPTIONAL: Token for the daemon's admin API, served alongside the health
+# endpoint. Requests must send "Authorization: Bearer <admin_token>".
+# Use at least 16 random characters, e.g. from: openssl rand -hex 32
+# Default: disabled
+# admin_token: ""
+
+# OPTIONAL: Serve a web dashboard at /dashboard/ with the admin API, showing
+# feed health, the queue with previews, and posted history, with buttons to
+# skip and requeue entries. Log in with admin_token as the password.
+# Default: false
+# dashboard: false
+
+# OPTIONAL: Outage handling. Each request is retried twice after a network
+# error or 5xx response, and waits up to 5 minutes for an exhausted rate
+# limit to reset. After this many consecutive failed requests, posting stops
+# and is deferred for outage_cooldown. A rate limit that resets later stops
+# posting until it resets.
+# outage_threshold: 3
+# outage_cooldown: "15m"
+
+# OPTIONAL: Retry entries that fail to post on later runs, waiting
+# retry_backoff after the first failure and doubling the wait after each
+# further failure (up to a day). Give up after max_post_attempts (0 = never).
+# See the 'failures' command.
+# max_post_attempts: 5
+# retry_backoff: "15m"
+
+# OPTIONAL: Tell an admin when the feed fails to fetch, or posting fails,
+# this many times in a row (default: 3). account gets a direct message from
+# the posting account; webhook gets a POST of {event, message, feed_url,
+# failures, error, time} as JSON. Each streak of failures is notified once.
+# notify:
+#   account: "@admin@example.social"
+#   webhook: "https://example.com/hooks/alerts"
+#   after: 3
+
+# OPTIONAL: Shell commands run around posting each entry. Each gets
+# {hook, entry_id, entry, content, status_url, error, dry_run} as JSON on
+# stdin, where entry is the feed item, and FEED_TO_MASTODON_HOOK, _ENTRY_ID,
+# _ENTRY_TITLE, _ENTRY_LINK, _CONTENT, _STATUS_URL, _ERROR, and _DRY_RUN in
+# the environment. pre_post runs before an entry is posted, also in dry
+# runs; exiting with status 1 holds the entry back as filtered, with the
+# first line it prints as the reason, and other failures count as a failed
+# attempt to post. post_success runs after an entry is posted, and
+# post_failure after it fails to post (not during outages).
+# Default: none, timeout 30s
+# hooks:
+#   pre_post: "./check-entry.sh"
+#   post_success: "jq -c . >> posted.jsonl"
+#   post_failure: "logger -t feed-to-mastodon \"$FEED_TO_MASTODON_ERROR\""
+#   timeout: "30s"
+
+# OPTIONAL: Publish posts somewhere other than Mastodon, using the same
+# fetch, filter, and template pipeline. mastodon_server isn't needed then,
+# and 'review' and post --update only work with Mastodon.
+# stdout: print posts
+# file: append posts to path
+# directory: write each post to its own file in path
+# webhook: POST {id, content, content_warning, item} as JSON to url
+# Default: mastodon
+# destination:
+#   type: "webhook"
+#   url: "https://example.com/hooks/posts"
+#   headers:
+#     Authorization: "Bearer your-token"
+
+# OPTIONAL: Cross-post each entry to other Mastodon accounts after it's
+# posted to the main account. Entries posted before an account was added
+# aren't cross-posted. Failed cross-posts are retried on later runs, up to
+# max_post_attempts. Limit an account to some entries with categories.
+# 'show' lists each entry's cross-posts.
+# accounts:
+#   - name: "work"
+#     server: "https://hachyderm.io"
+#     token: "another-access-token"
+#   - name: "art"
+#     server: "https://mastodon.art"
+#     token: "yet-another-access-token"
+#     categories: ["art", "photography"]
+
+# OPTIONAL: Post entries to Bluesky too, after they're posted to Mastodon.
+# Log in with 'feed-to-mastodon bluesky login', or set app_password to log
+# in on each run. Posts are limited to 300 characters, so a shorter
+# template may be needed. Links and hashtags are made clickable; content
+# warnings and media aren't posted.
+# bluesky:
+#   handle: "me.bsky.social"
+#   server: "https://bsky.social"
+#   app_password: "xxxx-xxxx-xxxx-xxxx"
+#   template_path: "bluesky-template.txt"
+
+# OPTIONAL: Announce entries in Discord or Slack channels through incoming
+# webhooks, after they're posted to Mastodon. Each may use its own template
+# and be limited to some categories. Content warnings are shown as Discord
+# spoilers, and mentions in entries don't ping anyone.
+# chat_webhooks:
+#   - name: "team"
+#     type: "slack"
+#     url: "https://hooks.slack.com/services/T000/B000/XXXX"
+#   - name: "community"
+#     type: "discord"
+#     url: "https://discord.com/api/webhooks/000/XXXX"
+#     template_path: "discord-template.txt"
 `
 
 	return os.WriteFile(path, []byte(defaultConfig), 0o644)
@@ -2,9 +2,10 @@ package commands
 
 import (
 	"fmt"
-	"net/url"
 
 	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -17,7 +18,10 @@ func NewLinkCmd() *cobra.Command {
 
 This command requires mastodon_server and mastodon_client_id to be configured.
 After visiting the link and authorizing, use the 'code' command with the
-authorization code to obtain an access token.`,
+authorization code to obtain an access token.
+
+The link requests the scopes configured in oauth_scopes (default:
+write:statuses write:media read:accounts).`,
 		RunE: runLink,
 	}
 
@@ -41,26 +45,17 @@ func runLink(cmd *cobra.Command, args []string) error {
 	}
 
 	// Construct the authorization URL
-	serverURL, err := url.Parse(cfg.MastodonServer)
+	authURL, err := mastodon.AuthorizeURL(cfg.MastodonServer, cfg.MastodonClientID, cfg.OAuthScopes)
 	if err != nil {
 		return fmt.Errorf("invalid mastodon_server URL: %w", err)
 	}
 
-	serverURL.Path = "/oauth/authorize"
-
-	// Set query parameters
-	params := url.Values{}
-	params.Set("client_id", cfg.MastodonClientID)
-	params.Set("scope", "read write")
-	params.Set("redirect_uri", "urn:ietf:wg:oauth:2.0:oob")
-	params.Set("response_type", "code")
-
-	serverURL.RawQuery = params.Encode()
-
 	// Display the authorization link
 	fmt.Println("Authorization Link:")
 	fmt.Println()
-	fmt.Println(serverURL.String())
+	fmt.Println(authURL)
+	fmt.Println()
+	fmt.Printf("Requested scopes: %s\n", cfg.OAuthScopes)
 	fmt.Println()
 	fmt.Println("Visit this URL in your browser to authorize the application.")
 	fmt.Println("After authorizing, you will receive an authorization code.")
@@ -69,5 +64,18 @@ func runLink(cmd *cobra.Command, args []string) error {
 	fmt.Println("  feed-to-mastodon code <authorization-code>")
 	fmt.Println()
 
+	// Say what happens to a token stored before
+	db, err := openDatabase(cfg)
+	if err != nil {
+		logrus.Debugf("Not checking for a stored access token: %v", err)
+		return nil
+	}
+	defer db.Close()
+	if _, source, err := storedAccessToken(cfg, db); err == nil {
+		fmt.Printf("An access token is already stored in the %s. 'code' replaces it\n", source)
+		fmt.Println("and revokes the old one; 'logout' revokes it without a replacement.")
+		fmt.Println()
+	}
+
 	return nil
 }
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/mmcdole/gofeed"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listUnposted bool
+	listPosted   bool
+	listFailed   bool
+	listFiltered bool
+	listSkipped  bool
+	listSince    string
+	listLimit    int
+	listOffset   int
+	listFormat   string
+)
+
+// NewListCmd creates the list command.
+func NewListCmd() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List entries with their IDs and states",
+		Long: `List shows entries in the database, newest first, with their IDs,
+titles, states, and when they were fetched and posted.
+
+Entries are in one of these states:
+  unposted  waiting to be posted
+  posted    posted (or scheduled, or marked by catchup)
+  failed    failed to post, waiting to retry or given up on
+  filtered  held back by filters or rejected in review
+  skipped   skipped with the skip command or in the dashboard
+
+Use --unposted, --posted, --failed, --filtered, or --skipped to show only
+entries in that state, --since to show entries fetched within a duration (24h) or
+since a date (2024-03-09), and --limit and --offset to page through them.
+Use --format json for output other tools can read.`,
+		RunE: runList,
+	}
+
+	listCmd.Flags().BoolVar(&listUnposted, "unposted", false, "show only unposted entries")
+	listCmd.Flags().BoolVar(&listPosted, "posted", false, "show only posted entries")
+	listCmd.Flags().BoolVar(&listFailed, "failed", false, "show only entries that failed to post")
+	listCmd.Flags().BoolVar(&listFiltered, "filtered", false, "show only filtered entries")
+	listCmd.Flags().BoolVar(&listSkipped, "skipped", false, "show only skipped entries")
+	listCmd.Flags().StringVar(&listSince, "since", "", "show entries fetched within a duration (24h) or since a date (2024-03-09)")
+	listCmd.Flags().IntVar(&listLimit, "limit", 50, "maximum number of entries to show (0 = all)")
+	listCmd.Flags().IntVar(&listOffset, "offset", 0, "number of entries to skip, for paging")
+	listCmd.Flags().StringVar(&listFormat, "format", "text", "output format: text or json")
+
+	return listCmd
+}
+
+// listedEntry is an entry as printed by list --format json.
+type listedEntry struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Link      string     `json:"link,omitempty"`
+	State     string     `json:"state"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	PostedAt  *time.Time `json:"posted_at,omitempty"`
+	StatusURL string     `json:"status_url,omitempty"`
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	opts := database.ListOptions{Limit: listLimit, Offset: listOffset}
+
+	states := 0
+	for state, set := range map[string]bool{
+		database.StateUnposted: listUnposted,
+		database.StatePosted:   listPosted,
+		database.StateFailed:   listFailed,
+		database.StateFiltered: listFiltered,
+		database.StateSkipped:  listSkipped,
+	} {
+		if set {
+			opts.State = state
+			states++
+		}
+	}
+	if states > 1 {
+		return fmt.Errorf("use only one of --unposted, --posted, --failed, --filtered, and --skipped")
+	}
+	if listFormat != "text" && listFormat != "json" {
+		return fmt.Errorf("--format must be text or json")
+	}
+	if listSince != "" {
+		since, err := parseSince(listSince)
+		if err != nil {
+			return err
+		}
+		opts.Since = since
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	entries, err := db.ListEntries(opts)
+	if err != nil {
+		return fmt.Errorf("failed to list entries: %w", err)
+	}
+
+	listed := make([]listedEntry, 0, len(entries))
+	for _, entry := range entries {
+		listed = append(listed, newListedEntry(entry))
+	}
+
+	if listFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listed)
+	}
+
+	if len(listed) == 0 {
+		fmt.Println("No entries found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATE\tFETCHED\tPOSTED\tTITLE")
+	for _, entry := range listed {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.ID, entry.State, formatListTime(entry.FetchedAt), formatListTime(entry.PostedAt), truncateTitle(entry.Title, 60))
+	}
+	return w.Flush()
+}
+
+// newListedEntry summarizes an entry for listing.
+func newListedEntry(entry *database.Entry) listedEntry {
+	listed := listedEntry{ID: entry.ID, State: entry.State(), StatusURL: entry.StatusURL.String}
+
+	var item gofeed.Item
+	if err := json.Unmarshal(entry.EntryData, &item); err == nil {
+		listed.Title = item.Title
+		listed.Link = item.Link
+	}
+	if entry.FetchedAt.Valid {
+		listed.FetchedAt = &entry.FetchedAt.Time
+	}
+	if entry.PostedAt != nil && entry.PostedAt.Valid {
+		listed.PostedAt = &entry.PostedAt.Time
+	}
+	return listed
+}
+
+// formatListTime formats an optional time for the list table.
+func formatListTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// truncateTitle shortens a title to at most max characters for display.
+func truncateTitle(title string, max int) string {
+	runes := []rune(title)
+	if len(runes) <= max {
+		return title
+	}
+	return string(runes[:max-3]) + "..."
+}
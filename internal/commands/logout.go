@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/spf13/cobra"
+)
+
+var logoutNoRevoke bool
+
+// NewLogoutCmd creates the logout command.
+func NewLogoutCmd() *cobra.Command {
+	logoutCmd := &cobra.Command{
+		Use:     "logout",
+		Aliases: []string{"revoke"},
+		Short:   "Revoke and forget the stored access token",
+		Long: `Logout revokes the access token stored by 'code' with the server's OAuth
+revoke endpoint, so it can't be used anymore, and removes it from the
+database (or the OS keyring, with token_storage set to keyring) along with
+its granted scopes.
+
+Revoking requires mastodon_client_id and mastodon_client_secret. Use
+--no-revoke to only forget the token, for example when the server is
+gone; then revoke it under Settings > Account > Authorized apps.
+
+Tokens set in config with mastodon_token or mastodon_token_command aren't
+stored, so they're left alone. Run 'link' and 'code' to log in again.`,
+		Args: cobra.NoArgs,
+		RunE: runLogout,
+	}
+
+	logoutCmd.Flags().BoolVar(&logoutNoRevoke, "no-revoke", false, "forget the token without revoking it")
+
+	return logoutCmd
+}
+
+func runLogout(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if tokenConfigured(cfg) {
+		source := tokenSourceConfig
+		if cfg.MastodonAccessToken == "" {
+			source = tokenSourceCommand
+		}
+		return fmt.Errorf("the access token comes from %s, so there's no stored token to remove - remove it from config, and revoke it under Settings > Account > Authorized apps", source)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	token, source, err := storedAccessToken(cfg, db)
+	if errors.Is(err, errNoAccessToken) {
+		fmt.Println("No access token is stored")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !logoutNoRevoke {
+		if cfg.MastodonClientID == "" || cfg.MastodonClientSecret == "" {
+			return fmt.Errorf("revoking the access token requires mastodon_client_id and mastodon_client_secret - use --no-revoke to only forget it")
+		}
+		err := mastodon.RevokeToken(cmd.Context(), cfg.MastodonServer, cfg.MastodonClientID, cfg.MastodonClientSecret, token)
+		if err != nil {
+			return fmt.Errorf("%w - use --no-revoke to forget the token anyway", err)
+		}
+		fmt.Printf("Revoked access token for %s\n", cfg.MastodonServer)
+	}
+
+	if err := forgetAccessToken(cfg, db); err != nil {
+		return fmt.Errorf("failed to remove access token: %w", err)
+	}
+	fmt.Printf("Removed access token from the %s\n", source)
+	if logoutNoRevoke {
+		fmt.Println("The token wasn't revoked - revoke it under Settings > Account > Authorized apps")
+	}
+
+	return nil
+}
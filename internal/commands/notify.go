@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// postFailureSetting counts the post runs in a row in which nothing could
+// be posted because of errors.
+const postFailureSetting = "post_failure_streak"
+
+// notifyFetchFailure tells the admin when the feed has failed to fetch
+// notify.after times in a row. Longer streaks aren't notified again.
+func notifyFetchFailure(ctx context.Context, cfg *config.Config, db *database.DB, fetchErr error) {
+	if !cfg.Notify.Enabled() {
+		return
+	}
+
+	health, err := db.GetFeedHealth(cfg.FeedURL)
+	if err != nil {
+		logrus.Warnf("Failed to check feed health: %v", err)
+		return
+	}
+	if health.ConsecutiveFailures != cfg.Notify.After {
+		return
+	}
+
+	sendNotification(ctx, cfg, db, notify.Notification{
+		Event:    notify.EventFetchFailed,
+		Message:  fmt.Sprintf("feed-to-mastodon failed to fetch %s %d times in a row: %v", cfg.FeedURL, health.ConsecutiveFailures, fetchErr),
+		FeedURL:  cfg.FeedURL,
+		Failures: health.ConsecutiveFailures,
+		Error:    fetchErr.Error(),
+	})
+}
+
+// recordPostRun keeps count of the post runs in a row that failed, and
+// tells the admin when the count reaches notify.after. A run that posts
+// anything ends the streak; runs with nothing to post don't change it.
+func recordPostRun(ctx context.Context, cfg *config.Config, db *database.DB, result *postResult, postErr error) {
+	if result != nil && result.Posted > 0 {
+		if err := db.Settings().Delete(postFailureSetting); err != nil {
+			logrus.Warnf("Failed to clear post failure count: %v", err)
+		}
+		return
+	}
+	if postErr == nil && (result == nil || result.Failed == 0) {
+		return
+	}
+
+	var streak int
+	if _, err := db.Settings().GetJSON(postFailureSetting, &streak); err != nil {
+		logrus.Warnf("Failed to load post failure count: %v", err)
+	}
+	streak++
+	if err := db.Settings().SetJSON(postFailureSetting, streak); err != nil {
+		logrus.Warnf("Failed to record post failure count: %v", err)
+	}
+
+	if !cfg.Notify.Enabled() || streak != cfg.Notify.After {
+		return
+	}
+
+	var errText string
+	if postErr != nil {
+		errText = postErr.Error()
+	} else {
+		errText = fmt.Sprintf("%d entries failed to post", result.Failed)
+	}
+	sendNotification(ctx, cfg, db, notify.Notification{
+		Event:    notify.EventPostFailed,
+		Message:  fmt.Sprintf("feed-to-mastodon failed to post entries from %s %d runs in a row: %s", cfg.FeedURL, streak, errText),
+		FeedURL:  cfg.FeedURL,
+		Failures: streak,
+		Error:    errText,
+	})
+}
+
+// sendNotification sends a failure notification, logging rather than
+// failing when it can't be sent.
+func sendNotification(ctx context.Context, cfg *config.Config, db *database.DB, notification notify.Notification) {
+	notifier := notify.New(cfg.Notify.Webhook)
+	if cfg.Notify.Account != "" {
+		accessToken, err := findAccessToken(ctx, cfg, db)
+		if err != nil {
+			logrus.Warnf("Can't send direct message to %s: %v", cfg.Notify.Account, err)
+		} else {
+			notifier.SetDirectMessage(cfg.MastodonServer, accessToken, cfg.Notify.Account)
+		}
+	}
+
+	logrus.Infof("Sending failure notification: %s", notification.Message)
+	if err := notifier.Send(ctx, notification); err != nil {
+		logrus.Warnf("Failed to send failure notification: %v", err)
+	}
+}
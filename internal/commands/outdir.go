@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+)
+
+// postOutDir is the --out-dir flag of post.
+var postOutDir string
+
+// previewIndexFile lists the previews written with --out-dir.
+const previewIndexFile = "index.json"
+
+// previewEntry describes one entry of a dry run in the preview index.
+type previewEntry struct {
+	// File is the preview's file name in the directory, empty if the
+	// entry failed to render.
+	File           string `json:"file,omitempty"`
+	ID             string `json:"id"`
+	Title          string `json:"title,omitempty"`
+	Link           string `json:"link,omitempty"`
+	ContentWarning string `json:"content_warning,omitempty"`
+	Characters     int    `json:"characters"`
+	OverLimit      bool   `json:"over_limit,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// writePreviews writes the post each entry of a dry run would be sent
+// with to a numbered text file in dir, in posting order, along with an
+// index.json describing them. Previews left from an earlier run are
+// removed, so the directory can be diffed against a copy of it.
+func writePreviews(dir string, cfg *config.Config, db *database.DB, results []destination.PostResult) (int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create preview directory: %w", err)
+	}
+	if err := removePreviews(dir); err != nil {
+		return 0, err
+	}
+
+	renderer, err := newRenderer(cfg, db)
+	if err != nil {
+		return 0, err
+	}
+
+	index := []previewEntry{}
+	written := 0
+	for _, result := range results {
+		if result.Outcome == destination.OutcomeSkipped {
+			continue
+		}
+		entry := result.Entry
+		listed := newListedEntry(entry)
+		preview := previewEntry{ID: entry.ID, Title: listed.Title, Link: listed.Link}
+
+		content, err := destination.RenderEntry(renderer, entry)
+		if err != nil {
+			preview.Error = err.Error()
+			index = append(index, preview)
+			continue
+		}
+		preview.ContentWarning = entry.ContentWarning
+		preview.Characters = template.PostLength(content, preview.ContentWarning)
+		preview.OverLimit = preview.Characters > renderer.CharacterLimit()
+
+		written++
+		preview.File = fmt.Sprintf("%03d.txt", written)
+		text := strings.TrimRight(content, "\n") + "\n"
+		if preview.ContentWarning != "" {
+			text = "CW: " + preview.ContentWarning + "\n\n" + text
+		}
+		if err := os.WriteFile(filepath.Join(dir, preview.File), []byte(text), 0o644); err != nil {
+			return written, fmt.Errorf("failed to write preview: %w", err)
+		}
+		index = append(index, preview)
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return written, fmt.Errorf("failed to encode preview index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, previewIndexFile), append(data, '\n'), 0o644); err != nil {
+		return written, fmt.Errorf("failed to write preview index: %w", err)
+	}
+	return written, nil
+}
+
+// removePreviews removes the numbered previews and index written to dir
+// by an earlier run, leaving any other files alone.
+func removePreviews(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read preview directory: %w", err)
+	}
+	for _, file := range files {
+		name := file.Name()
+		number, isText := strings.CutSuffix(name, ".txt")
+		if name != previewIndexFile && (!isText || number == "" || strings.Trim(number, "0123456789") != "") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to remove old preview: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,21 +1,34 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/lorchard/feed-to-mastodon/internal/config"
 	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/feed"
+	"github.com/lorchard/feed-to-mastodon/internal/filter"
+	"github.com/lorchard/feed-to-mastodon/internal/hooks"
+	"github.com/lorchard/feed-to-mastodon/internal/imaging"
 	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
 	"github.com/lorchard/feed-to-mastodon/internal/template"
-	"github.com/mmcdole/gofeed"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
-	dryRun   bool
-	maxPosts int
+	dryRun         bool
+	maxPosts       int
+	postUpdates    bool
+	scheduleSpread time.Duration
+	visibility     string
+	postEntryID    string
+	postOrder      string
 )
 
 // NewPostCmd creates the post command.
@@ -27,122 +40,1150 @@ func NewPostCmd() *cobra.Command {
 to Mastodon using the configured template. Entries are marked as posted
 after successful posting.
 
-Use --dry-run to preview what would be posted without actually posting.`,
+Use --dry-run to preview what would be posted without actually posting.
+Add --out-dir to write each post to a numbered text file in a directory,
+with an index.json describing them, to diff template changes across the
+whole queue.
+
+Use --update to also edit the statuses of posted entries whose content
+changed in the feed since they were posted.
+
+Use --entry to post one specific entry, whether or not it was posted
+before, e.g. to try a template change against a known item.
+
+Use --order to choose which unposted entries go first: oldest (the
+default), newest, random, or published.
+
+Use --schedule-spread to post the backlog as scheduled statuses spaced
+out by the given interval, instead of all at once. Mastodon publishes
+them at their scheduled times, so nothing needs to keep running.
+
+If another fetch or post is already using the database, post exits
+without doing anything, unless --wait is given.`,
 		RunE: runPost,
 	}
 
 	postCmd.Flags().BoolVar(&dryRun, "dry-run", false, "preview posts without actually posting to Mastodon")
 	postCmd.Flags().IntVar(&maxPosts, "posts", 0, "maximum number of entries to post (0 = all, overrides config posts_per_run)")
+	postCmd.Flags().BoolVar(&postUpdates, "update", false, "edit statuses of posted entries that changed in the feed (overrides config update_edited)")
+	postCmd.Flags().StringVar(&postEntryID, "entry", "", "post only this entry, even if it was posted before")
+	postCmd.Flags().StringVar(&visibility, "visibility", "", "post with this visibility, ignoring visibility_rules (overrides config post_visibility)")
+	postCmd.Flags().StringVar(&postOrder, "order", "", "post entries in this order: oldest, newest, random, or published (overrides config post_order)")
+	postCmd.Flags().DurationVar(&scheduleSpread, "schedule-spread", 0, "schedule posts this far apart instead of posting at once (overrides config schedule_spread)")
+	postCmd.Flags().BoolVar(&waitForLock, "wait", false, "wait for another run using the database to finish instead of skipping this one")
+	postCmd.Flags().StringVar(&summaryOut, "summary-out", "", "write a JSON summary of the run to this file")
+	postCmd.Flags().StringVar(&postOutDir, "out-dir", "", "with --dry-run, write each post to a numbered file in this directory")
 
 	return postCmd
 }
 
-func runPost(cmd *cobra.Command, args []string) error {
+func runPost(cmd *cobra.Command, args []string) (err error) {
+	summary := newRunSummary("post")
+	defer func() { summary.write(summaryOut, err) }()
+
+	if postOutDir != "" && !dryRun {
+		return fmt.Errorf("--out-dir requires --dry-run")
+	}
+	if postOutDir != "" && postEntryID != "" {
+		return fmt.Errorf("--out-dir can't be used with --entry")
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig(GetConfigFile())
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// Keep overlapping runs, e.g. from cron, from racing on the database
+	runLock, err := lockRun(cmd.Context(), cfg, waitForLock)
+	if err != nil {
+		if msg := lockedMessage(cfg, err); msg != "" {
+			fmt.Printf("%s (use --wait to wait for it)\n", msg)
+			summary.Skipped = msg
+			return nil
+		}
+		return fmt.Errorf("failed to lock database: %w", err)
+	}
+	defer runLock.Release()
+
 	// Open database
-	db, err := database.New(cfg.DatabasePath)
+	db, err := openDatabase(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
-	// Get access token from config or database
-	accessToken, err := getAccessToken(cfg, db)
+	// Determine the limit: use flag if set, otherwise use config
+	limit := cfg.MaxItems
+	if cmd.Flags().Changed("posts") {
+		limit = maxPosts
+	}
+	if cmd.Flags().Changed("update") {
+		cfg.UpdateEdited = postUpdates
+	}
+	if cmd.Flags().Changed("visibility") {
+		cfg.PostVisibility = visibility
+		cfg.VisibilityRules = nil
+	}
+	if cmd.Flags().Changed("schedule-spread") {
+		cfg.ScheduleSpread = scheduleSpread
+	}
+	if cmd.Flags().Changed("order") {
+		cfg.PostOrder = postOrder
+	}
+
+	summary.DryRun = dryRun
+	if dryRun {
+		fmt.Println("DRY RUN: Previewing posts without actually posting")
+		fmt.Println()
+	}
+
+	if postEntryID != "" {
+		return postEntryByID(cmd.Context(), cfg, db, postEntryID, dryRun)
+	}
+
+	result, err := postUnposted(cmd.Context(), cfg, db, limit, dryRun)
+	summary.setPostResult(result)
+	if err != nil {
+		return err
+	}
+
+	if postOutDir != "" {
+		written, err := writePreviews(postOutDir, cfg, db, result.Results)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("DRY RUN: Wrote %d posts to %s\n", written, postOutDir)
+	}
+
+	if result.NextPostAt != nil {
+		summary.Skipped = fmt.Sprintf("post_interval is %s, next post allowed at %s", cfg.PostInterval, result.NextPostAt.Format(time.RFC3339))
+		fmt.Printf("Nothing posted: %s\n", summary.Skipped)
+		return nil
+	}
+	if result.WindowOpensAt != nil {
+		summary.Skipped = fmt.Sprintf("outside the posting window, next post allowed at %s", result.WindowOpensAt.Format(time.RFC3339))
+		fmt.Printf("Nothing posted: %s\n", summary.Skipped)
+		return nil
+	}
+
+	if result.Filtered > 0 {
+		if dryRun {
+			fmt.Printf("DRY RUN: Would filter %d entries\n", result.Filtered)
+		} else {
+			fmt.Printf("Filtered %d entries (see 'show <entry-id>' for the reason)\n", result.Filtered)
+		}
+	}
+
+	if result.Duplicate > 0 {
+		if dryRun {
+			fmt.Printf("DRY RUN: Would skip %d entries already posted to the account\n", result.Duplicate)
+		} else {
+			fmt.Printf("Skipped %d entries already posted to the account\n", result.Duplicate)
+		}
+	}
+
+	if result.Updated > 0 {
+		if dryRun {
+			fmt.Printf("DRY RUN: Would edit %d posts for changed entries\n", result.Updated)
+		} else {
+			fmt.Printf("Edited %d posts for changed entries\n", result.Updated)
+		}
+	}
+
+	if result.Attempted == 0 {
+		if result.Updated == 0 && result.CrossPosted == 0 {
+			fmt.Println("No unposted entries to post")
+			fmt.Println("\nRun 'feed-to-mastodon fetch' to fetch new entries")
+		}
+		printCrossPosted(result.CrossPosted, dryRun)
+		return nil
+	}
+
+	// Display summary
+	fmt.Printf("\n")
+	if dryRun {
+		fmt.Printf("DRY RUN: Would have posted %d entries\n", result.Posted)
+		fmt.Println("Remove --dry-run to actually post them")
+	} else {
+		fmt.Printf("Successfully posted %d entries to %s\n", result.Posted, describeDestination(cfg))
+		if result.Scheduled > 0 {
+			fmt.Printf("%d of them were scheduled, the last for %s\n", result.Scheduled, result.LastScheduledAt.Format(time.RFC3339))
+		}
+		if retrying := result.Failed - result.GaveUp; retrying > 0 {
+			fmt.Printf("Failed to post %d entries, they will be retried later (see 'failures')\n", retrying)
+		}
+		if result.GaveUp > 0 {
+			fmt.Printf("Gave up on %d entries after %d attempts (see 'failures')\n", result.GaveUp, cfg.MaxPostAttempts)
+		}
+		if result.Skipped > 0 {
+			fmt.Printf("Skipped %d entries, they will be retried on the next run\n", result.Skipped)
+		}
+	}
+	printCrossPosted(result.CrossPosted, dryRun)
+
+	return nil
+}
+
+// printCrossPosted reports the number of entries cross-posted to other
+// accounts, if any.
+func printCrossPosted(crossPosted int, dryRun bool) {
+	if crossPosted == 0 {
+		return
+	}
+	if dryRun {
+		fmt.Printf("DRY RUN: Would cross-post %d entries to other accounts\n", crossPosted)
+	} else {
+		fmt.Printf("Cross-posted %d entries to other accounts\n", crossPosted)
+	}
+}
+
+// applyFilter holds back entries that don't pass the filter, marking them
+// as filtered unless in dry run mode. Returns the remaining entries and the
+// number filtered.
+func applyFilter(entryFilter *filter.Filter, db *database.DB, entries []*database.Entry, dryRun bool) ([]*database.Entry, int) {
+	kept := make([]*database.Entry, 0, len(entries))
+	filtered := 0
+
+	for _, entry := range entries {
+		ok, reason, err := entryFilter.CheckEntry(entry.EntryData)
+		if err != nil {
+			// Let posting report the broken entry
+			kept = append(kept, entry)
+			continue
+		}
+		if ok {
+			kept = append(kept, entry)
+			continue
+		}
+
+		filtered++
+		if dryRun {
+			logrus.Infof("DRY RUN: Would filter entry %s: %s", entry.ID, reason)
+			continue
+		}
+		logrus.Infof("Filtered entry %s: %s", entry.ID, reason)
+		if err := db.MarkAsFiltered(entry.ID, reason); err != nil {
+			logrus.Errorf("Failed to mark entry %s as filtered: %v", entry.ID, err)
+		}
+	}
+
+	return kept, filtered
+}
+
+// newHooks returns a runner for the configured hooks.
+func newHooks(cfg *config.Config) *hooks.Runner {
+	return hooks.New(cfg.Hooks.PrePost, cfg.Hooks.PostSuccess, cfg.Hooks.PostFailure, cfg.Hooks.Timeout)
+}
+
+// hookEvent describes entry and its post for a hook.
+func hookEvent(entry *database.Entry, content string) hooks.Event {
+	return hooks.Event{
+		EntryID:   entry.ID,
+		Entry:     json.RawMessage(entry.EntryData),
+		Content:   content,
+		StatusURL: entry.StatusURL.String,
+	}
+}
+
+// applyPrePostHook runs the pre_post hook for each entry with its rendered
+// post, holding back the entries it rejects as filtered. Returns the
+// entries to post, the number held back, and failed results for the
+// entries the hook failed on, which aren't posted.
+func applyPrePostHook(ctx context.Context, runner *hooks.Runner, renderer *template.Renderer, db *database.DB, entries []*database.Entry, dryRun bool) ([]*database.Entry, int, []destination.PostResult) {
+	kept := make([]*database.Entry, 0, len(entries))
+	filtered := 0
+	var failed []destination.PostResult
+
+	for _, entry := range entries {
+		content, err := destination.RenderEntry(renderer, entry)
+		if err != nil {
+			// Let posting report the broken entry
+			kept = append(kept, entry)
+			continue
+		}
+		event := hookEvent(entry, content)
+		event.DryRun = dryRun
+		ok, reason, err := runner.CheckEntry(ctx, event)
+		if err != nil {
+			logrus.Errorf("Not posting entry %s: %v", entry.ID, err)
+			failed = append(failed, destination.PostResult{Entry: entry, Outcome: destination.OutcomeFailed, Err: err})
+			continue
+		}
+		if ok {
+			kept = append(kept, entry)
+			continue
+		}
+
+		filtered++
+		if dryRun {
+			logrus.Infof("DRY RUN: Would filter entry %s: %s", entry.ID, reason)
+			continue
+		}
+		logrus.Infof("Filtered entry %s: %s", entry.ID, reason)
+		if err := db.MarkAsFiltered(entry.ID, reason); err != nil {
+			logrus.Errorf("Failed to mark entry %s as filtered: %v", entry.ID, err)
+		}
+	}
+
+	return kept, filtered, failed
+}
+
+// dropTimelineDuplicates holds back entries whose links the account posted
+// recently, e.g. by hand or from an installation that lost its database,
+// marking them as posted as the status that linked them. Returns the entries
+// to post and the number held back. If the timeline can't be read, every
+// entry is posted.
+func dropTimelineDuplicates(ctx context.Context, poster *mastodon.Poster, db *database.DB, entries []*database.Entry, limit int, dryRun bool) ([]*database.Entry, int) {
+	links, err := poster.RecentLinksContext(ctx, limit)
 	if err != nil {
-		return fmt.Errorf("authentication required: %w", err)
+		logrus.Warnf("Failed to check the account's recent statuses for duplicates: %v", err)
+		return entries, 0
+	}
+
+	kept := make([]*database.Entry, 0, len(entries))
+	duplicates := 0
+	for _, entry := range entries {
+		status, ok := links.Find(entryLink(entry))
+		if !ok {
+			kept = append(kept, entry)
+			continue
+		}
+
+		duplicates++
+		if dryRun {
+			logrus.Infof("DRY RUN: Would skip entry %s, already posted as %s", entry.ID, status.URL)
+			continue
+		}
+		logrus.Infof("Skipping entry %s, already posted as %s", entry.ID, status.URL)
+		if err := db.MarkAsPosted(entry.ID, status.ID, status.URL); err != nil {
+			logrus.Errorf("Failed to mark entry %s as posted: %v", entry.ID, err)
+		}
+	}
+
+	return kept, duplicates
+}
+
+// outageSetting holds the time until which posting is deferred because the
+// Mastodon server appeared to be down.
+const outageSetting = "mastodon_outage_until"
+
+// errInstanceOutage indicates that posting was deferred because the
+// Mastodon server appeared to be down.
+var errInstanceOutage = errors.New("mastodon server appears to be down")
+
+// rateLimitSetting holds the time until which posting is deferred because
+// the Mastodon server's rate limit was used up.
+const rateLimitSetting = "mastodon_rate_limited_until"
+
+// errRateLimited indicates that posting was deferred until the Mastodon
+// server's rate limit resets.
+var errRateLimited = errors.New("mastodon rate limit is used up")
+
+// postResult summarizes a single posting run.
+type postResult struct {
+	Attempted int
+	Posted    int
+	Failed    int
+	Skipped   int
+	Filtered  int
+	Duplicate int
+	Updated   int
+	GaveUp    int
+	Scheduled int
+
+	// CrossPosted is the number of posts to accounts other than the main
+	// one.
+	CrossPosted int
+
+	// LastScheduledAt is the latest time an entry was scheduled for.
+	LastScheduledAt time.Time
+
+	// NextPostAt is set when nothing was posted because post_interval
+	// hasn't passed since the last post.
+	NextPostAt *time.Time
+
+	// WindowOpensAt is set when nothing was posted because it's outside
+	// post_window or post_days.
+	WindowOpensAt *time.Time
+
+	// Results are the outcomes of the entries that were posted or tried.
+	Results []destination.PostResult
+}
+
+// postUnposted posts up to limit unposted entries (0 = all) and marks the
+// posted entries in the database. When ctx is done, posting stops, and
+// the entries posted until then are still marked. Runs that keep failing
+// are notified as configured.
+func postUnposted(ctx context.Context, cfg *config.Config, db *database.DB, limit int, dryRun bool) (*postResult, error) {
+	result, err := postUnpostedEntries(ctx, cfg, db, limit, dryRun)
+	if !dryRun && ctx.Err() == nil {
+		recordPostRun(ctx, cfg, db, result, err)
+	}
+	return result, err
+}
+
+// postUnpostedEntries does the work of postUnposted.
+func postUnpostedEntries(ctx context.Context, cfg *config.Config, db *database.DB, limit int, dryRun bool) (*postResult, error) {
+	// Get access token from config or database
+	var accessToken string
+	if cfg.IsMastodon() {
+		var err error
+		accessToken, err = getAccessToken(ctx, cfg, db)
+		if err != nil {
+			return nil, fmt.Errorf("authentication required: %w", err)
+		}
 	}
 
 	// Validate configuration (but don't require access token since we got it from DB)
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Cross-post to newly added accounts starting with this run's posts
+	for _, name := range crossPostAccounts(cfg) {
+		if _, err := accountSince(db, name); err != nil {
+			logrus.Warnf("Failed to record start of cross-posting to %s: %v", name, err)
+		}
+	}
+
+	// Don't hammer a server that was down recently
+	if !dryRun && cfg.IsMastodon() {
+		if until, err := getOutageUntil(db); err != nil {
+			logrus.Warnf("Failed to check instance outage state: %v", err)
+		} else if until != nil && time.Now().Before(*until) {
+			return nil, fmt.Errorf("%w - deferring posts until %s", errInstanceOutage, until.Format(time.RFC3339))
+		}
+		if until, err := db.Settings().GetTime(rateLimitSetting); err != nil {
+			logrus.Warnf("Failed to check rate limit state: %v", err)
+		} else if until != nil && time.Now().Before(*until) {
+			return nil, fmt.Errorf("%w - deferring posts until %s", errRateLimited, until.Format(time.RFC3339))
+		}
+	}
+
+	// Respect the minimum interval since the last post. Scheduled posts
+	// are spaced out by schedule_spread instead.
+	if cfg.PostInterval > 0 && cfg.ScheduleSpread <= 0 && !dryRun {
+		if lastPostedAt, err := db.GetLastPostedAt(); err != nil {
+			logrus.Warnf("Failed to check last post time: %v", err)
+		} else if lastPostedAt != nil {
+			next := lastPostedAt.Add(cfg.PostInterval)
+			if time.Now().Before(next) {
+				logrus.Infof("Last post was less than %s ago, waiting until %s", cfg.PostInterval, next.Format(time.RFC3339))
+				return &postResult{NextPostAt: &next}, nil
+			}
+		}
+	}
+
+	// Outside the posting window, post nothing, unless posts can be
+	// scheduled for when it opens
+	window, err := cfg.Window()
+	if err != nil {
+		return nil, err
+	}
+	if now := time.Now(); cfg.ScheduleSpread <= 0 && !dryRun && !window.Contains(now) {
+		next := window.Next(now)
+		logrus.Infof("Outside the posting window, waiting until %s", next.Format(time.RFC3339))
+		return &postResult{WindowOpensAt: &next}, nil
+	}
+
+	entryFilter, err := filter.New(cfg.Filters.IncludeRegex, cfg.Filters.ExcludeRegex, cfg.Filters.IncludeCategories, cfg.Filters.ExcludeCategories)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filters: %w", err)
+	}
+
+	// Get unposted entries. When filtering, the limit applies after
+	// filtered entries are held back.
+	fetchLimit := limit
+	if !entryFilter.Empty() {
+		fetchLimit = 0
+	}
+	entries, err := db.GetUnpostedEntriesOrdered(ctx, fetchLimit, queueOrder(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unposted entries: %w", err)
+	}
+
+	result := &postResult{}
+	if !entryFilter.Empty() {
+		entries, result.Filtered = applyFilter(entryFilter, db, entries, dryRun)
+		if limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
+		}
+	}
+
+	// Get posted entries that changed, to edit their statuses
+	var changed []*database.Entry
+	if cfg.UpdateEdited && cfg.IsMastodon() {
+		changed, err = db.GetChangedEntriesContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get changed entries: %w", err)
+		}
+	}
+
+	result.Attempted = len(entries)
+	if len(entries) == 0 && len(changed) == 0 {
+		result.CrossPosted = crossPost(ctx, cfg, db, dryRun)
+		return result, nil
+	}
+
+	logrus.Infof("Found %d unposted entries", len(entries))
+	if len(changed) > 0 {
+		logrus.Infof("Found %d posted entries that changed", len(changed))
+	}
+
+	var renderer *template.Renderer
+	var dest destination.Destination
+	var poster *mastodon.Poster
+	if cfg.IsMastodon() {
+		renderer, poster, err = newPoster(ctx, cfg, db, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.DedupeTimeline > 0 && len(entries) > 0 {
+			entries, result.Duplicate = dropTimelineDuplicates(ctx, poster, db, entries, cfg.DedupeTimeline, dryRun)
+		}
+		if cfg.ScheduleSpread > 0 {
+			poster.SetScheduleSpread(scheduleStart(db, cfg.ScheduleSpread), cfg.ScheduleSpread)
+			if window != nil {
+				poster.SetScheduleWindow(window.Next)
+			}
+		}
+		dest = poster
+	} else {
+		if renderer, err = newRenderer(cfg, db); err != nil {
+			return nil, err
+		}
+		if dest, err = newDestination(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Let the pre_post hook hold back entries, once they can be rendered
+	var hookFailures []destination.PostResult
+	if runner := newHooks(cfg); runner.Enabled(hooks.PrePost) && len(entries) > 0 {
+		var held int
+		entries, held, hookFailures = applyPrePostHook(ctx, runner, renderer, db, entries, dryRun)
+		result.Filtered += held
+	}
+
+	// Post entries, shortening and archiving their links only for real
+	renderer.SetFinalLinks(!dryRun)
+	var results []destination.PostResult
+	var postErr error
+	if len(entries) > 0 && poster != nil {
+		results, postErr = poster.PostEntriesContext(ctx, entries, renderer, dryRun)
+	} else if len(entries) > 0 {
+		results, postErr = dest.PostEntries(entries, renderer, dryRun)
+	}
+	if postErr != nil && !errors.Is(postErr, mastodon.ErrUnauthorized) && !errors.Is(postErr, mastodon.ErrServerUnavailable) &&
+		!errors.Is(postErr, mastodon.ErrRateLimited) && ctx.Err() == nil {
+		return nil, fmt.Errorf("failed to post entries: %w", postErr)
+	}
+	results = append(hookFailures, results...)
+	result.Results = results
+	for _, postResult := range results {
+		switch postResult.Outcome {
+		case destination.OutcomePosted:
+			result.Posted++
+			if entry := postResult.Entry; entry.ScheduledAt.Valid {
+				result.Scheduled++
+				if entry.ScheduledAt.Time.After(result.LastScheduledAt) {
+					result.LastScheduledAt = entry.ScheduledAt.Time
+				}
+			}
+		case destination.OutcomeFailed:
+			result.Failed++
+		default:
+			result.Skipped++
+		}
+	}
+
+	// Mark exactly the entries that were posted, if not dry run
+	if !dryRun {
+		for _, postResult := range results {
+			if postResult.Outcome == destination.OutcomeFailed {
+				if recordFailure(ctx, cfg, db, postResult.Entry, postResult.Err) {
+					result.GaveUp++
+				}
+				continue
+			}
+			if postResult.Outcome == destination.OutcomePosted {
+				recordPosted(ctx, cfg, db, postResult.Entry)
+			}
+		}
+	}
+
+	// Cross-post to the other accounts, unless posting failed
+	if postErr == nil {
+		result.CrossPosted = crossPost(ctx, cfg, db, dryRun)
+	}
+
+	// Edit the statuses of changed entries, unless posting already failed
+	if len(changed) > 0 && postErr == nil {
+		var updateResults []destination.PostResult
+		updateResults, postErr = poster.UpdateEntriesContext(ctx, changed, renderer, dryRun)
+		if postErr != nil && !errors.Is(postErr, mastodon.ErrUnauthorized) && !errors.Is(postErr, mastodon.ErrServerUnavailable) &&
+			!errors.Is(postErr, mastodon.ErrRateLimited) {
+			return nil, fmt.Errorf("failed to edit changed entries: %w", postErr)
+		}
+		for _, updateResult := range updateResults {
+			if updateResult.Outcome != destination.OutcomePosted {
+				continue
+			}
+			result.Updated++
+			if dryRun {
+				continue
+			}
+			entry := updateResult.Entry
+			if err := db.SetPostedContent(entry.ID, entry.PostedContent.String); err != nil {
+				logrus.Errorf("Failed to store edited content for entry %s: %v", entry.ID, err)
+			}
+			savePost(cfg, db, entry, true)
+			if err := db.ClearChanged(entry.ID); err != nil {
+				logrus.Errorf("Failed to clear changed flag for entry %s: %v", entry.ID, err)
+			}
+		}
+	}
+
+	// Stop using a rejected token until the user re-authenticates
+	if errors.Is(postErr, mastodon.ErrUnauthorized) {
+		if err := markAccessTokenInvalid(ctx, cfg, db); err != nil {
+			logrus.Warnf("Failed to record rejected access token: %v", err)
+		}
+		return result, rejectedTokenError(cfg, postErr)
+	}
+
+	// Defer further posting until the rate limit resets
+	var rateLimitErr *mastodon.RateLimitError
+	if errors.As(postErr, &rateLimitErr) {
+		if err := db.Settings().SetTime(rateLimitSetting, rateLimitErr.Reset); err != nil {
+			logrus.Warnf("Failed to record rate limit: %v", err)
+		}
+		return result, fmt.Errorf("%w - deferring posts until %s", errRateLimited, rateLimitErr.Reset.Format(time.RFC3339))
+	}
+
+	// Defer further posting for a while if the server is down
+	if errors.Is(postErr, mastodon.ErrServerUnavailable) {
+		until := time.Now().Add(cfg.OutageCooldown)
+		if err := db.Settings().SetTime(outageSetting, until); err != nil {
+			logrus.Warnf("Failed to record instance outage: %v", err)
+		}
+		return result, fmt.Errorf("%w - deferring posts until %s", errInstanceOutage, until.Format(time.RFC3339))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("posting interrupted: %w", err)
+	}
+
+	if !dryRun && cfg.IsMastodon() {
+		if err := db.Settings().Delete(outageSetting); err != nil {
+			logrus.Warnf("Failed to clear instance outage state: %v", err)
+		}
+		if err := db.Settings().Delete(rateLimitSetting); err != nil {
+			logrus.Warnf("Failed to clear rate limit state: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// postEntryByID posts a single entry, whether or not it was posted or
+// filtered before, and records it as posted.
+func postEntryByID(ctx context.Context, cfg *config.Config, db *database.DB, id string, dryRun bool) error {
+	var accessToken string
+	if cfg.IsMastodon() {
+		var err error
+		accessToken, err = getAccessToken(ctx, cfg, db)
+		if err != nil {
+			return fmt.Errorf("authentication required: %w", err)
+		}
+	}
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
-	// Determine the limit: use flag if set, otherwise use config
-	limit := cfg.MaxItems
-	if cmd.Flags().Changed("posts") {
-		limit = maxPosts
+	entry, err := db.GetEntry(id)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("entry not found: %s", id)
 	}
 
-	// Get unposted entries
-	entries, err := db.GetUnpostedEntries(limit)
+	if cfg.IsMastodon() {
+		err = postMastodonEntry(ctx, cfg, db, accessToken, entry, dryRun)
+	} else {
+		err = postDestinationEntry(cfg, db, entry, dryRun)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get unposted entries: %w", err)
+		return err
 	}
 
-	if len(entries) == 0 {
-		fmt.Println("No unposted entries to post")
-		fmt.Println("\nRun 'feed-to-mastodon fetch' to fetch new entries")
+	if dryRun {
+		fmt.Printf("DRY RUN: Would post entry %s\n", id)
 		return nil
 	}
+	recordPosted(ctx, cfg, db, entry)
+	if entry.StatusURL.Valid {
+		fmt.Printf("Posted entry %s: %s\n", id, entry.StatusURL.String)
+	} else {
+		fmt.Printf("Posted entry %s\n", id)
+	}
+	return nil
+}
 
-	logrus.Infof("Found %d unposted entries", len(entries))
+// postMastodonEntry posts a single entry to Mastodon.
+func postMastodonEntry(ctx context.Context, cfg *config.Config, db *database.DB, accessToken string, entry *database.Entry, dryRun bool) error {
+	renderer, poster, err := newPoster(ctx, cfg, db, accessToken)
+	if err != nil {
+		return err
+	}
+	renderer.SetFinalLinks(!dryRun)
+	content, err := destination.RenderEntry(renderer, entry)
+	if err != nil {
+		return fmt.Errorf("failed to render entry %s: %w", entry.ID, err)
+	}
 
-	// Create template renderer
-	renderer, err := template.New(cfg.TemplateFile, cfg.CharacterLimit)
+	err = poster.PostContentContext(ctx, entry, content, renderer.CharacterLimit(), dryRun)
+	if errors.Is(err, mastodon.ErrUnauthorized) {
+		if err := markAccessTokenInvalid(ctx, cfg, db); err != nil {
+			logrus.Warnf("Failed to record rejected access token: %v", err)
+		}
+		return rejectedTokenError(cfg, err)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create template renderer: %w", err)
+		return fmt.Errorf("failed to post entry %s: %w", entry.ID, err)
 	}
+	return nil
+}
 
-	// Load feed metadata from database for use in templates
-	feedMetadata, err := db.GetSetting("feed_metadata")
+// postDestinationEntry posts a single entry to the configured destination
+// other than Mastodon.
+func postDestinationEntry(cfg *config.Config, db *database.DB, entry *database.Entry, dryRun bool) error {
+	renderer, err := newRenderer(cfg, db)
 	if err != nil {
-		logrus.Warnf("Failed to load feed metadata: %v", err)
-	} else if feedMetadata != nil && *feedMetadata != "" {
-		var feed gofeed.Feed
-		if err := json.Unmarshal([]byte(*feedMetadata), &feed); err != nil {
-			logrus.Warnf("Failed to unmarshal feed metadata: %v", err)
+		return err
+	}
+	renderer.SetFinalLinks(!dryRun)
+	dest, err := newDestination(cfg)
+	if err != nil {
+		return err
+	}
+
+	results, err := dest.PostEntries([]*database.Entry{entry}, renderer, dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to post entry %s: %w", entry.ID, err)
+	}
+	if results[0].Outcome != destination.OutcomePosted {
+		return fmt.Errorf("failed to post entry %s: %w", entry.ID, results[0].Err)
+	}
+	return nil
+}
+
+// newDestination creates the configured destination other than Mastodon.
+func newDestination(cfg *config.Config) (destination.Destination, error) {
+	switch cfg.Destination.Type {
+	case "stdout":
+		return destination.NewWriter(os.Stdout), nil
+	case "file":
+		return destination.NewFile(cfg.Destination.Path)
+	case "directory":
+		return destination.NewDirectory(cfg.Destination.Path)
+	case "webhook":
+		return destination.NewWebhook(cfg.Destination.URL, cfg.Destination.Headers)
+	default:
+		return nil, fmt.Errorf("unknown destination type: %s", cfg.Destination.Type)
+	}
+}
+
+// describeDestination describes the configured destination for display.
+func describeDestination(cfg *config.Config) string {
+	switch cfg.Destination.Type {
+	case "", "mastodon":
+		return "Mastodon (" + cfg.MastodonServer + ")"
+	case "file", "directory":
+		return cfg.Destination.Type + " (" + cfg.Destination.Path + ")"
+	case "webhook":
+		return "webhook (" + cfg.Destination.URL + ")"
+	default:
+		return cfg.Destination.Type
+	}
+}
+
+// newPoster creates a template renderer and a Mastodon poster from config.
+// With detect_instance_limits on, the server's character and media limits
+// are applied to cfg first, and with instance_flavor auto, the defaults of
+// the server's flavor.
+func newPoster(ctx context.Context, cfg *config.Config, db *database.DB, accessToken string) (*template.Renderer, *mastodon.Poster, error) {
+	// Use the server's limits rather than the defaults
+	flavor, detect := configuredFlavor(cfg)
+	var mimeTypes []string
+	var maxPixels int
+	if cfg.DetectInstanceLimits || detect {
+		limits, err := mastodon.DetectInstanceLimits(ctx, cfg.MastodonServer)
+		if err != nil {
+			logrus.Warnf("Failed to detect instance limits, using configured limits: %v", err)
 		} else {
-			renderer.SetFeed(&feed)
+			if detect {
+				flavor = limits.Flavor
+				cfg.ApplyInstanceFlavor(string(flavor))
+				logrus.Debugf("Detected %s server", flavor)
+			}
+			if cfg.DetectInstanceLimits {
+				cfg.ApplyInstanceLimits(limits.MaxCharacters, limits.ImageSizeLimit, limits.VideoSizeLimit)
+				mimeTypes = limits.SupportedMimeTypes
+				maxPixels = limits.ImageMatrixLimit
+			}
+			logrus.Debugf("Using character limit %d and media size limit %d", cfg.CharacterLimit, cfg.MediaMaxBytes)
+		}
+	}
+
+	// Create template renderer
+	renderer, err := newRenderer(cfg, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Emoji the server doesn't have are replaced by their fallbacks
+	if len(renderer.Shortcodes()) > 0 {
+		shortcodes, err := mastodon.CustomEmoji(ctx, cfg.MastodonServer)
+		if err != nil {
+			logrus.Warnf("Failed to get custom emoji, posting shortcodes as they are: %v", err)
+		} else {
+			renderer.SetCustomEmoji(shortcodes)
 		}
 	}
 
 	// Create Mastodon poster
-	poster, err := mastodon.New(
+	poster, err := mastodon.NewWithFlavor(
 		cfg.MastodonServer,
 		accessToken,
 		cfg.PostVisibility,
 		cfg.ContentWarning,
+		flavor,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create Mastodon poster: %w", err)
+		return nil, nil, fmt.Errorf("failed to create Mastodon poster: %w", err)
+	}
+	poster.SetOutageThreshold(cfg.OutageThreshold)
+	poster.SetPostInterval(cfg.PostInterval)
+	poster.SetSplitLongPosts(cfg.SplitLongPosts)
+	if err := poster.SetStatusLinkMode(cfg.StatusLinks); err != nil {
+		return nil, nil, err
+	}
+	poster.SetThreadAnchor(cfg.ThreadAnchor)
+	if cfg.ContinueThread {
+		previousID, err := db.GetLastStatusID()
+		if err != nil {
+			return nil, nil, err
+		}
+		poster.SetContinueThread(previousID)
+	}
+	for _, rule := range cfg.VisibilityRules {
+		var categories []string
+		if rule.MatchCategory != "" {
+			categories = []string{rule.MatchCategory}
+		}
+		match, err := filter.New(rule.MatchRegex, "", categories, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid visibility_rules rule: %w", err)
+		}
+		if err := poster.AddVisibilityRule(match, rule.Visibility); err != nil {
+			return nil, nil, fmt.Errorf("invalid visibility_rules rule: %w", err)
+		}
+	}
+	if cfg.MediaAttachments {
+		poster.EnableMedia(cfg.MediaMaxBytes)
+		poster.SetSupportedMimeTypes(mimeTypes)
+		poster.SetFirstImageMedia(cfg.MediaFirstImage)
+		poster.SetImageProcessing(imaging.Options{
+			MaxDimension:  cfg.MediaMaxDimension,
+			MaxPixels:     maxPixels,
+			Downscale:     cfg.MediaDownscale,
+			StripMetadata: cfg.MediaStripMetadata,
+		})
+		poster.SetMediaFocus(cfg.MediaFocus)
+		poster.SetMediaCache(db)
+		if cfg.MediaAudioVideo {
+			poster.EnableAudioVideo(cfg.MediaMaxAVBytes)
+		}
+		if cfg.SensitiveMedia || len(cfg.SensitiveCategories) > 0 {
+			var categories []string
+			if !cfg.SensitiveMedia {
+				categories = cfg.SensitiveCategories
+			}
+			match, err := filter.New("", "", categories, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			poster.SetSensitiveMedia(match)
+		}
 	}
 
-	// Post entries
-	if dryRun {
-		fmt.Println("DRY RUN: Previewing posts without actually posting")
-		fmt.Println()
+	return renderer, poster, nil
+}
+
+// configuredFlavor returns the server flavor set by instance_flavor, and
+// whether it should be detected instead. Mastodon is assumed until it is.
+func configuredFlavor(cfg *config.Config) (mastodon.Flavor, bool) {
+	flavor, err := mastodon.ParseFlavor(cfg.InstanceFlavor)
+	if err != nil {
+		return mastodon.FlavorMastodon, true
 	}
+	return flavor, false
+}
 
-	posted, err := poster.PostEntries(entries, renderer, dryRun)
+// detectFlavor returns the server flavor set by instance_flavor, or the
+// one detected from the server with instance_flavor auto.
+func detectFlavor(ctx context.Context, cfg *config.Config) mastodon.Flavor {
+	flavor, detect := configuredFlavor(cfg)
+	if !detect {
+		return flavor
+	}
+	limits, err := mastodon.DetectInstanceLimits(ctx, cfg.MastodonServer)
 	if err != nil {
-		return fmt.Errorf("failed to post entries: %w", err)
+		logrus.Warnf("Failed to detect the server's flavor, assuming Mastodon: %v", err)
+		return flavor
 	}
+	return limits.Flavor
+}
 
-	// Mark entries as posted if not dry run
-	if !dryRun {
-		for _, entry := range entries[:posted] {
-			if err := db.MarkAsPosted(entry.ID); err != nil {
-				logrus.Errorf("Failed to mark entry %s as posted: %v", entry.ID, err)
-			}
-		}
+// queueOrder returns the order to post unposted entries in, as set by
+// post_order.
+func queueOrder(cfg *config.Config) string {
+	if cfg.PostOrder == "" {
+		return database.OrderOldest
+	}
+	return cfg.PostOrder
+}
+
+// scheduleStart returns when the first post of a scheduled run is due:
+// now, or one spread after the last post scheduled by an earlier run, so
+// that runs continue the same schedule.
+func scheduleStart(db *database.DB, spread time.Duration) time.Time {
+	start := time.Now()
+	last, err := db.GetLastScheduledAt()
+	if err != nil {
+		logrus.Warnf("Failed to check last scheduled post: %v", err)
+		return start
 	}
+	if last != nil && last.Add(spread).After(start) {
+		return last.Add(spread)
+	}
+	return start
+}
 
-	// Display summary
-	fmt.Printf("\n")
-	if dryRun {
-		fmt.Printf("DRY RUN: Would have posted %d entries\n", posted)
-		fmt.Println("Remove --dry-run to actually post to Mastodon")
+// recordPosted marks a posted or scheduled entry in the database, storing
+// the sent text, the status and any thread replies, and any uploaded
+// attachments, logs the post in the post history, and runs the
+// post_success hook.
+func recordPosted(ctx context.Context, cfg *config.Config, db *database.DB, entry *database.Entry) {
+	var err error
+	if entry.ScheduledAt.Valid {
+		err = db.MarkAsScheduled(entry.ID, entry.ScheduledID.String, entry.ScheduledAt.Time)
 	} else {
-		fmt.Printf("Successfully posted %d entries to Mastodon\n", posted)
-		if posted < len(entries) {
-			fmt.Printf("Failed to post %d entries (see logs for details)\n", len(entries)-posted)
+		err = db.MarkAsPosted(entry.ID, entry.StatusID.String, entry.StatusURL.String)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to mark entry %s as posted: %v", entry.ID, err)
+		return
+	}
+	if !entry.ScheduledAt.Valid {
+		if err := db.SaveThreadStatuses(entry.ID, entry.ThreadStatusIDs); err != nil {
+			logrus.Errorf("Failed to record thread of entry %s: %v", entry.ID, err)
 		}
 	}
+	if err := db.RecordPost(entry.ID, cfg.FeedURL, nil); err != nil {
+		logrus.Warnf("Failed to record post history: %v", err)
+	}
+	if entry.PostedContent.Valid {
+		if err := db.SetPostedContent(entry.ID, entry.PostedContent.String); err != nil {
+			logrus.Errorf("Failed to store posted content for entry %s: %v", entry.ID, err)
+		}
+	}
+	for _, attachment := range entry.Attachments {
+		if err := db.SaveAttachment(attachment); err != nil {
+			logrus.Errorf("Failed to record attachment for entry %s: %v", entry.ID, err)
+		}
+	}
+	savePost(cfg, db, entry, false)
 
-	return nil
+	if err := newHooks(cfg).Posted(ctx, hookEvent(entry, entry.PostedContent.String)); err != nil {
+		logrus.Warnf("Entry %s was posted, but %v", entry.ID, err)
+	}
+}
+
+// savePost keeps the text and media of a status as published, which stays
+// with the entry even after it's requeued or its template changes.
+func savePost(cfg *config.Config, db *database.DB, entry *database.Entry, edited bool) {
+	if !entry.PostedContent.Valid {
+		return
+	}
+	post := database.Post{
+		EntryID:        entry.ID,
+		StatusID:       entry.StatusID.String,
+		StatusURL:      entry.StatusURL.String,
+		Content:        entry.PostedContent.String,
+		ContentWarning: entry.ContentWarning,
+		Edited:         edited,
+	}
+	if post.ContentWarning == "" {
+		post.ContentWarning = cfg.ContentWarning
+	}
+	for _, attachment := range entry.Attachments {
+		post.MediaIDs = append(post.MediaIDs, attachment.MediaID)
+	}
+	if err := db.SavePost(post); err != nil {
+		logrus.Errorf("Failed to store post of entry %s: %v", entry.ID, err)
+	}
+}
+
+// maxRetryDelay caps the backoff between attempts to post a failing entry.
+const maxRetryDelay = 24 * time.Hour
+
+// retryDelay returns how long to wait before retrying an entry that has
+// failed the given number of times, doubling backoff for each failure.
+func retryDelay(backoff time.Duration, failures int) time.Duration {
+	delay := backoff
+	for i := 1; i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// recordFailure records a failed attempt to post entry, scheduling a retry
+// with exponential backoff or giving up after max_post_attempts, and runs
+// the post_failure hook. Outages, rate limits, rejected tokens, and
+// interruptions aren't the entry's fault and aren't counted. Returns true
+// if the entry was given up on.
+func recordFailure(ctx context.Context, cfg *config.Config, db *database.DB, entry *database.Entry, postErr error) bool {
+	if errors.Is(postErr, mastodon.ErrUnauthorized) || errors.Is(postErr, mastodon.ErrServerUnavailable) ||
+		errors.Is(postErr, mastodon.ErrRateLimited) || errors.Is(postErr, context.Canceled) || errors.Is(postErr, context.DeadlineExceeded) {
+		return false
+	}
+
+	failures := entry.FailureCount + 1
+	var retryAt *time.Time
+	if cfg.MaxPostAttempts == 0 || failures < cfg.MaxPostAttempts {
+		next := time.Now().Add(retryDelay(cfg.RetryBackoff, failures))
+		retryAt = &next
+	}
+
+	message := "unknown error"
+	if postErr != nil {
+		message = postErr.Error()
+	}
+	if err := db.RecordFailure(entry.ID, message, retryAt); err != nil {
+		logrus.Errorf("Failed to record failure for entry %s: %v", entry.ID, err)
+		return false
+	}
+	if err := db.RecordPost(entry.ID, cfg.FeedURL, errors.New(message)); err != nil {
+		logrus.Warnf("Failed to record post history: %v", err)
+	}
+
+	event := hookEvent(entry, entry.PostedContent.String)
+	event.Error = message
+	if err := newHooks(cfg).Failed(ctx, event); err != nil {
+		logrus.Warnf("Entry %s failed to post, and %v", entry.ID, err)
+	}
+
+	if retryAt == nil {
+		logrus.Warnf("Giving up on entry %s after %d attempts", entry.ID, failures)
+		return true
+	}
+	logrus.Infof("Will retry entry %s after %s", entry.ID, retryAt.Format(time.RFC3339))
+	return false
+}
+
+// getOutageUntil returns the time until which posting is deferred, or nil
+// if no outage is recorded.
+func getOutageUntil(db *database.DB) (*time.Time, error) {
+	return db.Settings().GetTime(outageSetting)
+}
+
+// newRenderer creates a template renderer from config, with the stored
+// feed metadata and URL rewrite rules applied.
+func newRenderer(cfg *config.Config, db *database.DB) (*template.Renderer, error) {
+	renderer, err := template.New(cfg.TemplateFile, cfg.CharacterLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template renderer: %w", err)
+	}
+	renderer.SetContentWarning(cfg.ContentWarning)
+	if err := renderer.SetCharacterLimitMode(cfg.CharacterLimitMode); err != nil {
+		return nil, err
+	}
+
+	location, err := cfg.Location()
+	if err != nil {
+		return nil, err
+	}
+	renderer.SetTimezone(location)
+	renderer.SetVars(cfg.TemplateVars)
+	renderer.SetEscapeMentions(cfg.EscapeMentions)
+
+	// Load feed metadata from database for use in templates
+	feedMetadata, err := feed.LoadFeedMetadata(db.FeedSettings(cfg.FeedURL))
+	if err != nil {
+		logrus.Warnf("Failed to load feed metadata: %v", err)
+	} else if feedMetadata != nil {
+		renderer.SetFeed(feedMetadata)
+	}
+
+	// Use content warnings for matching entries
+	for _, rule := range cfg.ContentWarnings {
+		if err := renderer.AddContentWarningRule(rule.MatchCategory, rule.MatchTitle, rule.Text); err != nil {
+			return nil, fmt.Errorf("invalid content_warnings rule: %w", err)
+		}
+	}
+	if cfg.CWTemplate != "" {
+		if err := renderer.SetContentWarningTemplate(cfg.CWTemplate); err != nil {
+			return nil, fmt.Errorf("invalid cw_template: %w", err)
+		}
+	}
+
+	if cfg.AltTextTemplate != "" {
+		if err := renderer.SetAltTextTemplate(cfg.AltTextTemplate); err != nil {
+			return nil, fmt.Errorf("invalid alt_text_template: %w", err)
+		}
+	}
+
+	// Use other templates for matching entries
+	for _, rule := range cfg.Templates {
+		if err := renderer.AddTemplateRule(rule.MatchCategory, rule.MatchFeed, rule.Path); err != nil {
+			return nil, fmt.Errorf("invalid templates rule: %w", err)
+		}
+	}
+
+	// Prefer configured hashtags for feed categories
+	for _, mapping := range cfg.Hashtags {
+		if err := renderer.AddHashtagMapping(mapping.Category, mapping.Tag); err != nil {
+			return nil, fmt.Errorf("invalid hashtags mapping: %w", err)
+		}
+	}
+
+	// Clean up links in rendered posts before rewriting them
+	renderer.SetCleanURLs(cfg.CleanURLs)
+	for _, host := range cfg.ResolveRedirects {
+		renderer.AddRedirectHost(host)
+	}
+
+	// Apply URL rewrite rules to rendered posts
+	for _, rule := range cfg.URLRewrites {
+		if err := renderer.AddURLRewrite(rule.From, rule.To); err != nil {
+			return nil, fmt.Errorf("invalid url_rewrites rule: %w", err)
+		}
+	}
+
+	// Shorten links and add an archive link, once the caller says links
+	// are final
+	if cfg.LinkShortener.URL != "" {
+		if err := renderer.SetLinkShortener(cfg.LinkShortener.URL, cfg.LinkShortener.ResponseField, cfg.LinkShortener.Headers); err != nil {
+			return nil, fmt.Errorf("invalid link_shortener: %w", err)
+		}
+	}
+	if cfg.ArchiveLinks {
+		renderer.SetArchiveLinks(cfg.ArchiveLabel, cfg.ArchiveSave)
+	}
+
+	return renderer, nil
 }
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+)
+
+// newPostTest returns a config posting to server, with a template of the
+// entry's title and extra config, and a database with one entry.
+func newPostTest(t *testing.T, server, extraYAML string) (*config.Config, *database.DB) {
+	t.Helper()
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "feed-to-mastodon.yaml")
+	configYAML := fmt.Sprintf("feed_url: https://example.com/feed.xml\nmastodon_server: %s\nmastodon_token: token\n%s", server, extraYAML)
+	if err := os.WriteFile(configFile, []byte(configYAML), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "post-template.txt"), []byte("{{.Item.Title}} {{.Item.Link}}"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.SaveEntry("entry-1", []byte(`{"title": "Entry 1", "link": "https://example.com/entry-1"}`)); err != nil {
+		t.Fatalf("SaveEntry() error = %v", err)
+	}
+	return cfg, db
+}
+
+func TestPostDefersUntilRateLimitResets(t *testing.T) {
+	reset := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Reset", reset.Format(time.RFC3339))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	cfg, db := newPostTest(t, server.URL, "")
+
+	_, err := postUnpostedEntries(context.Background(), cfg, db, 0, false)
+	if !errors.Is(err, errRateLimited) || errors.Is(err, errInstanceOutage) {
+		t.Fatalf("postUnpostedEntries() error = %v, want errRateLimited", err)
+	}
+	until, err := db.Settings().GetTime(rateLimitSetting)
+	if err != nil || until == nil || !until.Equal(reset) {
+		t.Errorf("rate limit setting = %v, %v; want %s", until, err, reset)
+	}
+	if outage, err := getOutageUntil(db); err != nil || outage != nil {
+		t.Errorf("outage setting = %v, %v; want none", outage, err)
+	}
+	entry, err := db.GetEntry("entry-1")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if entry.FailureCount != 0 {
+		t.Errorf("FailureCount = %d, want rate limits not counted against the entry", entry.FailureCount)
+	}
+
+	// The next run doesn't ask the server until the limit resets
+	sent := requests
+	if _, err := postUnpostedEntries(context.Background(), cfg, db, 0, false); !errors.Is(err, errRateLimited) {
+		t.Errorf("postUnpostedEntries() while deferred error = %v, want errRateLimited", err)
+	}
+	if requests != sent {
+		t.Errorf("server received %d requests while deferred, want none", requests-sent)
+	}
+}
+
+func TestOnlyPostingShortensLinks(t *testing.T) {
+	shortened := 0
+	shortener := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shortened++
+		fmt.Fprint(w, "https://sho.rt/1")
+	}))
+	defer shortener.Close()
+
+	var posted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/statuses" {
+			http.NotFound(w, r)
+			return
+		}
+		posted = r.FormValue("status")
+		fmt.Fprint(w, `{"id": "1", "url": "https://mastodon.example/@user/1"}`)
+	}))
+	defer server.Close()
+
+	cfg, db := newPostTest(t, server.URL, fmt.Sprintf("link_shortener:\n  url: %s/?url={url}\n", shortener.URL))
+
+	t.Run("preview", func(t *testing.T) {
+		renderer, err := newRenderer(cfg, db)
+		if err != nil {
+			t.Fatalf("newRenderer() error = %v", err)
+		}
+		entry, err := db.GetEntry("entry-1")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		content, err := destination.RenderEntry(renderer, entry)
+		if err != nil || !strings.Contains(content, "https://example.com/entry-1") {
+			t.Errorf("RenderEntry() = %q, %v; want the link as it is", content, err)
+		}
+		if shortened != 0 {
+			t.Errorf("shortener received %d requests, want none", shortened)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		if _, err := postUnpostedEntries(context.Background(), cfg, db, 0, true); err != nil {
+			t.Fatalf("postUnpostedEntries() error = %v", err)
+		}
+		if shortened != 0 {
+			t.Errorf("shortener received %d requests, want none", shortened)
+		}
+	})
+
+	t.Run("post", func(t *testing.T) {
+		if _, err := postUnpostedEntries(context.Background(), cfg, db, 0, false); err != nil {
+			t.Fatalf("postUnpostedEntries() error = %v", err)
+		}
+		if shortened != 1 || posted != "Entry 1 https://sho.rt/1" {
+			t.Errorf("posted %q with %d shortener requests; want the short link", posted, shortened)
+		}
+	})
+}
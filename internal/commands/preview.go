@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/spf13/cobra"
+)
+
+var previewPost bool
+
+// NewPreviewCmd creates the preview command.
+func NewPreviewCmd() *cobra.Command {
+	previewCmd := &cobra.Command{
+		Use:   "preview <entry-id>",
+		Short: "Render the post for a single entry",
+		Long: `Preview renders the post for one entry with the current template and
+shows it with its content warning and length, whether or not the entry was
+posted before. This is useful for checking template changes against a
+known item.
+
+Use --post to also post it, like 'post --entry'.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runPreview,
+	}
+
+	previewCmd.Flags().BoolVar(&previewPost, "post", false, "post the entry after previewing it")
+
+	return previewCmd
+}
+
+func runPreview(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	entry, err := db.GetEntry(args[0])
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("entry not found: %s", args[0])
+	}
+
+	renderer, err := newRenderer(cfg, db)
+	if err != nil {
+		return err
+	}
+	content, err := destination.RenderEntry(renderer, entry)
+	if err != nil {
+		return fmt.Errorf("failed to render entry %s: %w", entry.ID, err)
+	}
+
+	printEntryHeader(entry)
+	if entry.PostedAt != nil && entry.PostedAt.Valid {
+		fmt.Printf("Already posted: %s\n", entry.PostedAt.Time)
+	}
+	printPost(cfg, renderer, entry, content)
+
+	if !previewPost {
+		return nil
+	}
+	fmt.Println()
+	return postEntryByID(cmd.Context(), cfg, db, entry.ID, false)
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	gomastodon "github.com/mattn/go-mastodon"
+	"github.com/spf13/cobra"
+)
+
+var registerAppName string
+
+// NewRegisterCmd creates the register command.
+func NewRegisterCmd() *cobra.Command {
+	registerCmd := &cobra.Command{
+		Use:   "register",
+		Short: "Register an OAuth application with the Mastodon server",
+		Long: `Register creates an OAuth application on the configured Mastodon server
+and prints the client ID and client secret to add to your config.
+
+The application is registered with the scopes configured in oauth_scopes
+(default: write:statuses write:media read:accounts). Afterwards, use the
+'link' and 'code' commands to obtain an access token.`,
+		RunE: runRegister,
+	}
+
+	registerCmd.Flags().StringVar(&registerAppName, "name", "feed-to-mastodon", "application name shown on the server")
+
+	return registerCmd
+}
+
+func runRegister(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.MastodonServer == "" {
+		return fmt.Errorf("mastodon_server is required")
+	}
+
+	app, err := gomastodon.RegisterApp(cmd.Context(), &gomastodon.AppConfig{
+		Server:       cfg.MastodonServer,
+		ClientName:   registerAppName,
+		RedirectURIs: mastodon.OutOfBandRedirectURI,
+		Scopes:       cfg.OAuthScopes,
+		Website:      "https://github.com/lorchard/feed-to-mastodon",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register application: %w", err)
+	}
+
+	fmt.Println("✓ Registered application!")
+	fmt.Println()
+	fmt.Printf("Scopes: %s\n", cfg.OAuthScopes)
+	fmt.Println()
+	fmt.Println("Add these lines to your config file:")
+	fmt.Println()
+	fmt.Printf("  mastodon_client_id: %q\n", app.ClientID)
+	fmt.Printf("  mastodon_client_secret: %q\n", app.ClientSecret)
+	fmt.Println()
+	fmt.Println("Then run 'feed-to-mastodon link' to authorize the application.")
+	fmt.Println()
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportSince  string
+	reportPeriod string
+	reportFormat string
+)
+
+// NewReportCmd creates the report command.
+func NewReportCmd() *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Summarize posting over a period",
+		Long: `Report summarizes posting since --since (default: the last 30 days), to
+judge whether posting keeps up with the feed:
+- Posts and failed attempts per day or week (--by), in UTC
+- Posts and failed attempts per feed, and the failure rate
+- How long posted entries waited between being fetched and posted
+- Entries fetched in the period, and the entries still waiting now
+
+Posts are counted from the post history, which starts with the posts
+already in the database when it was added. Use --format json for output
+other tools can read.`,
+		Args: cobra.NoArgs,
+		RunE: runReport,
+	}
+
+	reportCmd.Flags().StringVar(&reportSince, "since", "720h", "report on posts within a duration (168h) or since a date (2024-03-09)")
+	reportCmd.Flags().StringVar(&reportPeriod, "by", database.PeriodDay, "count posts per day or week")
+	reportCmd.Flags().StringVar(&reportFormat, "format", "text", "output format: text or json")
+
+	return reportCmd
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	if reportPeriod != database.PeriodDay && reportPeriod != database.PeriodWeek {
+		return fmt.Errorf("--by must be day or week")
+	}
+	if reportFormat != "text" && reportFormat != "json" {
+		return fmt.Errorf("--format must be text or json")
+	}
+	since, err := parseSince(reportSince)
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	report, err := db.GetPostReport(since, reportPeriod)
+	if err != nil {
+		return err
+	}
+
+	if reportFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			*database.PostReport
+			AverageBacklogSeconds int64 `json:"average_backlog_seconds"`
+			MaxBacklogSeconds     int64 `json:"max_backlog_seconds"`
+		}{report, int64(report.AverageBacklog.Seconds()), int64(report.MaxBacklog.Seconds())})
+	}
+
+	fmt.Printf("Posting since %s\n", since.Format("2006-01-02 15:04"))
+	fmt.Println()
+
+	if len(report.Periods) == 0 {
+		fmt.Println("No posts in this period")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%s\tPOSTED\tFAILED\n", map[string]string{database.PeriodDay: "DAY", database.PeriodWeek: "WEEK OF"}[reportPeriod])
+		for _, period := range report.Periods {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", period.Start, period.Posted, period.Failed)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FEED\tPOSTED\tFAILED")
+		for _, feed := range report.Feeds {
+			feedURL := feed.FeedURL
+			if feedURL == "" {
+				feedURL = "(posted before post history)"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\n", feedURL, feed.Posted, feed.Failed)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Posted: %d\n", report.Posted)
+	fmt.Printf("Failed attempts: %d (%.1f%% of attempts)\n", report.Failed, report.FailureRate()*100)
+	if report.Posted > 0 {
+		fmt.Printf("Wait before posting: %s on average, %s at most\n", formatAge(report.AverageBacklog), formatAge(report.MaxBacklog))
+	}
+	fmt.Printf("Fetched: %d entries\n", report.Fetched)
+	if report.OldestUnposted != nil {
+		fmt.Printf("Waiting now: %d entries, the oldest fetched %s ago\n", report.Unposted, formatAge(time.Since(*report.OldestUnposted)))
+	} else {
+		fmt.Println("Waiting now: none")
+	}
+
+	return nil
+}
+
+// formatAge formats a duration for people, to the minute.
+func formatAge(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return d.Round(time.Minute).String()
+}
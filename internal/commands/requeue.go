@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/mmcdole/gofeed"
+	"github.com/spf13/cobra"
+)
+
+var (
+	requeueAll    bool
+	requeueSince  string
+	requeueDryRun bool
+)
+
+// NewRequeueCmd creates the requeue command.
+func NewRequeueCmd() *cobra.Command {
+	requeueCmd := &cobra.Command{
+		Use:   "requeue [entry-id...]",
+		Short: "Return posted entries to the queue so they are posted again",
+		Long: `Requeue marks posted, skipped, or filtered entries as unposted, so they
+are posted again on the next run. This is useful after fixing a template, or after an
+accidental catchup.
+
+Give entry IDs to requeue, or use --since to requeue the entries posted
+within a duration (e.g. 24h) or since a date (e.g. 2024-03-09), or --all
+to requeue every posted entry.
+
+Requeued entries that were really posted will be posted a second time;
+the earlier statuses are left on Mastodon.
+
+Use --dry-run to preview what would be requeued.`,
+		RunE: runRequeue,
+	}
+
+	requeueCmd.Flags().BoolVar(&requeueAll, "all", false, "requeue every posted entry")
+	requeueCmd.Flags().StringVar(&requeueSince, "since", "", "requeue entries posted within a duration (24h) or since a date (2024-03-09)")
+	requeueCmd.Flags().BoolVar(&requeueDryRun, "dry-run", false, "preview entries without requeueing them")
+
+	return requeueCmd
+}
+
+func runRequeue(cmd *cobra.Command, args []string) error {
+	selectors := 0
+	for _, set := range []bool{len(args) > 0, requeueAll, requeueSince != ""} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return fmt.Errorf("give entry IDs, --since, or --all")
+	}
+
+	var since time.Time
+	if requeueSince != "" {
+		var err error
+		if since, err = parseSince(requeueSince); err != nil {
+			return err
+		}
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	ids := args
+	if len(ids) == 0 {
+		entries, err := db.GetPostedEntries(since)
+		if err != nil {
+			return fmt.Errorf("failed to get posted entries: %w", err)
+		}
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+	}
+
+	requeued := 0
+	for _, id := range ids {
+		entry, err := db.GetEntry(id)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			fmt.Printf("Entry %s not found\n", id)
+			continue
+		}
+
+		if requeueDryRun {
+			if (entry.PostedAt != nil && entry.PostedAt.Valid) || entry.SkippedAt.Valid || entry.FilteredAt.Valid {
+				fmt.Printf("DRY RUN: Would requeue %s\n", entryLabel(entry))
+				requeued++
+			} else {
+				fmt.Printf("Entry %s is already queued\n", id)
+			}
+			continue
+		}
+
+		ok, err := db.Requeue(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Printf("Entry %s is already queued\n", id)
+			continue
+		}
+		fmt.Printf("Requeued %s\n", entryLabel(entry))
+		requeued++
+	}
+
+	if requeueDryRun {
+		fmt.Printf("\nDRY RUN: Would requeue %d entries\n", requeued)
+		return nil
+	}
+	fmt.Printf("\nRequeued %d entries to be posted on the next run\n", requeued)
+	return nil
+}
+
+// entryLabel returns an entry's ID with its title, if it has one.
+func entryLabel(entry *database.Entry) string {
+	var item gofeed.Item
+	if err := json.Unmarshal(entry.EntryData, &item); err == nil && item.Title != "" {
+		return fmt.Sprintf("%s (%s)", entry.ID, item.Title)
+	}
+	return entry.ID
+}
+
+// entryLink returns an entry's link, or "" if it has none.
+func entryLink(entry *database.Entry) string {
+	var item gofeed.Item
+	if err := json.Unmarshal(entry.EntryData, &item); err != nil {
+		return ""
+	}
+	return item.Link
+}
+
+// parseSince parses a --since value: a duration before now, like 24h, or a
+// date or time, like 2024-03-09 or 2024-03-09T15:04:05Z.
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q: use a duration like 24h or a date like 2024-03-09", value)
+}
@@ -0,0 +1,338 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/filter"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+	"github.com/mmcdole/gofeed"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// reviewRejectReason is the filter reason recorded for rejected entries.
+const reviewRejectReason = "rejected in review"
+
+var reviewDryRun bool
+
+// NewReviewCmd creates the review command.
+func NewReviewCmd() *cobra.Command {
+	reviewCmd := &cobra.Command{
+		Use:   "review",
+		Short: "Interactively approve unposted entries before posting",
+		Long: `Review walks through unposted entries one at a time, showing the post
+that would be sent, and asks what to do with each:
+
+  p  post it now
+  s  skip it for now, leaving it in the queue
+  e  edit the post in $EDITOR before deciding
+  r  reject it, so it is never posted
+  q  quit
+
+Rejected entries are marked as filtered. Use --dry-run to walk through
+entries without posting or rejecting anything.`,
+		RunE: runReview,
+	}
+
+	reviewCmd.Flags().BoolVar(&reviewDryRun, "dry-run", false, "walk through entries without posting or rejecting them")
+
+	return reviewCmd
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if !cfg.IsMastodon() {
+		return fmt.Errorf("review only works with the mastodon destination")
+	}
+
+	accessToken, err := getAccessToken(cmd.Context(), cfg, db)
+	if err != nil {
+		return fmt.Errorf("authentication required: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	entries, err := db.GetUnpostedEntries(0)
+	if err != nil {
+		return fmt.Errorf("failed to get unposted entries: %w", err)
+	}
+
+	// Don't ask about entries the filters would hold back anyway
+	entryFilter, err := filter.New(cfg.Filters.IncludeRegex, cfg.Filters.ExcludeRegex, cfg.Filters.IncludeCategories, cfg.Filters.ExcludeCategories)
+	if err != nil {
+		return fmt.Errorf("invalid filters: %w", err)
+	}
+	if !entryFilter.Empty() {
+		entries, _ = applyFilter(entryFilter, db, entries, reviewDryRun)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No unposted entries to review")
+		return nil
+	}
+
+	renderer, poster, err := newPoster(cmd.Context(), cfg, db, accessToken)
+	if err != nil {
+		return err
+	}
+
+	reviewer := &reviewer{
+		cfg:      cfg,
+		db:       db,
+		renderer: renderer,
+		poster:   poster,
+		input:    bufio.NewReader(os.Stdin),
+	}
+	return reviewer.run(cmd.Context(), entries)
+}
+
+// reviewer holds the state of an interactive review session.
+type reviewer struct {
+	cfg      *config.Config
+	db       *database.DB
+	renderer *template.Renderer
+	poster   *mastodon.Poster
+	input    *bufio.Reader
+
+	posted, skipped, rejected int
+}
+
+// run reviews entries in order until they run out or the user quits.
+func (r *reviewer) run(ctx context.Context, entries []*database.Entry) error {
+	defer r.printSummary()
+
+	for i, entry := range entries {
+		content, err := destination.RenderEntry(r.renderer, entry)
+		if err != nil {
+			fmt.Printf("\nFailed to render entry %s, skipping: %v\n", entry.ID, err)
+			r.skipped++
+			continue
+		}
+
+		fmt.Printf("\n[%d/%d] ", i+1, len(entries))
+		printEntryHeader(entry)
+
+		quit, err := r.reviewEntry(ctx, entry, content)
+		if err != nil {
+			return err
+		}
+		if quit {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// reviewEntry shows an entry's post and prompts until the user decides what
+// to do with it. Returns true if the user chose to quit.
+func (r *reviewer) reviewEntry(ctx context.Context, entry *database.Entry, content string) (bool, error) {
+	for {
+		printPost(r.cfg, r.renderer, entry, content)
+
+		fmt.Print("[p]ost, [s]kip, [e]dit, [r]eject, [q]uit? ")
+		answer, err := r.input.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return false, fmt.Errorf("failed to read answer: %w", err)
+		}
+		if errors.Is(err, io.EOF) && answer == "" {
+			fmt.Println()
+			return true, nil
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "p", "post":
+			return false, r.post(ctx, entry, content)
+		case "s", "skip":
+			r.skipped++
+			return false, nil
+		case "e", "edit":
+			edited, err := editText(content)
+			if err != nil {
+				fmt.Printf("Failed to edit post: %v\n", err)
+				continue
+			}
+			content = edited
+		case "r", "reject":
+			r.reject(entry)
+			return false, nil
+		case "q", "quit":
+			return true, nil
+		default:
+			fmt.Println("Please answer p, s, e, r, or q")
+		}
+	}
+}
+
+// post publishes an entry with the reviewed content and records it.
+// A rejected access token ends the review; other errors leave the entry
+// in the queue.
+func (r *reviewer) post(ctx context.Context, entry *database.Entry, content string) error {
+	// Links are shortened and archived only once the post is approved
+	if !reviewDryRun {
+		var err error
+		if content, err = r.renderer.FinalizeLinks(content, entry.EntryData); err != nil {
+			fmt.Printf("Failed to post entry, leaving it in the queue: %v\n", err)
+			r.skipped++
+			return nil
+		}
+	}
+
+	err := r.poster.PostContentContext(ctx, entry, content, r.renderer.CharacterLimit(), reviewDryRun)
+	if errors.Is(err, mastodon.ErrUnauthorized) {
+		if err := markAccessTokenInvalid(ctx, r.cfg, r.db); err != nil {
+			logrus.Warnf("Failed to record rejected access token: %v", err)
+		}
+		return rejectedTokenError(r.cfg, err)
+	}
+	if err != nil {
+		fmt.Printf("Failed to post entry, leaving it in the queue: %v\n", err)
+		r.skipped++
+		return nil
+	}
+
+	r.posted++
+	if reviewDryRun {
+		fmt.Println("DRY RUN: Would post this entry")
+		return nil
+	}
+
+	recordPosted(ctx, r.cfg, r.db, entry)
+	if entry.StatusURL.Valid {
+		fmt.Printf("Posted: %s\n", entry.StatusURL.String)
+	} else {
+		fmt.Println("Posted")
+	}
+	return nil
+}
+
+// reject marks an entry as filtered so it is never posted.
+func (r *reviewer) reject(entry *database.Entry) {
+	r.rejected++
+	if reviewDryRun {
+		fmt.Println("DRY RUN: Would reject this entry")
+		return
+	}
+	if err := r.db.MarkAsFiltered(entry.ID, reviewRejectReason); err != nil {
+		logrus.Errorf("Failed to reject entry %s: %v", entry.ID, err)
+		return
+	}
+	fmt.Println("Rejected")
+}
+
+// printPost shows the post text and content warning with its length
+// against the limit.
+func printPost(cfg *config.Config, renderer *template.Renderer, entry *database.Entry, content string) {
+	length := template.PostLength(content, entry.ContentWarning)
+	limit := renderer.CharacterLimit()
+
+	fmt.Println()
+	if entry.ContentWarning != "" {
+		fmt.Printf("CW: %s\n", entry.ContentWarning)
+	}
+	fmt.Println(strings.TrimRight(content, "\n"))
+	fmt.Println()
+
+	switch {
+	case length <= limit:
+		fmt.Printf("(%d/%d characters)\n", length, limit)
+	case cfg.SplitLongPosts:
+		fmt.Printf("(%d/%d characters, will be posted as a thread)\n", length, limit)
+	default:
+		fmt.Printf("(%d/%d characters, over the limit)\n", length, limit)
+	}
+}
+
+// printSummary reports what was done during the review.
+func (r *reviewer) printSummary() {
+	prefix := ""
+	if reviewDryRun {
+		prefix = "DRY RUN: "
+	}
+	fmt.Printf("\n%sPosted %d, skipped %d, rejected %d entries\n", prefix, r.posted, r.skipped, r.rejected)
+}
+
+// printEntryHeader prints the ID, title, and link of an entry.
+func printEntryHeader(entry *database.Entry) {
+	var item gofeed.Item
+	if err := json.Unmarshal(entry.EntryData, &item); err != nil || item.Title == "" {
+		fmt.Println(entry.ID)
+	} else {
+		fmt.Printf("%s (%s)\n", item.Title, entry.ID)
+	}
+	if item.Link != "" {
+		fmt.Println(item.Link)
+	}
+}
+
+// editText opens text in the user's editor ($VISUAL, $EDITOR, or vi) and
+// returns the edited text, without trailing newlines.
+func editText(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	file, err := os.CreateTemp("", "feed-to-mastodon-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(text + "\n"); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	// The editor may be given with arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	editorCmd := exec.Command(parts[0], append(parts[1:], file.Name())...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", parts[0], err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited post: %w", err)
+	}
+
+	result := strings.TrimRight(string(edited), "\r\n")
+	if strings.TrimSpace(result) == "" {
+		return "", errors.New("edited post is empty")
+	}
+	return result, nil
+}
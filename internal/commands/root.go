@@ -1,17 +1,22 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfgFile string
-	verbose bool
-	debug   bool
+	cfgFile       string
+	workspaceName string
+	profileName   string
+	verbose       bool
+	debug         bool
 )
 
 // InitRootCmd initializes and returns the root command.
@@ -22,25 +27,59 @@ func InitRootCmd() *cobra.Command {
 		Long: `feed-to-mastodon is a CLI tool that fetches RSS or Atom feeds,
 stores entries in a SQLite database, and posts them to Mastodon
 using customizable templates.`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			// Configure logging based on flags
 			setupLogging()
+
+			// Switch to the selected workspace or profile directory
+			if workspaceName != "" && profileName != "" {
+				return fmt.Errorf("--workspace and --profile can't be used together")
+			}
+			if workspaceName != "" {
+				return enterWorkspace(workspaceName)
+			}
+			if profileName != "" {
+				return enterProfile(profileName, cmd.Name() == "init")
+			}
+			return nil
 		},
 	}
 
 	// Add persistent flags
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default is ./feed-to-mastodon.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&workspaceName, "workspace", "w", "", "run in a registered workspace directory (see 'workspaces list')")
+	rootCmd.PersistentFlags().StringVarP(&profileName, "profile", "p", "", "run in a profile directory under the user config directory, e.g. ~/.config/feed-to-mastodon/<name>")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug output")
 
 	// Add subcommands
 	rootCmd.AddCommand(NewInitCmd())
 	rootCmd.AddCommand(NewFetchCmd())
+	rootCmd.AddCommand(NewFeedsCmd())
 	rootCmd.AddCommand(NewStatusCmd())
+	rootCmd.AddCommand(NewReportCmd())
+	rootCmd.AddCommand(NewVerifyCmd())
+	rootCmd.AddCommand(NewWhoamiCmd())
+	rootCmd.AddCommand(NewShowCmd())
+	rootCmd.AddCommand(NewListCmd())
 	rootCmd.AddCommand(NewPostCmd())
+	rootCmd.AddCommand(NewPreviewCmd())
+	rootCmd.AddCommand(NewTemplateCmd())
+	rootCmd.AddCommand(NewReviewCmd())
 	rootCmd.AddCommand(NewCatchupCmd())
+	rootCmd.AddCommand(NewFailuresCmd())
+	rootCmd.AddCommand(NewRequeueCmd())
+	rootCmd.AddCommand(NewSkipCmd())
+	rootCmd.AddCommand(NewDeletePostCmd())
+	rootCmd.AddCommand(NewExpireCmd())
+	rootCmd.AddCommand(NewDaemonCmd())
+	rootCmd.AddCommand(NewRegisterCmd())
 	rootCmd.AddCommand(NewLinkCmd())
 	rootCmd.AddCommand(NewCodeCmd())
+	rootCmd.AddCommand(NewLogoutCmd())
+	rootCmd.AddCommand(NewBlueskyCmd())
+	rootCmd.AddCommand(NewWipeCmd())
+	rootCmd.AddCommand(NewWorkspacesCmd())
 
 	return rootCmd
 }
@@ -65,10 +104,20 @@ func setupLogging() {
 	})
 }
 
-// Execute runs the root command.
+// Execute runs the root command. Its context is cancelled on SIGINT or
+// SIGTERM, so commands can stop what they're doing and clean up; a second
+// signal kills the process as usual.
 func Execute() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	rootCmd := InitRootCmd()
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/mmcdole/gofeed"
+	"github.com/spf13/cobra"
+)
+
+// NewShowCmd creates the show command.
+func NewShowCmd() *cobra.Command {
+	showCmd := &cobra.Command{
+		Use:   "show <entry-id>",
+		Short: "Show details of a single entry",
+		Long: `Show displays the stored details of a single entry, including when it
+was fetched and posted.
+
+For posted entries, the exact text that was sent to Mastodon is shown,
+so you can check what was actually posted even if the template or the
+feed has changed since. Entries that were edited, requeued, or posted
+again also list every version that was published.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runShow,
+	}
+
+	return showCmd
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	entryID := args[0]
+
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	entry, err := db.GetEntry(entryID)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	if entry == nil {
+		return fmt.Errorf("entry not found: %s", entryID)
+	}
+
+	var item gofeed.Item
+	if err := json.Unmarshal(entry.EntryData, &item); err != nil {
+		return fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+
+	fmt.Printf("ID: %s\n", entry.ID)
+	fmt.Printf("Title: %s\n", item.Title)
+	if item.Link != "" {
+		fmt.Printf("Link: %s\n", item.Link)
+	}
+	if item.Published != "" {
+		fmt.Printf("Published: %s\n", item.Published)
+	}
+	if entry.FetchedAt.Valid {
+		fmt.Printf("Fetched: %s\n", entry.FetchedAt.Time)
+	}
+
+	if entry.PostedAt != nil && entry.PostedAt.Valid {
+		fmt.Printf("Posted: %s\n", entry.PostedAt.Time)
+		if entry.ScheduledAt.Valid {
+			fmt.Printf("Scheduled: %s (scheduled status %s)\n", entry.ScheduledAt.Time, entry.ScheduledID.String)
+		} else if entry.StatusURL.Valid {
+			fmt.Printf("Status: %s\n", entry.StatusURL.String)
+		} else if entry.StatusID.Valid {
+			fmt.Printf("Status ID: %s\n", entry.StatusID.String)
+		}
+		if entry.DeletedAt.Valid {
+			fmt.Printf("Deleted: %s\n", entry.DeletedAt.Time)
+		}
+	} else if entry.SkippedAt.Valid {
+		fmt.Printf("Skipped: %s\n", entry.SkippedAt.Time)
+	} else if entry.FilteredAt.Valid {
+		fmt.Printf("Filtered: %s (%s)\n", entry.FilteredAt.Time, entry.FilterReason.String)
+	} else {
+		fmt.Println("Posted: not yet")
+	}
+
+	if entry.PostedAt == nil || !entry.PostedAt.Valid {
+		if entry.FailedAt.Valid {
+			fmt.Printf("Gave up: %s after %d attempts (%s)\n", entry.FailedAt.Time, entry.FailureCount, entry.LastError.String)
+		} else if entry.FailureCount > 0 {
+			fmt.Printf("Failed: %d attempts, retry at %s (%s)\n", entry.FailureCount, entry.RetryAt.Time, entry.LastError.String)
+		}
+	}
+
+	accountPosts, err := db.GetAccountPosts(entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get cross-posts: %w", err)
+	}
+	for _, p := range accountPosts {
+		switch {
+		case p.PostedAt.Valid && p.StatusURL.Valid:
+			fmt.Printf("Cross-posted to %s: %s\n", p.Account, p.StatusURL.String)
+		case p.PostedAt.Valid:
+			fmt.Printf("Cross-posted to %s: %s\n", p.Account, p.PostedAt.Time)
+		default:
+			fmt.Printf("Failed to cross-post to %s: %d attempts (%s)\n", p.Account, p.FailureCount, p.LastError.String)
+		}
+	}
+
+	if entry.PostedContent.Valid {
+		fmt.Println()
+		fmt.Println("Posted content:")
+		fmt.Println("---------------")
+		fmt.Println(entry.PostedContent.String)
+	}
+
+	// Earlier versions are only worth showing once the entry was edited,
+	// requeued, or reposted
+	posts, err := db.GetPosts(entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get posts: %w", err)
+	}
+	if len(posts) > 1 || (len(posts) == 1 && posts[0].Content != entry.PostedContent.String) {
+		fmt.Println()
+		fmt.Println("Published versions:")
+		for _, p := range posts {
+			action := "Posted"
+			if p.Edited {
+				action = "Edited"
+			}
+			fmt.Printf("  %s %s", action, p.PostedAt.Time)
+			if p.StatusURL != "" {
+				fmt.Printf(" (%s)", p.StatusURL)
+			}
+			fmt.Println()
+			if p.ContentWarning != "" {
+				fmt.Printf("  CW: %s\n", p.ContentWarning)
+			}
+			if len(p.MediaIDs) > 0 {
+				fmt.Printf("  Media: %s\n", strings.Join(p.MediaIDs, ", "))
+			}
+			fmt.Printf("    %s\n", strings.ReplaceAll(strings.TrimRight(p.Content, "\n"), "\n", "\n    "))
+		}
+	}
+
+	attachments, err := db.GetAttachments(entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get attachments: %w", err)
+	}
+	if len(attachments) > 0 {
+		fmt.Println()
+		fmt.Println("Attachments:")
+		for _, a := range attachments {
+			fmt.Printf("  %s (media %s, %s, %d bytes)\n", a.SourceURL, a.MediaID, a.ContentType, a.Size)
+		}
+	}
+
+	return nil
+}
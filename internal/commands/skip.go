@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var skipDryRun bool
+
+// NewSkipCmd creates the skip command.
+func NewSkipCmd() *cobra.Command {
+	skipCmd := &cobra.Command{
+		Use:   "skip <entry-id>...",
+		Short: "Keep entries from being posted",
+		Long: `Skip marks entries as skipped, so they are never posted. Unlike catchup,
+skipped entries aren't counted as posted, and unlike deleting them, they
+stay in the database so the next fetch doesn't add them again.
+
+Use 'requeue' to return a skipped entry to the queue, and --dry-run to
+preview what would be skipped.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runSkip,
+	}
+
+	skipCmd.Flags().BoolVar(&skipDryRun, "dry-run", false, "preview entries without skipping them")
+
+	return skipCmd
+}
+
+func runSkip(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	skipped := 0
+	for _, id := range args {
+		entry, err := db.GetEntry(id)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			fmt.Printf("Entry %s not found\n", id)
+			continue
+		}
+		if entry.PostedAt != nil && entry.PostedAt.Valid {
+			fmt.Printf("Entry %s was already posted (see 'delete-post')\n", id)
+			continue
+		}
+		if entry.SkippedAt.Valid {
+			fmt.Printf("Entry %s is already skipped\n", id)
+			continue
+		}
+
+		if skipDryRun {
+			fmt.Printf("DRY RUN: Would skip %s\n", entryLabel(entry))
+			skipped++
+			continue
+		}
+
+		if _, err := db.MarkAsSkipped(id); err != nil {
+			return err
+		}
+		fmt.Printf("Skipped %s\n", entryLabel(entry))
+		skipped++
+	}
+
+	if skipDryRun {
+		fmt.Printf("\nDRY RUN: Would skip %d entries\n", skipped)
+		return nil
+	}
+	fmt.Printf("\nSkipped %d entries (use 'requeue' to post them after all)\n", skipped)
+	return nil
+}
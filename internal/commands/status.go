@@ -1,12 +1,10 @@
 package commands
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/lorchard/feed-to-mastodon/internal/config"
-	"github.com/lorchard/feed-to-mastodon/internal/database"
 	mastodon "github.com/mattn/go-mastodon"
 	"github.com/mmcdole/gofeed"
 	"github.com/sirupsen/logrus"
@@ -19,6 +17,8 @@ func NewStatusCmd() *cobra.Command {
 		Use:   "status",
 		Short: "Show status of the feed-to-mastodon database",
 		Long: `Status displays information about the current state of the database:
+- Where the access token comes from: config, mastodon_token_command, the
+  OS keyring, or the database
 - Total entries, posted entries, and unposted entries
 - Last fetch time and last post time
 - Preview of the next entries that will be posted`,
@@ -36,7 +36,7 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	// Open database
-	db, err := database.New(cfg.DatabasePath)
+	db, err := openDatabase(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -48,6 +48,26 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get database stats: %w", err)
 	}
 
+	filtered, err := db.GetFilteredCount()
+	if err != nil {
+		return fmt.Errorf("failed to get filtered count: %w", err)
+	}
+
+	skipped, err := db.GetSkippedCount()
+	if err != nil {
+		return fmt.Errorf("failed to get skipped count: %w", err)
+	}
+
+	retrying, gaveUp, err := db.GetFailureCounts()
+	if err != nil {
+		return fmt.Errorf("failed to get failure counts: %w", err)
+	}
+
+	lastScheduled, err := db.GetLastScheduledAt()
+	if err != nil {
+		return fmt.Errorf("failed to get last scheduled time: %w", err)
+	}
+
 	// Get last fetch and post times
 	lastFetch, err := db.GetLastFetchTime()
 	if err != nil {
@@ -59,15 +79,25 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get last post time: %w", err)
 	}
 
+	lastStatusURL, err := db.GetLastStatusURL()
+	if err != nil {
+		return fmt.Errorf("failed to get last status URL: %w", err)
+	}
+
 	// Display overview
 	fmt.Println("Feed to Mastodon Status")
 	fmt.Println("=======================")
 	fmt.Printf("Feed URL: %s\n", cfg.FeedURL)
 	fmt.Printf("Database: %s\n", cfg.DatabasePath)
+	if cfg.IsMastodon() {
+		fmt.Printf("Access Token: %s\n", describeTokenSource(cmd.Context(), cfg, db))
+	}
 
 	// Try to show Mastodon account info
-	accessToken, err := getAccessToken(cfg, db)
-	if err != nil {
+	accessToken, err := getAccessToken(cmd.Context(), cfg, db)
+	if !cfg.IsMastodon() {
+		fmt.Printf("Destination: %s\n\n", describeDestination(cfg))
+	} else if err != nil {
 		fmt.Printf("Mastodon Account: Not authenticated (%v)\n\n", err)
 	} else {
 		// Create Mastodon client
@@ -77,18 +107,46 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		})
 
 		// Get account info
-		account, err := client.GetAccountCurrentUser(context.Background())
+		account, err := client.GetAccountCurrentUser(cmd.Context())
 		if err != nil {
 			fmt.Printf("Mastodon Account: Authentication error (%v)\n\n", err)
 		} else {
 			fmt.Printf("Mastodon Account: @%s@%s\n", account.Username, cfg.MastodonServer)
-			fmt.Printf("Display Name: %s\n\n", account.DisplayName)
+			fmt.Printf("Display Name: %s\n", account.DisplayName)
+			if scopes, err := db.Settings().Get("mastodon_token_scopes"); err == nil && scopes != nil {
+				fmt.Printf("Granted Scopes: %s\n", *scopes)
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(cfg.Accounts) > 0 || cfg.Bluesky.Handle != "" {
+		fmt.Println("Cross-posting to:")
+		for _, account := range cfg.Accounts {
+			fmt.Printf("  %s (%s)\n", account.Name, account.Server)
+		}
+		if cfg.Bluesky.Handle != "" {
+			fmt.Printf("  Bluesky (@%s)\n", cfg.Bluesky.Handle)
 		}
+		fmt.Println()
 	}
 
 	fmt.Printf("Total entries: %d\n", total)
 	fmt.Printf("Posted entries: %d\n", posted)
-	fmt.Printf("Unposted entries: %d\n\n", unposted)
+	fmt.Printf("Unposted entries: %d\n", unposted)
+	if filtered > 0 {
+		fmt.Printf("Filtered entries: %d\n", filtered)
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipped entries: %d\n", skipped)
+	}
+	if retrying > 0 || gaveUp > 0 {
+		fmt.Printf("Failed entries: %d waiting to retry, %d given up (see 'failures')\n", retrying, gaveUp)
+	}
+	if lastScheduled != nil {
+		fmt.Printf("Scheduled posts until: %s\n", *lastScheduled)
+	}
+	fmt.Println()
 
 	if lastFetch != nil {
 		fmt.Printf("Last fetch: %s\n", *lastFetch)
@@ -97,7 +155,11 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	if lastPost != nil {
-		fmt.Printf("Last post: %s\n\n", *lastPost)
+		fmt.Printf("Last post: %s\n", *lastPost)
+		if lastStatusURL != nil {
+			fmt.Printf("Last status: %s\n", *lastStatusURL)
+		}
+		fmt.Println()
 	} else {
 		fmt.Println("Last post: never")
 		fmt.Println()
@@ -108,7 +170,7 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		fmt.Println("Next entries to be posted:")
 		fmt.Println("--------------------------")
 
-		entries, err := db.GetUnpostedEntries(5)
+		entries, err := db.GetUnpostedEntriesOrdered(cmd.Context(), 5, queueOrder(cfg))
 		if err != nil {
 			return fmt.Errorf("failed to get unposted entries: %w", err)
 		}
@@ -127,7 +189,7 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		}
 
 		if unposted > 5 {
-			fmt.Printf("\n... and %d more\n", unposted-5)
+			fmt.Printf("\n... and %d more (see 'list --unposted')\n", unposted-5)
 		}
 	} else {
 		fmt.Println("No unposted entries")
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/mmcdole/gofeed"
+	"github.com/sirupsen/logrus"
+)
+
+// summaryOut is the --summary-out flag of fetch, post, and daemon.
+var summaryOut string
+
+// runSummary is the JSON summary of a fetch, post, or daemon run written
+// with --summary-out, for scripts that act on the outcome.
+type runSummary struct {
+	Command    string    `json:"command"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	// Skipped explains why the run did nothing, e.g. another run held the
+	// lock or it's outside the posting window.
+	Skipped string `json:"skipped,omitempty"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+
+	Fetch *fetchSummary `json:"fetch,omitempty"`
+	Post  *postSummary  `json:"post,omitempty"`
+}
+
+// fetchSummary holds the counts of a fetch run.
+type fetchSummary struct {
+	NewEntries int `json:"new_entries"`
+	Purged     int `json:"purged"`
+	CaughtUp   int `json:"caught_up"`
+}
+
+// postSummary holds the counts and entry outcomes of a post run.
+type postSummary struct {
+	Attempted   int            `json:"attempted"`
+	Posted      int            `json:"posted"`
+	Scheduled   int            `json:"scheduled"`
+	Failed      int            `json:"failed"`
+	GaveUp      int            `json:"gave_up"`
+	Skipped     int            `json:"skipped"`
+	Filtered    int            `json:"filtered"`
+	Duplicate   int            `json:"duplicate"`
+	Updated     int            `json:"updated"`
+	CrossPosted int            `json:"cross_posted"`
+	Entries     []entryOutcome `json:"entries"`
+}
+
+// entryOutcome is what happened to one entry in a post run.
+type entryOutcome struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title,omitempty"`
+	Link        string     `json:"link,omitempty"`
+	Outcome     string     `json:"outcome"`
+	StatusURL   string     `json:"status_url,omitempty"`
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// newRunSummary starts the summary of a run of command.
+func newRunSummary(command string) *runSummary {
+	return &runSummary{Command: command, StartedAt: time.Now()}
+}
+
+// setPostResult records the outcome of a post run.
+func (s *runSummary) setPostResult(result *postResult) {
+	if result == nil {
+		return
+	}
+	post := &postSummary{
+		Attempted:   result.Attempted,
+		Posted:      result.Posted,
+		Scheduled:   result.Scheduled,
+		Failed:      result.Failed,
+		GaveUp:      result.GaveUp,
+		Skipped:     result.Skipped,
+		Filtered:    result.Filtered,
+		Duplicate:   result.Duplicate,
+		Updated:     result.Updated,
+		CrossPosted: result.CrossPosted,
+		Entries:     []entryOutcome{},
+	}
+	for _, r := range result.Results {
+		post.Entries = append(post.Entries, newEntryOutcome(r))
+	}
+	s.Post = post
+}
+
+// newEntryOutcome describes the result of posting an entry.
+func newEntryOutcome(result destination.PostResult) entryOutcome {
+	entry := result.Entry
+	outcome := entryOutcome{
+		ID:        entry.ID,
+		Outcome:   result.Outcome.String(),
+		StatusURL: entry.StatusURL.String,
+	}
+
+	var item gofeed.Item
+	if err := json.Unmarshal(entry.EntryData, &item); err == nil {
+		outcome.Title = item.Title
+		outcome.Link = item.Link
+	}
+	if entry.ScheduledAt.Valid {
+		scheduledAt := entry.ScheduledAt.Time
+		outcome.ScheduledAt = &scheduledAt
+	}
+	if result.Err != nil {
+		outcome.Error = result.Err.Error()
+	}
+	return outcome
+}
+
+// write finishes the summary with the run's error and writes it to path,
+// if path is set. Failing to write it is logged, not returned, so it
+// doesn't hide the outcome of the run.
+func (s *runSummary) write(path string, runErr error) {
+	if path == "" {
+		return
+	}
+
+	s.FinishedAt = time.Now()
+	s.DurationMS = s.FinishedAt.Sub(s.StartedAt).Milliseconds()
+	s.Success = runErr == nil
+	if runErr != nil {
+		s.Error = runErr.Error()
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		logrus.Errorf("Failed to encode run summary: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		logrus.Errorf("Failed to write run summary: %v", fmt.Errorf("%s: %w", path, err))
+	}
+}
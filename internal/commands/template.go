@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+	"github.com/mmcdole/gofeed"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templateEntryID string
+	templateFixture string
+	templateLatest  bool
+)
+
+// NewTemplateCmd creates the template command and its subcommands.
+func NewTemplateCmd() *cobra.Command {
+	templateCmd := &cobra.Command{
+		Use:   "template",
+		Short: "Work on the post template",
+	}
+
+	testCmd := &cobra.Command{
+		Use:   "test",
+		Short: "Render the template against an entry or a fixture item",
+		Long: `Test renders the configured template against one item and prints the post
+with a breakdown of its length, without posting anything or changing the
+database. The item is one of:
+- --entry: a fetched entry, by ID
+- --fixture: a JSON file holding a feed item, in the format 'show' and
+  the feed parser use (title, link, description, categories, ...)
+- --latest: the most recently fetched entry (the default)
+
+Run it after each template change for a quick check; use 'post --dry-run
+--out-dir' to check the whole queue.`,
+		Args: cobra.NoArgs,
+		RunE: runTemplateTest,
+	}
+	testCmd.Flags().StringVar(&templateEntryID, "entry", "", "render the entry with this ID")
+	testCmd.Flags().StringVar(&templateFixture, "fixture", "", "render the feed item in this JSON file")
+	testCmd.Flags().BoolVar(&templateLatest, "latest", false, "render the most recently fetched entry")
+	testCmd.MarkFlagsMutuallyExclusive("entry", "fixture", "latest")
+	templateCmd.AddCommand(testCmd)
+
+	templateCmd.AddCommand(&cobra.Command{
+		Use:   "lint [file...]",
+		Short: "Check templates for common mistakes",
+		Long: `Lint checks the configured template, and those of the templates rules,
+for common mistakes:
+- References to fields that feed items or feeds don't have, like
+  {{.Item.Summary}} or {{.Item.link}}, or to variables that aren't set
+  in template_vars
+- Not including the entry's link, {{.Item.Link}}
+- Posts that can be longer than character_limit, counting truncated
+  values at their limit and links as 23 characters
+
+Values output without truncate, like {{.Item.Title}}, make the length
+unbounded; they're listed as a warning rather than failing the lint.
+
+Pass template files to check those instead. Lint exits with an error if
+it finds a problem, so it can run before deploying a template change.`,
+		RunE: runTemplateLint,
+	})
+
+	return templateCmd
+}
+
+func runTemplateTest(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	entry, err := templateTestEntry(db)
+	if err != nil {
+		return err
+	}
+
+	renderer, err := newRenderer(cfg, db)
+	if err != nil {
+		return err
+	}
+	content, err := destination.RenderEntry(renderer, entry)
+	if err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	printEntryHeader(entry)
+	printPost(cfg, renderer, entry, content)
+
+	measure := template.MeasurePost(content, entry.ContentWarning)
+	fmt.Printf("Length: %d text + %d links (%d at 23 each)", measure.Text, measure.URLCharacters(), measure.URLs)
+	if measure.ContentWarning > 0 {
+		fmt.Printf(" + %d content warning", measure.ContentWarning)
+	}
+	fmt.Printf(" = %d, %d left\n", measure.Total(), renderer.CharacterLimit()-measure.Total())
+	if entry.AltText != "" {
+		fmt.Printf("Alt text: %s\n", entry.AltText)
+	}
+
+	return nil
+}
+
+// templateTestEntry returns the entry selected by the flags of template
+// test: a stored entry, or one made up from a fixture file.
+func templateTestEntry(db *database.DB) (*database.Entry, error) {
+	if templateFixture != "" {
+		data, err := os.ReadFile(templateFixture)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %w", err)
+		}
+		var item gofeed.Item
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("failed to parse fixture %s: %w", templateFixture, err)
+		}
+		return &database.Entry{ID: "fixture", EntryData: data}, nil
+	}
+
+	if templateEntryID != "" {
+		entry, err := db.GetEntry(templateEntryID)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("entry not found: %s", templateEntryID)
+		}
+		return entry, nil
+	}
+
+	entries, err := db.ListEntries(database.ListOptions{Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries fetched yet (run 'fetch', or use --fixture)")
+	}
+	return entries[0], nil
+}
+
+func runTemplateLint(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	paths := args
+	if len(paths) == 0 {
+		paths = append(paths, cfg.TemplateFile)
+		for _, rule := range cfg.Templates {
+			paths = append(paths, rule.Path)
+		}
+	}
+
+	problems := 0
+	for i, path := range paths {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s:\n", path)
+
+		text, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("  %v\n", err)
+			problems++
+			continue
+		}
+		result, err := template.Lint(string(text), cfg.CharacterLimit, cfg.TemplateVars)
+		if err != nil {
+			fmt.Printf("  %v\n", err)
+			problems++
+			continue
+		}
+
+		for _, issue := range result.Issues {
+			if issue.Warning {
+				fmt.Printf("  warning: %s\n", issue)
+			} else {
+				fmt.Printf("  error: %s\n", issue)
+				problems++
+			}
+		}
+		if len(result.Unbounded) > 0 {
+			fmt.Printf("  Longest post: unbounded, at least %d/%d characters\n", result.MaxLength, cfg.CharacterLimit)
+		} else {
+			fmt.Printf("  Longest post: %d/%d characters\n", result.MaxLength, cfg.CharacterLimit)
+		}
+	}
+
+	if problems > 0 {
+		// The problems are listed above; usage would only bury them
+		cmd.SilenceUsage = true
+		return fmt.Errorf("found %d problems in templates", problems)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+	gomastodon "github.com/mattn/go-mastodon"
+	"github.com/mmcdole/gofeed"
+	"github.com/spf13/cobra"
+)
+
+// NewVerifyCmd creates the verify command.
+func NewVerifyCmd() *cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check the config, template, feed, and credentials",
+		Long: `Verify checks that everything needed to post is in place, without
+posting or changing any entries:
+- The config is complete and valid
+- The feed can be fetched and parsed
+- The template renders the newest feed entry (or a sample entry)
+- The Mastodon access token works and can post, for the main account
+  and each cross-posting account
+- The custom emoji the templates use, like :blobcat:, exist on each
+  server, which is only warned about
+
+All problems are reported at once, and the command fails if any are found.`,
+		Args: cobra.NoArgs,
+		RunE: runVerify,
+	}
+
+	return verifyCmd
+}
+
+// verifier runs the checks and counts the problems found.
+type verifier struct {
+	problems int
+}
+
+// check prints the result of one check.
+func (v *verifier) check(name string, detail string, err error) {
+	if err != nil {
+		v.problems++
+		fmt.Printf("FAIL  %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("ok    %s: %s\n", name, detail)
+}
+
+// warn prints a concern that doesn't keep posts from working.
+func (v *verifier) warn(name string, detail string) {
+	fmt.Printf("WARN  %s: %s\n", name, detail)
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	v := &verifier{}
+
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	v.check("Config", "valid", cfg.Validate())
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	sample := v.checkFeed(ctx, cfg)
+	v.checkTemplate(cfg, db, sample)
+
+	if cfg.IsMastodon() {
+		v.checkMastodon(ctx, cfg, db)
+		v.checkEmoji(ctx, cfg, db, cfg.MastodonServer)
+	}
+	for _, account := range cfg.Accounts {
+		detail, err := verifyAccount(ctx, account.Server, account.Token, "")
+		v.check("Account "+account.Name, detail, err)
+		v.checkEmoji(ctx, cfg, db, account.Server)
+	}
+
+	fmt.Println()
+	if v.problems > 0 {
+		return fmt.Errorf("found %d problem(s)", v.problems)
+	}
+	fmt.Println("Everything looks ready to post")
+	return nil
+}
+
+// checkFeed fetches the feed and returns its newest entry, or nil if it
+// can't be fetched or has no entries.
+func (v *verifier) checkFeed(ctx context.Context, cfg *config.Config) *gofeed.Item {
+	if cfg.FeedURL == "" {
+		v.check("Feed", "", fmt.Errorf("feed_url is not set"))
+		return nil
+	}
+
+	fetcher, err := newFetcher(cfg)
+	if err != nil {
+		v.check("Feed", "", err)
+		return nil
+	}
+	// Report a failing feed right away rather than retrying it
+	fetcher.SetRetries(0, 0)
+
+	feedData, err := fetcher.FetchContext(ctx, cfg.FeedURL)
+	if err != nil {
+		v.check("Feed", "", err)
+		return nil
+	}
+
+	title := feedData.Title
+	if title == "" {
+		title = cfg.FeedURL
+	}
+	v.check("Feed", fmt.Sprintf("%s (%d entries)", title, len(feedData.Items)), nil)
+
+	if len(feedData.Items) == 0 {
+		return nil
+	}
+	newest := feedData.Items[0]
+	for _, item := range feedData.Items[1:] {
+		if item.PublishedParsed != nil && (newest.PublishedParsed == nil || item.PublishedParsed.After(*newest.PublishedParsed)) {
+			newest = item
+		}
+	}
+	return newest
+}
+
+// checkTemplate renders item, or a made-up entry if item is nil, with the
+// configured templates.
+func (v *verifier) checkTemplate(cfg *config.Config, db *database.DB, item *gofeed.Item) {
+	source := "newest entry"
+	if item == nil {
+		source = "sample entry"
+		published := time.Now()
+		item = &gofeed.Item{
+			Title:           "Sample entry",
+			Description:     "A sample entry to check that the template renders.",
+			Link:            "https://example.com/sample-entry",
+			GUID:            "https://example.com/sample-entry",
+			Categories:      []string{"sample"},
+			Published:       published.Format(time.RFC3339),
+			PublishedParsed: &published,
+		}
+	}
+
+	renderer, err := newRenderer(cfg, db)
+	if err != nil {
+		v.check("Template", "", err)
+		return
+	}
+
+	entryData, err := json.Marshal(item)
+	if err != nil {
+		v.check("Template", "", fmt.Errorf("failed to encode %s: %w", source, err))
+		return
+	}
+	entry := &database.Entry{ID: item.GUID, EntryData: entryData}
+	content, err := destination.RenderEntry(renderer, entry)
+	if err != nil {
+		v.check("Template", "", fmt.Errorf("failed to render %s: %w", source, err))
+		return
+	}
+
+	length := template.PostLength(content, entry.ContentWarning)
+	limit := renderer.CharacterLimit()
+	if length > limit && !cfg.SplitLongPosts {
+		v.check("Template", "", fmt.Errorf("%s renders to %d characters, over the limit of %d (set split_long_posts to post it as a thread, or character_limit_mode to truncate it)", source, length, limit))
+		return
+	}
+	v.check("Template", fmt.Sprintf("%s renders to %d/%d characters", source, length, limit), nil)
+}
+
+// checkEmoji warns about custom emoji the templates use that server
+// doesn't have, which would be posted as plain :shortcode: text.
+func (v *verifier) checkEmoji(ctx context.Context, cfg *config.Config, db *database.DB, server string) {
+	renderer, err := newRenderer(cfg, db)
+	if err != nil {
+		return
+	}
+	shortcodes := renderer.Shortcodes()
+	if len(shortcodes) == 0 {
+		return
+	}
+
+	available, err := mastodon.CustomEmoji(ctx, server)
+	if err != nil {
+		v.warn("Emoji", fmt.Sprintf("can't check custom emoji on %s: %v", server, err))
+		return
+	}
+	var missing []string
+	for _, shortcode := range shortcodes {
+		if !slices.Contains(available, shortcode) {
+			missing = append(missing, ":"+shortcode+":")
+		}
+	}
+	if len(missing) > 0 {
+		v.warn("Emoji", fmt.Sprintf("%s doesn't have %s; give emoji a fallback, like {{emoji \"blobcat\" \"🐱\"}}", server, strings.Join(missing, ", ")))
+		return
+	}
+	v.check("Emoji", fmt.Sprintf("%d custom emoji found on %s", len(shortcodes), server), nil)
+}
+
+// checkMastodon checks the main account's access token.
+func (v *verifier) checkMastodon(ctx context.Context, cfg *config.Config, db *database.DB) {
+	token, err := getAccessToken(ctx, cfg, db)
+	if err != nil {
+		v.check("Mastodon", "", err)
+		return
+	}
+
+	// Scopes are only known for tokens from 'code'
+	scopes := ""
+	if !tokenConfigured(cfg) {
+		if stored, err := db.Settings().Get("mastodon_token_scopes"); err == nil && stored != nil {
+			scopes = *stored
+		}
+	}
+
+	detail, err := verifyAccount(ctx, cfg.MastodonServer, token, scopes)
+	v.check("Mastodon", detail, err)
+}
+
+// verifyAccount checks that token works on server and, if its scopes are
+// known, that it can post statuses. Returns a description of the account.
+func verifyAccount(ctx context.Context, server, token, scopes string) (string, error) {
+	client := gomastodon.NewClient(&gomastodon.Config{
+		Server:      server,
+		AccessToken: token,
+	})
+	account, err := client.GetAccountCurrentUser(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify credentials with %s: %w", server, err)
+	}
+
+	detail := fmt.Sprintf("@%s on %s", account.Acct, server)
+	if scopes == "" {
+		return detail, nil
+	}
+	if !canPost(scopes) {
+		return "", fmt.Errorf("%s was granted %q, which doesn't allow posting - %s", detail, scopes, reauthInstructions)
+	}
+	return fmt.Sprintf("%s (scopes: %s)", detail, scopes), nil
+}
+
+// canPost reports whether space-separated OAuth scopes allow posting
+// statuses.
+func canPost(scopes string) bool {
+	return mastodon.ScopesAllow(scopes, "write:statuses")
+}
+
+// neededScopes returns the OAuth scopes the features enabled in cfg use:
+// posting, looking up the account, and whatever else is configured.
+func neededScopes(cfg *config.Config) []string {
+	scopes := []string{"write:statuses", "read:accounts"}
+	if cfg.MediaAttachments {
+		scopes = append(scopes, "write:media")
+	}
+	if cfg.DedupeTimeline > 0 {
+		scopes = append(scopes, "read:statuses")
+	}
+	// Quoting, replying to, and boosting statuses first resolves them
+	// with a search
+	if cfg.StatusLinks != mastodon.StatusLinkPlain {
+		scopes = append(scopes, "read:search")
+	}
+	return scopes
+}
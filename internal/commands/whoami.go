@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	gomastodon "github.com/mattn/go-mastodon"
+	"github.com/spf13/cobra"
+)
+
+// NewWhoamiCmd creates the whoami command.
+func NewWhoamiCmd() *cobra.Command {
+	whoamiCmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Show the Mastodon account the access token belongs to",
+		Long: `Whoami checks the access token with the server and shows the account
+it belongs to, the scopes it was granted, and the server's posting limits,
+to confirm that the token stored by 'code' works before running 'post'.`,
+		Args: cobra.NoArgs,
+		RunE: runWhoami,
+	}
+
+	return whoamiCmd
+}
+
+func runWhoami(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.IsMastodon() {
+		return fmt.Errorf("whoami needs the mastodon destination, not %s", describeDestination(cfg))
+	}
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	accessToken, err := getAccessToken(ctx, cfg, db)
+	if err != nil {
+		return err
+	}
+
+	client := gomastodon.NewClient(&gomastodon.Config{
+		Server:      cfg.MastodonServer,
+		AccessToken: accessToken,
+	})
+	account, err := client.GetAccountCurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify credentials with %s: %w", cfg.MastodonServer, err)
+	}
+
+	fmt.Printf("Account: @%s (%s)\n", account.Acct, cfg.MastodonServer)
+	if account.DisplayName != "" {
+		fmt.Printf("Display Name: %s\n", account.DisplayName)
+	}
+	fmt.Printf("Profile: %s\n", account.URL)
+	fmt.Printf("Statuses: %d\n", account.StatusesCount)
+
+	// Scopes are only recorded for tokens from 'code'
+	switch scopes, err := db.Settings().Get("mastodon_token_scopes"); {
+	case tokenConfigured(cfg):
+		fmt.Println("Granted Scopes: unknown (the token is set in config)")
+	case err == nil && scopes != nil:
+		fmt.Printf("Granted Scopes: %s\n", *scopes)
+		if !canPost(*scopes) {
+			fmt.Printf("  These scopes don't allow posting - %s\n", reauthInstructions)
+		}
+	default:
+		fmt.Println("Granted Scopes: unknown")
+	}
+
+	limits, err := mastodon.DetectInstanceLimits(ctx, cfg.MastodonServer)
+	if err != nil {
+		fmt.Printf("\nPosting limits: unknown (%v)\n", err)
+		return nil
+	}
+
+	flavor, detect := configuredFlavor(cfg)
+	if detect {
+		flavor = limits.Flavor
+		cfg.ApplyInstanceFlavor(string(flavor))
+		fmt.Printf("\nServer flavor: %s (detected)\n", flavor)
+	} else {
+		fmt.Printf("\nServer flavor: %s (instance_flavor)\n", flavor)
+	}
+	if flavor != mastodon.FlavorMastodon {
+		fmt.Printf("  Visibilities: %s\n", strings.Join(flavor.Visibilities(), ", "))
+	}
+
+	fmt.Println("\nPosting limits:")
+	printLimit("Characters per status", limits.MaxCharacters)
+	printLimit("Media per status", limits.MaxMediaAttachments)
+	if limits.ImageSizeLimit > 0 {
+		fmt.Printf("  Image size: %.1f MB\n", float64(limits.ImageSizeLimit)/(1024*1024))
+	} else {
+		fmt.Println("  Image size: not reported")
+	}
+	if limits.VideoSizeLimit > 0 {
+		fmt.Printf("  Video size: %.1f MB\n", float64(limits.VideoSizeLimit)/(1024*1024))
+	}
+	if len(limits.SupportedMimeTypes) > 0 {
+		fmt.Printf("  Media types: %d supported\n", len(limits.SupportedMimeTypes))
+	}
+
+	// What posting will actually use, as post does
+	if cfg.DetectInstanceLimits {
+		cfg.ApplyInstanceLimits(limits.MaxCharacters, limits.ImageSizeLimit, limits.VideoSizeLimit)
+		fmt.Printf("\nPosts will use a %d character limit\n", cfg.CharacterLimit)
+	} else {
+		fmt.Printf("\nPosts will use the configured %d character limit (detect_instance_limits is off)\n", cfg.CharacterLimit)
+	}
+
+	return nil
+}
+
+// printLimit prints a limit reported by the server.
+func printLimit(name string, limit int) {
+	if limit > 0 {
+		fmt.Printf("  %s: %d\n", name, limit)
+	} else {
+		fmt.Printf("  %s: not reported\n", name)
+	}
+}
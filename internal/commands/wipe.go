@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/config"
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/lock"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/lorchard/feed-to-mastodon/internal/secret"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	wipeYes            bool
+	wipeNoRevoke       bool
+	wipeDeleteStatuses bool
+)
+
+// NewWipeCmd creates the wipe command.
+func NewWipeCmd() *cobra.Command {
+	wipeCmd := &cobra.Command{
+		Use:   "wipe",
+		Short: "Delete all local data and revoke the access token",
+		Long: `Wipe decommissions a feed-to-mastodon project by:
+- With --delete-statuses, deleting every status the bot posted that the
+  database knows of from Mastodon
+- Revoking the Mastodon access token (requires client credentials)
+- Deleting all entries, and the fetch and post history, from the database
+- Deleting all stored settings, including the access token (also from the
+  OS keyring, with token_storage set to keyring)
+
+If a status can't be deleted, nothing is wiped, so running wipe again
+picks up where it stopped. Mastodon only allows 30 deletes every 30
+minutes, so deleting a long history takes a while. Statuses that were
+scheduled rather than posted directly, and cross-posts to other accounts,
+are left alone.
+
+You will be asked to confirm before anything is deleted. Use --yes to
+skip the confirmation, for example in scripts.`,
+		RunE: runWipe,
+	}
+
+	wipeCmd.Flags().BoolVarP(&wipeYes, "yes", "y", false, "skip the confirmation prompt")
+	wipeCmd.Flags().BoolVar(&wipeNoRevoke, "no-revoke", false, "don't revoke the access token")
+	wipeCmd.Flags().BoolVar(&wipeDeleteStatuses, "delete-statuses", false, "delete the bot's statuses from Mastodon first")
+
+	return wipeCmd
+}
+
+func runWipe(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Don't wipe the database out from under a fetch, post, or daemon run
+	runLock, err := lockRun(cmd.Context(), cfg, false)
+	if errors.Is(err, lock.ErrLocked) {
+		return fmt.Errorf("can't wipe while another run is using the database: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock database: %w", err)
+	}
+	defer runLock.Release()
+
+	// Open database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	total, _, _, err := db.GetStats()
+	if err != nil {
+		return fmt.Errorf("failed to get database stats: %w", err)
+	}
+
+	var statuses []database.PostedStatus
+	if wipeDeleteStatuses {
+		if !cfg.IsMastodon() {
+			return fmt.Errorf("--delete-statuses only works with the mastodon destination")
+		}
+		statuses, err = db.GetPostedStatuses()
+		if err != nil {
+			return err
+		}
+	}
+
+	if !wipeYes {
+		if wipeDeleteStatuses {
+			fmt.Printf("This will delete the statuses of %d entries from %s, and ", len(statuses), cfg.MastodonServer)
+		} else {
+			fmt.Print("This will ")
+		}
+		fmt.Printf("delete %d entries and all settings from %s", total, cfg.DatabasePath)
+		if !wipeNoRevoke {
+			fmt.Print(" and revoke the access token")
+		}
+		fmt.Println(".")
+		fmt.Print("Type 'wipe' to confirm: ")
+
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		if strings.TrimSpace(answer) != "wipe" {
+			fmt.Println("Aborted, nothing was deleted")
+			return nil
+		}
+	}
+
+	// Delete the statuses while their IDs and the token are still around
+	if len(statuses) > 0 {
+		deleted, err := deleteStatuses(cmd.Context(), cfg, db, statuses)
+		fmt.Printf("Deleted the statuses of %d of %d entries\n", deleted, len(statuses))
+		if err != nil {
+			return fmt.Errorf("%w - nothing was wiped, run wipe again to continue", err)
+		}
+	}
+
+	// Revoke the token before wiping, since the stored token is wiped too
+	if !wipeNoRevoke {
+		revokeAccessToken(cmd.Context(), cfg, db)
+	}
+
+	entries, settings, err := db.Wipe()
+	if err != nil {
+		return fmt.Errorf("failed to wipe database: %w", err)
+	}
+
+	if cfg.TokenStorage == "keyring" {
+		if err := secret.Delete(keyringAccount(cfg)); err != nil {
+			logrus.Warnf("Failed to remove access token from keyring: %v", err)
+		}
+	}
+
+	fmt.Printf("\nDeleted %d entries and %d settings\n", entries, settings)
+
+	return nil
+}
+
+// deleteStatuses deletes entries' statuses from Mastodon, with their
+// thread replies, recording each one as deleted so an interrupted wipe
+// doesn't try it again. Returns the number of entries whose statuses were
+// deleted, stopping at the first status that can't be deleted.
+func deleteStatuses(ctx context.Context, cfg *config.Config, db *database.DB, statuses []database.PostedStatus) (int, error) {
+	accessToken, err := getAccessToken(ctx, cfg, db)
+	if err != nil {
+		return 0, fmt.Errorf("authentication required: %w", err)
+	}
+
+	flavor := detectFlavor(ctx, cfg)
+	poster, err := mastodon.NewWithFlavor(cfg.MastodonServer, accessToken, cfg.PostVisibility, cfg.ContentWarning, flavor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create Mastodon poster: %w", err)
+	}
+
+	deleted := 0
+	for _, status := range statuses {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		if err := deleteEntryStatuses(ctx, poster, db, status.EntryID, status.StatusID, false); err != nil {
+			if errors.Is(err, mastodon.ErrUnauthorized) {
+				return deleted, rejectedTokenError(cfg, err)
+			}
+			return deleted, fmt.Errorf("failed to delete status of entry %s: %w", status.EntryID, err)
+		}
+		deleted++
+
+		if err := db.RecordStatusDeleted(status, false); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// revokeAccessToken revokes the current access token, logging rather than
+// failing when it can't, so that a wipe can still proceed.
+func revokeAccessToken(ctx context.Context, cfg *config.Config, db *database.DB) {
+	accessToken, err := getAccessToken(ctx, cfg, db)
+	if err != nil {
+		logrus.Infof("No access token to revoke")
+		return
+	}
+
+	if cfg.MastodonClientID == "" || cfg.MastodonClientSecret == "" {
+		logrus.Warn("Cannot revoke access token without mastodon_client_id and mastodon_client_secret")
+		logrus.Warn("Revoke it manually under Settings > Account > Authorized apps")
+		return
+	}
+
+	err = mastodon.RevokeToken(ctx, cfg.MastodonServer, cfg.MastodonClientID, cfg.MastodonClientSecret, accessToken)
+	if err != nil {
+		logrus.Warnf("Failed to revoke access token: %v", err)
+		return
+	}
+
+	fmt.Println("Revoked access token")
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lorchard/feed-to-mastodon/internal/workspace"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NewWorkspacesCmd creates the workspaces command and its subcommands.
+func NewWorkspacesCmd() *cobra.Command {
+	workspacesCmd := &cobra.Command{
+		Use:   "workspaces",
+		Short: "Manage named project directories",
+		Long: `Workspaces let one installed binary operate several independent
+feed-to-mastodon projects. Register a project directory under a name,
+then select it with the global --workspace flag:
+
+  feed-to-mastodon workspaces add blogbot /srv/bots/blog
+  feed-to-mastodon -w blogbot post
+
+The workspace registry is stored in the user config directory.
+
+For projects kept in the user config directory itself, use the global
+--profile flag instead, which needs no registration:
+
+  feed-to-mastodon --profile blogbot init
+  feed-to-mastodon -p blogbot post`,
+	}
+
+	workspacesCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List registered workspaces",
+		Args:  cobra.NoArgs,
+		RunE:  runWorkspacesList,
+	})
+
+	workspacesCmd.AddCommand(&cobra.Command{
+		Use:   "add <name> [directory]",
+		Short: "Register a project directory (default: current directory)",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE:  runWorkspacesAdd,
+	})
+
+	workspacesCmd.AddCommand(&cobra.Command{
+		Use:   "remove <name>",
+		Short: "Unregister a workspace (the directory is not deleted)",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runWorkspacesRemove,
+	})
+
+	return workspacesCmd
+}
+
+func loadWorkspaces() (*workspace.Registry, error) {
+	path, err := workspace.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return workspace.Load(path)
+}
+
+func runWorkspacesList(cmd *cobra.Command, args []string) error {
+	registry, err := loadWorkspaces()
+	if err != nil {
+		return err
+	}
+
+	names := registry.Names()
+	if len(names) == 0 {
+		fmt.Println("No workspaces registered")
+		fmt.Println("\nRun 'feed-to-mastodon workspaces add <name> [directory]' to register one")
+		return nil
+	}
+
+	for _, name := range names {
+		dir := registry.Workspaces[name]
+		note := ""
+		if _, err := os.Stat(filepath.Join(dir, "feed-to-mastodon.yaml")); err != nil {
+			note = " (no feed-to-mastodon.yaml)"
+		}
+		fmt.Printf("%s\t%s%s\n", name, dir, note)
+	}
+
+	return nil
+}
+
+func runWorkspacesAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	dir := "."
+	if len(args) > 1 {
+		dir = args[1]
+	}
+
+	registry, err := loadWorkspaces()
+	if err != nil {
+		return err
+	}
+
+	if err := registry.Add(name, dir); err != nil {
+		return fmt.Errorf("failed to add workspace: %w", err)
+	}
+
+	if err := registry.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Registered workspace %s: %s\n", name, registry.Workspaces[name])
+	return nil
+}
+
+func runWorkspacesRemove(cmd *cobra.Command, args []string) error {
+	registry, err := loadWorkspaces()
+	if err != nil {
+		return err
+	}
+
+	if err := registry.Remove(args[0]); err != nil {
+		return err
+	}
+
+	if err := registry.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed workspace %s\n", args[0])
+	return nil
+}
+
+// enterWorkspace changes into the named workspace's project directory so
+// that relative config, template, and database paths resolve there.
+func enterWorkspace(name string) error {
+	registry, err := loadWorkspaces()
+	if err != nil {
+		return err
+	}
+
+	dir, err := registry.Get(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("failed to enter workspace %s: %w", name, err)
+	}
+
+	logrus.Debugf("Using workspace %s: %s", name, dir)
+	return nil
+}
+
+// enterProfile changes into the named profile's directory, like
+// enterWorkspace does, creating it first for init.
+func enterProfile(name string, create bool) error {
+	dir, err := workspace.ProfileDir(name)
+	if err != nil {
+		return err
+	}
+
+	if create {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create profile %s: %w", name, err)
+		}
+	} else if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("unknown profile: %s (create it with 'feed-to-mastodon --profile %s init')", name, name)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("failed to enter profile %s: %w", name, err)
+	}
+
+	logrus.Debugf("Using profile %s: %s", name, dir)
+	return nil
+}
@@ -2,7 +2,13 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -12,26 +18,374 @@ type Config struct {
 	FeedURL              string
 	MastodonServer       string
 	MastodonAccessToken  string
+	MastodonTokenCommand string
+	TokenStorage         string
+	SecretEncryption     string
 	MastodonClientID     string
 	MastodonClientSecret string
+	OAuthScopes          string
 	TemplateFile         string
 	DatabasePath         string
+	SQLiteBusyTimeout    time.Duration
+	SQLiteSynchronous    string
+	SQLiteCacheSize      int
 	CharacterLimit       int
+	SplitLongPosts       bool
+	CharacterLimitMode   string
+	UpdateEdited         bool
 	MaxItems             int
+	PostOrder            string
+	PostInterval         time.Duration
+	ScheduleSpread       time.Duration
+	PostWindow           string
+	PostDays             []string
+	MaxEntryAge          time.Duration
+	InitialMode          string
+	InitialPosts         int
+	PurgeMode            string
+	PurgePosted          bool
+	FetchTimeout         time.Duration
+	FetchRetries         int
+	FetchRetryBackoff    time.Duration
+	HTTPProxy            string
+	UserAgent            string
+	FetchHeaders         map[string]string
+	SourceType           string
+	JSONMapping          JSONMapping
+	TemplateVars         map[string]string
+	EscapeMentions       bool
+	FeedUsername         string
+	FeedPassword         string
+	ExtractContent       bool
+	ExpireAfter          time.Duration
+	DedupeTimeline       int
 	PostVisibility       string
+	VisibilityRules      []VisibilityRule
 	ContentWarning       string
+	ContentWarnings      []ContentWarningRule
+	CWTemplate           string
+	StatusLinks          string
+	ThreadAnchor         string
+	ContinueThread       bool
+	MediaAttachments     bool
+	MediaMaxBytes        int64
+	MediaFirstImage      bool
+	MediaAudioVideo      bool
+	MediaMaxAVBytes      int64
+	MediaDownscale       bool
+	MediaMaxDimension    int
+	MediaStripMetadata   bool
+	MediaFocus           string
+	SensitiveMedia       bool
+	SensitiveCategories  []string
+	AltTextTemplate      string
+	URLRewrites          []URLRewrite
+	CleanURLs            bool
+	ResolveRedirects     []string
+	LinkShortener        LinkShortener
+	ArchiveLinks         bool
+	ArchiveSave          bool
+	ArchiveLabel         string
+	Templates            []TemplateRule
+	Hashtags             []HashtagMapping
+	Timezone             string
+	Filters              Filters
+	DaemonInterval       time.Duration
+	HealthListen         string
+	AdminToken           string
+	Dashboard            bool
+	OutageThreshold      int
+	OutageCooldown       time.Duration
+	MaxPostAttempts      int
+	RetryBackoff         time.Duration
+	DetectInstanceLimits bool
+	InstanceFlavor       string
+	Destination          Destination
+	Accounts             []Account
+	Bluesky              Bluesky
+	ChatWebhooks         []ChatWebhook
+	Notify               Notify
+	Hooks                Hooks
+
+	// characterLimitSet records whether character_limit was configured
+	// explicitly, rather than coming from the default.
+	characterLimitSet bool
+}
+
+// URLRewrite describes a rule for rewriting links in posts, e.g. to send
+// youtube.com links to an Invidious instance instead.
+type URLRewrite struct {
+	// From is the host to match. Subdomains of the host also match.
+	From string `mapstructure:"from"`
+	// To is the replacement host, optionally with a scheme (https://example.com).
+	To string `mapstructure:"to"`
+}
+
+// LinkShortener shortens the links in posts through a URL shortener's
+// API, like is.gd, YOURLS, or Shlink.
+type LinkShortener struct {
+	// URL is requested with GET to shorten a link, with {url} in it
+	// replaced by the escaped link. Shortening is off unless it's set.
+	URL string `mapstructure:"url"`
+	// ResponseField, for APIs that respond with JSON, is the dotted path
+	// of the short link in the response, like shorturl or data.link.
+	// Without it, the response is the short link as text.
+	ResponseField string `mapstructure:"response_field"`
+	// Headers are extra HTTP headers to send, like an API key.
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// TemplateRule selects a different template file for some entries, e.g.
+// to post podcast episodes differently from blog posts.
+type TemplateRule struct {
+	// MatchCategory, if set, must be one of the entry's categories.
+	MatchCategory string `mapstructure:"match_category"`
+	// MatchFeed, if set, must be the feed's title, link, or feed URL.
+	MatchFeed string `mapstructure:"match_feed"`
+	// Path is the template file to use for matching entries.
+	Path string `mapstructure:"path"`
+}
+
+// HashtagMapping sets the hashtag the template hashtag function uses for
+// a feed category.
+type HashtagMapping struct {
+	// Category is the feed category to match, ignoring case.
+	Category string `mapstructure:"category"`
+	// Tag is the preferred hashtag. An empty tag drops the category.
+	Tag string `mapstructure:"tag"`
+}
+
+// VisibilityRule sets the visibility of entries matching a regex or with a
+// category, e.g. to post some entries unlisted.
+type VisibilityRule struct {
+	// MatchRegex, if set, must match the title, categories, or content.
+	MatchRegex string `mapstructure:"match_regex"`
+	// MatchCategory, if set, must be one of the entry's categories.
+	MatchCategory string `mapstructure:"match_category"`
+	// Visibility is the visibility for matching entries.
+	Visibility string `mapstructure:"visibility"`
+}
+
+// ContentWarningRule sets the content warning for entries with a category
+// or a title matching a regex, e.g. "politics" for entries tagged politics.
+type ContentWarningRule struct {
+	// MatchCategory, if set, must be one of the entry's categories.
+	MatchCategory string `mapstructure:"match_category"`
+	// MatchTitle, if set, is a regex the entry's title must match.
+	MatchTitle string `mapstructure:"match_title"`
+	// Text is the content warning for matching entries.
+	Text string `mapstructure:"text"`
+}
+
+// Destination selects where posts are published, for posting somewhere
+// other than Mastodon.
+type Destination struct {
+	// Type is mastodon (the default), stdout, file, directory, or webhook.
+	Type string `mapstructure:"type"`
+	// Path is the file to append posts to, or the directory to write them to.
+	Path string `mapstructure:"path"`
+	// URL is the webhook to POST posts to.
+	URL string `mapstructure:"url"`
+	// Headers are extra HTTP headers to send to the webhook.
+	Headers map[string]string `mapstructure:"headers"`
 }
 
+// Account is another Mastodon account to cross-post entries to, after
+// they're posted to the main account.
+type Account struct {
+	// Name identifies the account in the database and in output.
+	Name string `mapstructure:"name"`
+	// Server is the account's Mastodon server URL.
+	Server string `mapstructure:"server"`
+	// Token is an access token for the account.
+	Token string `mapstructure:"token"`
+	// Categories, if set, limits cross-posting to entries with one of
+	// these categories.
+	Categories []string `mapstructure:"categories"`
+}
+
+// Bluesky configures posting entries to Bluesky as well as Mastodon.
+type Bluesky struct {
+	// Handle is the Bluesky account to post as. Posting to Bluesky is off
+	// unless it's set.
+	Handle string `mapstructure:"handle"`
+	// Server is the account's PDS. Defaults to https://bsky.social.
+	Server string `mapstructure:"server"`
+	// AppPassword logs in on each run, instead of the session stored by
+	// 'bluesky login'.
+	AppPassword string `mapstructure:"app_password"`
+	// TemplatePath, if set, is the template for Bluesky posts, instead of
+	// template_path.
+	TemplatePath string `mapstructure:"template_path"`
+}
+
+// ChatWebhook announces entries in a Discord or Slack channel, after
+// they're posted to Mastodon.
+type ChatWebhook struct {
+	// Name identifies the webhook in the database and in output.
+	Name string `mapstructure:"name"`
+	// Type is discord or slack.
+	Type string `mapstructure:"type"`
+	// URL is the channel's incoming webhook URL.
+	URL string `mapstructure:"url"`
+	// TemplatePath, if set, is the template for messages, instead of
+	// template_path.
+	TemplatePath string `mapstructure:"template_path"`
+	// Categories, if set, limits announcements to entries with one of
+	// these categories.
+	Categories []string `mapstructure:"categories"`
+}
+
+// JSONMapping describes how to read entries from a JSON API's response,
+// for source_type json. Each field is a path into the JSON, like
+// .data.items or .links[0].href, optionally with alternatives separated
+// by //.
+type JSONMapping struct {
+	// Items is the path of the array of items; empty if the response is
+	// the array.
+	Items string `mapstructure:"items"`
+	// ID, Title, Link, Date, Description, Content, and Author are paths
+	// within each item.
+	ID          string `mapstructure:"id"`
+	Title       string `mapstructure:"title"`
+	Link        string `mapstructure:"link"`
+	Date        string `mapstructure:"date"`
+	Description string `mapstructure:"description"`
+	Content     string `mapstructure:"content"`
+	Author      string `mapstructure:"author"`
+	// FeedTitle is the path of the feed's title in the response.
+	FeedTitle string `mapstructure:"feed_title"`
+}
+
+// Notify tells an admin when fetching or posting keeps failing.
+type Notify struct {
+	// Account is a Mastodon account, like @admin@example.social, to send a
+	// direct message to from the posting account.
+	Account string `mapstructure:"account"`
+	// Webhook is a URL to POST a JSON description of the failure to.
+	Webhook string `mapstructure:"webhook"`
+	// After is how many fetches or post runs in a row must fail before
+	// notifying. Defaults to 3.
+	After int `mapstructure:"after"`
+}
+
+// Hooks are shell commands run around posting each entry. Each gets a
+// JSON description of the entry and its post on stdin, and the same in
+// FEED_TO_MASTODON_* environment variables.
+type Hooks struct {
+	// PrePost runs before an entry is posted, and holds it back by
+	// exiting with status 1.
+	PrePost string `mapstructure:"pre_post"`
+	// PostSuccess runs after an entry is posted.
+	PostSuccess string `mapstructure:"post_success"`
+	// PostFailure runs after an entry fails to post.
+	PostFailure string `mapstructure:"post_failure"`
+	// Timeout is how long a hook may run. Defaults to 30s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// notifyAccountPattern matches a full Mastodon account address, with or
+// without the leading @.
+var notifyAccountPattern = regexp.MustCompile(`^@?[A-Za-z0-9_]+(\.[A-Za-z0-9_-]+)*@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`)
+
+// defaultNotifyAfter is the number of failures in a row to notify after
+// when notify.after isn't set.
+const defaultNotifyAfter = 3
+
+// Enabled reports whether failure notifications are configured.
+func (n Notify) Enabled() bool {
+	return n.Account != "" || n.Webhook != ""
+}
+
+// Filters holds rules for holding back entries that shouldn't be posted.
+type Filters struct {
+	// IncludeRegex, if set, must match the title, categories, or content.
+	IncludeRegex string `mapstructure:"include_regex"`
+	// ExcludeRegex holds back entries whose title, categories, or content match.
+	ExcludeRegex string `mapstructure:"exclude_regex"`
+	// IncludeCategories, if set, is an allowlist of categories.
+	IncludeCategories []string `mapstructure:"include_categories"`
+	// ExcludeCategories is a denylist of categories.
+	ExcludeCategories []string `mapstructure:"exclude_categories"`
+}
+
+// defaultCharacterLimit is used when character_limit isn't set and the
+// server's limit isn't known.
+const defaultCharacterLimit = 500
+
+// flavorCharacterLimits are the default character limits of server
+// flavors other than Mastodon, used instead of defaultCharacterLimit.
+var flavorCharacterLimits = map[string]int{
+	"gotosocial": 5000,
+	"akkoma":     5000,
+	"pleroma":    5000,
+}
+
+// minAdminTokenLength keeps the daemon's admin API from being guarded by a
+// guessable token.
+const minAdminTokenLength = 16
+
 // LoadConfig loads configuration from file and environment variables.
 // If configFile is not empty, it will be used; otherwise default locations are searched.
 func LoadConfig(configFile string) (*Config, error) {
 	// Set defaults
 	viper.SetDefault("template_path", "post-template.txt")
+	viper.SetDefault("oauth_scopes", "write:statuses write:media read:accounts")
+	viper.SetDefault("token_storage", "database")
+	viper.SetDefault("secret_encryption", "off")
 	viper.SetDefault("database_path", "feed-to-mastodon.db")
-	viper.SetDefault("character_limit", 500)
+	viper.SetDefault("sqlite_busy_timeout", "5s")
+	viper.SetDefault("sqlite_synchronous", "normal")
+	viper.SetDefault("sqlite_cache_size", 0)
+	viper.SetDefault("split_long_posts", false)
+	viper.SetDefault("character_limit_mode", "warn")
+	viper.SetDefault("escape_mentions", true)
+	viper.SetDefault("update_edited", false)
 	viper.SetDefault("posts_per_run", 0)
+	viper.SetDefault("post_order", "oldest")
+	viper.SetDefault("post_interval", "0s")
+	viper.SetDefault("schedule_spread", "0s")
+	viper.SetDefault("max_entry_age", "0s")
+	viper.SetDefault("initial_mode", "post_all")
+	viper.SetDefault("initial_posts", 1)
+	viper.SetDefault("purge", "stale")
+	viper.SetDefault("purge_posted", true)
+	viper.SetDefault("expire_after", "0s")
+	viper.SetDefault("dedupe_timeline", 0)
+	viper.SetDefault("source_type", "feed")
+	viper.SetDefault("fetch_timeout", "30s")
+	viper.SetDefault("fetch_retries", 2)
+	viper.SetDefault("fetch_retry_backoff", "5s")
+	viper.SetDefault("extract_content", false)
+	viper.SetDefault("clean_urls", false)
+	viper.SetDefault("archive_links", false)
+	viper.SetDefault("archive_save", false)
+	viper.SetDefault("archive_label", "Archived:")
 	viper.SetDefault("post_visibility", "public")
 	viper.SetDefault("content_warning", "")
+	viper.SetDefault("status_links", "link")
+	viper.SetDefault("thread_anchor", "")
+	viper.SetDefault("continue_thread", false)
+	viper.SetDefault("media_attachments", false)
+	viper.SetDefault("media_max_bytes", 8*1024*1024)
+	viper.SetDefault("media_first_image", false)
+	viper.SetDefault("media_audio_video", false)
+	viper.SetDefault("media_max_audio_video_bytes", 40*1024*1024)
+	viper.SetDefault("media_downscale", false)
+	viper.SetDefault("media_max_dimension", 0)
+	viper.SetDefault("media_strip_metadata", false)
+	viper.SetDefault("media_focus", "")
+	viper.SetDefault("sensitive_media", false)
+	viper.SetDefault("daemon_interval", "15m")
+	viper.SetDefault("health_listen", "")
+	viper.SetDefault("admin_token", "")
+	viper.SetDefault("dashboard", false)
+	viper.SetDefault("outage_threshold", 3)
+	viper.SetDefault("outage_cooldown", "15m")
+	viper.SetDefault("max_post_attempts", 5)
+	viper.SetDefault("retry_backoff", "15m")
+	viper.SetDefault("detect_instance_limits", true)
+	viper.SetDefault("instance_flavor", "auto")
 
 	// Configure config file
 	if configFile != "" {
@@ -63,44 +417,617 @@ func LoadConfig(configFile string) (*Config, error) {
 		FeedURL:              viper.GetString("feed_url"),
 		MastodonServer:       viper.GetString("mastodon_server"),
 		MastodonAccessToken:  viper.GetString("mastodon_token"),
+		MastodonTokenCommand: viper.GetString("mastodon_token_command"),
+		TokenStorage:         viper.GetString("token_storage"),
+		SecretEncryption:     viper.GetString("secret_encryption"),
 		MastodonClientID:     viper.GetString("mastodon_client_id"),
 		MastodonClientSecret: viper.GetString("mastodon_client_secret"),
+		OAuthScopes:          viper.GetString("oauth_scopes"),
 		TemplateFile:         viper.GetString("template_path"),
 		DatabasePath:         viper.GetString("database_path"),
+		SQLiteBusyTimeout:    viper.GetDuration("sqlite_busy_timeout"),
+		SQLiteSynchronous:    viper.GetString("sqlite_synchronous"),
+		SQLiteCacheSize:      viper.GetInt("sqlite_cache_size"),
 		CharacterLimit:       viper.GetInt("character_limit"),
+		SplitLongPosts:       viper.GetBool("split_long_posts"),
+		CharacterLimitMode:   viper.GetString("character_limit_mode"),
+		UpdateEdited:         viper.GetBool("update_edited"),
 		MaxItems:             viper.GetInt("posts_per_run"),
+		PostOrder:            viper.GetString("post_order"),
+		PostInterval:         viper.GetDuration("post_interval"),
+		ScheduleSpread:       viper.GetDuration("schedule_spread"),
+		PostWindow:           viper.GetString("post_window"),
+		PostDays:             viper.GetStringSlice("post_days"),
+		MaxEntryAge:          viper.GetDuration("max_entry_age"),
+		InitialMode:          viper.GetString("initial_mode"),
+		InitialPosts:         viper.GetInt("initial_posts"),
+		PurgeMode:            viper.GetString("purge"),
+		PurgePosted:          viper.GetBool("purge_posted"),
+		ExpireAfter:          viper.GetDuration("expire_after"),
+		DedupeTimeline:       viper.GetInt("dedupe_timeline"),
+		FetchTimeout:         viper.GetDuration("fetch_timeout"),
+		FetchRetries:         viper.GetInt("fetch_retries"),
+		FetchRetryBackoff:    viper.GetDuration("fetch_retry_backoff"),
+		HTTPProxy:            viper.GetString("http_proxy"),
+		UserAgent:            viper.GetString("user_agent"),
+		FetchHeaders:         viper.GetStringMapString("fetch_headers"),
+		SourceType:           viper.GetString("source_type"),
+		TemplateVars:         viper.GetStringMapString("template_vars"),
+		EscapeMentions:       viper.GetBool("escape_mentions"),
+		FeedUsername:         viper.GetString("feed_username"),
+		FeedPassword:         viper.GetString("feed_password"),
+		CleanURLs:            viper.GetBool("clean_urls"),
+		ResolveRedirects:     viper.GetStringSlice("resolve_redirects"),
+		ArchiveLinks:         viper.GetBool("archive_links"),
+		ArchiveSave:          viper.GetBool("archive_save"),
+		ArchiveLabel:         viper.GetString("archive_label"),
+		ExtractContent:       viper.GetBool("extract_content"),
 		PostVisibility:       viper.GetString("post_visibility"),
 		ContentWarning:       viper.GetString("content_warning"),
+		CWTemplate:           viper.GetString("cw_template"),
+		StatusLinks:          viper.GetString("status_links"),
+		ThreadAnchor:         viper.GetString("thread_anchor"),
+		ContinueThread:       viper.GetBool("continue_thread"),
+		Timezone:             viper.GetString("timezone"),
+		MediaAttachments:     viper.GetBool("media_attachments"),
+		MediaMaxBytes:        viper.GetInt64("media_max_bytes"),
+		MediaFirstImage:      viper.GetBool("media_first_image"),
+		MediaAudioVideo:      viper.GetBool("media_audio_video"),
+		MediaMaxAVBytes:      viper.GetInt64("media_max_audio_video_bytes"),
+		MediaDownscale:       viper.GetBool("media_downscale"),
+		MediaMaxDimension:    viper.GetInt("media_max_dimension"),
+		MediaStripMetadata:   viper.GetBool("media_strip_metadata"),
+		MediaFocus:           viper.GetString("media_focus"),
+		SensitiveMedia:       viper.GetBool("sensitive_media"),
+		SensitiveCategories:  viper.GetStringSlice("sensitive_categories"),
+		AltTextTemplate:      viper.GetString("alt_text_template"),
+		DaemonInterval:       viper.GetDuration("daemon_interval"),
+		HealthListen:         viper.GetString("health_listen"),
+		AdminToken:           viper.GetString("admin_token"),
+		Dashboard:            viper.GetBool("dashboard"),
+		OutageThreshold:      viper.GetInt("outage_threshold"),
+		OutageCooldown:       viper.GetDuration("outage_cooldown"),
+		MaxPostAttempts:      viper.GetInt("max_post_attempts"),
+		RetryBackoff:         viper.GetDuration("retry_backoff"),
+		DetectInstanceLimits: viper.GetBool("detect_instance_limits"),
+		InstanceFlavor:       strings.ToLower(viper.GetString("instance_flavor")),
+		characterLimitSet:    viper.IsSet("character_limit"),
+	}
+
+	// character_limit has no viper default, so that an explicit setting can
+	// be told apart from the default
+	if !cfg.characterLimitSet {
+		cfg.CharacterLimit = defaultCharacterLimit
+	}
+	cfg.ApplyInstanceFlavor(cfg.InstanceFlavor)
+
+	// Load URL rewrite rules
+	if err := viper.UnmarshalKey("url_rewrites", &cfg.URLRewrites); err != nil {
+		return nil, fmt.Errorf("invalid url_rewrites: %w", err)
+	}
+
+	// Load the link shortener
+	if err := viper.UnmarshalKey("link_shortener", &cfg.LinkShortener); err != nil {
+		return nil, fmt.Errorf("invalid link_shortener: %w", err)
+	}
+
+	// Load per-entry template rules
+	if err := viper.UnmarshalKey("templates", &cfg.Templates); err != nil {
+		return nil, fmt.Errorf("invalid templates: %w", err)
+	}
+
+	// Load category to hashtag mappings
+	if err := viper.UnmarshalKey("hashtags", &cfg.Hashtags); err != nil {
+		return nil, fmt.Errorf("invalid hashtags: %w", err)
+	}
+
+	// Load per-entry visibility rules
+	if err := viper.UnmarshalKey("visibility_rules", &cfg.VisibilityRules); err != nil {
+		return nil, fmt.Errorf("invalid visibility_rules: %w", err)
+	}
+
+	// Load content warning rules
+	if err := viper.UnmarshalKey("content_warnings", &cfg.ContentWarnings); err != nil {
+		return nil, fmt.Errorf("invalid content_warnings: %w", err)
+	}
+
+	// Load entry filters
+	if err := viper.UnmarshalKey("filters", &cfg.Filters); err != nil {
+		return nil, fmt.Errorf("invalid filters: %w", err)
+	}
+
+	// Load the posting destination
+	if err := viper.UnmarshalKey("destination", &cfg.Destination); err != nil {
+		return nil, fmt.Errorf("invalid destination: %w", err)
+	}
+
+	// Load the accounts to cross-post to
+	if err := viper.UnmarshalKey("accounts", &cfg.Accounts); err != nil {
+		return nil, fmt.Errorf("invalid accounts: %w", err)
 	}
 
+	// Load Bluesky settings
+	if err := viper.UnmarshalKey("bluesky", &cfg.Bluesky); err != nil {
+		return nil, fmt.Errorf("invalid bluesky: %w", err)
+	}
+
+	// Load Discord and Slack webhooks
+	if err := viper.UnmarshalKey("chat_webhooks", &cfg.ChatWebhooks); err != nil {
+		return nil, fmt.Errorf("invalid chat_webhooks: %w", err)
+	}
+
+	// Load the JSON API mapping
+	if err := viper.UnmarshalKey("json_mapping", &cfg.JSONMapping); err != nil {
+		return nil, fmt.Errorf("invalid json_mapping: %w", err)
+	}
+
+	// Load failure notification settings
+	if err := viper.UnmarshalKey("notify", &cfg.Notify); err != nil {
+		return nil, fmt.Errorf("invalid notify: %w", err)
+	}
+	if cfg.Notify.After == 0 {
+		cfg.Notify.After = defaultNotifyAfter
+	}
+
+	// Load the hooks run around posting
+	if err := viper.UnmarshalKey("hooks", &cfg.Hooks); err != nil {
+		return nil, fmt.Errorf("invalid hooks: %w", err)
+	}
+
+	// Relative paths are relative to the config file, so runs from cron
+	// find the same files as runs from the project directory
+	baseDir := ""
+	if used := viper.ConfigFileUsed(); used != "" {
+		baseDir = filepath.Dir(used)
+	}
+	cfg.resolvePaths(baseDir)
+
 	return cfg, nil
 }
 
+// ApplyInstanceLimits adjusts the configuration to limits reported by the
+// Mastodon server. The server's character limit replaces the default unless
+// character_limit was set explicitly, and its image and video size limits
+// cap media_max_bytes and media_max_audio_video_bytes. Zero limits are
+// ignored.
+func (c *Config) ApplyInstanceLimits(maxCharacters int, imageSizeLimit, videoSizeLimit int64) {
+	if maxCharacters > 0 && !c.characterLimitSet {
+		c.CharacterLimit = maxCharacters
+	}
+	if imageSizeLimit > 0 && (c.MediaMaxBytes <= 0 || imageSizeLimit < c.MediaMaxBytes) {
+		c.MediaMaxBytes = imageSizeLimit
+	}
+	if videoSizeLimit > 0 && (c.MediaMaxAVBytes <= 0 || videoSizeLimit < c.MediaMaxAVBytes) {
+		c.MediaMaxAVBytes = videoSizeLimit
+	}
+}
+
+// ApplyInstanceFlavor adjusts the configuration to the flavor of the
+// server, detected or configured: servers other than Mastodon default to
+// a longer character limit, unless character_limit was set explicitly.
+func (c *Config) ApplyInstanceFlavor(flavor string) {
+	if limit, ok := flavorCharacterLimits[flavor]; ok && !c.characterLimitSet {
+		c.CharacterLimit = limit
+	}
+}
+
+// resolvePaths expands ~ and environment variables in the configured file
+// paths, and makes relative paths relative to baseDir.
+func (c *Config) resolvePaths(baseDir string) {
+	paths := []*string{&c.DatabasePath, &c.TemplateFile, &c.Bluesky.TemplatePath}
+	if c.Destination.Type == "file" || c.Destination.Type == "directory" {
+		paths = append(paths, &c.Destination.Path)
+	}
+	for i := range c.Templates {
+		paths = append(paths, &c.Templates[i].Path)
+	}
+	for i := range c.ChatWebhooks {
+		paths = append(paths, &c.ChatWebhooks[i].TemplatePath)
+	}
+
+	for _, path := range paths {
+		*path = expandPath(*path, baseDir)
+	}
+}
+
+// expandPath expands a leading ~ and environment variables like $HOME in
+// path, and joins it to baseDir if it's still relative. Empty paths are
+// left empty.
+func expandPath(path, baseDir string) string {
+	if path == "" {
+		return ""
+	}
+
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+
+	if baseDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return path
+}
+
 // Validate checks that required fields are set and valid
 func (c *Config) Validate() error {
 	if c.FeedURL == "" {
 		return fmt.Errorf("feedUrl is required")
 	}
 
-	if c.MastodonServer == "" {
+	if c.IsMastodon() && c.MastodonServer == "" {
 		return fmt.Errorf("mastodonServer is required")
 	}
 
-	// Validate post visibility
+	if c.MastodonAccessToken != "" && c.MastodonTokenCommand != "" {
+		return fmt.Errorf("mastodon_token and mastodon_token_command can't both be set")
+	}
+	switch c.TokenStorage {
+	case "", "database", "keyring":
+	default:
+		return fmt.Errorf("token_storage must be one of: database, keyring")
+	}
+	switch c.SecretEncryption {
+	case "", "off", "env", "keyring":
+	default:
+		return fmt.Errorf("secret_encryption must be one of: off, env, keyring")
+	}
+
+	switch c.Destination.Type {
+	case "", "mastodon", "stdout":
+	case "file", "directory":
+		if c.Destination.Path == "" {
+			return fmt.Errorf("destination.path is required for the %s destination", c.Destination.Type)
+		}
+	case "webhook":
+		if c.Destination.URL == "" {
+			return fmt.Errorf("destination.url is required for the webhook destination")
+		}
+	default:
+		return fmt.Errorf("destination.type must be one of: mastodon, stdout, file, directory, webhook")
+	}
+
+	accountNames := make(map[string]bool)
+	for i, account := range c.Accounts {
+		if !c.IsMastodon() {
+			return fmt.Errorf("accounts can only be used with the mastodon destination")
+		}
+		if account.Name == "" || account.Server == "" || account.Token == "" {
+			return fmt.Errorf("accounts[%d] requires name, server, and token", i)
+		}
+		if account.Name == "bluesky" {
+			return fmt.Errorf("accounts[%d] can't be named bluesky, which is used for the bluesky settings", i)
+		}
+		if accountNames[account.Name] {
+			return fmt.Errorf("accounts[%d] has the same name as another account: %s", i, account.Name)
+		}
+		accountNames[account.Name] = true
+	}
+
+	if c.Bluesky.Handle != "" && !c.IsMastodon() {
+		return fmt.Errorf("bluesky can only be used with the mastodon destination")
+	}
+
+	for i, hook := range c.ChatWebhooks {
+		if !c.IsMastodon() {
+			return fmt.Errorf("chat_webhooks can only be used with the mastodon destination")
+		}
+		if hook.Name == "" || hook.URL == "" {
+			return fmt.Errorf("chat_webhooks[%d] requires name and url", i)
+		}
+		if hook.Type != "discord" && hook.Type != "slack" {
+			return fmt.Errorf("chat_webhooks[%d].type must be one of: discord, slack", i)
+		}
+		if hook.Name == "bluesky" || accountNames[hook.Name] {
+			return fmt.Errorf("chat_webhooks[%d] has the same name as another account: %s", i, hook.Name)
+		}
+		accountNames[hook.Name] = true
+	}
+
+	switch c.InstanceFlavor {
+	case "", "auto", "mastodon", "gotosocial", "akkoma", "pleroma":
+	default:
+		return fmt.Errorf("instance_flavor must be one of: auto, mastodon, gotosocial, akkoma, pleroma")
+	}
+
+	// Validate post visibility. Akkoma and Pleroma also have local, which
+	// is checked against the detected flavor when posting.
 	validVisibilities := map[string]bool{
 		"public":   true,
 		"unlisted": true,
 		"private":  true,
 		"direct":   true,
 	}
+	visibilityNames := "public, unlisted, private, direct"
+	switch c.InstanceFlavor {
+	case "", "auto", "akkoma", "pleroma":
+		validVisibilities["local"] = true
+		visibilityNames += ", local"
+	}
 
 	if !validVisibilities[c.PostVisibility] {
-		return fmt.Errorf("postVisibility must be one of: public, unlisted, private, direct")
+		return fmt.Errorf("postVisibility must be one of: %s", visibilityNames)
+	}
+
+	for i, rule := range c.VisibilityRules {
+		if !validVisibilities[rule.Visibility] {
+			return fmt.Errorf("visibility_rules[%d].visibility must be one of: %s", i, visibilityNames)
+		}
+		if rule.MatchRegex == "" && rule.MatchCategory == "" {
+			return fmt.Errorf("visibility_rules[%d] requires match_regex or match_category", i)
+		}
+		if _, err := regexp.Compile(rule.MatchRegex); err != nil {
+			return fmt.Errorf("visibility_rules[%d].match_regex is invalid: %w", i, err)
+		}
+	}
+
+	if c.PostInterval < 0 {
+		return fmt.Errorf("post_interval must not be negative")
+	}
+
+	// Mastodon rejects scheduled statuses less than 5 minutes ahead
+	if c.ScheduleSpread < 0 {
+		return fmt.Errorf("schedule_spread must not be negative")
+	}
+	if c.ScheduleSpread > 0 && c.ScheduleSpread < 5*time.Minute {
+		return fmt.Errorf("schedule_spread must be at least 5m")
+	}
+
+	if _, err := c.Window(); err != nil {
+		return err
+	}
+
+	if c.MaxEntryAge < 0 {
+		return fmt.Errorf("max_entry_age must not be negative")
+	}
+
+	if _, err := c.PurgePolicy(); err != nil {
+		return err
+	}
+
+	switch c.InitialMode {
+	case "", "post_all", "catchup":
+	case "post_latest_n":
+		if c.InitialPosts < 1 {
+			return fmt.Errorf("initial_mode post_latest_n requires initial_posts of at least 1")
+		}
+	default:
+		return fmt.Errorf("initial_mode must be one of: post_all, catchup, post_latest_n")
+	}
+
+	if c.SQLiteBusyTimeout < 0 {
+		return fmt.Errorf("sqlite_busy_timeout must not be negative")
+	}
+	switch strings.ToLower(c.SQLiteSynchronous) {
+	case "", "off", "normal", "full", "extra":
+	default:
+		return fmt.Errorf("sqlite_synchronous must be one of: off, normal, full, extra")
+	}
+	if c.SQLiteCacheSize < 0 {
+		return fmt.Errorf("sqlite_cache_size must not be negative")
+	}
+
+	if c.AdminToken != "" && len(c.AdminToken) < minAdminTokenLength {
+		return fmt.Errorf("admin_token must be at least %d characters", minAdminTokenLength)
+	}
+	if c.Dashboard && c.AdminToken == "" {
+		return fmt.Errorf("dashboard requires admin_token")
+	}
+
+	if c.FetchTimeout < 0 {
+		return fmt.Errorf("fetch_timeout must not be negative")
+	}
+
+	if c.FetchRetries < 0 {
+		return fmt.Errorf("fetch_retries must not be negative")
+	}
+	if c.FetchRetryBackoff < 0 {
+		return fmt.Errorf("fetch_retry_backoff must not be negative")
+	}
+
+	if c.FeedPassword != "" && c.FeedUsername == "" {
+		return fmt.Errorf("feed_password requires feed_username")
+	}
+
+	switch c.SourceType {
+	case "", "feed":
+	case "json":
+		mapping := c.JSONMapping
+		if mapping.ID == "" && mapping.Link == "" && mapping.Title == "" {
+			return fmt.Errorf("source_type json requires json_mapping.id, link, or title")
+		}
+	default:
+		return fmt.Errorf("source_type must be one of: feed, json")
+	}
+
+	if c.HTTPProxy != "" {
+		if proxyURL, err := url.Parse(c.HTTPProxy); err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return fmt.Errorf("http_proxy must be a URL like http://proxy.example:3128")
+		}
+	}
+
+	if c.Notify.After < 0 {
+		return fmt.Errorf("notify.after must not be negative")
+	}
+	if c.Notify.Account != "" {
+		if !c.IsMastodon() {
+			return fmt.Errorf("notify.account can only be used with the mastodon destination")
+		}
+		if !notifyAccountPattern.MatchString(c.Notify.Account) {
+			return fmt.Errorf("notify.account must be an account like @admin@example.social")
+		}
+	}
+	if c.Notify.Webhook != "" {
+		if webhookURL, err := url.Parse(c.Notify.Webhook); err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+			return fmt.Errorf("notify.webhook must be an http or https URL")
+		}
+	}
+
+	if c.Hooks.Timeout < 0 {
+		return fmt.Errorf("hooks.timeout must not be negative")
+	}
+
+	if c.DedupeTimeline < 0 {
+		return fmt.Errorf("dedupe_timeline must not be negative")
+	}
+	if c.DedupeTimeline > 0 && !c.IsMastodon() {
+		return fmt.Errorf("dedupe_timeline can only be used with the mastodon destination")
+	}
+	if c.ExpireAfter < 0 {
+		return fmt.Errorf("expire_after must not be negative")
+	}
+	if c.ExpireAfter > 0 && !c.IsMastodon() {
+		return fmt.Errorf("expire_after can only be used with the mastodon destination")
+	}
+
+	if c.MaxPostAttempts < 0 {
+		return fmt.Errorf("max_post_attempts must not be negative")
+	}
+
+	if c.RetryBackoff < 0 {
+		return fmt.Errorf("retry_backoff must not be negative")
+	}
+
+	switch c.CharacterLimitMode {
+	case "", "warn":
+	case "truncate", "fail":
+		if c.SplitLongPosts {
+			return fmt.Errorf("split_long_posts requires character_limit_mode warn")
+		}
+	default:
+		return fmt.Errorf("character_limit_mode must be one of: warn, truncate, fail")
+	}
+
+	switch c.PostOrder {
+	case "", "oldest", "newest", "random", "published":
+	default:
+		return fmt.Errorf("post_order must be one of: oldest, newest, random, published")
+	}
+
+	// Validate status link mode
+	switch c.StatusLinks {
+	case "", "link", "quote", "reply", "boost":
+	default:
+		return fmt.Errorf("status_links must be one of: link, quote, reply, boost")
+	}
+
+	// The anchor is a status ID, not the status's URL
+	if strings.ContainsAny(c.ThreadAnchor, " /:@") {
+		return fmt.Errorf("thread_anchor must be a status ID, like 109876543210987654")
+	}
+
+	if c.MediaAttachments && c.MediaMaxBytes <= 0 {
+		return fmt.Errorf("media_max_bytes must be positive when media_attachments is enabled")
+	}
+	if c.MediaAudioVideo && c.MediaMaxAVBytes <= 0 {
+		return fmt.Errorf("media_max_audio_video_bytes must be positive when media_audio_video is enabled")
+	}
+	if c.MediaMaxDimension < 0 {
+		return fmt.Errorf("media_max_dimension must not be negative")
+	}
+	if c.MediaFocus != "" && !validFocus(c.MediaFocus) {
+		return fmt.Errorf("media_focus must be x,y with both from -1.0 to 1.0, like 0.0,0.5")
+	}
+
+	// Validate filter patterns
+	if _, err := regexp.Compile(c.Filters.IncludeRegex); err != nil {
+		return fmt.Errorf("filters.include_regex is invalid: %w", err)
+	}
+	if _, err := regexp.Compile(c.Filters.ExcludeRegex); err != nil {
+		return fmt.Errorf("filters.exclude_regex is invalid: %w", err)
+	}
+
+	// Validate URL rewrite rules
+	for i, rule := range c.URLRewrites {
+		if rule.From == "" || rule.To == "" {
+			return fmt.Errorf("url_rewrites[%d] requires both from and to", i)
+		}
+	}
+
+	if c.LinkShortener.URL != "" {
+		shortenerURL, err := url.Parse(strings.ReplaceAll(c.LinkShortener.URL, "{url}", ""))
+		if err != nil || (shortenerURL.Scheme != "http" && shortenerURL.Scheme != "https") || shortenerURL.Host == "" {
+			return fmt.Errorf("link_shortener.url must be an http or https URL")
+		}
+		if !strings.Contains(c.LinkShortener.URL, "{url}") {
+			return fmt.Errorf("link_shortener.url must contain {url} where the link goes")
+		}
+	}
+	if c.ArchiveSave && !c.ArchiveLinks {
+		return fmt.Errorf("archive_save requires archive_links")
+	}
+
+	if _, err := c.Location(); err != nil {
+		return err
+	}
+
+	for i, mapping := range c.Hashtags {
+		if strings.TrimSpace(mapping.Category) == "" {
+			return fmt.Errorf("hashtags[%d] requires a category", i)
+		}
+	}
+
+	for i, rule := range c.ContentWarnings {
+		if strings.TrimSpace(rule.Text) == "" {
+			return fmt.Errorf("content_warnings[%d] requires text", i)
+		}
+		if rule.MatchCategory == "" && rule.MatchTitle == "" {
+			return fmt.Errorf("content_warnings[%d] requires match_category or match_title", i)
+		}
+		if _, err := regexp.Compile(rule.MatchTitle); err != nil {
+			return fmt.Errorf("content_warnings[%d].match_title is invalid: %w", i, err)
+		}
+	}
+
+	for i, rule := range c.Templates {
+		if rule.Path == "" {
+			return fmt.Errorf("templates[%d] requires a path", i)
+		}
+		if rule.MatchCategory == "" && rule.MatchFeed == "" {
+			return fmt.Errorf("templates[%d] requires match_category or match_feed", i)
+		}
 	}
 
 	return nil
 }
 
+// IsMastodon reports whether posts are published to Mastodon, rather than
+// another destination.
+func (c *Config) IsMastodon() bool {
+	return c.Destination.Type == "" || c.Destination.Type == "mastodon"
+}
+
+// Location returns the timezone template date functions use, or nil if
+// timezone isn't set. "Local" is the system's timezone.
+func (c *Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone is invalid: %w", err)
+	}
+	return location, nil
+}
+
+// validFocus reports whether focus is a focal point like 0.0,0.5, with
+// both coordinates from -1.0 to 1.0.
+func validFocus(focus string) bool {
+	x, y, ok := strings.Cut(focus, ",")
+	if !ok {
+		return false
+	}
+	for _, coordinate := range []string{x, y} {
+		value, err := strconv.ParseFloat(strings.TrimSpace(coordinate), 64)
+		if err != nil || value < -1 || value > 1 {
+			return false
+		}
+	}
+	return true
+}
+
 // ValidateForPosting checks that we have authentication configured for posting
 func (c *Config) ValidateForPosting() error {
 	if err := c.Validate(); err != nil {
@@ -108,7 +1035,7 @@ func (c *Config) ValidateForPosting() error {
 	}
 
 	// Must have either an access token OR client credentials
-	hasAccessToken := c.MastodonAccessToken != ""
+	hasAccessToken := c.MastodonAccessToken != "" || c.MastodonTokenCommand != ""
 	hasClientCreds := c.MastodonClientID != "" && c.MastodonClientSecret != ""
 
 	if !hasAccessToken && !hasClientCreds {
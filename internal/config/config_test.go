@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -35,12 +36,48 @@ func TestLoadConfig(t *testing.T) {
 		if cfg.CharacterLimit != 500 {
 			t.Errorf("CharacterLimit = %v, want %v", cfg.CharacterLimit, 500)
 		}
+		if cfg.characterLimitSet {
+			t.Error("characterLimitSet = true for the default character limit")
+		}
+		if !cfg.DetectInstanceLimits {
+			t.Error("DetectInstanceLimits = false, want true")
+		}
+		if cfg.UpdateEdited {
+			t.Error("UpdateEdited = true, want false")
+		}
 		if cfg.MaxItems != 0 {
 			t.Errorf("MaxItems = %v, want %v", cfg.MaxItems, 0)
 		}
 		if cfg.PostVisibility != "public" {
 			t.Errorf("PostVisibility = %v, want %v", cfg.PostVisibility, "public")
 		}
+		if cfg.OAuthScopes != "write:statuses write:media read:accounts" {
+			t.Errorf("OAuthScopes = %v, want %v", cfg.OAuthScopes, "write:statuses write:media read:accounts")
+		}
+		if cfg.DaemonInterval != 15*time.Minute {
+			t.Errorf("DaemonInterval = %v, want %v", cfg.DaemonInterval, 15*time.Minute)
+		}
+		if cfg.HealthListen != "" {
+			t.Errorf("HealthListen = %v, want empty", cfg.HealthListen)
+		}
+		if cfg.OutageThreshold != 3 {
+			t.Errorf("OutageThreshold = %v, want 3", cfg.OutageThreshold)
+		}
+		if cfg.OutageCooldown != 15*time.Minute {
+			t.Errorf("OutageCooldown = %v, want %v", cfg.OutageCooldown, 15*time.Minute)
+		}
+		if cfg.MaxPostAttempts != 5 {
+			t.Errorf("MaxPostAttempts = %v, want 5", cfg.MaxPostAttempts)
+		}
+		if cfg.RetryBackoff != 15*time.Minute {
+			t.Errorf("RetryBackoff = %v, want %v", cfg.RetryBackoff, 15*time.Minute)
+		}
+		if cfg.FetchTimeout != 30*time.Second {
+			t.Errorf("FetchTimeout = %v, want %v", cfg.FetchTimeout, 30*time.Second)
+		}
+		if cfg.FetchRetries != 2 || cfg.FetchRetryBackoff != 5*time.Second {
+			t.Errorf("FetchRetries = %d, FetchRetryBackoff = %v; want 2 and 5s", cfg.FetchRetries, cfg.FetchRetryBackoff)
+		}
 	})
 
 	t.Run("loads from YAML config file", func(t *testing.T) {
@@ -93,12 +130,16 @@ content_warning: CW Test
 		if cfg.MastodonAccessToken != "test-token-123" {
 			t.Errorf("MastodonAccessToken = %v", cfg.MastodonAccessToken)
 		}
-		if cfg.TemplateFile != "custom-template.txt" {
+		// Relative to the config file found in the working directory
+		if !filepath.IsAbs(cfg.TemplateFile) || filepath.Base(cfg.TemplateFile) != "custom-template.txt" {
 			t.Errorf("TemplateFile = %v", cfg.TemplateFile)
 		}
 		if cfg.CharacterLimit != 1000 {
 			t.Errorf("CharacterLimit = %v, want 1000", cfg.CharacterLimit)
 		}
+		if !cfg.characterLimitSet {
+			t.Error("characterLimitSet = false for an explicit character limit")
+		}
 		if cfg.MaxItems != 5 {
 			t.Errorf("MaxItems = %v, want 5", cfg.MaxItems)
 		}
@@ -149,13 +190,387 @@ mastodon_token: test-token
 		}
 
 		// Defaults should still apply
-		if cfg.TemplateFile != "post-template.txt" {
+		if filepath.Base(cfg.TemplateFile) != "post-template.txt" {
 			t.Errorf("TemplateFile default not applied")
 		}
 		if cfg.CharacterLimit != 500 {
 			t.Errorf("CharacterLimit default not applied")
 		}
 	})
+
+	t.Run("loads url rewrite rules", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Reset()
+
+		tmpDir := t.TempDir()
+		configContent := `feed_url: https://example.com/feed.xml
+mastodon_server: https://mastodon.example
+url_rewrites:
+  - from: youtube.com
+    to: https://yewtu.be
+  - from: twitter.com
+    to: nitter.example
+`
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+
+		if len(cfg.URLRewrites) != 2 {
+			t.Fatalf("len(URLRewrites) = %d, want 2", len(cfg.URLRewrites))
+		}
+		if cfg.URLRewrites[0].From != "youtube.com" || cfg.URLRewrites[0].To != "https://yewtu.be" {
+			t.Errorf("URLRewrites[0] = %+v", cfg.URLRewrites[0])
+		}
+		if cfg.URLRewrites[1].From != "twitter.com" || cfg.URLRewrites[1].To != "nitter.example" {
+			t.Errorf("URLRewrites[1] = %+v", cfg.URLRewrites[1])
+		}
+	})
+
+	t.Run("loads template rules", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Reset()
+
+		tmpDir := t.TempDir()
+		configContent := `feed_url: https://example.com/feed.xml
+mastodon_server: https://mastodon.example
+templates:
+  - match_category: podcast
+    path: podcast.txt
+  - match_feed: My Blog
+    path: blog.txt
+`
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+
+		if len(cfg.Templates) != 2 {
+			t.Fatalf("len(Templates) = %d, want 2", len(cfg.Templates))
+		}
+		if cfg.Templates[0].MatchCategory != "podcast" || cfg.Templates[0].Path != filepath.Join(tmpDir, "podcast.txt") {
+			t.Errorf("Templates[0] = %+v", cfg.Templates[0])
+		}
+		if cfg.Templates[1].MatchFeed != "My Blog" || cfg.Templates[1].Path != filepath.Join(tmpDir, "blog.txt") {
+			t.Errorf("Templates[1] = %+v", cfg.Templates[1])
+		}
+	})
+
+	t.Run("loads hashtag mappings", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Reset()
+
+		tmpDir := t.TempDir()
+		configContent := `feed_url: https://example.com/feed.xml
+mastodon_server: https://mastodon.example
+hashtags:
+  - category: Node.js
+    tag: NodeJS
+  - category: Uncategorized
+    tag: ""
+`
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+
+		if len(cfg.Hashtags) != 2 {
+			t.Fatalf("len(Hashtags) = %d, want 2", len(cfg.Hashtags))
+		}
+		if cfg.Hashtags[0].Category != "Node.js" || cfg.Hashtags[0].Tag != "NodeJS" {
+			t.Errorf("Hashtags[0] = %+v", cfg.Hashtags[0])
+		}
+		if cfg.Hashtags[1].Category != "Uncategorized" || cfg.Hashtags[1].Tag != "" {
+			t.Errorf("Hashtags[1] = %+v", cfg.Hashtags[1])
+		}
+	})
+
+	t.Run("loads entry filters", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Reset()
+
+		tmpDir := t.TempDir()
+		configContent := `feed_url: https://example.com/feed.xml
+mastodon_server: https://mastodon.example
+filters:
+  exclude_regex: "(?i)sponsored"
+  include_categories: [go, rust]
+  exclude_categories:
+    - meta
+`
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+
+		if cfg.Filters.ExcludeRegex != "(?i)sponsored" {
+			t.Errorf("Filters.ExcludeRegex = %q", cfg.Filters.ExcludeRegex)
+		}
+		if len(cfg.Filters.IncludeCategories) != 2 || cfg.Filters.IncludeCategories[1] != "rust" {
+			t.Errorf("Filters.IncludeCategories = %v", cfg.Filters.IncludeCategories)
+		}
+		if len(cfg.Filters.ExcludeCategories) != 1 || cfg.Filters.ExcludeCategories[0] != "meta" {
+			t.Errorf("Filters.ExcludeCategories = %v", cfg.Filters.ExcludeCategories)
+		}
+	})
+
+	t.Run("loads destination", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Reset()
+
+		tmpDir := t.TempDir()
+		configContent := `feed_url: https://example.com/feed.xml
+destination:
+  type: webhook
+  url: https://hooks.example/post
+  headers:
+    Authorization: Bearer secret
+`
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+
+		if cfg.IsMastodon() {
+			t.Error("IsMastodon() = true for a webhook destination")
+		}
+		if cfg.Destination.URL != "https://hooks.example/post" {
+			t.Errorf("Destination.URL = %q", cfg.Destination.URL)
+		}
+		if got := cfg.Destination.Headers["authorization"]; got != "Bearer secret" {
+			t.Errorf("Destination.Headers = %v", cfg.Destination.Headers)
+		}
+	})
+
+	t.Run("loads fetch options", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Reset()
+
+		tmpDir := t.TempDir()
+		configContent := `feed_url: https://example.com/feed.xml
+fetch_timeout: 10s
+http_proxy: http://proxy.example:3128
+user_agent: my-bot/1.0
+fetch_headers:
+  X-Api-Key: secret
+`
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+
+		if cfg.FetchTimeout != 10*time.Second {
+			t.Errorf("FetchTimeout = %v, want 10s", cfg.FetchTimeout)
+		}
+		if cfg.HTTPProxy != "http://proxy.example:3128" || cfg.UserAgent != "my-bot/1.0" {
+			t.Errorf("HTTPProxy = %q, UserAgent = %q", cfg.HTTPProxy, cfg.UserAgent)
+		}
+		if got := cfg.FetchHeaders["x-api-key"]; got != "secret" {
+			t.Errorf("FetchHeaders = %v", cfg.FetchHeaders)
+		}
+	})
+
+	t.Run("loads hooks", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Reset()
+
+		tmpDir := t.TempDir()
+		configContent := `feed_url: https://example.com/feed.xml
+hooks:
+  pre_post: ./check-entry.sh
+  post_success: logger posted
+  timeout: 1m
+`
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+
+		want := Hooks{PrePost: "./check-entry.sh", PostSuccess: "logger posted", Timeout: time.Minute}
+		if cfg.Hooks != want {
+			t.Errorf("Hooks = %+v, want %+v", cfg.Hooks, want)
+		}
+	})
+
+	t.Run("loads link shortener and archive links", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Reset()
+
+		tmpDir := t.TempDir()
+		configContent := `feed_url: https://example.com/feed.xml
+link_shortener:
+  url: https://sho.rt/api?url={url}
+  response_field: data.link
+  headers:
+    X-Api-Key: secret
+archive_links: true
+`
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+
+		shortener := cfg.LinkShortener
+		if shortener.URL != "https://sho.rt/api?url={url}" || shortener.ResponseField != "data.link" || shortener.Headers["x-api-key"] != "secret" {
+			t.Errorf("LinkShortener = %+v", shortener)
+		}
+		if !cfg.ArchiveLinks || cfg.ArchiveSave || cfg.ArchiveLabel != "Archived:" {
+			t.Errorf("ArchiveLinks = %v, ArchiveSave = %v, ArchiveLabel = %q", cfg.ArchiveLinks, cfg.ArchiveSave, cfg.ArchiveLabel)
+		}
+	})
+	t.Run("resolves paths relative to the config file", func(t *testing.T) {
+		defer viper.Reset()
+		viper.Reset()
+
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("F2M_TEST_DATA", "/srv/data")
+
+		tmpDir := t.TempDir()
+		configContent := `feed_url: https://example.com/feed.xml
+mastodon_server: https://mastodon.example
+database_path: $F2M_TEST_DATA/bot.db
+template_path: ~/templates/post.txt
+destination:
+  type: file
+  path: posts.txt
+`
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(configContent), 0o644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+
+		if want := filepath.Join("/srv/data", "bot.db"); cfg.DatabasePath != want {
+			t.Errorf("DatabasePath = %v, want %v", cfg.DatabasePath, want)
+		}
+		if want := filepath.Join(home, "templates", "post.txt"); cfg.TemplateFile != want {
+			t.Errorf("TemplateFile = %v, want %v", cfg.TemplateFile, want)
+		}
+		if want := filepath.Join(tmpDir, "posts.txt"); cfg.Destination.Path != want {
+			t.Errorf("Destination.Path = %v, want %v", cfg.Destination.Path, want)
+		}
+		if cfg.Bluesky.TemplatePath != "" {
+			t.Errorf("Bluesky.TemplatePath = %v, want empty", cfg.Bluesky.TemplatePath)
+		}
+	})
+}
+
+func TestApplyInstanceLimits(t *testing.T) {
+	t.Run("replaces default character limit", func(t *testing.T) {
+		cfg := &Config{CharacterLimit: 500, MediaMaxBytes: 8 << 20}
+		cfg.ApplyInstanceLimits(1000, 16<<20, 0)
+
+		if cfg.CharacterLimit != 1000 {
+			t.Errorf("CharacterLimit = %d, want 1000", cfg.CharacterLimit)
+		}
+		if cfg.MediaMaxBytes != 8<<20 {
+			t.Errorf("MediaMaxBytes = %d, want configured 8 MiB", cfg.MediaMaxBytes)
+		}
+	})
+
+	t.Run("keeps explicit character limit and caps media size", func(t *testing.T) {
+		cfg := &Config{CharacterLimit: 300, MediaMaxBytes: 8 << 20, characterLimitSet: true}
+		cfg.ApplyInstanceLimits(1000, 2<<20, 0)
+
+		if cfg.CharacterLimit != 300 {
+			t.Errorf("CharacterLimit = %d, want 300", cfg.CharacterLimit)
+		}
+		if cfg.MediaMaxBytes != 2<<20 {
+			t.Errorf("MediaMaxBytes = %d, want instance 2 MiB", cfg.MediaMaxBytes)
+		}
+	})
+
+	t.Run("caps audio and video size", func(t *testing.T) {
+		cfg := &Config{CharacterLimit: 500, MediaMaxAVBytes: 40 << 20}
+		cfg.ApplyInstanceLimits(0, 0, 16<<20)
+
+		if cfg.MediaMaxAVBytes != 16<<20 {
+			t.Errorf("MediaMaxAVBytes = %d, want instance 16 MiB", cfg.MediaMaxAVBytes)
+		}
+	})
+
+	t.Run("ignores missing limits", func(t *testing.T) {
+		cfg := &Config{CharacterLimit: 500, MediaMaxBytes: 8 << 20}
+		cfg.ApplyInstanceLimits(0, 0, 0)
+
+		if cfg.CharacterLimit != 500 || cfg.MediaMaxBytes != 8<<20 {
+			t.Errorf("limits changed: %d, %d", cfg.CharacterLimit, cfg.MediaMaxBytes)
+		}
+	})
+}
+
+func TestApplyInstanceFlavor(t *testing.T) {
+	t.Run("uses the flavor's default character limit", func(t *testing.T) {
+		cfg := &Config{CharacterLimit: 500}
+		cfg.ApplyInstanceFlavor("gotosocial")
+
+		if cfg.CharacterLimit != 5000 {
+			t.Errorf("CharacterLimit = %d, want 5000", cfg.CharacterLimit)
+		}
+	})
+
+	t.Run("keeps explicit character limit", func(t *testing.T) {
+		cfg := &Config{CharacterLimit: 300, characterLimitSet: true}
+		cfg.ApplyInstanceFlavor("akkoma")
+
+		if cfg.CharacterLimit != 300 {
+			t.Errorf("CharacterLimit = %d, want 300", cfg.CharacterLimit)
+		}
+	})
+
+	t.Run("keeps Mastodon's default", func(t *testing.T) {
+		cfg := &Config{CharacterLimit: 500}
+		cfg.ApplyInstanceFlavor("mastodon")
+
+		if cfg.CharacterLimit != 500 {
+			t.Errorf("CharacterLimit = %d, want 500", cfg.CharacterLimit)
+		}
+	})
 }
 
 func TestValidate(t *testing.T) {
@@ -255,48 +670,770 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.config.Validate()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if tt.wantErr && tt.errMsg != "" {
-				if err == nil || !contains(err.Error(), tt.errMsg) {
-					t.Errorf("Validate() error = %v, want error containing %v", err, tt.errMsg)
-				}
-			}
-		})
-	}
-}
-
-func TestValidateForPosting(t *testing.T) {
-	tests := []struct {
-		name    string
-		config  Config
-		wantErr bool
-		errMsg  string
-	}{
 		{
-			name: "missing both access token and client credentials",
+			name: "valid url rewrite",
 			config: Config{
 				FeedURL:        "https://example.com/feed",
 				MastodonServer: "https://mastodon.social",
 				PostVisibility: "public",
+				URLRewrites:    []URLRewrite{{From: "youtube.com", To: "https://yewtu.be"}},
 			},
-			wantErr: true,
-			errMsg:  "either mastodon_token or both mastodon_client_id and mastodon_client_secret are required",
+			wantErr: false,
 		},
 		{
-			name: "has access token",
+			name: "url rewrite missing to",
 			config: Config{
-				FeedURL:             "https://example.com/feed",
-				MastodonServer:      "https://mastodon.social",
-				MastodonAccessToken: "token",
-				PostVisibility:      "public",
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				URLRewrites:    []URLRewrite{{From: "youtube.com"}},
+			},
+			wantErr: true,
+			errMsg:  "url_rewrites[0] requires both from and to",
+		},
+		{
+			name: "hashtag mapping without category",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Hashtags:       []HashtagMapping{{Tag: "golang"}},
+			},
+			wantErr: true,
+			errMsg:  "hashtags[0] requires a category",
+		},
+		{
+			name: "invalid timezone",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Timezone:       "Mars/Olympus_Mons",
+			},
+			wantErr: true,
+			errMsg:  "timezone is invalid",
+		},
+		{
+			name: "template rule without match",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Templates:      []TemplateRule{{Path: "podcast.txt"}},
+			},
+			wantErr: true,
+			errMsg:  "templates[0] requires match_category or match_feed",
+		},
+		{
+			name: "negative post interval",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				PostInterval:   -time.Minute,
+			},
+			wantErr: true,
+			errMsg:  "post_interval must not be negative",
+		},
+		{
+			name: "visibility rule with invalid visibility",
+			config: Config{
+				FeedURL:         "https://example.com/feed",
+				MastodonServer:  "https://mastodon.social",
+				PostVisibility:  "public",
+				VisibilityRules: []VisibilityRule{{MatchCategory: "links", Visibility: "hidden"}},
+			},
+			wantErr: true,
+			errMsg:  "visibility_rules[0].visibility must be one of",
+		},
+		{
+			name: "visibility rule without match",
+			config: Config{
+				FeedURL:         "https://example.com/feed",
+				MastodonServer:  "https://mastodon.social",
+				PostVisibility:  "public",
+				VisibilityRules: []VisibilityRule{{Visibility: "unlisted"}},
+			},
+			wantErr: true,
+			errMsg:  "visibility_rules[0] requires match_regex or match_category",
+		},
+		{
+			name: "content warning rule without text",
+			config: Config{
+				FeedURL:         "https://example.com/feed",
+				MastodonServer:  "https://mastodon.social",
+				PostVisibility:  "public",
+				ContentWarnings: []ContentWarningRule{{MatchCategory: "politics"}},
+			},
+			wantErr: true,
+			errMsg:  "content_warnings[0] requires text",
+		},
+		{
+			name: "content warning rule with invalid regex",
+			config: Config{
+				FeedURL:         "https://example.com/feed",
+				MastodonServer:  "https://mastodon.social",
+				PostVisibility:  "public",
+				ContentWarnings: []ContentWarningRule{{MatchTitle: "(unclosed", Text: "cw"}},
+			},
+			wantErr: true,
+			errMsg:  "content_warnings[0].match_title is invalid",
+		},
+		{
+			name: "schedule spread too short",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				ScheduleSpread: time.Minute,
+			},
+			wantErr: true,
+			errMsg:  "schedule_spread must be at least 5m",
+		},
+		{
+			name: "negative max post attempts",
+			config: Config{
+				FeedURL:         "https://example.com/feed",
+				MastodonServer:  "https://mastodon.social",
+				PostVisibility:  "public",
+				MaxPostAttempts: -1,
+			},
+			wantErr: true,
+			errMsg:  "max_post_attempts must not be negative",
+		},
+		{
+			name: "negative fetch timeout",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				FetchTimeout:   -time.Second,
+			},
+			wantErr: true,
+			errMsg:  "fetch_timeout must not be negative",
+		},
+		{
+			name: "negative sqlite busy timeout",
+			config: Config{
+				FeedURL:           "https://example.com/feed",
+				MastodonServer:    "https://mastodon.social",
+				PostVisibility:    "public",
+				SQLiteBusyTimeout: -time.Second,
+			},
+			wantErr: true,
+			errMsg:  "sqlite_busy_timeout must not be negative",
+		},
+		{
+			name: "unknown sqlite synchronous mode",
+			config: Config{
+				FeedURL:           "https://example.com/feed",
+				MastodonServer:    "https://mastodon.social",
+				PostVisibility:    "public",
+				SQLiteSynchronous: "sometimes",
+			},
+			wantErr: true,
+			errMsg:  "sqlite_synchronous must be one of: off, normal, full, extra",
+		},
+		{
+			name: "token and token command",
+			config: Config{
+				FeedURL:              "https://example.com/feed",
+				MastodonServer:       "https://mastodon.social",
+				PostVisibility:       "public",
+				MastodonAccessToken:  "token",
+				MastodonTokenCommand: "pass show mastodon",
+			},
+			wantErr: true,
+			errMsg:  "mastodon_token and mastodon_token_command can't both be set",
+		},
+		{
+			name: "unknown token storage",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				TokenStorage:   "vault",
+			},
+			wantErr: true,
+			errMsg:  "token_storage must be one of: database, keyring",
+		},
+		{
+			name: "unknown secret encryption",
+			config: Config{
+				FeedURL:          "https://example.com/feed",
+				MastodonServer:   "https://mastodon.social",
+				PostVisibility:   "public",
+				SecretEncryption: "vault",
+			},
+			wantErr: true,
+			errMsg:  "secret_encryption must be one of: off, env, keyring",
+		},
+		{
+			name: "notify account without full address",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Notify:         Notify{Account: "admin"},
+			},
+			wantErr: true,
+			errMsg:  "notify.account must be an account like @admin@example.social",
+		},
+		{
+			name: "notify account with other destination",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				PostVisibility: "public",
+				Destination:    Destination{Type: "stdout"},
+				Notify:         Notify{Account: "@admin@example.social"},
+			},
+			wantErr: true,
+			errMsg:  "notify.account can only be used with the mastodon destination",
+		},
+		{
+			name: "valid notify settings",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Notify:         Notify{Account: "@admin@example.social", Webhook: "https://hooks.example.com/f2m", After: 5},
+			},
+			wantErr: false,
+		},
+		{
+			name: "short admin token",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				AdminToken:     "hunter2",
+			},
+			wantErr: true,
+		},
+		{
+			name: "dashboard without admin token",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Dashboard:      true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "truncate character limit mode",
+			config: Config{
+				FeedURL:            "https://example.com/feed",
+				MastodonServer:     "https://mastodon.social",
+				PostVisibility:     "public",
+				CharacterLimitMode: "truncate",
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown character limit mode",
+			config: Config{
+				FeedURL:            "https://example.com/feed",
+				MastodonServer:     "https://mastodon.social",
+				PostVisibility:     "public",
+				CharacterLimitMode: "shorten",
+			},
+			wantErr: true,
+		},
+		{
+			name: "character limit mode fail with split long posts",
+			config: Config{
+				FeedURL:            "https://example.com/feed",
+				MastodonServer:     "https://mastodon.social",
+				PostVisibility:     "public",
+				CharacterLimitMode: "fail",
+				SplitLongPosts:     true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "published post order",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				PostOrder:      "published",
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown post order",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				PostOrder:      "latest",
+			},
+			wantErr: true,
+		},
+		{
+			name: "initial mode post latest n",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				InitialMode:    "post_latest_n",
+				InitialPosts:   3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "initial mode post latest n without posts",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				InitialMode:    "post_latest_n",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown initial mode",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				InitialMode:    "skip",
+			},
+			wantErr: true,
+		},
+		{
+			name: "local visibility on akkoma",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://akkoma.example",
+				PostVisibility: "local",
+				InstanceFlavor: "akkoma",
+			},
+			wantErr: false,
+		},
+		{
+			name: "local visibility on mastodon",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "local",
+				InstanceFlavor: "mastodon",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown instance flavor",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				InstanceFlavor: "misskey",
+			},
+			wantErr: true,
+		},
+		{
+			name: "thread anchor",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				ThreadAnchor:   "109876543210987654",
+			},
+			wantErr: false,
+		},
+		{
+			name: "thread anchor url",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				ThreadAnchor:   "https://mastodon.social/@bot/109876543210987654",
+			},
+			wantErr: true,
+		},
+		{
+			name: "json source type",
+			config: Config{
+				FeedURL:        "https://example.com/api/posts",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				SourceType:     "json",
+				JSONMapping:    JSONMapping{Items: ".posts", Link: ".url"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "json source type without mapping",
+			config: Config{
+				FeedURL:        "https://example.com/api/posts",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				SourceType:     "json",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown source type",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				SourceType:     "csv",
+			},
+			wantErr: true,
+		},
+		{
+			name: "audio and video without a size limit",
+			config: Config{
+				FeedURL:          "https://example.com/feed",
+				MastodonServer:   "https://mastodon.social",
+				PostVisibility:   "public",
+				MediaAttachments: true,
+				MediaMaxBytes:    8 << 20,
+				MediaAudioVideo:  true,
+			},
+			wantErr: true,
+			errMsg:  "media_max_audio_video_bytes must be positive when media_audio_video is enabled",
+		},
+		{
+			name: "media focus out of range",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				MediaFocus:     "0.0,1.5",
+			},
+			wantErr: true,
+			errMsg:  "media_focus must be x,y with both from -1.0 to 1.0, like 0.0,0.5",
+		},
+		{
+			name: "media focus",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				MediaFocus:     "-0.25, 0.5",
+			},
+		},
+		{
+			name: "link shortener without url placeholder",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				LinkShortener:  LinkShortener{URL: "https://is.gd/create.php?format=simple"},
+			},
+			wantErr: true,
+			errMsg:  "link_shortener.url must contain {url} where the link goes",
+		},
+		{
+			name: "link shortener not a URL",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				LinkShortener:  LinkShortener{URL: "is.gd/create.php?url={url}"},
+			},
+			wantErr: true,
+			errMsg:  "link_shortener.url must be an http or https URL",
+		},
+		{
+			name: "link shortener",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				LinkShortener:  LinkShortener{URL: "https://is.gd/create.php?format=simple&url={url}"},
+			},
+		},
+		{
+			name: "archive save without archive links",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				ArchiveSave:    true,
+			},
+			wantErr: true,
+			errMsg:  "archive_save requires archive_links",
+		},
+		{
+			name: "negative hook timeout",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Hooks:          Hooks{PostSuccess: "true", Timeout: -time.Second},
+			},
+			wantErr: true,
+			errMsg:  "hooks.timeout must not be negative",
+		},
+		{
+			name: "negative fetch retries",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				FetchRetries:   -1,
+			},
+			wantErr: true,
+			errMsg:  "fetch_retries must not be negative",
+		},
+		{
+			name: "feed password without username",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				FeedPassword:   "hunter2",
+			},
+			wantErr: true,
+			errMsg:  "feed_password requires feed_username",
+		},
+		{
+			name: "invalid http proxy",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				HTTPProxy:      "proxy.example:3128",
+			},
+			wantErr: true,
+			errMsg:  "http_proxy must be a URL",
+		},
+		{
+			name: "dedupe timeline without mastodon",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				PostVisibility: "public",
+				DedupeTimeline: 40,
+				Destination:    Destination{Type: "stdout"},
+			},
+			wantErr: true,
+			errMsg:  "dedupe_timeline can only be used with the mastodon destination",
+		},
+		{
+			name: "negative expire after",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				ExpireAfter:    -time.Hour,
+			},
+			wantErr: true,
+			errMsg:  "expire_after must not be negative",
+		},
+		{
+			name: "expire after without mastodon",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				PostVisibility: "public",
+				ExpireAfter:    time.Hour,
+				Destination:    Destination{Type: "stdout"},
+			},
+			wantErr: true,
+			errMsg:  "expire_after can only be used with the mastodon destination",
+		},
+		{
+			name: "invalid filter regex",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Filters:        Filters{ExcludeRegex: "(unclosed"},
+			},
+			wantErr: true,
+			errMsg:  "filters.exclude_regex is invalid",
+		},
+		{
+			name: "valid status links mode",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				StatusLinks:    "quote",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid status links mode",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				StatusLinks:    "shout",
+			},
+			wantErr: true,
+			errMsg:  "status_links must be one of",
+		},
+		{
+			name: "webhook destination without mastodon server",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				PostVisibility: "public",
+				Destination:    Destination{Type: "webhook", URL: "https://hooks.example/post"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "webhook destination without url",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				PostVisibility: "public",
+				Destination:    Destination{Type: "webhook"},
+			},
+			wantErr: true,
+			errMsg:  "destination.url is required",
+		},
+		{
+			name: "directory destination without path",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				PostVisibility: "public",
+				Destination:    Destination{Type: "directory"},
+			},
+			wantErr: true,
+			errMsg:  "destination.path is required",
+		},
+		{
+			name: "accounts",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Accounts: []Account{
+					{Name: "work", Server: "https://work.example", Token: "t1"},
+					{Name: "art", Server: "https://art.example", Token: "t2", Categories: []string{"art"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "account without token",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Accounts:       []Account{{Name: "work", Server: "https://work.example"}},
+			},
+			wantErr: true,
+			errMsg:  "accounts[0] requires name, server, and token",
+		},
+		{
+			name: "duplicate account names",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Accounts: []Account{
+					{Name: "work", Server: "https://work.example", Token: "t1"},
+					{Name: "work", Server: "https://other.example", Token: "t2"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "accounts[1] has the same name",
+		},
+		{
+			name: "bluesky with another destination",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				PostVisibility: "public",
+				Destination:    Destination{Type: "stdout"},
+				Bluesky:        Bluesky{Handle: "me.bsky.social"},
+			},
+			wantErr: true,
+			errMsg:  "bluesky can only be used with the mastodon destination",
+		},
+		{
+			name: "chat webhooks",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				ChatWebhooks: []ChatWebhook{
+					{Name: "team", Type: "slack", URL: "https://hooks.slack.com/services/x"},
+					{Name: "server", Type: "discord", URL: "https://discord.com/api/webhooks/x", TemplatePath: "discord.txt"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "chat webhook with unknown type",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				ChatWebhooks:   []ChatWebhook{{Name: "team", Type: "irc", URL: "https://example.com"}},
+			},
+			wantErr: true,
+			errMsg:  "chat_webhooks[0].type must be one of",
+		},
+		{
+			name: "chat webhook named like an account",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Accounts:       []Account{{Name: "work", Server: "https://work.example", Token: "t1"}},
+				ChatWebhooks:   []ChatWebhook{{Name: "work", Type: "slack", URL: "https://hooks.slack.com/services/x"}},
+			},
+			wantErr: true,
+			errMsg:  "chat_webhooks[0] has the same name",
+		},
+		{
+			name: "unknown destination",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+				Destination:    Destination{Type: "carrier-pigeon"},
+			},
+			wantErr: true,
+			errMsg:  "destination.type must be one of",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && tt.errMsg != "" {
+				if err == nil || !contains(err.Error(), tt.errMsg) {
+					t.Errorf("Validate() error = %v, want error containing %v", err, tt.errMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateForPosting(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "missing both access token and client credentials",
+			config: Config{
+				FeedURL:        "https://example.com/feed",
+				MastodonServer: "https://mastodon.social",
+				PostVisibility: "public",
+			},
+			wantErr: true,
+			errMsg:  "either mastodon_token or both mastodon_client_id and mastodon_client_secret are required",
+		},
+		{
+			name: "has access token",
+			config: Config{
+				FeedURL:             "https://example.com/feed",
+				MastodonServer:      "https://mastodon.social",
+				MastodonAccessToken: "token",
+				PostVisibility:      "public",
 			},
 			wantErr: false,
 		},
@@ -364,3 +1501,111 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestLocation(t *testing.T) {
+	cfg := &Config{}
+	if location, err := cfg.Location(); err != nil || location != nil {
+		t.Errorf("Location() = %v, %v; want nil, nil when unset", location, err)
+	}
+
+	cfg.Timezone = "America/New_York"
+	location, err := cfg.Location()
+	if err != nil {
+		t.Fatalf("Location() error = %v", err)
+	}
+	if location.String() != "America/New_York" {
+		t.Errorf("Location() = %v, want America/New_York", location)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	cfg := &Config{}
+	if window, err := cfg.Window(); err != nil || window != nil {
+		t.Errorf("Window() = %v, %v; want nil, nil when unset", window, err)
+	}
+
+	cfg = &Config{PostWindow: "08:00-22:00", PostDays: []string{"Mon-Fri"}, Timezone: "UTC"}
+	window, err := cfg.Window()
+	if err != nil {
+		t.Fatalf("Window() error = %v", err)
+	}
+
+	// 2024-03-08 is a Friday
+	friday := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 8, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		at       time.Time
+		contains bool
+		next     time.Time
+	}{
+		{"inside", friday(12, 0), true, friday(12, 0)},
+		{"before start", friday(7, 59), false, friday(8, 0)},
+		{"at end", friday(22, 0), false, time.Date(2024, 3, 11, 8, 0, 0, 0, time.UTC)},
+		{"weekend", friday(12, 0).AddDate(0, 0, 1), false, time.Date(2024, 3, 11, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := window.Contains(tt.at); got != tt.contains {
+				t.Errorf("Contains(%v) = %v, want %v", tt.at, got, tt.contains)
+			}
+			if got := window.Next(tt.at); !got.Equal(tt.next) {
+				t.Errorf("Next(%v) = %v, want %v", tt.at, got, tt.next)
+			}
+		})
+	}
+
+	t.Run("past midnight", func(t *testing.T) {
+		cfg := &Config{PostWindow: "22:00-02:00", Timezone: "UTC"}
+		window, err := cfg.Window()
+		if err != nil {
+			t.Fatalf("Window() error = %v", err)
+		}
+		if !window.Contains(friday(1, 0)) || !window.Contains(friday(23, 0)) || window.Contains(friday(12, 0)) {
+			t.Error("Contains() doesn't follow a window past midnight")
+		}
+	})
+
+	for _, bad := range []*Config{
+		{PostWindow: "8am-10pm"},
+		{PostWindow: "08:00"},
+		{PostWindow: "08:00-08:00"},
+		{PostDays: []string{"Funday"}},
+	} {
+		if _, err := bad.Window(); err == nil {
+			t.Errorf("Window() with %q %v: expected error", bad.PostWindow, bad.PostDays)
+		}
+	}
+}
+
+func TestPurgePolicy(t *testing.T) {
+	tests := []struct {
+		purge string
+		want  PurgePolicy
+	}{
+		{"", PurgePolicy{}},
+		{"stale", PurgePolicy{}},
+		{"never", PurgePolicy{Never: true}},
+		{"age:720h", PurgePolicy{MinAge: 720 * time.Hour}},
+		{"keep:500", PurgePolicy{Keep: 500}},
+	}
+	for _, tt := range tests {
+		cfg := &Config{PurgeMode: tt.purge}
+		got, err := cfg.PurgePolicy()
+		if err != nil {
+			t.Errorf("PurgePolicy() with %q error = %v", tt.purge, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("PurgePolicy() with %q = %+v, want %+v", tt.purge, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"always", "never:1", "age:30d", "age:-1h", "keep:0", "keep:all"} {
+		cfg := &Config{PurgeMode: bad}
+		if _, err := cfg.PurgePolicy(); err == nil {
+			t.Errorf("PurgePolicy() with %q: expected error", bad)
+		}
+	}
+}
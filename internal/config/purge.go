@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PurgePolicy describes which entries that are no longer in the feed are
+// purged after a fetch, from purge. The zero value purges all of them.
+type PurgePolicy struct {
+	// Never disables purging.
+	Never bool
+	// MinAge keeps entries fetched more recently than this.
+	MinAge time.Duration
+	// Keep keeps this many of the most recently fetched entries.
+	Keep int
+}
+
+// PurgePolicy parses the purge setting: never, stale, age:<duration>, or
+// keep:<n>.
+func (c *Config) PurgePolicy() (PurgePolicy, error) {
+	mode, value, _ := strings.Cut(c.PurgeMode, ":")
+	switch mode {
+	case "", "stale":
+		if value != "" {
+			break
+		}
+		return PurgePolicy{}, nil
+	case "never":
+		if value != "" {
+			break
+		}
+		return PurgePolicy{Never: true}, nil
+	case "age":
+		age, err := time.ParseDuration(value)
+		if err != nil || age <= 0 {
+			return PurgePolicy{}, fmt.Errorf("purge age must be a positive duration, like age:720h")
+		}
+		return PurgePolicy{MinAge: age}, nil
+	case "keep":
+		keep, err := strconv.Atoi(value)
+		if err != nil || keep < 1 {
+			return PurgePolicy{}, fmt.Errorf("purge keep must be a positive number, like keep:500")
+		}
+		return PurgePolicy{Keep: keep}, nil
+	}
+	return PurgePolicy{}, fmt.Errorf("purge must be one of: never, stale, age:<duration>, keep:<n>")
+}
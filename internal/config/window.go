@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PostingWindow describes when posting is allowed, from post_window and
+// post_days. A nil window allows posting at any time.
+type PostingWindow struct {
+	// start and end are minutes since midnight. A window that ends before
+	// it starts runs past midnight.
+	start, end int
+	days       [7]bool
+	location   *time.Location
+}
+
+// dayNames maps day names and abbreviations to weekdays.
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Window returns the posting window configured by post_window and
+// post_days, in the configured timezone or else the system's. Returns nil
+// if neither is set.
+func (c *Config) Window() (*PostingWindow, error) {
+	if c.PostWindow == "" && len(c.PostDays) == 0 {
+		return nil, nil
+	}
+
+	location, err := c.Location()
+	if err != nil {
+		return nil, err
+	}
+	if location == nil {
+		location = time.Local
+	}
+	w := &PostingWindow{end: 24 * 60, location: location}
+
+	if c.PostWindow != "" {
+		from, to, ok := strings.Cut(c.PostWindow, "-")
+		if !ok {
+			return nil, fmt.Errorf("post_window must look like 08:00-22:00")
+		}
+		if w.start, err = parseClock(from); err != nil {
+			return nil, fmt.Errorf("post_window is invalid: %w", err)
+		}
+		if w.end, err = parseClock(to); err != nil {
+			return nil, fmt.Errorf("post_window is invalid: %w", err)
+		}
+		if w.start == w.end {
+			return nil, fmt.Errorf("post_window must not start and end at the same time")
+		}
+	}
+
+	if len(c.PostDays) == 0 {
+		w.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, day := range c.PostDays {
+		if err := w.addDays(day); err != nil {
+			return nil, fmt.Errorf("post_days is invalid: %w", err)
+		}
+	}
+
+	return w, nil
+}
+
+// parseClock parses a time of day like 08:00 into minutes since midnight.
+// 24:00 is allowed as the end of the day.
+func parseClock(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time like 08:00", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// addDays allows posting on a day, e.g. "Mon", or a range of days, e.g.
+// "Mon-Fri".
+func (w *PostingWindow) addDays(s string) error {
+	from, to, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "-")
+	first, ok := dayNames[from]
+	if !ok {
+		return fmt.Errorf("%q is not a day", s)
+	}
+	last := first
+	if isRange {
+		if last, ok = dayNames[to]; !ok {
+			return fmt.Errorf("%q is not a day", s)
+		}
+	}
+
+	for day := first; ; day = (day + 1) % 7 {
+		w.days[day] = true
+		if day == last {
+			return nil
+		}
+	}
+}
+
+// Contains reports whether posting is allowed at t.
+func (w *PostingWindow) Contains(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	t = t.In(w.location)
+	if !w.days[t.Weekday()] {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// Next returns t if posting is allowed at t, or else when the window next
+// opens.
+func (w *PostingWindow) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	local := t.In(w.location)
+	for day := 0; day <= 7; day++ {
+		date := local.AddDate(0, 0, day)
+		opens := time.Date(date.Year(), date.Month(), date.Day(), w.start/60, w.start%60, 0, 0, w.location)
+		if opens.After(t) && w.Contains(opens) {
+			return opens
+		}
+	}
+	return t
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AccountPost records an entry cross-posted to an additional account, or
+// the failed attempts to.
+type AccountPost struct {
+	EntryID      string
+	Account      string
+	StatusID     sql.NullString
+	StatusURL    sql.NullString
+	PostedAt     sql.NullTime
+	FailureCount int
+	LastError    sql.NullString
+}
+
+// GetUncrossPostedEntries retrieves the entries posted to the main account
+// at or after since that haven't been posted to the named account yet,
+// oldest first. Catchup entries and scheduled posts that aren't published
+// yet are left out, as are entries that failed maxAttempts times for the
+// account (0 = never give up).
+func (db *DB) GetUncrossPostedEntries(account string, since time.Time, maxAttempts int) ([]*Entry, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+entryColumns+`
+		FROM entries
+		WHERE posted_at >= ?
+			AND ((scheduled_at IS NULL AND status_id IS NOT NULL) OR scheduled_at <= ?)
+			AND NOT EXISTS (
+				SELECT 1 FROM account_posts
+				WHERE account_posts.entry_id = entries.id AND account_posts.account = ?
+					AND (account_posts.posted_at IS NOT NULL OR (? > 0 AND account_posts.failure_count >= ?))
+			)
+		ORDER BY posted_at ASC, id ASC
+	`, dbTime(since), dbTime(time.Now()), account, maxAttempts, maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries to cross-post: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// MarkAccountPosted records that an entry was posted to the named account.
+func (db *DB) MarkAccountPosted(entryID, account, statusID, statusURL string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO account_posts (entry_id, account, status_id, status_url, posted_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (entry_id, account) DO UPDATE
+		SET status_id = excluded.status_id, status_url = excluded.status_url,
+			posted_at = excluded.posted_at, last_error = NULL
+	`, entryID, account, nullString(statusID), nullString(statusURL))
+	if err != nil {
+		return fmt.Errorf("failed to mark entry as posted to %s: %w", account, err)
+	}
+
+	logrus.Debugf("Marked entry %s as posted to %s", entryID, account)
+	return nil
+}
+
+// RecordAccountFailure records a failed attempt to post an entry to the
+// named account.
+func (db *DB) RecordAccountFailure(entryID, account, message string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO account_posts (entry_id, account, failure_count, last_error)
+		VALUES (?, ?, 1, ?)
+		ON CONFLICT (entry_id, account) DO UPDATE
+		SET failure_count = failure_count + 1, last_error = excluded.last_error
+	`, entryID, account, message)
+	if err != nil {
+		return fmt.Errorf("failed to record failure for %s: %w", account, err)
+	}
+	return nil
+}
+
+// GetAccountPosts returns the cross-posts of an entry, by account name.
+func (db *DB) GetAccountPosts(entryID string) ([]AccountPost, error) {
+	rows, err := db.conn.Query(`
+		SELECT entry_id, account, status_id, status_url, posted_at, failure_count, last_error
+		FROM account_posts
+		WHERE entry_id = ?
+		ORDER BY account
+	`, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []AccountPost
+	for rows.Next() {
+		var p AccountPost
+		if err := rows.Scan(&p.EntryID, &p.Account, &p.StatusID, &p.StatusURL, &p.PostedAt, &p.FailureCount, &p.LastError); err != nil {
+			return nil, fmt.Errorf("failed to scan account post: %w", err)
+		}
+		posts = append(posts, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating account posts: %w", err)
+	}
+
+	return posts, nil
+}
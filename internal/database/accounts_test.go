@@ -0,0 +1,109 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccountPosts(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	since := time.Now().Add(-time.Hour)
+	for _, id := range []string{"posted", "catchup", "scheduled-later", "scheduled-past", "unposted"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+	if err := db.MarkAsPosted("posted", "101", "https://mastodon.example/@me/101"); err != nil {
+		t.Fatalf("MarkAsPosted() error = %v", err)
+	}
+	if err := db.MarkAsPosted("catchup", "", ""); err != nil {
+		t.Fatalf("MarkAsPosted() error = %v", err)
+	}
+	if err := db.MarkAsScheduled("scheduled-later", "201", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("MarkAsScheduled() error = %v", err)
+	}
+	if err := db.MarkAsScheduled("scheduled-past", "202", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("MarkAsScheduled() error = %v", err)
+	}
+
+	pendingIDs := func(account string, maxAttempts int) []string {
+		t.Helper()
+		entries, err := db.GetUncrossPostedEntries(account, since, maxAttempts)
+		if err != nil {
+			t.Fatalf("GetUncrossPostedEntries() error = %v", err)
+		}
+		ids := make([]string, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+		return ids
+	}
+
+	t.Run("selects published entries", func(t *testing.T) {
+		ids := pendingIDs("alt", 0)
+		if len(ids) != 2 || ids[0] != "posted" || ids[1] != "scheduled-past" {
+			t.Errorf("GetUncrossPostedEntries() = %v, want [posted scheduled-past]", ids)
+		}
+		if ids := pendingIDs("alt", 0); len(ids) != 2 {
+			t.Errorf("GetUncrossPostedEntries() = %v", ids)
+		}
+		if entries, _ := db.GetUncrossPostedEntries("alt", time.Now().Add(time.Hour), 0); len(entries) != 0 {
+			t.Errorf("entries posted before since were selected: %d", len(entries))
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := db.RecordAccountFailure("posted", "alt", "boom"); err != nil {
+				t.Fatalf("RecordAccountFailure() error = %v", err)
+			}
+		}
+		if ids := pendingIDs("alt", 3); len(ids) != 2 {
+			t.Errorf("GetUncrossPostedEntries() = %v, want 2 entries before giving up", ids)
+		}
+		if ids := pendingIDs("alt", 2); len(ids) != 1 || ids[0] != "scheduled-past" {
+			t.Errorf("GetUncrossPostedEntries() = %v, want [scheduled-past]", ids)
+		}
+	})
+
+	t.Run("marks entries posted per account", func(t *testing.T) {
+		if err := db.MarkAccountPosted("posted", "alt", "301", "https://other.example/@me/301"); err != nil {
+			t.Fatalf("MarkAccountPosted() error = %v", err)
+		}
+		if ids := pendingIDs("alt", 0); len(ids) != 1 || ids[0] != "scheduled-past" {
+			t.Errorf("GetUncrossPostedEntries(alt) = %v, want [scheduled-past]", ids)
+		}
+		if ids := pendingIDs("other", 0); len(ids) != 2 {
+			t.Errorf("GetUncrossPostedEntries(other) = %v, want both entries", ids)
+		}
+
+		posts, err := db.GetAccountPosts("posted")
+		if err != nil {
+			t.Fatalf("GetAccountPosts() error = %v", err)
+		}
+		if len(posts) != 1 || posts[0].StatusURL.String != "https://other.example/@me/301" || !posts[0].PostedAt.Valid {
+			t.Errorf("GetAccountPosts() = %+v", posts)
+		}
+		if posts[0].FailureCount != 2 || posts[0].LastError.Valid {
+			t.Errorf("failure history = %d, %v", posts[0].FailureCount, posts[0].LastError)
+		}
+	})
+
+	t.Run("requeue clears account posts", func(t *testing.T) {
+		if _, err := db.Requeue("posted"); err != nil {
+			t.Fatalf("Requeue() error = %v", err)
+		}
+		posts, err := db.GetAccountPosts("posted")
+		if err != nil {
+			t.Fatalf("GetAccountPosts() error = %v", err)
+		}
+		if len(posts) != 0 {
+			t.Errorf("GetAccountPosts() after requeue = %+v", posts)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Attachment records a media file uploaded to Mastodon for an entry.
+type Attachment struct {
+	EntryID     string
+	SourceURL   string
+	MediaID     string
+	ContentType string
+	Size        int64
+	UploadedAt  *string
+}
+
+// SaveAttachment records a media upload for an entry.
+func (db *DB) SaveAttachment(a Attachment) error {
+	_, err := db.conn.Exec(
+		"INSERT INTO attachments (entry_id, source_url, media_id, content_type, size) VALUES (?, ?, ?, ?, ?)",
+		a.EntryID, a.SourceURL, a.MediaID, a.ContentType, a.Size,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save attachment: %w", err)
+	}
+
+	logrus.Debugf("Saved attachment %s for entry %s", a.MediaID, a.EntryID)
+	return nil
+}
+
+// GetAttachments returns the media uploaded for an entry, in upload order.
+func (db *DB) GetAttachments(entryID string) ([]Attachment, error) {
+	rows, err := db.conn.Query(`
+		SELECT entry_id, source_url, media_id, COALESCE(content_type, ''), size, uploaded_at
+		FROM attachments
+		WHERE entry_id = ?
+		ORDER BY id
+	`, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.EntryID, &a.SourceURL, &a.MediaID, &a.ContentType, &a.Size, &a.UploadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// uploadReuseWindow is how long an upload that hasn't been attached to a
+// status can be reused. Mastodon deletes unattached media after a day.
+const uploadReuseWindow = "-12 hours"
+
+// SaveUploadedMedia records that media with the given hash was uploaded
+// as mediaID, so an identical upload can reuse it.
+func (db *DB) SaveUploadedMedia(hash, mediaID string) error {
+	_, err := db.conn.Exec(
+		"INSERT OR REPLACE INTO media_uploads (hash, media_id, uploaded_at) VALUES (?, ?, CURRENT_TIMESTAMP)",
+		hash, mediaID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save media upload: %w", err)
+	}
+	return nil
+}
+
+// GetUploadedMedia returns the ID of recently uploaded media with the
+// given hash, or "" if there is none.
+func (db *DB) GetUploadedMedia(hash string) (string, error) {
+	var mediaID string
+	err := db.conn.QueryRow(
+		"SELECT media_id FROM media_uploads WHERE hash = ? AND uploaded_at > datetime('now', ?)",
+		hash, uploadReuseWindow,
+	).Scan(&mediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query media uploads: %w", err)
+	}
+	return mediaID, nil
+}
+
+// ForgetUploadedMedia stops media from being reused once it's attached to
+// a status, since servers ignore media that's already attached.
+func (db *DB) ForgetUploadedMedia(mediaID string) error {
+	if _, err := db.conn.Exec("DELETE FROM media_uploads WHERE media_id = ?", mediaID); err != nil {
+		return fmt.Errorf("failed to forget media upload: %w", err)
+	}
+	return nil
+}
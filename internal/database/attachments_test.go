@@ -0,0 +1,104 @@
+package database
+
+import "testing"
+
+func TestAttachments(t *testing.T) {
+	t.Run("saves and lists attachments", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		for _, a := range []Attachment{
+			{EntryID: "entry-1", SourceURL: "https://example.com/a.jpg", MediaID: "101", ContentType: "image/jpeg", Size: 1024},
+			{EntryID: "entry-1", SourceURL: "https://example.com/b.png", MediaID: "102", ContentType: "image/png", Size: 2048},
+			{EntryID: "entry-2", SourceURL: "https://example.com/c.gif", MediaID: "103"},
+		} {
+			if err := db.SaveAttachment(a); err != nil {
+				t.Fatalf("SaveAttachment() error = %v", err)
+			}
+		}
+
+		attachments, err := db.GetAttachments("entry-1")
+		if err != nil {
+			t.Fatalf("GetAttachments() error = %v", err)
+		}
+		if len(attachments) != 2 {
+			t.Fatalf("GetAttachments() returned %d attachments, want 2", len(attachments))
+		}
+		if attachments[0].MediaID != "101" || attachments[1].MediaID != "102" {
+			t.Errorf("attachments out of order: %+v", attachments)
+		}
+		if attachments[1].Size != 2048 || attachments[1].ContentType != "image/png" {
+			t.Errorf("attachment fields not stored: %+v", attachments[1])
+		}
+		if attachments[0].UploadedAt == nil {
+			t.Error("UploadedAt should be set")
+		}
+	})
+
+	t.Run("deleting entries removes their attachments", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.SaveEntry("entry-1", []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if err := db.SaveAttachment(Attachment{EntryID: "entry-1", SourceURL: "https://example.com/a.jpg", MediaID: "101"}); err != nil {
+			t.Fatalf("SaveAttachment() error = %v", err)
+		}
+
+		if _, err := db.DeleteEntries([]string{"entry-1"}); err != nil {
+			t.Fatalf("DeleteEntries() error = %v", err)
+		}
+
+		attachments, err := db.GetAttachments("entry-1")
+		if err != nil {
+			t.Fatalf("GetAttachments() error = %v", err)
+		}
+		if len(attachments) != 0 {
+			t.Errorf("GetAttachments() returned %d attachments after delete, want 0", len(attachments))
+		}
+	})
+}
+
+func TestUploadedMedia(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SaveUploadedMedia("abc", "201"); err != nil {
+		t.Fatalf("SaveUploadedMedia() error = %v", err)
+	}
+	if id, err := db.GetUploadedMedia("abc"); err != nil || id != "201" {
+		t.Errorf("GetUploadedMedia() = %q, %v; want 201", id, err)
+	}
+	if id, err := db.GetUploadedMedia("def"); err != nil || id != "" {
+		t.Errorf("GetUploadedMedia() = %q, %v; want none for another hash", id, err)
+	}
+
+	// Media attached to a posted status can't be attached again
+	if err := db.ForgetUploadedMedia("201"); err != nil {
+		t.Fatalf("ForgetUploadedMedia() error = %v", err)
+	}
+	if id, err := db.GetUploadedMedia("abc"); err != nil || id != "" {
+		t.Errorf("GetUploadedMedia() = %q, %v; want none once attached", id, err)
+	}
+
+	// Old uploads may have been deleted by the server
+	if err := db.SaveUploadedMedia("ghi", "202"); err != nil {
+		t.Fatalf("SaveUploadedMedia() error = %v", err)
+	}
+	if _, err := db.conn.Exec("UPDATE media_uploads SET uploaded_at = datetime('now', '-1 day') WHERE hash = 'ghi'"); err != nil {
+		t.Fatalf("failed to age upload: %v", err)
+	}
+	if id, err := db.GetUploadedMedia("ghi"); err != nil || id != "" {
+		t.Errorf("GetUploadedMedia() = %q, %v; want none after the reuse window", id, err)
+	}
+}
@@ -1,28 +1,80 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/lorchard/feed-to-mastodon/internal/secret"
+	"github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultBusyTimeout is how long a connection waits for another to release
+// its lock by default, before failing with "database is locked".
+const DefaultBusyTimeout = 5 * time.Second
+
 // DB wraps the SQLite database connection.
 type DB struct {
 	conn *sql.DB
+
+	// settings caches the settings read and written through this
+	// connection, with nil for those that aren't set.
+	settingsMu sync.Mutex
+	settings   map[string]*string
+
+	// secrets encrypts secret settings, if set.
+	secrets *secret.Cipher
+}
+
+// Options tunes the SQLite connection, e.g. for overlapping runs or a
+// database on network storage.
+type Options struct {
+	// BusyTimeout is how long to wait for another connection's lock.
+	BusyTimeout time.Duration
+	// Synchronous is the synchronous pragma: off, normal, full, or extra.
+	// Empty means normal, which is safe with the write-ahead log.
+	Synchronous string
+	// CacheSize is the page cache size in KiB, or 0 for SQLite's default.
+	CacheSize int
 }
 
-// New creates and initializes a new database connection.
+// New creates and initializes a new database connection with the default
+// options.
 func New(dbPath string) (*DB, error) {
+	return NewWithOptions(dbPath, Options{BusyTimeout: DefaultBusyTimeout})
+}
+
+// NewWithOptions creates and initializes a new database connection.
+func NewWithOptions(dbPath string, opts Options) (*DB, error) {
 	logrus.Infof("Opening database: %s", dbPath)
 
 	// Open SQLite database with proper pragmas
-	conn, err := sql.Open("sqlite3", fmt.Sprintf("%s?_foreign_keys=ON&_journal_mode=WAL", dbPath))
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+	params := url.Values{}
+	params.Set("_foreign_keys", "ON")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_busy_timeout", fmt.Sprint(opts.BusyTimeout.Milliseconds()))
+	if opts.Synchronous != "" {
+		params.Set("_synchronous", strings.ToUpper(opts.Synchronous))
 	}
 
+	sqliteDriver := &sqlite3.SQLiteDriver{}
+	if opts.CacheSize > 0 {
+		// Negative sizes are in KiB rather than pages
+		pragma := fmt.Sprintf("PRAGMA cache_size = -%d", opts.CacheSize)
+		sqliteDriver.ConnectHook = func(c *sqlite3.SQLiteConn) error {
+			_, err := c.Exec(pragma, nil)
+			return err
+		}
+	}
+	conn := sql.OpenDB(connector{driver: sqliteDriver, dsn: dbPath + "?" + params.Encode()})
+
 	db := &DB{conn: conn}
 
 	// Initialize schema
@@ -41,55 +93,421 @@ func New(dbPath string) (*DB, error) {
 	return db, nil
 }
 
-// Close closes the database connection.
+// connector opens connections with a configured driver, since every
+// connection in the pool needs the same pragmas.
+type connector struct {
+	driver *sqlite3.SQLiteDriver
+	dsn    string
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close closes the database connection, first moving the write-ahead log
+// into the database so it's left as a single file.
 func (db *DB) Close() error {
-	if db.conn != nil {
-		return db.conn.Close()
+	if db.conn == nil {
+		return nil
 	}
-	return nil
+	// Another process reading the database can keep the log from being
+	// truncated, which is harmless
+	if _, err := db.conn.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		logrus.Debugf("Failed to checkpoint database: %v", err)
+	}
+	return db.conn.Close()
 }
 
 // Entry represents a feed entry in the database.
 type Entry struct {
-	ID        string
-	EntryData []byte
-	PostedAt  *sql.NullTime
-	FetchedAt sql.NullTime
-	CreatedAt sql.NullTime
+	ID            string
+	EntryData     []byte
+	PostedAt      *sql.NullTime
+	FetchedAt     sql.NullTime
+	CreatedAt     sql.NullTime
+	PostedContent sql.NullString
+	StatusID      sql.NullString
+	StatusURL     sql.NullString
+	FilteredAt    sql.NullTime
+	FilterReason  sql.NullString
+	ChangedAt     sql.NullTime
+	FailureCount  int
+	LastError     sql.NullString
+	RetryAt       sql.NullTime
+	FailedAt      sql.NullTime
+	ScheduledAt   sql.NullTime
+	ScheduledID   sql.NullString
+	DeletedAt     sql.NullTime
+	FullContent   sql.NullString
+	LeadImage     sql.NullString
+	ExtractedAt   sql.NullTime
+	SkippedAt     sql.NullTime
+
+	// ContentWarning is the content warning to post the entry with, if
+	// it differs from the poster's. It is set by the caller before posting
+	// and not stored.
+	ContentWarning string
+
+	// AltText describes the entry's media that the feed doesn't describe.
+	// Like ContentWarning, it is set before posting and not stored.
+	AltText string
+
+	// Attachments holds media uploaded while posting the entry.
+	// It is filled in by the poster and not loaded from the database.
+	Attachments []Attachment
+
+	// ThreadStatusIDs holds the replies a long entry was split into after
+	// its first status, in order. Like Attachments, it is filled in by the
+	// poster; SaveThreadStatuses stores it.
+	ThreadStatusIDs []string
+}
+
+// Entry states, as returned by Entry.State and selected by ListEntries.
+const (
+	StateUnposted = "unposted"
+	StatePosted   = "posted"
+	StateFailed   = "failed"
+	StateFiltered = "filtered"
+	StateSkipped  = "skipped"
+)
+
+// Orders of the unposted queue, as selected by GetUnpostedEntriesOrdered.
+const (
+	// OrderOldest posts entries in the order they were fetched.
+	OrderOldest = "oldest"
+	// OrderNewest posts the most recently fetched entries first.
+	OrderNewest = "newest"
+	// OrderRandom posts entries in a random order.
+	OrderRandom = "random"
+	// OrderPublished posts entries in the order the feed published them,
+	// falling back to when they were fetched for items without a date.
+	OrderPublished = "published"
+)
+
+// queueOrders holds the SQL ORDER BY clauses for each queue order.
+// Entries fetched together, which share a fetched_at, are ordered by
+// their published dates, with undated entries in feed order after them.
+var queueOrders = map[string]string{
+	OrderOldest:    "fetched_at ASC, COALESCE(published_at, fetched_at) ASC, rowid ASC",
+	OrderNewest:    "fetched_at DESC, published_at DESC, rowid ASC",
+	OrderRandom:    "RANDOM()",
+	OrderPublished: "COALESCE(published_at, fetched_at) ASC, rowid ASC",
+}
+
+// stateConditions holds the SQL conditions selecting entries in each state.
+var stateConditions = map[string]string{
+	StateUnposted: "posted_at IS NULL AND skipped_at IS NULL AND filtered_at IS NULL AND failure_count = 0",
+	StatePosted:   "posted_at IS NOT NULL",
+	StateFailed:   "posted_at IS NULL AND skipped_at IS NULL AND filtered_at IS NULL AND failure_count > 0",
+	StateFiltered: "posted_at IS NULL AND skipped_at IS NULL AND filtered_at IS NOT NULL",
+	StateSkipped:  "posted_at IS NULL AND skipped_at IS NOT NULL",
 }
 
-// SaveEntry inserts a new entry or ignores if it already exists.
-func (db *DB) SaveEntry(id string, entryJSON []byte) error {
+// State returns whether the entry was posted, was skipped, was held back by
+// filters, failed to post, or is waiting to be posted.
+func (e *Entry) State() string {
+	switch {
+	case e.PostedAt != nil && e.PostedAt.Valid:
+		return StatePosted
+	case e.SkippedAt.Valid:
+		return StateSkipped
+	case e.FilteredAt.Valid:
+		return StateFiltered
+	case e.FailureCount > 0:
+		return StateFailed
+	default:
+		return StateUnposted
+	}
+}
+
+// entryColumns lists the entries columns read by scanEntry, in order.
+const entryColumns = `id, entry_data, posted_at, fetched_at, created_at, posted_content, status_id, status_url,
+		filtered_at, filter_reason, changed_at, failure_count, last_error, retry_at, failed_at, scheduled_at, scheduled_id, deleted_at,
+		full_content, lead_image, extracted_at, skipped_at`
+
+// scanEntry scans a row selected with entryColumns.
+func scanEntry(row interface{ Scan(...any) error }) (*Entry, error) {
+	entry := &Entry{}
+	err := row.Scan(
+		&entry.ID, &entry.EntryData, &entry.PostedAt, &entry.FetchedAt, &entry.CreatedAt, &entry.PostedContent,
+		&entry.StatusID, &entry.StatusURL, &entry.FilteredAt, &entry.FilterReason, &entry.ChangedAt,
+		&entry.FailureCount, &entry.LastError, &entry.RetryAt, &entry.FailedAt, &entry.ScheduledAt, &entry.ScheduledID,
+		&entry.DeletedAt, &entry.FullContent, &entry.LeadImage, &entry.ExtractedAt, &entry.SkippedAt,
+	)
+	return entry, err
+}
+
+// SaveEntry inserts a new entry, leaving an existing entry with the same
+// ID alone. Returns true if the entry was new. An entry that was purged
+// after it was posted comes back posted, and isn't new.
+func (db *DB) SaveEntry(id string, entryJSON []byte) (bool, error) {
 	query := `
-		INSERT OR IGNORE INTO entries (id, entry_data, fetched_at, posted_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP, NULL)
+		INSERT OR IGNORE INTO entries (id, entry_data, fetched_at, posted_at, published_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP, NULL, ?)
 	`
 
-	_, err := db.conn.Exec(query, id, entryJSON)
+	result, err := db.conn.Exec(query, id, entryJSON, publishedAt(entryJSON))
+	if err != nil {
+		return false, fmt.Errorf("failed to save entry: %w", err)
+	}
+	inserted, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to save entry: %w", err)
+		return false, fmt.Errorf("failed to check saved entry: %w", err)
+	}
+
+	if inserted == 0 {
+		return false, nil
+	}
+	restored, err := db.restoreTombstone(context.Background(), id)
+	if err != nil {
+		return false, err
+	}
+	if restored {
+		return false, nil
 	}
 
 	logrus.Debugf("Saved entry: %s", id)
+	return true, nil
+}
+
+// publishedAt returns when the feed item in entryJSON was published, or
+// last updated if it has no published date, for the published_at column.
+// Returns nil for items with neither date.
+func publishedAt(entryJSON []byte) any {
+	var item struct {
+		PublishedParsed *time.Time `json:"publishedParsed"`
+		UpdatedParsed   *time.Time `json:"updatedParsed"`
+	}
+	if err := json.Unmarshal(entryJSON, &item); err != nil {
+		return nil
+	}
+	switch {
+	case item.PublishedParsed != nil:
+		return dbTime(*item.PublishedParsed)
+	case item.UpdatedParsed != nil:
+		return dbTime(*item.UpdatedParsed)
+	}
+	return nil
+}
+
+// SaveResult describes what SaveEntryContent did with an entry.
+type SaveResult int
+
+const (
+	// SaveUnchanged means the entry was already stored with the same
+	// content.
+	SaveUnchanged SaveResult = iota
+	// SaveInserted means the entry was new.
+	SaveInserted
+	// SaveUpdated means the stored entry's content was updated.
+	SaveUpdated
+	// SaveChanged means the stored entry's content was updated after it
+	// was first saved with a content hash, i.e. the entry was edited.
+	SaveChanged
+)
+
+// SaveEntryContent inserts a new entry, or updates an existing one whose
+// content hash differs from the stored one. When a posted entry's content
+// changes, it's flagged as changed so its status can be edited. An entry
+// that was purged after it was posted comes back posted, as SaveUpdated.
+func (db *DB) SaveEntryContent(id string, entryJSON []byte, contentHash string) (SaveResult, error) {
+	return db.SaveEntryContentContext(context.Background(), id, entryJSON, contentHash)
+}
+
+// SaveEntryContentContext is like SaveEntryContent, but its queries are
+// cancelled when ctx is done.
+func (db *DB) SaveEntryContentContext(ctx context.Context, id string, entryJSON []byte, contentHash string) (SaveResult, error) {
+	published := publishedAt(entryJSON)
+	result, err := db.conn.ExecContext(ctx, `
+		INSERT OR IGNORE INTO entries (id, entry_data, fetched_at, posted_at, content_hash, published_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP, NULL, ?, ?)
+	`, id, entryJSON, contentHash, published)
+	if err != nil {
+		return SaveUnchanged, fmt.Errorf("failed to save entry: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return SaveUnchanged, fmt.Errorf("failed to check saved entry: %w", err)
+	}
+	if inserted > 0 {
+		if restored, err := db.restoreTombstone(ctx, id); err != nil {
+			return SaveUnchanged, err
+		} else if restored {
+			return SaveUpdated, nil
+		}
+		logrus.Debugf("Saved entry: %s", id)
+		return SaveInserted, nil
+	}
+
+	var storedHash sql.NullString
+	var posted bool
+	err = db.conn.QueryRowContext(ctx,
+		"SELECT content_hash, posted_at IS NOT NULL FROM entries WHERE id = ?", id,
+	).Scan(&storedHash, &posted)
+	if err != nil {
+		return SaveUnchanged, fmt.Errorf("failed to look up entry: %w", err)
+	}
+
+	if storedHash.String == contentHash {
+		return SaveUnchanged, nil
+	}
+
+	// Entries saved before hashes were stored just get their hash recorded
+	changed := storedHash.Valid
+	query := "UPDATE entries SET entry_data = ?, content_hash = ?, published_at = ? WHERE id = ?"
+	if changed && posted {
+		query = "UPDATE entries SET entry_data = ?, content_hash = ?, published_at = ?, changed_at = CURRENT_TIMESTAMP WHERE id = ?"
+	}
+	if _, err := db.conn.ExecContext(ctx, query, entryJSON, contentHash, published, id); err != nil {
+		return SaveUnchanged, fmt.Errorf("failed to update entry: %w", err)
+	}
+
+	if !changed {
+		return SaveUpdated, nil
+	}
+	logrus.Debugf("Entry content changed: %s", id)
+	return SaveChanged, nil
+}
+
+// GetChangedEntries retrieves posted entries whose content changed since
+// they were posted and whose status can be edited, i.e. wasn't deleted.
+func (db *DB) GetChangedEntries() ([]*Entry, error) {
+	return db.GetChangedEntriesContext(context.Background())
+}
+
+// GetChangedEntriesContext is like GetChangedEntries, but the query is
+// cancelled when ctx is done.
+func (db *DB) GetChangedEntriesContext(ctx context.Context) ([]*Entry, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT `+entryColumns+`
+		FROM entries
+		WHERE changed_at IS NOT NULL AND status_id IS NOT NULL AND deleted_at IS NULL
+		ORDER BY changed_at ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changed entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ClearChanged records that an entry's status was edited to match its
+// changed content.
+func (db *DB) ClearChanged(id string) error {
+	if _, err := db.conn.Exec("UPDATE entries SET changed_at = NULL WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to clear changed flag: %w", err)
+	}
+	return nil
+}
+
+// GetUnextractedEntries retrieves the entries waiting to be posted whose
+// linked articles haven't been extracted yet, oldest first.
+func (db *DB) GetUnextractedEntries() ([]*Entry, error) {
+	rows, err := db.conn.Query(`
+		SELECT ` + entryColumns + `
+		FROM entries
+		WHERE posted_at IS NULL AND skipped_at IS NULL AND filtered_at IS NULL AND extracted_at IS NULL
+		ORDER BY fetched_at ASC, id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unextracted entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// SetArticle stores the readable content and lead image extracted from
+// an entry's linked article. Empty values record that extraction was
+// tried, so it isn't tried again.
+func (db *DB) SetArticle(id, content, leadImage string) error {
+	result, err := db.conn.Exec(`
+		UPDATE entries SET full_content = ?, lead_image = ?, extracted_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, nullString(content), nullString(leadImage), id)
+	if err != nil {
+		return fmt.Errorf("failed to set article: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("entry not found: %s", id)
+	}
+
+	logrus.Debugf("Stored article for entry: %s", id)
 	return nil
 }
 
-// GetUnpostedEntries retrieves entries that haven't been posted yet.
+// GetUnpostedEntries retrieves entries that haven't been posted or filtered yet.
+// Entries waiting to retry after a failure are left out until their retry
+// time, and entries that were given up on are left out entirely.
 // If limit > 0, returns at most that many entries.
-// Returns oldest entries first (by fetched_at).
+// Returns oldest entries first (by fetched_at, then by published date).
 func (db *DB) GetUnpostedEntries(limit int) ([]*Entry, error) {
+	return db.GetUnpostedEntriesContext(context.Background(), limit)
+}
+
+// GetUnpostedEntriesContext is like GetUnpostedEntries, but the query is
+// cancelled when ctx is done.
+func (db *DB) GetUnpostedEntriesContext(ctx context.Context, limit int) ([]*Entry, error) {
+	return db.GetUnpostedEntriesOrdered(ctx, limit, OrderOldest)
+}
+
+// GetUnpostedEntriesOrdered is like GetUnpostedEntriesContext, but returns
+// the entries in the given order, e.g. OrderNewest.
+func (db *DB) GetUnpostedEntriesOrdered(ctx context.Context, limit int, order string) ([]*Entry, error) {
+	orderBy, ok := queueOrders[order]
+	if !ok {
+		return nil, fmt.Errorf("unknown post order: %s", order)
+	}
+
 	query := `
-		SELECT id, entry_data, posted_at, fetched_at, created_at
+		SELECT ` + entryColumns + `
 		FROM entries
-		WHERE posted_at IS NULL
-		ORDER BY fetched_at ASC
+		WHERE posted_at IS NULL AND skipped_at IS NULL AND filtered_at IS NULL AND failed_at IS NULL
+			AND (retry_at IS NULL OR retry_at <= ?)
+		ORDER BY ` + orderBy + `
 	`
 
 	if limit > 0 {
 		query += fmt.Sprintf(" LIMIT %d", limit)
 	}
 
-	rows, err := db.conn.Query(query)
+	rows, err := db.conn.QueryContext(ctx, query, dbTime(time.Now()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query unposted entries: %w", err)
 	}
@@ -97,8 +515,7 @@ func (db *DB) GetUnpostedEntries(limit int) ([]*Entry, error) {
 
 	entries := make([]*Entry, 0)
 	for rows.Next() {
-		entry := &Entry{}
-		err := rows.Scan(&entry.ID, &entry.EntryData, &entry.PostedAt, &entry.FetchedAt, &entry.CreatedAt)
+		entry, err := scanEntry(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan entry: %w", err)
 		}
@@ -112,11 +529,53 @@ func (db *DB) GetUnpostedEntries(limit int) ([]*Entry, error) {
 	return entries, nil
 }
 
-// MarkAsPosted updates an entry's posted_at timestamp to the current time.
-func (db *DB) MarkAsPosted(id string) error {
-	query := `UPDATE entries SET posted_at = CURRENT_TIMESTAMP WHERE id = ?`
+// GetEntry retrieves a single entry by ID.
+// Returns nil if the entry doesn't exist.
+func (db *DB) GetEntry(id string) (*Entry, error) {
+	query := `
+		SELECT ` + entryColumns + `
+		FROM entries
+		WHERE id = ?
+	`
 
-	result, err := db.conn.Exec(query, id)
+	entry, err := scanEntry(db.conn.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry %s: %w", id, err)
+	}
+
+	return entry, nil
+}
+
+// SetPostedContent stores the exact text that was sent to Mastodon for an entry.
+func (db *DB) SetPostedContent(id, content string) error {
+	result, err := db.conn.Exec("UPDATE entries SET posted_content = ? WHERE id = ?", content, id)
+	if err != nil {
+		return fmt.Errorf("failed to set posted content: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("entry not found: %s", id)
+	}
+
+	logrus.Debugf("Stored posted content for entry: %s", id)
+	return nil
+}
+
+// MarkAsPosted updates an entry's posted_at timestamp to the current time and
+// records the ID and URL of the Mastodon status it was posted as.
+// Pass empty strings for entries that were skipped rather than posted.
+func (db *DB) MarkAsPosted(id, statusID, statusURL string) error {
+	query := `UPDATE entries SET posted_at = CURRENT_TIMESTAMP, status_id = ?, status_url = ? WHERE id = ?`
+
+	result, err := db.conn.Exec(query, nullString(statusID), nullString(statusURL), id)
 	if err != nil {
 		return fmt.Errorf("failed to mark entry as posted: %w", err)
 	}
@@ -134,7 +593,539 @@ func (db *DB) MarkAsPosted(id string) error {
 	return nil
 }
 
+// CatchUp marks unposted entries as posted without posting them, like the
+// catchup command, except for the keep most recently published ones.
+// Returns the number of entries marked.
+func (db *DB) CatchUp(keep int) (int, error) {
+	unposted := stateConditions[StateUnposted]
+	result, err := db.conn.Exec(`
+		UPDATE entries SET posted_at = CURRENT_TIMESTAMP
+		WHERE `+unposted+` AND id NOT IN (
+			SELECT id FROM entries
+			WHERE `+unposted+`
+			ORDER BY COALESCE(published_at, fetched_at) DESC, rowid ASC
+			LIMIT ?
+		)
+	`, max(keep, 0))
+	if err != nil {
+		return 0, fmt.Errorf("failed to catch up entries: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	logrus.Debugf("Caught up %d entries", rows)
+	return int(rows), nil
+}
+
+// MarkAsScheduled marks an entry as posted by scheduling a status for the
+// given time, recording the ID of the scheduled status.
+func (db *DB) MarkAsScheduled(id, scheduledID string, scheduledAt time.Time) error {
+	query := `UPDATE entries SET posted_at = CURRENT_TIMESTAMP, scheduled_at = ?, scheduled_id = ? WHERE id = ?`
+
+	result, err := db.conn.Exec(query, dbTime(scheduledAt), nullString(scheduledID), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark entry as scheduled: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("entry not found: %s", id)
+	}
+
+	logrus.Debugf("Marked entry as scheduled: %s", id)
+	return nil
+}
+
+// GetLastScheduledAt returns the latest time a status is scheduled for,
+// or nil if no statuses are scheduled in the future.
+func (db *DB) GetLastScheduledAt() (*time.Time, error) {
+	var scheduledAt sql.NullTime
+	err := db.conn.QueryRow(`
+		SELECT scheduled_at FROM entries
+		WHERE scheduled_at > ?
+		ORDER BY scheduled_at DESC
+		LIMIT 1
+	`, dbTime(time.Now())).Scan(&scheduledAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last scheduled time: %w", err)
+	}
+
+	return &scheduledAt.Time, nil
+}
+
+// MarkAsFiltered records that an entry was held back by the entry filters,
+// so it won't be posted.
+func (db *DB) MarkAsFiltered(id, reason string) error {
+	result, err := db.conn.Exec(
+		"UPDATE entries SET filtered_at = CURRENT_TIMESTAMP, filter_reason = ? WHERE id = ?",
+		reason, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark entry as filtered: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("entry not found: %s", id)
+	}
+
+	logrus.Debugf("Marked entry as filtered: %s (%s)", id, reason)
+	return nil
+}
+
+// MarkAsSkipped records that an entry was skipped, so it won't be posted
+// but is kept, unlike a deleted entry, which the next fetch adds again.
+// Returns false if the entry was already posted or skipped.
+func (db *DB) MarkAsSkipped(id string) (bool, error) {
+	result, err := db.conn.Exec(
+		"UPDATE entries SET skipped_at = CURRENT_TIMESTAMP WHERE id = ? AND posted_at IS NULL AND skipped_at IS NULL",
+		id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark entry as skipped: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return false, nil
+	}
+
+	logrus.Debugf("Marked entry as skipped: %s", id)
+	return true, nil
+}
+
+// GetSkippedCount returns the number of skipped entries.
+func (db *DB) GetSkippedCount() (int, error) {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM entries WHERE skipped_at IS NOT NULL AND posted_at IS NULL").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get skipped count: %w", err)
+	}
+	return count, nil
+}
+
+// GetFilteredCount returns the number of entries held back by filters.
+func (db *DB) GetFilteredCount() (int, error) {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM entries WHERE filtered_at IS NOT NULL AND posted_at IS NULL AND skipped_at IS NULL").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get filtered count: %w", err)
+	}
+	return count, nil
+}
+
+// RecordFailure records a failed attempt to post an entry. The entry is
+// retried at retryAt, or given up on if retryAt is nil.
+func (db *DB) RecordFailure(id, message string, retryAt *time.Time) error {
+	query := `
+		UPDATE entries
+		SET failure_count = failure_count + 1, last_error = ?, retry_at = ?,
+			failed_at = CASE WHEN ? THEN CURRENT_TIMESTAMP ELSE NULL END
+		WHERE id = ?
+	`
+
+	var retry any
+	if retryAt != nil {
+		retry = dbTime(*retryAt)
+	}
+
+	result, err := db.conn.Exec(query, message, retry, retryAt == nil, id)
+	if err != nil {
+		return fmt.Errorf("failed to record failure: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("entry not found: %s", id)
+	}
+
+	logrus.Debugf("Recorded failure for entry: %s", id)
+	return nil
+}
+
+// GetFailedEntries retrieves unposted entries that have failed to post,
+// both those waiting to retry and those given up on, most failures first.
+func (db *DB) GetFailedEntries() ([]*Entry, error) {
+	rows, err := db.conn.Query(`
+		SELECT ` + entryColumns + `
+		FROM entries
+		WHERE posted_at IS NULL AND skipped_at IS NULL AND failure_count > 0
+		ORDER BY failure_count DESC, fetched_at ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ResetFailures clears the failure history of an unposted entry, so it is
+// posted again on the next run. Returns false if the entry had no failures.
+func (db *DB) ResetFailures(id string) (bool, error) {
+	result, err := db.conn.Exec(`
+		UPDATE entries
+		SET failure_count = 0, last_error = NULL, retry_at = NULL, failed_at = NULL
+		WHERE id = ? AND posted_at IS NULL AND failure_count > 0
+	`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to reset failures: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// GetPostedEntries retrieves entries posted or filtered at or after since,
+// or all of them if since is zero, most recently posted first.
+func (db *DB) GetPostedEntries(since time.Time) ([]*Entry, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+entryColumns+`
+		FROM entries
+		WHERE COALESCE(posted_at, filtered_at) >= ?
+		ORDER BY COALESCE(posted_at, filtered_at) DESC
+	`, dbTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query posted entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetExpiredEntries retrieves the entries posted before the given time
+// whose statuses haven't been deleted, oldest first. Entries posted as
+// scheduled statuses are left out, as their status IDs aren't known.
+func (db *DB) GetExpiredEntries(before time.Time) ([]*Entry, error) {
+	return db.GetExpiredEntriesContext(context.Background(), before)
+}
+
+// GetExpiredEntriesContext is like GetExpiredEntries, but the query is
+// cancelled when ctx is done.
+func (db *DB) GetExpiredEntriesContext(ctx context.Context, before time.Time) ([]*Entry, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT `+entryColumns+`
+		FROM entries
+		WHERE posted_at < ? AND status_id IS NOT NULL AND deleted_at IS NULL
+		ORDER BY posted_at ASC
+	`, dbTime(before))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// MarkAsDeleted records that an entry's status was deleted from Mastodon.
+// The entry stays posted, so it isn't posted again.
+func (db *DB) MarkAsDeleted(id string) error {
+	result, err := db.conn.Exec("UPDATE entries SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to mark entry as deleted: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("entry not found: %s", id)
+	}
+
+	logrus.Debugf("Marked entry as deleted: %s", id)
+	return nil
+}
+
+// PostedStatus is a status of the main account that's still on Mastodon,
+// as far as the database knows.
+type PostedStatus struct {
+	EntryID  string
+	StatusID string
+	// Purged is set when the entry was purged, leaving its tombstone.
+	Purged bool
+}
+
+// GetPostedStatuses returns the statuses posted for entries, and for
+// purged entries, that haven't been deleted, oldest first. Statuses that
+// were scheduled are left out, as their IDs aren't known.
+func (db *DB) GetPostedStatuses() ([]PostedStatus, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, status_id, 0, posted_at FROM entries
+		WHERE status_id IS NOT NULL AND deleted_at IS NULL
+		UNION ALL
+		SELECT id, status_id, 1, posted_at FROM tombstones
+		WHERE status_id IS NOT NULL AND deleted_at IS NULL
+		ORDER BY posted_at ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query posted statuses: %w", err)
+	}
+	defer rows.Close()
+
+	var statuses []PostedStatus
+	for rows.Next() {
+		var s PostedStatus
+		// posted_at is only selected to order by
+		var postedAt any
+		if err := rows.Scan(&s.EntryID, &s.StatusID, &s.Purged, &postedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan posted status: %w", err)
+		}
+		statuses = append(statuses, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating posted statuses: %w", err)
+	}
+
+	return statuses, nil
+}
+
+// GetPostedStatus returns the status an entry was posted as, from its
+// tombstone if the entry was purged, so it can be deleted. Returns an
+// error if the entry is unknown, its status was already deleted, or it has
+// no status ID, e.g. because it was posted as a scheduled status.
+func (db *DB) GetPostedStatus(id string) (*PostedStatus, error) {
+	entry, err := db.GetEntry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Purged entries that were posted only have their tombstone left
+	purged := entry == nil
+	if purged {
+		tombstone, err := db.GetTombstone(id)
+		if err != nil {
+			return nil, err
+		}
+		if tombstone == nil {
+			return nil, fmt.Errorf("entry %s not found", id)
+		}
+		entry = &Entry{ID: id, StatusID: tombstone.StatusID, DeletedAt: tombstone.DeletedAt}
+	}
+
+	if entry.DeletedAt.Valid {
+		return nil, fmt.Errorf("entry %s's status was already deleted", id)
+	}
+	if !entry.StatusID.Valid {
+		if entry.ScheduledID.Valid {
+			return nil, fmt.Errorf("entry %s was posted as a scheduled status, whose ID isn't known - delete it on Mastodon", id)
+		}
+		return nil, fmt.Errorf("entry %s has no status to delete", id)
+	}
+	return &PostedStatus{EntryID: id, StatusID: entry.StatusID.String, Purged: purged}, nil
+}
+
+// RecordStatusDeleted records that a status was deleted from Mastodon. The
+// entry, or the tombstone of a purged entry, stays posted and is marked as
+// deleted, so it isn't posted again. With requeue set, the entry goes back
+// to the queue instead, which a purged entry can't.
+func (db *DB) RecordStatusDeleted(status PostedStatus, requeue bool) error {
+	switch {
+	case requeue && status.Purged:
+		return fmt.Errorf("can't requeue purged entry %s", status.EntryID)
+	case requeue:
+		_, err := db.Requeue(status.EntryID)
+		return err
+	case status.Purged:
+		return db.MarkTombstoneDeleted(status.EntryID)
+	default:
+		return db.MarkAsDeleted(status.EntryID)
+	}
+}
+
+// ListOptions selects the entries returned by ListEntries.
+type ListOptions struct {
+	// State, if set, is the state entries must be in, e.g. StatePosted.
+	State string
+	// Since, if set, is the earliest time entries were fetched.
+	Since time.Time
+	// Limit, if > 0, is the most entries to return.
+	Limit int
+	// Offset is the number of matching entries to skip, for paging.
+	Offset int
+}
+
+// ListEntries retrieves the entries selected by opts, newest first.
+func (db *DB) ListEntries(opts ListOptions) ([]*Entry, error) {
+	where := "fetched_at >= ?"
+	if opts.State != "" {
+		condition, ok := stateConditions[opts.State]
+		if !ok {
+			return nil, fmt.Errorf("unknown entry state: %s", opts.State)
+		}
+		where += " AND " + condition
+	}
+
+	query := `
+		SELECT ` + entryColumns + `
+		FROM entries
+		WHERE ` + where + `
+		ORDER BY fetched_at DESC, id ASC
+	`
+	if opts.Limit > 0 || opts.Offset > 0 {
+		limit := opts.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, opts.Offset)
+	}
+
+	rows, err := db.conn.Query(query, dbTime(opts.Since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Requeue returns a posted, skipped, or filtered entry to the queue,
+// clearing its status, posted content, failure history, and cross-posts so
+// it is posted again on the next run. Returns false if the entry wasn't
+// posted, skipped, or filtered.
+func (db *DB) Requeue(id string) (bool, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec(`
+		UPDATE entries
+		SET posted_at = NULL, status_id = NULL, status_url = NULL, posted_content = NULL,
+			scheduled_at = NULL, scheduled_id = NULL, changed_at = NULL, deleted_at = NULL,
+			filtered_at = NULL, filter_reason = NULL, skipped_at = NULL,
+			failure_count = 0, last_error = NULL, retry_at = NULL, failed_at = NULL
+		WHERE id = ? AND (posted_at IS NOT NULL OR filtered_at IS NOT NULL OR skipped_at IS NOT NULL)
+	`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue entry: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return false, nil
+	}
+
+	// Cross-post it to the other accounts again too
+	if _, err := tx.Exec("DELETE FROM account_posts WHERE entry_id = ?", id); err != nil {
+		return false, fmt.Errorf("failed to requeue account posts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit requeue: %w", err)
+	}
+	logrus.Debugf("Requeued entry: %s", id)
+	return true, nil
+}
+
+// GetFailureCounts returns the number of unposted entries waiting to retry
+// after a failure, and the number given up on.
+func (db *DB) GetFailureCounts() (retrying, gaveUp int, err error) {
+	err = db.conn.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN failed_at IS NULL THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN failed_at IS NOT NULL THEN 1 ELSE 0 END), 0)
+		FROM entries
+		WHERE posted_at IS NULL AND skipped_at IS NULL AND failure_count > 0
+	`).Scan(&retrying, &gaveUp)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get failure counts: %w", err)
+	}
+	return retrying, gaveUp, nil
+}
+
+// dbTime formats t for storage and comparison in DATETIME columns, in the
+// same format SQLite uses for CURRENT_TIMESTAMP.
+func dbTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
 // GetStats returns statistics about entries in the database.
+// Filtered entries and entries given up on are not counted as unposted.
 func (db *DB) GetStats() (total, posted, unposted int, err error) {
 	// Get total count
 	err = db.conn.QueryRow("SELECT COUNT(*) FROM entries").Scan(&total)
@@ -149,7 +1140,7 @@ func (db *DB) GetStats() (total, posted, unposted int, err error) {
 	}
 
 	// Get unposted count
-	err = db.conn.QueryRow("SELECT COUNT(*) FROM entries WHERE posted_at IS NULL").Scan(&unposted)
+	err = db.conn.QueryRow("SELECT COUNT(*) FROM entries WHERE posted_at IS NULL AND skipped_at IS NULL AND filtered_at IS NULL AND failed_at IS NULL").Scan(&unposted)
 	if err != nil {
 		return 0, 0, 0, fmt.Errorf("failed to get unposted count: %w", err)
 	}
@@ -179,38 +1170,72 @@ func (db *DB) GetLastPostTime() (*string, error) {
 	return postTime, nil
 }
 
-// SetSetting stores a key-value pair in the settings table.
-func (db *DB) SetSetting(key, value string) error {
-	query := `
-		INSERT INTO settings (key, value, updated_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(key) DO UPDATE SET
-			value = excluded.value,
-			updated_at = CURRENT_TIMESTAMP
-	`
-
-	_, err := db.conn.Exec(query, key, value)
+// GetLastPostedAt returns when the most recent status was posted, or nil if
+// none has been. Entries marked as posted by catchup don't count, since
+// nothing was published for them.
+func (db *DB) GetLastPostedAt() (*time.Time, error) {
+	var postedAt sql.NullTime
+	err := db.conn.QueryRow(`
+		SELECT posted_at FROM entries
+		WHERE posted_at IS NOT NULL AND status_id IS NOT NULL
+		ORDER BY posted_at DESC
+		LIMIT 1
+	`).Scan(&postedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to set setting %s: %w", key, err)
+		return nil, fmt.Errorf("failed to get last post time: %w", err)
 	}
 
-	logrus.Debugf("Set setting: %s", key)
-	return nil
+	return &postedAt.Time, nil
 }
 
-// GetSetting retrieves a value from the settings table.
-// Returns nil if the key doesn't exist.
-func (db *DB) GetSetting(key string) (*string, error) {
-	var value string
-	err := db.conn.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+// GetLastStatusURL returns the URL of the most recently posted status,
+// or nil if no posted entry has one.
+func (db *DB) GetLastStatusURL() (*string, error) {
+	var statusURL *string
+	err := db.conn.QueryRow(`
+		SELECT status_url FROM entries
+		WHERE status_url IS NOT NULL
+		ORDER BY posted_at DESC
+		LIMIT 1
+	`).Scan(&statusURL)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get setting %s: %w", key, err)
+		return nil, fmt.Errorf("failed to get last status URL: %w", err)
+	}
+
+	return statusURL, nil
+}
+
+// GetLastStatusID returns the ID of the most recently posted status that
+// wasn't deleted, or "" if there is none. Boosts, recorded with posted
+// content starting "Boosted ", aren't statuses of the account's own.
+func (db *DB) GetLastStatusID() (string, error) {
+	var statusID string
+	err := db.conn.QueryRow(`
+		SELECT status_id FROM entries
+		WHERE posted_at IS NOT NULL AND status_id IS NOT NULL AND deleted_at IS NULL
+			AND (posted_content IS NULL OR posted_content NOT LIKE 'Boosted %')
+		ORDER BY posted_at DESC, rowid DESC
+		LIMIT 1
+	`).Scan(&statusID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get last status ID: %w", err)
 	}
 
-	return &value, nil
+	return statusID, nil
+}
+
+// nullString converts an empty string to NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
 }
 
 // GetAllEntryIDs returns all entry IDs currently in the database.
@@ -237,7 +1262,8 @@ func (db *DB) GetAllEntryIDs() ([]string, error) {
 	return ids, nil
 }
 
-// DeleteEntries deletes entries by their IDs.
+// DeleteEntries deletes entries by their IDs. Posted entries leave a
+// tombstone, so they're saved as posted if they come back.
 // Returns the number of entries deleted.
 func (db *DB) DeleteEntries(ids []string) (int, error) {
 	if len(ids) == 0 {
@@ -246,6 +1272,23 @@ func (db *DB) DeleteEntries(ids []string) (int, error) {
 
 	deleted := 0
 	for _, id := range ids {
+		if _, err := db.conn.Exec("DELETE FROM attachments WHERE entry_id = ?", id); err != nil {
+			logrus.Errorf("Failed to delete attachments for entry %s: %v", id, err)
+			continue
+		}
+		if _, err := db.conn.Exec("DELETE FROM account_posts WHERE entry_id = ?", id); err != nil {
+			logrus.Errorf("Failed to delete account posts for entry %s: %v", id, err)
+			continue
+		}
+		if _, err := db.conn.Exec("DELETE FROM posts WHERE entry_id = ?", id); err != nil {
+			logrus.Errorf("Failed to delete posts for entry %s: %v", id, err)
+			continue
+		}
+		if err := db.buryEntry(id); err != nil {
+			logrus.Errorf("Failed to keep tombstone of entry %s: %v", id, err)
+			continue
+		}
+
 		result, err := db.conn.Exec("DELETE FROM entries WHERE id = ?", id)
 		if err != nil {
 			logrus.Errorf("Failed to delete entry %s: %v", id, err)
@@ -266,3 +1309,72 @@ func (db *DB) DeleteEntries(ids []string) (int, error) {
 
 	return deleted, nil
 }
+
+// Wipe deletes all entries and settings from the database, along with
+// the records of posts, fetches, and uploaded media.
+// Returns the number of entries and settings deleted.
+func (db *DB) Wipe() (entries, settings int, err error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec("DELETE FROM entries")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete entries: %w", err)
+	}
+	entryRows, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM attachments"); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete attachments: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM media_uploads"); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete media uploads: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM account_posts"); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete account posts: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM posts"); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete posts: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM tombstones"); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete tombstones: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM thread_statuses"); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete thread statuses: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM fetch_log"); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete fetch log: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM post_history"); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete post history: %w", err)
+	}
+
+	result, err = tx.Exec("DELETE FROM settings")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete settings: %w", err)
+	}
+	settingRows, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit wipe: %w", err)
+	}
+	db.ReloadSettings()
+
+	logrus.Debugf("Wiped %d entries and %d settings", entryRows, settingRows)
+	return int(entryRows), int(settingRows), nil
+}
@@ -1,9 +1,14 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestDatabaseInitialization(t *testing.T) {
@@ -75,6 +80,73 @@ func TestDatabaseInitialization(t *testing.T) {
 	})
 }
 
+func TestNewWithOptions(t *testing.T) {
+	t.Run("applies pragmas to connections", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "test.db")
+
+		db, err := NewWithOptions(dbPath, Options{BusyTimeout: 7 * time.Second, Synchronous: "full", CacheSize: 4096})
+		if err != nil {
+			t.Fatalf("NewWithOptions() error = %v", err)
+		}
+		defer db.Close()
+
+		pragmas := map[string]int{"busy_timeout": 7000, "synchronous": 2, "cache_size": -4096}
+		for pragma, want := range pragmas {
+			var got int
+			if err := db.conn.QueryRow("PRAGMA " + pragma).Scan(&got); err != nil {
+				t.Fatalf("PRAGMA %s error = %v", pragma, err)
+			}
+			if got != want {
+				t.Errorf("%s = %d, want %d", pragma, got, want)
+			}
+		}
+	})
+
+	t.Run("defaults to busy timeout and normal sync", func(t *testing.T) {
+		db, err := New(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		var busyTimeout, synchronous int
+		db.conn.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout)
+		db.conn.QueryRow("PRAGMA synchronous").Scan(&synchronous)
+		if busyTimeout != int(DefaultBusyTimeout.Milliseconds()) {
+			t.Errorf("busy_timeout = %d, want %d", busyTimeout, DefaultBusyTimeout.Milliseconds())
+		}
+		if synchronous != 1 {
+			t.Errorf("synchronous = %d, want 1 (normal)", synchronous)
+		}
+	})
+
+	t.Run("rejects unknown synchronous mode", func(t *testing.T) {
+		_, err := NewWithOptions(filepath.Join(t.TempDir(), "test.db"), Options{Synchronous: "sometimes"})
+		if err == nil {
+			t.Error("Expected error for unknown synchronous mode")
+		}
+	})
+
+	t.Run("checkpoints write-ahead log on close", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "test.db")
+
+		db, err := New(dbPath)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if _, err := db.SaveEntry("entry-1", []byte(`{"title":"Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if err := db.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		if info, err := os.Stat(dbPath + "-wal"); err == nil && info.Size() > 0 {
+			t.Errorf("Write-ahead log is %d bytes after Close(), want empty", info.Size())
+		}
+	})
+}
+
 func TestSaveEntry(t *testing.T) {
 	t.Run("saves new entry", func(t *testing.T) {
 		db, err := New(":memory:")
@@ -84,7 +156,7 @@ func TestSaveEntry(t *testing.T) {
 		defer db.Close()
 
 		entryData := []byte(`{"title": "Test Entry"}`)
-		err = db.SaveEntry("test-id-1", entryData)
+		_, err = db.SaveEntry("test-id-1", entryData)
 		if err != nil {
 			t.Errorf("SaveEntry() error = %v", err)
 		}
@@ -107,15 +179,15 @@ func TestSaveEntry(t *testing.T) {
 		entryData := []byte(`{"title": "Test Entry"}`)
 
 		// Save once
-		err = db.SaveEntry("test-id-1", entryData)
-		if err != nil {
-			t.Errorf("First SaveEntry() error = %v", err)
+		inserted, err := db.SaveEntry("test-id-1", entryData)
+		if err != nil || !inserted {
+			t.Errorf("First SaveEntry() = %v, %v; want true, nil", inserted, err)
 		}
 
 		// Save again (should be ignored)
-		err = db.SaveEntry("test-id-1", entryData)
-		if err != nil {
-			t.Errorf("Second SaveEntry() error = %v", err)
+		inserted, err = db.SaveEntry("test-id-1", entryData)
+		if err != nil || inserted {
+			t.Errorf("Second SaveEntry() = %v, %v; want false, nil", inserted, err)
 		}
 
 		// Verify only one entry exists
@@ -137,7 +209,7 @@ func TestSaveEntry(t *testing.T) {
 		defer db.Close()
 
 		entryData := []byte(`{"title": "Test Entry"}`)
-		err = db.SaveEntry("test-id-1", entryData)
+		_, err = db.SaveEntry("test-id-1", entryData)
 		if err != nil {
 			t.Errorf("SaveEntry() error = %v", err)
 		}
@@ -160,7 +232,7 @@ func TestSaveEntry(t *testing.T) {
 		defer db.Close()
 
 		entryData := []byte(`{"title": "Test Entry"}`)
-		err = db.SaveEntry("test-id-1", entryData)
+		_, err = db.SaveEntry("test-id-1", entryData)
 		if err != nil {
 			t.Errorf("SaveEntry() error = %v", err)
 		}
@@ -185,15 +257,15 @@ func TestGetUnpostedEntries(t *testing.T) {
 		defer db.Close()
 
 		// Add unposted entry
-		if err := db.SaveEntry("unposted-1", []byte(`{"title": "Unposted"}`)); err != nil {
+		if _, err := db.SaveEntry("unposted-1", []byte(`{"title": "Unposted"}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
 
 		// Add posted entry
-		if err := db.SaveEntry("posted-1", []byte(`{"title": "Posted"}`)); err != nil {
+		if _, err := db.SaveEntry("posted-1", []byte(`{"title": "Posted"}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
-		if err := db.MarkAsPosted("posted-1"); err != nil {
+		if err := db.MarkAsPosted("posted-1", "", ""); err != nil {
 			t.Fatalf("MarkAsPosted() error = %v", err)
 		}
 
@@ -219,13 +291,13 @@ func TestGetUnpostedEntries(t *testing.T) {
 		defer db.Close()
 
 		// Add entries in specific order
-		if err := db.SaveEntry("entry-1", []byte(`{"title": "First"}`)); err != nil {
+		if _, err := db.SaveEntry("entry-1", []byte(`{"title": "First"}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
-		if err := db.SaveEntry("entry-2", []byte(`{"title": "Second"}`)); err != nil {
+		if _, err := db.SaveEntry("entry-2", []byte(`{"title": "Second"}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
-		if err := db.SaveEntry("entry-3", []byte(`{"title": "Third"}`)); err != nil {
+		if _, err := db.SaveEntry("entry-3", []byte(`{"title": "Third"}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
 
@@ -259,7 +331,7 @@ func TestGetUnpostedEntries(t *testing.T) {
 
 		// Add 5 entries
 		for i := 1; i <= 5; i++ {
-			if err := db.SaveEntry("entry-"+string(rune('0'+i)), []byte(`{"title": "Entry"}`)); err != nil {
+			if _, err := db.SaveEntry("entry-"+string(rune('0'+i)), []byte(`{"title": "Entry"}`)); err != nil {
 				t.Fatalf("SaveEntry() error = %v", err)
 			}
 		}
@@ -296,6 +368,123 @@ func TestGetUnpostedEntries(t *testing.T) {
 	})
 }
 
+func TestGetUnpostedEntriesOrdered(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	// A backlog fetched yesterday, and today's entries, fetched together,
+	// with the newest item first as in most feeds
+	entries := []struct {
+		id        string
+		published string
+		fetched   string
+	}{
+		{"backlog-1", "2024-01-01T09:00:00Z", "-1 day"},
+		{"backlog-2", "2024-01-02T09:00:00+02:00", "-1 day"},
+		{"today-2", "2024-01-04T09:00:00Z", "-1 hour"},
+		{"today-1", "2024-01-03T09:00:00-05:00", "-1 hour"},
+		{"undated", "", "-1 hour"},
+	}
+	for _, e := range entries {
+		data := `{"title": "Entry"}`
+		if e.published != "" {
+			data = `{"title": "Entry", "publishedParsed": "` + e.published + `"}`
+		}
+		if _, err := db.SaveEntry(e.id, []byte(data)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if _, err := db.conn.Exec("UPDATE entries SET fetched_at = datetime('now', ?) WHERE id = ?", e.fetched, e.id); err != nil {
+			t.Fatalf("failed to set fetched_at: %v", err)
+		}
+	}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{OrderOldest, []string{"backlog-1", "backlog-2", "today-1", "today-2", "undated"}},
+		{OrderNewest, []string{"today-2", "today-1", "undated", "backlog-2", "backlog-1"}},
+		{OrderPublished, []string{"backlog-1", "backlog-2", "today-1", "today-2", "undated"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			got, err := db.GetUnpostedEntriesOrdered(context.Background(), 0, tt.order)
+			if err != nil {
+				t.Fatalf("GetUnpostedEntriesOrdered() error = %v", err)
+			}
+			var ids []string
+			for _, entry := range got {
+				ids = append(ids, entry.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("GetUnpostedEntriesOrdered(%q) = %v, want %v", tt.order, ids, tt.want)
+			}
+		})
+	}
+
+	t.Run("random", func(t *testing.T) {
+		got, err := db.GetUnpostedEntriesOrdered(context.Background(), 2, OrderRandom)
+		if err != nil {
+			t.Fatalf("GetUnpostedEntriesOrdered() error = %v", err)
+		}
+		if len(got) != 2 {
+			t.Errorf("GetUnpostedEntriesOrdered() = %d entries, want 2", len(got))
+		}
+	})
+
+	t.Run("unknown order", func(t *testing.T) {
+		if _, err := db.GetUnpostedEntriesOrdered(context.Background(), 0, "sideways"); err == nil {
+			t.Error("GetUnpostedEntriesOrdered() expected error for unknown order")
+		}
+	})
+}
+
+func TestCatchUp(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for i, published := range []string{"2024-01-03T00:00:00Z", "2024-01-01T00:00:00Z", "2024-01-04T00:00:00Z", "2024-01-02T00:00:00Z"} {
+		data := `{"title": "Entry", "publishedParsed": "` + published + `"}`
+		if _, err := db.SaveEntry(fmt.Sprintf("entry-%d", i+1), []byte(data)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+
+	marked, err := db.CatchUp(2)
+	if err != nil {
+		t.Fatalf("CatchUp() error = %v", err)
+	}
+	if marked != 2 {
+		t.Errorf("CatchUp(2) = %d, want 2", marked)
+	}
+
+	entries, err := db.GetUnpostedEntries(0)
+	if err != nil {
+		t.Fatalf("GetUnpostedEntries() error = %v", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	if want := []string{"entry-1", "entry-3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("unposted after CatchUp(2) = %v, want the newest %v", ids, want)
+	}
+
+	marked, err = db.CatchUp(0)
+	if err != nil {
+		t.Fatalf("CatchUp() error = %v", err)
+	}
+	if marked != 2 {
+		t.Errorf("CatchUp(0) = %d, want 2", marked)
+	}
+}
+
 func TestMarkAsPosted(t *testing.T) {
 	t.Run("marks entry as posted with timestamp", func(t *testing.T) {
 		db, err := New(":memory:")
@@ -304,11 +493,11 @@ func TestMarkAsPosted(t *testing.T) {
 		}
 		defer db.Close()
 
-		if err := db.SaveEntry("test-id", []byte(`{"title": "Test"}`)); err != nil {
+		if _, err := db.SaveEntry("test-id", []byte(`{"title": "Test"}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
 
-		err = db.MarkAsPosted("test-id")
+		err = db.MarkAsPosted("test-id", "", "")
 		if err != nil {
 			t.Errorf("MarkAsPosted() error = %v", err)
 		}
@@ -324,6 +513,60 @@ func TestMarkAsPosted(t *testing.T) {
 		}
 	})
 
+	t.Run("records status ID and URL", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.SaveEntry("test-id", []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if _, err := db.SaveEntry("skipped-id", []byte(`{"title": "Skipped"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+
+		if err := db.MarkAsPosted("test-id", "110", "https://mastodon.example/@me/110"); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+		if err := db.MarkAsPosted("skipped-id", "", ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+
+		entry, err := db.GetEntry("test-id")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if entry.StatusID.String != "110" || entry.StatusURL.String != "https://mastodon.example/@me/110" {
+			t.Errorf("status = %+v %+v, want 110 and URL", entry.StatusID, entry.StatusURL)
+		}
+
+		skipped, err := db.GetEntry("skipped-id")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if skipped.StatusID.Valid || skipped.StatusURL.Valid {
+			t.Errorf("skipped entry should have NULL status, got %+v %+v", skipped.StatusID, skipped.StatusURL)
+		}
+
+		lastPostedAt, err := db.GetLastPostedAt()
+		if err != nil {
+			t.Fatalf("GetLastPostedAt() error = %v", err)
+		}
+		if lastPostedAt == nil || time.Since(*lastPostedAt) > time.Minute {
+			t.Errorf("GetLastPostedAt() = %v, want about now", lastPostedAt)
+		}
+
+		lastURL, err := db.GetLastStatusURL()
+		if err != nil {
+			t.Fatalf("GetLastStatusURL() error = %v", err)
+		}
+		if lastURL == nil || *lastURL != "https://mastodon.example/@me/110" {
+			t.Errorf("GetLastStatusURL() = %v", lastURL)
+		}
+	})
+
 	t.Run("error on non-existent entry ID", func(t *testing.T) {
 		db, err := New(":memory:")
 		if err != nil {
@@ -331,13 +574,709 @@ func TestMarkAsPosted(t *testing.T) {
 		}
 		defer db.Close()
 
-		err = db.MarkAsPosted("non-existent")
+		err = db.MarkAsPosted("non-existent", "", "")
 		if err == nil {
 			t.Error("Expected error for non-existent entry, got nil")
 		}
 	})
 }
 
+func TestGetEntry(t *testing.T) {
+	t.Run("returns existing entry", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.SaveEntry("test-id", []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+
+		entry, err := db.GetEntry("test-id")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if entry == nil {
+			t.Fatal("Expected entry, got nil")
+		}
+		if entry.ID != "test-id" {
+			t.Errorf("ID = %s, want test-id", entry.ID)
+		}
+		if string(entry.EntryData) != `{"title": "Test"}` {
+			t.Errorf("EntryData = %s", entry.EntryData)
+		}
+		if entry.PostedContent.Valid {
+			t.Error("PostedContent should not be set for unposted entry")
+		}
+	})
+
+	t.Run("returns nil for non-existent entry", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		entry, err := db.GetEntry("non-existent")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if entry != nil {
+			t.Errorf("Expected nil, got %v", entry)
+		}
+	})
+}
+
+func TestSetPostedContent(t *testing.T) {
+	t.Run("stores posted content", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.SaveEntry("test-id", []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+
+		if err := db.SetPostedContent("test-id", "Test\nhttps://example.com"); err != nil {
+			t.Fatalf("SetPostedContent() error = %v", err)
+		}
+
+		entry, err := db.GetEntry("test-id")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if !entry.PostedContent.Valid || entry.PostedContent.String != "Test\nhttps://example.com" {
+			t.Errorf("PostedContent = %+v", entry.PostedContent)
+		}
+	})
+
+	t.Run("error on non-existent entry ID", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if err := db.SetPostedContent("non-existent", "content"); err == nil {
+			t.Error("Expected error for non-existent entry, got nil")
+		}
+	})
+}
+
+func TestSaveEntryContent(t *testing.T) {
+	t.Run("flags posted entries whose content changed", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.SaveEntryContent("entry-1", []byte(`{"title": "Old"}`), "hash-1"); err != nil {
+			t.Fatalf("SaveEntryContent() error = %v", err)
+		}
+		if err := db.MarkAsPosted("entry-1", "110", "https://mastodon.example/@me/110"); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+
+		result, err := db.SaveEntryContent("entry-1", []byte(`{"title": "Old"}`), "hash-1")
+		if err != nil || result != SaveUnchanged {
+			t.Fatalf("SaveEntryContent() unchanged = %v, %v; want SaveUnchanged, nil", result, err)
+		}
+
+		result, err = db.SaveEntryContent("entry-1", []byte(`{"title": "New"}`), "hash-2")
+		if err != nil || result != SaveChanged {
+			t.Fatalf("SaveEntryContent() changed = %v, %v; want SaveChanged, nil", result, err)
+		}
+
+		entries, err := db.GetChangedEntries()
+		if err != nil {
+			t.Fatalf("GetChangedEntries() error = %v", err)
+		}
+		if len(entries) != 1 || string(entries[0].EntryData) != `{"title": "New"}` {
+			t.Fatalf("GetChangedEntries() = %d entries, want entry-1 with new data", len(entries))
+		}
+		if entries[0].StatusID.String != "110" {
+			t.Errorf("StatusID = %q, want 110", entries[0].StatusID.String)
+		}
+
+		if err := db.ClearChanged("entry-1"); err != nil {
+			t.Fatalf("ClearChanged() error = %v", err)
+		}
+		entries, err = db.GetChangedEntries()
+		if err != nil {
+			t.Fatalf("GetChangedEntries() error = %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("GetChangedEntries() = %d entries after ClearChanged, want 0", len(entries))
+		}
+	})
+
+	t.Run("updates unposted entries without flagging", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		result, err := db.SaveEntryContent("entry-1", []byte(`{"title": "Old"}`), "hash-1")
+		if err != nil || result != SaveInserted {
+			t.Fatalf("SaveEntryContent() new = %v, %v; want SaveInserted, nil", result, err)
+		}
+		if _, err := db.SaveEntryContent("entry-1", []byte(`{"title": "New"}`), "hash-2"); err != nil {
+			t.Fatalf("SaveEntryContent() error = %v", err)
+		}
+
+		entry, err := db.GetEntry("entry-1")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if string(entry.EntryData) != `{"title": "New"}` || entry.ChangedAt.Valid {
+			t.Errorf("entry = %s (changed %v), want new data and not changed", entry.EntryData, entry.ChangedAt.Valid)
+		}
+	})
+
+	t.Run("records hash for entries saved without one", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.SaveEntry("entry-1", []byte(`{"title": "Old"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if err := db.MarkAsPosted("entry-1", "110", ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+
+		result, err := db.SaveEntryContent("entry-1", []byte(`{"title": "Old"}`), "hash-1")
+		if err != nil || result != SaveUpdated {
+			t.Errorf("SaveEntryContent() = %v, %v; want SaveUpdated, nil", result, err)
+		}
+	})
+}
+
+func TestRecordFailure(t *testing.T) {
+	newDB := func(t *testing.T) *DB {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if _, err := db.SaveEntry("entry-1", []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		return db
+	}
+
+	t.Run("holds back entries until their retry time", func(t *testing.T) {
+		db := newDB(t)
+
+		retryAt := time.Now().Add(time.Hour)
+		if err := db.RecordFailure("entry-1", "boom", &retryAt); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+
+		entries, err := db.GetUnpostedEntries(0)
+		if err != nil {
+			t.Fatalf("GetUnpostedEntries() error = %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("GetUnpostedEntries() = %d entries, want 0 before retry time", len(entries))
+		}
+
+		failed, err := db.GetFailedEntries()
+		if err != nil {
+			t.Fatalf("GetFailedEntries() error = %v", err)
+		}
+		if len(failed) != 1 {
+			t.Fatalf("GetFailedEntries() = %d entries, want 1", len(failed))
+		}
+		if failed[0].FailureCount != 1 || failed[0].LastError.String != "boom" || !failed[0].RetryAt.Valid || failed[0].FailedAt.Valid {
+			t.Errorf("entry = %d failures, error %q, retry %v, failed %v", failed[0].FailureCount, failed[0].LastError.String, failed[0].RetryAt.Valid, failed[0].FailedAt.Valid)
+		}
+
+		retryAt = time.Now().Add(-time.Minute)
+		if err := db.RecordFailure("entry-1", "boom again", &retryAt); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+		entries, err = db.GetUnpostedEntries(0)
+		if err != nil {
+			t.Fatalf("GetUnpostedEntries() error = %v", err)
+		}
+		if len(entries) != 1 || entries[0].FailureCount != 2 {
+			t.Errorf("GetUnpostedEntries() = %d entries, want entry-1 with 2 failures after retry time", len(entries))
+		}
+	})
+
+	t.Run("gives up without a retry time", func(t *testing.T) {
+		db := newDB(t)
+
+		if err := db.RecordFailure("entry-1", "boom", nil); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+
+		entries, err := db.GetUnpostedEntries(0)
+		if err != nil {
+			t.Fatalf("GetUnpostedEntries() error = %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("GetUnpostedEntries() = %d entries, want 0", len(entries))
+		}
+
+		retrying, gaveUp, err := db.GetFailureCounts()
+		if err != nil || retrying != 0 || gaveUp != 1 {
+			t.Errorf("GetFailureCounts() = %d, %d, %v; want 0, 1, nil", retrying, gaveUp, err)
+		}
+		_, _, unposted, err := db.GetStats()
+		if err != nil || unposted != 0 {
+			t.Errorf("GetStats() unposted = %d, %v; want 0, nil", unposted, err)
+		}
+	})
+
+	t.Run("reset puts entries back in the queue", func(t *testing.T) {
+		db := newDB(t)
+
+		if err := db.RecordFailure("entry-1", "boom", nil); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+		reset, err := db.ResetFailures("entry-1")
+		if err != nil || !reset {
+			t.Fatalf("ResetFailures() = %v, %v; want true, nil", reset, err)
+		}
+
+		entries, err := db.GetUnpostedEntries(0)
+		if err != nil {
+			t.Fatalf("GetUnpostedEntries() error = %v", err)
+		}
+		if len(entries) != 1 || entries[0].FailureCount != 0 || entries[0].LastError.Valid {
+			t.Errorf("GetUnpostedEntries() = %d entries, want entry-1 without failures", len(entries))
+		}
+
+		reset, err = db.ResetFailures("entry-1")
+		if err != nil || reset {
+			t.Errorf("ResetFailures() again = %v, %v; want false, nil", reset, err)
+		}
+	})
+
+	t.Run("errors for missing entry", func(t *testing.T) {
+		db := newDB(t)
+		if err := db.RecordFailure("missing", "boom", nil); err == nil {
+			t.Error("Expected error for missing entry")
+		}
+	})
+}
+
+func TestMarkAsScheduled(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"entry-1", "entry-2"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+
+	last, err := db.GetLastScheduledAt()
+	if err != nil || last != nil {
+		t.Fatalf("GetLastScheduledAt() = %v, %v; want nil, nil", last, err)
+	}
+
+	later := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+	if err := db.MarkAsScheduled("entry-1", "sched-1", later.Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkAsScheduled() error = %v", err)
+	}
+	if err := db.MarkAsScheduled("entry-2", "sched-2", later); err != nil {
+		t.Fatalf("MarkAsScheduled() error = %v", err)
+	}
+
+	last, err = db.GetLastScheduledAt()
+	if err != nil || last == nil || !last.Equal(later) {
+		t.Errorf("GetLastScheduledAt() = %v, %v; want %v", last, err, later)
+	}
+
+	entry, err := db.GetEntry("entry-2")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if entry.PostedAt == nil || !entry.PostedAt.Valid || entry.ScheduledID.String != "sched-2" || !entry.ScheduledAt.Time.Equal(later) {
+		t.Errorf("entry = posted %v, scheduled %v (%s); want posted and scheduled for %v", entry.PostedAt, entry.ScheduledAt.Time, entry.ScheduledID.String, later)
+	}
+
+	// Scheduled entries are no longer unposted
+	unposted, err := db.GetUnpostedEntries(0)
+	if err != nil || len(unposted) != 0 {
+		t.Errorf("GetUnpostedEntries() = %d, %v; want 0, nil", len(unposted), err)
+	}
+}
+
+func TestRequeue(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"posted", "filtered", "queued"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+	if err := db.MarkAsPosted("posted", "123", "https://mastodon.example/@me/123"); err != nil {
+		t.Fatalf("MarkAsPosted() error = %v", err)
+	}
+	if err := db.SetPostedContent("posted", "Test"); err != nil {
+		t.Fatalf("SetPostedContent() error = %v", err)
+	}
+	if err := db.MarkAsFiltered("filtered", "rejected in review"); err != nil {
+		t.Fatalf("MarkAsFiltered() error = %v", err)
+	}
+
+	posted, err := db.GetPostedEntries(time.Time{})
+	if err != nil || len(posted) != 2 {
+		t.Fatalf("GetPostedEntries() = %d, %v; want 2, nil", len(posted), err)
+	}
+	recent, err := db.GetPostedEntries(time.Now().Add(time.Hour))
+	if err != nil || len(recent) != 0 {
+		t.Errorf("GetPostedEntries(future) = %d, %v; want 0, nil", len(recent), err)
+	}
+
+	for _, id := range []string{"posted", "filtered"} {
+		if ok, err := db.Requeue(id); err != nil || !ok {
+			t.Errorf("Requeue(%s) = %v, %v; want true, nil", id, ok, err)
+		}
+	}
+	if ok, err := db.Requeue("queued"); err != nil || ok {
+		t.Errorf("Requeue(queued) = %v, %v; want false, nil", ok, err)
+	}
+
+	entry, err := db.GetEntry("posted")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if (entry.PostedAt != nil && entry.PostedAt.Valid) || entry.StatusID.Valid || entry.PostedContent.Valid {
+		t.Errorf("requeued entry still has posted state: %+v", entry)
+	}
+
+	unposted, err := db.GetUnpostedEntries(0)
+	if err != nil || len(unposted) != 3 {
+		t.Errorf("GetUnpostedEntries() = %d, %v; want 3, nil", len(unposted), err)
+	}
+}
+
+func TestGetExpiredEntries(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"posted", "scheduled", "queued"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+	if err := db.MarkAsPosted("posted", "123", "https://mastodon.example/@me/123"); err != nil {
+		t.Fatalf("MarkAsPosted() error = %v", err)
+	}
+	if err := db.MarkAsScheduled("scheduled", "9", time.Now()); err != nil {
+		t.Fatalf("MarkAsScheduled() error = %v", err)
+	}
+
+	expired, err := db.GetExpiredEntries(time.Now().Add(-time.Hour))
+	if err != nil || len(expired) != 0 {
+		t.Errorf("GetExpiredEntries(an hour ago) = %d, %v; want 0, nil", len(expired), err)
+	}
+	expired, err = db.GetExpiredEntries(time.Now().Add(time.Hour))
+	if err != nil || len(expired) != 1 || expired[0].ID != "posted" {
+		t.Fatalf("GetExpiredEntries(in an hour) = %d, %v; want posted, nil", len(expired), err)
+	}
+
+	if err := db.MarkAsDeleted("posted"); err != nil {
+		t.Fatalf("MarkAsDeleted() error = %v", err)
+	}
+	expired, err = db.GetExpiredEntries(time.Now().Add(time.Hour))
+	if err != nil || len(expired) != 0 {
+		t.Errorf("GetExpiredEntries() after delete = %d, %v; want 0, nil", len(expired), err)
+	}
+	entry, err := db.GetEntry("posted")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if !entry.DeletedAt.Valid || entry.State() != StatePosted {
+		t.Errorf("deleted entry = %v, %s; want deleted and still posted", entry.DeletedAt, entry.State())
+	}
+
+	if err := db.MarkAsDeleted("missing"); err == nil {
+		t.Error("MarkAsDeleted(missing) error = nil, want error")
+	}
+}
+
+func TestQueriesStopWhenContextIsDone(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.GetChangedEntriesContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetChangedEntriesContext() error = %v, want context.Canceled", err)
+	}
+	if _, err := db.GetExpiredEntriesContext(ctx, time.Now()); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetExpiredEntriesContext() error = %v, want context.Canceled", err)
+	}
+	if _, err := db.GetUnpostedEntriesContext(ctx, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetUnpostedEntriesContext() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSetArticle(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"queued", "posted", "stub"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+	if err := db.MarkAsPosted("posted", "123", ""); err != nil {
+		t.Fatalf("MarkAsPosted() error = %v", err)
+	}
+
+	entries, err := db.GetUnextractedEntries()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("GetUnextractedEntries() = %d, %v; want 2, nil", len(entries), err)
+	}
+
+	if err := db.SetArticle("queued", "Full text", "https://example.com/lead.jpg"); err != nil {
+		t.Fatalf("SetArticle() error = %v", err)
+	}
+	if err := db.SetArticle("stub", "", ""); err != nil {
+		t.Fatalf("SetArticle() error = %v", err)
+	}
+	entries, err = db.GetUnextractedEntries()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("GetUnextractedEntries() after extraction = %d, %v; want 0, nil", len(entries), err)
+	}
+
+	entry, err := db.GetEntry("queued")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if entry.FullContent.String != "Full text" || entry.LeadImage.String != "https://example.com/lead.jpg" || !entry.ExtractedAt.Valid {
+		t.Errorf("article = %v, %v, %v", entry.FullContent, entry.LeadImage, entry.ExtractedAt)
+	}
+	entry, err = db.GetEntry("stub")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if entry.FullContent.Valid || !entry.ExtractedAt.Valid {
+		t.Errorf("empty article = %v, %v; want no content but extracted", entry.FullContent, entry.ExtractedAt)
+	}
+}
+
+func TestListEntries(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"posted", "filtered", "failed", "unposted-1", "unposted-2"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+	if err := db.MarkAsPosted("posted", "", ""); err != nil {
+		t.Fatalf("MarkAsPosted() error = %v", err)
+	}
+	if err := db.MarkAsFiltered("filtered", "test"); err != nil {
+		t.Fatalf("MarkAsFiltered() error = %v", err)
+	}
+	if err := db.RecordFailure("failed", "boom", nil); err != nil {
+		t.Fatalf("RecordFailure() error = %v", err)
+	}
+
+	all, err := db.ListEntries(ListOptions{})
+	if err != nil || len(all) != 5 {
+		t.Fatalf("ListEntries() = %d, %v; want 5, nil", len(all), err)
+	}
+	states := map[string]string{}
+	for _, entry := range all {
+		states[entry.ID] = entry.State()
+	}
+	for id, want := range map[string]string{"posted": StatePosted, "filtered": StateFiltered, "failed": StateFailed, "unposted-1": StateUnposted} {
+		if states[id] != want {
+			t.Errorf("%s State() = %q, want %q", id, states[id], want)
+		}
+	}
+
+	for state, want := range map[string]int{StatePosted: 1, StateFiltered: 1, StateFailed: 1, StateUnposted: 2} {
+		entries, err := db.ListEntries(ListOptions{State: state})
+		if err != nil || len(entries) != want {
+			t.Errorf("ListEntries(%s) = %d, %v; want %d, nil", state, len(entries), err, want)
+		}
+	}
+
+	page, err := db.ListEntries(ListOptions{State: StateUnposted, Limit: 1, Offset: 1})
+	if err != nil || len(page) != 1 || page[0].ID != "unposted-2" {
+		t.Errorf("ListEntries(page 2) = %v, %v; want [unposted-2]", page, err)
+	}
+	if recent, err := db.ListEntries(ListOptions{Since: time.Now().Add(time.Hour)}); err != nil || len(recent) != 0 {
+		t.Errorf("ListEntries(future) = %d, %v; want 0, nil", len(recent), err)
+	}
+	if _, err := db.ListEntries(ListOptions{State: "lost"}); err == nil {
+		t.Error("Expected error for unknown state")
+	}
+}
+
+func TestMarkAsFiltered(t *testing.T) {
+	t.Run("filtered entries are held back", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if _, err := db.SaveEntry("entry-2", []byte(`{}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+
+		if err := db.MarkAsFiltered("entry-1", "matches exclude_regex"); err != nil {
+			t.Fatalf("MarkAsFiltered() error = %v", err)
+		}
+
+		entries, err := db.GetUnpostedEntries(0)
+		if err != nil {
+			t.Fatalf("GetUnpostedEntries() error = %v", err)
+		}
+		if len(entries) != 1 || entries[0].ID != "entry-2" {
+			t.Errorf("GetUnpostedEntries() = %d entries, want only entry-2", len(entries))
+		}
+
+		entry, err := db.GetEntry("entry-1")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if !entry.FilteredAt.Valid || entry.FilterReason.String != "matches exclude_regex" {
+			t.Errorf("filter state = %+v %+v", entry.FilteredAt, entry.FilterReason)
+		}
+
+		total, posted, unposted, err := db.GetStats()
+		if err != nil {
+			t.Fatalf("GetStats() error = %v", err)
+		}
+		if total != 2 || posted != 0 || unposted != 1 {
+			t.Errorf("Expected (2, 0, 1), got (%d, %d, %d)", total, posted, unposted)
+		}
+
+		filtered, err := db.GetFilteredCount()
+		if err != nil {
+			t.Fatalf("GetFilteredCount() error = %v", err)
+		}
+		if filtered != 1 {
+			t.Errorf("GetFilteredCount() = %d, want 1", filtered)
+		}
+	})
+
+	t.Run("error on non-existent entry ID", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if err := db.MarkAsFiltered("non-existent", "reason"); err == nil {
+			t.Error("Expected error for non-existent entry, got nil")
+		}
+	})
+}
+
+func TestMarkAsSkipped(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"skipped", "queued", "posted"} {
+		if _, err := db.SaveEntry(id, []byte(`{}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+	if err := db.MarkAsPosted("posted", "123", "https://mastodon.example/@me/123"); err != nil {
+		t.Fatalf("MarkAsPosted() error = %v", err)
+	}
+
+	if ok, err := db.MarkAsSkipped("skipped"); err != nil || !ok {
+		t.Fatalf("MarkAsSkipped(skipped) = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := db.MarkAsSkipped("skipped"); err != nil || ok {
+		t.Errorf("MarkAsSkipped() again = %v, %v; want false, nil", ok, err)
+	}
+	if ok, err := db.MarkAsSkipped("posted"); err != nil || ok {
+		t.Errorf("MarkAsSkipped(posted) = %v, %v; want false, nil", ok, err)
+	}
+	if ok, err := db.MarkAsSkipped("missing"); err != nil || ok {
+		t.Errorf("MarkAsSkipped(missing) = %v, %v; want false, nil", ok, err)
+	}
+
+	entries, err := db.GetUnpostedEntries(0)
+	if err != nil {
+		t.Fatalf("GetUnpostedEntries() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != "queued" {
+		t.Errorf("GetUnpostedEntries() = %d entries, want only queued", len(entries))
+	}
+
+	// Skipped entries aren't counted as posted or unposted
+	total, posted, unposted, err := db.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if total != 3 || posted != 1 || unposted != 1 {
+		t.Errorf("Expected (3, 1, 1), got (%d, %d, %d)", total, posted, unposted)
+	}
+	if skipped, err := db.GetSkippedCount(); err != nil || skipped != 1 {
+		t.Errorf("GetSkippedCount() = %d, %v; want 1, nil", skipped, err)
+	}
+
+	listed, err := db.ListEntries(ListOptions{State: StateSkipped})
+	if err != nil || len(listed) != 1 || listed[0].State() != StateSkipped {
+		t.Errorf("ListEntries(skipped) = %d, %v; want the skipped entry", len(listed), err)
+	}
+
+	// Saving the entry again, as a fetch does, keeps it skipped
+	if _, err := db.SaveEntry("skipped", []byte(`{}`)); err != nil {
+		t.Fatalf("SaveEntry() error = %v", err)
+	}
+	entry, err := db.GetEntry("skipped")
+	if err != nil || !entry.SkippedAt.Valid {
+		t.Fatalf("GetEntry() = %+v, %v; want it skipped", entry, err)
+	}
+
+	if ok, err := db.Requeue("skipped"); err != nil || !ok {
+		t.Errorf("Requeue(skipped) = %v, %v; want true, nil", ok, err)
+	}
+	if entry, _ := db.GetEntry("skipped"); entry.State() != StateUnposted {
+		t.Errorf("requeued entry state = %s, want unposted", entry.State())
+	}
+}
+
 func TestGetStats(t *testing.T) {
 	t.Run("with empty database", func(t *testing.T) {
 		db, err := New(":memory:")
@@ -363,13 +1302,13 @@ func TestGetStats(t *testing.T) {
 		}
 		defer db.Close()
 
-		if err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
-		if err := db.SaveEntry("entry-2", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-2", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
-		if err := db.SaveEntry("entry-3", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-3", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
 
@@ -390,20 +1329,20 @@ func TestGetStats(t *testing.T) {
 		}
 		defer db.Close()
 
-		if err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
-		if err := db.SaveEntry("entry-2", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-2", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
-		if err := db.SaveEntry("entry-3", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-3", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
 
-		if err := db.MarkAsPosted("entry-1"); err != nil {
+		if err := db.MarkAsPosted("entry-1", "", ""); err != nil {
 			t.Fatalf("MarkAsPosted() error = %v", err)
 		}
-		if err := db.MarkAsPosted("entry-2"); err != nil {
+		if err := db.MarkAsPosted("entry-2", "", ""); err != nil {
 			t.Fatalf("MarkAsPosted() error = %v", err)
 		}
 
@@ -443,10 +1382,10 @@ func TestGetLastFetchTime(t *testing.T) {
 		}
 		defer db.Close()
 
-		if err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
-		if err := db.SaveEntry("entry-2", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-2", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
 
@@ -486,7 +1425,7 @@ func TestGetLastPostTime(t *testing.T) {
 		}
 		defer db.Close()
 
-		if err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
 
@@ -507,10 +1446,10 @@ func TestGetLastPostTime(t *testing.T) {
 		}
 		defer db.Close()
 
-		if err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
+		if _, err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
-		if err := db.MarkAsPosted("entry-1"); err != nil {
+		if err := db.MarkAsPosted("entry-1", "", ""); err != nil {
 			t.Fatalf("MarkAsPosted() error = %v", err)
 		}
 
@@ -539,9 +1478,9 @@ func TestMigrations(t *testing.T) {
 			t.Fatalf("GetMigrationVersion() error = %v", err)
 		}
 
-		// Version should be 2 (initial schema + settings table migration)
-		if version != 2 {
-			t.Errorf("Expected version 2, got %d", version)
+		// Version should match the latest migration
+		if version != 21 {
+			t.Errorf("Expected version 21, got %d", version)
 		}
 	})
 
@@ -564,3 +1503,196 @@ func TestMigrations(t *testing.T) {
 		defer db2.Close()
 	})
 }
+
+func TestWipe(t *testing.T) {
+	t.Run("deletes all entries and settings", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.SaveEntry("entry-1", []byte(`{}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if _, err := db.SaveEntry("entry-2", []byte(`{}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if err := db.SetSetting("mastodon_access_token", "token"); err != nil {
+			t.Fatalf("SetSetting() error = %v", err)
+		}
+
+		entries, settings, err := db.Wipe()
+		if err != nil {
+			t.Fatalf("Wipe() error = %v", err)
+		}
+		if entries != 2 || settings != 1 {
+			t.Errorf("Wipe() = (%d, %d), want (2, 1)", entries, settings)
+		}
+
+		total, _, _, err := db.GetStats()
+		if err != nil {
+			t.Fatalf("GetStats() error = %v", err)
+		}
+		if total != 0 {
+			t.Errorf("Expected 0 entries after wipe, got %d", total)
+		}
+
+		token, err := db.GetSetting("mastodon_access_token")
+		if err != nil {
+			t.Fatalf("GetSetting() error = %v", err)
+		}
+		if token != nil {
+			t.Errorf("Expected token to be wiped, got %v", *token)
+		}
+	})
+
+	t.Run("deletes fetch and post history and threads", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if err := db.RecordFetch("https://example.com/feed.xml", 1, nil); err != nil {
+			t.Fatalf("RecordFetch() error = %v", err)
+		}
+		if err := db.RecordPost("entry-1", "https://example.com/feed.xml", nil); err != nil {
+			t.Fatalf("RecordPost() error = %v", err)
+		}
+		if err := db.SaveThreadStatuses("entry-1", []string{"2", "3"}); err != nil {
+			t.Fatalf("SaveThreadStatuses() error = %v", err)
+		}
+
+		if _, _, err := db.Wipe(); err != nil {
+			t.Fatalf("Wipe() error = %v", err)
+		}
+
+		for _, table := range []string{"fetch_log", "post_history", "thread_statuses"} {
+			var count int
+			if err := db.conn.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+				t.Fatalf("counting %s: %v", table, err)
+			}
+			if count != 0 {
+				t.Errorf("%s has %d rows after wipe, want 0", table, count)
+			}
+		}
+	})
+}
+
+func TestGetPostedStatuses(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"posted", "deleted", "purged", "unposted"} {
+		if _, err := db.SaveEntry(id, []byte(`{}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+	for id, statusID := range map[string]string{"posted": "101", "deleted": "102", "purged": "103"} {
+		if err := db.MarkAsPosted(id, statusID, ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+	}
+	if err := db.MarkAsDeleted("deleted"); err != nil {
+		t.Fatalf("MarkAsDeleted() error = %v", err)
+	}
+	if _, err := db.DeleteEntries([]string{"purged"}); err != nil {
+		t.Fatalf("DeleteEntries() error = %v", err)
+	}
+
+	statuses, err := db.GetPostedStatuses()
+	if err != nil {
+		t.Fatalf("GetPostedStatuses() error = %v", err)
+	}
+	want := map[string]PostedStatus{
+		"101": {EntryID: "posted", StatusID: "101"},
+		"103": {EntryID: "purged", StatusID: "103", Purged: true},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("GetPostedStatuses() = %+v, want %+v", statuses, want)
+	}
+	for _, status := range statuses {
+		if status != want[status.StatusID] {
+			t.Errorf("GetPostedStatuses() returned %+v, want %+v", status, want[status.StatusID])
+		}
+	}
+}
+
+func TestDeleteSetting(t *testing.T) {
+	t.Run("deletes existing setting", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if err := db.SetSetting("key", "value"); err != nil {
+			t.Fatalf("SetSetting() error = %v", err)
+		}
+		if err := db.DeleteSetting("key"); err != nil {
+			t.Fatalf("DeleteSetting() error = %v", err)
+		}
+
+		value, err := db.GetSetting("key")
+		if err != nil {
+			t.Fatalf("GetSetting() error = %v", err)
+		}
+		if value != nil {
+			t.Errorf("Expected nil after delete, got %v", *value)
+		}
+	})
+
+	t.Run("missing setting is not an error", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if err := db.DeleteSetting("missing"); err != nil {
+			t.Errorf("DeleteSetting() error = %v", err)
+		}
+	})
+}
+
+func TestGetLastStatusID(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	if id, err := db.GetLastStatusID(); err != nil || id != "" {
+		t.Fatalf("GetLastStatusID() = %q, %v; want none", id, err)
+	}
+
+	for i, id := range []string{"posted", "boosted", "deleted", "unposted"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Entry"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if id == "unposted" {
+			continue
+		}
+		if err := db.MarkAsPosted(id, fmt.Sprint(i+1), ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+	}
+	if err := db.SetPostedContent("boosted", "Boosted https://example.social/@someone/1"); err != nil {
+		t.Fatalf("SetPostedContent() error = %v", err)
+	}
+	if err := db.MarkAsDeleted("deleted"); err != nil {
+		t.Fatalf("MarkAsDeleted() error = %v", err)
+	}
+
+	id, err := db.GetLastStatusID()
+	if err != nil {
+		t.Fatalf("GetLastStatusID() error = %v", err)
+	}
+	if id != "1" {
+		t.Errorf("GetLastStatusID() = %q, want 1, skipping the boost and the deleted status", id)
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fetchLogRetention is the number of fetch log rows kept per feed.
+const fetchLogRetention = 100
+
+// FeedHealth summarizes recent fetch attempts for a feed.
+type FeedHealth struct {
+	FeedURL             string
+	Fetches             int
+	LastAttempt         *string
+	LastSuccess         *string
+	ConsecutiveFailures int
+	AverageNewEntries   float64
+	LastError           *string
+}
+
+// RecordFetch logs the outcome of a fetch attempt for a feed.
+// Pass a nil fetchErr for a successful fetch.
+func (db *DB) RecordFetch(feedURL string, newEntries int, fetchErr error) error {
+	var errText sql.NullString
+	if fetchErr != nil {
+		errText = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+
+	_, err := db.conn.Exec(
+		"INSERT INTO fetch_log (feed_url, success, new_entries, error) VALUES (?, ?, ?, ?)",
+		feedURL, fetchErr == nil, newEntries, errText,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record fetch: %w", err)
+	}
+
+	// Keep only the most recent rows for this feed
+	_, err = db.conn.Exec(`
+		DELETE FROM fetch_log
+		WHERE feed_url = ? AND id NOT IN (
+			SELECT id FROM fetch_log WHERE feed_url = ? ORDER BY id DESC LIMIT ?
+		)
+	`, feedURL, feedURL, fetchLogRetention)
+	if err != nil {
+		return fmt.Errorf("failed to prune fetch log: %w", err)
+	}
+
+	logrus.Debugf("Recorded fetch for %s (success: %v)", feedURL, fetchErr == nil)
+	return nil
+}
+
+// GetFeedHealth summarizes the logged fetch attempts for a feed.
+func (db *DB) GetFeedHealth(feedURL string) (*FeedHealth, error) {
+	health := &FeedHealth{FeedURL: feedURL}
+
+	var avg sql.NullFloat64
+	err := db.conn.QueryRow(`
+		SELECT
+			COUNT(*),
+			MAX(fetched_at),
+			MAX(CASE WHEN success THEN fetched_at END),
+			AVG(CASE WHEN success THEN new_entries END)
+		FROM fetch_log
+		WHERE feed_url = ?
+	`, feedURL).Scan(&health.Fetches, &health.LastAttempt, &health.LastSuccess, &avg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed health: %w", err)
+	}
+	health.AverageNewEntries = avg.Float64
+
+	// Failures since the most recent success
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*) FROM fetch_log
+		WHERE feed_url = ? AND NOT success AND id > COALESCE(
+			(SELECT MAX(id) FROM fetch_log WHERE feed_url = ? AND success), 0
+		)
+	`, feedURL, feedURL).Scan(&health.ConsecutiveFailures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count consecutive failures: %w", err)
+	}
+
+	if health.ConsecutiveFailures > 0 {
+		err = db.conn.QueryRow(
+			"SELECT error FROM fetch_log WHERE feed_url = ? ORDER BY id DESC LIMIT 1",
+			feedURL,
+		).Scan(&health.LastError)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last fetch error: %w", err)
+		}
+	}
+
+	return health, nil
+}
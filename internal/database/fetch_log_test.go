@@ -0,0 +1,117 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRecordFetch(t *testing.T) {
+	t.Run("prunes old rows", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		for i := 0; i < fetchLogRetention+10; i++ {
+			if err := db.RecordFetch("https://example.com/feed", 1, nil); err != nil {
+				t.Fatalf("RecordFetch() error = %v", err)
+			}
+		}
+
+		var count int
+		if err := db.conn.QueryRow("SELECT COUNT(*) FROM fetch_log").Scan(&count); err != nil {
+			t.Fatalf("Query error: %v", err)
+		}
+		if count != fetchLogRetention {
+			t.Errorf("fetch_log rows = %d, want %d", count, fetchLogRetention)
+		}
+	})
+}
+
+func TestGetFeedHealth(t *testing.T) {
+	t.Run("empty log", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		health, err := db.GetFeedHealth("https://example.com/feed")
+		if err != nil {
+			t.Fatalf("GetFeedHealth() error = %v", err)
+		}
+		if health.Fetches != 0 || health.LastSuccess != nil || health.ConsecutiveFailures != 0 {
+			t.Errorf("unexpected health for empty log: %+v", health)
+		}
+	})
+
+	t.Run("summarizes successes and failures", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		feedURL := "https://example.com/feed"
+		if err := db.RecordFetch(feedURL, 2, nil); err != nil {
+			t.Fatalf("RecordFetch() error = %v", err)
+		}
+		if err := db.RecordFetch(feedURL, 4, nil); err != nil {
+			t.Fatalf("RecordFetch() error = %v", err)
+		}
+		if err := db.RecordFetch(feedURL, 0, errors.New("timeout")); err != nil {
+			t.Fatalf("RecordFetch() error = %v", err)
+		}
+		if err := db.RecordFetch(feedURL, 0, errors.New("404 Not Found")); err != nil {
+			t.Fatalf("RecordFetch() error = %v", err)
+		}
+		// Other feeds don't count
+		if err := db.RecordFetch("https://other.example/feed", 10, nil); err != nil {
+			t.Fatalf("RecordFetch() error = %v", err)
+		}
+
+		health, err := db.GetFeedHealth(feedURL)
+		if err != nil {
+			t.Fatalf("GetFeedHealth() error = %v", err)
+		}
+		if health.Fetches != 4 {
+			t.Errorf("Fetches = %d, want 4", health.Fetches)
+		}
+		if health.ConsecutiveFailures != 2 {
+			t.Errorf("ConsecutiveFailures = %d, want 2", health.ConsecutiveFailures)
+		}
+		if health.AverageNewEntries != 3 {
+			t.Errorf("AverageNewEntries = %v, want 3", health.AverageNewEntries)
+		}
+		if health.LastSuccess == nil {
+			t.Error("Expected LastSuccess to be set")
+		}
+		if health.LastError == nil || *health.LastError != "404 Not Found" {
+			t.Errorf("LastError = %v, want 404 Not Found", health.LastError)
+		}
+	})
+
+	t.Run("success resets consecutive failures", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		feedURL := "https://example.com/feed"
+		_ = db.RecordFetch(feedURL, 0, errors.New("timeout"))
+		_ = db.RecordFetch(feedURL, 1, nil)
+
+		health, err := db.GetFeedHealth(feedURL)
+		if err != nil {
+			t.Fatalf("GetFeedHealth() error = %v", err)
+		}
+		if health.ConsecutiveFailures != 0 {
+			t.Errorf("ConsecutiveFailures = %d, want 0", health.ConsecutiveFailures)
+		}
+		if health.LastError != nil {
+			t.Errorf("LastError = %v, want nil", *health.LastError)
+		}
+	})
+}
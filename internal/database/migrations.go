@@ -17,6 +17,147 @@ func getMigrations() map[int]string {
 				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 			);
 		`,
+		3: `
+			ALTER TABLE entries ADD COLUMN posted_content TEXT;
+		`,
+		4: `
+			CREATE TABLE IF NOT EXISTS fetch_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				feed_url TEXT NOT NULL,
+				fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				success BOOLEAN NOT NULL,
+				new_entries INTEGER NOT NULL DEFAULT 0,
+				error TEXT
+			);
+			CREATE INDEX IF NOT EXISTS idx_fetch_log_feed_url ON fetch_log(feed_url);
+		`,
+		5: `
+			CREATE TABLE IF NOT EXISTS attachments (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				entry_id TEXT NOT NULL,
+				source_url TEXT NOT NULL,
+				media_id TEXT NOT NULL,
+				content_type TEXT,
+				size INTEGER NOT NULL DEFAULT 0,
+				uploaded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_attachments_entry_id ON attachments(entry_id);
+		`,
+		6: `
+			ALTER TABLE entries ADD COLUMN status_id TEXT;
+			ALTER TABLE entries ADD COLUMN status_url TEXT;
+		`,
+		7: `
+			ALTER TABLE entries ADD COLUMN filtered_at DATETIME;
+			ALTER TABLE entries ADD COLUMN filter_reason TEXT;
+		`,
+		8: `
+			ALTER TABLE entries ADD COLUMN content_hash TEXT;
+			ALTER TABLE entries ADD COLUMN changed_at DATETIME;
+		`,
+		9: `
+			ALTER TABLE entries ADD COLUMN failure_count INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE entries ADD COLUMN last_error TEXT;
+			ALTER TABLE entries ADD COLUMN retry_at DATETIME;
+			ALTER TABLE entries ADD COLUMN failed_at DATETIME;
+		`,
+		10: `
+			ALTER TABLE entries ADD COLUMN scheduled_at DATETIME;
+			ALTER TABLE entries ADD COLUMN scheduled_id TEXT;
+		`,
+		11: `
+			CREATE TABLE IF NOT EXISTS account_posts (
+				entry_id TEXT NOT NULL,
+				account TEXT NOT NULL,
+				status_id TEXT,
+				status_url TEXT,
+				posted_at DATETIME,
+				failure_count INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT,
+				PRIMARY KEY (entry_id, account)
+			);
+		`,
+		12: `
+			ALTER TABLE entries ADD COLUMN deleted_at DATETIME;
+		`,
+		13: `
+			ALTER TABLE entries ADD COLUMN full_content TEXT;
+			ALTER TABLE entries ADD COLUMN lead_image TEXT;
+			ALTER TABLE entries ADD COLUMN extracted_at DATETIME;
+		`,
+		14: `
+			ALTER TABLE entries ADD COLUMN skipped_at DATETIME;
+		`,
+		15: `
+			CREATE TABLE IF NOT EXISTS post_history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				entry_id TEXT NOT NULL,
+				feed_url TEXT,
+				attempted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				success BOOLEAN NOT NULL,
+				backlog_seconds INTEGER,
+				error TEXT
+			);
+			CREATE INDEX IF NOT EXISTS idx_post_history_attempted_at ON post_history(attempted_at);
+			INSERT INTO post_history (entry_id, attempted_at, success, backlog_seconds)
+				SELECT id, posted_at, 1, CAST((julianday(posted_at) - julianday(fetched_at)) * 86400 AS INTEGER)
+				FROM entries WHERE posted_at IS NOT NULL;
+		`,
+		16: `
+			CREATE TABLE IF NOT EXISTS posts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				entry_id TEXT NOT NULL,
+				status_id TEXT,
+				status_url TEXT,
+				content TEXT NOT NULL,
+				content_warning TEXT,
+				media_ids TEXT,
+				edited BOOLEAN NOT NULL DEFAULT 0,
+				posted_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_posts_entry_id ON posts(entry_id);
+			INSERT INTO posts (entry_id, status_id, status_url, content, media_ids, posted_at)
+				SELECT id, status_id, status_url, posted_content,
+					(SELECT group_concat(media_id, ',') FROM attachments WHERE attachments.entry_id = entries.id),
+					posted_at
+				FROM entries WHERE posted_at IS NOT NULL AND posted_content IS NOT NULL;
+		`,
+		17: `
+			ALTER TABLE entries ADD COLUMN published_at DATETIME;
+			CREATE INDEX IF NOT EXISTS idx_entries_published_at ON entries(published_at);
+			UPDATE entries SET published_at = datetime(COALESCE(
+				json_extract(entry_data, '$.publishedParsed'),
+				json_extract(entry_data, '$.updatedParsed')
+			));
+		`,
+		18: `
+			CREATE TABLE IF NOT EXISTS tombstones (
+				id TEXT PRIMARY KEY,
+				status_id TEXT,
+				posted_at DATETIME NOT NULL,
+				deleted_at DATETIME
+			);
+		`,
+		19: `
+			CREATE TABLE IF NOT EXISTS media_uploads (
+				hash TEXT PRIMARY KEY,
+				media_id TEXT NOT NULL,
+				uploaded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+		// Feed metadata and poll hints are kept per feed now, and the
+		// next fetch stores them again
+		20: `
+			DELETE FROM settings WHERE key IN ('feed_metadata', 'feed_poll_hints');
+		`,
+		21: `
+			CREATE TABLE IF NOT EXISTS thread_statuses (
+				entry_id TEXT NOT NULL,
+				position INTEGER NOT NULL,
+				status_id TEXT NOT NULL,
+				PRIMARY KEY (entry_id, position)
+			);
+		`,
 	}
 }
 
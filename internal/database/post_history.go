@@ -0,0 +1,196 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Report periods, the buckets posts are counted in by GetPostReport.
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+// periodExpressions group post_history rows by the UTC day, or the week
+// starting on Monday.
+var periodExpressions = map[string]string{
+	PeriodDay:  "date(attempted_at)",
+	PeriodWeek: "date(attempted_at, 'weekday 0', '-6 days')",
+}
+
+// PeriodStats counts the post attempts in a day or week.
+type PeriodStats struct {
+	// Start is the first day of the period, like 2024-03-09.
+	Start  string `json:"start"`
+	Posted int    `json:"posted"`
+	Failed int    `json:"failed"`
+}
+
+// FeedStats counts the post attempts for entries of a feed.
+type FeedStats struct {
+	// FeedURL is empty for posts made before post history was kept.
+	FeedURL string `json:"feed_url"`
+	Posted  int    `json:"posted"`
+	Failed  int    `json:"failed"`
+}
+
+// PostReport summarizes posting since a point in time.
+type PostReport struct {
+	Since   time.Time     `json:"since"`
+	Periods []PeriodStats `json:"periods"`
+	Feeds   []FeedStats   `json:"feeds"`
+	Posted  int           `json:"posted"`
+	Failed  int           `json:"failed"`
+	// Fetched is the number of entries fetched since then that are still
+	// in the database.
+	Fetched int `json:"fetched"`
+	// AverageBacklog and MaxBacklog are how long posted entries waited
+	// between being fetched and posted.
+	AverageBacklog time.Duration `json:"-"`
+	MaxBacklog     time.Duration `json:"-"`
+	// Unposted is the number of entries waiting to be posted now, and
+	// OldestUnposted when the oldest of them was fetched.
+	Unposted       int        `json:"unposted"`
+	OldestUnposted *time.Time `json:"oldest_unposted,omitempty"`
+}
+
+// FailureRate returns the share of post attempts that failed, from 0 to 1.
+func (r *PostReport) FailureRate() float64 {
+	if r.Posted+r.Failed == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Posted+r.Failed)
+}
+
+// RecordPost logs an attempt to post an entry of the feed, with how long
+// the entry waited since it was fetched. Pass a nil postErr for a
+// successful post.
+func (db *DB) RecordPost(entryID, feedURL string, postErr error) error {
+	var errText sql.NullString
+	if postErr != nil {
+		errText = sql.NullString{String: postErr.Error(), Valid: true}
+	}
+
+	_, err := db.conn.Exec(`
+		INSERT INTO post_history (entry_id, feed_url, success, backlog_seconds, error)
+		SELECT id, ?, ?, CAST((julianday('now') - julianday(fetched_at)) * 86400 AS INTEGER), ?
+		FROM entries WHERE id = ?
+	`, feedURL, postErr == nil, errText, entryID)
+	if err != nil {
+		return fmt.Errorf("failed to record post: %w", err)
+	}
+
+	logrus.Debugf("Recorded post of %s (success: %v)", entryID, postErr == nil)
+	return nil
+}
+
+// GetPostReport summarizes the post attempts since the given time, counted
+// per period (PeriodDay or PeriodWeek) and per feed.
+func (db *DB) GetPostReport(since time.Time, period string) (*PostReport, error) {
+	periodExpression, ok := periodExpressions[period]
+	if !ok {
+		return nil, fmt.Errorf("unknown report period: %s", period)
+	}
+
+	report := &PostReport{Since: since, Periods: []PeriodStats{}, Feeds: []FeedStats{}}
+
+	rows, err := db.conn.Query(`
+		SELECT `+periodExpression+` AS period,
+			SUM(CASE WHEN success THEN 1 ELSE 0 END),
+			SUM(CASE WHEN success THEN 0 ELSE 1 END)
+		FROM post_history
+		WHERE attempted_at >= ?
+		GROUP BY period
+		ORDER BY period ASC
+	`, dbTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count posts per %s: %w", period, err)
+	}
+	for rows.Next() {
+		var stats PeriodStats
+		if err := rows.Scan(&stats.Start, &stats.Posted, &stats.Failed); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan post counts: %w", err)
+		}
+		report.Periods = append(report.Periods, stats)
+		report.Posted += stats.Posted
+		report.Failed += stats.Failed
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating post counts: %w", err)
+	}
+
+	rows, err = db.conn.Query(`
+		SELECT COALESCE(feed_url, ''),
+			SUM(CASE WHEN success THEN 1 ELSE 0 END),
+			SUM(CASE WHEN success THEN 0 ELSE 1 END)
+		FROM post_history
+		WHERE attempted_at >= ?
+		GROUP BY COALESCE(feed_url, '')
+		ORDER BY COUNT(*) DESC
+	`, dbTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count posts per feed: %w", err)
+	}
+	for rows.Next() {
+		var stats FeedStats
+		if err := rows.Scan(&stats.FeedURL, &stats.Posted, &stats.Failed); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan post counts: %w", err)
+		}
+		report.Feeds = append(report.Feeds, stats)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating post counts: %w", err)
+	}
+
+	var average sql.NullFloat64
+	var maximum sql.NullInt64
+	err = db.conn.QueryRow(`
+		SELECT AVG(backlog_seconds), MAX(backlog_seconds)
+		FROM post_history
+		WHERE attempted_at >= ? AND success AND backlog_seconds IS NOT NULL
+	`, dbTime(since)).Scan(&average, &maximum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backlog age: %w", err)
+	}
+	report.AverageBacklog = time.Duration(average.Float64 * float64(time.Second)).Round(time.Second)
+	report.MaxBacklog = time.Duration(maximum.Int64) * time.Second
+
+	err = db.conn.QueryRow("SELECT COUNT(*) FROM entries WHERE fetched_at >= ?", dbTime(since)).Scan(&report.Fetched)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count fetched entries: %w", err)
+	}
+
+	var oldest sql.NullString
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*), MIN(fetched_at) FROM entries
+		WHERE posted_at IS NULL AND skipped_at IS NULL AND filtered_at IS NULL AND failed_at IS NULL
+	`).Scan(&report.Unposted, &oldest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unposted entries: %w", err)
+	}
+	if oldest.Valid {
+		if t, err := parseDBTime(oldest.String); err == nil {
+			report.OldestUnposted = &t
+		}
+	}
+
+	return report, nil
+}
+
+// parseDBTime parses a DATETIME value read as a string, as stored by
+// dbTime, CURRENT_TIMESTAMP, or the SQLite driver.
+func parseDBTime(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetPostReport(t *testing.T) {
+	t.Run("empty history", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		report, err := db.GetPostReport(time.Time{}, PeriodDay)
+		if err != nil {
+			t.Fatalf("GetPostReport() error = %v", err)
+		}
+		if report.Posted != 0 || report.Failed != 0 || len(report.Periods) != 0 || report.OldestUnposted != nil {
+			t.Errorf("report = %+v, want empty", report)
+		}
+		if rate := report.FailureRate(); rate != 0 {
+			t.Errorf("FailureRate() = %v, want 0", rate)
+		}
+	})
+
+	t.Run("counts posts and failures", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		for _, id := range []string{"entry-1", "entry-2", "entry-3"} {
+			if _, err := db.SaveEntry(id, []byte(`{}`)); err != nil {
+				t.Fatalf("SaveEntry() error = %v", err)
+			}
+		}
+		// entry-1 waited two hours to be posted
+		if _, err := db.conn.Exec("UPDATE entries SET fetched_at = datetime('now', '-2 hours') WHERE id = 'entry-1'"); err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+
+		feed := "https://example.com/feed"
+		if err := db.RecordPost("entry-1", feed, nil); err != nil {
+			t.Fatalf("RecordPost() error = %v", err)
+		}
+		if err := db.RecordPost("entry-2", feed, errors.New("422 Unprocessable Entity")); err != nil {
+			t.Fatalf("RecordPost() error = %v", err)
+		}
+		if err := db.RecordPost("entry-2", feed, nil); err != nil {
+			t.Fatalf("RecordPost() error = %v", err)
+		}
+		if err := db.MarkAsPosted("entry-1", "1", ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+		if err := db.MarkAsPosted("entry-2", "2", ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+
+		for _, period := range []string{PeriodDay, PeriodWeek} {
+			report, err := db.GetPostReport(time.Now().Add(-24*time.Hour), period)
+			if err != nil {
+				t.Fatalf("GetPostReport(%s) error = %v", period, err)
+			}
+			if report.Posted != 2 || report.Failed != 1 {
+				t.Errorf("%s: posted, failed = %d, %d; want 2, 1", period, report.Posted, report.Failed)
+			}
+			if len(report.Periods) != 1 {
+				t.Errorf("%s: periods = %+v, want 1", period, report.Periods)
+			}
+			if len(report.Feeds) != 1 || report.Feeds[0].FeedURL != feed {
+				t.Errorf("%s: feeds = %+v", period, report.Feeds)
+			}
+			if report.MaxBacklog < 2*time.Hour-time.Minute || report.MaxBacklog > 2*time.Hour+time.Minute {
+				t.Errorf("%s: MaxBacklog = %s, want about 2h", period, report.MaxBacklog)
+			}
+			if report.Fetched != 3 || report.Unposted != 1 || report.OldestUnposted == nil {
+				t.Errorf("%s: fetched %d, unposted %d, oldest %v", period, report.Fetched, report.Unposted, report.OldestUnposted)
+			}
+		}
+
+		report, err := db.GetPostReport(time.Now().Add(time.Hour), PeriodDay)
+		if err != nil {
+			t.Fatalf("GetPostReport(future) error = %v", err)
+		}
+		if report.Posted != 0 {
+			t.Errorf("GetPostReport(future) posted = %d, want 0", report.Posted)
+		}
+	})
+
+	t.Run("rejects unknown periods", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.GetPostReport(time.Time{}, "month"); err == nil {
+			t.Error("Expected error for unknown period")
+		}
+	})
+}
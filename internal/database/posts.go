@@ -0,0 +1,73 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Post records a status as it was published for an entry: the text that
+// was sent, which later template or feed changes can't alter. An entry
+// has a post each time it's posted or its status is edited.
+type Post struct {
+	EntryID        string
+	StatusID       string
+	StatusURL      string
+	Content        string
+	ContentWarning string
+	MediaIDs       []string
+	// Edited is set when the post is an edit of an earlier one.
+	Edited   bool
+	PostedAt sql.NullTime
+}
+
+// SavePost records a status published for an entry.
+func (db *DB) SavePost(p Post) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO posts (entry_id, status_id, status_url, content, content_warning, media_ids, edited)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, p.EntryID, nullString(p.StatusID), nullString(p.StatusURL), p.Content,
+		nullString(p.ContentWarning), nullString(strings.Join(p.MediaIDs, ",")), p.Edited)
+	if err != nil {
+		return fmt.Errorf("failed to save post: %w", err)
+	}
+
+	logrus.Debugf("Saved post of entry %s", p.EntryID)
+	return nil
+}
+
+// GetPosts returns the statuses published for an entry, oldest first.
+func (db *DB) GetPosts(entryID string) ([]Post, error) {
+	rows, err := db.conn.Query(`
+		SELECT entry_id, COALESCE(status_id, ''), COALESCE(status_url, ''), content,
+			COALESCE(content_warning, ''), COALESCE(media_ids, ''), edited, posted_at
+		FROM posts
+		WHERE entry_id = ?
+		ORDER BY id
+	`, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []Post
+	for rows.Next() {
+		var p Post
+		var mediaIDs string
+		if err := rows.Scan(&p.EntryID, &p.StatusID, &p.StatusURL, &p.Content, &p.ContentWarning, &mediaIDs, &p.Edited, &p.PostedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		if mediaIDs != "" {
+			p.MediaIDs = strings.Split(mediaIDs, ",")
+		}
+		posts = append(posts, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating posts: %w", err)
+	}
+
+	return posts, nil
+}
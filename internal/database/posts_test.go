@@ -0,0 +1,83 @@
+package database
+
+import "testing"
+
+func TestPosts(t *testing.T) {
+	t.Run("saves and lists posts", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		for _, p := range []Post{
+			{EntryID: "entry-1", StatusID: "1", StatusURL: "https://example.social/@bot/1", Content: "First", MediaIDs: []string{"101", "102"}},
+			{EntryID: "entry-1", StatusID: "1", StatusURL: "https://example.social/@bot/1", Content: "First, edited", ContentWarning: "spoilers", Edited: true},
+			{EntryID: "entry-2", Content: "Other"},
+		} {
+			if err := db.SavePost(p); err != nil {
+				t.Fatalf("SavePost() error = %v", err)
+			}
+		}
+
+		posts, err := db.GetPosts("entry-1")
+		if err != nil {
+			t.Fatalf("GetPosts() error = %v", err)
+		}
+		if len(posts) != 2 {
+			t.Fatalf("GetPosts() returned %d posts, want 2", len(posts))
+		}
+		if posts[0].Content != "First" || posts[0].Edited {
+			t.Errorf("first post = %+v", posts[0])
+		}
+		if len(posts[0].MediaIDs) != 2 || posts[0].MediaIDs[1] != "102" {
+			t.Errorf("MediaIDs = %v, want [101 102]", posts[0].MediaIDs)
+		}
+		if posts[1].Content != "First, edited" || !posts[1].Edited || posts[1].ContentWarning != "spoilers" {
+			t.Errorf("second post = %+v", posts[1])
+		}
+		if posts[1].MediaIDs != nil {
+			t.Errorf("MediaIDs = %v, want none", posts[1].MediaIDs)
+		}
+		if !posts[0].PostedAt.Valid {
+			t.Error("PostedAt should be set")
+		}
+	})
+
+	t.Run("posts survive requeue but not deletion", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if _, err := db.SaveEntry("entry-1", []byte(`{"title": "Test"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if err := db.SavePost(Post{EntryID: "entry-1", Content: "Posted"}); err != nil {
+			t.Fatalf("SavePost() error = %v", err)
+		}
+
+		if _, err := db.Requeue("entry-1"); err != nil {
+			t.Fatalf("Requeue() error = %v", err)
+		}
+		posts, err := db.GetPosts("entry-1")
+		if err != nil {
+			t.Fatalf("GetPosts() error = %v", err)
+		}
+		if len(posts) != 1 {
+			t.Errorf("GetPosts() returned %d posts after requeue, want 1", len(posts))
+		}
+
+		if _, err := db.DeleteEntries([]string{"entry-1"}); err != nil {
+			t.Fatalf("DeleteEntries() error = %v", err)
+		}
+		posts, err = db.GetPosts("entry-1")
+		if err != nil {
+			t.Fatalf("GetPosts() error = %v", err)
+		}
+		if len(posts) != 0 {
+			t.Errorf("GetPosts() returned %d posts after delete, want 0", len(posts))
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/secret"
+	"github.com/sirupsen/logrus"
+)
+
+// feedSettingsPrefix starts the keys of settings that belong to one feed.
+const feedSettingsPrefix = "feed:"
+
+// Settings reads and writes one namespace of the settings table, with
+// accessors for values stored as JSON or times as well as plain strings.
+type Settings struct {
+	db     *DB
+	prefix string
+}
+
+// Settings returns the settings that don't belong to a feed, like tokens.
+func (db *DB) Settings() *Settings {
+	return &Settings{db: db}
+}
+
+// FeedSettings returns the settings that belong to the feed at feedURL,
+// like its metadata and poll hints, kept apart from other feeds' so they
+// don't carry over when the feed changes.
+func (db *DB) FeedSettings(feedURL string) *Settings {
+	return &Settings{db: db, prefix: feedSettingsPrefix + feedURL + ":"}
+}
+
+// Get returns the value of key, or nil if it isn't set.
+func (s *Settings) Get(key string) (*string, error) {
+	return s.db.GetSetting(s.prefix + key)
+}
+
+// Set sets key to value.
+func (s *Settings) Set(key, value string) error {
+	return s.db.SetSetting(s.prefix+key, value)
+}
+
+// Delete unsets key. Deleting a key that isn't set is not an error.
+func (s *Settings) Delete(key string) error {
+	return s.db.DeleteSetting(s.prefix + key)
+}
+
+// GetJSON decodes the JSON value of key into v. Returns false, leaving v
+// alone, if it isn't set.
+func (s *Settings) GetJSON(key string, v any) (bool, error) {
+	value, err := s.Get(key)
+	if err != nil || value == nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(*value), v); err != nil {
+		return false, fmt.Errorf("invalid %s setting: %w", key, err)
+	}
+	return true, nil
+}
+
+// SetJSON sets key to v encoded as JSON.
+func (s *Settings) SetJSON(key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s setting: %w", key, err)
+	}
+	return s.Set(key, string(value))
+}
+
+// GetTime returns the time stored in key, or nil if it isn't set.
+func (s *Settings) GetTime(key string) (*time.Time, error) {
+	value, err := s.Get(key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s setting: %w", key, err)
+	}
+	return &t, nil
+}
+
+// SetTime sets key to t, in UTC to the second.
+func (s *Settings) SetTime(key string, t time.Time) error {
+	return s.Set(key, t.UTC().Format(time.RFC3339))
+}
+
+// GetSecret returns the value of a secret setting, like an access token,
+// decrypting it if it's encrypted, or nil if it isn't set. With a cipher
+// set, secrets stored in plain text before are encrypted as they're read.
+func (s *Settings) GetSecret(key string) (*string, error) {
+	value, err := s.Get(key)
+	if err != nil || value == nil {
+		return value, err
+	}
+
+	if !secret.Encrypted(*value) {
+		if s.db.secrets != nil {
+			if err := s.SetSecret(key, *value); err != nil {
+				logrus.Warnf("Failed to encrypt the %s setting: %v", key, err)
+			} else {
+				logrus.Infof("Encrypted the %s setting", key)
+			}
+		}
+		return value, nil
+	}
+
+	if s.db.secrets == nil {
+		return nil, fmt.Errorf("%s setting is encrypted, and no secret key is set", key)
+	}
+	plaintext, err := s.db.secrets.Decrypt(*value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s setting: %w", key, err)
+	}
+	return &plaintext, nil
+}
+
+// SetSecret sets a secret setting to value, encrypted if a cipher is set.
+func (s *Settings) SetSecret(key, value string) error {
+	if s.db.secrets == nil {
+		return s.Set(key, value)
+	}
+	encrypted, err := s.db.secrets.Encrypt(value)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s setting: %w", key, err)
+	}
+	return s.Set(key, encrypted)
+}
+
+// SetSecretCipher has secret settings encrypted with c from now on.
+func (db *DB) SetSecretCipher(c *secret.Cipher) {
+	db.secrets = c
+}
+
+// SetSetting stores a key-value pair in the settings table.
+func (db *DB) SetSetting(key, value string) error {
+	query := `
+		INSERT INTO settings (key, value, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(key) DO UPDATE SET
+			value = excluded.value,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := db.conn.Exec(query, key, value)
+	if err != nil {
+		db.forgetSetting(key)
+		return fmt.Errorf("failed to set setting %s: %w", key, err)
+	}
+	db.cacheSetting(key, &value)
+
+	logrus.Debugf("Set setting: %s", key)
+	return nil
+}
+
+// GetSetting retrieves a value from the settings table.
+// Returns nil if the key doesn't exist. Values, including missing ones,
+// are cached until ReloadSettings.
+func (db *DB) GetSetting(key string) (*string, error) {
+	if value, ok := db.cachedSetting(key); ok {
+		return value, nil
+	}
+
+	var value string
+	err := db.conn.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		db.cacheSetting(key, nil)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+
+	db.cacheSetting(key, &value)
+	return &value, nil
+}
+
+// DeleteSetting removes a key from the settings table.
+// Deleting a key that doesn't exist is not an error.
+func (db *DB) DeleteSetting(key string) error {
+	if _, err := db.conn.Exec("DELETE FROM settings WHERE key = ?", key); err != nil {
+		db.forgetSetting(key)
+		return fmt.Errorf("failed to delete setting %s: %w", key, err)
+	}
+	db.cacheSetting(key, nil)
+
+	logrus.Debugf("Deleted setting: %s", key)
+	return nil
+}
+
+// ReloadSettings forgets the cached settings, so changes made by other
+// processes, like a new token from the code command, are seen. Long-running
+// processes call it before each run.
+func (db *DB) ReloadSettings() {
+	db.settingsMu.Lock()
+	defer db.settingsMu.Unlock()
+	db.settings = nil
+}
+
+// cachedSetting returns the cached value of key, and whether it's cached.
+func (db *DB) cachedSetting(key string) (*string, bool) {
+	db.settingsMu.Lock()
+	defer db.settingsMu.Unlock()
+	value, ok := db.settings[key]
+	return value, ok
+}
+
+// cacheSetting caches value, which is nil if it's not set, as the value of
+// key.
+func (db *DB) cacheSetting(key string, value *string) {
+	db.settingsMu.Lock()
+	defer db.settingsMu.Unlock()
+	if db.settings == nil {
+		db.settings = make(map[string]*string)
+	}
+	db.settings[key] = value
+}
+
+// forgetSetting drops key from the cache, when its value is uncertain
+// after a failed change.
+func (db *DB) forgetSetting(key string) {
+	db.settingsMu.Lock()
+	defer db.settingsMu.Unlock()
+	delete(db.settings, key)
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/secret"
+)
+
+func TestSettings(t *testing.T) {
+	newDB := func(t *testing.T) *DB {
+		t.Helper()
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	t.Run("round-trips JSON values", func(t *testing.T) {
+		settings := newDB(t).Settings()
+
+		type hints struct {
+			SkipHours []int `json:"skip_hours"`
+		}
+		var got hints
+		found, err := settings.GetJSON("hints", &got)
+		if err != nil || found {
+			t.Fatalf("GetJSON() of unset key = %v, %v; want false", found, err)
+		}
+
+		if err := settings.SetJSON("hints", hints{SkipHours: []int{1, 2}}); err != nil {
+			t.Fatalf("SetJSON() error = %v", err)
+		}
+		found, err = settings.GetJSON("hints", &got)
+		if err != nil || !found {
+			t.Fatalf("GetJSON() = %v, %v; want true", found, err)
+		}
+		if len(got.SkipHours) != 2 || got.SkipHours[1] != 2 {
+			t.Errorf("GetJSON() decoded %+v, want skip hours 1 and 2", got)
+		}
+	})
+
+	t.Run("rejects invalid JSON", func(t *testing.T) {
+		settings := newDB(t).Settings()
+		if err := settings.Set("count", "three"); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		var count int
+		if _, err := settings.GetJSON("count", &count); err == nil {
+			t.Error("GetJSON() error = nil, want invalid setting")
+		}
+	})
+
+	t.Run("round-trips times", func(t *testing.T) {
+		settings := newDB(t).Settings()
+
+		got, err := settings.GetTime("until")
+		if err != nil || got != nil {
+			t.Fatalf("GetTime() of unset key = %v, %v; want nil", got, err)
+		}
+
+		want := time.Date(2024, 3, 9, 12, 30, 15, 500, time.FixedZone("EST", -5*60*60))
+		if err := settings.SetTime("until", want); err != nil {
+			t.Fatalf("SetTime() error = %v", err)
+		}
+		got, err = settings.GetTime("until")
+		if err != nil || got == nil {
+			t.Fatalf("GetTime() = %v, %v", got, err)
+		}
+		if !got.Equal(want.Truncate(time.Second)) {
+			t.Errorf("GetTime() = %v, want %v", got, want.Truncate(time.Second))
+		}
+
+		// Times are stored like the settings written before there were
+		// typed accessors
+		value, err := settings.Get("until")
+		if err != nil || value == nil || *value != "2024-03-09T17:30:15Z" {
+			t.Errorf("Get() = %v, %v; want 2024-03-09T17:30:15Z", value, err)
+		}
+	})
+
+	t.Run("keeps feeds apart", func(t *testing.T) {
+		db := newDB(t)
+		first := db.FeedSettings("https://example.com/feed.xml")
+		second := db.FeedSettings("https://example.org/feed.xml")
+
+		if err := first.Set("etag", "abc"); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		if value, err := second.Get("etag"); err != nil || value != nil {
+			t.Errorf("Get() of another feed = %v, %v; want nil", value, err)
+		}
+		if value, err := db.Settings().Get("etag"); err != nil || value != nil {
+			t.Errorf("Get() outside the feed = %v, %v; want nil", value, err)
+		}
+		if value, err := first.Get("etag"); err != nil || value == nil || *value != "abc" {
+			t.Errorf("Get() = %v, %v; want abc", value, err)
+		}
+
+		if err := first.Delete("etag"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if value, err := first.Get("etag"); err != nil || value != nil {
+			t.Errorf("Get() after Delete() = %v, %v; want nil", value, err)
+		}
+	})
+}
+
+func TestSettingsCache(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+	other, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer other.Close()
+
+	if err := db.SetSetting("token", "old"); err != nil {
+		t.Fatalf("SetSetting() error = %v", err)
+	}
+	if value, err := db.GetSetting("missing"); err != nil || value != nil {
+		t.Fatalf("GetSetting() = %v, %v; want nil", value, err)
+	}
+
+	// Changes through another connection aren't seen until reloading
+	if err := other.SetSetting("token", "new"); err != nil {
+		t.Fatalf("SetSetting() error = %v", err)
+	}
+	if err := other.SetSetting("missing", "found"); err != nil {
+		t.Fatalf("SetSetting() error = %v", err)
+	}
+	if value, err := db.GetSetting("token"); err != nil || value == nil || *value != "old" {
+		t.Errorf("cached GetSetting() = %v, %v; want old", value, err)
+	}
+	if value, err := db.GetSetting("missing"); err != nil || value != nil {
+		t.Errorf("cached GetSetting() = %v, %v; want nil", value, err)
+	}
+
+	db.ReloadSettings()
+	if value, err := db.GetSetting("token"); err != nil || value == nil || *value != "new" {
+		t.Errorf("GetSetting() after ReloadSettings() = %v, %v; want new", value, err)
+	}
+	if value, err := db.GetSetting("missing"); err != nil || value == nil || *value != "found" {
+		t.Errorf("GetSetting() after ReloadSettings() = %v, %v; want found", value, err)
+	}
+
+	// Wiping the database clears the cache too
+	if _, _, err := db.Wipe(); err != nil {
+		t.Fatalf("Wipe() error = %v", err)
+	}
+	if value, err := db.GetSetting("token"); err != nil || value != nil {
+		t.Errorf("GetSetting() after Wipe() = %v, %v; want nil", value, err)
+	}
+}
+
+func TestSecretSettings(t *testing.T) {
+	newCipher := func(t *testing.T, key string) *secret.Cipher {
+		t.Helper()
+		c, err := secret.NewCipher(key)
+		if err != nil {
+			t.Fatalf("NewCipher() error = %v", err)
+		}
+		return c
+	}
+
+	t.Run("stores secrets encrypted", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+		db.SetSecretCipher(newCipher(t, "key"))
+
+		if err := db.Settings().SetSecret("token", "token-123"); err != nil {
+			t.Fatalf("SetSecret() error = %v", err)
+		}
+		stored, err := db.Settings().Get("token")
+		if err != nil || stored == nil || strings.Contains(*stored, "token-123") {
+			t.Errorf("stored value = %v, %v; want it encrypted", stored, err)
+		}
+		value, err := db.Settings().GetSecret("token")
+		if err != nil || value == nil || *value != "token-123" {
+			t.Errorf("GetSecret() = %v, %v; want token-123", value, err)
+		}
+	})
+
+	t.Run("encrypts plain secrets stored before", func(t *testing.T) {
+		db, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+
+		if err := db.Settings().SetSecret("token", "token-123"); err != nil {
+			t.Fatalf("SetSecret() error = %v", err)
+		}
+		db.SetSecretCipher(newCipher(t, "key"))
+
+		value, err := db.Settings().GetSecret("token")
+		if err != nil || value == nil || *value != "token-123" {
+			t.Errorf("GetSecret() = %v, %v; want token-123", value, err)
+		}
+		stored, err := db.Settings().Get("token")
+		if err != nil || stored == nil || !secret.Encrypted(*stored) {
+			t.Errorf("stored value = %v, %v; want it encrypted after reading", stored, err)
+		}
+	})
+
+	t.Run("requires the right key", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "test.db")
+		db, err := New(dbPath)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		db.SetSecretCipher(newCipher(t, "key"))
+		if err := db.Settings().SetSecret("token", "token-123"); err != nil {
+			t.Fatalf("SetSecret() error = %v", err)
+		}
+		db.Close()
+
+		db, err = New(dbPath)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer db.Close()
+		if _, err := db.Settings().GetSecret("token"); err == nil {
+			t.Error("GetSecret() without a key succeeded")
+		}
+		db.SetSecretCipher(newCipher(t, "another key"))
+		if _, err := db.Settings().GetSecret("token"); err == nil {
+			t.Error("GetSecret() with another key succeeded")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SaveThreadStatuses records the replies a long entry was split into, in
+// order, replacing those of an earlier post of the entry. They're kept
+// after the entry is purged, so its whole thread can still be deleted.
+func (db *DB) SaveThreadStatuses(entryID string, statusIDs []string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("DELETE FROM thread_statuses WHERE entry_id = ?", entryID); err != nil {
+		return fmt.Errorf("failed to clear thread statuses: %w", err)
+	}
+	for i, statusID := range statusIDs {
+		_, err := tx.Exec(
+			"INSERT INTO thread_statuses (entry_id, position, status_id) VALUES (?, ?, ?)",
+			entryID, i+1, statusID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save thread status: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit thread statuses: %w", err)
+	}
+	if len(statusIDs) > 0 {
+		logrus.Debugf("Saved %d thread statuses of entry %s", len(statusIDs), entryID)
+	}
+	return nil
+}
+
+// GetThreadStatuses returns the IDs of the replies a long entry was split
+// into that weren't deleted yet, in thread order.
+func (db *DB) GetThreadStatuses(entryID string) ([]string, error) {
+	rows, err := db.conn.Query(
+		"SELECT status_id FROM thread_statuses WHERE entry_id = ? ORDER BY position", entryID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query thread statuses: %w", err)
+	}
+	defer rows.Close()
+
+	var statusIDs []string
+	for rows.Next() {
+		var statusID string
+		if err := rows.Scan(&statusID); err != nil {
+			return nil, fmt.Errorf("failed to scan thread status: %w", err)
+		}
+		statusIDs = append(statusIDs, statusID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating thread statuses: %w", err)
+	}
+	return statusIDs, nil
+}
+
+// RemoveThreadStatus forgets a reply of an entry's thread once it's been
+// deleted from Mastodon.
+func (db *DB) RemoveThreadStatus(entryID, statusID string) error {
+	result, err := db.conn.Exec(
+		"DELETE FROM thread_statuses WHERE entry_id = ? AND status_id = ?", entryID, statusID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove thread status: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("thread status not found: %s", statusID)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestThreadStatuses(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SaveThreadStatuses("entry", []string{"2", "3", "4"}); err != nil {
+		t.Fatalf("SaveThreadStatuses() error = %v", err)
+	}
+	if got, err := db.GetThreadStatuses("entry"); err != nil || !reflect.DeepEqual(got, []string{"2", "3", "4"}) {
+		t.Fatalf("GetThreadStatuses() = %v, %v; want [2 3 4], nil", got, err)
+	}
+	if got, err := db.GetThreadStatuses("other"); err != nil || len(got) != 0 {
+		t.Errorf("GetThreadStatuses(other) = %v, %v; want none", got, err)
+	}
+
+	if err := db.RemoveThreadStatus("entry", "4"); err != nil {
+		t.Fatalf("RemoveThreadStatus() error = %v", err)
+	}
+	if got, err := db.GetThreadStatuses("entry"); err != nil || !reflect.DeepEqual(got, []string{"2", "3"}) {
+		t.Errorf("GetThreadStatuses() after remove = %v, %v; want [2 3], nil", got, err)
+	}
+	if err := db.RemoveThreadStatus("entry", "4"); err == nil {
+		t.Error("RemoveThreadStatus() of removed status error = nil, want error")
+	}
+
+	t.Run("posting again replaces the thread", func(t *testing.T) {
+		if err := db.SaveThreadStatuses("entry", []string{"9"}); err != nil {
+			t.Fatalf("SaveThreadStatuses() error = %v", err)
+		}
+		if got, err := db.GetThreadStatuses("entry"); err != nil || !reflect.DeepEqual(got, []string{"9"}) {
+			t.Errorf("GetThreadStatuses() = %v, %v; want [9], nil", got, err)
+		}
+
+		if err := db.SaveThreadStatuses("entry", nil); err != nil {
+			t.Fatalf("SaveThreadStatuses(nil) error = %v", err)
+		}
+		if got, err := db.GetThreadStatuses("entry"); err != nil || len(got) != 0 {
+			t.Errorf("GetThreadStatuses() after unsplit post = %v, %v; want none", got, err)
+		}
+	})
+
+	t.Run("kept after the entry is purged", func(t *testing.T) {
+		if _, err := db.SaveEntry("purged", []byte(`{"title": "Entry"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if err := db.MarkAsPosted("purged", "1", ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+		if err := db.SaveThreadStatuses("purged", []string{"2"}); err != nil {
+			t.Fatalf("SaveThreadStatuses() error = %v", err)
+		}
+		if _, err := db.DeleteEntries([]string{"purged"}); err != nil {
+			t.Fatalf("DeleteEntries() error = %v", err)
+		}
+		if got, err := db.GetThreadStatuses("purged"); err != nil || !reflect.DeepEqual(got, []string{"2"}) {
+			t.Errorf("GetThreadStatuses() of purged entry = %v, %v; want [2], nil", got, err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Tombstone is what's left of a posted entry after it was purged: enough
+// to recognize the entry if it comes back into the feed, and to delete its
+// status.
+type Tombstone struct {
+	ID        string
+	StatusID  sql.NullString
+	PostedAt  sql.NullTime
+	DeletedAt sql.NullTime
+}
+
+// GetTombstone returns the tombstone of a purged entry, or nil if there
+// is none.
+func (db *DB) GetTombstone(id string) (*Tombstone, error) {
+	t := &Tombstone{}
+	err := db.conn.QueryRow(
+		"SELECT id, status_id, posted_at, deleted_at FROM tombstones WHERE id = ?", id,
+	).Scan(&t.ID, &t.StatusID, &t.PostedAt, &t.DeletedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tombstone: %w", err)
+	}
+	return t, nil
+}
+
+// MarkTombstoneDeleted records that the status of a purged entry was
+// deleted.
+func (db *DB) MarkTombstoneDeleted(id string) error {
+	result, err := db.conn.Exec("UPDATE tombstones SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to mark tombstone as deleted: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("tombstone not found: %s", id)
+	}
+
+	logrus.Debugf("Marked tombstone as deleted: %s", id)
+	return nil
+}
+
+// buryEntry leaves a tombstone for an entry about to be deleted, if it was
+// posted.
+func (db *DB) buryEntry(id string) error {
+	_, err := db.conn.Exec(`
+		INSERT OR REPLACE INTO tombstones (id, status_id, posted_at, deleted_at)
+		SELECT id, status_id, posted_at, deleted_at FROM entries
+		WHERE id = ? AND posted_at IS NOT NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to save tombstone: %w", err)
+	}
+	return nil
+}
+
+// restoreTombstone marks a newly saved entry as posted as its status, if
+// it was purged after being posted, and removes its tombstone. Returns
+// whether the entry had a tombstone.
+func (db *DB) restoreTombstone(ctx context.Context, id string) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE entries SET
+			posted_at = tombstones.posted_at,
+			status_id = tombstones.status_id,
+			deleted_at = tombstones.deleted_at
+		FROM tombstones
+		WHERE entries.id = ? AND tombstones.id = entries.id
+	`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore tombstone: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	if _, err := db.conn.ExecContext(ctx, "DELETE FROM tombstones WHERE id = ?", id); err != nil {
+		return false, fmt.Errorf("failed to remove tombstone: %w", err)
+	}
+	logrus.Debugf("Entry came back after being purged, keeping it posted: %s", id)
+	return true, nil
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTombstones(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"posted", "unposted"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Entry"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+	if err := db.MarkAsPosted("posted", "109", "https://example.social/@bot/109"); err != nil {
+		t.Fatalf("MarkAsPosted() error = %v", err)
+	}
+
+	if deleted, err := db.DeleteEntries([]string{"posted", "unposted"}); err != nil || deleted != 2 {
+		t.Fatalf("DeleteEntries() = %d, %v; want 2, nil", deleted, err)
+	}
+
+	tombstone, err := db.GetTombstone("posted")
+	if err != nil {
+		t.Fatalf("GetTombstone() error = %v", err)
+	}
+	if tombstone == nil || tombstone.StatusID.String != "109" || !tombstone.PostedAt.Valid {
+		t.Fatalf("GetTombstone() = %+v, want status 109", tombstone)
+	}
+	if tombstone, err := db.GetTombstone("unposted"); err != nil || tombstone != nil {
+		t.Errorf("GetTombstone() of unposted entry = %+v, %v; want nil, nil", tombstone, err)
+	}
+
+	if err := db.MarkTombstoneDeleted("posted"); err != nil {
+		t.Fatalf("MarkTombstoneDeleted() error = %v", err)
+	}
+
+	t.Run("entries that come back stay posted", func(t *testing.T) {
+		result, err := db.SaveEntryContent("posted", []byte(`{"title": "Entry"}`), "hash")
+		if err != nil {
+			t.Fatalf("SaveEntryContent() error = %v", err)
+		}
+		if result != SaveUpdated {
+			t.Errorf("SaveEntryContent() = %v, want SaveUpdated", result)
+		}
+
+		entry, err := db.GetEntry("posted")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if entry.PostedAt == nil || !entry.PostedAt.Valid || entry.StatusID.String != "109" || !entry.DeletedAt.Valid {
+			t.Errorf("entry = %+v, want posted as deleted status 109", entry)
+		}
+		if tombstone, err := db.GetTombstone("posted"); err != nil || tombstone != nil {
+			t.Errorf("GetTombstone() after restoring = %+v, %v; want nil, nil", tombstone, err)
+		}
+	})
+
+	t.Run("unposted entries come back new", func(t *testing.T) {
+		inserted, err := db.SaveEntry("unposted", []byte(`{"title": "Entry"}`))
+		if err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if !inserted {
+			t.Error("SaveEntry() = false, want true for an entry without a tombstone")
+		}
+	})
+}
+
+func TestGetPostedStatus(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"posted", "purged", "scheduled", "deleted", "queued"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Entry"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+	for id, statusID := range map[string]string{"posted": "1", "purged": "2", "deleted": "3"} {
+		if err := db.MarkAsPosted(id, statusID, ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+	}
+	if err := db.MarkAsScheduled("scheduled", "9", time.Now()); err != nil {
+		t.Fatalf("MarkAsScheduled() error = %v", err)
+	}
+	if err := db.MarkAsDeleted("deleted"); err != nil {
+		t.Fatalf("MarkAsDeleted() error = %v", err)
+	}
+	if _, err := db.DeleteEntries([]string{"purged"}); err != nil {
+		t.Fatalf("DeleteEntries() error = %v", err)
+	}
+
+	tests := []struct {
+		id      string
+		want    *PostedStatus
+		wantErr string
+	}{
+		{id: "posted", want: &PostedStatus{EntryID: "posted", StatusID: "1"}},
+		{id: "purged", want: &PostedStatus{EntryID: "purged", StatusID: "2", Purged: true}},
+		{id: "scheduled", wantErr: "scheduled status"},
+		{id: "deleted", wantErr: "already deleted"},
+		{id: "queued", wantErr: "no status"},
+		{id: "missing", wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := db.GetPostedStatus(tt.id)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GetPostedStatus() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPostedStatus() = %+v, %v; want %+v, nil", got, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("purged entry whose status was deleted", func(t *testing.T) {
+		if err := db.MarkTombstoneDeleted("purged"); err != nil {
+			t.Fatalf("MarkTombstoneDeleted() error = %v", err)
+		}
+		if _, err := db.GetPostedStatus("purged"); err == nil || !strings.Contains(err.Error(), "already deleted") {
+			t.Errorf("GetPostedStatus() error = %v, want already deleted", err)
+		}
+	})
+}
+
+func TestRecordStatusDeleted(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"kept", "requeued", "purged"} {
+		if _, err := db.SaveEntry(id, []byte(`{"title": "Entry"}`)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+		if err := db.MarkAsPosted(id, "1"+id, ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+	}
+	if _, err := db.DeleteEntries([]string{"purged"}); err != nil {
+		t.Fatalf("DeleteEntries() error = %v", err)
+	}
+
+	t.Run("marks the entry deleted", func(t *testing.T) {
+		if err := db.RecordStatusDeleted(PostedStatus{EntryID: "kept", StatusID: "1kept"}, false); err != nil {
+			t.Fatalf("RecordStatusDeleted() error = %v", err)
+		}
+		entry, err := db.GetEntry("kept")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if !entry.DeletedAt.Valid || entry.State() != StatePosted {
+			t.Errorf("entry = %v, %s; want deleted and still posted", entry.DeletedAt, entry.State())
+		}
+	})
+
+	t.Run("requeues the entry", func(t *testing.T) {
+		if err := db.RecordStatusDeleted(PostedStatus{EntryID: "requeued", StatusID: "1requeued"}, true); err != nil {
+			t.Fatalf("RecordStatusDeleted() error = %v", err)
+		}
+		entry, err := db.GetEntry("requeued")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if entry.State() != StateUnposted || entry.StatusID.Valid || entry.DeletedAt.Valid {
+			t.Errorf("entry = %s, status %v, deleted %v; want unposted with no status", entry.State(), entry.StatusID, entry.DeletedAt)
+		}
+	})
+
+	t.Run("marks the tombstone of a purged entry deleted", func(t *testing.T) {
+		purged := PostedStatus{EntryID: "purged", StatusID: "1purged", Purged: true}
+		if err := db.RecordStatusDeleted(purged, true); err == nil {
+			t.Error("RecordStatusDeleted(requeue) of purged entry error = nil, want error")
+		}
+		if err := db.RecordStatusDeleted(purged, false); err != nil {
+			t.Fatalf("RecordStatusDeleted() error = %v", err)
+		}
+		tombstone, err := db.GetTombstone("purged")
+		if err != nil {
+			t.Fatalf("GetTombstone() error = %v", err)
+		}
+		if !tombstone.DeletedAt.Valid {
+			t.Error("tombstone isn't marked deleted")
+		}
+		if entry, err := db.GetEntry("purged"); err != nil || entry != nil {
+			t.Errorf("GetEntry() of purged entry = %v, %v; want nil, nil", entry, err)
+		}
+	})
+}
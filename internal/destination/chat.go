@@ -0,0 +1,79 @@
+package destination
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+)
+
+// Chat webhook kinds, as accepted by NewChatWebhook.
+const (
+	ChatDiscord = "discord"
+	ChatSlack   = "slack"
+)
+
+// The longest messages Discord and Slack accept.
+const (
+	DiscordMaxCharacters = 2000
+	SlackMaxCharacters   = 40000
+)
+
+// ChatWebhook is a Destination that announces posts in a Discord or Slack
+// channel through an incoming webhook.
+type ChatWebhook struct {
+	kind   string
+	url    string
+	client *http.Client
+}
+
+// NewChatWebhook creates a destination that sends posts to a Discord or
+// Slack incoming webhook URL.
+func NewChatWebhook(kind, url string) (*ChatWebhook, error) {
+	if kind != ChatDiscord && kind != ChatSlack {
+		return nil, fmt.Errorf("unknown chat webhook type: %s", kind)
+	}
+	if url == "" {
+		return nil, fmt.Errorf("%s webhook requires a url", kind)
+	}
+	return &ChatWebhook{kind: kind, url: url, client: &http.Client{Timeout: webhookTimeout}}, nil
+}
+
+// PostEntries sends the rendered posts of entries to the channel.
+func (d *ChatWebhook) PostEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]PostResult, error) {
+	return PostEach(entries, renderer, dryRun, d.send)
+}
+
+// send sends a single post as a chat message. Content warnings are shown
+// with the post hidden as a spoiler on Discord, and above it on Slack.
+func (d *ChatWebhook) send(entry *database.Entry, content string) error {
+	var message any
+	switch d.kind {
+	case ChatDiscord:
+		if entry.ContentWarning != "" {
+			content = fmt.Sprintf("CW: %s\n||%s||", entry.ContentWarning, content)
+		}
+		if n := utf8.RuneCountInString(content); n > DiscordMaxCharacters {
+			return fmt.Errorf("message is %d characters, over Discord's limit of %d", n, DiscordMaxCharacters)
+		}
+		// Don't let feed content ping @everyone or anyone else
+		message = map[string]any{
+			"content":          content,
+			"allowed_mentions": map[string]any{"parse": []string{}},
+		}
+	default:
+		if entry.ContentWarning != "" {
+			content = fmt.Sprintf("CW: %s\n%s", entry.ContentWarning, content)
+		}
+		message = map[string]any{"text": content}
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", d.kind, err)
+	}
+	return postJSON(d.client, d.url, nil, body)
+}
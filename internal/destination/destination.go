@@ -0,0 +1,119 @@
+// Package destination defines where rendered posts are published, with
+// built-in destinations that print them, write them to files, or send them
+// to a webhook. The Mastodon poster is a Destination too.
+package destination
+
+import (
+	"database/sql"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+	"github.com/sirupsen/logrus"
+)
+
+// Destination publishes the rendered posts of feed entries.
+type Destination interface {
+	// PostEntries renders and publishes entries, returning one result per
+	// entry in the same order. It continues past failures of single
+	// entries, and returns an error only if it stopped early.
+	PostEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]PostResult, error)
+}
+
+// Outcome describes what happened to an entry in PostEntries.
+type Outcome int
+
+const (
+	// OutcomeSkipped means the entry wasn't attempted because the batch
+	// was stopped early.
+	OutcomeSkipped Outcome = iota
+	// OutcomePosted means the entry was posted (or would be, in dry run mode).
+	OutcomePosted
+	// OutcomeFailed means rendering or posting the entry failed.
+	OutcomeFailed
+)
+
+// String returns a human-readable name for the outcome.
+func (o Outcome) String() string {
+	switch o {
+	case OutcomePosted:
+		return "posted"
+	case OutcomeFailed:
+		return "failed"
+	default:
+		return "skipped"
+	}
+}
+
+// PostResult is the result of posting a single entry.
+type PostResult struct {
+	Entry   *database.Entry
+	Outcome Outcome
+	Err     error
+}
+
+// CountPosted returns the number of results with OutcomePosted.
+func CountPosted(results []PostResult) int {
+	posted := 0
+	for _, result := range results {
+		if result.Outcome == OutcomePosted {
+			posted++
+		}
+	}
+	return posted
+}
+
+// PublishFunc publishes the rendered content of a single entry.
+type PublishFunc func(entry *database.Entry, content string) error
+
+// RenderEntry renders an entry's post content, and sets the entry's
+// content warning and media alt text from the renderer's rules.
+func RenderEntry(renderer *template.Renderer, entry *database.Entry) (string, error) {
+	content, err := renderer.RenderArticle(entry.EntryData, entry.FullContent.String, entry.LeadImage.String)
+	if err != nil {
+		return "", err
+	}
+	if entry.ContentWarning, err = renderer.ContentWarning(entry.EntryData); err != nil {
+		return "", err
+	}
+	if entry.AltText, err = renderer.AltText(entry.EntryData); err != nil {
+		return "", err
+	}
+	return content, nil
+}
+
+// PostEach renders and publishes entries one at a time with publish, for
+// destinations without batching or rate limits of their own. The published
+// content is recorded on each entry, unless in dry run mode.
+func PostEach(entries []*database.Entry, renderer *template.Renderer, dryRun bool, publish PublishFunc) ([]PostResult, error) {
+	results := make([]PostResult, len(entries))
+	for i, entry := range entries {
+		results[i] = PostResult{Entry: entry, Outcome: OutcomeSkipped}
+	}
+
+	for i, entry := range entries {
+		result := &results[i]
+
+		content, err := RenderEntry(renderer, entry)
+		if err != nil {
+			logrus.Errorf("Failed to render entry %s: %v", entry.ID, err)
+			result.Outcome, result.Err = OutcomeFailed, err
+			continue
+		}
+
+		if dryRun {
+			logrus.Infof("DRY RUN: Would publish entry %s", entry.ID)
+			result.Outcome = OutcomePosted
+			continue
+		}
+
+		if err := publish(entry, content); err != nil {
+			logrus.Errorf("Failed to publish entry %s: %v", entry.ID, err)
+			result.Outcome, result.Err = OutcomeFailed, err
+			continue
+		}
+		entry.PostedContent = sql.NullString{String: content, Valid: true}
+		result.Outcome = OutcomePosted
+	}
+
+	return results, nil
+}
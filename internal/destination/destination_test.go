@@ -0,0 +1,251 @@
+package destination
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+)
+
+func newTestRenderer(t *testing.T, tmpl string) *template.Renderer {
+	t.Helper()
+
+	tmplPath := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(tmplPath, []byte(tmpl), 0o644); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	renderer, err := template.New(tmplPath, 500)
+	if err != nil {
+		t.Fatalf("template.New() error = %v", err)
+	}
+	return renderer
+}
+
+func testEntries() []*database.Entry {
+	return []*database.Entry{
+		{ID: "https://example.com/posts/1", EntryData: []byte(`{"title":"First"}`)},
+		{ID: "broken", EntryData: []byte(`not json`)},
+		{ID: "https://example.com/posts/3", EntryData: []byte(`{"title":"Third"}`)},
+	}
+}
+
+func checkOutcomes(t *testing.T, results []PostResult, want ...Outcome) {
+	t.Helper()
+
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Outcome != want[i] {
+			t.Errorf("results[%d].Outcome = %s, want %s (err %v)", i, result.Outcome, want[i], result.Err)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	entries := testEntries()
+	renderer := newTestRenderer(t, "{{.Item.Title}}")
+	renderer.SetContentWarning("spoilers")
+
+	results, err := NewWriter(&buf).PostEntries(entries, renderer, false)
+	if err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	checkOutcomes(t, results, OutcomePosted, OutcomeFailed, OutcomePosted)
+
+	want := "--- https://example.com/posts/1\nCW: spoilers\nFirst\n\n--- https://example.com/posts/3\nCW: spoilers\nThird\n\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+	if entries[0].PostedContent.String != "First" {
+		t.Errorf("PostedContent = %q, want First", entries[0].PostedContent.String)
+	}
+}
+
+func TestWriter_DryRun(t *testing.T) {
+	var buf bytes.Buffer
+	entries := testEntries()
+
+	results, err := NewWriter(&buf).PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), true)
+	if err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	checkOutcomes(t, results, OutcomePosted, OutcomeFailed, OutcomePosted)
+	if buf.Len() != 0 {
+		t.Errorf("dry run wrote %q", buf.String())
+	}
+	if entries[0].PostedContent.Valid {
+		t.Error("dry run recorded posted content")
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.txt")
+	dest, err := NewFile(path)
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+	}
+	renderer := newTestRenderer(t, "{{.Item.Title}}")
+
+	for i := 0; i < 2; i++ {
+		if _, err := dest.PostEntries(testEntries()[:1], renderer, false); err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if got := strings.Count(string(data), "First\n"); got != 2 {
+		t.Errorf("file has %d posts, want 2 appended:\n%s", got, data)
+	}
+
+	if _, err := NewFile(""); err == nil {
+		t.Error("NewFile(\"\") error = nil, want error")
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "posts")
+	dest, err := NewDirectory(dir)
+	if err != nil {
+		t.Fatalf("NewDirectory() error = %v", err)
+	}
+
+	results, err := dest.PostEntries(testEntries(), newTestRenderer(t, "{{.Item.Title}}"), false)
+	if err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	checkOutcomes(t, results, OutcomePosted, OutcomeFailed, OutcomePosted)
+
+	path := dest.Path("https://example.com/posts/3")
+	if want := filepath.Join(dir, "https___example.com_posts_3.txt"); path != want {
+		t.Errorf("Path() = %q, want %q", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read post: %v", err)
+	}
+	if string(data) != "Third\n" {
+		t.Errorf("post = %q, want %q", data, "Third\n")
+	}
+
+	if got := dest.Path("../.."); got != filepath.Join(dir, "entry.txt") {
+		t.Errorf("Path(\"../..\") = %q, want it inside the directory", got)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var payloads []webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		payloads = append(payloads, payload)
+
+		if payload.Content == "Third" {
+			http.Error(w, "nope", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	dest, err := NewWebhook(server.URL, map[string]string{"authorization": "Bearer secret"})
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+
+	results, err := dest.PostEntries(testEntries(), newTestRenderer(t, "{{.Item.Title}}"), false)
+	if err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	checkOutcomes(t, results, OutcomePosted, OutcomeFailed, OutcomeFailed)
+
+	if len(payloads) != 2 {
+		t.Fatalf("webhook got %d requests, want 2", len(payloads))
+	}
+	if payloads[0].ID != "https://example.com/posts/1" || payloads[0].Content != "First" {
+		t.Errorf("payload = %+v", payloads[0])
+	}
+	if string(payloads[0].Item) != `{"title":"First"}` {
+		t.Errorf("payload item = %s", payloads[0].Item)
+	}
+	if err := results[2].Err; err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("results[2].Err = %v, want the 400 response", err)
+	}
+}
+
+func TestChatWebhook(t *testing.T) {
+	var messages []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("Failed to decode message: %v", err)
+		}
+		messages = append(messages, message)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	renderer := newTestRenderer(t, "{{.Item.Title}}")
+	renderer.SetContentWarning("spoilers")
+
+	t.Run("discord", func(t *testing.T) {
+		messages = nil
+		dest, err := NewChatWebhook(ChatDiscord, server.URL)
+		if err != nil {
+			t.Fatalf("NewChatWebhook() error = %v", err)
+		}
+
+		results, err := dest.PostEntries(testEntries()[:1], renderer, false)
+		if err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+		checkOutcomes(t, results, OutcomePosted)
+
+		if len(messages) != 1 || messages[0]["content"] != "CW: spoilers\n||First||" {
+			t.Fatalf("messages = %v", messages)
+		}
+		if mentions, ok := messages[0]["allowed_mentions"].(map[string]any); !ok || len(mentions["parse"].([]any)) != 0 {
+			t.Errorf("allowed_mentions = %v, want no mentions parsed", messages[0]["allowed_mentions"])
+		}
+	})
+
+	t.Run("slack", func(t *testing.T) {
+		messages = nil
+		dest, err := NewChatWebhook(ChatSlack, server.URL)
+		if err != nil {
+			t.Fatalf("NewChatWebhook() error = %v", err)
+		}
+
+		if _, err := dest.PostEntries(testEntries()[:1], renderer, false); err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+		if len(messages) != 1 || messages[0]["text"] != "CW: spoilers\nFirst" {
+			t.Errorf("messages = %v", messages)
+		}
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		if _, err := NewChatWebhook("irc", server.URL); err == nil {
+			t.Error("NewChatWebhook(irc) error = nil, want error")
+		}
+	})
+}
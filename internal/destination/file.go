@@ -0,0 +1,89 @@
+package destination
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+)
+
+// File is a Destination that appends posts to a single file, in the same
+// format as Writer.
+type File struct {
+	path string
+}
+
+// NewFile creates a File destination that appends posts to path.
+func NewFile(path string) (*File, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file destination requires a path")
+	}
+	return &File{path: path}, nil
+}
+
+// PostEntries appends the rendered posts of entries to the file.
+func (d *File) PostEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]PostResult, error) {
+	return PostEach(entries, renderer, dryRun, func(entry *database.Entry, content string) error {
+		f, err := os.OpenFile(d.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", d.path, err)
+		}
+		if err := writePost(f, entry, content); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write to %s: %w", d.path, err)
+		}
+		return f.Close()
+	})
+}
+
+// Directory is a Destination that writes each post to its own file in a
+// directory, named after the entry's ID. Posting an entry again replaces
+// its file.
+type Directory struct {
+	dir string
+}
+
+// NewDirectory creates a Directory destination that writes posts to dir,
+// creating it if needed.
+func NewDirectory(dir string) (*Directory, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("directory destination requires a path")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	return &Directory{dir: dir}, nil
+}
+
+// PostEntries writes the rendered post of each entry to its file.
+func (d *Directory) PostEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]PostResult, error) {
+	return PostEach(entries, renderer, dryRun, func(entry *database.Entry, content string) error {
+		path := d.Path(entry.ID)
+		if err := os.WriteFile(path, []byte(content+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// Path returns the file the post for the entry with the given ID is
+// written to. Entry IDs are often URLs, so characters that aren't safe
+// in file names are replaced.
+func (d *Directory) Path(id string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, id)
+	name = strings.Trim(name, "._")
+	if name == "" {
+		name = "entry"
+	}
+	return filepath.Join(d.dir, name+".txt")
+}
@@ -0,0 +1,42 @@
+package destination
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+)
+
+// Writer is a Destination that writes posts to an io.Writer, such as
+// stdout, each under a header line with the entry's ID.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter creates a Writer destination that writes posts to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// PostEntries writes the rendered posts of entries to the writer.
+func (d *Writer) PostEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]PostResult, error) {
+	return PostEach(entries, renderer, dryRun, func(entry *database.Entry, content string) error {
+		return writePost(d.w, entry, content)
+	})
+}
+
+// writePost writes a post with a header line naming the entry, and its
+// content warning if it has one, followed by a blank line.
+func writePost(w io.Writer, entry *database.Entry, content string) error {
+	if _, err := fmt.Fprintf(w, "--- %s\n", entry.ID); err != nil {
+		return err
+	}
+	if entry.ContentWarning != "" {
+		if _, err := fmt.Fprintf(w, "CW: %s\n", entry.ContentWarning); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s\n\n", content)
+	return err
+}
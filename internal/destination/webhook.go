@@ -0,0 +1,89 @@
+package destination
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+)
+
+// webhookTimeout bounds each request to a webhook.
+const webhookTimeout = 30 * time.Second
+
+// Webhook is a Destination that POSTs each post as JSON to a URL.
+type Webhook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// webhookPayload is the JSON body sent to a webhook for each post.
+type webhookPayload struct {
+	ID             string          `json:"id"`
+	Content        string          `json:"content"`
+	ContentWarning string          `json:"content_warning,omitempty"`
+	Item           json.RawMessage `json:"item"`
+}
+
+// NewWebhook creates a Webhook destination that posts to url, sending the
+// given extra headers with each request.
+func NewWebhook(url string, headers map[string]string) (*Webhook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook destination requires a url")
+	}
+	return &Webhook{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: webhookTimeout},
+	}, nil
+}
+
+// PostEntries sends the rendered posts of entries to the webhook. Any
+// response other than 2xx fails the entry.
+func (d *Webhook) PostEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]PostResult, error) {
+	return PostEach(entries, renderer, dryRun, d.send)
+}
+
+// send POSTs a single post to the webhook.
+func (d *Webhook) send(entry *database.Entry, content string) error {
+	body, err := json.Marshal(webhookPayload{
+		ID:             entry.ID,
+		Content:        content,
+		ContentWarning: entry.ContentWarning,
+		Item:           json.RawMessage(entry.EntryData),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	return postJSON(d.client, d.url, d.headers, body)
+}
+
+// postJSON POSTs a JSON body to url with the given extra headers. Any
+// response other than 2xx is an error.
+func postJSON(client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
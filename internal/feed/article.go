@@ -0,0 +1,276 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/mmcdole/gofeed"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxArticleBytes bounds how much of an article page is read.
+const maxArticleBytes = 5 << 20
+
+// minParagraphLength is the shortest text counted as a paragraph of the
+// article, rather than a caption, byline, or button.
+const minParagraphLength = 25
+
+// Article is the readable content of the page an entry links to.
+type Article struct {
+	// Content is the article's text, with paragraphs separated by blank
+	// lines.
+	Content string
+	// LeadImage is the URL of the page's og:image or twitter:image.
+	LeadImage string
+}
+
+// skippedElements hold page furniture rather than article text.
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Nav: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Form: true,
+	atom.Iframe: true, atom.Svg: true, atom.Button: true, atom.Template: true,
+}
+
+// textElements are the blocks of an article's text.
+var textElements = map[atom.Atom]bool{
+	atom.P: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+	atom.H6: true, atom.Li: true, atom.Blockquote: true, atom.Pre: true,
+}
+
+// ExtractArticles fetches the linked articles of entries waiting to be
+// posted and stores their readable content and lead images, for feeds that
+// only include a summary. Entries whose articles can't be fetched are
+// stored without one, so they aren't tried again. Returns the number of
+// articles extracted. Extraction stops when ctx is done.
+func (f *Fetcher) ExtractArticles(ctx context.Context, db *database.DB) (int, error) {
+	entries, err := db.GetUnextractedEntries()
+	if err != nil {
+		return 0, err
+	}
+
+	extracted := 0
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return extracted, err
+		}
+
+		var item gofeed.Item
+		if err := json.Unmarshal(entry.EntryData, &item); err != nil {
+			logrus.Warnf("Failed to parse entry %s: %v", entry.ID, err)
+			continue
+		}
+
+		var article Article
+		if item.Link != "" {
+			found, err := f.FetchArticle(ctx, item.Link)
+			if err != nil {
+				logrus.Warnf("Failed to extract article for entry %s: %v", entry.ID, err)
+			} else {
+				article = *found
+				extracted++
+			}
+		}
+
+		if err := db.SetArticle(entry.ID, article.Content, article.LeadImage); err != nil {
+			return extracted, err
+		}
+	}
+	return extracted, nil
+}
+
+// FetchArticle fetches the page at link and extracts its article.
+func (f *Fetcher) FetchArticle(ctx context.Context, link string) (*Article, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch article: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch article: %w", &StatusError{StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("article is %s, not HTML", contentType)
+	}
+
+	return ExtractArticle(io.LimitReader(resp.Body, maxArticleBytes), resp.Request.URL)
+}
+
+// ExtractArticle finds the article text and lead image of an HTML page,
+// readability-style: the article is the <article> element, or else the
+// element holding the most paragraph text. base resolves relative image
+// URLs.
+func ExtractArticle(page io.Reader, base *url.URL) (*Article, error) {
+	doc, err := html.Parse(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse article: %w", err)
+	}
+
+	article := &Article{LeadImage: leadImage(doc, base)}
+	if body := articleElement(doc); body != nil {
+		article.Content = articleText(body)
+	}
+	return article, nil
+}
+
+// leadImage returns the page's og:image, or its twitter:image, resolved
+// against base.
+func leadImage(doc *html.Node, base *url.URL) string {
+	images := make(map[string]string)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Meta {
+			var name, content string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "property", "name":
+					name = strings.ToLower(attr.Val)
+				case "content":
+					content = strings.TrimSpace(attr.Val)
+				}
+			}
+			if _, ok := images[name]; !ok && content != "" {
+				images[name] = content
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	for _, name := range []string{"og:image", "og:image:url", "twitter:image", "twitter:image:src"} {
+		image, ok := images[name]
+		if !ok {
+			continue
+		}
+		ref, err := url.Parse(image)
+		if err != nil {
+			continue
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		return ref.String()
+	}
+	return ""
+}
+
+// articleElement returns the element most likely to hold the article.
+func articleElement(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]int)
+	var best *html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if skippedElements[n.DataAtom] {
+				return
+			}
+			if n.DataAtom == atom.P && n.Parent != nil {
+				if length := len(nodeText(n)); length >= minParagraphLength {
+					// Longer paragraphs count for more, up to a point
+					score := 1 + min(length/100, 3)
+					for node, weight := n.Parent, 2; node != nil && weight > 0; node, weight = node.Parent, weight-1 {
+						scores[node] += score * weight
+						if best == nil || scores[node] > scores[best] {
+							best = node
+						}
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	// An <article> with any of the text is the article
+	if article := findElement(doc, atom.Article); article != nil && best != nil && contains(article, best) {
+		return article
+	}
+	return best
+}
+
+// articleText returns the text of the blocks in n, separated by blank
+// lines.
+func articleText(n *html.Node) string {
+	var blocks []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if skippedElements[n.DataAtom] {
+				return
+			}
+			if textElements[n.DataAtom] {
+				if text := nodeText(n); text != "" {
+					blocks = append(blocks, text)
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(blocks, "\n\n")
+}
+
+// nodeText returns the text in n with whitespace collapsed.
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && skippedElements[n.DataAtom] {
+			return
+		}
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// findElement returns the first element of type a in n, depth first.
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// contains reports whether descendant is n or inside it.
+func contains(n, descendant *html.Node) bool {
+	for node := descendant; node != nil; node = node.Parent {
+		if node == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html><head>
+<title>A Story</title>
+<meta property="og:image" content="/images/lead.jpg">
+<meta name="twitter:image" content="https://example.com/twitter.jpg">
+</head><body>
+<header><nav><a href="/">Home</a> <a href="/about">About this site and its many sections</a></nav></header>
+<div class="layout">
+  <aside><p>Subscribe to our newsletter for more stories like this one.</p></aside>
+  <div class="story">
+    <h2>The beginning</h2>
+    <p>It was a dark and stormy night, and the rain fell in torrents.</p>
+    <p>Except at occasional intervals, when it was checked by a violent gust of wind.</p>
+    <script>track("page view, from a paragraph-length script");</script>
+  </div>
+  <p class="credit">Photo: Staff</p>
+</div>
+<footer><p>Copyright the publisher, all rights reserved, forever and ever.</p></footer>
+</body></html>`
+
+func TestExtractArticle(t *testing.T) {
+	base, _ := url.Parse("https://example.com/stories/1")
+
+	t.Run("finds the paragraphs and og:image", func(t *testing.T) {
+		article, err := ExtractArticle(strings.NewReader(articlePage), base)
+		if err != nil {
+			t.Fatalf("ExtractArticle() error = %v", err)
+		}
+
+		expected := "The beginning\n\n" +
+			"It was a dark and stormy night, and the rain fell in torrents.\n\n" +
+			"Except at occasional intervals, when it was checked by a violent gust of wind."
+		if article.Content != expected {
+			t.Errorf("Content = %q, want %q", article.Content, expected)
+		}
+		if article.LeadImage != "https://example.com/images/lead.jpg" {
+			t.Errorf("LeadImage = %q, want the resolved og:image", article.LeadImage)
+		}
+	})
+
+	t.Run("prefers the article element", func(t *testing.T) {
+		page := `<html><body><article><h1>Title</h1><div>
+<p>The first paragraph of the article is long enough to count.</p></div>
+<div><p>The second paragraph sits in a different container.</p></div>
+</article></body></html>`
+
+		article, err := ExtractArticle(strings.NewReader(page), base)
+		if err != nil {
+			t.Fatalf("ExtractArticle() error = %v", err)
+		}
+		if !strings.Contains(article.Content, "first paragraph") || !strings.Contains(article.Content, "second paragraph") {
+			t.Errorf("Content = %q, want both paragraphs", article.Content)
+		}
+		if article.LeadImage != "" {
+			t.Errorf("LeadImage = %q, want none", article.LeadImage)
+		}
+	})
+
+	t.Run("falls back to twitter:image", func(t *testing.T) {
+		page := `<html><head><meta name="twitter:image" content="https://cdn.example/t.png"></head><body></body></html>`
+
+		article, err := ExtractArticle(strings.NewReader(page), base)
+		if err != nil {
+			t.Fatalf("ExtractArticle() error = %v", err)
+		}
+		if article.LeadImage != "https://cdn.example/t.png" || article.Content != "" {
+			t.Errorf("article = %+v, want only the twitter:image", article)
+		}
+	})
+}
+
+func TestExtractArticles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/story" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(articlePage))
+	}))
+	defer server.Close()
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	defer db.Close()
+
+	entries := map[string]string{
+		"story":   `{"title": "Story", "link": "` + server.URL + `/story"}`,
+		"missing": `{"title": "Missing", "link": "` + server.URL + `/missing"}`,
+		"no-link": `{"title": "No link"}`,
+	}
+	for id, data := range entries {
+		if _, err := db.SaveEntry(id, []byte(data)); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+
+	extracted, err := New().ExtractArticles(context.Background(), db)
+	if err != nil {
+		t.Fatalf("ExtractArticles() error = %v", err)
+	}
+	if extracted != 1 {
+		t.Errorf("ExtractArticles() = %d, want 1", extracted)
+	}
+
+	story, err := db.GetEntry("story")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if !strings.HasPrefix(story.FullContent.String, "The beginning") {
+		t.Errorf("FullContent = %q, want the article text", story.FullContent.String)
+	}
+	if story.LeadImage.String != server.URL+"/images/lead.jpg" {
+		t.Errorf("LeadImage = %q, want the resolved og:image", story.LeadImage.String)
+	}
+
+	// Failed and linkless entries aren't tried again
+	remaining, err := db.GetUnextractedEntries()
+	if err != nil || len(remaining) != 0 {
+		t.Errorf("GetUnextractedEntries() = %d, %v; want 0, nil", len(remaining), err)
+	}
+}
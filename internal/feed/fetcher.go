@@ -1,41 +1,275 @@
 package feed
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
 
 	"github.com/lorchard/feed-to-mastodon/internal/database"
 	"github.com/mmcdole/gofeed"
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultUserAgent identifies feed requests when no user agent is set.
+const DefaultUserAgent = "feed-to-mastodon"
+
+// metadataSetting holds the feed's own details from the latest fetch.
+const metadataSetting = "feed_metadata"
+
 // Fetcher handles fetching and parsing RSS/Atom feeds.
 type Fetcher struct {
-	parser *gofeed.Parser
+	parser    *gofeed.Parser
+	client    *http.Client
+	userAgent string
+	headers   map[string]string
+	username  string
+	password  string
+	retries   int
+	backoff   time.Duration
+	sleep     func(context.Context, time.Duration) error
+	hints     PollHints
+	fetched   bool
+	refused   bool
+	maxAge    time.Duration
+	purge     PurgeOptions
+	now       func() time.Time
+	// jsonAPI, if set, reads feeds from JSON API responses
+	jsonAPI *JSONMapping
 }
 
 // New creates a new Fetcher instance.
 func New() *Fetcher {
 	return &Fetcher{
-		parser: gofeed.NewParser(),
+		parser:    gofeed.NewParser(),
+		client:    &http.Client{},
+		userAgent: DefaultUserAgent,
+		sleep:     sleepContext,
+		now:       time.Now,
+	}
+}
+
+// HTTPOptions configures the requests made to fetch feeds.
+type HTTPOptions struct {
+	// Timeout bounds each request, including reading the feed.
+	// Zero means no timeout.
+	Timeout time.Duration
+	// Proxy is the URL of a proxy to fetch through. If empty, the
+	// HTTP_PROXY and HTTPS_PROXY environment variables are used.
+	Proxy string
+	// UserAgent replaces DefaultUserAgent if set.
+	UserAgent string
+	// Headers are added to each request, e.g. for an API key.
+	Headers map[string]string
+	// Username and Password, if Username is set, are sent with HTTP basic
+	// authentication, for private feeds.
+	Username string
+	Password string
+}
+
+// SetHTTPOptions configures the HTTP client used to fetch feeds.
+func (f *Fetcher) SetHTTPOptions(opts HTTPOptions) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
 	}
+
+	f.client = &http.Client{Timeout: opts.Timeout, Transport: transport}
+	f.userAgent = DefaultUserAgent
+	if opts.UserAgent != "" {
+		f.userAgent = opts.UserAgent
+	}
+	f.headers = opts.Headers
+	f.username = opts.Username
+	f.password = opts.Password
+	return nil
 }
 
-// Fetch retrieves and parses a feed from the given URL.
+// StatusError is returned by Fetch when the server responds to the feed
+// request with an error status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// RetryAfter is how long the server asked to wait before trying
+	// again, if it did.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return "server returned " + e.Status
+}
+
+// SetMaxEntryAge makes SaveEntriesToDB skip items published longer ago
+// than maxAge. Items without a published or updated date are kept.
+// Zero disables the check.
+func (f *Fetcher) SetMaxEntryAge(maxAge time.Duration) {
+	f.maxAge = maxAge
+}
+
+// tooOld reports whether an item is older than the maximum entry age.
+func (f *Fetcher) tooOld(item *gofeed.Item) bool {
+	if f.maxAge <= 0 {
+		return false
+	}
+
+	published := item.PublishedParsed
+	if published == nil {
+		published = item.UpdatedParsed
+	}
+	if published == nil {
+		return false
+	}
+
+	return published.Before(f.now().Add(-f.maxAge))
+}
+
+// Fetch retrieves and parses a feed from the given URL. Transient
+// failures are retried as configured with SetRetries.
 func (f *Fetcher) Fetch(feedURL string) (*gofeed.Feed, error) {
+	return f.FetchContext(context.Background(), feedURL)
+}
+
+// FetchContext is like Fetch, but gives up when ctx is done, even while
+// waiting to retry.
+func (f *Fetcher) FetchContext(ctx context.Context, feedURL string) (*gofeed.Feed, error) {
 	logrus.Infof("Fetching feed: %s", feedURL)
 
-	feed, err := f.parser.ParseURL(feedURL)
+	var body []byte
+	var header http.Header
+	var err error
+	for attempt := 0; ; attempt++ {
+		body, header, err = f.get(ctx, feedURL)
+		if err == nil || attempt >= f.retries || !isTransient(err) || ctx.Err() != nil {
+			break
+		}
+		delay := f.retryDelay(attempt)
+		logrus.Warnf("Fetch failed, retrying in %s: %v", delay.Round(time.Millisecond), err)
+		if err = f.sleep(ctx, delay); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		f.recordError(err)
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+
+	feed, err := f.parse(body)
 	if err != nil {
+		f.recordError(err)
 		return nil, fmt.Errorf("failed to parse feed: %w", err)
 	}
+	f.recordResponse(header, body, feed.FeedType)
 
 	logrus.Infof("Successfully fetched feed: %s (%d items)", feed.Title, len(feed.Items))
 	return feed, nil
 }
 
+// SetJSONMapping makes Fetch read feeds from JSON API responses, mapping
+// their items to feed items, instead of parsing RSS, Atom, or JSON Feed.
+func (f *Fetcher) SetJSONMapping(mapping JSONMapping) error {
+	if err := mapping.Validate(); err != nil {
+		return fmt.Errorf("invalid JSON mapping: %w", err)
+	}
+	f.jsonAPI = &mapping
+	return nil
+}
+
+// parse parses a fetched feed.
+func (f *Fetcher) parse(body []byte) (*gofeed.Feed, error) {
+	if f.jsonAPI != nil {
+		return parseJSONAPI(body, *f.jsonAPI)
+	}
+	return f.parser.Parse(bytes.NewReader(body))
+}
+
+// get requests the feed and returns its body and response headers.
+func (f *Fetcher) get(ctx context.Context, feedURL string) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	for name, value := range f.headers {
+		req.Header.Set(name, value)
+	}
+	if f.username != "" {
+		req.SetBasicAuth(f.username, f.password)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: retryAfter(resp.Header, f.now()),
+		}
+	}
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.Header, err
+}
+
+// SetRetries makes Fetch retry transient failures up to retries times,
+// waiting about backoff before the first retry and twice as long before
+// each one after that.
+func (f *Fetcher) SetRetries(retries int, backoff time.Duration) {
+	f.retries = retries
+	f.backoff = backoff
+}
+
+// retryDelay returns how long to wait before retrying after the given
+// attempt, with jitter so many clients don't retry in lockstep.
+func (f *Fetcher) retryDelay(attempt int) time.Duration {
+	delay := f.backoff << attempt
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// isTransient reports whether a fetch error is worth retrying: a network
+// error, like a timeout or reset connection, or a server error.
+func isTransient(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		// Servers asking to come back later are left alone until then
+		return statusErr.StatusCode >= http.StatusInternalServerError && statusErr.RetryAfter == 0
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// sleepContext waits for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // GenerateEntryID generates a unique ID for a feed entry.
 // Uses the item's GUID if available, otherwise creates a SHA256 hash
 // of the title, link, and published date.
@@ -61,19 +295,54 @@ func GenerateEntryID(item *gofeed.Item) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// SaveEntriesToDB saves all feed items to the database.
-// Returns the count of entries saved (may be less than total if some are duplicates).
+// ContentHash returns a SHA256 hash of the parts of an item that end up
+// in a post, used to notice when an entry is edited after it was fetched.
+func ContentHash(item *gofeed.Item) string {
+	hash := sha256.New()
+	for _, part := range []string{item.Title, item.Description, item.Content, item.Link} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	for _, category := range item.Categories {
+		hash.Write([]byte(category))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// SaveEntriesToDB saves all feed items to the database, updating the stored
+// data of entries whose content changed since they were last fetched.
+// Returns the count of new entries, not counting ones already stored.
 func (f *Fetcher) SaveEntriesToDB(feed *gofeed.Feed, db *database.DB) (int, error) {
+	return f.SaveEntriesToDBContext(context.Background(), feed, db)
+}
+
+// SaveEntriesToDBContext is like SaveEntriesToDB, but stops saving when ctx
+// is done, returning the count of new entries saved until then.
+func (f *Fetcher) SaveEntriesToDBContext(ctx context.Context, feed *gofeed.Feed, db *database.DB) (int, error) {
 	if feed == nil || len(feed.Items) == 0 {
 		logrus.Debug("No items to save")
 		return 0, nil
 	}
 
-	savedCount := 0
+	newCount := 0
+	tooOld := 0
+	changed := 0
 	for _, item := range feed.Items {
+		if err := ctx.Err(); err != nil {
+			return newCount, err
+		}
+
 		// Generate ID
 		id := GenerateEntryID(item)
 
+		// Don't queue old items, e.g. from a feed with years of archives
+		if f.tooOld(item) {
+			logrus.Debugf("Skipping entry %s older than %s", id, f.maxAge)
+			tooOld++
+			continue
+		}
+
 		// Marshal item to JSON
 		itemJSON, err := json.Marshal(item)
 		if err != nil {
@@ -82,27 +351,34 @@ func (f *Fetcher) SaveEntriesToDB(feed *gofeed.Feed, db *database.DB) (int, erro
 		}
 
 		// Save to database
-		err = db.SaveEntry(id, itemJSON)
+		result, err := db.SaveEntryContentContext(ctx, id, itemJSON, ContentHash(item))
 		if err != nil {
 			logrus.Warnf("Failed to save entry %s: %v", id, err)
 			continue
 		}
-
-		savedCount++
+		switch result {
+		case database.SaveInserted:
+			newCount++
+		case database.SaveChanged:
+			logrus.Debugf("Entry %s changed since it was posted", id)
+			changed++
+		}
 	}
 
-	logrus.Infof("Saved %d/%d entries to database", savedCount, len(feed.Items))
-	return savedCount, nil
-}
-
-// StoreFeedMetadata stores feed metadata in the database for use in templates.
-func (f *Fetcher) StoreFeedMetadata(feed *gofeed.Feed, db *database.DB) error {
-	feedJSON, err := json.Marshal(feed)
-	if err != nil {
-		return fmt.Errorf("failed to marshal feed data: %w", err)
+	if tooOld > 0 {
+		logrus.Infof("Skipped %d entries older than %s", tooOld, f.maxAge)
+	}
+	if changed > 0 {
+		logrus.Infof("%d posted entries changed since they were posted", changed)
 	}
+	logrus.Infof("Saved %d new entries, %d were already stored", newCount, len(feed.Items)-tooOld-newCount)
+	return newCount, nil
+}
 
-	if err := db.SetSetting("feed_metadata", string(feedJSON)); err != nil {
+// StoreFeedMetadata stores feed metadata in the feed's settings for use in
+// templates.
+func (f *Fetcher) StoreFeedMetadata(feed *gofeed.Feed, settings *database.Settings) error {
+	if err := settings.SetJSON(metadataSetting, feed); err != nil {
 		return fmt.Errorf("failed to store feed metadata: %w", err)
 	}
 
@@ -110,7 +386,51 @@ func (f *Fetcher) StoreFeedMetadata(feed *gofeed.Feed, db *database.DB) error {
 	return nil
 }
 
-// PurgeStaleEntries removes entries from the database that are no longer in the feed.
+// LoadFeedMetadata returns the feed metadata stored by the latest fetch, or
+// nil if there is none.
+func LoadFeedMetadata(settings *database.Settings) (*gofeed.Feed, error) {
+	var feed gofeed.Feed
+	found, err := settings.GetJSON(metadataSetting, &feed)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// PurgeOptions selects which entries that are no longer in the feed
+// PurgeStaleEntries removes. The zero value removes all of them.
+type PurgeOptions struct {
+	// MinAge keeps entries fetched more recently than this.
+	MinAge time.Duration
+	// Keep keeps this many of the most recently fetched entries.
+	Keep int
+	// KeepPosted keeps entries that were posted, so an item that comes
+	// back into a rolling feed is recognized instead of posted again.
+	KeepPosted bool
+}
+
+// SetPurgeOptions sets which stale entries PurgeStaleEntries removes.
+func (f *Fetcher) SetPurgeOptions(opts PurgeOptions) {
+	f.purge = opts
+}
+
+// purgeable reports whether a stale entry, the i-th most recently fetched
+// in the database, may be purged under the purge options.
+func (f *Fetcher) purgeable(i int, entry *database.Entry) bool {
+	if f.purge.Keep > 0 && i < f.purge.Keep {
+		return false
+	}
+	if f.purge.MinAge > 0 && entry.FetchedAt.Valid && entry.FetchedAt.Time.After(f.now().Add(-f.purge.MinAge)) {
+		return false
+	}
+	if f.purge.KeepPosted && entry.PostedAt != nil && entry.PostedAt.Valid {
+		return false
+	}
+	return true
+}
+
+// PurgeStaleEntries removes entries from the database that are no longer in the feed,
+// except those kept by the options set with SetPurgeOptions.
 // Returns the number of entries purged.
 func (f *Fetcher) PurgeStaleEntries(feed *gofeed.Feed, db *database.DB) (int, error) {
 	if feed == nil {
@@ -124,17 +444,15 @@ func (f *Fetcher) PurgeStaleEntries(feed *gofeed.Feed, db *database.DB) (int, er
 		feedIDs[id] = true
 	}
 
-	// Get all IDs from database
-	dbIDs, err := db.GetAllEntryIDs()
+	// Find entries that are in DB but not in feed, newest first
+	entries, err := db.ListEntries(database.ListOptions{})
 	if err != nil {
-		return 0, fmt.Errorf("failed to get database entry IDs: %w", err)
+		return 0, fmt.Errorf("failed to get database entries: %w", err)
 	}
-
-	// Find IDs that are in DB but not in feed
 	toPurge := make([]string, 0)
-	for _, dbID := range dbIDs {
-		if !feedIDs[dbID] {
-			toPurge = append(toPurge, dbID)
+	for i, entry := range entries {
+		if !feedIDs[entry.ID] && f.purgeable(i, entry) {
+			toPurge = append(toPurge, entry.ID)
 		}
 	}
 
@@ -1,7 +1,9 @@
 package feed
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -225,8 +227,9 @@ func TestFetch(t *testing.T) {
 
 		fetcher := New()
 		_, err := fetcher.Fetch(server.URL)
-		if err == nil {
-			t.Error("Expected error for 404 response, got nil")
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+			t.Errorf("Fetch() error = %v, want a 404 StatusError", err)
 		}
 	})
 
@@ -259,6 +262,222 @@ func TestFetch(t *testing.T) {
 	})
 }
 
+func TestFetch_HTTPOptions(t *testing.T) {
+	rssContent := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>Test Feed</title></channel></rss>`
+
+	t.Run("sends user agent and headers", func(t *testing.T) {
+		var userAgent, apiKey string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userAgent = r.Header.Get("User-Agent")
+			apiKey = r.Header.Get("X-Api-Key")
+			_, _ = w.Write([]byte(rssContent))
+		}))
+		defer server.Close()
+
+		fetcher := New()
+		if _, err := fetcher.Fetch(server.URL); err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if userAgent != DefaultUserAgent {
+			t.Errorf("User-Agent = %q, want %q", userAgent, DefaultUserAgent)
+		}
+
+		// viper lowercases configured header names
+		err := fetcher.SetHTTPOptions(HTTPOptions{UserAgent: "my-bot/1.0", Headers: map[string]string{"x-api-key": "secret"}})
+		if err != nil {
+			t.Fatalf("SetHTTPOptions() error = %v", err)
+		}
+		if _, err := fetcher.Fetch(server.URL); err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if userAgent != "my-bot/1.0" || apiKey != "secret" {
+			t.Errorf("User-Agent = %q, X-Api-Key = %q; want my-bot/1.0 and secret", userAgent, apiKey)
+		}
+	})
+
+	t.Run("sends basic auth credentials", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if username, password, ok := r.BasicAuth(); !ok || username != "reader" || password != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(rssContent))
+		}))
+		defer server.Close()
+
+		fetcher := New()
+		var statusErr *StatusError
+		if _, err := fetcher.Fetch(server.URL); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Fetch() without credentials error = %v, want 401", err)
+		}
+
+		if err := fetcher.SetHTTPOptions(HTTPOptions{Username: "reader", Password: "hunter2"}); err != nil {
+			t.Fatalf("SetHTTPOptions() error = %v", err)
+		}
+		if _, err := fetcher.Fetch(server.URL); err != nil {
+			t.Errorf("Fetch() with credentials error = %v", err)
+		}
+	})
+
+	t.Run("times out slow servers", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte(rssContent))
+		}))
+		defer server.Close()
+
+		fetcher := New()
+		if err := fetcher.SetHTTPOptions(HTTPOptions{Timeout: 50 * time.Millisecond}); err != nil {
+			t.Fatalf("SetHTTPOptions() error = %v", err)
+		}
+		if _, err := fetcher.Fetch(server.URL); err == nil {
+			t.Error("Fetch() error = nil, want timeout")
+		}
+	})
+
+	t.Run("fetches through a proxy", func(t *testing.T) {
+		var proxied string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = r.URL.String()
+			_, _ = w.Write([]byte(rssContent))
+		}))
+		defer proxy.Close()
+
+		fetcher := New()
+		if err := fetcher.SetHTTPOptions(HTTPOptions{Proxy: proxy.URL}); err != nil {
+			t.Fatalf("SetHTTPOptions() error = %v", err)
+		}
+		if _, err := fetcher.Fetch("http://feeds.example/feed.xml"); err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if proxied != "http://feeds.example/feed.xml" {
+			t.Errorf("proxy got request for %q", proxied)
+		}
+	})
+
+	t.Run("rejects invalid proxy", func(t *testing.T) {
+		if err := New().SetHTTPOptions(HTTPOptions{Proxy: "://nope"}); err == nil {
+			t.Error("SetHTTPOptions() error = nil, want error")
+		}
+	})
+}
+
+func TestFetch_Retries(t *testing.T) {
+	rssContent := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>Test Feed</title></channel></rss>`
+
+	// newServer fails with status the first failures requests
+	newServer := func(status, failures int) (*httptest.Server, *int) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests <= failures {
+				w.WriteHeader(status)
+				return
+			}
+			_, _ = w.Write([]byte(rssContent))
+		}))
+		return server, &requests
+	}
+
+	newFetcher := func(retries int, delays *[]time.Duration) *Fetcher {
+		fetcher := New()
+		fetcher.SetRetries(retries, time.Second)
+		fetcher.sleep = func(ctx context.Context, d time.Duration) error {
+			*delays = append(*delays, d)
+			return nil
+		}
+		return fetcher
+	}
+
+	t.Run("stops retrying when cancelled", func(t *testing.T) {
+		server, requests := newServer(http.StatusBadGateway, 2)
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		fetcher := New()
+		fetcher.SetRetries(3, time.Hour)
+		fetcher.sleep = func(ctx context.Context, d time.Duration) error {
+			cancel()
+			return sleepContext(ctx, d)
+		}
+		if _, err := fetcher.FetchContext(ctx, server.URL); !errors.Is(err, context.Canceled) {
+			t.Fatalf("FetchContext() error = %v, want context.Canceled", err)
+		}
+		if *requests != 1 {
+			t.Errorf("requests = %d, want 1", *requests)
+		}
+	})
+
+	t.Run("retries server errors with backoff", func(t *testing.T) {
+		server, requests := newServer(http.StatusBadGateway, 2)
+		defer server.Close()
+
+		var delays []time.Duration
+		if _, err := newFetcher(3, &delays).Fetch(server.URL); err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if *requests != 3 || len(delays) != 2 {
+			t.Fatalf("requests = %d, delays = %v; want 3 requests after 2 delays", *requests, delays)
+		}
+		if delays[0] < 500*time.Millisecond || delays[0] > time.Second {
+			t.Errorf("first delay = %v, want between 0.5s and 1s", delays[0])
+		}
+		if delays[1] < time.Second || delays[1] > 2*time.Second {
+			t.Errorf("second delay = %v, want between 1s and 2s", delays[1])
+		}
+	})
+
+	t.Run("gives up after the configured retries", func(t *testing.T) {
+		server, requests := newServer(http.StatusServiceUnavailable, 10)
+		defer server.Close()
+
+		var delays []time.Duration
+		if _, err := newFetcher(2, &delays).Fetch(server.URL); err == nil {
+			t.Error("Fetch() error = nil, want error")
+		}
+		if *requests != 3 {
+			t.Errorf("requests = %d, want 3", *requests)
+		}
+	})
+
+	t.Run("doesn't retry client errors", func(t *testing.T) {
+		server, requests := newServer(http.StatusNotFound, 10)
+		defer server.Close()
+
+		var delays []time.Duration
+		if _, err := newFetcher(2, &delays).Fetch(server.URL); err == nil {
+			t.Error("Fetch() error = nil, want error")
+		}
+		if *requests != 1 {
+			t.Errorf("requests = %d, want 1", *requests)
+		}
+	})
+
+	t.Run("retries dropped connections", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 1 {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				_ = conn.Close()
+				return
+			}
+			_, _ = w.Write([]byte(rssContent))
+		}))
+		defer server.Close()
+
+		var delays []time.Duration
+		if _, err := newFetcher(1, &delays).Fetch(server.URL); err != nil {
+			t.Errorf("Fetch() error = %v", err)
+		}
+		if requests != 2 {
+			t.Errorf("requests = %d, want 2", requests)
+		}
+	})
+}
+
 func TestSaveEntriesToDB(t *testing.T) {
 	t.Run("saves entries correctly", func(t *testing.T) {
 		db, err := database.New(":memory:")
@@ -286,6 +505,36 @@ func TestSaveEntriesToDB(t *testing.T) {
 		}
 	})
 
+	t.Run("counts only new entries", func(t *testing.T) {
+		db, err := database.New(":memory:")
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		feed := &gofeed.Feed{
+			Items: []*gofeed.Item{
+				{GUID: "item-1", Title: "Item 1"},
+				{GUID: "item-2", Title: "Item 2"},
+			},
+		}
+
+		fetcher := New()
+		if _, err := fetcher.SaveEntriesToDB(feed, db); err != nil {
+			t.Fatalf("SaveEntriesToDB() error = %v", err)
+		}
+
+		feed.Items[1].Title = "Item 2, edited"
+		feed.Items = append(feed.Items, &gofeed.Item{GUID: "item-3", Title: "Item 3"})
+		count, err := fetcher.SaveEntriesToDB(feed, db)
+		if err != nil {
+			t.Fatalf("SaveEntriesToDB() error = %v", err)
+		}
+		if count != 1 {
+			t.Errorf("Expected 1 new entry, got %d", count)
+		}
+	})
+
 	t.Run("marshals items to JSON correctly", func(t *testing.T) {
 		db, err := database.New(":memory:")
 		if err != nil {
@@ -327,6 +576,50 @@ func TestSaveEntriesToDB(t *testing.T) {
 		}
 	})
 
+	t.Run("skips entries older than max age", func(t *testing.T) {
+		db, err := database.New(":memory:")
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+		recent := now.Add(-24 * time.Hour)
+		old := now.Add(-30 * 24 * time.Hour)
+		feed := &gofeed.Feed{
+			Items: []*gofeed.Item{
+				{GUID: "recent", Title: "Recent", PublishedParsed: &recent},
+				{GUID: "old", Title: "Old", PublishedParsed: &old},
+				{GUID: "old-updated", Title: "Old, only updated date", UpdatedParsed: &old},
+				{GUID: "undated", Title: "Undated"},
+			},
+		}
+
+		fetcher := New()
+		fetcher.now = func() time.Time { return now }
+		fetcher.SetMaxEntryAge(7 * 24 * time.Hour)
+
+		count, err := fetcher.SaveEntriesToDB(feed, db)
+		if err != nil {
+			t.Fatalf("SaveEntriesToDB() error = %v", err)
+		}
+		if count != 2 {
+			t.Errorf("Expected 2 entries saved, got %d", count)
+		}
+
+		ids, err := db.GetAllEntryIDs()
+		if err != nil {
+			t.Fatalf("GetAllEntryIDs() error = %v", err)
+		}
+		saved := map[string]bool{}
+		for _, id := range ids {
+			saved[id] = true
+		}
+		if !saved["recent"] || !saved["undated"] || saved["old"] || saved["old-updated"] {
+			t.Errorf("saved entries = %v, want recent and undated", ids)
+		}
+	})
+
 	t.Run("generates IDs for all items", func(t *testing.T) {
 		db, err := database.New(":memory:")
 		if err != nil {
@@ -391,6 +684,61 @@ func TestSaveEntriesToDB(t *testing.T) {
 			t.Errorf("Expected 0 entries saved for nil feed, got %d", count)
 		}
 	})
+
+	t.Run("flags posted entries that changed", func(t *testing.T) {
+		db, err := database.New(":memory:")
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		fetcher := New()
+		feed := &gofeed.Feed{Items: []*gofeed.Item{{GUID: "item-1", Title: "Typo"}}}
+		if _, err := fetcher.SaveEntriesToDB(feed, db); err != nil {
+			t.Fatalf("SaveEntriesToDB() error = %v", err)
+		}
+		if err := db.MarkAsPosted("item-1", "110", ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+
+		feed.Items[0].Title = "Fixed"
+		if _, err := fetcher.SaveEntriesToDB(feed, db); err != nil {
+			t.Fatalf("SaveEntriesToDB() error = %v", err)
+		}
+
+		entries, err := db.GetChangedEntries()
+		if err != nil {
+			t.Fatalf("GetChangedEntries() error = %v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 changed entry, got %d", len(entries))
+		}
+		var item gofeed.Item
+		if err := json.Unmarshal(entries[0].EntryData, &item); err != nil {
+			t.Fatalf("Failed to unmarshal entry: %v", err)
+		}
+		if item.Title != "Fixed" {
+			t.Errorf("Expected updated title 'Fixed', got %q", item.Title)
+		}
+	})
+}
+
+func TestContentHash(t *testing.T) {
+	item := &gofeed.Item{Title: "Title", Description: "Description", Link: "https://example.com"}
+	hash := ContentHash(item)
+	if hash != ContentHash(&gofeed.Item{Title: "Title", Description: "Description", Link: "https://example.com"}) {
+		t.Error("Expected same hash for same content")
+	}
+
+	item.Categories = []string{"go"}
+	if ContentHash(item) == hash {
+		t.Error("Expected different hash after categories changed")
+	}
+
+	// Field boundaries matter
+	if ContentHash(&gofeed.Item{Title: "ab"}) == ContentHash(&gofeed.Item{Title: "a", Description: "b"}) {
+		t.Error("Expected different hashes when text moves between fields")
+	}
 }
 
 func TestStoreFeedMetadata(t *testing.T) {
@@ -407,22 +755,32 @@ func TestStoreFeedMetadata(t *testing.T) {
 			Link:        "https://example.com",
 		}
 
+		settings := db.FeedSettings("https://example.com/feed.xml")
 		fetcher := New()
-		err = fetcher.StoreFeedMetadata(feed, db)
+		err = fetcher.StoreFeedMetadata(feed, settings)
 		if err != nil {
 			t.Fatalf("StoreFeedMetadata() error = %v", err)
 		}
 
 		// Verify metadata was stored
-		metadata, err := db.GetSetting("feed_metadata")
+		stored, err := LoadFeedMetadata(settings)
 		if err != nil {
-			t.Fatalf("GetSetting() error = %v", err)
+			t.Fatalf("LoadFeedMetadata() error = %v", err)
 		}
-		if metadata == nil {
+		if stored == nil {
 			t.Fatal("Expected feed metadata to be stored")
 		}
-		if *metadata == "" {
-			t.Error("Feed metadata is empty")
+		if stored.Title != "Test Feed" || stored.Link != "https://example.com" {
+			t.Errorf("LoadFeedMetadata() = %+v, want the stored feed", stored)
+		}
+
+		// Other feeds' metadata is kept apart
+		other, err := LoadFeedMetadata(db.FeedSettings("https://example.org/feed.xml"))
+		if err != nil {
+			t.Fatalf("LoadFeedMetadata() error = %v", err)
+		}
+		if other != nil {
+			t.Errorf("LoadFeedMetadata() of another feed = %+v, want nil", other)
 		}
 	})
 
@@ -436,35 +794,29 @@ func TestStoreFeedMetadata(t *testing.T) {
 		feed1 := &gofeed.Feed{Title: "Feed 1"}
 		feed2 := &gofeed.Feed{Title: "Feed 2"}
 
+		settings := db.FeedSettings("https://example.com/feed.xml")
 		fetcher := New()
 
 		// Store first feed
-		err = fetcher.StoreFeedMetadata(feed1, db)
+		err = fetcher.StoreFeedMetadata(feed1, settings)
 		if err != nil {
 			t.Fatalf("StoreFeedMetadata() error = %v", err)
 		}
 
 		// Store second feed
-		err = fetcher.StoreFeedMetadata(feed2, db)
+		err = fetcher.StoreFeedMetadata(feed2, settings)
 		if err != nil {
 			t.Fatalf("StoreFeedMetadata() error = %v", err)
 		}
 
 		// Verify latest metadata is stored
-		metadata, err := db.GetSetting("feed_metadata")
+		feed, err := LoadFeedMetadata(settings)
 		if err != nil {
-			t.Fatalf("GetSetting() error = %v", err)
+			t.Fatalf("LoadFeedMetadata() error = %v", err)
 		}
-		if metadata == nil || *metadata == "" {
+		if feed == nil {
 			t.Fatal("Expected feed metadata to be stored")
 		}
-
-		// Unmarshal and verify it contains the latest feed
-		var feed gofeed.Feed
-		err = json.Unmarshal([]byte(*metadata), &feed)
-		if err != nil {
-			t.Fatalf("Failed to unmarshal feed metadata: %v", err)
-		}
 		if feed.Title != "Feed 2" {
 			t.Errorf("Expected feed title 'Feed 2', got '%s'", feed.Title)
 		}
@@ -695,4 +1047,63 @@ func TestPurgeStaleEntries(t *testing.T) {
 			t.Errorf("Expected 1 entry purged, got %d", purged)
 		}
 	})
+	t.Run("keeps entries selected by purge options", func(t *testing.T) {
+		db, err := database.New(":memory:")
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		defer db.Close()
+
+		fetcher := New()
+		initialFeed := &gofeed.Feed{
+			Items: []*gofeed.Item{
+				{GUID: "item-1", Title: "Item 1"},
+				{GUID: "item-2", Title: "Item 2"},
+				{GUID: "item-3", Title: "Item 3"},
+			},
+		}
+		if _, err := fetcher.SaveEntriesToDB(initialFeed, db); err != nil {
+			t.Fatalf("SaveEntriesToDB() error = %v", err)
+		}
+		if err := db.MarkAsPosted("item-1", "", ""); err != nil {
+			t.Fatalf("MarkAsPosted() error = %v", err)
+		}
+		emptyFeed := &gofeed.Feed{Items: []*gofeed.Item{}}
+
+		// Entries were just fetched, so none is old enough
+		fetcher.SetPurgeOptions(PurgeOptions{MinAge: time.Hour})
+		purged, err := fetcher.PurgeStaleEntries(emptyFeed, db)
+		if err != nil {
+			t.Fatalf("PurgeStaleEntries() error = %v", err)
+		}
+		if purged != 0 {
+			t.Errorf("PurgeStaleEntries() with MinAge = %d, want 0", purged)
+		}
+
+		fetcher.SetPurgeOptions(PurgeOptions{Keep: 2, KeepPosted: true})
+		purged, err = fetcher.PurgeStaleEntries(emptyFeed, db)
+		if err != nil {
+			t.Fatalf("PurgeStaleEntries() error = %v", err)
+		}
+		if purged != 1 {
+			t.Errorf("PurgeStaleEntries() with Keep = %d, want 1", purged)
+		}
+
+		fetcher.SetPurgeOptions(PurgeOptions{KeepPosted: true})
+		purged, err = fetcher.PurgeStaleEntries(emptyFeed, db)
+		if err != nil {
+			t.Fatalf("PurgeStaleEntries() error = %v", err)
+		}
+		if purged != 1 {
+			t.Errorf("PurgeStaleEntries() with KeepPosted = %d, want 1", purged)
+		}
+
+		entry, err := db.GetEntry("item-1")
+		if err != nil {
+			t.Fatalf("GetEntry() error = %v", err)
+		}
+		if entry == nil {
+			t.Error("PurgeStaleEntries() purged the posted entry with KeepPosted")
+		}
+	})
 }
@@ -0,0 +1,251 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// jsonAPIFeedType is the feed type of feeds read from JSON APIs.
+const jsonAPIFeedType = "json-api"
+
+// JSONMapping describes how to read feed items from the response of a JSON
+// API instead of an RSS, Atom, or JSON Feed document. Each field is a path
+// into the JSON, like .data.items or .links[0].href; a path can list
+// alternatives separated by //, the first with a value being used, as in
+// .published_at // .created_at.
+type JSONMapping struct {
+	// Items is the path of the array of items in the response. Empty or
+	// "." means the response is the array.
+	Items string
+	// ID, Title, Link, Date, Description, Content, and Author are paths
+	// within each item. Items without an ID are identified like feed items
+	// without a GUID, by their title, link, and date.
+	ID          string
+	Title       string
+	Link        string
+	Date        string
+	Description string
+	Content     string
+	Author      string
+	// FeedTitle is the path of the feed's title in the response.
+	FeedTitle string
+}
+
+// jsonPath is a parsed path into a JSON document: a list of alternatives,
+// each a list of object keys (strings) and array indexes (ints).
+type jsonPath [][]any
+
+// Validate checks that every path of the mapping can be parsed, and that
+// items can be told apart.
+func (m JSONMapping) Validate() error {
+	if _, err := m.paths(); err != nil {
+		return err
+	}
+	if m.ID == "" && m.Link == "" && m.Title == "" {
+		return fmt.Errorf("one of id, link, or title is needed to tell items apart")
+	}
+	return nil
+}
+
+// paths parses the paths of the mapping, by field name.
+func (m JSONMapping) paths() (map[string]jsonPath, error) {
+	paths := map[string]jsonPath{}
+	for name, path := range map[string]string{
+		"items": m.Items, "id": m.ID, "title": m.Title, "link": m.Link,
+		"date": m.Date, "description": m.Description, "content": m.Content,
+		"author": m.Author, "feed_title": m.FeedTitle,
+	} {
+		parsed, err := parseJSONPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		paths[name] = parsed
+	}
+	return paths, nil
+}
+
+// parseJSONPath parses a path like .data.items[0].title // .title.
+func parseJSONPath(path string) (jsonPath, error) {
+	var parsed jsonPath
+	if strings.TrimSpace(path) == "" {
+		return nil, nil
+	}
+	for _, alternative := range strings.Split(path, "//") {
+		alternative = strings.TrimSpace(alternative)
+		if alternative == "" {
+			return nil, fmt.Errorf("empty alternative in path %q", path)
+		}
+		steps := []any{}
+		rest := strings.TrimPrefix(alternative, ".")
+		for rest != "" {
+			switch {
+			case rest[0] == '[':
+				end := strings.IndexByte(rest, ']')
+				if end < 0 {
+					return nil, fmt.Errorf("unclosed [ in path %q", path)
+				}
+				inside := rest[1:end]
+				if index, err := strconv.Atoi(inside); err == nil {
+					steps = append(steps, index)
+				} else if key, err := strconv.Unquote(inside); err == nil {
+					steps = append(steps, key)
+				} else {
+					return nil, fmt.Errorf("invalid index %q in path %q", inside, path)
+				}
+				rest = strings.TrimPrefix(rest[end+1:], ".")
+			default:
+				end := strings.IndexAny(rest, ".[")
+				if end < 0 {
+					end = len(rest)
+				}
+				if end == 0 {
+					return nil, fmt.Errorf("empty key in path %q", path)
+				}
+				steps = append(steps, rest[:end])
+				rest = strings.TrimPrefix(rest[end:], ".")
+			}
+		}
+		parsed = append(parsed, steps)
+	}
+	return parsed, nil
+}
+
+// lookup returns the first alternative of the path with a value in v, or
+// nil if none has one.
+func (p jsonPath) lookup(v any) any {
+	for _, steps := range p {
+		if value := lookupSteps(v, steps); value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+// lookupSteps follows steps into v, returning nil if one is missing.
+func lookupSteps(v any, steps []any) any {
+	for _, step := range steps {
+		switch step := step.(type) {
+		case string:
+			object, ok := v.(map[string]any)
+			if !ok {
+				return nil
+			}
+			v = object[step]
+		case int:
+			array, ok := v.([]any)
+			if !ok {
+				return nil
+			}
+			if step < 0 {
+				step += len(array)
+			}
+			if step < 0 || step >= len(array) {
+				return nil
+			}
+			v = array[step]
+		}
+	}
+	return v
+}
+
+// text returns the value at the path in v as a string. Numbers and
+// booleans are formatted, and other values give "".
+func (p jsonPath) text(v any) string {
+	switch value := p.lookup(v).(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return ""
+}
+
+// jsonDateLayouts are the date formats tried for item dates, after Unix
+// timestamps.
+var jsonDateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseJSONDate parses an item date, either a Unix timestamp in seconds or
+// milliseconds, or a date in one of jsonDateLayouts.
+func parseJSONDate(value string) *time.Time {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		// Timestamps this large are in milliseconds
+		if seconds > 1e11 {
+			seconds /= 1000
+		}
+		date := time.Unix(0, int64(seconds*float64(time.Second))).UTC()
+		return &date
+	}
+	for _, layout := range jsonDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &date
+		}
+	}
+	return nil
+}
+
+// parseJSONAPI reads the items of a JSON API response as a feed, as
+// described by mapping.
+func parseJSONAPI(body []byte, mapping JSONMapping) (*gofeed.Feed, error) {
+	paths, err := mapping.paths()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON mapping: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	items := document
+	if paths["items"] != nil {
+		items = paths["items"].lookup(document)
+	}
+	array, ok := items.([]any)
+	if !ok {
+		return nil, fmt.Errorf("items path %q doesn't select an array", mapping.Items)
+	}
+
+	feed := &gofeed.Feed{
+		Title:    paths["feed_title"].text(document),
+		FeedType: jsonAPIFeedType,
+	}
+	for _, value := range array {
+		item := &gofeed.Item{
+			GUID:        paths["id"].text(value),
+			Title:       paths["title"].text(value),
+			Link:        paths["link"].text(value),
+			Description: paths["description"].text(value),
+			Content:     paths["content"].text(value),
+			Published:   paths["date"].text(value),
+		}
+		if item.Published != "" {
+			item.PublishedParsed = parseJSONDate(item.Published)
+		}
+		if author := paths["author"].text(value); author != "" {
+			item.Author = &gofeed.Person{Name: author}
+			item.Authors = []*gofeed.Person{item.Author}
+		}
+		if item.GUID == "" && item.Title == "" && item.Link == "" {
+			continue
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, nil
+}
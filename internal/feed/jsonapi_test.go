@@ -0,0 +1,136 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: ""},
+		{path: "."},
+		{path: ".data.items"},
+		{path: "data.items[0].href"},
+		{path: `.links["rel-alternate"]`},
+		{path: ".published // .created"},
+		{path: ".data..items", wantErr: true},
+		{path: ".links[0", wantErr: true},
+		{path: ".links[first]", wantErr: true},
+		{path: ".title //", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseJSONPath(tt.path); (err != nil) != tt.wantErr {
+			t.Errorf("parseJSONPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseJSONAPI(t *testing.T) {
+	body := []byte(`{
+		"data": {
+			"title": "Releases",
+			"posts": [
+				{
+					"id": 42,
+					"title": "Version 2",
+					"url": "https://example.com/v2",
+					"created_at": "2024-03-01T12:00:00Z",
+					"body": "<p>Notes</p>",
+					"author": {"name": "Ada"},
+					"links": [{"href": "https://example.com/v2/notes"}]
+				},
+				{
+					"id": 41,
+					"title": "Version 1",
+					"url": "https://example.com/v1",
+					"published_at": 1700000000000
+				},
+				{"draft": true}
+			]
+		}
+	}`)
+	mapping := JSONMapping{
+		Items:     ".data.posts",
+		ID:        ".id",
+		Title:     ".title",
+		Link:      ".url // .links[0].href",
+		Date:      ".published_at // .created_at",
+		Content:   ".body",
+		Author:    ".author.name",
+		FeedTitle: ".data.title",
+	}
+
+	feed, err := parseJSONAPI(body, mapping)
+	if err != nil {
+		t.Fatalf("parseJSONAPI() error = %v", err)
+	}
+	if feed.Title != "Releases" {
+		t.Errorf("Title = %q, want Releases", feed.Title)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("got %d items, want 2 without the empty one", len(feed.Items))
+	}
+
+	first := feed.Items[0]
+	if first.GUID != "42" || first.Title != "Version 2" || first.Link != "https://example.com/v2" || first.Content != "<p>Notes</p>" {
+		t.Errorf("first item = %+v", first)
+	}
+	if first.Author == nil || first.Author.Name != "Ada" {
+		t.Errorf("Author = %+v, want Ada", first.Author)
+	}
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC); first.PublishedParsed == nil || !first.PublishedParsed.Equal(want) {
+		t.Errorf("PublishedParsed = %v, want %v", first.PublishedParsed, want)
+	}
+
+	// Millisecond timestamps are recognized
+	if second := feed.Items[1]; second.PublishedParsed == nil || second.PublishedParsed.Unix() != 1700000000 {
+		t.Errorf("PublishedParsed = %v, want the timestamp in seconds", second.PublishedParsed)
+	}
+
+	t.Run("a response that's an array", func(t *testing.T) {
+		feed, err := parseJSONAPI([]byte(`[{"title": "One"}, {"title": "Two"}]`), JSONMapping{Title: ".title"})
+		if err != nil {
+			t.Fatalf("parseJSONAPI() error = %v", err)
+		}
+		if len(feed.Items) != 2 || feed.Items[1].Title != "Two" {
+			t.Errorf("items = %+v", feed.Items)
+		}
+	})
+
+	t.Run("items that aren't an array", func(t *testing.T) {
+		if _, err := parseJSONAPI(body, JSONMapping{Items: ".data", Title: ".title"}); err == nil {
+			t.Error("parseJSONAPI() succeeded, want an error")
+		}
+	})
+}
+
+func TestFetch_JSONAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results": [{"slug": "first", "name": "First", "href": "https://example.com/first"}]}`))
+	}))
+	defer server.Close()
+
+	fetcher := New()
+	if err := fetcher.SetJSONMapping(JSONMapping{}); err == nil {
+		t.Error("SetJSONMapping() without id, link, or title succeeded, want an error")
+	}
+	if err := fetcher.SetJSONMapping(JSONMapping{Items: ".results", ID: ".slug", Title: ".name", Link: ".href"}); err != nil {
+		t.Fatalf("SetJSONMapping() error = %v", err)
+	}
+
+	feed, err := fetcher.FetchContext(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("FetchContext() error = %v", err)
+	}
+	if len(feed.Items) != 1 || GenerateEntryID(feed.Items[0]) != "first" {
+		t.Errorf("items = %+v, want one identified by its slug", feed.Items)
+	}
+}
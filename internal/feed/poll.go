@@ -0,0 +1,186 @@
+package feed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/mmcdole/gofeed/rss"
+	"github.com/sirupsen/logrus"
+)
+
+// pollHintsSetting holds the PollHints from the latest fetch.
+const pollHintsSetting = "feed_poll_hints"
+
+// Bounds for backing off when the feed's server refuses requests without
+// saying for how long. The backoff doubles with each refusal.
+const (
+	minRefusedBackoff = 15 * time.Minute
+	maxRefusedBackoff = 24 * time.Hour
+)
+
+// PollHints holds the feed's and its server's hints about when to fetch
+// the feed again: the RSS ttl, skipHours, and skipDays elements, and the
+// Cache-Control and Retry-After headers.
+type PollHints struct {
+	// NotBefore is the earliest time to fetch again.
+	NotBefore time.Time `json:"not_before,omitempty"`
+	// SkipHours are the hours, in UTC, not to fetch during.
+	SkipHours []int `json:"skip_hours,omitempty"`
+	// SkipDays are the days, in UTC, not to fetch on.
+	SkipDays []time.Weekday `json:"skip_days,omitempty"`
+	// Backoff is how long the latest refusal was backed off for.
+	Backoff time.Duration `json:"backoff,omitempty"`
+}
+
+// NextFetch returns the earliest time at or after t that the hints allow
+// fetching the feed.
+func (h PollHints) NextFetch(t time.Time) time.Time {
+	if t.Before(h.NotBefore) {
+		t = h.NotBefore
+	}
+	// A feed skipping every hour is ignored after a week
+	for i := 0; i < 7*24 && h.skipped(t); i++ {
+		t = t.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
+
+// skipped reports whether t falls in the skipped hours or days.
+func (h PollHints) skipped(t time.Time) bool {
+	t = t.UTC()
+	for _, hour := range h.SkipHours {
+		if t.Hour() == hour {
+			return true
+		}
+	}
+	for _, day := range h.SkipDays {
+		if t.Weekday() == day {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPollHints returns the hints stored in the feed's settings by the
+// latest fetch.
+func LoadPollHints(settings *database.Settings) (PollHints, error) {
+	var hints PollHints
+	if _, err := settings.GetJSON(pollHintsSetting, &hints); err != nil {
+		return PollHints{}, err
+	}
+	return hints, nil
+}
+
+// StorePollHints stores the hints from the latest Fetch. When it failed,
+// the feed's skipped hours and days from before are kept, and if the
+// server refused the request without a Retry-After, the stored hints back
+// off twice as long as the previous refusal.
+func (f *Fetcher) StorePollHints(settings *database.Settings) error {
+	hints := f.hints
+	if !f.fetched {
+		previous, err := LoadPollHints(settings)
+		if err != nil {
+			logrus.Warnf("Failed to load previous poll hints: %v", err)
+		}
+		hints.SkipHours, hints.SkipDays = previous.SkipHours, previous.SkipDays
+		if f.refused {
+			hints.Backoff = min(max(previous.Backoff*2, minRefusedBackoff), maxRefusedBackoff)
+			hints.NotBefore = f.now().Add(hints.Backoff)
+		}
+	}
+
+	if err := settings.SetJSON(pollHintsSetting, hints); err != nil {
+		return fmt.Errorf("failed to store poll hints: %w", err)
+	}
+	return nil
+}
+
+// recordResponse sets the poll hints from a successful response to the
+// feed request.
+func (f *Fetcher) recordResponse(header http.Header, body []byte, feedType string) {
+	now := f.now()
+	f.hints = PollHints{}
+	f.fetched, f.refused = true, false
+	if maxAge := cacheMaxAge(header); maxAge > 0 {
+		f.hints.NotBefore = now.Add(maxAge)
+	}
+	if feedType != "rss" {
+		return
+	}
+
+	// The universal feed leaves out RSS's polling elements
+	rssFeed, err := (&rss.Parser{}).Parse(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	if ttl, err := strconv.Atoi(strings.TrimSpace(rssFeed.TTL)); err == nil && ttl > 0 {
+		if next := now.Add(time.Duration(ttl) * time.Minute); next.After(f.hints.NotBefore) {
+			f.hints.NotBefore = next
+		}
+	}
+	for _, value := range rssFeed.SkipHours {
+		// Hour 24 is sometimes used for midnight
+		if hour, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && hour >= 0 && hour <= 24 {
+			f.hints.SkipHours = append(f.hints.SkipHours, hour%24)
+		}
+	}
+	for _, value := range rssFeed.SkipDays {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(strings.TrimSpace(value), day.String()) {
+				f.hints.SkipDays = append(f.hints.SkipDays, day)
+			}
+		}
+	}
+}
+
+// recordError sets the poll hints from a failed feed request. Servers
+// that refuse requests with 429 or 403 are backed off from, for as long
+// as their Retry-After asks if they send one.
+func (f *Fetcher) recordError(err error) {
+	f.hints = PollHints{}
+	f.fetched, f.refused = false, false
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return
+	}
+	if statusErr.RetryAfter > 0 {
+		f.hints.NotBefore = f.now().Add(statusErr.RetryAfter)
+		return
+	}
+	f.refused = statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == http.StatusForbidden
+}
+
+// cacheMaxAge returns the max-age of a Cache-Control header, or zero.
+func cacheMaxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return 0
+}
+
+// retryAfter returns the delay asked for by a Retry-After header, given
+// in seconds or as a date, or zero.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,173 @@
+package feed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+)
+
+func TestPollHints_NextFetch(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2024, 3, 6, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		hints PollHints
+		want  time.Time
+	}{
+		{
+			name: "no hints",
+			want: now,
+		},
+		{
+			name:  "not before a later time",
+			hints: PollHints{NotBefore: now.Add(time.Hour)},
+			want:  now.Add(time.Hour),
+		},
+		{
+			name:  "skipped hours",
+			hints: PollHints{SkipHours: []int{10, 11}},
+			want:  time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "skipped days",
+			hints: PollHints{SkipDays: []time.Weekday{time.Wednesday, time.Thursday}},
+			want:  time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "every hour skipped",
+			hints: PollHints{SkipHours: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23}},
+			want:  time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hints.NextFetch(now); !got.Equal(tt.want) {
+				t.Errorf("NextFetch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorePollHints(t *testing.T) {
+	now := time.Date(2024, 3, 6, 10, 30, 0, 0, time.UTC)
+
+	newFetcher := func() *Fetcher {
+		fetcher := New()
+		fetcher.now = func() time.Time { return now }
+		return fetcher
+	}
+
+	newDB := func(t *testing.T) *database.DB {
+		db, err := database.New(":memory:")
+		if err != nil {
+			t.Fatalf("database.New() error = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	// fetch fetches url and stores the resulting hints
+	fetch := func(t *testing.T, fetcher *Fetcher, db *database.DB, url string) PollHints {
+		_, _ = fetcher.Fetch(url)
+		if err := fetcher.StorePollHints(db.FeedSettings(url)); err != nil {
+			t.Fatalf("StorePollHints() error = %v", err)
+		}
+		hints, err := LoadPollHints(db.FeedSettings(url))
+		if err != nil {
+			t.Fatalf("LoadPollHints() error = %v", err)
+		}
+		return hints
+	}
+
+	t.Run("reads RSS ttl and skip elements", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "public, max-age=600")
+			_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Test</title><ttl>60</ttl>
+<skipHours><hour>0</hour><hour>24</hour><hour>3</hour></skipHours>
+<skipDays><day>Sunday</day></skipDays>
+</channel></rss>`))
+		}))
+		defer server.Close()
+
+		hints := fetch(t, newFetcher(), newDB(t), server.URL)
+		if !hints.NotBefore.Equal(now.Add(time.Hour)) {
+			t.Errorf("NotBefore = %v, want the ttl from now", hints.NotBefore)
+		}
+		if len(hints.SkipHours) != 3 || hints.SkipHours[1] != 0 || hints.SkipHours[2] != 3 {
+			t.Errorf("SkipHours = %v, want [0 0 3]", hints.SkipHours)
+		}
+		if len(hints.SkipDays) != 1 || hints.SkipDays[0] != time.Sunday {
+			t.Errorf("SkipDays = %v, want [Sunday]", hints.SkipDays)
+		}
+	})
+
+	t.Run("reads Cache-Control max-age", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=1800")
+			_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>Test</title></feed>`))
+		}))
+		defer server.Close()
+
+		hints := fetch(t, newFetcher(), newDB(t), server.URL)
+		if !hints.NotBefore.Equal(now.Add(30 * time.Minute)) {
+			t.Errorf("NotBefore = %v, want max-age from now", hints.NotBefore)
+		}
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		hints := fetch(t, newFetcher(), newDB(t), server.URL)
+		if !hints.NotBefore.Equal(now.Add(2 * time.Minute)) {
+			t.Errorf("NotBefore = %v, want Retry-After from now", hints.NotBefore)
+		}
+	})
+
+	t.Run("backs off from refusals", func(t *testing.T) {
+		status := http.StatusOK
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				return
+			}
+			_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Test</title><skipDays><day>Sunday</day></skipDays></channel></rss>`))
+		}))
+		defer server.Close()
+
+		fetcher, db := newFetcher(), newDB(t)
+		fetch(t, fetcher, db, server.URL)
+
+		status = http.StatusTooManyRequests
+		hints := fetch(t, fetcher, db, server.URL)
+		if hints.Backoff != 15*time.Minute || !hints.NotBefore.Equal(now.Add(15*time.Minute)) {
+			t.Errorf("first refusal hints = %+v, want a 15m backoff", hints)
+		}
+		if len(hints.SkipDays) != 1 {
+			t.Errorf("SkipDays = %v, want them kept from the last fetch", hints.SkipDays)
+		}
+
+		status = http.StatusForbidden
+		hints = fetch(t, fetcher, db, server.URL)
+		if hints.Backoff != 30*time.Minute {
+			t.Errorf("second refusal Backoff = %v, want 30m", hints.Backoff)
+		}
+
+		status = http.StatusOK
+		hints = fetch(t, fetcher, db, server.URL)
+		if hints.Backoff != 0 || !hints.NotBefore.IsZero() {
+			t.Errorf("hints after success = %+v, want no backoff", hints)
+		}
+	})
+}
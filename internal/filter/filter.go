@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Filter decides which feed items should be posted, based on regular
+// expressions matched against the item text and on category lists.
+type Filter struct {
+	include           *regexp.Regexp
+	exclude           *regexp.Regexp
+	includeCategories map[string]bool
+	excludeCategories map[string]bool
+}
+
+// New creates a Filter. Empty patterns and lists are ignored.
+// Category names are compared case-insensitively.
+func New(includeRegex, excludeRegex string, includeCategories, excludeCategories []string) (*Filter, error) {
+	f := &Filter{
+		includeCategories: categorySet(includeCategories),
+		excludeCategories: categorySet(excludeCategories),
+	}
+
+	var err error
+	if includeRegex != "" {
+		if f.include, err = regexp.Compile(includeRegex); err != nil {
+			return nil, fmt.Errorf("invalid include_regex: %w", err)
+		}
+	}
+	if excludeRegex != "" {
+		if f.exclude, err = regexp.Compile(excludeRegex); err != nil {
+			return nil, fmt.Errorf("invalid exclude_regex: %w", err)
+		}
+	}
+
+	return f, nil
+}
+
+// Empty reports whether the filter has no rules, so every item passes.
+func (f *Filter) Empty() bool {
+	return f.include == nil && f.exclude == nil &&
+		len(f.includeCategories) == 0 && len(f.excludeCategories) == 0
+}
+
+// Check reports whether item passes the filter. If it doesn't, reason
+// describes which rule rejected it.
+func (f *Filter) Check(item *gofeed.Item) (ok bool, reason string) {
+	for _, category := range item.Categories {
+		if f.excludeCategories[strings.ToLower(category)] {
+			return false, fmt.Sprintf("excluded category %q", category)
+		}
+	}
+
+	if len(f.includeCategories) > 0 && !hasCategory(item, f.includeCategories) {
+		return false, "no allowed category"
+	}
+
+	text := itemText(item)
+	if f.exclude != nil && f.exclude.MatchString(text) {
+		return false, fmt.Sprintf("matches exclude_regex %q", f.exclude.String())
+	}
+	if f.include != nil && !f.include.MatchString(text) {
+		return false, fmt.Sprintf("doesn't match include_regex %q", f.include.String())
+	}
+
+	return true, ""
+}
+
+// CheckEntry is like Check, for an item stored as JSON.
+func (f *Filter) CheckEntry(entryJSON []byte) (ok bool, reason string, err error) {
+	var item gofeed.Item
+	if err := json.Unmarshal(entryJSON, &item); err != nil {
+		return false, "", fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+	ok, reason = f.Check(&item)
+	return ok, reason, nil
+}
+
+// itemText joins the fields that regexes are matched against.
+func itemText(item *gofeed.Item) string {
+	return strings.Join([]string{
+		item.Title,
+		strings.Join(item.Categories, "\n"),
+		item.Description,
+		item.Content,
+	}, "\n")
+}
+
+// hasCategory reports whether item has any of the given categories.
+func hasCategory(item *gofeed.Item, categories map[string]bool) bool {
+	for _, category := range item.Categories {
+		if categories[strings.ToLower(category)] {
+			return true
+		}
+	}
+	return false
+}
+
+// categorySet builds a lowercase lookup set from a list of categories.
+func categorySet(categories []string) map[string]bool {
+	set := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		if category = strings.TrimSpace(category); category != "" {
+			set[strings.ToLower(category)] = true
+		}
+	}
+	return set
+}
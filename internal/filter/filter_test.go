@@ -0,0 +1,131 @@
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("rejects invalid include regex", func(t *testing.T) {
+		if _, err := New("(", "", nil, nil); err == nil {
+			t.Error("Expected error for invalid include regex")
+		}
+	})
+
+	t.Run("rejects invalid exclude regex", func(t *testing.T) {
+		if _, err := New("", "[", nil, nil); err == nil {
+			t.Error("Expected error for invalid exclude regex")
+		}
+	})
+
+	t.Run("empty filter", func(t *testing.T) {
+		f, err := New("", "", nil, []string{" "})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if !f.Empty() {
+			t.Error("Empty() = false, want true")
+		}
+	})
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name              string
+		include, exclude  string
+		includeCategories []string
+		excludeCategories []string
+		item              gofeed.Item
+		wantOK            bool
+		wantReason        string
+	}{
+		{
+			name:   "no rules",
+			item:   gofeed.Item{Title: "Anything"},
+			wantOK: true,
+		},
+		{
+			name:    "include regex matches title",
+			include: `(?i)golang`,
+			item:    gofeed.Item{Title: "Why I like Golang"},
+			wantOK:  true,
+		},
+		{
+			name:       "include regex doesn't match",
+			include:    `(?i)golang`,
+			item:       gofeed.Item{Title: "Rust news"},
+			wantReason: "include_regex",
+		},
+		{
+			name:       "exclude regex matches content",
+			exclude:    `(?i)sponsored`,
+			item:       gofeed.Item{Title: "Deal", Content: "This post is Sponsored"},
+			wantReason: "exclude_regex",
+		},
+		{
+			name:       "exclude wins over include",
+			include:    `news`,
+			exclude:    `rumor`,
+			item:       gofeed.Item{Title: "news and rumor"},
+			wantReason: "exclude_regex",
+		},
+		{
+			name:              "excluded category",
+			excludeCategories: []string{"Meta"},
+			item:              gofeed.Item{Title: "Site update", Categories: []string{"meta"}},
+			wantReason:        `excluded category "meta"`,
+		},
+		{
+			name:              "allowed category",
+			includeCategories: []string{"go", "rust"},
+			item:              gofeed.Item{Title: "Post", Categories: []string{"Go"}},
+			wantOK:            true,
+		},
+		{
+			name:              "no allowed category",
+			includeCategories: []string{"go"},
+			item:              gofeed.Item{Title: "Post", Categories: []string{"python"}},
+			wantReason:        "no allowed category",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.include, tt.exclude, tt.includeCategories, tt.excludeCategories)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			ok, reason := f.Check(&tt.item)
+			if ok != tt.wantOK {
+				t.Errorf("Check() ok = %v, want %v (reason %q)", ok, tt.wantOK, reason)
+			}
+			if !strings.Contains(reason, tt.wantReason) {
+				t.Errorf("Check() reason = %q, want it to contain %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCheckEntry(t *testing.T) {
+	f, err := New("", "skip", nil, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	itemJSON, _ := json.Marshal(&gofeed.Item{Title: "please skip me"})
+	ok, _, err := f.CheckEntry(itemJSON)
+	if err != nil {
+		t.Fatalf("CheckEntry() error = %v", err)
+	}
+	if ok {
+		t.Error("CheckEntry() ok = true, want false")
+	}
+
+	if _, _, err := f.CheckEntry([]byte("invalid json")); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,108 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RunResult describes the outcome of a single fetch-and-post run.
+type RunResult struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	NewEntries int       `json:"new_entries"`
+	Posted     int       `json:"posted"`
+	Failed     int       `json:"failed"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status tracks daemon state for health checks. It is safe for concurrent use.
+type Status struct {
+	mu         sync.RWMutex
+	startedAt  time.Time
+	queueDepth int
+	lastRun    *RunResult
+}
+
+// Document is the JSON status document served by the status endpoint.
+type Document struct {
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	QueueDepth int        `json:"queue_depth"`
+	LastRun    *RunResult `json:"last_run,omitempty"`
+}
+
+// NewStatus creates a new Status for a daemon started now.
+func NewStatus() *Status {
+	return &Status{startedAt: time.Now()}
+}
+
+// RecordRun stores the result of the latest run and the current queue depth.
+func (s *Status) RecordRun(result RunResult, queueDepth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun = &result
+	s.queueDepth = queueDepth
+}
+
+// Ready reports whether the daemon has completed a run and the latest run
+// succeeded.
+func (s *Status) Ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastRun != nil && s.lastRun.Error == ""
+}
+
+// Document returns a snapshot of the current status.
+func (s *Status) Document() Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc := Document{
+		Status:     "starting",
+		StartedAt:  s.startedAt,
+		QueueDepth: s.queueDepth,
+	}
+	if s.lastRun != nil {
+		run := *s.lastRun
+		doc.LastRun = &run
+		if run.Error == "" {
+			doc.Status = "ok"
+		} else {
+			doc.Status = "error"
+		}
+	}
+
+	return doc
+}
+
+// NewHandler returns an HTTP handler serving:
+//   - /healthz: liveness, always 200 while the process is serving
+//   - /readyz: readiness, 200 once the latest run succeeded, 503 otherwise
+//   - /status: a JSON status document with queue depth and last-run results
+func NewHandler(status *Status) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !status.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("not ready\n"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(status.Document())
+	})
+
+	return mux
+}
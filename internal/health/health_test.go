@@ -0,0 +1,86 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	t.Run("healthz is always ok", func(t *testing.T) {
+		handler := NewHandler(NewStatus())
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", rec.Code)
+		}
+	})
+
+	t.Run("readyz is unavailable before first run", func(t *testing.T) {
+		handler := NewHandler(NewStatus())
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", rec.Code)
+		}
+	})
+
+	t.Run("readyz is ok after successful run", func(t *testing.T) {
+		status := NewStatus()
+		status.RecordRun(RunResult{StartedAt: time.Now(), FinishedAt: time.Now()}, 0)
+		handler := NewHandler(status)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200", rec.Code)
+		}
+	})
+
+	t.Run("readyz is unavailable after failed run", func(t *testing.T) {
+		status := NewStatus()
+		status.RecordRun(RunResult{Error: "failed to fetch feed"}, 0)
+		handler := NewHandler(status)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", rec.Code)
+		}
+	})
+
+	t.Run("status returns JSON document", func(t *testing.T) {
+		status := NewStatus()
+		status.RecordRun(RunResult{NewEntries: 3, Posted: 2, Failed: 1}, 5)
+		handler := NewHandler(status)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+
+		var doc Document
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if doc.Status != "ok" {
+			t.Errorf("Status = %s, want ok", doc.Status)
+		}
+		if doc.QueueDepth != 5 {
+			t.Errorf("QueueDepth = %d, want 5", doc.QueueDepth)
+		}
+		if doc.LastRun == nil || doc.LastRun.Posted != 2 {
+			t.Errorf("LastRun = %+v", doc.LastRun)
+		}
+	})
+}
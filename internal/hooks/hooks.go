@@ -0,0 +1,183 @@
+// Package hooks runs the shell commands configured to run around posting
+// each entry, for filtering, notifications, or archiving without changing
+// feed-to-mastodon itself.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Hook names, as in the hooks config and FEED_TO_MASTODON_HOOK.
+const (
+	PrePost     = "pre_post"
+	PostSuccess = "post_success"
+	PostFailure = "post_failure"
+)
+
+// DefaultTimeout is how long a hook may run when no timeout is set.
+const DefaultTimeout = 30 * time.Second
+
+// waitDelay is how long a hook's output is read after it's stopped for
+// running too long.
+const waitDelay = time.Second
+
+// rejectExitCode is the exit status of a pre_post hook that holds an entry
+// back. Other failures are errors.
+const rejectExitCode = 1
+
+// Event describes the entry a hook runs for. It's written to the hook's
+// stdin as JSON, and its fields are also set in the environment.
+type Event struct {
+	// Hook is the name of the hook being run.
+	Hook string `json:"hook"`
+	// EntryID is the ID of the entry in the database.
+	EntryID string `json:"entry_id"`
+	// Entry is the feed item as stored, in gofeed's JSON format.
+	Entry json.RawMessage `json:"entry"`
+	// Content is the rendered post.
+	Content string `json:"content"`
+	// StatusURL is the URL of the posted status, for post_success.
+	StatusURL string `json:"status_url,omitempty"`
+	// Error is why posting failed, for post_failure.
+	Error string `json:"error,omitempty"`
+	// DryRun is set when nothing is actually posted.
+	DryRun bool `json:"dry_run"`
+}
+
+// Runner runs the configured hooks. Hooks that aren't set don't run.
+type Runner struct {
+	commands map[string]string
+	timeout  time.Duration
+}
+
+// New returns a Runner for the given hook commands, which are run with the
+// system shell. A timeout of 0 is DefaultTimeout.
+func New(prePost, postSuccess, postFailure string, timeout time.Duration) *Runner {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Runner{
+		commands: map[string]string{
+			PrePost:     prePost,
+			PostSuccess: postSuccess,
+			PostFailure: postFailure,
+		},
+		timeout: timeout,
+	}
+}
+
+// Enabled reports whether the named hook is set.
+func (r *Runner) Enabled(hook string) bool {
+	return r.commands[hook] != ""
+}
+
+// CheckEntry runs the pre_post hook for an entry about to be posted.
+// Returns false and the reason, the first line the hook printed, if it
+// exits with status 1 to hold the entry back. Other failures, like other
+// exit statuses or running too long, are errors. Entries are allowed if the
+// hook isn't set.
+func (r *Runner) CheckEntry(ctx context.Context, event Event) (bool, string, error) {
+	event.Hook = PrePost
+	output, err := r.run(ctx, event)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == rejectExitCode {
+		reason, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+		if reason == "" {
+			reason = "held back by the pre_post hook"
+		}
+		return false, reason, nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, "", nil
+}
+
+// Posted runs the post_success hook for an entry that was posted.
+func (r *Runner) Posted(ctx context.Context, event Event) error {
+	event.Hook = PostSuccess
+	_, err := r.run(ctx, event)
+	return err
+}
+
+// Failed runs the post_failure hook for an entry that failed to post.
+func (r *Runner) Failed(ctx context.Context, event Event) error {
+	event.Hook = PostFailure
+	_, err := r.run(ctx, event)
+	return err
+}
+
+// run runs the event's hook, if it's set, returning what it printed.
+func (r *Runner) run(ctx context.Context, event Event) (string, error) {
+	command := r.commands[event.Hook]
+	if command == "" {
+		return "", nil
+	}
+
+	input, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s hook input: %w", event.Hook, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), event.environment()...)
+	// Don't wait on children of the shell that outlive a timeout
+	cmd.WaitDelay = waitDelay
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return stdout.String(), fmt.Errorf("%s hook timed out after %s", event.Hook, r.timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), fmt.Errorf("%s hook failed: %w: %s", event.Hook, err, msg)
+		}
+		return stdout.String(), fmt.Errorf("%s hook failed: %w", event.Hook, err)
+	}
+	return stdout.String(), nil
+}
+
+// environment returns the environment variables describing the event.
+func (e Event) environment() []string {
+	var item struct {
+		Title string `json:"title"`
+		Link  string `json:"link"`
+	}
+	// Broken entries still run hooks, without their title and link
+	_ = json.Unmarshal(e.Entry, &item)
+
+	dryRun := "0"
+	if e.DryRun {
+		dryRun = "1"
+	}
+	return []string{
+		"FEED_TO_MASTODON_HOOK=" + e.Hook,
+		"FEED_TO_MASTODON_ENTRY_ID=" + e.EntryID,
+		"FEED_TO_MASTODON_ENTRY_TITLE=" + item.Title,
+		"FEED_TO_MASTODON_ENTRY_LINK=" + item.Link,
+		"FEED_TO_MASTODON_CONTENT=" + e.Content,
+		"FEED_TO_MASTODON_STATUS_URL=" + e.StatusURL,
+		"FEED_TO_MASTODON_ERROR=" + e.Error,
+		"FEED_TO_MASTODON_DRY_RUN=" + dryRun,
+	}
+}
@@ -0,0 +1,101 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEvent is an event for an entry with a title and link.
+var testEvent = Event{
+	EntryID: "entry-1",
+	Entry:   json.RawMessage(`{"title": "Hello", "link": "https://example.com/hello"}`),
+	Content: "Hello https://example.com/hello",
+}
+
+func skipOnWindows(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+}
+
+func TestCheckEntry(t *testing.T) {
+	skipOnWindows(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		command    string
+		wantOK     bool
+		wantReason string
+		wantErr    string
+	}{
+		{name: "not set", command: "", wantOK: true},
+		{name: "allows", command: "true", wantOK: true},
+		{name: "holds back with the first line as reason", command: `echo "no giveaways"; echo more; exit 1`, wantReason: "no giveaways"},
+		{name: "holds back without a reason", command: "exit 1", wantReason: "held back by the pre_post hook"},
+		{name: "fails on other statuses", command: "echo broken >&2; exit 2", wantErr: "pre_post hook failed: exit status 2: broken"},
+		{name: "reads the environment", command: `test "$FEED_TO_MASTODON_ENTRY_TITLE" = Hello && test "$FEED_TO_MASTODON_HOOK" = pre_post`, wantOK: true},
+		{name: "reads stdin", command: `grep -q '"content":"Hello https://example.com/hello"'`, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reason, err := New(tt.command, "", "", 0).CheckEntry(ctx, testEvent)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("CheckEntry() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || ok != tt.wantOK || reason != tt.wantReason {
+				t.Errorf("CheckEntry() = %v, %q, %v; want %v, %q", ok, reason, err, tt.wantOK, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCheckEntry_Timeout(t *testing.T) {
+	skipOnWindows(t)
+	runner := New("sleep 5", "", "", 50*time.Millisecond)
+	if _, _, err := runner.CheckEntry(context.Background(), testEvent); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("CheckEntry() error = %v, want a timeout", err)
+	}
+}
+
+func TestPostedAndFailed(t *testing.T) {
+	skipOnWindows(t)
+	out := filepath.Join(t.TempDir(), "hooks.log")
+	command := `echo "$FEED_TO_MASTODON_HOOK $FEED_TO_MASTODON_ENTRY_ID $FEED_TO_MASTODON_STATUS_URL$FEED_TO_MASTODON_ERROR" >> ` + out
+	runner := New("", command, command, 0)
+
+	posted := testEvent
+	posted.StatusURL = "https://mastodon.example/@bot/1"
+	if err := runner.Posted(context.Background(), posted); err != nil {
+		t.Fatalf("Posted() error = %v", err)
+	}
+	failed := testEvent
+	failed.Error = "server error"
+	if err := runner.Failed(context.Background(), failed); err != nil {
+		t.Fatalf("Failed() error = %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	want := "post_success entry-1 https://mastodon.example/@bot/1\npost_failure entry-1 server error\n"
+	if string(data) != want {
+		t.Errorf("hooks wrote %q, want %q", data, want)
+	}
+
+	if err := New("", "exit 3", "", 0).Posted(context.Background(), posted); err == nil {
+		t.Error("Posted() of a failing hook succeeded")
+	}
+}
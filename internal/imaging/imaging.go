@@ -0,0 +1,240 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+)
+
+// jpegQualities are the qualities tried, in order, when re-encoding a JPEG
+// to fit a size limit, before shrinking it further.
+var jpegQualities = []int{90, 80, 70, 60}
+
+// minDimension is the smallest side an image is shrunk to while trying to
+// fit a size limit.
+const minDimension = 64
+
+// Options describes how images are prepared before they're uploaded.
+type Options struct {
+	// MaxDimension is the longest side allowed, in pixels, or 0 for any.
+	MaxDimension int
+	// MaxPixels is the most pixels allowed, width times height, or 0 for
+	// any.
+	MaxPixels int
+	// MaxBytes is the largest file allowed, or 0 for any.
+	MaxBytes int64
+	// Downscale shrinks and re-encodes images over the limits. Without
+	// it, images are left at their size.
+	Downscale bool
+	// StripMetadata removes EXIF, XMP, IPTC, and text metadata, which can
+	// include locations and camera details.
+	StripMetadata bool
+}
+
+// Enabled reports whether the options change any images.
+func (o Options) Enabled() bool {
+	return o.Downscale || o.StripMetadata
+}
+
+// fits reports whether an image of the given size is within the limits.
+func (o Options) fits(width, height int, size int64) bool {
+	if o.MaxDimension > 0 && (width > o.MaxDimension || height > o.MaxDimension) {
+		return false
+	}
+	if o.MaxPixels > 0 && width*height > o.MaxPixels {
+		return false
+	}
+	return o.MaxBytes <= 0 || size <= o.MaxBytes
+}
+
+// targetSize returns the largest size with the aspect ratio of width and
+// height that is within MaxDimension and MaxPixels.
+func (o Options) targetSize(width, height int) (int, int) {
+	scale := 1.0
+	if o.MaxDimension > 0 {
+		if longest := max(width, height); longest > o.MaxDimension {
+			scale = float64(o.MaxDimension) / float64(longest)
+		}
+	}
+	if o.MaxPixels > 0 && float64(width*height)*scale*scale > float64(o.MaxPixels) {
+		scale = math.Sqrt(float64(o.MaxPixels) / float64(width*height))
+	}
+	return max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+}
+
+// Prepare processes a JPEG or PNG image as described by opts, returning
+// the image to upload and its content type. Images in other formats, like
+// GIF and WebP, are returned as they are. An image that has to be
+// re-encoded, to shrink it or to apply the rotation recorded in metadata
+// being stripped, loses all its metadata.
+func Prepare(data []byte, opts Options) ([]byte, string, error) {
+	contentType := http.DetectContentType(data)
+	if !opts.Enabled() || (contentType != "image/jpeg" && contentType != "image/png") {
+		return data, contentType, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image: %w", err)
+	}
+	orientation := 1
+	if contentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	width, height := config.Width, config.Height
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	shrink := opts.Downscale && !opts.fits(width, height, int64(len(data)))
+	rotate := opts.StripMetadata && orientation != 1
+	if !shrink && !rotate {
+		if opts.StripMetadata {
+			return stripMetadata(data, contentType), contentType, nil
+		}
+		return data, contentType, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image: %w", err)
+	}
+	oriented := orient(toRGBA(img), orientation)
+	if !shrink {
+		return encode(oriented, contentType, jpegQualities[0])
+	}
+	return shrinkToFit(oriented, contentType, opts)
+}
+
+// shrinkToFit scales img down to the dimension limits and re-encodes it,
+// lowering the JPEG quality and then shrinking it further until it's
+// within MaxBytes. PNGs without transparency become JPEGs if they're still
+// too large.
+func shrinkToFit(img *image.RGBA, contentType string, opts Options) ([]byte, string, error) {
+	width, height := opts.targetSize(img.Bounds().Dx(), img.Bounds().Dy())
+	for width >= minDimension || height >= minDimension {
+		scaled := img
+		if width != img.Bounds().Dx() || height != img.Bounds().Dy() {
+			scaled = resize(img, width, height)
+		}
+
+		qualities := jpegQualities
+		if contentType == "image/png" {
+			qualities = qualities[:1]
+		}
+		for _, quality := range qualities {
+			data, encodedType, err := encode(scaled, contentType, quality)
+			if err != nil {
+				return nil, "", err
+			}
+			if opts.MaxBytes <= 0 || int64(len(data)) <= opts.MaxBytes {
+				return data, encodedType, nil
+			}
+		}
+
+		if contentType == "image/png" && scaled.Opaque() {
+			contentType = "image/jpeg"
+			continue
+		}
+		width, height = width*3/4, height*3/4
+	}
+	return nil, "", fmt.Errorf("can't shrink the image below %d bytes", opts.MaxBytes)
+}
+
+// encode encodes img as a JPEG of the given quality, or a PNG.
+func encode(img image.Image, contentType string, quality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// toRGBA converts img to an RGBA image with its origin at 0, 0.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// resize scales img down to width by height, averaging the pixels each
+// new pixel covers.
+func resize(img *image.RGBA, width, height int) *image.RGBA {
+	srcWidth, srcHeight := img.Bounds().Dx(), img.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max((y+1)*srcHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				offset := img.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(img.Pix[offset+c])
+					}
+					offset += 4
+				}
+			}
+			count := (x1 - x0) * (y1 - y0)
+			offset := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[offset+c] = uint8((sum[c] + count/2) / count)
+			}
+		}
+	}
+	return dst
+}
+
+// orient transforms img as the EXIF orientation says it should be shown:
+// 2 to 4 flip or turn it over, 5 to 8 also turn it on its side.
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dstWidth, dstHeight := w, h
+	if orientation >= 5 {
+		dstWidth, dstHeight = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
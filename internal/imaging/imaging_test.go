@@ -0,0 +1,228 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+// testImage returns a width by height image of noise, which compresses
+// poorly, so size limits are hard to meet.
+func testImage(width, height int) *image.RGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+		if i%4 == 3 {
+			img.Pix[i] = 0xFF
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("jpeg.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation adds an EXIF segment recording orientation to a JPEG.
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = append(tiff, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, markerAPP1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func decodeConfig(t *testing.T, data []byte) image.Config {
+	t.Helper()
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeConfig() error = %v", err)
+	}
+	return config
+}
+
+func TestPrepare_StripMetadata(t *testing.T) {
+	t.Run("JPEG EXIF is removed", func(t *testing.T) {
+		data := withOrientation(encodeJPEG(t, testImage(40, 20)), 1)
+		if jpegOrientation(data) != 1 || !bytes.Contains(data, []byte("Exif")) {
+			t.Fatal("test image has no EXIF")
+		}
+
+		prepared, contentType, err := Prepare(data, Options{StripMetadata: true})
+		if err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if contentType != "image/jpeg" || bytes.Contains(prepared, []byte("Exif")) {
+			t.Errorf("Prepare() = %s with EXIF %v, want a JPEG without it", contentType, bytes.Contains(prepared, []byte("Exif")))
+		}
+		if len(prepared) != len(data)-len("Exif\x00\x00")-26-4 {
+			t.Errorf("Prepare() re-encoded the image: %d bytes from %d", len(prepared), len(data))
+		}
+		decodeConfig(t, prepared)
+	})
+
+	t.Run("rotated JPEG is turned upright", func(t *testing.T) {
+		data := withOrientation(encodeJPEG(t, testImage(40, 20)), 6)
+		if jpegOrientation(data) != 6 {
+			t.Fatalf("jpegOrientation() = %d, want 6", jpegOrientation(data))
+		}
+
+		prepared, _, err := Prepare(data, Options{StripMetadata: true})
+		if err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if config := decodeConfig(t, prepared); config.Width != 20 || config.Height != 40 {
+			t.Errorf("size = %dx%d, want 20x40", config.Width, config.Height)
+		}
+		if bytes.Contains(prepared, []byte("Exif")) {
+			t.Error("rotated image still has EXIF")
+		}
+	})
+
+	t.Run("PNG text is removed", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, testImage(8, 8)); err != nil {
+			t.Fatalf("png.Encode() error = %v", err)
+		}
+		data := buf.Bytes()
+		// Insert a tEXt chunk after the IHDR chunk; its CRC isn't checked
+		text := []byte("\x00\x00\x00\x0btEXtGPS\x0048.8584\x00\x00\x00\x00")
+		ihdrEnd := len(pngSignature) + 25
+		data = append(append(append([]byte{}, data[:ihdrEnd]...), text...), data[ihdrEnd:]...)
+
+		prepared, _, err := Prepare(data, Options{StripMetadata: true})
+		if err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if bytes.Contains(prepared, []byte("tEXt")) || len(prepared) != buf.Len() {
+			t.Errorf("Prepare() kept the text chunk: %d bytes, want %d", len(prepared), buf.Len())
+		}
+		decodeConfig(t, prepared)
+	})
+}
+
+func TestPrepare_Downscale(t *testing.T) {
+	t.Run("fits the longest side", func(t *testing.T) {
+		data := encodeJPEG(t, testImage(400, 200))
+		prepared, contentType, err := Prepare(data, Options{Downscale: true, MaxDimension: 100})
+		if err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if config := decodeConfig(t, prepared); contentType != "image/jpeg" || config.Width != 100 || config.Height != 50 {
+			t.Errorf("Prepare() = %s %dx%d, want a 100x50 JPEG", contentType, config.Width, config.Height)
+		}
+	})
+
+	t.Run("fits the pixel count", func(t *testing.T) {
+		data := encodeJPEG(t, testImage(400, 100))
+		prepared, _, err := Prepare(data, Options{Downscale: true, MaxPixels: 10000})
+		if err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if config := decodeConfig(t, prepared); config.Width*config.Height > 10000 || config.Width < 190 {
+			t.Errorf("size = %dx%d, want about 200x50", config.Width, config.Height)
+		}
+	})
+
+	t.Run("fits the size limit", func(t *testing.T) {
+		data := encodeJPEG(t, testImage(300, 300))
+		prepared, _, err := Prepare(data, Options{Downscale: true, MaxBytes: 20000})
+		if err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if len(prepared) > 20000 || len(data) <= 20000 {
+			t.Errorf("Prepare() = %d bytes from %d, want at most 20000", len(prepared), len(data))
+		}
+	})
+
+	t.Run("opaque PNG becomes a JPEG", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, testImage(200, 200)); err != nil {
+			t.Fatalf("png.Encode() error = %v", err)
+		}
+		prepared, contentType, err := Prepare(buf.Bytes(), Options{Downscale: true, MaxBytes: int64(buf.Len() / 4)})
+		if err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if contentType != "image/jpeg" || int64(len(prepared)) > int64(buf.Len()/4) {
+			t.Errorf("Prepare() = %s of %d bytes, want a smaller JPEG", contentType, len(prepared))
+		}
+	})
+
+	t.Run("images within the limits are unchanged", func(t *testing.T) {
+		data := encodeJPEG(t, testImage(50, 50))
+		prepared, _, err := Prepare(data, Options{Downscale: true, MaxDimension: 100, MaxBytes: int64(len(data))})
+		if err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if !bytes.Equal(prepared, data) {
+			t.Error("Prepare() changed an image within the limits")
+		}
+	})
+
+	t.Run("GIFs are left alone", func(t *testing.T) {
+		var buf bytes.Buffer
+		palette := color.Palette{color.Black, color.White}
+		if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 400, 400), palette), nil); err != nil {
+			t.Fatalf("gif.Encode() error = %v", err)
+		}
+		prepared, contentType, err := Prepare(buf.Bytes(), Options{Downscale: true, MaxDimension: 100})
+		if err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+		if contentType != "image/gif" || !bytes.Equal(prepared, buf.Bytes()) {
+			t.Errorf("Prepare() changed a %s", contentType)
+		}
+	})
+}
+
+func TestOrient(t *testing.T) {
+	// A red pixel left of a blue one
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 0xFF, A: 0xFF})
+	img.Set(1, 0, color.RGBA{B: 0xFF, A: 0xFF})
+
+	tests := []struct {
+		orientation int
+		wantSize    image.Point
+		wantRedAt   image.Point
+	}{
+		{orientation: 1, wantSize: image.Pt(2, 1), wantRedAt: image.Pt(0, 0)},
+		{orientation: 2, wantSize: image.Pt(2, 1), wantRedAt: image.Pt(1, 0)},
+		{orientation: 3, wantSize: image.Pt(2, 1), wantRedAt: image.Pt(1, 0)},
+		{orientation: 6, wantSize: image.Pt(1, 2), wantRedAt: image.Pt(0, 0)},
+		{orientation: 8, wantSize: image.Pt(1, 2), wantRedAt: image.Pt(0, 1)},
+	}
+
+	for _, tt := range tests {
+		oriented := orient(img, tt.orientation)
+		if size := oriented.Bounds().Size(); size != tt.wantSize {
+			t.Errorf("orientation %d: size = %v, want %v", tt.orientation, size, tt.wantSize)
+			continue
+		}
+		if r, _, _, _ := oriented.At(tt.wantRedAt.X, tt.wantRedAt.Y).RGBA(); r == 0 {
+			t.Errorf("orientation %d: red pixel isn't at %v", tt.orientation, tt.wantRedAt)
+		}
+	}
+}
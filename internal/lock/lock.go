@@ -0,0 +1,110 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pollInterval is how often a waiting Acquire tries the lock again.
+const pollInterval = 500 * time.Millisecond
+
+// ErrLocked is returned when another process holds the lock.
+var ErrLocked = errors.New("locked by another process")
+
+// Lock is an advisory lock on a file, held until Release is called or the
+// process exits.
+type Lock struct {
+	file *os.File
+}
+
+// LockedError reports that another process holds a lock, and which one.
+type LockedError struct {
+	Path string
+	// PID is the process holding the lock, or 0 if it's unknown.
+	PID int
+}
+
+func (e *LockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s is locked by another process (pid %d)", e.Path, e.PID)
+	}
+	return fmt.Sprintf("%s is locked by another process", e.Path)
+}
+
+// Unwrap makes LockedError match ErrLocked.
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// Acquire takes the lock on the file at path, creating it if needed. If
+// another process holds the lock, Acquire returns a *LockedError at once,
+// or with wait set, tries again until the lock is free or ctx is done.
+func Acquire(ctx context.Context, path string, wait bool) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	for {
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+		if !wait {
+			pid := holder(file)
+			file.Close()
+			return nil, &LockedError{Path: path, PID: pid}
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+
+	// Note who holds the lock for the next process to find it locked. The
+	// PID only makes LockedError more helpful, so failing to write it
+	// doesn't fail the lock.
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// Release releases the lock. The lock file is left in place, as removing
+// it would let another process lock a different file at the same path.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	// A PID left behind is overwritten by the next holder, and holder is
+	// only read while the lock is held, so it's harmless
+	_ = l.file.Truncate(0)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// holder returns the process ID written to a lock file by the process
+// holding it, or 0 if it can't be read.
+func holder(file *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -0,0 +1,98 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Run("fails while another holder has the lock", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.lock")
+
+		held, err := Acquire(context.Background(), path, false)
+		if err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		defer held.Release()
+
+		_, err = Acquire(context.Background(), path, false)
+		if !errors.Is(err, ErrLocked) {
+			t.Fatalf("Acquire() error = %v, want ErrLocked", err)
+		}
+		var lockedErr *LockedError
+		if !errors.As(err, &lockedErr) {
+			t.Fatalf("Acquire() error = %T, want *LockedError", err)
+		}
+		if lockedErr.PID != os.Getpid() {
+			t.Errorf("PID = %d, want %d", lockedErr.PID, os.Getpid())
+		}
+	})
+
+	t.Run("succeeds after release", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.lock")
+
+		held, err := Acquire(context.Background(), path, false)
+		if err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		if err := held.Release(); err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+
+		again, err := Acquire(context.Background(), path, false)
+		if err != nil {
+			t.Fatalf("Acquire() after Release() error = %v", err)
+		}
+		again.Release()
+
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected lock file to be left in place: %v", err)
+		}
+	})
+
+	t.Run("waits for the lock to be released", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.lock")
+
+		held, err := Acquire(context.Background(), path, false)
+		if err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		time.AfterFunc(100*time.Millisecond, func() { held.Release() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		waited, err := Acquire(ctx, path, true)
+		if err != nil {
+			t.Fatalf("Acquire() with wait error = %v", err)
+		}
+		waited.Release()
+	})
+
+	t.Run("stops waiting when context is done", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.lock")
+
+		held, err := Acquire(context.Background(), path, false)
+		if err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		defer held.Release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = Acquire(ctx, path, true)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Acquire() error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestReleaseNil(t *testing.T) {
+	var l *Lock
+	if err := l.Release(); err != nil {
+		t.Errorf("Release() on nil lock error = %v", err)
+	}
+}
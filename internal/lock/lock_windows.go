@@ -0,0 +1,28 @@
+//go:build windows
+
+package lock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// tryLock takes an exclusive lock on file without blocking, and reports
+// whether it got it.
+func tryLock(file *os.File) (bool, error) {
+	// Lock a byte past any the PID is written to, since Windows locks
+	// are mandatory and would keep other processes from reading it
+	overlapped := &windows.Overlapped{Offset: 1 << 20}
+	err := windows.LockFileEx(windows.Handle(file.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, overlapped)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, windows.ERROR_LOCK_VIOLATION):
+		return false, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,164 @@
+package mastodon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// OutOfBandRedirectURI is the redirect URI for flows where the user copies
+// the authorization code by hand.
+const OutOfBandRedirectURI = "urn:ietf:wg:oauth:2.0:oob"
+
+// DefaultScopes are the scopes requested by default: enough to post
+// statuses with media and to look up the account, and nothing more.
+const DefaultScopes = "write:statuses write:media read:accounts"
+
+// Token is an access token issued by the OAuth token endpoint.
+type Token struct {
+	AccessToken string `json:"access_token"`
+	// Scope is the space-separated list of scopes actually granted.
+	Scope string `json:"scope"`
+}
+
+// AuthorizeURL returns the URL a user visits to authorize the application
+// with the given space-separated scopes.
+func AuthorizeURL(server, clientID, scopes string) (string, error) {
+	authURL, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL: %w", err)
+	}
+	authURL.Path = "/oauth/authorize"
+
+	params := url.Values{}
+	params.Set("client_id", clientID)
+	params.Set("scope", scopes)
+	params.Set("redirect_uri", OutOfBandRedirectURI)
+	params.Set("response_type", "code")
+	authURL.RawQuery = params.Encode()
+
+	return authURL.String(), nil
+}
+
+// ExchangeCode exchanges an authorization code for an access token.
+func ExchangeCode(ctx context.Context, server, clientID, clientSecret, code, scopes string) (*Token, error) {
+	tokenURL, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL: %w", err)
+	}
+	tokenURL.Path = "/oauth/token"
+
+	params := url.Values{}
+	params.Set("client_id", clientID)
+	params.Set("client_secret", clientSecret)
+	params.Set("grant_type", "authorization_code")
+	params.Set("code", code)
+	params.Set("redirect_uri", OutOfBandRedirectURI)
+	if scopes != "" {
+		params.Set("scope", scopes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to request access token: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var token Token
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("received empty access token")
+	}
+
+	return &token, nil
+}
+
+// RevokeToken revokes an access token using the OAuth revoke endpoint.
+// Revoking requires the client credentials the token was issued to.
+func RevokeToken(ctx context.Context, server, clientID, clientSecret, token string) error {
+	revokeURL, err := url.Parse(server)
+	if err != nil {
+		return fmt.Errorf("invalid server URL: %w", err)
+	}
+	revokeURL.Path = "/oauth/revoke"
+
+	params := url.Values{}
+	params.Set("client_id", clientID)
+	params.Set("client_secret", clientSecret)
+	params.Set("token", token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create revoke request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to revoke token: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// ScopesAllow reports whether the space-separated granted scopes include
+// scope, either itself or through its parent scope, like write for
+// write:statuses.
+func ScopesAllow(granted, scope string) bool {
+	parent, _, _ := strings.Cut(scope, ":")
+	for _, g := range strings.Fields(granted) {
+		if g == scope || g == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingScopes returns the needed scopes that the space-separated granted
+// scopes don't allow.
+func MissingScopes(granted string, needed []string) []string {
+	var missing []string
+	for _, scope := range needed {
+		if !ScopesAllow(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// ExtraScopes returns the space-separated granted scopes that aren't in
+// wanted, including parent scopes like write that give more than the
+// wanted write:statuses.
+func ExtraScopes(granted string, wanted []string) []string {
+	var extra []string
+	for _, scope := range strings.Fields(granted) {
+		if !slices.Contains(wanted, scope) {
+			extra = append(extra, scope)
+		}
+	}
+	return extra
+}
@@ -0,0 +1,158 @@
+package mastodon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestAuthorizeURL(t *testing.T) {
+	t.Run("includes configured scopes", func(t *testing.T) {
+		authURL, err := AuthorizeURL("https://mastodon.example", "client-id", "write:statuses write:media")
+		if err != nil {
+			t.Fatalf("AuthorizeURL() error = %v", err)
+		}
+
+		u, err := url.Parse(authURL)
+		if err != nil {
+			t.Fatalf("url.Parse() error = %v", err)
+		}
+		if u.Path != "/oauth/authorize" {
+			t.Errorf("path = %s, want /oauth/authorize", u.Path)
+		}
+		if got := u.Query().Get("scope"); got != "write:statuses write:media" {
+			t.Errorf("scope = %q", got)
+		}
+		if got := u.Query().Get("client_id"); got != "client-id" {
+			t.Errorf("client_id = %q", got)
+		}
+	})
+}
+
+func TestExchangeCode(t *testing.T) {
+	t.Run("returns token and granted scopes", func(t *testing.T) {
+		var gotCode string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			gotCode = r.PostForm.Get("code")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"new-token","token_type":"Bearer","scope":"write:statuses"}`))
+		}))
+		defer server.Close()
+
+		token, err := ExchangeCode(context.Background(), server.URL, "id", "secret", "auth-code", "write:statuses")
+		if err != nil {
+			t.Fatalf("ExchangeCode() error = %v", err)
+		}
+		if gotCode != "auth-code" {
+			t.Errorf("code = %q, want auth-code", gotCode)
+		}
+		if token.AccessToken != "new-token" {
+			t.Errorf("AccessToken = %q, want new-token", token.AccessToken)
+		}
+		if token.Scope != "write:statuses" {
+			t.Errorf("Scope = %q, want write:statuses", token.Scope)
+		}
+	})
+
+	t.Run("returns error on bad code", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		}))
+		defer server.Close()
+
+		if _, err := ExchangeCode(context.Background(), server.URL, "id", "secret", "bad", ""); err == nil {
+			t.Error("Expected error for bad code")
+		}
+	})
+}
+
+func TestRevokeToken(t *testing.T) {
+	t.Run("posts credentials to revoke endpoint", func(t *testing.T) {
+		var gotPath, gotToken, gotClientID string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("ParseForm() error = %v", err)
+			}
+			gotPath = r.URL.Path
+			gotToken = r.PostForm.Get("token")
+			gotClientID = r.PostForm.Get("client_id")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("{}"))
+		}))
+		defer server.Close()
+
+		err := RevokeToken(context.Background(), server.URL, "client-id", "client-secret", "the-token")
+		if err != nil {
+			t.Fatalf("RevokeToken() error = %v", err)
+		}
+
+		if gotPath != "/oauth/revoke" {
+			t.Errorf("path = %s, want /oauth/revoke", gotPath)
+		}
+		if gotToken != "the-token" {
+			t.Errorf("token = %s, want the-token", gotToken)
+		}
+		if gotClientID != "client-id" {
+			t.Errorf("client_id = %s, want client-id", gotClientID)
+		}
+	})
+
+	t.Run("returns error on failure response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":"unauthorized_client"}`))
+		}))
+		defer server.Close()
+
+		err := RevokeToken(context.Background(), server.URL, "client-id", "client-secret", "the-token")
+		if err == nil {
+			t.Error("Expected error for forbidden response")
+		}
+	})
+}
+
+func TestScopes(t *testing.T) {
+	tests := []struct {
+		name        string
+		granted     string
+		wantMissing []string
+		wantExtra   []string
+	}{
+		{
+			name:    "exactly the needed scopes",
+			granted: "write:statuses read:accounts",
+		},
+		{
+			name:      "parent scopes allow but give more",
+			granted:   "read write",
+			wantExtra: []string{"read", "write"},
+		},
+		{
+			name:        "missing a scope",
+			granted:     "write:media read:accounts follow",
+			wantMissing: []string{"write:statuses"},
+			wantExtra:   []string{"write:media", "follow"},
+		},
+	}
+
+	needed := []string{"write:statuses", "read:accounts"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MissingScopes(tt.granted, needed); !slices.Equal(got, tt.wantMissing) {
+				t.Errorf("MissingScopes() = %v, want %v", got, tt.wantMissing)
+			}
+			if got := ExtraScopes(tt.granted, needed); !slices.Equal(got, tt.wantExtra) {
+				t.Errorf("ExtraScopes() = %v, want %v", got, tt.wantExtra)
+			}
+		})
+	}
+
+	if ScopesAllow("write:media", "write:statuses") {
+		t.Error("ScopesAllow() = true for a sibling scope")
+	}
+}
@@ -0,0 +1,160 @@
+package mastodon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Flavor is the server software a Poster talks to. Servers other than
+// Mastodon implement its API with some differences.
+type Flavor string
+
+// Supported server flavors.
+const (
+	FlavorMastodon   Flavor = "mastodon"
+	FlavorGoToSocial Flavor = "gotosocial"
+	FlavorAkkoma     Flavor = "akkoma"
+	FlavorPleroma    Flavor = "pleroma"
+)
+
+// ParseFlavor returns the flavor named name.
+func ParseFlavor(name string) (Flavor, error) {
+	switch flavor := Flavor(strings.ToLower(name)); flavor {
+	case FlavorMastodon, FlavorGoToSocial, FlavorAkkoma, FlavorPleroma:
+		return flavor, nil
+	}
+	return "", fmt.Errorf("unknown server flavor: %s (must be mastodon, gotosocial, akkoma, or pleroma)", name)
+}
+
+// Visibilities returns the visibilities statuses can be posted with on
+// servers of this flavor. Akkoma and Pleroma add local, for posts that
+// aren't federated.
+func (f Flavor) Visibilities() []string {
+	visibilities := []string{"public", "unlisted", "private", "direct"}
+	if f == FlavorAkkoma || f == FlavorPleroma {
+		visibilities = append(visibilities, "local")
+	}
+	return visibilities
+}
+
+// validVisibility reports whether statuses can be posted with visibility
+// on servers of this flavor.
+func (f Flavor) validVisibility(visibility string) bool {
+	for _, v := range f.Visibilities() {
+		if v == visibility {
+			return true
+		}
+	}
+	return false
+}
+
+// visibilityError describes an unsupported visibility.
+func (f Flavor) visibilityError(visibility string) error {
+	return fmt.Errorf("invalid visibility: %s (must be one of %s on %s)", visibility, strings.Join(f.Visibilities(), ", "), f)
+}
+
+// idempotent reports whether servers of this flavor honor the
+// Idempotency-Key header, so status posts can be retried safely.
+// GoToSocial ignores it.
+func (f Flavor) idempotent() bool {
+	return f != FlavorGoToSocial
+}
+
+// flavorFromVersion recognizes servers that name their software in the
+// version they report in /api/v1/instance, like Akkoma's
+// "2.7.2 (compatible; Akkoma 3.13.2)". Returns "" for other servers.
+func flavorFromVersion(version string) Flavor {
+	version = strings.ToLower(version)
+	for _, flavor := range []Flavor{FlavorAkkoma, FlavorPleroma, FlavorGoToSocial} {
+		if strings.Contains(version, string(flavor)) {
+			return flavor
+		}
+	}
+	return ""
+}
+
+// flavorFromNodeInfo recognizes the server software from its NodeInfo
+// document, falling back to Mastodon for software it doesn't know.
+func flavorFromNodeInfo(ctx context.Context, server string) (Flavor, error) {
+	var index struct {
+		Links []struct {
+			Rel  string `json:"rel"`
+			Href string `json:"href"`
+		} `json:"links"`
+	}
+	if err := getJSON(ctx, strings.TrimRight(server, "/")+"/.well-known/nodeinfo", &index); err != nil {
+		return "", err
+	}
+	if len(index.Links) == 0 {
+		return "", fmt.Errorf("no NodeInfo documents listed")
+	}
+
+	// Prefer the newest schema version listed
+	sort.Slice(index.Links, func(i, j int) bool { return index.Links[i].Rel > index.Links[j].Rel })
+	var info struct {
+		Software struct {
+			Name string `json:"name"`
+		} `json:"software"`
+	}
+	if err := getJSON(ctx, index.Links[0].Href, &info); err != nil {
+		return "", err
+	}
+	if flavor, err := ParseFlavor(info.Software.Name); err == nil {
+		return flavor, nil
+	}
+	return FlavorMastodon, nil
+}
+
+// getJSON fetches url and decodes its JSON response into v.
+func getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// compatTransport smooths over differences in how servers of other flavors
+// implement the Mastodon API. Servers that don't have the v2 media
+// endpoint get uploads sent to the v1 endpoint instead.
+type compatTransport struct {
+	base   http.RoundTripper
+	flavor Flavor
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *compatTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.flavor == FlavorMastodon || req.Method != http.MethodPost || req.URL.Path != "/api/v2/media" {
+		return t.base.RoundTrip(req)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusNotFound || req.GetBody == nil {
+		return resp, err
+	}
+	discard(resp)
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	v1 := req.Clone(req.Context())
+	v1.URL.Path = "/api/v1/media"
+	v1.Body = body
+	return t.base.RoundTrip(v1)
+}
@@ -0,0 +1,165 @@
+package mastodon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/filter"
+)
+
+func TestDetectInstanceLimits_Flavor(t *testing.T) {
+	t.Run("recognizes Akkoma from its version", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/instance" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{
+				"uri": "akkoma.example",
+				"version": "2.7.2 (compatible; Akkoma 3.13.2)",
+				"max_toot_chars": 8000,
+				"upload_limit": 16000000
+			}`))
+		}))
+		defer server.Close()
+
+		limits, err := DetectInstanceLimits(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("DetectInstanceLimits() error = %v", err)
+		}
+		if limits.Flavor != FlavorAkkoma {
+			t.Errorf("Flavor = %q, want akkoma", limits.Flavor)
+		}
+		if limits.MaxCharacters != 8000 || limits.ImageSizeLimit != 16000000 {
+			t.Errorf("limits = %+v, want max_toot_chars and upload_limit", limits)
+		}
+	})
+
+	t.Run("recognizes GoToSocial from NodeInfo", func(t *testing.T) {
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/instance":
+				_, _ = w.Write([]byte(`{"uri": "gts.example", "version": "0.17.3+git-2b8a8e3"}`))
+			case "/.well-known/nodeinfo":
+				_, _ = w.Write([]byte(`{"links": [
+					{"rel": "http://nodeinfo.diaspora.software/ns/schema/2.0", "href": "` + server.URL + `/nodeinfo/2.0"},
+					{"rel": "http://nodeinfo.diaspora.software/ns/schema/2.1", "href": "` + server.URL + `/nodeinfo/2.1"}
+				]}`))
+			case "/nodeinfo/2.1":
+				_, _ = w.Write([]byte(`{"software": {"name": "gotosocial"}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		limits, err := DetectInstanceLimits(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("DetectInstanceLimits() error = %v", err)
+		}
+		if limits.Flavor != FlavorGoToSocial {
+			t.Errorf("Flavor = %q, want gotosocial", limits.Flavor)
+		}
+	})
+
+	t.Run("assumes Mastodon without NodeInfo", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/instance" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"uri": "example.social", "version": "4.3.0"}`))
+		}))
+		defer server.Close()
+
+		limits, err := DetectInstanceLimits(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("DetectInstanceLimits() error = %v", err)
+		}
+		if limits.Flavor != FlavorMastodon {
+			t.Errorf("Flavor = %q, want mastodon", limits.Flavor)
+		}
+	})
+}
+
+func TestNewWithFlavor_Visibility(t *testing.T) {
+	if _, err := New("https://mastodon.example", "token", "local", ""); err == nil {
+		t.Error("New() with local visibility succeeded, want error on Mastodon")
+	}
+
+	poster, err := NewWithFlavor("https://akkoma.example", "token", "local", "", FlavorAkkoma)
+	if err != nil {
+		t.Fatalf("NewWithFlavor() error = %v", err)
+	}
+	if err := poster.AddVisibilityRule(&filter.Filter{}, "local"); err != nil {
+		t.Errorf("AddVisibilityRule() error = %v", err)
+	}
+
+	if _, err := NewWithFlavor("https://gts.example", "token", "local", "", FlavorGoToSocial); err == nil {
+		t.Error("NewWithFlavor() with local visibility succeeded, want error on GoToSocial")
+	}
+}
+
+func TestNewWithFlavor_GoToSocialPostsAreNotRetried(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			t.Errorf("Idempotency-Key = %q, want none", key)
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	poster, err := NewWithFlavor(server.URL, "token", "public", "", FlavorGoToSocial)
+	if err != nil {
+		t.Fatalf("NewWithFlavor() error = %v", err)
+	}
+	poster.limits.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	if _, err := poster.Post("Hello", false); err == nil {
+		t.Fatal("Post() succeeded, want error")
+	}
+	if requests != 1 {
+		t.Errorf("server received %d requests, want 1", requests)
+	}
+}
+
+func TestCompatTransport_MediaFallback(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/api/v1/media" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "7", "type": "image"}`))
+	}))
+	defer server.Close()
+
+	for _, flavor := range []Flavor{FlavorMastodon, FlavorPleroma} {
+		paths = nil
+		poster, err := NewWithFlavor(server.URL, "token", "public", "", flavor)
+		if err != nil {
+			t.Fatalf("NewWithFlavor() error = %v", err)
+		}
+
+		_, err = poster.client.UploadMediaFromBytes(context.Background(), []byte("GIF89a"))
+		if flavor == FlavorMastodon {
+			if err == nil || len(paths) != 1 {
+				t.Errorf("%s upload = %v to %v, want only a failed v2 upload", flavor, err, paths)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s upload error = %v", flavor, err)
+		}
+		if len(paths) != 2 || paths[1] != "/api/v1/media" {
+			t.Errorf("%s upload paths = %v, want v2 then v1", flavor, paths)
+		}
+	}
+}
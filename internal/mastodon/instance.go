@@ -0,0 +1,101 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	mastodon "github.com/mattn/go-mastodon"
+	"github.com/sirupsen/logrus"
+)
+
+// InstanceLimits describes the posting limits advertised by a server.
+// Zero values mean the server didn't report that limit.
+type InstanceLimits struct {
+	Flavor              Flavor
+	MaxCharacters       int
+	MaxMediaAttachments int
+	ImageSizeLimit      int64
+	VideoSizeLimit      int64
+	ImageMatrixLimit    int
+	SupportedMimeTypes  []string
+}
+
+// DetectInstanceLimits queries the server's /api/v1/instance endpoint for
+// its character and media limits, and recognizes its flavor. No access
+// token is needed.
+func DetectInstanceLimits(ctx context.Context, server string) (*InstanceLimits, error) {
+	// Akkoma and Pleroma report their limits outside configuration
+	var instance struct {
+		mastodon.Instance
+		MaxTootChars int   `json:"max_toot_chars"`
+		UploadLimit  int64 `json:"upload_limit"`
+	}
+	if err := getJSON(ctx, strings.TrimRight(server, "/")+"/api/v1/instance", &instance); err != nil {
+		return nil, fmt.Errorf("failed to get instance information: %w", err)
+	}
+
+	limits := &InstanceLimits{
+		Flavor:         flavorFromVersion(instance.Version),
+		MaxCharacters:  instance.MaxTootChars,
+		ImageSizeLimit: instance.UploadLimit,
+	}
+	if limits.Flavor == "" {
+		flavor, err := flavorFromNodeInfo(ctx, server)
+		if err != nil {
+			logrus.Debugf("Failed to read NodeInfo, assuming Mastodon: %v", err)
+			flavor = FlavorMastodon
+		}
+		limits.Flavor = flavor
+	}
+
+	config := instance.Configuration
+	if config == nil {
+		return limits, nil
+	}
+
+	if config.Statuses != nil {
+		if maxCharacters := int(number((*config.Statuses)["max_characters"])); maxCharacters > 0 {
+			limits.MaxCharacters = maxCharacters
+		}
+		limits.MaxMediaAttachments = int(number((*config.Statuses)["max_media_attachments"]))
+	}
+
+	media := config.MediaAttachments
+	if imageSizeLimit := int64(number(media["image_size_limit"])); imageSizeLimit > 0 {
+		limits.ImageSizeLimit = imageSizeLimit
+	}
+	limits.ImageMatrixLimit = int(number(media["image_matrix_limit"]))
+	if videoSizeLimit := int64(number(media["video_size_limit"])); videoSizeLimit > 0 {
+		limits.VideoSizeLimit = videoSizeLimit
+	}
+	if types, ok := media["supported_mime_types"].([]interface{}); ok {
+		for _, t := range types {
+			if s, ok := t.(string); ok {
+				limits.SupportedMimeTypes = append(limits.SupportedMimeTypes, s)
+			}
+		}
+	}
+
+	return limits, nil
+}
+
+// number converts a decoded JSON number to float64, or 0 if v isn't one.
+func number(v interface{}) float64 {
+	n, _ := v.(float64)
+	return n
+}
+
+// CustomEmoji returns the shortcodes of the server's custom emoji, without
+// colons. No access token is needed.
+func CustomEmoji(ctx context.Context, server string) ([]string, error) {
+	var emojis []mastodon.Emoji
+	if err := getJSON(ctx, strings.TrimRight(server, "/")+"/api/v1/custom_emojis", &emojis); err != nil {
+		return nil, fmt.Errorf("failed to get custom emoji: %w", err)
+	}
+	shortcodes := make([]string, 0, len(emojis))
+	for _, emoji := range emojis {
+		shortcodes = append(shortcodes, emoji.ShortCode)
+	}
+	return shortcodes, nil
+}
@@ -0,0 +1,103 @@
+package mastodon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectInstanceLimits(t *testing.T) {
+	t.Run("reads limits from configuration", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/instance" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{
+				"uri": "example.social",
+				"configuration": {
+					"statuses": {"max_characters": 1000, "max_media_attachments": 4, "characters_reserved_per_url": 23},
+					"media_attachments": {
+						"image_size_limit": 16777216,
+						"video_size_limit": 103809024,
+						"image_matrix_limit": 33177600,
+						"supported_mime_types": ["image/jpeg", "image/png"]
+					}
+				}
+			}`))
+		}))
+		defer server.Close()
+
+		limits, err := DetectInstanceLimits(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("DetectInstanceLimits() error = %v", err)
+		}
+		if limits.MaxCharacters != 1000 {
+			t.Errorf("MaxCharacters = %d, want 1000", limits.MaxCharacters)
+		}
+		if limits.MaxMediaAttachments != 4 {
+			t.Errorf("MaxMediaAttachments = %d, want 4", limits.MaxMediaAttachments)
+		}
+		if limits.ImageSizeLimit != 16777216 {
+			t.Errorf("ImageSizeLimit = %d, want 16777216", limits.ImageSizeLimit)
+		}
+		if limits.ImageMatrixLimit != 33177600 {
+			t.Errorf("ImageMatrixLimit = %d, want 33177600", limits.ImageMatrixLimit)
+		}
+		if limits.VideoSizeLimit != 103809024 {
+			t.Errorf("VideoSizeLimit = %d, want 103809024", limits.VideoSizeLimit)
+		}
+		if len(limits.SupportedMimeTypes) != 2 || limits.SupportedMimeTypes[1] != "image/png" {
+			t.Errorf("SupportedMimeTypes = %v", limits.SupportedMimeTypes)
+		}
+	})
+
+	t.Run("missing configuration yields zero limits", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"uri": "old.example"}`))
+		}))
+		defer server.Close()
+
+		limits, err := DetectInstanceLimits(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("DetectInstanceLimits() error = %v", err)
+		}
+		if limits.MaxCharacters != 0 || limits.ImageSizeLimit != 0 {
+			t.Errorf("limits = %+v, want zero", limits)
+		}
+	})
+
+	t.Run("reports server errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		if _, err := DetectInstanceLimits(context.Background(), server.URL); err == nil {
+			t.Error("Expected error for server error")
+		}
+	})
+}
+
+func TestCustomEmoji(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/custom_emojis" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"shortcode": "blobcat", "url": "https://example.social/blobcat.png", "visible_in_picker": true},
+			{"shortcode": "rss", "url": "https://example.social/rss.png", "visible_in_picker": false}
+		]`))
+	}))
+	defer server.Close()
+
+	shortcodes, err := CustomEmoji(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("CustomEmoji() error = %v", err)
+	}
+	if len(shortcodes) != 2 || shortcodes[0] != "blobcat" || shortcodes[1] != "rss" {
+		t.Errorf("CustomEmoji() = %v, want blobcat and rss", shortcodes)
+	}
+}
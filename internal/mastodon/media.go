@@ -0,0 +1,532 @@
+package mastodon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/filter"
+	"github.com/lorchard/feed-to-mastodon/internal/imaging"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+	mastodon "github.com/mattn/go-mastodon"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+	"github.com/sirupsen/logrus"
+)
+
+// maxAttachments is the number of media attachments Mastodon allows per status.
+const maxAttachments = 4
+
+// mediaDownloadTimeout bounds how long a single media download may take.
+const mediaDownloadTimeout = 30 * time.Second
+
+// avDownloadTimeout bounds how long downloading an audio or video file,
+// much larger than an image, may take.
+const avDownloadTimeout = 5 * time.Minute
+
+// mediaPollInterval is how often to check whether the server has finished
+// processing an uploaded audio or video file.
+const mediaPollInterval = 2 * time.Second
+
+// mediaProcessingTimeout bounds how long to wait for the server to process
+// an uploaded audio or video file before posting without it.
+const mediaProcessingTimeout = 3 * time.Minute
+
+// maxSourceImageBytes is the largest image downloaded to be downscaled to
+// the size limit.
+const maxSourceImageBytes = 32 << 20
+
+// MediaCache remembers uploaded media by a hash of its contents, so media
+// uploaded for an entry that then failed to post isn't uploaded again when
+// it's retried. *database.DB implements it.
+type MediaCache interface {
+	GetUploadedMedia(hash string) (string, error)
+	SaveUploadedMedia(hash, mediaID string) error
+	ForgetUploadedMedia(mediaID string) error
+}
+
+// mediaSource is an image, audio, or video file referenced by a feed item.
+// Type is the media type the feed declares, if any.
+type mediaSource struct {
+	URL         string
+	Description string
+	Type        string
+}
+
+// EnableMedia turns on uploading image enclosures and media:content as
+// attachments. Images larger than maxBytes are skipped.
+func (p *Poster) EnableMedia(maxBytes int64) {
+	p.mediaMaxBytes = maxBytes
+	if p.httpClient == nil {
+		p.httpClient = &http.Client{Timeout: mediaDownloadTimeout}
+	}
+}
+
+// EnableAudioVideo turns on uploading an entry's audio or video enclosure
+// or media:content, like a podcast episode, as its attachment in place of
+// its images. Files larger than maxBytes are skipped. It has no effect
+// unless media is enabled.
+func (p *Poster) EnableAudioVideo(maxBytes int64) {
+	p.avMaxBytes = maxBytes
+	if p.avClient == nil {
+		p.avClient = &http.Client{Timeout: avDownloadTimeout}
+	}
+}
+
+// SetImageProcessing sets how images are prepared before they're
+// uploaded: downscaled to fit the limits, instead of skipped, and stripped
+// of metadata. The size limit is the one given to EnableMedia.
+func (p *Poster) SetImageProcessing(opts imaging.Options) {
+	p.imageOptions = opts
+}
+
+// SetMediaFocus sets the focal point of uploaded images, as "x,y" with
+// both from -1.0 to 1.0, which servers keep in view when cropping
+// previews. An empty focus leaves it centered.
+func (p *Poster) SetMediaFocus(focus string) {
+	p.mediaFocus = focus
+}
+
+// SetMediaCache makes uploads reuse identical media uploaded earlier that
+// hasn't been attached to a status yet.
+func (p *Poster) SetMediaCache(cache MediaCache) {
+	p.mediaCache = cache
+}
+
+// SetFirstImageMedia turns on attaching the first image embedded in the
+// HTML of entries that have no image enclosures or media:content.
+func (p *Poster) SetFirstImageMedia(enabled bool) {
+	p.firstImage = enabled
+}
+
+// SetSensitiveMedia marks the media of entries that pass match as
+// sensitive. An empty filter marks every entry's media, and nil none.
+func (p *Poster) SetSensitiveMedia(match *filter.Filter) {
+	p.sensitiveMedia = match
+}
+
+// SetSupportedMimeTypes limits uploads to the given media types, as
+// reported by the server. An empty list allows any image type.
+func (p *Poster) SetSupportedMimeTypes(types []string) {
+	p.mimeTypes = types
+}
+
+// entryMedia returns the images referenced by an entry's enclosures and
+// media:content elements, up to the attachment limit. An image listed as
+// both takes its description from the media:content element. If there are
+// none and firstImage is set, the first image embedded in the entry's HTML
+// is used, described by its alt text.
+func entryMedia(entryJSON []byte, firstImage bool) []mediaSource {
+	var item gofeed.Item
+	if err := json.Unmarshal(entryJSON, &item); err != nil {
+		return nil
+	}
+
+	var sources []mediaSource
+	seen := map[string]int{}
+	add := func(url, description string) {
+		if i, ok := seen[url]; ok {
+			if sources[i].Description == "" {
+				sources[i].Description = description
+			}
+			return
+		}
+		if url == "" || len(sources) >= maxAttachments {
+			return
+		}
+		seen[url] = len(sources)
+		sources = append(sources, mediaSource{URL: url, Description: description})
+	}
+
+	for _, enclosure := range item.Enclosures {
+		if enclosure != nil && strings.HasPrefix(enclosure.Type, "image/") {
+			add(enclosure.URL, "")
+		}
+	}
+
+	// Images in a media:group share the group's description
+	media := item.Extensions["media"]
+	addContent := func(content ext.Extension, groupDescription string) {
+		if !isImageContent(content) {
+			return
+		}
+		description := mediaDescription(content)
+		if description == "" {
+			description = groupDescription
+		}
+		add(content.Attrs["url"], description)
+	}
+	for _, content := range media["content"] {
+		addContent(content, "")
+	}
+	for _, group := range media["group"] {
+		for _, content := range group.Children["content"] {
+			addContent(content, mediaDescription(group))
+		}
+	}
+
+	if len(sources) == 0 && firstImage {
+		add(template.FirstImage(&item))
+	}
+
+	return sources
+}
+
+// entryAudioVideo returns the first audio or video file referenced by an
+// entry's enclosures or media:content elements, if it has one.
+func entryAudioVideo(entryJSON []byte) (mediaSource, bool) {
+	var item gofeed.Item
+	if err := json.Unmarshal(entryJSON, &item); err != nil {
+		return mediaSource{}, false
+	}
+
+	for _, enclosure := range item.Enclosures {
+		if enclosure != nil && enclosure.URL != "" && isAudioVideoType(enclosure.Type) {
+			return mediaSource{URL: enclosure.URL, Type: enclosure.Type}, true
+		}
+	}
+
+	media := item.Extensions["media"]
+	contents := append([]ext.Extension{}, media["content"]...)
+	for _, group := range media["group"] {
+		contents = append(contents, group.Children["content"]...)
+	}
+	for _, content := range contents {
+		medium := content.Attrs["medium"]
+		if content.Attrs["url"] != "" && (medium == "audio" || medium == "video" || isAudioVideoType(content.Attrs["type"])) {
+			return mediaSource{
+				URL:         content.Attrs["url"],
+				Description: mediaDescription(content),
+				Type:        content.Attrs["type"],
+			}, true
+		}
+	}
+	return mediaSource{}, false
+}
+
+// isAudioVideoType reports whether a media type is audio or video.
+func isAudioVideoType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "audio/") || strings.HasPrefix(mediaType, "video/")
+}
+
+// isImageContent reports whether a media:content element is an image.
+func isImageContent(content ext.Extension) bool {
+	return content.Attrs["medium"] == "image" || strings.HasPrefix(content.Attrs["type"], "image/")
+}
+
+// mediaDescription returns the media:description of a media:content or
+// media:group element, or else its media:title, for use as alt text.
+func mediaDescription(content ext.Extension) string {
+	for _, name := range []string{"description", "title"} {
+		for _, child := range content.Children[name] {
+			if text := strings.TrimSpace(child.Value); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// attachMedia downloads and uploads the entry's audio or video file, with
+// EnableAudioVideo, or else its images, adding them to the toot and
+// recording them on the entry. Media the feed doesn't describe gets the
+// entry's alt text, and is marked sensitive if the entry matches the
+// sensitive media rule. Media that fails to download or upload
+// is skipped so the entry is still posted.
+func (p *Poster) attachMedia(ctx context.Context, toot *mastodon.Toot, entry *database.Entry, dryRun bool) {
+	if p.mediaMaxBytes <= 0 {
+		return
+	}
+
+	// Servers allow one audio or video file per status, without images
+	if !p.attachAudioVideo(ctx, toot, entry, dryRun) {
+		p.attachImages(ctx, toot, entry, dryRun)
+	}
+
+	if len(toot.MediaIDs) > 0 && p.sensitiveMedia != nil {
+		if ok, _, err := p.sensitiveMedia.CheckEntry(entry.EntryData); err == nil && ok {
+			toot.Sensitive = true
+		}
+	}
+}
+
+// attachImages downloads and uploads the entry's images, adding them to
+// the toot and recording them on the entry.
+func (p *Poster) attachImages(ctx context.Context, toot *mastodon.Toot, entry *database.Entry, dryRun bool) {
+	for _, source := range entryMedia(entry.EntryData, p.firstImage) {
+		if dryRun {
+			logrus.Infof("DRY RUN: Would attach %s", source.URL)
+			continue
+		}
+
+		data, contentType, err := p.downloadMedia(ctx, source.URL)
+		if err != nil {
+			logrus.Warnf("Skipping media %s for entry %s: %v", source.URL, entry.ID, err)
+			continue
+		}
+
+		description := source.Description
+		if description == "" {
+			description = entry.AltText
+		}
+		id, err := p.uploadMedia(ctx, data, description, p.mediaFocus, false)
+		if err != nil {
+			logrus.Warnf("Failed to upload media %s for entry %s: %v", source.URL, entry.ID, err)
+			continue
+		}
+
+		toot.MediaIDs = append(toot.MediaIDs, id)
+		entry.Attachments = append(entry.Attachments, database.Attachment{
+			EntryID:     entry.ID,
+			SourceURL:   source.URL,
+			MediaID:     string(id),
+			ContentType: contentType,
+			Size:        int64(len(data)),
+		})
+		logrus.Debugf("Uploaded media %s as %s", source.URL, id)
+	}
+}
+
+// attachAudioVideo downloads and uploads the entry's audio or video file,
+// if it has one and audio and video are enabled, waiting for the server to
+// process it. It reports whether the file was attached; if it fails, the
+// entry's images are attached instead.
+func (p *Poster) attachAudioVideo(ctx context.Context, toot *mastodon.Toot, entry *database.Entry, dryRun bool) bool {
+	if p.avMaxBytes <= 0 {
+		return false
+	}
+	source, ok := entryAudioVideo(entry.EntryData)
+	if !ok {
+		return false
+	}
+	if dryRun {
+		logrus.Infof("DRY RUN: Would attach %s", source.URL)
+		return true
+	}
+
+	data, contentType, err := p.downloadAudioVideo(ctx, source)
+	if err != nil {
+		logrus.Warnf("Skipping media %s for entry %s: %v", source.URL, entry.ID, err)
+		return false
+	}
+
+	description := source.Description
+	if description == "" {
+		description = entry.AltText
+	}
+	id, err := p.uploadMedia(ctx, data, description, "", true)
+	if err != nil {
+		logrus.Warnf("Failed to upload media %s for entry %s: %v", source.URL, entry.ID, err)
+		return false
+	}
+
+	toot.MediaIDs = append(toot.MediaIDs, id)
+	entry.Attachments = append(entry.Attachments, database.Attachment{
+		EntryID:     entry.ID,
+		SourceURL:   source.URL,
+		MediaID:     string(id),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	})
+	logrus.Debugf("Uploaded media %s as %s", source.URL, id)
+	return true
+}
+
+// uploadMedia uploads data with a description and focal point, returning
+// its media ID. With wait, it waits for the server to finish processing
+// the file, as audio and video need. Identical media uploaded earlier and
+// not attached to a status yet is reused instead, if there's a cache.
+func (p *Poster) uploadMedia(ctx context.Context, data []byte, description, focus string, wait bool) (mastodon.ID, error) {
+	// Media belongs to the account that uploaded it
+	hash := sha256.New()
+	for _, part := range []string{p.client.Config.Server, p.client.Config.AccessToken, description, focus} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(data)
+	key := hex.EncodeToString(hash.Sum(nil))
+
+	if p.mediaCache != nil {
+		id, err := p.mediaCache.GetUploadedMedia(key)
+		if err != nil {
+			logrus.Warnf("Failed to look up uploaded media: %v", err)
+		} else if id != "" && !p.usedMedia[mastodon.ID(id)] {
+			logrus.Debugf("Reusing uploaded media %s", id)
+			return mastodon.ID(id), nil
+		}
+	}
+
+	attachment, err := p.client.UploadMediaFromMedia(ctx, &mastodon.Media{
+		File:        bytes.NewReader(data),
+		Description: description,
+		Focus:       focus,
+	})
+	if err != nil {
+		return "", err
+	}
+	if wait && attachment.URL == "" {
+		if err := p.waitForMedia(ctx, attachment.ID); err != nil {
+			return "", fmt.Errorf("failed to process media %s: %w", attachment.ID, err)
+		}
+	}
+
+	if p.mediaCache != nil {
+		if err := p.mediaCache.SaveUploadedMedia(key, string(attachment.ID)); err != nil {
+			logrus.Warnf("Failed to remember uploaded media: %v", err)
+		}
+	}
+	return attachment.ID, nil
+}
+
+// forgetMedia stops media attached to a posted status from being reused,
+// both by this Poster and, through the cache, by later runs.
+func (p *Poster) forgetMedia(ids []mastodon.ID) {
+	for _, id := range ids {
+		if p.usedMedia == nil {
+			p.usedMedia = map[mastodon.ID]bool{}
+		}
+		p.usedMedia[id] = true
+		if p.mediaCache != nil {
+			if err := p.mediaCache.ForgetUploadedMedia(string(id)); err != nil {
+				logrus.Warnf("Failed to forget uploaded media %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// waitForMedia polls the server until it has finished processing an
+// uploaded file, which it reports with a 200 response rather than a 206,
+// giving up after mediaProcessingTimeout.
+func (p *Poster) waitForMedia(ctx context.Context, id mastodon.ID) error {
+	ctx, cancel := context.WithTimeout(ctx, mediaProcessingTimeout)
+	defer cancel()
+
+	endpoint := strings.TrimRight(p.client.Config.Server, "/") + "/api/v1/media/" + string(id)
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+p.client.Config.AccessToken)
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return err
+		}
+		discard(resp)
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return nil
+		case http.StatusPartialContent:
+			logrus.Debugf("Waiting for the server to process media %s", id)
+		default:
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+
+		if err := p.sleep(ctx, mediaPollInterval); err != nil {
+			return fmt.Errorf("still processing after %s", mediaProcessingTimeout)
+		}
+	}
+}
+
+// downloadMedia fetches an image, enforcing the size limit, and prepares
+// it as set by SetImageProcessing. Images to be downscaled may be larger
+// to begin with.
+func (p *Poster) downloadMedia(ctx context.Context, url string) ([]byte, string, error) {
+	limit := p.mediaMaxBytes
+	if p.imageOptions.Downscale {
+		limit = max(limit, maxSourceImageBytes)
+	}
+	data, _, err := fetchMedia(ctx, p.httpClient, url, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("not an image (%s)", contentType)
+	}
+	if p.imageOptions.Enabled() {
+		opts := p.imageOptions
+		opts.MaxBytes = p.mediaMaxBytes
+		if data, contentType, err = imaging.Prepare(data, opts); err != nil {
+			return nil, "", err
+		}
+	}
+	if int64(len(data)) > p.mediaMaxBytes {
+		return nil, "", fmt.Errorf("size %d exceeds limit of %d bytes", len(data), p.mediaMaxBytes)
+	}
+	if len(p.mimeTypes) > 0 && !slices.Contains(p.mimeTypes, contentType) {
+		return nil, "", fmt.Errorf("%s is not supported by the server", contentType)
+	}
+
+	return data, contentType, nil
+}
+
+// downloadAudioVideo fetches an audio or video file, enforcing its size
+// limit. Formats that can't be recognized from their contents, like M4A,
+// take their type from the response or else the feed.
+func (p *Poster) downloadAudioVideo(ctx context.Context, source mediaSource) ([]byte, string, error) {
+	data, header, err := fetchMedia(ctx, p.avClient, source.URL, p.avMaxBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := ""
+	for _, candidate := range []string{http.DetectContentType(data), header, source.Type} {
+		if mediaType, _, err := mime.ParseMediaType(candidate); err == nil && isAudioVideoType(mediaType) {
+			contentType = mediaType
+			break
+		}
+	}
+	if contentType == "" {
+		return nil, "", fmt.Errorf("not audio or video (%s)", http.DetectContentType(data))
+	}
+	if len(p.mimeTypes) > 0 && !slices.Contains(p.mimeTypes, contentType) {
+		return nil, "", fmt.Errorf("%s is not supported by the server", contentType)
+	}
+
+	return data, contentType, nil
+}
+
+// fetchMedia downloads url with client, failing if it's larger than
+// maxBytes. It returns the data and the response's Content-Type.
+func fetchMedia(ctx context.Context, client *http.Client, url string, maxBytes int64) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("size %d exceeds limit of %d bytes", resp.ContentLength, maxBytes)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("size exceeds limit of %d bytes", maxBytes)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,516 @@
+package mastodon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/filter"
+	"github.com/lorchard/feed-to-mastodon/internal/imaging"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// pngHeader is enough of a PNG file for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestEntryMedia(t *testing.T) {
+	item := &gofeed.Item{
+		Title: "Photos",
+		Enclosures: []*gofeed.Enclosure{
+			{URL: "https://example.com/a.jpg", Type: "image/jpeg"},
+			{URL: "https://example.com/episode.mp3", Type: "audio/mpeg"},
+		},
+		Extensions: ext.Extensions{
+			"media": {
+				"content": {
+					{Name: "content", Attrs: map[string]string{"url": "https://example.com/b.png", "medium": "image"},
+						Children: map[string][]ext.Extension{"description": {{Value: "A cat"}}}},
+					{Name: "content", Attrs: map[string]string{"url": "https://example.com/a.jpg", "type": "image/jpeg"},
+						Children: map[string][]ext.Extension{"title": {{Value: "A dog"}}}},
+					{Name: "content", Attrs: map[string]string{"url": "https://example.com/v.mp4", "medium": "video"}},
+				},
+				"group": {
+					{Name: "group", Children: map[string][]ext.Extension{
+						"content":     {{Name: "content", Attrs: map[string]string{"url": "https://example.com/c.gif", "type": "image/gif"}}},
+						"description": {{Value: "A bird"}},
+					}},
+				},
+			},
+		},
+	}
+	itemJSON, err := json.Marshal(item)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	sources := entryMedia(itemJSON, true)
+	want := []mediaSource{
+		{URL: "https://example.com/a.jpg", Description: "A dog"},
+		{URL: "https://example.com/b.png", Description: "A cat"},
+		{URL: "https://example.com/c.gif", Description: "A bird"},
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("entryMedia() = %+v, want %+v", sources, want)
+	}
+	for i := range want {
+		if sources[i] != want[i] {
+			t.Errorf("entryMedia()[%d] = %+v, want %+v", i, sources[i], want[i])
+		}
+	}
+}
+
+func TestEntryMedia_FirstImage(t *testing.T) {
+	item := &gofeed.Item{
+		Link:        "https://example.com/posts/1",
+		Description: `<p>Look</p><img src="/img/a.jpg" alt="A dog"><img src="/img/b.jpg">`,
+	}
+	itemJSON, err := json.Marshal(item)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	if sources := entryMedia(itemJSON, false); len(sources) != 0 {
+		t.Errorf("entryMedia() = %+v without firstImage, want none", sources)
+	}
+	sources := entryMedia(itemJSON, true)
+	want := mediaSource{URL: "https://example.com/img/a.jpg", Description: "A dog"}
+	if len(sources) != 1 || sources[0] != want {
+		t.Errorf("entryMedia() = %+v, want %+v", sources, []mediaSource{want})
+	}
+
+	// Enclosures take precedence over embedded images
+	item.Enclosures = []*gofeed.Enclosure{{URL: "https://example.com/e.jpg", Type: "image/jpeg"}}
+	itemJSON, _ = json.Marshal(item)
+	if sources := entryMedia(itemJSON, true); len(sources) != 1 || sources[0].URL != "https://example.com/e.jpg" {
+		t.Errorf("entryMedia() = %+v, want only the enclosure", sources)
+	}
+}
+
+func TestPostEntries_Media(t *testing.T) {
+	var postedMediaIDs []string
+	uploads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.png":
+			_, _ = w.Write(pngHeader)
+		case "/large.png":
+			_, _ = w.Write(append(pngHeader, make([]byte, 1024)...))
+		case "/page.png":
+			_, _ = w.Write([]byte("<html><body>not an image</body></html>"))
+		case "/api/v2/media":
+			uploads++
+			_, _ = w.Write([]byte(`{"id":"501","type":"image"}`))
+		case "/api/v1/statuses":
+			_ = r.ParseForm()
+			postedMediaIDs = r.PostForm["media_ids[]"]
+			_, _ = w.Write([]byte(`{"id":"100","url":"https://mastodon.example/@me/100"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var enclosures []*gofeed.Enclosure
+	for _, name := range []string{"small.png", "large.png", "page.png", "missing.png"} {
+		enclosures = append(enclosures, &gofeed.Enclosure{URL: server.URL + "/" + name, Type: "image/png"})
+	}
+	itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Photo", Enclosures: enclosures})
+	entries := []*database.Entry{{ID: "entry-1", EntryData: itemJSON}}
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	poster.EnableMedia(512)
+
+	results, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false)
+	count := destination.CountPosted(results)
+	if err != nil || count != 1 {
+		t.Fatalf("PostEntries() = %d, %v; want 1, nil", count, err)
+	}
+
+	if uploads != 1 {
+		t.Errorf("uploaded %d files, want 1", uploads)
+	}
+	if strings.Join(postedMediaIDs, ",") != "501" {
+		t.Errorf("media_ids = %v, want [501]", postedMediaIDs)
+	}
+	if len(entries[0].Attachments) != 1 {
+		t.Fatalf("entry has %d attachments, want 1", len(entries[0].Attachments))
+	}
+	attachment := entries[0].Attachments[0]
+	if attachment.MediaID != "501" || attachment.SourceURL != server.URL+"/small.png" || attachment.ContentType != "image/png" {
+		t.Errorf("unexpected attachment: %+v", attachment)
+	}
+}
+
+func TestDownloadMedia_SupportedMimeTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(pngHeader)
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	poster.EnableMedia(1024)
+
+	poster.SetSupportedMimeTypes([]string{"image/jpeg"})
+	if _, _, err := poster.downloadMedia(context.Background(), server.URL+"/a.png"); err == nil {
+		t.Error("Expected error for unsupported media type")
+	}
+
+	poster.SetSupportedMimeTypes([]string{"image/jpeg", "image/png"})
+	if _, _, err := poster.downloadMedia(context.Background(), server.URL+"/a.png"); err != nil {
+		t.Errorf("downloadMedia() error = %v", err)
+	}
+}
+
+func TestPostEntries_MediaDisabled(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"id":"100","url":"https://mastodon.example/@me/100"}`))
+	}))
+	defer server.Close()
+
+	itemJSON, _ := json.Marshal(&gofeed.Item{
+		Title:      "Photo",
+		Enclosures: []*gofeed.Enclosure{{URL: server.URL + "/a.png", Type: "image/png"}},
+	})
+	entries := []*database.Entry{{ID: "entry-1", EntryData: itemJSON}}
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false); err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("server received %d requests, want 1 (status only)", requests)
+	}
+}
+
+func TestPostEntries_MediaAltTextAndSensitive(t *testing.T) {
+	var descriptions []string
+	var sensitive []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.png":
+			_, _ = w.Write(pngHeader)
+		case "/api/v2/media":
+			_ = r.ParseMultipartForm(1024)
+			descriptions = append(descriptions, r.FormValue("description"))
+			_, _ = w.Write([]byte(`{"id":"501","type":"image"}`))
+		case "/api/v1/statuses":
+			_ = r.ParseForm()
+			sensitive = append(sensitive, r.PostForm.Get("sensitive"))
+			_, _ = w.Write([]byte(`{"id":"100"}`))
+		}
+	}))
+	defer server.Close()
+
+	var entries []*database.Entry
+	for i, category := range []string{"nsfw", "cats"} {
+		itemJSON, _ := json.Marshal(&gofeed.Item{
+			Title:      "Photo " + category,
+			Categories: []string{category},
+			Enclosures: []*gofeed.Enclosure{{URL: server.URL + "/photo.png", Type: "image/png"}},
+		})
+		entries = append(entries, &database.Entry{ID: fmt.Sprintf("entry-%d", i), EntryData: itemJSON})
+	}
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	poster.EnableMedia(1024)
+	match, err := filter.New("", "", []string{"NSFW"}, nil)
+	if err != nil {
+		t.Fatalf("filter.New() error = %v", err)
+	}
+	poster.SetSensitiveMedia(match)
+
+	renderer := newTestRenderer(t, "{{.Item.Title}}")
+	if err := renderer.SetAltTextTemplate("Image for {{.Item.Title}}"); err != nil {
+		t.Fatalf("SetAltTextTemplate() error = %v", err)
+	}
+
+	if _, err := poster.PostEntries(entries, renderer, false); err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	if strings.Join(descriptions, "|") != "Image for Photo nsfw|Image for Photo cats" {
+		t.Errorf("descriptions = %q, want the rendered alt text", descriptions)
+	}
+	if strings.Join(sensitive, ",") != "true," {
+		t.Errorf("sensitive = %q, want only the first entry", sensitive)
+	}
+}
+
+func TestEntryAudioVideo(t *testing.T) {
+	itemJSON, _ := json.Marshal(&gofeed.Item{
+		Enclosures: []*gofeed.Enclosure{
+			{URL: "https://example.com/cover.jpg", Type: "image/jpeg"},
+			{URL: "https://example.com/ep1.mp3", Type: "audio/mpeg"},
+		},
+	})
+	source, ok := entryAudioVideo(itemJSON)
+	if !ok || source.URL != "https://example.com/ep1.mp3" || source.Type != "audio/mpeg" {
+		t.Errorf("entryAudioVideo() = %+v, %v; want the audio enclosure", source, ok)
+	}
+
+	itemJSON, _ = json.Marshal(&gofeed.Item{
+		Extensions: ext.Extensions{"media": {"content": {{
+			Attrs:    map[string]string{"url": "https://example.com/clip", "medium": "video"},
+			Children: map[string][]ext.Extension{"title": {{Value: "A clip"}}},
+		}}}},
+	})
+	source, ok = entryAudioVideo(itemJSON)
+	if !ok || source.URL != "https://example.com/clip" || source.Description != "A clip" {
+		t.Errorf("entryAudioVideo() = %+v, %v; want the video media:content", source, ok)
+	}
+
+	itemJSON, _ = json.Marshal(&gofeed.Item{
+		Enclosures: []*gofeed.Enclosure{{URL: "https://example.com/cover.jpg", Type: "image/jpeg"}},
+	})
+	if source, ok := entryAudioVideo(itemJSON); ok {
+		t.Errorf("entryAudioVideo() = %+v, want none for images", source)
+	}
+}
+
+func TestPostEntries_AudioVideo(t *testing.T) {
+	var postedMediaIDs []string
+	var uploads, polls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cover.png":
+			_, _ = w.Write(pngHeader)
+		case "/ep1.m4a":
+			w.Header().Set("Content-Type", "audio/mp4")
+			_, _ = w.Write([]byte("\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00"))
+		case "/api/v2/media":
+			uploads++
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"id":"601","type":"audio","url":null}`))
+		case "/api/v1/media/601":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+			}
+			polls++
+			if polls < 3 {
+				w.WriteHeader(http.StatusPartialContent)
+			}
+			_, _ = w.Write([]byte(`{"id":"601","type":"audio","url":"https://mastodon.example/ep1.m4a"}`))
+		case "/api/v1/statuses":
+			_ = r.ParseForm()
+			postedMediaIDs = r.PostForm["media_ids[]"]
+			_, _ = w.Write([]byte(`{"id":"100","url":"https://mastodon.example/@me/100"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	itemJSON, _ := json.Marshal(&gofeed.Item{
+		Title: "Episode 1",
+		Enclosures: []*gofeed.Enclosure{
+			{URL: server.URL + "/cover.png", Type: "image/png"},
+			{URL: server.URL + "/ep1.m4a", Type: "audio/x-m4a"},
+		},
+	})
+	entries := []*database.Entry{{ID: "entry-1", EntryData: itemJSON}}
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	poster.EnableMedia(1024)
+	poster.EnableAudioVideo(1024)
+	poster.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	results, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false)
+	if count := destination.CountPosted(results); err != nil || count != 1 {
+		t.Fatalf("PostEntries() = %d, %v; want 1, nil", count, err)
+	}
+
+	if uploads != 1 || polls != 3 {
+		t.Errorf("uploads = %d, polls = %d; want the audio only, polled until processed", uploads, polls)
+	}
+	if strings.Join(postedMediaIDs, ",") != "601" {
+		t.Errorf("media_ids = %v, want [601]", postedMediaIDs)
+	}
+	if len(entries[0].Attachments) != 1 || entries[0].Attachments[0].ContentType != "audio/mp4" {
+		t.Errorf("attachments = %+v, want the audio as audio/mp4", entries[0].Attachments)
+	}
+}
+
+func TestPostEntries_AudioVideoTooLarge(t *testing.T) {
+	var postedMediaIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cover.png":
+			_, _ = w.Write(pngHeader)
+		case "/ep1.mp3":
+			_, _ = w.Write(append([]byte("ID3"), make([]byte, 2048)...))
+		case "/api/v2/media":
+			_, _ = w.Write([]byte(`{"id":"501","type":"image"}`))
+		case "/api/v1/statuses":
+			_ = r.ParseForm()
+			postedMediaIDs = r.PostForm["media_ids[]"]
+			_, _ = w.Write([]byte(`{"id":"100","url":"https://mastodon.example/@me/100"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	itemJSON, _ := json.Marshal(&gofeed.Item{
+		Title: "Episode 1",
+		Enclosures: []*gofeed.Enclosure{
+			{URL: server.URL + "/ep1.mp3", Type: "audio/mpeg"},
+			{URL: server.URL + "/cover.png", Type: "image/png"},
+		},
+	})
+	entries := []*database.Entry{{ID: "entry-1", EntryData: itemJSON}}
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	poster.EnableMedia(1024)
+	poster.EnableAudioVideo(1024)
+
+	if _, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false); err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	if strings.Join(postedMediaIDs, ",") != "501" {
+		t.Errorf("media_ids = %v, want the cover image instead", postedMediaIDs)
+	}
+}
+
+// memoryMediaCache is a MediaCache in memory.
+type memoryMediaCache map[string]string
+
+func (c memoryMediaCache) GetUploadedMedia(hash string) (string, error) {
+	return c[hash], nil
+}
+
+func (c memoryMediaCache) SaveUploadedMedia(hash, mediaID string) error {
+	c[hash] = mediaID
+	return nil
+}
+
+func (c memoryMediaCache) ForgetUploadedMedia(mediaID string) error {
+	for hash, id := range c {
+		if id == mediaID {
+			delete(c, hash)
+		}
+	}
+	return nil
+}
+
+func TestPostEntries_MediaProcessing(t *testing.T) {
+	// Noise compresses poorly, so this is well over the limit
+	img := image.NewRGBA(image.Rect(0, 0, 300, 300))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7919 % 251)
+	}
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("jpeg.Encode() error = %v", err)
+	}
+	const limit = 8000
+	if photo.Len() <= limit {
+		t.Fatalf("test photo is only %d bytes", photo.Len())
+	}
+
+	var uploadedSize int
+	var focus string
+	statusCode := http.StatusUnprocessableEntity
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.jpg":
+			_, _ = w.Write(photo.Bytes())
+		case "/api/v2/media":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("ParseMultipartForm() error = %v", err)
+			}
+			file, _, _ := r.FormFile("file")
+			data, _ := io.ReadAll(file)
+			uploadedSize = len(data)
+			focus = r.FormValue("focus")
+			_, _ = w.Write([]byte(`{"id":"701","type":"image"}`))
+		case "/api/v1/statuses":
+			w.WriteHeader(statusCode)
+			_, _ = w.Write([]byte(`{"id":"100","url":"https://mastodon.example/@me/100","error":"Validation failed"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	itemJSON, _ := json.Marshal(&gofeed.Item{
+		Title:      "Photo",
+		Enclosures: []*gofeed.Enclosure{{URL: server.URL + "/photo.jpg", Type: "image/jpeg"}},
+	})
+	newEntries := func() []*database.Entry {
+		return []*database.Entry{{ID: "entry-1", EntryData: itemJSON}}
+	}
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	poster.EnableMedia(limit)
+	poster.SetImageProcessing(imaging.Options{Downscale: true, StripMetadata: true})
+	poster.SetMediaFocus("0.0,0.5")
+	cache := memoryMediaCache{}
+	poster.SetMediaCache(cache)
+	renderer := newTestRenderer(t, "{{.Item.Title}}")
+
+	// The upload succeeds but the status is rejected
+	if results, _ := poster.PostEntries(newEntries(), renderer, false); destination.CountPosted(results) != 0 {
+		t.Fatal("PostEntries() posted the entry, want a failure")
+	}
+	if uploadedSize == 0 || uploadedSize > limit {
+		t.Errorf("uploaded %d bytes, want the photo downscaled below %d", uploadedSize, limit)
+	}
+	if focus != "0.0,0.5" {
+		t.Errorf("focus = %q, want 0.0,0.5", focus)
+	}
+	if len(cache) != 1 {
+		t.Fatalf("cache = %v, want the upload", cache)
+	}
+
+	// The retry reuses the upload
+	uploadedSize = 0
+	statusCode = http.StatusOK
+	entries := newEntries()
+	if results, err := poster.PostEntries(entries, renderer, false); err != nil || destination.CountPosted(results) != 1 {
+		t.Fatalf("PostEntries() = %v, %v; want the entry posted", results, err)
+	}
+	if uploadedSize != 0 {
+		t.Error("retry uploaded the photo again, want the earlier upload reused")
+	}
+	if len(entries[0].Attachments) != 1 || entries[0].Attachments[0].MediaID != "701" {
+		t.Errorf("attachments = %+v, want media 701", entries[0].Attachments)
+	}
+	if len(cache) != 0 {
+		t.Errorf("cache = %v, want attached media forgotten", cache)
+	}
+}
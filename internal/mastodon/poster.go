@@ -2,33 +2,108 @@ package mastodon
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/filter"
+	"github.com/lorchard/feed-to-mastodon/internal/imaging"
 	"github.com/lorchard/feed-to-mastodon/internal/template"
 	mastodon "github.com/mattn/go-mastodon"
 	"github.com/sirupsen/logrus"
 )
 
+// ErrUnauthorized is returned when the server rejects the access token,
+// e.g. because it was revoked or has expired.
+var ErrUnauthorized = errors.New("access token was rejected by the server")
+
+// ErrServerUnavailable is returned when the server can't be reached or
+// responds with a server error.
+var ErrServerUnavailable = errors.New("server is unavailable")
+
+// defaultOutageThreshold is how many consecutive unavailable errors end a batch.
+const defaultOutageThreshold = 3
+
+// isServerUnavailable reports whether err is a network error or a 5xx
+// response from the Mastodon API. A request given up on because its
+// context is done isn't the server's fault.
+func isServerUnavailable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *mastodon.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isUnauthorized reports whether err is a 401 response from the Mastodon API.
+func isUnauthorized(err error) bool {
+	var apiErr *mastodon.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
 // Poster handles posting content to Mastodon.
 type Poster struct {
-	client         *mastodon.Client
-	visibility     string
-	contentWarning string
+	client          *mastodon.Client
+	limits          *rateLimitTransport
+	flavor          Flavor
+	visibility      string
+	contentWarning  string
+	outageThreshold int
+	statusLinkMode  string
+	threadAnchor    mastodon.ID
+	continueThread  bool
+	previousStatus  mastodon.ID
+	mediaMaxBytes   int64
+	firstImage      bool
+	httpClient      *http.Client
+	avMaxBytes      int64
+	avClient        *http.Client
+	imageOptions    imaging.Options
+	mediaFocus      string
+	mediaCache      MediaCache
+	usedMedia       map[mastodon.ID]bool
+	mimeTypes       []string
+	postInterval    time.Duration
+	sleep           func(context.Context, time.Duration) error
+	splitThreads    bool
+	scheduleStart   time.Time
+	scheduleSpread  time.Duration
+	scheduleWindow  func(time.Time) time.Time
+	visibilityRules []visibilityRule
+	sensitiveMedia  *filter.Filter
 }
 
-// New creates a new Poster instance.
+// visibilityRule posts entries that pass a filter with another visibility.
+type visibilityRule struct {
+	match      *filter.Filter
+	visibility string
+}
+
+// Poster is the Mastodon destination.
+var _ destination.Destination = (*Poster)(nil)
+
+// New creates a new Poster instance for a Mastodon server.
 func New(server, accessToken, visibility, contentWarning string) (*Poster, error) {
-	// Validate visibility
-	validVisibilities := map[string]bool{
-		"public":   true,
-		"unlisted": true,
-		"private":  true,
-		"direct":   true,
-	}
+	return NewWithFlavor(server, accessToken, visibility, contentWarning, FlavorMastodon)
+}
 
-	if !validVisibilities[visibility] {
-		return nil, fmt.Errorf("invalid visibility: %s (must be public, unlisted, private, or direct)", visibility)
+// NewWithFlavor creates a new Poster instance for a server of the given
+// flavor, allowing its visibilities and working around its quirks.
+func NewWithFlavor(server, accessToken, visibility, contentWarning string, flavor Flavor) (*Poster, error) {
+	// Validate visibility
+	if !flavor.validVisibility(visibility) {
+		return nil, flavor.visibilityError(visibility)
 	}
 
 	// Create Mastodon client
@@ -36,24 +111,149 @@ func New(server, accessToken, visibility, contentWarning string) (*Poster, error
 		Server:      server,
 		AccessToken: accessToken,
 	})
+	limits := newRateLimitTransport(http.DefaultTransport)
+	limits.idempotency = flavor.idempotent()
+	client.Transport = &quoteTransport{base: &compatTransport{base: limits, flavor: flavor}}
 
 	return &Poster{
-		client:         client,
-		visibility:     visibility,
-		contentWarning: contentWarning,
+		client:          client,
+		limits:          limits,
+		flavor:          flavor,
+		visibility:      visibility,
+		contentWarning:  contentWarning,
+		outageThreshold: defaultOutageThreshold,
+		statusLinkMode:  StatusLinkPlain,
+		sleep:           sleepContext,
 	}, nil
 }
 
-// Post posts content to Mastodon.
-// If dryRun is true, logs what would be posted without actually posting.
-func (p *Poster) Post(content string, dryRun bool) error {
-	if dryRun {
-		logrus.Info("DRY RUN: Would post to Mastodon")
-		logrus.Debugf("DRY RUN: Content:\n%s", content)
+// SetOutageThreshold sets how many consecutive unavailable errors make
+// PostEntries give up on the batch. Values below 1 are ignored.
+func (p *Poster) SetOutageThreshold(threshold int) {
+	if threshold > 0 {
+		p.outageThreshold = threshold
+	}
+}
+
+// Status identifies a status created on Mastodon.
+type Status struct {
+	ID  string
+	URL string
+}
+
+// SetPostInterval sets how long PostEntries waits between posts, so a
+// backlog doesn't flood followers' timelines.
+func (p *Poster) SetPostInterval(interval time.Duration) {
+	p.postInterval = interval
+}
+
+// SetSplitLongPosts makes PostEntries split posts over the character limit
+// into a thread of replies instead of posting them whole.
+func (p *Poster) SetSplitLongPosts(split bool) {
+	p.splitThreads = split
+}
+
+// SetThreadAnchor makes entries be posted as replies to the status with
+// the given ID, so the feed's posts form one thread under it instead of
+// separate posts. An empty ID posts entries as usual.
+func (p *Poster) SetThreadAnchor(id string) {
+	p.threadAnchor = mastodon.ID(id)
+}
+
+// SetContinueThread makes entries be posted as replies to the previous
+// entry's post, so the feed's posts form one ongoing thread. previousID is
+// the status the next entry replies to, like the feed's last post; if it's
+// empty, the next entry starts the thread, replying to the thread anchor
+// if there is one. Scheduled posts don't continue the thread, since they
+// don't exist yet when the next entry is posted.
+func (p *Poster) SetContinueThread(previousID string) {
+	p.continueThread = true
+	p.previousStatus = mastodon.ID(previousID)
+}
+
+// AddVisibilityRule posts entries that pass match with the given
+// visibility instead of the default one. The first matching rule wins.
+func (p *Poster) AddVisibilityRule(match *filter.Filter, visibility string) error {
+	if !p.flavor.validVisibility(visibility) {
+		return p.flavor.visibilityError(visibility)
+	}
+	p.visibilityRules = append(p.visibilityRules, visibilityRule{match: match, visibility: visibility})
+	return nil
+}
+
+// visibilityFor returns the visibility to post entry with.
+func (p *Poster) visibilityFor(entry *database.Entry) string {
+	for _, rule := range p.visibilityRules {
+		if ok, _, err := rule.match.CheckEntry(entry.EntryData); err == nil && ok {
+			return rule.visibility
+		}
+	}
+	return p.visibility
+}
+
+// minScheduleLead is how far ahead Mastodon requires scheduled statuses to be.
+const minScheduleLead = 5 * time.Minute
+
+// SetScheduleSpread makes PostEntries schedule posts spread apart by spread
+// instead of posting them at once, the first at start. Posts due within
+// minScheduleLead are scheduled that far ahead, and posts due already are
+// published immediately. Scheduled posts aren't split into threads.
+func (p *Poster) SetScheduleSpread(start time.Time, spread time.Duration) {
+	p.scheduleStart = start
+	p.scheduleSpread = spread
+}
+
+// SetScheduleWindow keeps scheduled posts within a posting window. next
+// returns the given time if posting is allowed then, or else when posting
+// is next allowed.
+func (p *Poster) SetScheduleWindow(next func(time.Time) time.Time) {
+	p.scheduleWindow = next
+}
+
+// scheduleTime returns when to schedule the nth scheduled post, or nil to
+// publish it immediately.
+func (p *Poster) scheduleTime(n int) *time.Time {
+	if p.scheduleSpread <= 0 {
+		return nil
+	}
+
+	// Slots that fall outside the posting window move to when it opens,
+	// and later slots follow on from there
+	at := p.scheduleStart
+	for i := 0; ; i++ {
+		if p.scheduleWindow != nil {
+			at = p.scheduleWindow(at)
+		}
+		if i == n {
+			break
+		}
+		at = at.Add(p.scheduleSpread)
+	}
+	now := time.Now()
+	if !at.After(now) {
 		return nil
 	}
+	if earliest := now.Add(minScheduleLead + time.Minute); at.Before(earliest) {
+		at = earliest
+	}
+	at = at.Truncate(time.Second)
+	return &at
+}
+
+// Post posts content to Mastodon and returns the created status.
+// If dryRun is true, logs what would be posted without actually posting
+// and returns a nil status.
+func (p *Poster) Post(content string, dryRun bool) (*Status, error) {
+	status, err := p.publish(context.Background(), p.newToot(content), dryRun)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	return &Status{ID: string(status.ID), URL: status.URL}, nil
+}
 
-	// Create toot
+// newToot creates a toot for content with the configured visibility and
+// content warning.
+func (p *Poster) newToot(content string) *mastodon.Toot {
 	toot := &mastodon.Toot{
 		Status:     content,
 		Visibility: p.visibility,
@@ -64,37 +264,303 @@ func (p *Poster) Post(content string, dryRun bool) error {
 		toot.SpoilerText = p.contentWarning
 	}
 
+	return toot
+}
+
+// newEntryToot creates a toot for an entry's content, with the visibility
+// of the first matching visibility rule and the entry's content warning if
+// it has one. The toot replies to the previous entry's post when
+// continuing a thread, or else to the thread anchor if there is one.
+func (p *Poster) newEntryToot(entry *database.Entry, content string) *mastodon.Toot {
+	toot := p.newToot(content)
+	toot.Visibility = p.visibilityFor(entry)
+	toot.InReplyToID = p.threadAnchor
+	if p.continueThread && p.previousStatus != "" {
+		toot.InReplyToID = p.previousStatus
+	}
+	if entry.ContentWarning != "" {
+		toot.SpoilerText = entry.ContentWarning
+	}
+	return toot
+}
+
+// publish posts a toot, classifying authentication, rate limit, and
+// availability errors.
+// Returns a nil status in dry run mode.
+func (p *Poster) publish(ctx context.Context, toot *mastodon.Toot, dryRun bool) (*mastodon.Status, error) {
+	if dryRun {
+		if toot.ScheduledAt != nil {
+			logrus.Infof("DRY RUN: Would schedule post for %s", toot.ScheduledAt.Format(time.RFC3339))
+		} else {
+			logrus.Info("DRY RUN: Would post to Mastodon")
+		}
+		logrus.Debugf("DRY RUN: Content:\n%s", toot.Status)
+		return nil, nil
+	}
+
 	// Post to Mastodon
-	status, err := p.client.PostStatus(context.Background(), toot)
+	status, err := p.client.PostStatus(ctx, toot)
 	if err != nil {
-		return fmt.Errorf("failed to post to Mastodon: %w", err)
+		if classified := classifyError(err); classified != nil {
+			return nil, classified
+		}
+		return nil, fmt.Errorf("failed to post to Mastodon: %w", err)
+	}
+
+	if toot.ScheduledAt != nil {
+		logrus.Infof("Scheduled post %s for %s", status.ID, toot.ScheduledAt.Format(time.RFC3339))
+	} else {
+		logrus.Infof("Posted to Mastodon: %s", status.URL)
+	}
+	return status, nil
+}
+
+// update edits an existing status to the toot's content, classifying
+// errors like publish. Returns a nil status in dry run mode.
+func (p *Poster) update(ctx context.Context, toot *mastodon.Toot, id string, dryRun bool) (*mastodon.Status, error) {
+	if dryRun {
+		logrus.Infof("DRY RUN: Would edit status %s", id)
+		logrus.Debugf("DRY RUN: Content:\n%s", toot.Status)
+		return nil, nil
+	}
+
+	status, err := p.client.UpdateStatus(ctx, toot, mastodon.ID(id))
+	if err != nil {
+		if classified := classifyError(err); classified != nil {
+			return nil, classified
+		}
+		return nil, fmt.Errorf("failed to edit status: %w", err)
+	}
+
+	logrus.Infof("Edited status on Mastodon: %s", status.URL)
+	return status, nil
+}
+
+// DeleteStatus deletes a posted status, classifying errors like publish.
+// A status that's already gone isn't an error.
+func (p *Poster) DeleteStatus(id string, dryRun bool) error {
+	return p.DeleteStatusContext(context.Background(), id, dryRun)
+}
+
+// DeleteStatusContext is like DeleteStatus, but gives up when ctx is done,
+// even while waiting for the rate limit to reset.
+func (p *Poster) DeleteStatusContext(ctx context.Context, id string, dryRun bool) error {
+	if dryRun {
+		logrus.Infof("DRY RUN: Would delete status %s", id)
+		return nil
+	}
+
+	err := p.client.DeleteStatus(ctx, mastodon.ID(id))
+	var apiErr *mastodon.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		logrus.Infof("Status %s was already deleted", id)
+		return nil
+	}
+	if err != nil {
+		if classified := classifyError(err); classified != nil {
+			return classified
+		}
+		return fmt.Errorf("failed to delete status: %w", err)
 	}
 
-	logrus.Infof("Posted to Mastodon: %s", status.URL)
+	logrus.Infof("Deleted status %s from Mastodon", id)
+	return nil
+}
+
+// sleepContext waits for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// threadSeparator separates the parts of a thread in stored posted content.
+const threadSeparator = "\n\n---\n\n"
+
+// publishEntry posts an entry's toot and returns the first status, the
+// text that was sent, and the IDs of any replies the rest was split into.
+// With thread splitting enabled, a toot over limit is posted as a thread of
+// replies. Once the first part is posted, failures of later parts are
+// logged rather than returned, so the entry isn't posted again.
+func (p *Poster) publishEntry(ctx context.Context, toot *mastodon.Toot, limit int, dryRun bool) (*mastodon.Status, string, []string, error) {
+	// Replies can't be scheduled before the post they reply to exists
+	if !p.splitThreads || toot.ScheduledAt != nil {
+		status, err := p.publish(ctx, toot, dryRun)
+		if toot.ScheduledAt == nil {
+			p.continueFrom(status)
+		}
+		return status, toot.Status, nil, err
+	}
+
+	// The content warning counts toward the limit of every part
+	parts := template.SplitThread(toot.Status, limit-utf8.RuneCountInString(toot.SpoilerText))
+	toot.Status = parts[0]
+	first, err := p.publish(ctx, toot, dryRun)
+	if err != nil || len(parts) == 1 {
+		p.continueFrom(first)
+		return first, toot.Status, nil, err
+	}
+
+	logrus.Infof("Posting long entry as a thread of %d posts", len(parts))
+	sent := []string{parts[0]}
+	var replies []string
+	previous := first
+	for i, part := range parts[1:] {
+		// Replies keep the entry's visibility and content warning
+		reply := p.newToot(part)
+		reply.Visibility = toot.Visibility
+		reply.SpoilerText = toot.SpoilerText
+		if previous != nil {
+			reply.InReplyToID = previous.ID
+		}
+		status, err := p.publish(withoutQuotedStatus(ctx), reply, dryRun)
+		if err != nil {
+			logrus.Errorf("Failed to post part %d/%d of thread: %v", i+2, len(parts), err)
+			break
+		}
+		sent = append(sent, part)
+		if status != nil {
+			replies = append(replies, string(status.ID))
+		}
+		previous = status
+	}
+
+	// The next entry continues from the end of the thread
+	p.continueFrom(previous)
+	return first, strings.Join(sent, threadSeparator), replies, nil
+}
+
+// continueFrom makes the next entry reply to status when continuing a
+// thread. A nil status, as in dry run mode, is ignored.
+func (p *Poster) continueFrom(status *mastodon.Status) {
+	if p.continueThread && status != nil {
+		p.previousStatus = status.ID
+	}
+}
+
+// PostContent posts content for a single entry, quoting or replying to a
+// linked status, attaching the entry's media, and splitting long content
+// into a thread as configured. On success the sent text and the created
+// status are recorded on the entry, unless in dry run mode.
+func (p *Poster) PostContent(entry *database.Entry, content string, limit int, dryRun bool) error {
+	return p.PostContentContext(context.Background(), entry, content, limit, dryRun)
+}
+
+// PostContentContext is like PostContent, but stops when ctx is done.
+func (p *Poster) PostContentContext(ctx context.Context, entry *database.Entry, content string, limit int, dryRun bool) error {
+	return p.postEntry(ctx, entry, content, limit, nil, dryRun)
+}
+
+// postEntry posts content for an entry like PostContent, scheduling it for
+// scheduledAt if it's not nil.
+func (p *Poster) postEntry(ctx context.Context, entry *database.Entry, content string, limit int, scheduledAt *time.Time, dryRun bool) error {
+	// Boosts can't be scheduled, so they happen right away
+	if p.statusLinkMode == StatusLinkBoost {
+		if boosted, err := p.boostStatusLink(ctx, entry, dryRun); boosted || err != nil {
+			return err
+		}
+	}
+
+	toot := p.newEntryToot(entry, content)
+	toot.ScheduledAt = scheduledAt
+	ctx = p.prepareStatusLink(ctx, toot, entryLink(entry.EntryData), dryRun)
+	p.attachMedia(ctx, toot, entry, dryRun)
+	status, sent, replies, err := p.publishEntry(ctx, toot, limit, dryRun)
+	if err != nil {
+		return err
+	}
+	p.forgetMedia(toot.MediaIDs)
+
+	// Keep the exact text that was sent so it can be stored for auditing,
+	// along with the status so it can be found again later
+	if !dryRun {
+		entry.PostedContent = sql.NullString{String: sent, Valid: true}
+		if scheduledAt != nil {
+			entry.ScheduledAt = sql.NullTime{Time: *scheduledAt, Valid: true}
+			entry.ScheduledID = sql.NullString{String: string(status.ID), Valid: true}
+		} else {
+			entry.StatusID = sql.NullString{String: string(status.ID), Valid: true}
+			entry.StatusURL = sql.NullString{String: status.URL, Valid: status.URL != ""}
+			entry.ThreadStatusIDs = replies
+		}
+	}
 	return nil
 }
 
 // PostEntries posts multiple entries to Mastodon.
-// Returns the count of successfully posted entries.
-// Continues on individual posting errors.
-func (p *Poster) PostEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) (int, error) {
+// Returns one result per entry, in the same order as entries.
+// Continues on individual posting errors, except when the access token is
+// rejected: then it stops and returns ErrUnauthorized, since every further
+// post would fail the same way. Likewise, after several consecutive server
+// unavailable errors it stops and returns ErrServerUnavailable. Entries not
+// attempted after stopping are reported as skipped.
+func (p *Poster) PostEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]destination.PostResult, error) {
+	return p.PostEntriesContext(context.Background(), entries, renderer, dryRun)
+}
+
+// PostEntriesContext is like PostEntries, but stops when ctx is done, even
+// while waiting between posts, and returns ctx's error.
+func (p *Poster) PostEntriesContext(ctx context.Context, entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]destination.PostResult, error) {
+	results := make([]destination.PostResult, len(entries))
+	for i, entry := range entries {
+		results[i] = destination.PostResult{Entry: entry, Outcome: destination.OutcomeSkipped}
+	}
+
 	posted := 0
+	unavailable := 0
+	attempted := 0
+
+	for i, entry := range entries {
+		result := &results[i]
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
 
-	for _, entry := range entries {
 		// Render template
-		content, err := renderer.Render(entry.EntryData)
+		content, err := destination.RenderEntry(renderer, entry)
 		if err != nil {
 			logrus.Errorf("Failed to render entry %s: %v", entry.ID, err)
+			result.Outcome, result.Err = destination.OutcomeFailed, err
 			continue
 		}
 
-		// Post to Mastodon
-		err = p.Post(content, dryRun)
+		// Space out posts, by scheduling them or by waiting between them
+		scheduledAt := p.scheduleTime(attempted)
+		if attempted > 0 && p.postInterval > 0 && p.scheduleSpread <= 0 && !dryRun {
+			logrus.Infof("Waiting %s before the next post", p.postInterval)
+			if err := p.sleep(ctx, p.postInterval); err != nil {
+				return results, err
+			}
+		}
+		attempted++
+
+		err = p.postEntry(ctx, entry, content, renderer.CharacterLimit(), scheduledAt, dryRun)
 		if err != nil {
 			logrus.Errorf("Failed to post entry %s: %v", entry.ID, err)
+			result.Outcome, result.Err = destination.OutcomeFailed, err
+		}
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrRateLimited) {
+			return results, err
+		}
+		if errors.Is(err, ErrServerUnavailable) {
+			unavailable++
+			if unavailable >= p.outageThreshold {
+				logrus.Errorf("Giving up after %d consecutive server errors", unavailable)
+				return results, err
+			}
+			continue
+		}
+		if err != nil {
 			continue
 		}
+		unavailable = 0
 
+		result.Outcome = destination.OutcomePosted
 		posted++
 	}
 
@@ -104,5 +570,101 @@ func (p *Poster) PostEntries(entries []*database.Entry, renderer *template.Rende
 		logrus.Infof("Successfully posted %d/%d entries", posted, len(entries))
 	}
 
-	return posted, nil
+	return results, nil
+}
+
+// UpdateEntries edits the statuses of entries that changed after they were
+// posted, re-rendering them with the current template. Only the first post
+// of a thread is edited, and attachments are left as they were. A mention
+// added when replying to a linked status is kept, and boosts are left
+// alone.
+// Returns one result per entry, stopping early like PostEntries.
+func (p *Poster) UpdateEntries(entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]destination.PostResult, error) {
+	return p.UpdateEntriesContext(context.Background(), entries, renderer, dryRun)
+}
+
+// UpdateEntriesContext is like UpdateEntries, but stops when ctx is done
+// and returns ctx's error.
+func (p *Poster) UpdateEntriesContext(ctx context.Context, entries []*database.Entry, renderer *template.Renderer, dryRun bool) ([]destination.PostResult, error) {
+	results := make([]destination.PostResult, len(entries))
+	for i, entry := range entries {
+		results[i] = destination.PostResult{Entry: entry, Outcome: destination.OutcomeSkipped}
+	}
+
+	updated := 0
+	unavailable := 0
+
+	for i, entry := range entries {
+		result := &results[i]
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		// A boost shows the original status, edits and all
+		if strings.HasPrefix(entry.PostedContent.String, boostedPrefix) {
+			result.Outcome = destination.OutcomePosted
+			continue
+		}
+
+		content, err := destination.RenderEntry(renderer, entry)
+		if err != nil {
+			logrus.Errorf("Failed to render entry %s: %v", entry.ID, err)
+			result.Outcome, result.Err = destination.OutcomeFailed, err
+			continue
+		}
+
+		toot := p.newEntryToot(entry, keepMention(entry.PostedContent.String, content))
+		if p.splitThreads {
+			toot.Status = template.SplitThread(toot.Status, renderer.CharacterLimit()-utf8.RuneCountInString(toot.SpoilerText))[0]
+		}
+
+		_, err = p.update(ctx, toot, entry.StatusID.String, dryRun)
+		if err != nil {
+			logrus.Errorf("Failed to edit status for entry %s: %v", entry.ID, err)
+			result.Outcome, result.Err = destination.OutcomeFailed, err
+		}
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrRateLimited) {
+			return results, err
+		}
+		if errors.Is(err, ErrServerUnavailable) {
+			unavailable++
+			if unavailable >= p.outageThreshold {
+				logrus.Errorf("Giving up after %d consecutive server errors", unavailable)
+				return results, err
+			}
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		unavailable = 0
+
+		if !dryRun {
+			entry.PostedContent = sql.NullString{String: toot.Status, Valid: true}
+		}
+
+		result.Outcome = destination.OutcomePosted
+		updated++
+	}
+
+	if dryRun {
+		logrus.Infof("DRY RUN: Would edit %d statuses", updated)
+	} else {
+		logrus.Infof("Successfully edited %d/%d statuses", updated, len(entries))
+	}
+
+	return results, nil
+}
+
+// keepMention carries a leading mention in previously posted content over
+// to content, so editing a reply doesn't drop the mention of its author.
+func keepMention(posted, content string) string {
+	if !strings.HasPrefix(posted, "@") {
+		return content
+	}
+	mention, _, _ := strings.Cut(posted, " ")
+	if strings.Contains(content, mention) {
+		return content
+	}
+	return mention + " " + content
 }
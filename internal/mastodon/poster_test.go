@@ -1,13 +1,21 @@
 package mastodon
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/filter"
 	"github.com/lorchard/feed-to-mastodon/internal/template"
 	"github.com/mmcdole/gofeed"
 )
@@ -93,10 +101,13 @@ func TestPost_DryRun(t *testing.T) {
 		}
 
 		// Dry run should always succeed without making API calls
-		err = poster.Post("Test content", true)
+		status, err := poster.Post("Test content", true)
 		if err != nil {
 			t.Errorf("Post() dry run error = %v", err)
 		}
+		if status != nil {
+			t.Errorf("Post() dry run status = %+v, want nil", status)
+		}
 	})
 
 	t.Run("dry run with various content", func(t *testing.T) {
@@ -113,7 +124,7 @@ func TestPost_DryRun(t *testing.T) {
 		}
 
 		for _, content := range testCases {
-			err = poster.Post(content, true)
+			_, err = poster.Post(content, true)
 			if err != nil {
 				t.Errorf("Post() dry run with content %q error = %v", content, err)
 			}
@@ -121,6 +132,76 @@ func TestPost_DryRun(t *testing.T) {
 	})
 }
 
+func TestPost_ReturnsStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"110","url":"https://mastodon.example/@me/110"}`))
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	status, err := poster.Post("Test content", false)
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if status == nil || status.ID != "110" || status.URL != "https://mastodon.example/@me/110" {
+		t.Errorf("Post() status = %+v", status)
+	}
+
+	entries := newTestEntries(t, 1)
+	if _, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false); err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	if entries[0].StatusID.String != "110" || entries[0].StatusURL.String != "https://mastodon.example/@me/110" {
+		t.Errorf("entry status = %+v %+v", entries[0].StatusID, entries[0].StatusURL)
+	}
+}
+
+func TestDeleteStatus(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/statuses/110":
+			deleted = append(deleted, "110")
+			_, _ = w.Write([]byte(`{"id":"110"}`))
+		case "/api/v1/statuses/404":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"Record not found"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"The access token is invalid"}`))
+		}
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := poster.DeleteStatus("110", true); err != nil || len(deleted) != 0 {
+		t.Errorf("DeleteStatus(dry run) = %v with %d deletes, want nil and none", err, len(deleted))
+	}
+	if err := poster.DeleteStatus("110", false); err != nil || len(deleted) != 1 {
+		t.Errorf("DeleteStatus() = %v with %d deletes, want nil and 1", err, len(deleted))
+	}
+	if err := poster.DeleteStatus("404", false); err != nil {
+		t.Errorf("DeleteStatus(already deleted) error = %v, want nil", err)
+	}
+	if err := poster.DeleteStatus("111", false); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("DeleteStatus(rejected token) error = %v, want ErrUnauthorized", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := poster.DeleteStatusContext(ctx, "110", false); !errors.Is(err, context.Canceled) || len(deleted) != 1 {
+		t.Errorf("DeleteStatusContext(cancelled) = %v with %d deletes, want context.Canceled and 1", err, len(deleted))
+	}
+}
+
 func TestPostEntries(t *testing.T) {
 	t.Run("posts multiple entries in dry run", func(t *testing.T) {
 		// Create test database
@@ -142,7 +223,7 @@ func TestPostEntries(t *testing.T) {
 			if err != nil {
 				t.Fatalf("json.Marshal() error = %v", err)
 			}
-			if err := db.SaveEntry(fmt.Sprintf("entry-%d", i+1), itemJSON); err != nil {
+			if _, err := db.SaveEntry(fmt.Sprintf("entry-%d", i+1), itemJSON); err != nil {
 				t.Fatalf("SaveEntry() error = %v", err)
 			}
 		}
@@ -172,7 +253,8 @@ func TestPostEntries(t *testing.T) {
 		}
 
 		// Post in dry run
-		count, err := poster.PostEntries(entries, renderer, true)
+		results, err := poster.PostEntries(entries, renderer, true)
+		count := destination.CountPosted(results)
 		if err != nil {
 			t.Fatalf("PostEntries() error = %v", err)
 		}
@@ -180,6 +262,13 @@ func TestPostEntries(t *testing.T) {
 		if count != 3 {
 			t.Errorf("PostEntries() count = %d, want 3", count)
 		}
+
+		// Dry run should not record posted content
+		for _, entry := range entries {
+			if entry.PostedContent.Valid {
+				t.Errorf("entry %s has PostedContent after dry run", entry.ID)
+			}
+		}
 	})
 
 	t.Run("handles empty entries list", func(t *testing.T) {
@@ -200,7 +289,8 @@ func TestPostEntries(t *testing.T) {
 			t.Fatalf("New() error = %v", err)
 		}
 
-		count, err := poster.PostEntries([]*database.Entry{}, renderer, true)
+		results, err := poster.PostEntries([]*database.Entry{}, renderer, true)
+		count := destination.CountPosted(results)
 		if err != nil {
 			t.Fatalf("PostEntries() error = %v", err)
 		}
@@ -224,12 +314,12 @@ func TestPostEntries(t *testing.T) {
 		if err != nil {
 			t.Fatalf("json.Marshal() error = %v", err)
 		}
-		if err := db.SaveEntry("valid", itemJSON); err != nil {
+		if _, err := db.SaveEntry("valid", itemJSON); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
 
 		// Add invalid JSON entry
-		if err := db.SaveEntry("invalid", []byte("invalid json")); err != nil {
+		if _, err := db.SaveEntry("invalid", []byte("invalid json")); err != nil {
 			t.Fatalf("SaveEntry() error = %v", err)
 		}
 
@@ -257,7 +347,8 @@ func TestPostEntries(t *testing.T) {
 		}
 
 		// Should post 1 out of 2 (one valid, one invalid)
-		count, err := poster.PostEntries(entries, renderer, true)
+		results, err := poster.PostEntries(entries, renderer, true)
+		count := destination.CountPosted(results)
 		if err != nil {
 			t.Fatalf("PostEntries() error = %v", err)
 		}
@@ -268,6 +359,376 @@ func TestPostEntries(t *testing.T) {
 	})
 }
 
+func TestPostEntries_PostInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"1","url":"https://mastodon.example/@me/1"}`))
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var waits []time.Duration
+	poster.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	poster.SetPostInterval(5 * time.Minute)
+
+	t.Run("waits between posts", func(t *testing.T) {
+		waits = nil
+		if _, err := poster.PostEntries(newTestEntries(t, 3), newTestRenderer(t, "{{.Item.Title}}"), false); err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+		if len(waits) != 2 || waits[0] != 5*time.Minute {
+			t.Errorf("waits = %v, want two waits of 5m", waits)
+		}
+	})
+
+	t.Run("doesn't wait in dry run", func(t *testing.T) {
+		waits = nil
+		if _, err := poster.PostEntries(newTestEntries(t, 3), newTestRenderer(t, "{{.Item.Title}}"), true); err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+		if len(waits) != 0 {
+			t.Errorf("waits = %v, want none", waits)
+		}
+	})
+
+	t.Run("stops waiting when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		poster.sleep = func(ctx context.Context, d time.Duration) error {
+			cancel()
+			return sleepContext(ctx, d)
+		}
+
+		results, err := poster.PostEntriesContext(ctx, newTestEntries(t, 3), newTestRenderer(t, "{{.Item.Title}}"), false)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("PostEntriesContext() error = %v, want context.Canceled", err)
+		}
+		if got := destination.CountPosted(results); got != 1 || results[1].Outcome != destination.OutcomeSkipped {
+			t.Errorf("posted %d, second outcome %v; want 1 posted and the rest skipped", got, results[1].Outcome)
+		}
+	})
+}
+
+func TestPostEntries_Unauthorized(t *testing.T) {
+	t.Run("stops posting when token is rejected", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"The access token is invalid"}`))
+		}))
+		defer server.Close()
+
+		entries := newTestEntries(t, 3)
+		renderer := newTestRenderer(t, "{{.Item.Title}}")
+
+		poster, err := New(server.URL, "expired-token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		results, err := poster.PostEntries(entries, renderer, false)
+		count := destination.CountPosted(results)
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("PostEntries() error = %v, want ErrUnauthorized", err)
+		}
+		if count != 0 {
+			t.Errorf("PostEntries() count = %d, want 0", count)
+		}
+		if requests != 1 {
+			t.Errorf("server received %d requests, want 1", requests)
+		}
+
+		wantOutcomes := []destination.Outcome{destination.OutcomeFailed, destination.OutcomeSkipped, destination.OutcomeSkipped}
+		for i, result := range results {
+			if result.Outcome != wantOutcomes[i] {
+				t.Errorf("results[%d].Outcome = %v, want %v", i, result.Outcome, wantOutcomes[i])
+			}
+		}
+	})
+}
+
+func TestPostEntries_Results(t *testing.T) {
+	t.Run("reports which entries were posted", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 2 {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = w.Write([]byte(`{"error":"Validation failed"}`))
+				return
+			}
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"id":"%d","url":"https://mastodon.example/@me/%d"}`, requests, requests)))
+		}))
+		defer server.Close()
+
+		entries := newTestEntries(t, 3)
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		results, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false)
+		if err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+		if len(results) != len(entries) {
+			t.Fatalf("PostEntries() returned %d results, want %d", len(results), len(entries))
+		}
+
+		wantOutcomes := []destination.Outcome{destination.OutcomePosted, destination.OutcomeFailed, destination.OutcomePosted}
+		for i, result := range results {
+			if result.Entry != entries[i] {
+				t.Errorf("results[%d].Entry = %s, want %s", i, result.Entry.ID, entries[i].ID)
+			}
+			if result.Outcome != wantOutcomes[i] {
+				t.Errorf("results[%d].Outcome = %v, want %v", i, result.Outcome, wantOutcomes[i])
+			}
+		}
+		if results[1].Err == nil {
+			t.Error("failed result should carry its error")
+		}
+		if results[2].Entry.StatusID.String != "3" {
+			t.Errorf("third entry StatusID = %q, want 3", results[2].Entry.StatusID.String)
+		}
+		if destination.CountPosted(results) != 2 {
+			t.Errorf("destination.CountPosted() = %d, want 2", destination.CountPosted(results))
+		}
+	})
+}
+
+func TestPostEntries_ServerUnavailable(t *testing.T) {
+	t.Run("gives up after consecutive server errors", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		entries := newTestEntries(t, 4)
+		renderer := newTestRenderer(t, "{{.Item.Title}}")
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		poster.SetOutageThreshold(2)
+		poster.limits.retries = 0
+
+		results, err := poster.PostEntries(entries, renderer, false)
+		count := destination.CountPosted(results)
+		if !errors.Is(err, ErrServerUnavailable) {
+			t.Errorf("PostEntries() error = %v, want ErrServerUnavailable", err)
+		}
+		if count != 0 {
+			t.Errorf("PostEntries() count = %d, want 0", count)
+		}
+		if requests != 2 {
+			t.Errorf("server received %d requests, want 2", requests)
+		}
+	})
+
+	t.Run("client errors don't count as outage", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}))
+		defer server.Close()
+
+		entries := newTestEntries(t, 3)
+		renderer := newTestRenderer(t, "{{.Item.Title}}")
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		poster.SetOutageThreshold(1)
+
+		if _, err := poster.PostEntries(entries, renderer, false); err != nil {
+			t.Errorf("PostEntries() error = %v, want nil", err)
+		}
+		if requests != 3 {
+			t.Errorf("server received %d requests, want 3", requests)
+		}
+	})
+}
+
+func TestPostContent(t *testing.T) {
+	var status string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		status = r.PostForm.Get("status")
+		_, _ = w.Write([]byte(`{"id":"7","url":"https://mastodon.example/@me/7"}`))
+	}))
+	defer server.Close()
+
+	entries := newTestEntries(t, 1)
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := poster.PostContent(entries[0], "Edited by hand", 500, false); err != nil {
+		t.Fatalf("PostContent() error = %v", err)
+	}
+	if status != "Edited by hand" {
+		t.Errorf("status = %q, want %q", status, "Edited by hand")
+	}
+	if entries[0].StatusID.String != "7" || entries[0].PostedContent.String != "Edited by hand" {
+		t.Errorf("entry = status %q, content %q; want 7, Edited by hand", entries[0].StatusID.String, entries[0].PostedContent.String)
+	}
+}
+
+func TestUpdateEntries(t *testing.T) {
+	t.Run("edits the stored status", func(t *testing.T) {
+		var method, path, status string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method, path = r.Method, r.URL.Path
+			_ = r.ParseForm()
+			status = r.PostForm.Get("status")
+			_, _ = w.Write([]byte(`{"id":"110","url":"https://mastodon.example/@me/110"}`))
+		}))
+		defer server.Close()
+
+		entries := newTestEntries(t, 1)
+		entries[0].StatusID = sql.NullString{String: "110", Valid: true}
+		entries[0].PostedContent = sql.NullString{String: "@author@example.social Old title", Valid: true}
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		results, err := poster.UpdateEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false)
+		if err != nil || destination.CountPosted(results) != 1 {
+			t.Fatalf("UpdateEntries() = %d, %v; want 1, nil", destination.CountPosted(results), err)
+		}
+		if method != http.MethodPut || path != "/api/v1/statuses/110" {
+			t.Errorf("request = %s %s, want PUT /api/v1/statuses/110", method, path)
+		}
+		want := "@author@example.social Entry 1"
+		if status != want {
+			t.Errorf("status = %q, want %q", status, want)
+		}
+		if entries[0].PostedContent.String != want {
+			t.Errorf("PostedContent = %q, want %q", entries[0].PostedContent.String, want)
+		}
+	})
+
+	t.Run("dry run makes no requests", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+		}))
+		defer server.Close()
+
+		entries := newTestEntries(t, 2)
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		results, err := poster.UpdateEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), true)
+		if err != nil || destination.CountPosted(results) != 2 {
+			t.Errorf("UpdateEntries() = %d, %v; want 2, nil", destination.CountPosted(results), err)
+		}
+		if requests != 0 {
+			t.Errorf("server received %d requests, want 0", requests)
+		}
+	})
+
+	t.Run("stops when the token is rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		entries := newTestEntries(t, 2)
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		results, err := poster.UpdateEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false)
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("UpdateEntries() error = %v, want ErrUnauthorized", err)
+		}
+		if results[1].Outcome != destination.OutcomeSkipped {
+			t.Errorf("second result = %v, want skipped", results[1].Outcome)
+		}
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+		}))
+		defer server.Close()
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results, err := poster.UpdateEntriesContext(ctx, newTestEntries(t, 2), newTestRenderer(t, "{{.Item.Title}}"), false)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("UpdateEntriesContext() error = %v, want context.Canceled", err)
+		}
+		if requests != 0 || results[0].Outcome != destination.OutcomeSkipped {
+			t.Errorf("server received %d requests with first result %v, want none and skipped", requests, results[0].Outcome)
+		}
+	})
+}
+
+// newTestEntries saves count simple entries to an in-memory database and
+// returns them as unposted entries.
+func newTestEntries(t *testing.T, count int) []*database.Entry {
+	t.Helper()
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for i := 1; i <= count; i++ {
+		itemJSON, _ := json.Marshal(&gofeed.Item{Title: fmt.Sprintf("Entry %d", i)})
+		if _, err := db.SaveEntry(fmt.Sprintf("entry-%d", i), itemJSON); err != nil {
+			t.Fatalf("SaveEntry() error = %v", err)
+		}
+	}
+
+	entries, err := db.GetUnpostedEntries(0)
+	if err != nil {
+		t.Fatalf("GetUnpostedEntries() error = %v", err)
+	}
+	return entries
+}
+
+// newTestRenderer creates a renderer for the given template text.
+func newTestRenderer(t *testing.T, tmpl string) *template.Renderer {
+	t.Helper()
+
+	tmplPath := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(tmplPath, []byte(tmpl), 0o644); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	renderer, err := template.New(tmplPath, 500)
+	if err != nil {
+		t.Fatalf("template.New() error = %v", err)
+	}
+	return renderer
+}
+
 // Note: Testing actual API calls to Mastodon would require either:
 // 1. A test Mastodon instance
 // 2. Mocking the Mastodon client (complex interface)
@@ -276,3 +737,62 @@ func TestPostEntries(t *testing.T) {
 // The tests above cover the logic we can test without making real API calls.
 // The dry run functionality is thoroughly tested, which is the most critical
 // path for ensuring the code works correctly.
+
+func TestPostEntries_ContentWarningRules(t *testing.T) {
+	var spoilers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		spoilers = append(spoilers, r.PostForm.Get("spoiler_text"))
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "static")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	renderer := newTestRenderer(t, "{{.Item.Title}}")
+	renderer.SetContentWarning("static")
+	if err := renderer.AddContentWarningRule("", "^Entry 2$", "second"); err != nil {
+		t.Fatalf("AddContentWarningRule() error = %v", err)
+	}
+
+	if _, err := poster.PostEntries(newTestEntries(t, 2), renderer, false); err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	if len(spoilers) != 2 || spoilers[0] != "static" || spoilers[1] != "second" {
+		t.Errorf("spoiler_text = %q, want [static second]", spoilers)
+	}
+}
+
+func TestPostEntries_VisibilityRules(t *testing.T) {
+	var visibilities []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		visibilities = append(visibilities, r.PostForm.Get("visibility"))
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	match, err := filter.New("Entry 2", "", nil, nil)
+	if err != nil {
+		t.Fatalf("filter.New() error = %v", err)
+	}
+	if err := poster.AddVisibilityRule(match, "unlisted"); err != nil {
+		t.Fatalf("AddVisibilityRule() error = %v", err)
+	}
+	if err := poster.AddVisibilityRule(match, "hidden"); err == nil {
+		t.Error("Expected error for invalid visibility")
+	}
+
+	if _, err := poster.PostEntries(newTestEntries(t, 2), newTestRenderer(t, "{{.Item.Title}}"), false); err != nil {
+		t.Fatalf("PostEntries() error = %v", err)
+	}
+	if len(visibilities) != 2 || visibilities[0] != "public" || visibilities[1] != "unlisted" {
+		t.Errorf("visibility = %q, want [public unlisted]", visibilities)
+	}
+}
@@ -0,0 +1,208 @@
+package mastodon
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	mastodon "github.com/mattn/go-mastodon"
+	"github.com/sirupsen/logrus"
+)
+
+// Status link modes control how entries that link to a fediverse status
+// are published.
+const (
+	// StatusLinkPlain posts the rendered content as-is.
+	StatusLinkPlain = "link"
+	// StatusLinkQuote publishes a quote post of the linked status.
+	StatusLinkQuote = "quote"
+	// StatusLinkReply publishes a reply to the linked status, mentioning
+	// its author.
+	StatusLinkReply = "reply"
+	// StatusLinkBoost boosts the linked status instead of posting.
+	StatusLinkBoost = "boost"
+)
+
+// boostedPrefix starts the posted content recorded for boosted entries.
+const boostedPrefix = "Boosted "
+
+// statusPathPatterns match the paths of status URLs on common fediverse
+// servers: Mastodon, Pleroma/Akkoma, and Misskey.
+var statusPathPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^/@[^/]+(@[^/]+)?/\d+/?$`),
+	regexp.MustCompile(`^/users/[^/]+/statuses/\d+/?$`),
+	regexp.MustCompile(`^/notice/[A-Za-z0-9]+/?$`),
+	regexp.MustCompile(`^/notes/[A-Za-z0-9]+/?$`),
+}
+
+// isStatusURL reports whether link looks like the URL of a fediverse status.
+func isStatusURL(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	for _, pattern := range statusPathPatterns {
+		if pattern.MatchString(u.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStatusLinkMode sets how entries linking to a fediverse status are
+// published: as a plain link, a quote post, a reply, or a boost.
+func (p *Poster) SetStatusLinkMode(mode string) error {
+	switch mode {
+	case "":
+		p.statusLinkMode = StatusLinkPlain
+	case StatusLinkPlain, StatusLinkQuote, StatusLinkReply, StatusLinkBoost:
+		p.statusLinkMode = mode
+	default:
+		return fmt.Errorf("invalid status link mode: %s (must be link, quote, reply, or boost)", mode)
+	}
+	return nil
+}
+
+// entryLink extracts the item link from stored entry JSON.
+func entryLink(entryJSON []byte) string {
+	var item struct {
+		Link string `json:"link"`
+	}
+	if err := json.Unmarshal(entryJSON, &item); err != nil {
+		return ""
+	}
+	return item.Link
+}
+
+// prepareStatusLink adjusts toot to quote or reply to the status at link,
+// according to the status link mode. Returns the context to post with.
+// If the status can't be resolved, the toot is left as a plain post.
+func (p *Poster) prepareStatusLink(ctx context.Context, toot *mastodon.Toot, link string, dryRun bool) context.Context {
+	if p.statusLinkMode == StatusLinkPlain || p.statusLinkMode == StatusLinkBoost || !isStatusURL(link) {
+		return ctx
+	}
+
+	if dryRun {
+		logrus.Infof("DRY RUN: Would publish as %s of %s", p.statusLinkMode, link)
+		return ctx
+	}
+
+	results, err := p.client.Search(ctx, link, true)
+	if err != nil || len(results.Statuses) == 0 {
+		logrus.Warnf("Could not resolve status %s, posting as a link: %v", link, err)
+		return ctx
+	}
+	target := results.Statuses[0]
+
+	switch p.statusLinkMode {
+	case StatusLinkQuote:
+		return withQuotedStatus(ctx, target.ID)
+	case StatusLinkReply:
+		toot.InReplyToID = target.ID
+		mention := "@" + target.Account.Acct
+		if !strings.Contains(toot.Status, mention) {
+			toot.Status = mention + " " + toot.Status
+		}
+	}
+	return ctx
+}
+
+// boostStatusLink boosts the status an entry links to, instead of posting
+// the entry, and records the boost on the entry unless in dry run mode.
+// Returns false if the entry doesn't link to a status or the status can't
+// be resolved, so the entry should be posted as usual.
+func (p *Poster) boostStatusLink(ctx context.Context, entry *database.Entry, dryRun bool) (bool, error) {
+	link := entryLink(entry.EntryData)
+	if !isStatusURL(link) {
+		return false, nil
+	}
+
+	if dryRun {
+		logrus.Infof("DRY RUN: Would boost %s", link)
+		return true, nil
+	}
+
+	results, err := p.client.Search(ctx, link, true)
+	if err != nil || len(results.Statuses) == 0 {
+		logrus.Warnf("Could not resolve status %s, posting as a link: %v", link, err)
+		return false, nil
+	}
+	target := results.Statuses[0]
+
+	status, err := p.client.Reblog(ctx, target.ID)
+	if err != nil {
+		if classified := classifyError(err); classified != nil {
+			return true, classified
+		}
+		return true, fmt.Errorf("failed to boost %s: %w", link, err)
+	}
+
+	// The boost links to the original status, which is what's worth
+	// showing later
+	statusURL := target.URL
+	if statusURL == "" {
+		statusURL = link
+	}
+	entry.PostedContent = sql.NullString{String: boostedPrefix + statusURL, Valid: true}
+	entry.StatusID = sql.NullString{String: string(status.ID), Valid: true}
+	entry.StatusURL = sql.NullString{String: statusURL, Valid: true}
+	logrus.Infof("Boosted %s", statusURL)
+	return true, nil
+}
+
+type quotedStatusKey struct{}
+
+// withQuotedStatus returns a context that makes the client's transport
+// attach a quoted_status_id to the next status it posts.
+func withQuotedStatus(ctx context.Context, id mastodon.ID) context.Context {
+	return context.WithValue(ctx, quotedStatusKey{}, id)
+}
+
+// withoutQuotedStatus returns a context for posting that doesn't quote a
+// status, like the replies of a thread whose first post is a quote.
+func withoutQuotedStatus(ctx context.Context) context.Context {
+	return context.WithValue(ctx, quotedStatusKey{}, nil)
+}
+
+// quoteTransport adds the quoted_status_id parameter to status posts, which
+// go-mastodon's Toot doesn't support. Servers without quote post support
+// ignore the parameter and publish a plain post.
+type quoteTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *quoteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id, ok := req.Context().Value(quotedStatusKey{}).(mastodon.ID)
+	if !ok || req.Method != http.MethodPost || req.URL.Path != "/api/v1/statuses" || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	params, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	params.Set("quoted_status_id", string(id))
+	encoded := params.Encode()
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(strings.NewReader(encoded))
+	req.ContentLength = int64(len(encoded))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader([]byte(encoded))), nil
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,231 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestIsStatusURL(t *testing.T) {
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://mastodon.social/@user/110000000000000000", true},
+		{"https://mastodon.social/@user@example.com/110000000000000000", true},
+		{"https://mastodon.social/users/user/statuses/110000000000000000", true},
+		{"https://pleroma.example/notice/AbC123", true},
+		{"https://misskey.example/notes/9abcdef", true},
+		{"https://mastodon.social/@user", false},
+		{"https://example.com/blog/2024/post", false},
+		{"ftp://mastodon.social/@user/1", false},
+		{"not a url", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isStatusURL(tt.link); got != tt.want {
+			t.Errorf("isStatusURL(%q) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestSetStatusLinkMode(t *testing.T) {
+	poster, err := New("https://mastodon.social", "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, mode := range []string{StatusLinkPlain, StatusLinkQuote, StatusLinkReply, StatusLinkBoost} {
+		if err := poster.SetStatusLinkMode(mode); err != nil {
+			t.Errorf("SetStatusLinkMode(%q) error = %v", mode, err)
+		}
+	}
+	if err := poster.SetStatusLinkMode("shout"); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+	if err := poster.SetStatusLinkMode(""); err != nil || poster.statusLinkMode != StatusLinkPlain {
+		t.Errorf("SetStatusLinkMode(\"\") mode = %q, err = %v; want link", poster.statusLinkMode, err)
+	}
+}
+
+func TestPostEntries_StatusLinks(t *testing.T) {
+	const link = "https://example.social/@author/111"
+
+	// newServer returns a fake Mastodon server that resolves link and
+	// records the form parameters of the posted status.
+	newServer := func(posted map[string]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v2/search":
+				_, _ = w.Write([]byte(`{"statuses":[{"id":"42","account":{"acct":"author@example.social"}}]}`))
+			case "/api/v1/statuses":
+				_ = r.ParseForm()
+				for key := range r.PostForm {
+					posted[key] = r.PostForm.Get(key)
+				}
+				_, _ = w.Write([]byte(`{"id":"100","url":"https://mastodon.example/@me/100"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	}
+
+	newEntries := func(t *testing.T) []*database.Entry {
+		itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Great toot", Link: link})
+		return []*database.Entry{{ID: "entry-1", EntryData: itemJSON}}
+	}
+
+	t.Run("quote mode adds quoted_status_id", func(t *testing.T) {
+		posted := map[string]string{}
+		server := newServer(posted)
+		defer server.Close()
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if err := poster.SetStatusLinkMode(StatusLinkQuote); err != nil {
+			t.Fatalf("SetStatusLinkMode() error = %v", err)
+		}
+
+		results, err := poster.PostEntries(newEntries(t), newTestRenderer(t, "{{.Item.Title}}"), false)
+		count := destination.CountPosted(results)
+		if err != nil || count != 1 {
+			t.Fatalf("PostEntries() = %d, %v; want 1, nil", count, err)
+		}
+		if posted["quoted_status_id"] != "42" {
+			t.Errorf("quoted_status_id = %q, want 42", posted["quoted_status_id"])
+		}
+		if posted["status"] != "Great toot" {
+			t.Errorf("status = %q, want %q", posted["status"], "Great toot")
+		}
+	})
+
+	t.Run("reply mode mentions the author", func(t *testing.T) {
+		posted := map[string]string{}
+		server := newServer(posted)
+		defer server.Close()
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if err := poster.SetStatusLinkMode(StatusLinkReply); err != nil {
+			t.Fatalf("SetStatusLinkMode() error = %v", err)
+		}
+
+		entries := newEntries(t)
+		if _, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false); err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+		if posted["in_reply_to_id"] != "42" {
+			t.Errorf("in_reply_to_id = %q, want 42", posted["in_reply_to_id"])
+		}
+		want := "@author@example.social Great toot"
+		if posted["status"] != want {
+			t.Errorf("status = %q, want %q", posted["status"], want)
+		}
+		if entries[0].PostedContent.String != want {
+			t.Errorf("PostedContent = %q, want %q", entries[0].PostedContent.String, want)
+		}
+		if _, ok := posted["quoted_status_id"]; ok {
+			t.Error("reply should not set quoted_status_id")
+		}
+	})
+
+	t.Run("boost mode boosts instead of posting", func(t *testing.T) {
+		reblogged := ""
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v2/search":
+				_, _ = w.Write([]byte(`{"statuses":[{"id":"42","url":"https://example.social/@author/111","account":{"acct":"author@example.social"}}]}`))
+			case "/api/v1/statuses/42/reblog":
+				reblogged = "42"
+				_, _ = w.Write([]byte(`{"id":"200","reblog":{"id":"42"}}`))
+			default:
+				t.Errorf("unexpected request to %s", r.URL.Path)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if err := poster.SetStatusLinkMode(StatusLinkBoost); err != nil {
+			t.Fatalf("SetStatusLinkMode() error = %v", err)
+		}
+
+		entries := newEntries(t)
+		results, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false)
+		if err != nil || destination.CountPosted(results) != 1 {
+			t.Fatalf("PostEntries() = %v, %v; want 1 posted", results, err)
+		}
+		if reblogged != "42" {
+			t.Error("status 42 was not boosted")
+		}
+		if entries[0].StatusID.String != "200" || entries[0].StatusURL.String != link {
+			t.Errorf("status = %q, %q", entries[0].StatusID.String, entries[0].StatusURL.String)
+		}
+		if entries[0].PostedContent.String != "Boosted "+link {
+			t.Errorf("PostedContent = %q", entries[0].PostedContent.String)
+		}
+
+		// Boosts have nothing to edit
+		results, err = poster.UpdateEntries(entries, newTestRenderer(t, "{{.Item.Title}} (edited)"), false)
+		if err != nil || results[0].Outcome != destination.OutcomePosted {
+			t.Errorf("UpdateEntries() = %v, %v", results, err)
+		}
+	})
+
+	t.Run("boost mode posts entries not linking to a status", func(t *testing.T) {
+		posted := map[string]string{}
+		server := newServer(posted)
+		defer server.Close()
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if err := poster.SetStatusLinkMode(StatusLinkBoost); err != nil {
+			t.Fatalf("SetStatusLinkMode() error = %v", err)
+		}
+
+		itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Blog post", Link: "https://example.com/blog/post"})
+		entries := []*database.Entry{{ID: "entry-1", EntryData: itemJSON}}
+		if _, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false); err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+		if posted["status"] != "Blog post" {
+			t.Errorf("status = %q, want the entry posted", posted["status"])
+		}
+	})
+
+	t.Run("link mode posts plainly", func(t *testing.T) {
+		posted := map[string]string{}
+		server := newServer(posted)
+		defer server.Close()
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		if _, err := poster.PostEntries(newEntries(t), newTestRenderer(t, "{{.Item.Title}}"), false); err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+		if _, ok := posted["in_reply_to_id"]; ok {
+			t.Error("link mode should not reply")
+		}
+		if _, ok := posted["quoted_status_id"]; ok {
+			t.Error("link mode should not quote")
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package mastodon
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mastodon "github.com/mattn/go-mastodon"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrRateLimited is returned when the server's rate limit is exhausted for
+// longer than the client is willing to wait.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitError reports that the server's rate limit is exhausted, and
+// when it resets.
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v until %s", ErrRateLimited, e.Reset.Format(time.RFC3339))
+}
+
+// Unwrap makes RateLimitError match ErrRateLimited.
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// ErrMissingScope is returned when the access token wasn't granted the
+// scope an action needs, e.g. write:statuses to post.
+var ErrMissingScope = errors.New("access token lacks the write:statuses scope")
+
+// Defaults for rateLimitTransport.
+const (
+	// defaultMaxRateLimitWait is the longest a request waits for the rate
+	// limit to reset.
+	defaultMaxRateLimitWait = 5 * time.Minute
+	// defaultRequestRetries is how many times a request is retried after a
+	// server error or a rate limit response.
+	defaultRequestRetries = 2
+	// defaultRequestBackoff is the delay before the first retry after a
+	// server error, doubled for each retry.
+	defaultRequestBackoff = 2 * time.Second
+)
+
+// isMissingScope reports whether err is a 403 response from the Mastodon
+// API about the token's scopes.
+func isMissingScope(err error) bool {
+	var apiErr *mastodon.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden &&
+		strings.Contains(apiErr.Message, "outside the authorized scopes")
+}
+
+// classifyError wraps an error from the Mastodon API in ErrUnauthorized,
+// ErrMissingScope, or ErrServerUnavailable, returns a rate limit error as
+// is, or returns nil if it's none of them.
+func classifyError(err error) error {
+	switch {
+	case isUnauthorized(err):
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	case isMissingScope(err):
+		// A token without the scope is as unusable as a revoked one
+		return fmt.Errorf("%w: %w", ErrUnauthorized, ErrMissingScope)
+	case errors.Is(err, ErrRateLimited):
+		// Keep the reset time, which says how long to wait, unlike an outage
+		return err
+	case isServerUnavailable(err):
+		return fmt.Errorf("%w: %v", ErrServerUnavailable, err)
+	}
+	return nil
+}
+
+// rateLimitTransport follows the server's rate limits, waiting for them to
+// reset when they're exhausted, and retries requests that failed with a
+// server error. Status posts get an idempotency key, so a retried post
+// whose first attempt went through isn't published twice. Without
+// idempotency, status posts aren't retried after server errors.
+//
+// A rate limit that resets later than maxWait fails the request with a
+// *RateLimitError, instead of returning the 429 response, which go-mastodon
+// would retry for up to hours.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	maxWait time.Duration
+	retries int
+	backoff time.Duration
+	sleep   func(context.Context, time.Duration) error
+	now     func() time.Time
+	// idempotency adds idempotency keys to status posts
+	idempotency bool
+
+	mu      sync.Mutex
+	resetAt time.Time
+}
+
+// newRateLimitTransport creates a rateLimitTransport with the default
+// limits.
+func newRateLimitTransport(base http.RoundTripper) *rateLimitTransport {
+	return &rateLimitTransport{
+		base:    base,
+		maxWait: defaultMaxRateLimitWait,
+		retries: defaultRequestRetries,
+		backoff: defaultRequestBackoff,
+		sleep:   sleepContext,
+		now:     time.Now,
+
+		idempotency: true,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if t.idempotency && req.Method == http.MethodPost && req.URL.Path == "/api/v1/statuses" && req.Header.Get("Idempotency-Key") == "" {
+		req = req.Clone(ctx)
+		req.Header.Set("Idempotency-Key", idempotencyKey())
+	}
+
+	for attempt := 0; ; attempt++ {
+		// Wait out a rate limit the last response used up
+		if wait := t.untilReset(); wait > 0 && wait <= t.maxWait {
+			logrus.Infof("Rate limit reached, waiting %s for it to reset", wait.Round(time.Second))
+			if err := t.sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			if attempt >= t.retries || !retryable(req) || ctx.Err() != nil {
+				return nil, err
+			}
+			if err := t.retryAfterError(ctx, attempt, err.Error()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		t.recordLimit(resp.Header)
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			discard(resp)
+			wait := t.rateLimitWait(resp.Header)
+			if attempt >= t.retries || wait > t.maxWait {
+				return nil, &RateLimitError{Reset: t.now().Add(wait)}
+			}
+			logrus.Infof("Rate limited by the server, retrying in %s", wait.Round(time.Second))
+			if err := t.sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		case resp.StatusCode >= http.StatusInternalServerError && attempt < t.retries && retryable(req):
+			discard(resp)
+			if err := t.retryAfterError(ctx, attempt, resp.Status); err != nil {
+				return nil, err
+			}
+		default:
+			return resp, nil
+		}
+	}
+}
+
+// retryable reports whether a request can be sent again after a server
+// error without risking doing its work twice. Posts without an idempotency
+// key, like media uploads, can't.
+func retryable(req *http.Request) bool {
+	return req.Method != http.MethodPost || req.Header.Get("Idempotency-Key") != ""
+}
+
+// rewind returns the request to send for an attempt, with a fresh copy of
+// the body for retries.
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("can't retry request to %s: body can't be rewound", req.URL.Path)
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
+
+// retryAfterError waits before retrying a request that failed, doubling
+// the backoff for each attempt and adding up to half again as jitter, so
+// clients don't retry in lockstep.
+func (t *rateLimitTransport) retryAfterError(ctx context.Context, attempt int, reason string) error {
+	delay := t.backoff << attempt
+	delay += time.Duration(rand.Int64N(int64(delay/2) + 1))
+	logrus.Warnf("Request to Mastodon failed (%s), retrying in %s", reason, delay.Round(time.Millisecond))
+	return t.sleep(ctx, delay)
+}
+
+// recordLimit remembers when the rate limit resets if the response used it
+// up, so the next request waits for it.
+func (t *rateLimitTransport) recordLimit(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return
+	}
+	reset, err := time.Parse(time.RFC3339, header.Get("X-RateLimit-Reset"))
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	t.resetAt = reset
+	t.mu.Unlock()
+}
+
+// untilReset returns how long until a used up rate limit resets, or 0.
+func (t *rateLimitTransport) untilReset() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return max(t.resetAt.Sub(t.now()), 0)
+}
+
+// rateLimitWait returns how long a 429 response asks to wait, from its
+// X-RateLimit-Reset or Retry-After header, or the backoff if it has neither.
+func (t *rateLimitTransport) rateLimitWait(header http.Header) time.Duration {
+	if reset, err := time.Parse(time.RFC3339, header.Get("X-RateLimit-Reset")); err == nil {
+		return max(reset.Sub(t.now()), 0)
+	}
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header.Get("Retry-After")); err == nil {
+		return max(at.Sub(t.now()), 0)
+	}
+	return t.backoff
+}
+
+// discard reads and closes the body of a response that won't be returned,
+// so its connection can be reused.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// idempotencyKey returns a random key identifying a status post across
+// retries.
+func idempotencyKey() string {
+	key := make([]byte, 16)
+	_, _ = cryptorand.Read(key)
+	return hex.EncodeToString(key)
+}
@@ -0,0 +1,176 @@
+package mastodon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newLimitedPoster creates a poster for server whose waits are recorded in
+// waits instead of slept.
+func newLimitedPoster(t *testing.T, server *httptest.Server, waits *[]time.Duration) *Poster {
+	t.Helper()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	poster.limits.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return poster
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	t.Run("waits for the rate limit to reset and retries", func(t *testing.T) {
+		var keys []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+			if len(keys) == 1 {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", time.Now().Add(30*time.Second).UTC().Format(time.RFC3339))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"1","url":"https://mastodon.example/@me/1"}`))
+		}))
+		defer server.Close()
+
+		var waits []time.Duration
+		poster := newLimitedPoster(t, server, &waits)
+		if _, err := poster.Post("Hello", false); err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+
+		if len(keys) != 2 {
+			t.Fatalf("server received %d requests, want 2", len(keys))
+		}
+		if keys[0] == "" || keys[0] != keys[1] {
+			t.Errorf("Idempotency-Key = %q then %q, want the same key", keys[0], keys[1])
+		}
+		if len(waits) == 0 || waits[0] < 20*time.Second || waits[0] > 30*time.Second {
+			t.Errorf("waits = %v, want about 30s for the reset", waits)
+		}
+	})
+
+	t.Run("fails when the reset is too far off", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("X-RateLimit-Reset", time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		var waits []time.Duration
+		poster := newLimitedPoster(t, server, &waits)
+		entries := newTestEntries(t, 3)
+		renderer := newTestRenderer(t, "{{.Item.Title}}")
+
+		_, err := poster.PostEntries(entries, renderer, false)
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) || errors.Is(err, ErrServerUnavailable) {
+			t.Fatalf("PostEntries() error = %v, want a RateLimitError and not ErrServerUnavailable", err)
+		}
+		if until := time.Until(rateLimitErr.Reset); until < 59*time.Minute || until > time.Hour {
+			t.Errorf("RateLimitError.Reset = %s, want in an hour", rateLimitErr.Reset)
+		}
+		if requests != 1 {
+			t.Errorf("server received %d requests, want 1 before giving up on the batch", requests)
+		}
+		if len(waits) != 0 {
+			t.Errorf("waits = %v, want none", waits)
+		}
+	})
+
+	t.Run("retries server errors with backoff", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"1","url":"https://mastodon.example/@me/1"}`))
+		}))
+		defer server.Close()
+
+		var waits []time.Duration
+		poster := newLimitedPoster(t, server, &waits)
+		if _, err := poster.Post("Hello", false); err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+
+		if requests != 3 {
+			t.Errorf("server received %d requests, want 3", requests)
+		}
+		if len(waits) != 2 || waits[0] < defaultRequestBackoff || waits[1] < 2*defaultRequestBackoff || waits[1] > 3*defaultRequestBackoff {
+			t.Errorf("waits = %v, want doubling backoff with jitter", waits)
+		}
+	})
+
+	t.Run("doesn't retry media uploads after server errors", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		var waits []time.Duration
+		poster := newLimitedPoster(t, server, &waits)
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/v2/media", nil)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		resp, err := poster.limits.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		resp.Body.Close()
+
+		if requests != 1 || resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("server received %d requests with status %d, want 1 with 502", requests, resp.StatusCode)
+		}
+	})
+
+	t.Run("waits before a request when the limit is used up", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", time.Now().Add(time.Minute).UTC().Format(time.RFC3339))
+			_, _ = w.Write([]byte(`{"id":"1","url":"https://mastodon.example/@me/1"}`))
+		}))
+		defer server.Close()
+
+		var waits []time.Duration
+		poster := newLimitedPoster(t, server, &waits)
+		for range 2 {
+			if _, err := poster.Post("Hello", false); err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+		}
+
+		if len(waits) != 1 || waits[0] < 50*time.Second {
+			t.Errorf("waits = %v, want one wait of about a minute before the second post", waits)
+		}
+	})
+}
+
+func TestPost_MissingScope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":"This action is outside the authorized scopes"}`))
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	poster := newLimitedPoster(t, server, &waits)
+	_, err := poster.Post("Hello", false)
+	if !errors.Is(err, ErrMissingScope) || !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Post() error = %v, want ErrMissingScope and ErrUnauthorized", err)
+	}
+}
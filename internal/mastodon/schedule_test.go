@@ -0,0 +1,97 @@
+package mastodon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+)
+
+func TestPostEntries_ScheduleSpread(t *testing.T) {
+	var scheduled []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		scheduled = append(scheduled, r.PostForm.Get("scheduled_at"))
+		id := len(scheduled)
+		if r.PostForm.Get("scheduled_at") != "" {
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"id":"s%d","scheduled_at":%q}`, id, r.PostForm.Get("scheduled_at"))))
+			return
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"id":"%d","url":"https://mastodon.example/@me/%d"}`, id, id)))
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	start := time.Now()
+	poster.SetScheduleSpread(start, 30*time.Minute)
+
+	entries := newTestEntries(t, 3)
+	results, err := poster.PostEntries(entries, newTestRenderer(t, "{{.Item.Title}}"), false)
+	if err != nil || destination.CountPosted(results) != 3 {
+		t.Fatalf("PostEntries() = %d, %v; want 3, nil", destination.CountPosted(results), err)
+	}
+
+	// The first entry is due now, so it's posted immediately
+	if scheduled[0] != "" {
+		t.Errorf("first post scheduled_at = %q, want none", scheduled[0])
+	}
+	if entries[0].StatusID.String != "1" || entries[0].ScheduledAt.Valid {
+		t.Errorf("first entry = status %q, scheduled %v; want posted", entries[0].StatusID.String, entries[0].ScheduledAt.Valid)
+	}
+
+	for i, entry := range entries[1:] {
+		want := start.Add(time.Duration(i+1) * 30 * time.Minute).Truncate(time.Second)
+		if !entry.ScheduledAt.Valid || !entry.ScheduledAt.Time.Equal(want) {
+			t.Errorf("entry %d ScheduledAt = %v, want %v", i+2, entry.ScheduledAt.Time, want)
+		}
+		if scheduled[i+1] != want.Format(time.RFC3339) {
+			t.Errorf("entry %d scheduled_at = %q, want %q", i+2, scheduled[i+1], want.Format(time.RFC3339))
+		}
+		if entry.StatusID.Valid || entry.ScheduledID.String != fmt.Sprintf("s%d", i+2) {
+			t.Errorf("entry %d = status %q, scheduled %q; want only a scheduled ID", i+2, entry.StatusID.String, entry.ScheduledID.String)
+		}
+	}
+}
+
+func TestScheduleTime(t *testing.T) {
+	poster := &Poster{}
+	if at := poster.scheduleTime(1); at != nil {
+		t.Errorf("scheduleTime() without spread = %v, want nil", at)
+	}
+
+	// Posts due too soon are pushed past the minimum lead time
+	poster.SetScheduleSpread(time.Now().Add(time.Minute), 10*time.Minute)
+	at := poster.scheduleTime(0)
+	if at == nil || time.Until(*at) < minScheduleLead {
+		t.Errorf("scheduleTime(0) = %v, want at least %s ahead", at, minScheduleLead)
+	}
+}
+
+func TestScheduleTime_Window(t *testing.T) {
+	poster := &Poster{}
+	// At least an hour ahead, so the minimum lead time never moves it
+	start := time.Now().Add(2 * time.Hour).Truncate(time.Hour)
+	poster.SetScheduleSpread(start, time.Hour)
+
+	// Only allow posting in the first hour after start, then a day later
+	opens := start.Add(24 * time.Hour)
+	poster.SetScheduleWindow(func(at time.Time) time.Time {
+		if at.Before(start.Add(time.Hour)) || !at.Before(opens) {
+			return at
+		}
+		return opens
+	})
+
+	want := []time.Time{start, opens, opens.Add(time.Hour)}
+	for n, w := range want {
+		if at := poster.scheduleTime(n); at == nil || !at.Equal(w) {
+			t.Errorf("scheduleTime(%d) = %v, want %v", n, at, w)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package mastodon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lorchard/feed-to-mastodon/internal/destination"
+	"github.com/lorchard/feed-to-mastodon/internal/filter"
+)
+
+func TestPostEntries_SplitLongPosts(t *testing.T) {
+	type post struct {
+		status     string
+		inReplyTo  string
+		visibility string
+		spoiler    string
+	}
+
+	// newServer returns a fake Mastodon server that records posted statuses.
+	newServer := func(posts *[]post) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			*posts = append(*posts, post{
+				status:     r.PostForm.Get("status"),
+				inReplyTo:  r.PostForm.Get("in_reply_to_id"),
+				visibility: r.PostForm.Get("visibility"),
+				spoiler:    r.PostForm.Get("spoiler_text"),
+			})
+			id := len(*posts)
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"id":"%d","url":"https://mastodon.example/@me/%d"}`, id, id)))
+		}))
+	}
+
+	longTitle := strings.Repeat("lorem ipsum ", 60)
+
+	t.Run("posts long entries as a thread", func(t *testing.T) {
+		var posts []post
+		server := newServer(&posts)
+		defer server.Close()
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		poster.SetSplitLongPosts(true)
+
+		entries := newTestEntries(t, 1)
+		renderer := newTestRenderer(t, longTitle+"{{.Item.Title}}")
+		results, err := poster.PostEntries(entries, renderer, false)
+		if err != nil || destination.CountPosted(results) != 1 {
+			t.Fatalf("PostEntries() = %d, %v; want 1, nil", destination.CountPosted(results), err)
+		}
+
+		if len(posts) < 2 {
+			t.Fatalf("posted %d statuses, want a thread", len(posts))
+		}
+		if posts[0].inReplyTo != "" {
+			t.Errorf("first post in_reply_to_id = %q, want none", posts[0].inReplyTo)
+		}
+		for i, p := range posts[1:] {
+			if want := fmt.Sprint(i + 1); p.inReplyTo != want {
+				t.Errorf("post %d in_reply_to_id = %q, want %q", i+2, p.inReplyTo, want)
+			}
+		}
+		if entries[0].StatusID.String != "1" {
+			t.Errorf("StatusID = %q, want the first post", entries[0].StatusID.String)
+		}
+		if n := len(entries[0].ThreadStatusIDs); n != len(posts)-1 || entries[0].ThreadStatusIDs[0] != "2" {
+			t.Errorf("ThreadStatusIDs = %v, want the %d replies", entries[0].ThreadStatusIDs, len(posts)-1)
+		}
+		if strings.Count(entries[0].PostedContent.String, threadSeparator) != len(posts)-1 {
+			t.Errorf("PostedContent doesn't include every part: %q", entries[0].PostedContent.String)
+		}
+	})
+
+	t.Run("replies keep the entry's visibility and content warning", func(t *testing.T) {
+		var posts []post
+		server := newServer(&posts)
+		defer server.Close()
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		poster.SetSplitLongPosts(true)
+		match, err := filter.New("Entry", "", nil, nil)
+		if err != nil {
+			t.Fatalf("filter.New() error = %v", err)
+		}
+		if err := poster.AddVisibilityRule(match, "unlisted"); err != nil {
+			t.Fatalf("AddVisibilityRule() error = %v", err)
+		}
+
+		renderer := newTestRenderer(t, longTitle+"{{.Item.Title}}")
+		if err := renderer.SetContentWarningTemplate("{{.Item.Title}}"); err != nil {
+			t.Fatalf("SetContentWarningTemplate() error = %v", err)
+		}
+		if _, err := poster.PostEntries(newTestEntries(t, 1), renderer, false); err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+
+		if len(posts) < 2 {
+			t.Fatalf("posted %d statuses, want a thread", len(posts))
+		}
+		for i, p := range posts {
+			if p.visibility != "unlisted" || p.spoiler != "Entry 1" {
+				t.Errorf("post %d visibility, spoiler_text = %q, %q; want unlisted, Entry 1", i+1, p.visibility, p.spoiler)
+			}
+		}
+	})
+
+	t.Run("posts whole when disabled", func(t *testing.T) {
+		var posts []post
+		server := newServer(&posts)
+		defer server.Close()
+
+		poster, err := New(server.URL, "token", "public", "")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		renderer := newTestRenderer(t, longTitle+"{{.Item.Title}}")
+		if _, err := poster.PostEntries(newTestEntries(t, 1), renderer, false); err != nil {
+			t.Fatalf("PostEntries() error = %v", err)
+		}
+		if len(posts) != 1 {
+			t.Errorf("posted %d statuses, want 1", len(posts))
+		}
+	})
+}
+
+func TestPostEntries_ThreadAnchor(t *testing.T) {
+	var replies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		replies = append(replies, r.PostForm.Get("in_reply_to_id"))
+		id := len(replies)
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"id":"%d","url":"https://mastodon.example/@me/%d"}`, id, id)))
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	poster.SetThreadAnchor("109")
+
+	entries := newTestEntries(t, 3)
+	renderer := newTestRenderer(t, "{{.Item.Title}}")
+	results, err := poster.PostEntries(entries, renderer, false)
+	if err != nil || destination.CountPosted(results) != 3 {
+		t.Fatalf("PostEntries() = %d, %v; want 3, nil", destination.CountPosted(results), err)
+	}
+
+	for i, inReplyTo := range replies {
+		if inReplyTo != "109" {
+			t.Errorf("post %d in_reply_to_id = %q, want the anchor 109", i+1, inReplyTo)
+		}
+	}
+}
+
+func TestPostEntries_ContinueThread(t *testing.T) {
+	var replies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		replies = append(replies, r.PostForm.Get("in_reply_to_id"))
+		id := len(replies)
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"id":"%d","url":"https://mastodon.example/@me/%d"}`, id, id)))
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	poster.SetThreadAnchor("109")
+	poster.SetContinueThread("")
+
+	entries := newTestEntries(t, 3)
+	renderer := newTestRenderer(t, "{{.Item.Title}}")
+	results, err := poster.PostEntries(entries, renderer, false)
+	if err != nil || destination.CountPosted(results) != 3 {
+		t.Fatalf("PostEntries() = %d, %v; want 3, nil", destination.CountPosted(results), err)
+	}
+
+	want := []string{"109", "1", "2"}
+	if strings.Join(replies, ",") != strings.Join(want, ",") {
+		t.Errorf("in_reply_to_id = %v, want %v", replies, want)
+	}
+}
@@ -0,0 +1,140 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+	mastodon "github.com/mattn/go-mastodon"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// timelinePageSize is the most statuses Mastodon returns per request.
+const timelinePageSize = 40
+
+// TimelineLinks maps the links in an account's recent statuses to the
+// status that linked them.
+type TimelineLinks map[string]Status
+
+// Find returns the status that linked to link, ignoring differences like
+// the scheme, a leading "www.", a trailing slash, and tracking parameters.
+func (l TimelineLinks) Find(link string) (Status, bool) {
+	if link == "" {
+		return Status{}, false
+	}
+	status, ok := l[linkKey(link)]
+	return status, ok
+}
+
+// RecentLinks returns the links in the authenticated account's latest
+// statuses, up to limit of them, to find entries that were already posted,
+// e.g. by hand or by an installation that lost its database. The links of
+// boosted statuses count too.
+func (p *Poster) RecentLinks(limit int) (TimelineLinks, error) {
+	return p.RecentLinksContext(context.Background(), limit)
+}
+
+// RecentLinksContext is like RecentLinks, but gives up when ctx is done.
+func (p *Poster) RecentLinksContext(ctx context.Context, limit int) (TimelineLinks, error) {
+	account, err := p.client.GetAccountCurrentUser(ctx)
+	if err != nil {
+		return nil, timelineError("failed to look up account", err)
+	}
+
+	links := make(TimelineLinks)
+	pageSize := min(limit, timelinePageSize)
+	pg := &mastodon.Pagination{Limit: int64(pageSize)}
+	for fetched := 0; fetched < limit; {
+		statuses, err := p.client.GetAccountStatuses(ctx, account.ID, pg)
+		if err != nil {
+			return nil, timelineError("failed to get recent statuses", err)
+		}
+		for _, status := range statuses[:min(len(statuses), limit-fetched)] {
+			found := Status{ID: string(status.ID), URL: status.URL}
+			for _, link := range statusLinks(status) {
+				if key := linkKey(link); key != "" {
+					if _, ok := links[key]; !ok {
+						links[key] = found
+					}
+				}
+			}
+		}
+
+		fetched += len(statuses)
+		if len(statuses) < pageSize {
+			break
+		}
+		// The client overwrites pg from the response's Link header
+		pg = &mastodon.Pagination{MaxID: statuses[len(statuses)-1].ID, Limit: int64(pageSize)}
+	}
+	return links, nil
+}
+
+// timelineError classifies an error reading the account's timeline like
+// publish does.
+func timelineError(action string, err error) error {
+	if classified := classifyError(err); classified != nil {
+		return classified
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
+// statusLinks returns the links in a status: its card, the links in its
+// content other than mentions and hashtags, and for boosts, the boosted
+// status itself.
+func statusLinks(status *mastodon.Status) []string {
+	var links []string
+	if status.Reblog != nil {
+		links = append(links, status.Reblog.URL, status.Reblog.URI)
+		status = status.Reblog
+	}
+	if status.Card != nil {
+		links = append(links, status.Card.URL)
+	}
+
+	doc, err := html.Parse(strings.NewReader(status.Content))
+	if err != nil {
+		return links
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			var href, class string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "href":
+					href = attr.Val
+				case "class":
+					class = attr.Val
+				}
+			}
+			if !strings.Contains(class, "mention") && !strings.Contains(class, "hashtag") {
+				links = append(links, href)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return links
+}
+
+// linkKey normalizes link for comparison, or returns "" if it isn't an
+// http(s) URL.
+func linkKey(link string) string {
+	u, err := url.Parse(template.CleanURL(strings.TrimSpace(link)))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	key := host + path
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
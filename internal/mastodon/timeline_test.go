@@ -0,0 +1,94 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestRecentLinks(t *testing.T) {
+	// The account's statuses, newest first
+	statuses := []map[string]any{
+		{"content": `<p>New post <a href="https://www.example.com/posts/1/?utm_source=rss">example.com/posts/1</a> <a href="https://social.example/tags/go" class="mention hashtag">#go</a></p>`},
+		{"content": "<p>Shared</p>", "reblog": map[string]any{"id": "5", "url": "https://other.example/@alice/5", "uri": "https://other.example/users/alice/statuses/5"}},
+	}
+	for i := 0; i < 40; i++ {
+		statuses = append(statuses, map[string]any{"content": "<p>Nothing to see</p>"})
+	}
+	statuses = append(statuses, map[string]any{"content": "<p>Card only</p>", "card": map[string]any{"url": "https://blog.example/old"}})
+	for i, status := range statuses {
+		id := strconv.Itoa(len(statuses) - i)
+		status["id"], status["url"] = id, "https://social.example/@bot/"+id
+	}
+
+	var maxIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/accounts/verify_credentials":
+			_, _ = w.Write([]byte(`{"id":"1","username":"bot"}`))
+		case "/api/v1/accounts/1/statuses":
+			maxIDs = append(maxIDs, r.URL.Query().Get("max_id"))
+			maxID, err := strconv.Atoi(r.URL.Query().Get("max_id"))
+			if err != nil {
+				maxID = len(statuses) + 1
+			}
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			page := []map[string]any{}
+			for _, status := range statuses {
+				if id, _ := strconv.Atoi(status["id"].(string)); id < maxID && len(page) < limit {
+					page = append(page, status)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(page)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	poster, err := New(server.URL, "token", "public", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	t.Run("finds links in content and boosts", func(t *testing.T) {
+		maxIDs = nil
+		links, err := poster.RecentLinks(2)
+		if err != nil {
+			t.Fatalf("RecentLinks() error = %v", err)
+		}
+		if len(maxIDs) != 1 {
+			t.Errorf("made %d requests, want 1", len(maxIDs))
+		}
+
+		if status, ok := links.Find("http://example.com/posts/1"); !ok || status.ID != "43" {
+			t.Errorf("Find(content link) = %+v, %v; want status 43", status, ok)
+		}
+		if status, ok := links.Find("https://other.example/@alice/5"); !ok || status.ID != "42" {
+			t.Errorf("Find(boosted status) = %+v, %v; want status 42", status, ok)
+		}
+		if _, ok := links.Find("https://social.example/tags/go"); ok {
+			t.Error("Find(hashtag) found a status, want none")
+		}
+		if _, ok := links.Find("https://blog.example/old"); ok {
+			t.Error("Find(link beyond limit) found a status, want none")
+		}
+	})
+
+	t.Run("pages through older statuses", func(t *testing.T) {
+		maxIDs = nil
+		links, err := poster.RecentLinks(100)
+		if err != nil {
+			t.Fatalf("RecentLinks() error = %v", err)
+		}
+		if status, ok := links.Find("https://blog.example/old"); !ok || status.ID != "1" {
+			t.Errorf("Find(card link) = %+v, %v; want status 1", status, ok)
+		}
+		if fmt.Sprint(maxIDs) != "[ 4]" {
+			t.Errorf("max_id of requests = %q, want the last status of each page", maxIDs)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	mastodon "github.com/mattn/go-mastodon"
+)
+
+// requestTimeout bounds each notification request.
+const requestTimeout = 30 * time.Second
+
+// maxMessageLength keeps direct messages well under the usual 500
+// character limit, leaving room for the mention.
+const maxMessageLength = 400
+
+// Events that notifications are sent for.
+const (
+	EventFetchFailed = "fetch_failed"
+	EventPostFailed  = "post_failed"
+)
+
+// Notification describes a repeated failure.
+type Notification struct {
+	// Event is EventFetchFailed or EventPostFailed.
+	Event string `json:"event"`
+	// Message describes the failure for people.
+	Message string `json:"message"`
+	// FeedURL is the feed being fetched or posted from.
+	FeedURL string `json:"feed_url"`
+	// Failures is how many times in a row it failed.
+	Failures int `json:"failures"`
+	// Error is the latest error.
+	Error string `json:"error,omitempty"`
+	// Time is when the notification was sent.
+	Time time.Time `json:"time"`
+}
+
+// Notifier sends notifications to a webhook, as a Mastodon direct message,
+// or both.
+type Notifier struct {
+	webhook string
+	client  *http.Client
+
+	// Direct messages are sent from the account of the access token
+	server      string
+	accessToken string
+	account     string
+}
+
+// New creates a Notifier that POSTs notifications as JSON to webhook, if
+// it's not empty.
+func New(webhook string) *Notifier {
+	return &Notifier{
+		webhook: webhook,
+		client:  &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// SetDirectMessage has notifications sent as direct messages to account,
+// like @admin@example.social, from the account of accessToken on server.
+func (n *Notifier) SetDirectMessage(server, accessToken, account string) {
+	n.server = server
+	n.accessToken = accessToken
+	n.account = "@" + strings.TrimPrefix(account, "@")
+}
+
+// Send delivers the notification everywhere it's configured to go,
+// returning the errors of any that failed.
+func (n *Notifier) Send(ctx context.Context, notification Notification) error {
+	if notification.Time.IsZero() {
+		notification.Time = time.Now()
+	}
+
+	var errs []error
+	if n.webhook != "" {
+		if err := n.sendWebhook(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if n.server != "" {
+		if err := n.sendDirectMessage(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendWebhook POSTs the notification as JSON to the webhook.
+func (n *Notifier) sendWebhook(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sendDirectMessage posts the notification as a direct-visibility status
+// mentioning the admin account.
+func (n *Notifier) sendDirectMessage(ctx context.Context, notification Notification) error {
+	message := notification.Message
+	if runes := []rune(message); len(runes) > maxMessageLength {
+		message = string(runes[:maxMessageLength-1]) + "…"
+	}
+
+	client := mastodon.NewClient(&mastodon.Config{
+		Server:      n.server,
+		AccessToken: n.accessToken,
+	})
+	_, err := client.PostStatus(ctx, &mastodon.Toot{
+		Status:     n.account + " " + message,
+		Visibility: "direct",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send direct message to %s: %w", n.account, err)
+	}
+	return nil
+}
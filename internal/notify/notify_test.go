@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendWebhook(t *testing.T) {
+	t.Run("posts notification as JSON", func(t *testing.T) {
+		var got Notification
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Errorf("Failed to decode notification: %v", err)
+			}
+		}))
+		defer server.Close()
+
+		err := New(server.URL).Send(context.Background(), Notification{
+			Event:    EventFetchFailed,
+			Message:  "Feed failed 3 times",
+			FeedURL:  "https://example.com/feed",
+			Failures: 3,
+			Error:    "connection refused",
+		})
+		if err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+
+		if got.Event != EventFetchFailed || got.Failures != 3 || got.Error != "connection refused" {
+			t.Errorf("notification = %+v", got)
+		}
+		if got.Time.IsZero() {
+			t.Error("Expected notification time to be set")
+		}
+	})
+
+	t.Run("reports non-2xx responses", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		if err := New(server.URL).Send(context.Background(), Notification{Message: "test"}); err == nil {
+			t.Error("Expected error for server error")
+		}
+	})
+}
+
+func TestSendDirectMessage(t *testing.T) {
+	var status, visibility string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/statuses" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token-123" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		_ = r.ParseForm()
+		status = r.FormValue("status")
+		visibility = r.FormValue("visibility")
+		_, _ = w.Write([]byte(`{"id": "1"}`))
+	}))
+	defer server.Close()
+
+	notifier := New("")
+	notifier.SetDirectMessage(server.URL, "token-123", "admin@example.social")
+	err := notifier.Send(context.Background(), Notification{Message: strings.Repeat("x", 600)})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if !strings.HasPrefix(status, "@admin@example.social ") {
+		t.Errorf("status = %q, want it to mention the admin", status)
+	}
+	if length := len([]rune(status)); length > 500 {
+		t.Errorf("status is %d characters, want at most 500", length)
+	}
+	if visibility != "direct" {
+		t.Errorf("visibility = %q, want direct", visibility)
+	}
+}
@@ -0,0 +1,104 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix marks values encrypted by a Cipher, and the scheme they
+// were encrypted with, AES-256-GCM.
+const encryptedPrefix = "enc:v1:"
+
+// keySize is the size of keys made by GenerateKey.
+const keySize = 32
+
+// ErrWrongKey is returned when a value can't be decrypted, because it was
+// encrypted with another key or was changed since.
+var ErrWrongKey = errors.New("wrong secret key, or the value was tampered with")
+
+// Cipher encrypts secrets kept at rest, like access tokens in the database.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher returns a Cipher for key. Any string works as a key, but it
+// should be long and random, like one from GenerateKey.
+func NewCipher(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, errors.New("secret key is empty")
+	}
+	hash := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(hash[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// GenerateKey returns a new random key.
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate secret key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypted reports whether value was encrypted by a Cipher.
+func Encrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Encrypt encrypts plaintext, returning text that's safe to store.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value from Encrypt.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return "", errors.New("value isn't encrypted")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("encrypted value is corrupt")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+	return string(plaintext), nil
+}
+
+// KeyringKey returns the key stored in the OS keyring for account,
+// generating and storing a new one the first time.
+func KeyringKey(account string) (string, error) {
+	key, err := Get(account)
+	if !errors.Is(err, ErrNotFound) {
+		return key, err
+	}
+	if key, err = GenerateKey(); err != nil {
+		return "", err
+	}
+	if err := Set(account, key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+
+	t.Run("round-trips values", func(t *testing.T) {
+		encrypted, err := c.Encrypt("token-123")
+		if err != nil {
+			t.Fatalf("Encrypt() error = %v", err)
+		}
+		if !Encrypted(encrypted) || strings.Contains(encrypted, "token-123") {
+			t.Errorf("Encrypt() = %q, want an encrypted value", encrypted)
+		}
+		decrypted, err := c.Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("Decrypt() error = %v", err)
+		}
+		if decrypted != "token-123" {
+			t.Errorf("Decrypt() = %q, want %q", decrypted, "token-123")
+		}
+	})
+
+	t.Run("uses a new nonce each time", func(t *testing.T) {
+		first, _ := c.Encrypt("token-123")
+		second, _ := c.Encrypt("token-123")
+		if first == second {
+			t.Error("Encrypt() returned the same value twice")
+		}
+	})
+
+	t.Run("rejects another key", func(t *testing.T) {
+		encrypted, _ := c.Encrypt("token-123")
+		other, err := NewCipher("another key")
+		if err != nil {
+			t.Fatalf("NewCipher() error = %v", err)
+		}
+		if _, err := other.Decrypt(encrypted); !errors.Is(err, ErrWrongKey) {
+			t.Errorf("Decrypt() error = %v, want ErrWrongKey", err)
+		}
+	})
+
+	t.Run("rejects tampered and plain values", func(t *testing.T) {
+		encrypted, _ := c.Encrypt("token-123")
+		tampered := encrypted[:len(encrypted)-4] + "AAAA"
+		if _, err := c.Decrypt(tampered); err == nil {
+			t.Error("Decrypt() of a tampered value succeeded")
+		}
+		if _, err := c.Decrypt("token-123"); err == nil {
+			t.Error("Decrypt() of a plain value succeeded")
+		}
+	})
+
+	t.Run("requires a key", func(t *testing.T) {
+		if _, err := NewCipher(""); err == nil {
+			t.Error("NewCipher(\"\") error = nil")
+		}
+	})
+}
+
+func TestKeyringKey(t *testing.T) {
+	keyring.MockInit()
+
+	key, err := KeyringKey("secret-key:/srv/feed.db")
+	if err != nil {
+		t.Fatalf("KeyringKey() error = %v", err)
+	}
+	if len(key) < keySize {
+		t.Errorf("KeyringKey() = %q, want a generated key", key)
+	}
+
+	again, err := KeyringKey("secret-key:/srv/feed.db")
+	if err != nil {
+		t.Fatalf("KeyringKey() error = %v", err)
+	}
+	if again != key {
+		t.Errorf("second KeyringKey() = %q, want the stored %q", again, key)
+	}
+}
@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the service name secrets are stored under in the OS
+// keyring.
+const keyringService = "feed-to-mastodon"
+
+// commandTimeout bounds how long a secret command may run, e.g. while a
+// password manager waits to be unlocked.
+const commandTimeout = 2 * time.Minute
+
+// ErrNotFound is returned when the keyring has no secret for an account.
+var ErrNotFound = errors.New("secret not found in keyring")
+
+// Get returns the secret stored in the OS keyring (macOS Keychain, the
+// Secret Service on Linux, or Windows Credential Manager) for account.
+func Get(account string) (string, error) {
+	value, err := keyring.Get(keyringService, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read from keyring: %w", err)
+	}
+	return value, nil
+}
+
+// Set stores a secret in the OS keyring for account, replacing any
+// secret stored for it before.
+func Set(account, value string) error {
+	if err := keyring.Set(keyringService, account, value); err != nil {
+		return fmt.Errorf("failed to store in keyring: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the secret stored in the OS keyring for account. It's not
+// an error if there is none.
+func Delete(account string) error {
+	err := keyring.Delete(keyringService, account)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete from keyring: %w", err)
+	}
+	return nil
+}
+
+// Command runs command with the system shell, e.g. "pass show mastodon",
+// and returns the first line of its output as the secret.
+func Command(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("secret command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("secret command failed: %w", err)
+	}
+
+	// Password managers like pass keep other fields on later lines
+	value, _, _ := strings.Cut(stdout.String(), "\n")
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("secret command printed nothing")
+	}
+	return value, nil
+}
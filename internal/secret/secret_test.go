@@ -0,0 +1,72 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestKeyring(t *testing.T) {
+	keyring.MockInit()
+
+	if _, err := Get("https://mastodon.example"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() before Set() error = %v, want ErrNotFound", err)
+	}
+
+	if err := Set("https://mastodon.example", "token-123"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	value, err := Get("https://mastodon.example")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if value != "token-123" {
+		t.Errorf("Get() = %q, want %q", value, "token-123")
+	}
+
+	if err := Delete("https://mastodon.example"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := Get("https://mastodon.example"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+	if err := Delete("https://mastodon.example"); err != nil {
+		t.Errorf("Delete() of missing secret error = %v", err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	t.Run("returns the first line of output", func(t *testing.T) {
+		value, err := Command(context.Background(), "printf '  token-123  \\nlogin: me\\n'")
+		if err != nil {
+			t.Fatalf("Command() error = %v", err)
+		}
+		if value != "token-123" {
+			t.Errorf("Command() = %q, want %q", value, "token-123")
+		}
+	})
+
+	t.Run("reports failures with stderr", func(t *testing.T) {
+		_, err := Command(context.Background(), "echo 'vault is locked' >&2; exit 1")
+		if err == nil {
+			t.Fatal("Expected error for failing command")
+		}
+		if want := "vault is locked"; !strings.Contains(err.Error(), want) {
+			t.Errorf("Command() error = %v, want it to mention %q", err, want)
+		}
+	})
+
+	t.Run("rejects empty output", func(t *testing.T) {
+		if _, err := Command(context.Background(), "true"); err == nil {
+			t.Error("Expected error for empty output")
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	t.Run("does nothing without NOTIFY_SOCKET", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+
+		sent, err := Notify("READY=1")
+		if err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		if sent {
+			t.Error("Expected no notification to be sent")
+		}
+	})
+
+	t.Run("sends state to notify socket", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "notify.sock")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+		if err != nil {
+			t.Fatalf("ListenUnixgram() error = %v", err)
+		}
+		defer conn.Close()
+
+		t.Setenv("NOTIFY_SOCKET", socketPath)
+
+		sent, err := Notify("READY=1")
+		if err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		if !sent {
+			t.Error("Expected notification to be sent")
+		}
+
+		buf := make([]byte, 64)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if string(buf[:n]) != "READY=1" {
+			t.Errorf("received %q, want READY=1", buf[:n])
+		}
+	})
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Run("disabled without WATCHDOG_USEC", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "")
+
+		interval, err := WatchdogInterval()
+		if err != nil {
+			t.Fatalf("WatchdogInterval() error = %v", err)
+		}
+		if interval != 0 {
+			t.Errorf("interval = %v, want 0", interval)
+		}
+	})
+
+	t.Run("returns half the watchdog timeout", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "30000000")
+		t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+		interval, err := WatchdogInterval()
+		if err != nil {
+			t.Fatalf("WatchdogInterval() error = %v", err)
+		}
+		if interval != 15*time.Second {
+			t.Errorf("interval = %v, want 15s", interval)
+		}
+	})
+
+	t.Run("ignores watchdog for other process", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "30000000")
+		t.Setenv("WATCHDOG_PID", "1")
+
+		interval, err := WatchdogInterval()
+		if err != nil {
+			t.Fatalf("WatchdogInterval() error = %v", err)
+		}
+		if interval != 0 {
+			t.Errorf("interval = %v, want 0", interval)
+		}
+	})
+
+	t.Run("invalid value errors", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "soon")
+		t.Setenv("WATCHDOG_PID", "")
+
+		if _, err := WatchdogInterval(); err == nil {
+			t.Error("Expected error for invalid WATCHDOG_USEC")
+		}
+	})
+}
+
+func TestListeners(t *testing.T) {
+	t.Run("returns nil when not socket activated", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", "")
+		t.Setenv("LISTEN_FDS", "")
+
+		listeners, err := Listeners()
+		if err != nil {
+			t.Fatalf("Listeners() error = %v", err)
+		}
+		if listeners != nil {
+			t.Errorf("Expected nil listeners, got %v", listeners)
+		}
+	})
+
+	t.Run("ignores sockets for other process", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", "1")
+		t.Setenv("LISTEN_FDS", "1")
+
+		listeners, err := Listeners()
+		if err != nil {
+			t.Fatalf("Listeners() error = %v", err)
+		}
+		if listeners != nil {
+			t.Errorf("Expected nil listeners, got %v", listeners)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// maxAltText is the longest media description Mastodon accepts.
+const maxAltText = 1500
+
+// SetAltTextTemplate renders the alt text of an entry's media from a
+// template, with the same data and functions as post templates, for media
+// the feed doesn't describe.
+func (r *Renderer) SetAltTextTemplate(text string) error {
+	tmpl, err := template.New("alt").Funcs(r.funcMap()).Parse(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse alt text template: %w", err)
+	}
+	r.altTemplate = tmpl
+	return nil
+}
+
+// AltText returns the alt text for an entry's undescribed media, rendered
+// from the alt text template and truncated to Mastodon's limit. Returns ""
+// without a template.
+func (r *Renderer) AltText(entryJSON []byte) (string, error) {
+	if r.altTemplate == nil {
+		return "", nil
+	}
+
+	var item gofeed.Item
+	if err := json.Unmarshal(entryJSON, &item); err != nil {
+		return "", fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+	alt, err := r.executeInline(r.altTemplate, &item)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute alt text template: %w", err)
+	}
+	return truncate(alt, maxAltText), nil
+}
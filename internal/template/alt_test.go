@@ -0,0 +1,37 @@
+package template
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestAltText(t *testing.T) {
+	r := &Renderer{}
+	itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Sunset", Description: strings.Repeat("orange ", 300)})
+
+	if alt, err := r.AltText(itemJSON); err != nil || alt != "" {
+		t.Errorf("AltText() without template = %q, %v; want empty", alt, err)
+	}
+
+	if err := r.SetAltTextTemplate("  Photo: {{.Item.Title}}  "); err != nil {
+		t.Fatalf("SetAltTextTemplate() error = %v", err)
+	}
+	if alt, err := r.AltText(itemJSON); err != nil || alt != "Photo: Sunset" {
+		t.Errorf("AltText() = %q, %v; want %q", alt, err, "Photo: Sunset")
+	}
+
+	if err := r.SetAltTextTemplate("{{.Item.Description}}"); err != nil {
+		t.Fatalf("SetAltTextTemplate() error = %v", err)
+	}
+	if alt, err := r.AltText(itemJSON); err != nil || utf8.RuneCountInString(alt) != maxAltText {
+		t.Errorf("AltText() length = %d, %v; want %d", utf8.RuneCountInString(alt), err, maxAltText)
+	}
+
+	if err := r.SetAltTextTemplate("{{.Item.Title"); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+}
@@ -0,0 +1,141 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Wayback Machine endpoints for finding and capturing snapshots.
+const (
+	waybackAvailableURL = "https://archive.org/wayback/available"
+	waybackSaveURL      = "https://web.archive.org/save/"
+)
+
+// archiveTimeout bounds a request to the Wayback Machine. Capturing a page
+// can take a while.
+const archiveTimeout = 90 * time.Second
+
+// archiveLinks appends a Wayback Machine snapshot link for each entry's
+// link to rendered posts.
+type archiveLinks struct {
+	label        string
+	save         bool
+	availableURL string
+	saveURL      string
+}
+
+// SetArchiveLinks has a link to a Wayback Machine snapshot of each entry's
+// link appended to rendered posts, on its own line after label, like
+// "Archived: https://web.archive.org/web/...". With save, pages that have
+// no snapshot yet are captured; otherwise, and if capturing fails, nothing
+// is appended. Like short links, archive links are only added once links
+// are final; see SetFinalLinks.
+func (r *Renderer) SetArchiveLinks(label string, save bool) {
+	r.archive = &archiveLinks{
+		label:        label,
+		save:         save,
+		availableURL: waybackAvailableURL,
+		saveURL:      waybackSaveURL,
+	}
+}
+
+// archiveLine returns the line with the snapshot link of item's link, or
+// "" if archive links are off, links aren't final, or there is no
+// snapshot. Snapshots are remembered, including the lack of one.
+func (r *Renderer) archiveLine(item *Item) string {
+	if r.archive == nil || !r.finalLinks || item.Link == "" {
+		return ""
+	}
+	link := r.cleanLinks(item.Link)
+
+	snapshot, ok := r.archived[link]
+	if !ok {
+		var err error
+		snapshot, err = r.archive.snapshot(link)
+		if err != nil {
+			logrus.Warnf("Failed to get an archive link for %s: %v", link, err)
+		}
+		if r.archived == nil {
+			r.archived = make(map[string]string)
+		}
+		r.archived[link] = snapshot
+	}
+	if snapshot == "" {
+		return ""
+	}
+
+	if r.archive.label != "" {
+		return r.archive.label + " " + snapshot
+	}
+	return snapshot
+}
+
+// appendLine appends line to text after a blank line, unless it's empty.
+func appendLine(text, line string) string {
+	if line == "" {
+		return text
+	}
+	return strings.TrimRight(text, "\n") + "\n\n" + line
+}
+
+// snapshot returns the link of the latest Wayback Machine snapshot of
+// link, capturing one if there isn't one and saving is enabled. Returns ""
+// if there's no snapshot.
+func (a *archiveLinks) snapshot(link string) (string, error) {
+	client := &http.Client{Timeout: archiveTimeout}
+
+	resp, err := client.Get(a.availableURL + "?url=" + url.QueryEscape(link))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("availability check returned %s", resp.Status)
+	}
+
+	var available struct {
+		ArchivedSnapshots struct {
+			Closest struct {
+				Available bool   `json:"available"`
+				URL       string `json:"url"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&available); err != nil {
+		return "", fmt.Errorf("invalid availability response: %w", err)
+	}
+	if closest := available.ArchivedSnapshots.Closest; closest.Available && closest.URL != "" {
+		return httpsLink(closest.URL), nil
+	}
+	if !a.save {
+		logrus.Debugf("No archived snapshot of %s", link)
+		return "", nil
+	}
+
+	// A capture redirects to the new snapshot when it's done
+	logrus.Infof("Archiving %s", link)
+	resp, err = client.Get(a.saveURL + link)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Request.URL.Path, "/web/") {
+		return "", fmt.Errorf("capture returned %s", resp.Status)
+	}
+	return httpsLink(resp.Request.URL.String()), nil
+}
+
+// httpsLink returns link with https instead of http, as the Wayback
+// Machine reports snapshot links with http.
+func httpsLink(link string) string {
+	if rest, ok := strings.CutPrefix(link, "http://"); ok {
+		return "https://" + rest
+	}
+	return link
+}
@@ -0,0 +1,94 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestArchiveLinks(t *testing.T) {
+	availableRequests, saveRequests := 0, 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/available":
+			availableRequests++
+			if link := r.URL.Query().Get("url"); link == "https://example.com/old" {
+				fmt.Fprintf(w, `{"archived_snapshots": {"closest": {"available": true, "status": "200", "url": "http://web.archive.example/web/20240101000000/%s"}}}`, link)
+				return
+			}
+			fmt.Fprint(w, `{"archived_snapshots": {}}`)
+		case strings.HasPrefix(r.URL.Path, "/save/"):
+			saveRequests++
+			http.Redirect(w, r, server.URL+"/web/20250101000000/"+strings.TrimPrefix(r.URL.Path, "/save/"), http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	newArchiveRenderer := func(t *testing.T, save bool) *Renderer {
+		renderer := newLinkRenderer(t)
+		renderer.SetArchiveLinks("Archived:", save)
+		renderer.archive.availableURL = server.URL + "/available"
+		renderer.archive.saveURL = server.URL + "/save/"
+		return renderer
+	}
+
+	t.Run("appends the latest snapshot once", func(t *testing.T) {
+		renderer := newArchiveRenderer(t, false)
+		availableRequests = 0
+		for i := 0; i < 2; i++ {
+			got, err := renderLink(t, renderer, "https://example.com/old")
+			want := "Post https://example.com/old\n\nArchived: https://web.archive.example/web/20240101000000/https://example.com/old"
+			if err != nil || got != want {
+				t.Errorf("Render() = %q, %v; want %q", got, err, want)
+			}
+		}
+		if availableRequests != 1 {
+			t.Errorf("availability requests = %d, want 1", availableRequests)
+		}
+	})
+
+	t.Run("appends nothing until links are final", func(t *testing.T) {
+		renderer := newArchiveRenderer(t, true)
+		renderer.SetFinalLinks(false)
+		availableRequests, saveRequests = 0, 0
+		got, err := renderLink(t, renderer, "https://example.com/old")
+		if err != nil || got != "Post https://example.com/old" || availableRequests+saveRequests != 0 {
+			t.Errorf("Render() = %q, %v with %d requests; want no archive link", got, err, availableRequests+saveRequests)
+		}
+	})
+
+	t.Run("appends nothing without a snapshot", func(t *testing.T) {
+		renderer := newArchiveRenderer(t, false)
+		if got, err := renderLink(t, renderer, "https://example.com/new"); err != nil || got != "Post https://example.com/new" {
+			t.Errorf("Render() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("captures pages without a snapshot", func(t *testing.T) {
+		renderer := newArchiveRenderer(t, true)
+		saveRequests = 0
+		got, err := renderLink(t, renderer, "https://example.com/new")
+		// Snapshot links are made https
+		want := "Post https://example.com/new\n\nArchived: " + httpsLink(server.URL) + "/web/20250101000000/https://example.com/new"
+		if err != nil || got != want || saveRequests != 1 {
+			t.Errorf("Render() = %q, %v with %d captures; want %q", got, err, saveRequests, want)
+		}
+	})
+
+	t.Run("counts toward the character limit", func(t *testing.T) {
+		renderer := newArchiveRenderer(t, false)
+		renderer.characterLimit = len("Post ") + urlLength + 10
+		if err := renderer.SetCharacterLimitMode(LimitFail); err != nil {
+			t.Fatalf("SetCharacterLimitMode() error = %v", err)
+		}
+		if _, err := renderLink(t, renderer, "https://example.com/old"); !errors.Is(err, ErrOverLimit) {
+			t.Errorf("Render() error = %v, want ErrOverLimit", err)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package template
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redirectTimeout bounds resolving a redirector link.
+const redirectTimeout = 10 * time.Second
+
+// trackingParams are query parameters added for analytics, which don't
+// change the page a link leads to. Parameters starting with utm_ are also
+// tracking parameters.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "gclsrc": true, "dclid": true,
+	"msclkid": true, "yclid": true, "igshid": true, "mc_cid": true,
+	"mc_eid": true, "_hsenc": true, "_hsmi": true, "mkt_tok": true,
+	"oly_anon_id": true, "oly_enc_id": true, "vero_id": true,
+	"wt_mc": true, "ref_src": true, "ref_url": true,
+}
+
+// CleanURL removes tracking parameters, like utm_source and fbclid, from
+// link. Other parameters are kept as they were, and links that can't be
+// parsed are returned unchanged.
+func CleanURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.RawQuery == "" {
+		return link
+	}
+
+	var kept []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		name = strings.ToLower(name)
+		if trackingParams[name] || strings.HasPrefix(name, "utm_") {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String()
+}
+
+// SetCleanURLs sets whether tracking parameters are removed from links in
+// rendered posts.
+func (r *Renderer) SetCleanURLs(enabled bool) {
+	r.cleanURLs = enabled
+}
+
+// AddRedirectHost has links on host, or its subdomains, resolved to where
+// they redirect to in rendered posts, e.g. for feedproxy.google.com links.
+func (r *Renderer) AddRedirectHost(host string) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host != "" {
+		r.redirectHosts = append(r.redirectHosts, host)
+	}
+}
+
+// cleanURL is the cleanURL template function: it resolves link if it's on
+// a redirect host, and removes its tracking parameters.
+func (r *Renderer) cleanURL(link string) string {
+	return CleanURL(r.resolveRedirect(link))
+}
+
+// cleanLinks resolves redirector links and, if enabled, removes tracking
+// parameters from every link in text.
+func (r *Renderer) cleanLinks(text string) string {
+	if !r.cleanURLs && len(r.redirectHosts) == 0 {
+		return text
+	}
+
+	return urlPattern.ReplaceAllStringFunc(text, func(link string) string {
+		link = r.resolveRedirect(link)
+		if r.cleanURLs {
+			link = CleanURL(link)
+		}
+		return link
+	})
+}
+
+// resolveRedirect returns the URL link redirects to if it's on one of the
+// redirect hosts, or link. Resolved links are remembered, and links that
+// fail to resolve are kept as they are.
+func (r *Renderer) resolveRedirect(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || !r.isRedirectHost(u.Hostname()) {
+		return link
+	}
+	if resolved, ok := r.resolved[link]; ok {
+		return resolved
+	}
+
+	resolved, err := r.followRedirects(link)
+	if err != nil {
+		logrus.Warnf("Failed to resolve redirect of %s: %v", link, err)
+		resolved = link
+	}
+	if r.resolved == nil {
+		r.resolved = make(map[string]string)
+	}
+	r.resolved[link] = resolved
+	return resolved
+}
+
+// isRedirectHost reports whether host is, or is a subdomain of, one of the
+// redirect hosts.
+func (r *Renderer) isRedirectHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, redirectHost := range r.redirectHosts {
+		if host == redirectHost || strings.HasSuffix(host, "."+redirectHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// followRedirects requests link and returns the URL it finally redirects
+// to. Servers that don't support HEAD requests get a GET instead.
+func (r *Renderer) followRedirects(link string) (string, error) {
+	client := &http.Client{Timeout: redirectTimeout}
+
+	resp, err := client.Head(link)
+	if err == nil && resp.StatusCode >= 400 {
+		resp.Body.Close()
+		resp, err = client.Get(link)
+	}
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Request.URL.String(), nil
+}
@@ -0,0 +1,99 @@
+package template
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestCleanURL(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://example.com/post", "https://example.com/post"},
+		{"https://example.com/post?utm_source=rss&utm_medium=feed", "https://example.com/post"},
+		{"https://example.com/post?id=42&UTM_Campaign=x&fbclid=abc", "https://example.com/post?id=42"},
+		{"https://example.com/search?q=a%20b&gclid=1&page=2#top", "https://example.com/search?q=a%20b&page=2#top"},
+		{"https://example.com/post?ref=home", "https://example.com/post?ref=home"},
+		{"not a url%", "not a url%"},
+	}
+
+	for _, tt := range tests {
+		if got := CleanURL(tt.link); got != tt.want {
+			t.Errorf("CleanURL(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestCleanLinks(t *testing.T) {
+	requests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			requests++
+			http.Redirect(w, r, server.URL+"/article?utm_source=feedproxy", http.StatusMovedPermanently)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	tmplPath := filepath.Join(tmpDir, "template.txt")
+	if err := os.WriteFile(tmplPath, []byte("{{.Item.Title}} {{.Item.Link}}"), 0o644); err != nil {
+		t.Fatalf("Failed to create test template: %v", err)
+	}
+	renderer, err := New(tmplPath, 500)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	render := func(link string) string {
+		itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Post", Link: link})
+		result, err := renderer.Render(itemJSON)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		return result
+	}
+
+	t.Run("leaves links alone by default", func(t *testing.T) {
+		link := server.URL + "/redirect?utm_source=rss"
+		if got := render(link); got != "Post "+link {
+			t.Errorf("Render() = %q", got)
+		}
+	})
+
+	t.Run("removes tracking parameters", func(t *testing.T) {
+		renderer.SetCleanURLs(true)
+		if got := render("https://example.com/post?utm_source=rss"); got != "Post https://example.com/post" {
+			t.Errorf("Render() = %q", got)
+		}
+	})
+
+	t.Run("resolves redirects once", func(t *testing.T) {
+		renderer.AddRedirectHost("127.0.0.1")
+		for i := 0; i < 2; i++ {
+			if got, want := render(server.URL+"/redirect"), "Post "+server.URL+"/article"; got != want {
+				t.Errorf("Render() = %q, want %q", got, want)
+			}
+		}
+		if requests != 1 {
+			t.Errorf("redirect requests = %d, want 1", requests)
+		}
+	})
+
+	t.Run("cleanURL function", func(t *testing.T) {
+		r := &Renderer{}
+		r.AddRedirectHost("127.0.0.1")
+		if got, want := r.cleanURL(server.URL+"/redirect"), server.URL+"/article"; got != want {
+			t.Errorf("cleanURL() = %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// urlLength is how many characters Mastodon counts for any URL.
+const urlLength = 23
+
+// mentionPattern matches remote mentions (@user@example.com), capturing the
+// part before the domain.
+var mentionPattern = regexp.MustCompile(`(^|[^\w/@])(@\w+)@[\w-]+(\.[\w-]+)+`)
+
+// CountCharacters counts text the way Mastodon does: every URL counts as 23
+// characters, and the domain part of a mention doesn't count.
+func CountCharacters(text string) int {
+	text = urlPattern.ReplaceAllLiteralString(text, strings.Repeat("x", urlLength))
+	text = mentionPattern.ReplaceAllString(text, "$1$2")
+	return utf8.RuneCountInString(text)
+}
+
+// PostLength returns the length Mastodon counts toward the character limit
+// for a post: the status text plus any content warning.
+func PostLength(status, contentWarning string) int {
+	return CountCharacters(status) + utf8.RuneCountInString(contentWarning)
+}
+
+// Measure breaks down the length Mastodon counts for a post.
+type Measure struct {
+	// Text is the length of the status outside its URLs.
+	Text int
+	// URLs is the number of URLs in the status, each counted as 23
+	// characters.
+	URLs int
+	// ContentWarning is the length of the content warning.
+	ContentWarning int
+}
+
+// URLCharacters returns the length counted for the status's URLs.
+func (m Measure) URLCharacters() int {
+	return m.URLs * urlLength
+}
+
+// Total returns the length counted toward the character limit, the same
+// as PostLength.
+func (m Measure) Total() int {
+	return m.Text + m.URLCharacters() + m.ContentWarning
+}
+
+// MeasurePost breaks down the length of a post into its parts, counted the
+// way PostLength counts them.
+func MeasurePost(status, contentWarning string) Measure {
+	urls := len(urlPattern.FindAllStringIndex(status, -1))
+	return Measure{
+		Text:           CountCharacters(status) - urls*urlLength,
+		URLs:           urls,
+		ContentWarning: utf8.RuneCountInString(contentWarning),
+	}
+}
@@ -0,0 +1,54 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountCharacters(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"plain text", "Hello world", 11},
+		{"multibyte runes", "你好 🌍", 4},
+		{"short URL counts as 23", "https://t.co", 23},
+		{"long URL counts as 23", "Read https://example.com/" + strings.Repeat("a", 100), 5 + 23},
+		{"two URLs", "http://a.example https://b.example/path", 23 + 1 + 23},
+		{"remote mention drops domain", "Hi @alice@mastodon.social!", len("Hi @alice!")},
+		{"local mention", "Hi @alice", 9},
+		{"email-like text in URL is untouched", "https://example.com/@alice@example.org", 23},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountCharacters(tt.text); got != tt.want {
+				t.Errorf("CountCharacters(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostLength(t *testing.T) {
+	if got := PostLength("Hello", "CW: spoilers"); got != 5+12 {
+		t.Errorf("PostLength() = %d, want %d", got, 17)
+	}
+}
+
+func TestMeasurePost(t *testing.T) {
+	status := "New post by @alice@example.social https://example.com/a https://example.com/b"
+	m := MeasurePost(status, "spoilers")
+	if m.URLs != 2 {
+		t.Errorf("URLs = %d, want 2", m.URLs)
+	}
+	if want := len("New post by @alice  "); m.Text != want {
+		t.Errorf("Text = %d, want %d", m.Text, want)
+	}
+	if m.ContentWarning != 8 {
+		t.Errorf("ContentWarning = %d, want 8", m.ContentWarning)
+	}
+	if got, want := m.Total(), PostLength(status, "spoilers"); got != want {
+		t.Errorf("Total() = %d, want PostLength() = %d", got, want)
+	}
+}
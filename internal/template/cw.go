@@ -0,0 +1,96 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// contentWarningRule sets the content warning for matching entries.
+type contentWarningRule struct {
+	category string
+	title    *regexp.Regexp
+	text     string
+}
+
+// AddContentWarningRule adds a rule that gives entries with the given
+// category, and a title matching titleRegex, the content warning text. An
+// empty category or regex matches anything. Categories match ignoring
+// case, and the first matching rule wins.
+func (r *Renderer) AddContentWarningRule(category, titleRegex, text string) error {
+	if category == "" && titleRegex == "" {
+		return fmt.Errorf("content warning rule %q needs a category or title to match", text)
+	}
+
+	rule := contentWarningRule{category: category, text: text}
+	if titleRegex != "" {
+		title, err := regexp.Compile(titleRegex)
+		if err != nil {
+			return fmt.Errorf("invalid title regex %q: %w", titleRegex, err)
+		}
+		rule.title = title
+	}
+
+	r.cwRules = append(r.cwRules, rule)
+	return nil
+}
+
+// SetContentWarningTemplate renders each entry's content warning from a
+// template, with the same data and functions as post templates, for entries
+// no content warning rule matches. If it renders to nothing, the content
+// warning set by SetContentWarning is used.
+func (r *Renderer) SetContentWarningTemplate(text string) error {
+	tmpl, err := template.New("cw").Funcs(r.funcMap()).Parse(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse content warning template: %w", err)
+	}
+	r.cwTemplate = tmpl
+	return nil
+}
+
+// ContentWarning returns the content warning to post an entry with: from
+// the first matching rule, else from the content warning template, else
+// the one set by SetContentWarning.
+func (r *Renderer) ContentWarning(entryJSON []byte) (string, error) {
+	var item gofeed.Item
+	if err := json.Unmarshal(entryJSON, &item); err != nil {
+		return "", fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+	return r.contentWarningFor(&item)
+}
+
+// contentWarningFor returns the content warning for item.
+func (r *Renderer) contentWarningFor(item *gofeed.Item) (string, error) {
+	for _, rule := range r.cwRules {
+		if rule.matches(item) {
+			return rule.text, nil
+		}
+	}
+
+	if r.cwTemplate != nil {
+		cw, err := r.executeInline(r.cwTemplate, item)
+		if err != nil {
+			return "", fmt.Errorf("failed to execute content warning template: %w", err)
+		}
+		if cw != "" {
+			return cw, nil
+		}
+	}
+
+	return r.contentWarning, nil
+}
+
+// matches reports whether the rule applies to item.
+func (rule contentWarningRule) matches(item *gofeed.Item) bool {
+	if rule.category != "" && !slices.ContainsFunc(item.Categories, func(category string) bool {
+		return strings.EqualFold(category, rule.category)
+	}) {
+		return false
+	}
+	return rule.title == nil || rule.title.MatchString(item.Title)
+}
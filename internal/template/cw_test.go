@@ -0,0 +1,63 @@
+package template
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestContentWarning(t *testing.T) {
+	r := &Renderer{}
+	r.SetContentWarning("default")
+	if err := r.AddContentWarningRule("Politics", "", "politics"); err != nil {
+		t.Fatalf("AddContentWarningRule() error = %v", err)
+	}
+	if err := r.AddContentWarningRule("", `(?i)spoiler`, "spoilers"); err != nil {
+		t.Fatalf("AddContentWarningRule() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		item gofeed.Item
+		want string
+	}{
+		{"category rule", gofeed.Item{Title: "Election news", Categories: []string{"politics"}}, "politics"},
+		{"title rule", gofeed.Item{Title: "Finale SPOILERS inside"}, "spoilers"},
+		{"first rule wins", gofeed.Item{Title: "Spoiler", Categories: []string{"Politics"}}, "politics"},
+		{"default", gofeed.Item{Title: "Nothing to see"}, "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			itemJSON, _ := json.Marshal(&tt.item)
+			got, err := r.ContentWarning(itemJSON)
+			if err != nil || got != tt.want {
+				t.Errorf("ContentWarning() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("template", func(t *testing.T) {
+		if err := r.SetContentWarningTemplate(`{{if .Item.Categories}}re: {{join ", " .Item.Categories}}{{end}}`); err != nil {
+			t.Fatalf("SetContentWarningTemplate() error = %v", err)
+		}
+
+		itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Food", Categories: []string{"cooking", "baking"}})
+		if got, err := r.ContentWarning(itemJSON); err != nil || got != "re: cooking, baking" {
+			t.Errorf("ContentWarning() = %q, %v; want %q", got, err, "re: cooking, baking")
+		}
+
+		// An empty result falls back to the default
+		itemJSON, _ = json.Marshal(&gofeed.Item{Title: "Plain"})
+		if got, err := r.ContentWarning(itemJSON); err != nil || got != "default" {
+			t.Errorf("ContentWarning() = %q, %v; want %q", got, err, "default")
+		}
+	})
+
+	if err := r.AddContentWarningRule("", "", "anything"); err == nil {
+		t.Error("Expected error for rule without a match")
+	}
+	if err := r.AddContentWarningRule("", "(unclosed", "broken"); err == nil {
+		t.Error("Expected error for invalid title regex")
+	}
+}
@@ -0,0 +1,181 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+	"unicode/utf8"
+)
+
+// shortcodePattern matches custom emoji shortcodes like :blobcat:.
+var shortcodePattern = regexp.MustCompile(`:([A-Za-z0-9_]{2,}):`)
+
+// shortcodeName matches the name of a custom emoji.
+var shortcodeName = regexp.MustCompile(`^[A-Za-z0-9_]{2,}$`)
+
+// SetCustomEmoji sets the shortcodes of the server's custom emoji, without
+// colons, so emoji can fall back for the ones it doesn't have.
+func (r *Renderer) SetCustomEmoji(shortcodes []string) {
+	r.customEmoji = map[string]bool{}
+	for _, shortcode := range shortcodes {
+		r.customEmoji[shortcode] = true
+	}
+}
+
+// emoji returns the custom emoji shortcode for name, like :blobcat: for
+// {{emoji "blobcat"}}. If the server's custom emoji are known and it
+// doesn't have name, the fallback is returned instead, as in
+// {{emoji "blobcat" "🐱"}}.
+func (r *Renderer) emoji(name string, fallback ...string) (string, error) {
+	name = strings.Trim(name, ":")
+	if !shortcodeName.MatchString(name) {
+		return "", fmt.Errorf("emoji: invalid shortcode %q, which can only have letters, digits, and underscores", name)
+	}
+	if r.customEmoji != nil && !r.customEmoji[name] && len(fallback) > 0 {
+		return fallback[0], nil
+	}
+	return ":" + name + ":", nil
+}
+
+// Shortcodes returns the names of the custom emoji the renderer's
+// templates use, written like :blobcat: in their text or given to emoji,
+// sorted and without duplicates.
+func (r *Renderer) Shortcodes() []string {
+	templates := []*template.Template{r.tmpl, r.cwTemplate, r.altTemplate}
+	for _, rule := range r.rules {
+		templates = append(templates, rule.tmpl)
+	}
+
+	var shortcodes []string
+	for _, tmpl := range templates {
+		if tmpl == nil {
+			continue
+		}
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				shortcodes = append(shortcodes, nodeShortcodes(t.Tree.Root)...)
+			}
+		}
+	}
+	texts := []string{r.contentWarning}
+	for _, rule := range r.cwRules {
+		texts = append(texts, rule.text)
+	}
+	for _, text := range texts {
+		shortcodes = append(shortcodes, textShortcodes(text)...)
+	}
+
+	slices.Sort(shortcodes)
+	return slices.Compact(shortcodes)
+}
+
+// nodeShortcodes returns the shortcodes in the text of a template node and
+// the names given to emoji in its actions.
+func nodeShortcodes(node parse.Node) []string {
+	var shortcodes []string
+	switch node := node.(type) {
+	case *parse.TextNode:
+		shortcodes = textShortcodes(string(node.Text))
+	case *parse.ListNode:
+		if node != nil {
+			for _, child := range node.Nodes {
+				shortcodes = append(shortcodes, nodeShortcodes(child)...)
+			}
+		}
+	case *parse.ActionNode:
+		shortcodes = nodeShortcodes(node.Pipe)
+	case *parse.IfNode:
+		shortcodes = branchShortcodes(&node.BranchNode)
+	case *parse.RangeNode:
+		shortcodes = branchShortcodes(&node.BranchNode)
+	case *parse.WithNode:
+		shortcodes = branchShortcodes(&node.BranchNode)
+	case *parse.TemplateNode:
+		shortcodes = nodeShortcodes(node.Pipe)
+	case *parse.PipeNode:
+		if node != nil {
+			for _, cmd := range node.Cmds {
+				shortcodes = append(shortcodes, nodeShortcodes(cmd)...)
+			}
+		}
+	case *parse.CommandNode:
+		if name, ok := emojiName(node); ok {
+			shortcodes = append(shortcodes, name)
+		}
+		for _, arg := range node.Args {
+			shortcodes = append(shortcodes, nodeShortcodes(arg)...)
+		}
+	}
+	return shortcodes
+}
+
+// branchShortcodes returns the shortcodes of an if, range, or with action.
+func branchShortcodes(branch *parse.BranchNode) []string {
+	shortcodes := nodeShortcodes(branch.Pipe)
+	shortcodes = append(shortcodes, nodeShortcodes(branch.List)...)
+	if branch.ElseList != nil {
+		shortcodes = append(shortcodes, nodeShortcodes(branch.ElseList)...)
+	}
+	return shortcodes
+}
+
+// emojiName returns the name given to an emoji command, like blobcat for
+// {{emoji "blobcat"}}, if it's a constant.
+func emojiName(cmd *parse.CommandNode) (string, bool) {
+	if len(cmd.Args) < 2 {
+		return "", false
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || ident.Ident != "emoji" {
+		return "", false
+	}
+	name, ok := cmd.Args[1].(*parse.StringNode)
+	if !ok {
+		return "", false
+	}
+	return strings.Trim(name.Text, ":"), true
+}
+
+// emojiLength returns the most characters an emoji action with constant
+// arguments outputs, like {{emoji "blobcat" "🐱"}}.
+func emojiLength(pipe *parse.PipeNode) (int, bool) {
+	if len(pipe.Cmds) != 1 {
+		return 0, false
+	}
+	name, ok := emojiName(pipe.Cmds[0])
+	if !ok {
+		return 0, false
+	}
+	length := len(name) + 2
+	if args := pipe.Cmds[0].Args; len(args) > 2 {
+		if fallback, ok := args[2].(*parse.StringNode); ok {
+			length = max(length, CountCharacters(fallback.Text))
+		}
+	}
+	return length, true
+}
+
+// textShortcodes returns the shortcodes written in text. Like Mastodon, it
+// ignores shortcodes run together with letters or digits, as in times
+// like 12:30:45.
+func textShortcodes(text string) []string {
+	var shortcodes []string
+	for _, match := range shortcodePattern.FindAllStringSubmatchIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:match[0]])
+		after, _ := utf8.DecodeRuneInString(text[match[1]:])
+		if boundaryRune(before) || boundaryRune(after) {
+			continue
+		}
+		shortcodes = append(shortcodes, text[match[2]:match[3]])
+	}
+	return shortcodes
+}
+
+// boundaryRune reports whether r next to a shortcode makes it part of
+// other text.
+func boundaryRune(r rune) bool {
+	return r == ':' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
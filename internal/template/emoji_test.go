@@ -0,0 +1,82 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestEmoji(t *testing.T) {
+	r := &Renderer{}
+	if got, err := r.emoji("blobcat", "🐱"); err != nil || got != ":blobcat:" {
+		t.Errorf("emoji() = %q, %v; want :blobcat: while the server's emoji are unknown", got, err)
+	}
+	if _, err := r.emoji("blob cat"); err == nil {
+		t.Error("emoji() with a space succeeded, want an error")
+	}
+
+	r.SetCustomEmoji([]string{"blobcat"})
+	if got, _ := r.emoji(":blobcat:", "🐱"); got != ":blobcat:" {
+		t.Errorf("emoji() = %q, want :blobcat:", got)
+	}
+	if got, _ := r.emoji("rss", "📰"); got != "📰" {
+		t.Errorf("emoji() = %q, want the fallback for a missing emoji", got)
+	}
+	if got, _ := r.emoji("rss"); got != ":rss:" {
+		t.Errorf("emoji() = %q, want :rss: without a fallback", got)
+	}
+}
+
+func TestRenderer_Shortcodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "post.tmpl")
+	text := `:rss: {{.Item.Title}} at 12:30:45
+{{if .Item.Link}}{{emoji "link" "🔗"}} {{.Item.Link}}{{else}}:nolink:{{end}}
+{{range .Item.Categories}}{{hashtag .}}{{end}} :rss:`
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	r, err := New(path, 500)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := r.SetAltTextTemplate("Picture :camera_flash:"); err != nil {
+		t.Fatalf("SetAltTextTemplate() error = %v", err)
+	}
+	r.SetContentWarning(":warning_sign: spoilers")
+
+	want := []string{"camera_flash", "link", "nolink", "rss", "warning_sign"}
+	if got := r.Shortcodes(); !slices.Equal(got, want) {
+		t.Errorf("Shortcodes() = %v, want %v", got, want)
+	}
+}
+
+func TestTextShortcodes(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: ":blobcat: and :rss:", want: []string{"blobcat", "rss"}},
+		{text: "at 12:30:45", want: nil},
+		{text: "a:b: c::d::", want: nil},
+		{text: "(:blobcat:)", want: []string{"blobcat"}},
+		{text: ":x: is too short", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := textShortcodes(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("textShortcodes(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLint_Emoji(t *testing.T) {
+	result, err := Lint(`{{emoji "blobcat" "🐱"}} {{.Item.Link}}`, 500, nil)
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+	if want := len(":blobcat:") + 1 + urlLength; result.MaxLength != want || len(result.Unbounded) != 0 {
+		t.Errorf("MaxLength = %d, Unbounded = %v; want %d and none", result.MaxLength, result.Unbounded, want)
+	}
+}
@@ -0,0 +1,57 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+)
+
+// zeroWidthSpace is inserted after the @ of mentions and the # of hashtags
+// to keep Mastodon from linking them, without visibly changing the text.
+const zeroWidthSpace = "\u200b"
+
+var (
+	// escapeMentionPattern matches mentions like @user and @user@host the
+	// way Mastodon finds them: not preceded by a word character or slash,
+	// so email addresses and links aren't matched.
+	escapeMentionPattern = regexp.MustCompile(`(^|[^\p{L}\p{N}_/@])@([\p{L}\p{N}_]+(?:@[\p{L}\p{N}_.-]+[\p{L}\p{N}_])?)`)
+	// escapeHashtagPattern matches hashtags, which need a letter, like
+	// #golang but not #1 or a link's #fragment.
+	escapeHashtagPattern = regexp.MustCompile(`(^|[^\p{L}\p{N}_/)&#])#([\p{L}\p{N}_]*[\p{L}_][\p{L}\p{N}_]*)`)
+)
+
+// escapeMentions neutralizes mentions and hashtags in text, so text from
+// a feed can't mention fediverse users or add hashtags to a post.
+func escapeMentions(text string) string {
+	if !strings.ContainsAny(text, "@#") {
+		return text
+	}
+	text = escapeMentionPattern.ReplaceAllString(text, "$1@"+zeroWidthSpace+"$2")
+	return escapeHashtagPattern.ReplaceAllString(text, "$1#"+zeroWidthSpace+"$2")
+}
+
+// SetEscapeMentions sets whether mentions and hashtags in the text of feed
+// items are neutralized before rendering, which is on by default. The
+// text of templates, like a hashtag added with {{hashtag .Item.Categories}},
+// isn't changed, and the escapeMentions template function escapes text
+// explicitly.
+func (r *Renderer) SetEscapeMentions(enabled bool) {
+	r.escapeMentions = enabled
+}
+
+// escapeItem returns a copy of item with mentions and hashtags escaped in
+// its title, description, content, and author, if escaping is on.
+func (r *Renderer) escapeItem(item *Item) *Item {
+	if !r.escapeMentions {
+		return item
+	}
+	feedItem := *item.Item
+	feedItem.Title = escapeMentions(feedItem.Title)
+	feedItem.Description = escapeMentions(feedItem.Description)
+	feedItem.Content = escapeMentions(feedItem.Content)
+	if feedItem.Author != nil {
+		author := *feedItem.Author
+		author.Name = escapeMentions(author.Name)
+		feedItem.Author = &author
+	}
+	return &Item{Item: &feedItem, FullContent: escapeMentions(item.FullContent), LeadImage: item.LeadImage}
+}
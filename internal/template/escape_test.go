@@ -0,0 +1,73 @@
+package template
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestEscapeMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"remote mention", "Thanks @alice@example.social!", "Thanks @\u200balice@example.social!"},
+		{"local mention", "@bob said so", "@\u200bbob said so"},
+		{"hashtag", "Big news #launch today", "Big news #\u200blaunch today"},
+		{"leading hashtag", "#1 hit and #Go", "#1 hit and #\u200bGo"},
+		{"email address", "Mail bob@example.com", "Mail bob@example.com"},
+		{"link with mention and fragment", "See https://example.social/@alice#top", "See https://example.social/@alice#top"},
+		{"entity", "It&#8217;s", "It&#8217;s"},
+		{"plain text", "Nothing to see", "Nothing to see"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeMentions(tt.text); got != tt.want {
+				t.Errorf("escapeMentions(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderEscapesFeedText(t *testing.T) {
+	tmplPath := filepath.Join(t.TempDir(), "template.txt")
+	text := "{{.Item.Title}} by {{.Item.Author.Name}} {{hashtag .Item.Categories}} @me@example.social"
+	if err := os.WriteFile(tmplPath, []byte(text), 0o644); err != nil {
+		t.Fatalf("Failed to create test template: %v", err)
+	}
+	renderer, err := New(tmplPath, 500)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	item := &gofeed.Item{
+		Title:      "Hi @alice@example.social #spam",
+		Author:     &gofeed.Person{Name: "@bob"},
+		Categories: []string{"news"},
+	}
+	itemJSON, _ := json.Marshal(item)
+
+	result, err := renderer.Render(itemJSON)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := "Hi @\u200balice@example.social #\u200bspam by @\u200bbob #news @me@example.social"
+	if result != want {
+		t.Errorf("Render() = %q, want %q", result, want)
+	}
+
+	renderer.SetEscapeMentions(false)
+	result, err = renderer.Render(itemJSON)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(result, "\u200b") {
+		t.Errorf("Render() = %q, want no escaping", result)
+	}
+}
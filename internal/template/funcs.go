@@ -0,0 +1,193 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// funcMap returns the functions available in post templates. Besides
+// truncate, htmltomarkdown, stripHTML, decodeEntities, hashtag, cleanURL,
+// formatDate, and relativeTime, it has a small subset of the sprig library, with the same
+// names and argument order so they work in pipelines, e.g.
+// {{.Item.Title | lower | replace " " "-"}}. Date functions use the
+// renderer's timezone.
+func (r *Renderer) funcMap() template.FuncMap {
+	return template.FuncMap{
+		"truncate":        truncate,
+		"summarize":       summarize,
+		"summarizeWords":  summarizeWords,
+		"htmltomarkdown":  htmlToMarkdown,
+		"stripHTML":       stripHTML,
+		"decodeEntities":  decodeEntities,
+		"escapeMentions":  escapeMentions,
+		"hashtag":         r.hashtag,
+		"cleanURL":        r.cleanURL,
+		"firstImage":      firstImage,
+		"emoji":           r.emoji,
+		"lower":           strings.ToLower,
+		"upper":           strings.ToUpper,
+		"trim":            strings.TrimSpace,
+		"trimPrefix":      func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix":      func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"contains":        func(substr, s string) bool { return strings.Contains(s, substr) },
+		"replace":         func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"join":            func(sep string, list []string) string { return strings.Join(list, sep) },
+		"regexReplaceAll": regexReplaceAll,
+		"regexReplace":    regexReplace,
+		"regexExtract":    regexExtract,
+		"default":         defaultValue,
+		"date":            func(layout string, date any) (string, error) { return r.formatDate(date, layout) },
+		"formatDate":      r.formatDate,
+		"relativeTime":    r.relativeTime,
+	}
+}
+
+// regexReplaceAll replaces matches of regex in s with repl, which may
+// refer to submatches as $1.
+func regexReplaceAll(regex, s, repl string) (string, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return "", fmt.Errorf("regexReplaceAll: %w", err)
+	}
+	return re.ReplaceAllString(s, repl), nil
+}
+
+// regexReplace is regexReplaceAll with the text last, for pipelines like
+// {{.Item.Title | regexReplace "^\\[Sponsored\\]\\s*" ""}}.
+func regexReplace(regex, repl, s string) (string, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return "", fmt.Errorf("regexReplace: %w", err)
+	}
+	return re.ReplaceAllString(s, repl), nil
+}
+
+// regexExtract returns the first match of regex in s, or its first
+// submatch if the regex has a group, like the episode number of
+// {{regexExtract "Episode (\\d+)" .Item.Title}}. It returns an empty string
+// if nothing matches.
+func regexExtract(regex, s string) (string, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return "", fmt.Errorf("regexExtract: %w", err)
+	}
+	match := re.FindStringSubmatch(s)
+	switch {
+	case match == nil:
+		return "", nil
+	case len(match) > 1:
+		return match[1], nil
+	}
+	return match[0], nil
+}
+
+// defaultValue returns given, or def if given is empty: nil, zero, or an
+// empty string, slice, or map.
+func defaultValue(def, given any) any {
+	if given == nil {
+		return def
+	}
+	v := reflect.ValueOf(given)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		if v.Len() == 0 {
+			return def
+		}
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+	return given
+}
+
+// toTime converts a template date argument to a time: a time.Time, a
+// *time.Time like .Item.PublishedParsed, or an RFC 3339 string. Returns
+// false for a missing date.
+func toTime(date any) (time.Time, bool, error) {
+	switch d := date.(type) {
+	case time.Time:
+		return d, true, nil
+	case *time.Time:
+		if d == nil {
+			return time.Time{}, false, nil
+		}
+		return *d, true, nil
+	case string:
+		if d == "" {
+			return time.Time{}, false, nil
+		}
+		t, err := time.Parse(time.RFC3339, d)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		return t, true, nil
+	case nil:
+		return time.Time{}, false, nil
+	default:
+		return time.Time{}, false, fmt.Errorf("unsupported type %T", date)
+	}
+}
+
+// formatDate formats a date with a Go layout, e.g. "Jan 2, 2006", in the
+// renderer's timezone. A missing date formats as an empty string.
+func (r *Renderer) formatDate(date any, layout string) (string, error) {
+	t, ok, err := toTime(date)
+	if err != nil {
+		return "", fmt.Errorf("formatDate: %w", err)
+	}
+	if !ok {
+		return "", nil
+	}
+	if r.location != nil {
+		t = t.In(r.location)
+	}
+	return t.Format(layout), nil
+}
+
+// relativeTime describes how long ago a date was, e.g. "3 hours ago".
+// A missing date is an empty string.
+func (r *Renderer) relativeTime(date any) (string, error) {
+	t, ok, err := toTime(date)
+	if err != nil {
+		return "", fmt.Errorf("relativeTime: %w", err)
+	}
+	if !ok {
+		return "", nil
+	}
+
+	elapsed := r.now().Sub(t)
+	if elapsed < 0 {
+		return "in the future", nil
+	}
+
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"week", 7 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, unit := range units {
+		n := int(elapsed / unit.size)
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit.name), nil
+		}
+		if n > 1 {
+			return fmt.Sprintf("%d %ss ago", n, unit.name), nil
+		}
+	}
+	return "just now", nil
+}
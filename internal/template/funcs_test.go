@@ -0,0 +1,146 @@
+package template
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	published := time.Date(2024, 3, 9, 15, 4, 0, 0, time.UTC)
+	item := &gofeed.Item{
+		Title:           "  Hello World  ",
+		Link:            "https://example.com/posts/hello",
+		Categories:      []string{"go", "feeds"},
+		PublishedParsed: &published,
+	}
+
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"lower and trim", `{{.Item.Title | trim | lower}}`, "hello world"},
+		{"upper", `{{.Item.Title | trim | upper}}`, "HELLO WORLD"},
+		{"replace", `{{.Item.Title | trim | replace " " "-"}}`, "Hello-World"},
+		{"regexReplaceAll", `{{regexReplaceAll "^https://([^/]+)/.*$" .Item.Link "$1"}}`, "example.com"},
+		{"regexReplace", `{{.Item.Title | trim | regexReplace "^Hello\\s*" ""}}`, "World"},
+		{"regexExtract submatch", `{{regexExtract "/posts/(\\w+)" .Item.Link}}`, "hello"},
+		{"regexExtract match", `{{regexExtract "[A-Z]\\w+" .Item.Title}}`, "Hello"},
+		{"regexExtract no match", `{{regexExtract "\\d+" .Item.Title}}`, ""},
+		{"summarizeWords", `{{.Item.Title | summarizeWords 1}}`, "Hello…"},
+		{"summarize", `{{summarize 1 .Item.Title}}`, "Hello World"},
+		{"trimPrefix", `{{.Item.Link | trimPrefix "https://"}}`, "example.com/posts/hello"},
+		{"trimSuffix", `{{.Item.Link | trimSuffix "/hello"}}`, "https://example.com/posts"},
+		{"contains", `{{if contains "example" .Item.Link}}yes{{end}}`, "yes"},
+		{"join", `{{join ", " .Item.Categories}}`, "go, feeds"},
+		{"default for empty", `{{.Item.Description | default "no description"}}`, "no description"},
+		{"default keeps value", `{{.Item.Link | default "none"}}`, "https://example.com/posts/hello"},
+		{"date", `{{date "2006-01-02" .Item.PublishedParsed}}`, "2024-03-09"},
+		{"date from nil", `{{date "2006-01-02" .Item.UpdatedParsed}}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmplPath := filepath.Join(t.TempDir(), "template.txt")
+			if err := os.WriteFile(tmplPath, []byte(tt.tmpl), 0o644); err != nil {
+				t.Fatalf("Failed to create test template: %v", err)
+			}
+
+			renderer, err := New(tmplPath, 500)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			itemJSON, _ := json.Marshal(item)
+			result, err := renderer.Render(itemJSON)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Render() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegexReplaceAll_InvalidRegex(t *testing.T) {
+	if _, err := regexReplaceAll("(unclosed", "text", ""); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+	if _, err := regexReplace("(unclosed", "", "text"); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+	if _, err := regexExtract("(unclosed", "text"); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	r := &Renderer{}
+	if got, err := r.formatDate("2024-03-09T15:04:00Z", "Jan 2"); err != nil || got != "Mar 9" {
+		t.Errorf("formatDate(string) = %q, %v; want %q", got, err, "Mar 9")
+	}
+	if _, err := r.formatDate("yesterday", "Jan 2"); err == nil {
+		t.Error("Expected error for unparseable date")
+	}
+	if _, err := r.formatDate(42, "Jan 2"); err == nil {
+		t.Error("Expected error for unsupported type")
+	}
+
+	t.Run("uses the configured timezone", func(t *testing.T) {
+		tmplPath := filepath.Join(t.TempDir(), "template.txt")
+		tmpl := `{{formatDate .Item.PublishedParsed "Jan 2 15:04 MST"}} / {{date "15:04" .Item.PublishedParsed}}`
+		if err := os.WriteFile(tmplPath, []byte(tmpl), 0o644); err != nil {
+			t.Fatalf("Failed to create test template: %v", err)
+		}
+
+		renderer, err := New(tmplPath, 500)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		renderer.SetTimezone(time.FixedZone("PDT", -7*60*60))
+
+		published := time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC)
+		itemJSON, _ := json.Marshal(&gofeed.Item{PublishedParsed: &published})
+		result, err := renderer.Render(itemJSON)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if want := "Mar 9 19:30 PDT / 19:30"; result != want {
+			t.Errorf("Render() = %q, want %q", result, want)
+		}
+	})
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	r := &Renderer{now: func() time.Time { return now }}
+
+	tests := []struct {
+		date time.Time
+		want string
+	}{
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(-3 * time.Hour), "3 hours ago"},
+		{now.Add(-50 * time.Hour), "2 days ago"},
+		{now.Add(-15 * 24 * time.Hour), "2 weeks ago"},
+		{now.Add(-400 * 24 * time.Hour), "1 year ago"},
+		{now.Add(time.Hour), "in the future"},
+	}
+	for _, tt := range tests {
+		got, err := r.relativeTime(tt.date)
+		if err != nil || got != tt.want {
+			t.Errorf("relativeTime(%v) = %q, %v; want %q", tt.date, got, err, tt.want)
+		}
+	}
+
+	if got, err := r.relativeTime((*time.Time)(nil)); err != nil || got != "" {
+		t.Errorf("relativeTime(nil) = %q, %v; want empty", got, err)
+	}
+}
@@ -0,0 +1,83 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// AddHashtagMapping makes the hashtag function use tag for entries with the
+// given category, instead of deriving one from the category. An empty tag
+// drops the category. Categories match ignoring case.
+func (r *Renderer) AddHashtagMapping(category, tag string) error {
+	key := strings.ToLower(strings.TrimSpace(category))
+	if key == "" {
+		return fmt.Errorf("hashtag mapping needs a category")
+	}
+	if r.hashtagMap == nil {
+		r.hashtagMap = map[string]string{}
+	}
+	r.hashtagMap[key] = tag
+	return nil
+}
+
+// hashtag converts a category, or a list of categories, into hashtags. Words
+// are joined in CamelCase, punctuation is removed, and a list is
+// deduplicated ignoring case and joined with spaces. Categories that can't
+// form a valid hashtag, such as numbers, are left out.
+func (r *Renderer) hashtag(categories any) (string, error) {
+	var list []string
+	switch c := categories.(type) {
+	case string:
+		list = []string{c}
+	case []string:
+		list = c
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("hashtag: unsupported type %T", categories)
+	}
+
+	tags := make([]string, 0, len(list))
+	seen := map[string]bool{}
+	for _, category := range list {
+		tag := r.categoryTag(category)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, "#"+tag)
+	}
+
+	return strings.Join(tags, " "), nil
+}
+
+// categoryTag returns the hashtag for a category, without the #.
+func (r *Renderer) categoryTag(category string) string {
+	if tag, ok := r.hashtagMap[strings.ToLower(strings.TrimSpace(category))]; ok {
+		return toHashtag(strings.TrimPrefix(tag, "#"))
+	}
+	return toHashtag(category)
+}
+
+// toHashtag joins the words of s in CamelCase, keeping only characters
+// Mastodon allows in hashtags. A single word is kept as it is. Returns ""
+// if s has no letters, since Mastodon doesn't link all-numeric hashtags.
+func toHashtag(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if len(words) > 1 {
+		for i, word := range words {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+	}
+
+	tag := strings.Join(words, "")
+	if !strings.ContainsFunc(tag, unicode.IsLetter) {
+		return ""
+	}
+	return tag
+}
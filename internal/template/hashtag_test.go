@@ -0,0 +1,86 @@
+package template
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestToHashtag(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"golang", "golang"},
+		{"iOS", "iOS"},
+		{"machine learning", "MachineLearning"},
+		{"web-development", "WebDevelopment"},
+		{"C++", "C"},
+		{"Node.js", "NodeJs"},
+		{"snake_case", "snake_case"},
+		{"café culture", "CaféCulture"},
+		{"2024", ""},
+		{"  ", ""},
+		{"#already", "already"},
+	}
+
+	for _, tt := range tests {
+		if got := toHashtag(tt.input); got != tt.want {
+			t.Errorf("toHashtag(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHashtag(t *testing.T) {
+	r := &Renderer{}
+	if err := r.AddHashtagMapping("Programming Languages", "#ProgLang"); err != nil {
+		t.Fatalf("AddHashtagMapping() error = %v", err)
+	}
+	if err := r.AddHashtagMapping("uncategorized", ""); err != nil {
+		t.Fatalf("AddHashtagMapping() error = %v", err)
+	}
+	if err := r.AddHashtagMapping(" ", "x"); err == nil {
+		t.Error("Expected error for mapping without category")
+	}
+
+	got, err := r.hashtag([]string{"go", "Go", "programming languages", "Uncategorized", "open source", "2024"})
+	if err != nil {
+		t.Fatalf("hashtag() error = %v", err)
+	}
+	if want := "#go #ProgLang #OpenSource"; got != want {
+		t.Errorf("hashtag() = %q, want %q", got, want)
+	}
+
+	got, err = r.hashtag("open source")
+	if err != nil || got != "#OpenSource" {
+		t.Errorf("hashtag(string) = %q, %v; want #OpenSource", got, err)
+	}
+
+	if _, err := r.hashtag(42); err == nil {
+		t.Error("Expected error for unsupported type")
+	}
+}
+
+func TestHashtagInTemplate(t *testing.T) {
+	tmplPath := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(tmplPath, []byte(`{{hashtag .Item.Categories}}`), 0o644); err != nil {
+		t.Fatalf("Failed to create test template: %v", err)
+	}
+
+	renderer, err := New(tmplPath, 500)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	itemJSON, _ := json.Marshal(&gofeed.Item{Categories: []string{"Home Automation", "diy"}})
+	result, err := renderer.Render(itemJSON)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "#HomeAutomation #diy"; result != want {
+		t.Errorf("Render() = %q, want %q", result, want)
+	}
+}
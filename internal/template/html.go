@@ -0,0 +1,107 @@
+package template
+
+import (
+	stdhtml "html"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements start a new line when HTML is converted to plain text.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"br": true, "dd": true, "div": true, "dl": true, "dt": true,
+	"figcaption": true, "figure": true, "footer": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "ol": true, "p": true,
+	"pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// paragraphElements are separated from surrounding text by a blank line.
+var paragraphElements = map[string]bool{
+	"blockquote": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "ol": true, "p": true, "pre": true, "ul": true,
+}
+
+var (
+	// spacePattern matches runs of spaces and tabs.
+	spacePattern = regexp.MustCompile(`[ \t\r\f\v]+`)
+	// blankLinesPattern matches more than one blank line.
+	blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// stripHTML converts HTML to plain text: tags are removed, entities are
+// decoded, block elements start new lines, and scripts and styles are
+// dropped. Unlike htmltomarkdown, no Markdown syntax is added, since
+// Mastodon shows it literally.
+func stripHTML(s string) string {
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+	skip := 0
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return cleanText(b.String())
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(tokenizer.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == "script" || tag == "style" {
+				skip++
+			}
+			writeBreak(&b, tag)
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if (tag == "script" || tag == "style") && skip > 0 {
+				skip--
+			}
+			writeBreak(&b, tag)
+		}
+	}
+}
+
+// writeBreak ends the current line, or paragraph, as a block element's tag
+// implies. Adjacent tags don't add to each other's breaks, but <br> always
+// breaks the line.
+func writeBreak(b *strings.Builder, tag string) {
+	want := 0
+	switch {
+	case paragraphElements[tag]:
+		want = 2
+	case tag == "br":
+		b.WriteString("\n")
+		return
+	case blockElements[tag]:
+		want = 1
+	}
+
+	text := b.String()
+	have := len(text) - len(strings.TrimRight(text, "\n"))
+	for ; have < want; have++ {
+		b.WriteString("\n")
+	}
+}
+
+// cleanText collapses runs of spaces, trims each line, and allows at most
+// one blank line in a row.
+func cleanText(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spacePattern.ReplaceAllString(line, " "))
+	}
+	text := strings.Join(lines, "\n")
+	text = blankLinesPattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// decodeEntities decodes HTML entities such as &amp; and &#8217; without
+// otherwise changing the text.
+func decodeEntities(s string) string {
+	return stdhtml.UnescapeString(s)
+}
@@ -0,0 +1,63 @@
+package template
+
+import "testing"
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "removes tags",
+			input: `<p>Hello <strong>world</strong>, see <a href="https://example.com">this</a>.</p>`,
+			want:  "Hello world, see this.",
+		},
+		{
+			name:  "separates paragraphs",
+			input: "<p>First</p><p>Second<br>line</p>",
+			want:  "First\n\nSecond\nline",
+		},
+		{
+			name:  "lists items on their own lines",
+			input: "<ul><li>One</li><li>Two</li></ul>",
+			want:  "One\nTwo",
+		},
+		{
+			name:  "decodes entities",
+			input: "Tom &amp; Jerry&#8217;s &lt;show&gt;",
+			want:  "Tom & Jerry’s <show>",
+		},
+		{
+			name:  "drops scripts and styles",
+			input: "<style>p { color: red }</style>Text<script>alert(1)</script>",
+			want:  "Text",
+		},
+		{
+			name:  "collapses whitespace",
+			input: "<div>\n  Lots   of\n\n\n\n  space  </div>",
+			want:  "Lots of\n\nspace",
+		},
+		{
+			name:  "plain text passes through",
+			input: "Just text",
+			want:  "Just text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripHTML(tt.input); got != tt.want {
+				t.Errorf("stripHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeEntities(t *testing.T) {
+	got := decodeEntities("Caf&eacute; &quot;<b>&amp;</b>&quot;")
+	want := "Café \"<b>&</b>\""
+	if got != want {
+		t.Errorf("decodeEntities() = %q, want %q", got, want)
+	}
+}
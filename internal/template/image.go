@@ -0,0 +1,68 @@
+package template
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html"
+)
+
+// FirstImage returns the source and alt text of the first image embedded
+// in an item's content, or else its description, for feeds that include
+// images only in their HTML. Relative sources are resolved against the
+// item's link, and inline data: images are skipped. It returns an empty
+// source if there's no image.
+func FirstImage(item *gofeed.Item) (src, alt string) {
+	for _, text := range []string{item.Content, item.Description} {
+		if src, alt = firstImageTag(text); src == "" {
+			continue
+		}
+		if base, err := url.Parse(item.Link); err == nil && item.Link != "" {
+			if ref, err := url.Parse(src); err == nil {
+				src = base.ResolveReference(ref).String()
+			}
+		}
+		return src, alt
+	}
+	return "", ""
+}
+
+// firstImageTag returns the src and alt attributes of the first <img> tag
+// in HTML with a usable src.
+func firstImageTag(s string) (src, alt string) {
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return "", ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "img" {
+				continue
+			}
+			src, alt = "", ""
+			for _, attr := range token.Attr {
+				switch attr.Key {
+				case "src":
+					src = strings.TrimSpace(attr.Val)
+				case "alt":
+					alt = strings.TrimSpace(attr.Val)
+				}
+			}
+			if src != "" && !strings.HasPrefix(src, "data:") {
+				return src, alt
+			}
+		}
+	}
+}
+
+// firstImage is the firstImage template function: the URL of the first
+// image in an item's HTML, like {{firstImage .Item}}.
+func firstImage(item *Item) string {
+	if item == nil || item.Item == nil {
+		return ""
+	}
+	src, _ := FirstImage(item.Item)
+	return src
+}
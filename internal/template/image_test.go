@@ -0,0 +1,74 @@
+package template
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestFirstImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    gofeed.Item
+		wantSrc string
+		wantAlt string
+	}{
+		{
+			name:    "content image",
+			item:    gofeed.Item{Content: `<p>Hi</p><img src="https://example.com/a.jpg" alt="A cat"><img src="https://example.com/b.jpg">`},
+			wantSrc: "https://example.com/a.jpg",
+			wantAlt: "A cat",
+		},
+		{
+			name:    "description when content has none",
+			item:    gofeed.Item{Content: "<p>No images</p>", Description: `<img src="https://example.com/d.png" />`},
+			wantSrc: "https://example.com/d.png",
+		},
+		{
+			name:    "relative source resolved against the link",
+			item:    gofeed.Item{Link: "https://example.com/posts/1/", Description: `<img src="../../img/c.jpg">`},
+			wantSrc: "https://example.com/img/c.jpg",
+		},
+		{
+			name:    "data images skipped",
+			item:    gofeed.Item{Description: `<img src="data:image/png;base64,AAAA"><img src="https://example.com/e.jpg">`},
+			wantSrc: "https://example.com/e.jpg",
+		},
+		{
+			name: "no image",
+			item: gofeed.Item{Description: "Just text"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, alt := FirstImage(&tt.item)
+			if src != tt.wantSrc || alt != tt.wantAlt {
+				t.Errorf("FirstImage() = %q, %q; want %q, %q", src, alt, tt.wantSrc, tt.wantAlt)
+			}
+		})
+	}
+}
+
+func TestFirstImageFunction(t *testing.T) {
+	tmplPath := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(tmplPath, []byte("{{with firstImage .Item}}Image: {{.}}{{end}}"), 0o644); err != nil {
+		t.Fatalf("Failed to create test template: %v", err)
+	}
+	renderer, err := New(tmplPath, 500)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	itemJSON, _ := json.Marshal(&gofeed.Item{Description: `<img src="https://example.com/a.jpg">`})
+	result, err := renderer.Render(itemJSON)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "Image: https://example.com/a.jpg"; result != want {
+		t.Errorf("Render() = %q, want %q", result, want)
+	}
+}
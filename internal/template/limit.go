@@ -0,0 +1,97 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Character limit modes, for posts that render over the character limit.
+const (
+	// LimitWarn logs a warning and leaves the post as it is.
+	LimitWarn = "warn"
+	// LimitTruncate shortens the entry's description and content until
+	// the post fits, keeping the rest of the template, like the link and
+	// hashtags, as it is.
+	LimitTruncate = "truncate"
+	// LimitFail refuses to render the post.
+	LimitFail = "fail"
+)
+
+// ErrOverLimit is returned when rendering a post over the character limit
+// that can't be, or isn't allowed to be, shortened.
+var ErrOverLimit = errors.New("post is over the character limit")
+
+// SetCharacterLimitMode sets what happens to posts over the character
+// limit: LimitWarn (the default), LimitTruncate, or LimitFail.
+func (r *Renderer) SetCharacterLimitMode(mode string) error {
+	switch mode {
+	case "":
+		r.limitMode = LimitWarn
+	case LimitWarn, LimitTruncate, LimitFail:
+		r.limitMode = mode
+	default:
+		return fmt.Errorf("invalid character limit mode: %s (must be warn, truncate, or fail)", mode)
+	}
+	return nil
+}
+
+// shorten renders item with its description and content cut to the
+// longest length that fits the post, with archiveLine appended, within the
+// character limit.
+func (r *Renderer) shorten(item *Item, archiveLine, contentWarning string) (string, error) {
+	longest := max(utf8.RuneCountInString(item.Description), utf8.RuneCountInString(item.Content), utf8.RuneCountInString(item.FullContent))
+
+	// Search for the longest cut that fits; shorter text renders shorter
+	var best string
+	fits := false
+	low, high := 0, longest-1
+	for low <= high {
+		n := (low + high) / 2
+		rendered, err := r.execute(cutItem(item, n))
+		if err != nil {
+			return "", err
+		}
+		if PostLength(appendLine(rendered, archiveLine), contentWarning) <= r.characterLimit {
+			best, fits = rendered, true
+			low = n + 1
+		} else {
+			high = n - 1
+		}
+	}
+
+	if !fits {
+		return "", fmt.Errorf("%w even without its description", ErrOverLimit)
+	}
+	logrus.Infof("Shortened the description of a post to fit the character limit of %d", r.characterLimit)
+	return best, nil
+}
+
+// cutItem returns a copy of item with its description, content, and
+// extracted content cut to at most n characters.
+func cutItem(item *Item, n int) *Item {
+	feedItem := *item.Item
+	feedItem.Description = cutText(feedItem.Description, n)
+	feedItem.Content = cutText(feedItem.Content, n)
+	return &Item{Item: &feedItem, FullContent: cutText(item.FullContent, n), LeadImage: item.LeadImage}
+}
+
+// cutText truncates text to at most n characters like the truncate
+// template function, without leaving part of an HTML tag behind.
+func cutText(text string, n int) string {
+	cut := truncate(text, n)
+	if cut == text {
+		return text
+	}
+	body := strings.TrimSuffix(cut, "...")
+	if open := strings.LastIndex(body, "<"); open > strings.LastIndex(body, ">") {
+		body = body[:open]
+	}
+	if body == "" {
+		return ""
+	}
+	return body + "..."
+}
@@ -0,0 +1,110 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestCharacterLimitMode(t *testing.T) {
+	newRenderer := func(t *testing.T, text string, limit int, mode string) *Renderer {
+		t.Helper()
+		tmplPath := filepath.Join(t.TempDir(), "template.txt")
+		if err := os.WriteFile(tmplPath, []byte(text), 0o644); err != nil {
+			t.Fatalf("Failed to create test template: %v", err)
+		}
+		renderer, err := New(tmplPath, limit)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if err := renderer.SetCharacterLimitMode(mode); err != nil {
+			t.Fatalf("SetCharacterLimitMode() error = %v", err)
+		}
+		return renderer
+	}
+
+	item := &gofeed.Item{
+		Title:       "Title",
+		Description: "<p>" + strings.Repeat("word ", 40) + "</p>",
+		Link:        "https://example.com/" + strings.Repeat("long/", 10),
+		Categories:  []string{"go"},
+	}
+	itemJSON, _ := json.Marshal(item)
+	text := "{{.Item.Title}}\n\n{{stripHTML .Item.Description}}\n\n{{.Item.Link}} {{range .Item.Categories}}#{{.}}{{end}}"
+
+	t.Run("truncate shortens the description to fit", func(t *testing.T) {
+		renderer := newRenderer(t, text, 100, LimitTruncate)
+		result, err := renderer.Render(itemJSON)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if length := PostLength(result, ""); length > 100 || length < 95 {
+			t.Errorf("Render() is %d characters, want just under 100:\n%s", length, result)
+		}
+		if !strings.HasPrefix(result, "Title\n\nword word") || !strings.Contains(result, "...") {
+			t.Errorf("Render() = %q, want a shortened description", result)
+		}
+		if !strings.HasSuffix(result, item.Link+" #go") {
+			t.Errorf("Render() = %q, want the link and hashtag kept", result)
+		}
+		if strings.Contains(result, "<") {
+			t.Errorf("Render() = %q, left part of a tag", result)
+		}
+	})
+
+	t.Run("truncate fails when the rest is over the limit", func(t *testing.T) {
+		renderer := newRenderer(t, text, 20, LimitTruncate)
+		if _, err := renderer.Render(itemJSON); !errors.Is(err, ErrOverLimit) {
+			t.Errorf("Render() error = %v, want ErrOverLimit", err)
+		}
+	})
+
+	t.Run("truncate leaves posts within the limit alone", func(t *testing.T) {
+		renderer := newRenderer(t, text, 500, LimitTruncate)
+		result, err := renderer.Render(itemJSON)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if strings.Contains(result, "...") {
+			t.Errorf("Render() = %q, want the full description", result)
+		}
+	})
+
+	t.Run("fail refuses posts over the limit", func(t *testing.T) {
+		renderer := newRenderer(t, text, 100, LimitFail)
+		if _, err := renderer.Render(itemJSON); !errors.Is(err, ErrOverLimit) {
+			t.Errorf("Render() error = %v, want ErrOverLimit", err)
+		}
+	})
+
+	t.Run("rejects unknown modes", func(t *testing.T) {
+		renderer := newRenderer(t, text, 100, "")
+		if err := renderer.SetCharacterLimitMode("shorten"); err == nil {
+			t.Error("SetCharacterLimitMode() error = nil, want an error")
+		}
+	})
+}
+
+func TestCutText(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"a longer text", 8, "a lon..."},
+		{"<p>text</p>", 8, "<p>te..."},
+		{"text <a href=\"x\">link</a>", 12, "text ..."},
+		{"anything", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := cutText(tt.text, tt.n); got != tt.want {
+			t.Errorf("cutText(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
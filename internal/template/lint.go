@@ -0,0 +1,409 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Issue is a likely mistake found in a template by Lint.
+type Issue struct {
+	// Line is the template line the issue is on, or 0 if it's about the
+	// template as a whole.
+	Line    int
+	Message string
+	// Warning is set for issues that may be intended, which shouldn't
+	// fail the lint.
+	Warning bool
+}
+
+// String formats the issue with its line number.
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return i.Message
+	}
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+// LintResult holds the issues Lint found and its estimate of the length of
+// posts rendered by the template.
+type LintResult struct {
+	Issues []Issue
+	// MaxLength is the most characters the template can render, counting
+	// truncated values at their limit and links at 23 characters. It's
+	// only a lower bound if Unbounded isn't empty.
+	MaxLength int
+	// Unbounded lists the values the template outputs without limiting
+	// their length, like {{.Item.Title}}.
+	Unbounded []string
+}
+
+// Failed reports whether the lint found an issue that isn't a warning.
+func (r *LintResult) Failed() bool {
+	for _, issue := range r.Issues {
+		if !issue.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// linkFields are the template values that are URLs, which Mastodon counts
+// as 23 characters whatever their length.
+var linkFields = []string{".Item.Link", ".Item.LeadImage", ".Item.AudioURL", ".Feed.Link", ".Feed.FeedLink"}
+
+// shortFields are fields with a known longest length: an 11 character
+// video ID, a duration like 99:59:59, and an episode number, assumed
+// to have at most 6 digits.
+var shortFields = map[string]int{".Item.VideoID": 11, ".Item.Duration": 8, ".Item.EpisodeNumber": 6}
+
+// Lint parses a template and checks it for common mistakes: references to
+// fields that feed items and feeds don't have or to variables not in vars,
+// not linking to the entry, and posts that can be longer than
+// characterLimit. It returns an error only if the template doesn't parse.
+func Lint(text string, characterLimit int, vars map[string]string) (*LintResult, error) {
+	funcs := (&Renderer{}).funcMap()
+	tmpl, err := template.New("post").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{text: text, funcs: funcs, vars: vars, result: &LintResult{}}
+	if tmpl.Tree != nil {
+		root := scope{typ: reflect.TypeOf(TemplateData{})}
+		l.result.MaxLength = l.walk(tmpl.Tree.Root, root, root)
+	}
+
+	if !l.linked {
+		l.issue(0, false, "the template doesn't include the entry's link; add {{.Item.Link}} so the post leads to the entry")
+	}
+	if len(l.result.Unbounded) > 0 {
+		l.issue(0, true, "the post length is unbounded, since %s %s output without a length limit; wrap long values in truncate, like {{truncate .Item.Description 200}}",
+			strings.Join(l.result.Unbounded, ", "), pluralVerb(len(l.result.Unbounded)))
+	}
+	if l.result.MaxLength > characterLimit {
+		l.issue(0, false, "posts can be %d characters long, over the limit of %d; lower the truncate limits", l.result.MaxLength, characterLimit)
+	}
+
+	return l.result, nil
+}
+
+// pluralVerb returns "is" or "are" for n things.
+func pluralVerb(n int) string {
+	if n == 1 {
+		return "is"
+	}
+	return "are"
+}
+
+// scope is what a name refers to in part of a template: its type, if
+// known, and its path from the template data, like .Item.
+type scope struct {
+	typ  reflect.Type
+	path string
+}
+
+// linter walks a parsed template, type-checking field references against
+// TemplateData and estimating the length of its output.
+type linter struct {
+	text   string
+	funcs  template.FuncMap
+	vars   map[string]string
+	result *LintResult
+	linked bool
+	// ranges is the depth of range actions being walked, whose items
+	// are reported as the range rather than one by one.
+	ranges int
+}
+
+// issue records an issue at the template position pos, or for the whole
+// template if pos is 0.
+func (l *linter) issue(pos parse.Pos, warning bool, format string, args ...any) {
+	line := 0
+	if pos > 0 {
+		line = strings.Count(l.text[:pos], "\n") + 1
+	}
+	l.result.Issues = append(l.result.Issues, Issue{Line: line, Message: fmt.Sprintf(format, args...), Warning: warning})
+}
+
+// unbounded records a value output without a length limit.
+func (l *linter) unbounded(name string) {
+	if !slices.Contains(l.result.Unbounded, name) {
+		l.result.Unbounded = append(l.result.Unbounded, name)
+	}
+}
+
+// walk checks node with dot and root ($) in scope, and returns the most
+// characters it can output.
+func (l *linter) walk(node parse.Node, dot, root scope) int {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		length := 0
+		if n != nil {
+			for _, child := range n.Nodes {
+				length += l.walk(child, dot, root)
+			}
+		}
+		return length
+
+	case *parse.TextNode:
+		return CountCharacters(string(n.Text))
+
+	case *parse.ActionNode:
+		result := l.pipe(n.Pipe, dot, root)
+		if len(n.Pipe.Decl) > 0 {
+			return 0
+		}
+		return l.outputLength(n.Pipe, result)
+
+	case *parse.IfNode:
+		l.pipe(n.Pipe, dot, root)
+		return max(l.walk(n.List, dot, root), l.walk(n.ElseList, dot, root))
+
+	case *parse.WithNode:
+		inner := l.pipe(n.Pipe, dot, root)
+		return max(l.walk(n.List, inner, root), l.walk(n.ElseList, dot, root))
+
+	case *parse.RangeNode:
+		over := l.pipe(n.Pipe, dot, root)
+		elem := scope{path: over.path + "[]"}
+		if t := deref(over.typ); t != nil {
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				elem.typ = t.Elem()
+			}
+		}
+		// Any number of items can be output
+		l.ranges++
+		l.walk(n.List, elem, root)
+		l.ranges--
+		if len(n.List.Nodes) > 0 {
+			l.unbounded("{{range " + over.path + "}}")
+		}
+		return l.walk(n.ElseList, dot, root)
+
+	case *parse.TemplateNode:
+		l.unbounded(fmt.Sprintf("{{template %q}}", n.Name))
+		return 0
+	}
+	return 0
+}
+
+// pipe checks the commands of a pipeline and returns the scope of its
+// result.
+func (l *linter) pipe(pipe *parse.PipeNode, dot, root scope) scope {
+	if pipe == nil {
+		return scope{}
+	}
+	var result scope
+	for _, cmd := range pipe.Cmds {
+		result = l.command(cmd, dot, root)
+	}
+	return result
+}
+
+// command checks a command of a pipeline and returns the scope of its
+// result.
+func (l *linter) command(cmd *parse.CommandNode, dot, root scope) scope {
+	var result scope
+	for i, arg := range cmd.Args {
+		argScope := l.arg(arg, dot, root)
+		if i == 0 {
+			result = argScope
+		}
+	}
+	return result
+}
+
+// arg checks an argument of a command and returns its scope.
+func (l *linter) arg(arg parse.Node, dot, root scope) scope {
+	switch a := arg.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return l.field(a.Position(), dot, a.Ident)
+	case *parse.VariableNode:
+		if a.Ident[0] == "$" {
+			return l.field(a.Position(), scope{typ: root.typ, path: "$"}, a.Ident[1:])
+		}
+		return scope{}
+	case *parse.ChainNode:
+		if pipe, ok := a.Node.(*parse.PipeNode); ok {
+			return l.field(a.Position(), l.pipe(pipe, dot, root), a.Field)
+		}
+		return scope{}
+	case *parse.PipeNode:
+		return l.pipe(a, dot, root)
+	case *parse.IdentifierNode:
+		if fn, ok := l.funcs[a.Ident]; ok {
+			if t := reflect.TypeOf(fn); t.NumOut() > 0 {
+				return scope{typ: t.Out(0), path: a.Ident}
+			}
+		}
+		return scope{path: a.Ident}
+	}
+	return scope{}
+}
+
+// field resolves a chain of field names from s, reporting the first one
+// its type doesn't have.
+func (l *linter) field(pos parse.Pos, s scope, names []string) scope {
+	for _, name := range names {
+		path := strings.TrimPrefix(s.path, "$") + "." + name
+		if s.typ == nil {
+			s = scope{path: path}
+			continue
+		}
+		if s.path == ".Vars" {
+			if _, ok := l.vars[name]; !ok {
+				l.issue(pos, false, "%s isn't set; add %s to template_vars in the config", path, name)
+			}
+		}
+		next, ok := fieldType(s.typ, name)
+		if !ok {
+			message := fmt.Sprintf("%s doesn't exist: %s has no field %s", path, typeName(s), name)
+			if suggestion := similarField(s.typ, name); suggestion != "" {
+				message += fmt.Sprintf(" (did you mean %s?)", strings.TrimSuffix(path, name)+suggestion)
+			}
+			l.issue(pos, false, "%s", message)
+			return scope{path: path}
+		}
+		s = scope{typ: next, path: path}
+	}
+	return s
+}
+
+// typeName names the type of s for issue messages.
+func typeName(s scope) string {
+	if s.path == "" || s.path == "$" {
+		return "the template data"
+	}
+	return strings.TrimPrefix(s.path, "$")
+}
+
+// outputLength returns the most characters an action with the pipeline
+// can output, recording values with no limit.
+func (l *linter) outputLength(pipe *parse.PipeNode, result scope) int {
+	// Functions like cleanURL keep a link a link
+	fields := []string{result.path}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			if field, ok := arg.(*parse.FieldNode); ok {
+				fields = append(fields, "."+strings.Join(field.Ident, "."))
+			}
+		}
+	}
+	if slices.Contains(fields, ".Item.Link") {
+		l.linked = true
+	}
+
+	for _, cmd := range pipe.Cmds {
+		if limit, ok := truncateLimit(cmd); ok {
+			return limit
+		}
+	}
+	for _, field := range fields {
+		if slices.Contains(linkFields, field) || field == "firstImage" {
+			return urlLength
+		}
+	}
+	if length, ok := emojiLength(pipe); ok {
+		return length
+	}
+	if length, ok := shortFields[result.path]; ok && len(pipe.Cmds) == 1 {
+		return length
+	}
+	// Variables are the same in every post
+	if name, ok := strings.CutPrefix(result.path, ".Vars."); ok && len(pipe.Cmds) == 1 {
+		return CountCharacters(l.vars[name])
+	}
+	if result.path != "" && l.ranges == 0 {
+		l.unbounded("{{" + pipe.String() + "}}")
+	}
+	return 0
+}
+
+// truncateLimit returns the length limit of a truncate command, like
+// {{truncate .Item.Description 100}}.
+func truncateLimit(cmd *parse.CommandNode) (int, bool) {
+	if len(cmd.Args) == 0 {
+		return 0, false
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || ident.Ident != "truncate" {
+		return 0, false
+	}
+	for _, arg := range cmd.Args[1:] {
+		if number, ok := arg.(*parse.NumberNode); ok {
+			limit, err := strconv.Atoi(number.Text)
+			if err != nil {
+				return 0, false
+			}
+			return limit, true
+		}
+	}
+	return 0, false
+}
+
+// deref returns the type pointers of t point to.
+func deref(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// fieldType returns the type of t's field or method called name. Fields
+// of maps and interfaces can't be checked, so any name is accepted with
+// an unknown type.
+func fieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	if method, ok := t.MethodByName(name); ok {
+		return methodResult(method.Type), true
+	}
+	if t.Kind() != reflect.Pointer {
+		if method, ok := reflect.PointerTo(t).MethodByName(name); ok {
+			return methodResult(method.Type), true
+		}
+	}
+
+	t = deref(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		field, ok := t.FieldByName(name)
+		if !ok || !field.IsExported() {
+			return nil, false
+		}
+		return field.Type, true
+	case reflect.Map:
+		return t.Elem(), true
+	case reflect.Interface:
+		return nil, true
+	}
+	return nil, false
+}
+
+// methodResult returns the type of a method's first result.
+func methodResult(t reflect.Type) reflect.Type {
+	if t.NumOut() == 0 {
+		return nil
+	}
+	return t.Out(0)
+}
+
+// similarField returns the field of t whose name differs from name only
+// in case, if any.
+func similarField(t reflect.Type, name string) string {
+	t = deref(t)
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+	for _, field := range reflect.VisibleFields(t) {
+		if field.IsExported() && !field.Anonymous && strings.EqualFold(field.Name, name) {
+			return field.Name
+		}
+	}
+	return ""
+}
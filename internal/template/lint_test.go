@@ -0,0 +1,126 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	t.Run("default template passes with a warning", func(t *testing.T) {
+		result, err := Lint(GetDefaultTemplate(), 500, nil)
+		if err != nil {
+			t.Fatalf("Lint() error = %v", err)
+		}
+		if result.Failed() {
+			t.Errorf("Lint() issues = %v, want none that fail", result.Issues)
+		}
+		// The title, the categories, and the feed title aren't truncated
+		if len(result.Unbounded) != 3 {
+			t.Errorf("Unbounded = %v, want the titles and categories", result.Unbounded)
+		}
+	})
+
+	t.Run("reports unknown fields", func(t *testing.T) {
+		result, err := Lint("{{.Item.Title}}\n{{.Item.link}} {{.Item.Summary}}\n{{with .Feed}}{{.Titel}}{{end}}", 500, nil)
+		if err != nil {
+			t.Fatalf("Lint() error = %v", err)
+		}
+		var messages []string
+		for _, issue := range result.Issues {
+			messages = append(messages, issue.String())
+		}
+		all := strings.Join(messages, "\n")
+		for _, want := range []string{
+			"line 2: .Item.link doesn't exist: .Item has no field link (did you mean .Item.Link?)",
+			"line 2: .Item.Summary doesn't exist",
+			"line 3: .Feed.Titel doesn't exist: .Feed has no field Titel",
+		} {
+			if !strings.Contains(all, want) {
+				t.Errorf("issues missing %q:\n%s", want, all)
+			}
+		}
+		if !result.Failed() {
+			t.Error("Failed() = false, want true")
+		}
+	})
+
+	t.Run("accepts fields of ranged items, maps, and extracted content", func(t *testing.T) {
+		text := `{{range .Item.Enclosures}}{{.URL}}{{end}}{{range $k, $v := .Item.Custom}}{{$v}}{{end}}` +
+			`{{with .Item.Author}}{{.Name}}{{end}}{{truncate .Item.FullContent 100}} {{$.Feed.Title}} {{.Item.Link}}`
+		result, err := Lint(text, 500, nil)
+		if err != nil {
+			t.Fatalf("Lint() error = %v", err)
+		}
+		for _, issue := range result.Issues {
+			if !issue.Warning {
+				t.Errorf("unexpected issue: %s", issue)
+			}
+		}
+	})
+
+	t.Run("reports unset variables", func(t *testing.T) {
+		result, err := Lint("{{.Item.Link}} {{.Vars.handle}} {{$.Vars.tag}}", 500, map[string]string{"handle": "@me@example.social"})
+		if err != nil {
+			t.Fatalf("Lint() error = %v", err)
+		}
+		var errors []string
+		for _, issue := range result.Issues {
+			if !issue.Warning {
+				errors = append(errors, issue.Message)
+			}
+		}
+		if len(errors) != 1 || !strings.Contains(errors[0], ".Vars.tag isn't set") {
+			t.Errorf("Issues = %v, want .Vars.tag unset", result.Issues)
+		}
+		// The domain of a mention doesn't count
+		if want := 23 + 1 + len("@me") + 1; result.MaxLength != want || len(result.Unbounded) != 0 {
+			t.Errorf("MaxLength = %d, Unbounded = %v; want %d and none", result.MaxLength, result.Unbounded, want)
+		}
+	})
+
+	t.Run("reports a missing link", func(t *testing.T) {
+		result, err := Lint("{{truncate .Item.Title 100}}", 500, nil)
+		if err != nil {
+			t.Fatalf("Lint() error = %v", err)
+		}
+		if !result.Failed() || !strings.Contains(result.Issues[0].Message, "{{.Item.Link}}") {
+			t.Errorf("Issues = %v, want a missing link", result.Issues)
+		}
+	})
+
+	t.Run("counts a cleaned link", func(t *testing.T) {
+		result, err := Lint("{{truncate .Item.Title 100}} {{cleanURL .Item.Link}}", 500, nil)
+		if err != nil {
+			t.Fatalf("Lint() error = %v", err)
+		}
+		if result.Failed() {
+			t.Errorf("Issues = %v, want none", result.Issues)
+		}
+		if result.MaxLength != 100+1+23 {
+			t.Errorf("MaxLength = %d, want %d", result.MaxLength, 124)
+		}
+	})
+
+	t.Run("estimates the worst case length", func(t *testing.T) {
+		text := "{{truncate .Item.Title 100}}\n\n{{if .Item.Description}}{{truncate .Item.Description 400}}{{else}}{{truncate .Item.Content 300}}{{end}}\n\n{{.Item.Link}}"
+		result, err := Lint(text, 500, nil)
+		if err != nil {
+			t.Fatalf("Lint() error = %v", err)
+		}
+		if want := 100 + 2 + 400 + 2 + 23; result.MaxLength != want {
+			t.Errorf("MaxLength = %d, want %d", result.MaxLength, want)
+		}
+		if len(result.Unbounded) != 0 {
+			t.Errorf("Unbounded = %v, want none", result.Unbounded)
+		}
+		if !result.Failed() || !strings.Contains(result.Issues[0].Message, "over the limit of 500") {
+			t.Errorf("Issues = %v, want over the limit", result.Issues)
+		}
+	})
+
+	t.Run("returns parse errors", func(t *testing.T) {
+		if _, err := Lint("{{.Item.Title", 500, nil); err == nil {
+			t.Error("Lint() error = nil, want a parse error")
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package template
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// youTubeIDPattern matches YouTube video IDs.
+var youTubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// audioExtensions are the file extensions of audio enclosures that don't
+// state their type.
+var audioExtensions = []string{".mp3", ".m4a", ".aac", ".ogg", ".oga", ".opus", ".wav", ".flac"}
+
+// VideoID returns the ID of the YouTube video an item is about, from the
+// yt:videoId element of YouTube's feeds or else from a YouTube link, or ""
+// if it isn't about one. Use it like
+// {{with .Item.VideoID}}https://youtu.be/{{.}}{{end}}.
+func (item *Item) VideoID() string {
+	if item == nil || item.Item == nil {
+		return ""
+	}
+	if id := extensionValue(item.Extensions, "yt", "videoId"); youTubeIDPattern.MatchString(id) {
+		return id
+	}
+	return youTubeLinkID(item.Link)
+}
+
+// youTubeLinkID returns the video ID in a YouTube watch, short, embed, or
+// youtu.be link, or "" if link isn't one.
+func youTubeLinkID(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+
+	var id string
+	switch host {
+	case "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case "youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+			break
+		}
+		for _, prefix := range []string{"/shorts/", "/embed/", "/live/", "/v/"} {
+			if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+				id = strings.Trim(rest, "/")
+			}
+		}
+	}
+	if !youTubeIDPattern.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+// Duration returns the running time of an item's episode or video, like
+// 1:02:03 or 4:05, from its itunes:duration or the duration of its
+// media:content, or "" if it doesn't have one.
+func (item *Item) Duration() string {
+	if item == nil || item.Item == nil {
+		return ""
+	}
+	durations := []string{}
+	if item.ITunesExt != nil {
+		durations = append(durations, item.ITunesExt.Duration)
+	}
+	for _, content := range mediaContents(item.Extensions) {
+		durations = append(durations, content.Attrs["duration"])
+	}
+	for _, duration := range durations {
+		if seconds, ok := parseDuration(duration); ok && seconds > 0 {
+			return formatDuration(seconds)
+		}
+	}
+	return ""
+}
+
+// parseDuration parses a duration in seconds, or as MM:SS or HH:MM:SS, as
+// used by itunes:duration. Fractions of seconds are dropped.
+func parseDuration(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	seconds := 0
+	for _, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || value < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + int(value)
+	}
+	return seconds, true
+}
+
+// formatDuration formats seconds like 1:02:03, or 4:05 under an hour.
+func formatDuration(seconds int) string {
+	hours, minutes := seconds/3600, seconds/60%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds%60)
+}
+
+// EpisodeNumber returns the episode number of a podcast item, from its
+// itunes:episode or podcast:episode element, or 0 if it doesn't have one.
+func (item *Item) EpisodeNumber() int {
+	if item == nil || item.Item == nil {
+		return 0
+	}
+	episodes := []string{extensionValue(item.Extensions, "podcast", "episode")}
+	if item.ITunesExt != nil {
+		episodes = append([]string{item.ITunesExt.Episode}, episodes...)
+	}
+	for _, episode := range episodes {
+		if number, err := strconv.Atoi(strings.TrimSpace(episode)); err == nil && number > 0 {
+			return number
+		}
+	}
+	return 0
+}
+
+// AudioURL returns the URL of an item's audio, like a podcast episode's
+// enclosure, or "" if it has none.
+func (item *Item) AudioURL() string {
+	if item == nil || item.Item == nil {
+		return ""
+	}
+	for _, enclosure := range item.Enclosures {
+		if enclosure != nil && enclosure.URL != "" && isAudio(enclosure.Type, enclosure.URL) {
+			return enclosure.URL
+		}
+	}
+	for _, content := range mediaContents(item.Extensions) {
+		if link := content.Attrs["url"]; link != "" && (content.Attrs["medium"] == "audio" || isAudio(content.Attrs["type"], link)) {
+			return link
+		}
+	}
+	return ""
+}
+
+// isAudio reports whether media of the given type, or at link if it has
+// no type, is audio.
+func isAudio(mediaType, link string) bool {
+	if mediaType != "" {
+		return strings.HasPrefix(mediaType, "audio/")
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(audioExtensions, strings.ToLower(path.Ext(u.Path)))
+}
+
+// extensionValue returns the value of the first prefix:name extension
+// element of an item, or "".
+func extensionValue(extensions ext.Extensions, prefix, name string) string {
+	for _, element := range extensions[prefix][name] {
+		if value := strings.TrimSpace(element.Value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// mediaContents returns an item's media:content elements, including those
+// in media:group elements.
+func mediaContents(extensions ext.Extensions) []ext.Extension {
+	media := extensions["media"]
+	contents := append([]ext.Extension{}, media["content"]...)
+	for _, group := range media["group"] {
+		contents = append(contents, group.Children["content"]...)
+	}
+	return contents
+}
@@ -0,0 +1,139 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestItem_VideoID(t *testing.T) {
+	tests := []struct {
+		name string
+		item gofeed.Item
+		want string
+	}{
+		{
+			name: "YouTube feed",
+			item: gofeed.Item{
+				Link:       "https://www.youtube.com/watch?v=ignoredIgnor",
+				Extensions: ext.Extensions{"yt": {"videoId": {{Value: "dQw4w9WgXcQ"}}}},
+			},
+			want: "dQw4w9WgXcQ",
+		},
+		{name: "watch link", item: gofeed.Item{Link: "https://m.youtube.com/watch?v=dQw4w9WgXcQ&t=42"}, want: "dQw4w9WgXcQ"},
+		{name: "short link", item: gofeed.Item{Link: "https://youtu.be/dQw4w9WgXcQ"}, want: "dQw4w9WgXcQ"},
+		{name: "shorts link", item: gofeed.Item{Link: "https://www.youtube.com/shorts/dQw4w9WgXcQ"}, want: "dQw4w9WgXcQ"},
+		{name: "channel link", item: gofeed.Item{Link: "https://www.youtube.com/@example"}},
+		{name: "other site", item: gofeed.Item{Link: "https://example.com/watch?v=dQw4w9WgXcQ"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &Item{Item: &tt.item}
+			if got := item.VideoID(); got != tt.want {
+				t.Errorf("VideoID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItem_Duration(t *testing.T) {
+	tests := []struct {
+		name string
+		item gofeed.Item
+		want string
+	}{
+		{name: "seconds", item: gofeed.Item{ITunesExt: &ext.ITunesItemExtension{Duration: "3725"}}, want: "1:02:05"},
+		{name: "minutes and seconds", item: gofeed.Item{ITunesExt: &ext.ITunesItemExtension{Duration: "4:05"}}, want: "4:05"},
+		{name: "invalid", item: gofeed.Item{ITunesExt: &ext.ITunesItemExtension{Duration: "about an hour"}}},
+		{
+			name: "media group",
+			item: gofeed.Item{Extensions: ext.Extensions{"media": {"group": {{
+				Children: map[string][]ext.Extension{"content": {{Attrs: map[string]string{"duration": "212.5"}}}},
+			}}}}},
+			want: "3:32",
+		},
+		{name: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &Item{Item: &tt.item}
+			if got := item.Duration(); got != tt.want {
+				t.Errorf("Duration() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItem_EpisodeNumber(t *testing.T) {
+	itunes := &Item{Item: &gofeed.Item{ITunesExt: &ext.ITunesItemExtension{Episode: "12"}}}
+	if got := itunes.EpisodeNumber(); got != 12 {
+		t.Errorf("EpisodeNumber() = %d, want 12 from itunes:episode", got)
+	}
+
+	podcast := &Item{Item: &gofeed.Item{Extensions: ext.Extensions{"podcast": {"episode": {{Value: "7"}}}}}}
+	if got := podcast.EpisodeNumber(); got != 7 {
+		t.Errorf("EpisodeNumber() = %d, want 7 from podcast:episode", got)
+	}
+
+	if got := (&Item{Item: &gofeed.Item{}}).EpisodeNumber(); got != 0 {
+		t.Errorf("EpisodeNumber() = %d, want 0", got)
+	}
+}
+
+func TestItem_AudioURL(t *testing.T) {
+	tests := []struct {
+		name string
+		item gofeed.Item
+		want string
+	}{
+		{
+			name: "typed enclosure",
+			item: gofeed.Item{Enclosures: []*gofeed.Enclosure{
+				{URL: "https://example.com/cover.jpg", Type: "image/jpeg"},
+				{URL: "https://example.com/ep1", Type: "audio/mpeg"},
+			}},
+			want: "https://example.com/ep1",
+		},
+		{
+			name: "untyped enclosure",
+			item: gofeed.Item{Enclosures: []*gofeed.Enclosure{{URL: "https://example.com/ep1.MP3?source=feed"}}},
+			want: "https://example.com/ep1.MP3?source=feed",
+		},
+		{
+			name: "media content",
+			item: gofeed.Item{Extensions: ext.Extensions{"media": {"content": {
+				{Attrs: map[string]string{"url": "https://example.com/ep1.m4a", "medium": "audio"}},
+			}}}},
+			want: "https://example.com/ep1.m4a",
+		},
+		{
+			name: "video only",
+			item: gofeed.Item{Enclosures: []*gofeed.Enclosure{{URL: "https://example.com/ep1.mp4", Type: "video/mp4"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &Item{Item: &tt.item}
+			if got := item.AudioURL(); got != tt.want {
+				t.Errorf("AudioURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLint_MediaFields(t *testing.T) {
+	result, err := Lint("{{.Item.VideoID}} {{.Item.Duration}} #{{.Item.EpisodeNumber}}\n{{.Item.AudioURL}}", 500, nil)
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+	if len(result.Unbounded) != 0 {
+		t.Errorf("Unbounded = %v, want none", result.Unbounded)
+	}
+	if want := 11 + 1 + 8 + 2 + 6 + 1 + 23; result.MaxLength != want {
+		t.Errorf("MaxLength = %d, want %d", result.MaxLength, want)
+	}
+}
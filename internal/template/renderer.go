@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"text/template"
-	"unicode/utf8"
+	"time"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/mmcdole/gofeed"
@@ -17,17 +21,105 @@ import (
 type Renderer struct {
 	tmpl           *template.Template
 	characterLimit int
+	limitMode      string
 	feed           *gofeed.Feed
+	vars           map[string]string
+	escapeMentions bool
+	urlRewrites    []urlRewrite
+	cleanURLs      bool
+	redirectHosts  []string
+	resolved       map[string]string
+	shortener      *linkShortener
+	shortened      map[string]string
+	archive        *archiveLinks
+	archived       map[string]string
+	finalLinks     bool
+	contentWarning string
+	cwRules        []contentWarningRule
+	cwTemplate     *template.Template
+	altTemplate    *template.Template
+	rules          []templateRule
+	location       *time.Location
+	hashtagMap     map[string]string
+	customEmoji    map[string]bool
+	now            func() time.Time
 }
 
+// templateRule selects an alternative template for matching entries.
+type templateRule struct {
+	category string
+	feed     string
+	tmpl     *template.Template
+}
+
+// urlRewrite maps links on one host to an alternative host.
+type urlRewrite struct {
+	from   string
+	scheme string
+	host   string
+}
+
+// urlPattern matches http(s) URLs in rendered post text.
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+
 // TemplateData holds the data passed to templates.
 type TemplateData struct {
-	Item *gofeed.Item
+	Item *Item
 	Feed *gofeed.Feed
+	// Vars holds the template_vars from config.
+	Vars map[string]string
+}
+
+// Item is a feed entry as seen by templates, with the content extracted
+// from its linked article when extract_content is enabled.
+type Item struct {
+	*gofeed.Item
+	// FullContent is the text of the linked article.
+	FullContent string
+	// LeadImage is the URL of the linked article's lead image.
+	LeadImage string
 }
 
 // New creates a new Renderer with the specified template file and character limit.
 func New(templatePath string, characterLimit int) (*Renderer, error) {
+	r := newRenderer(characterLimit)
+
+	tmpl, err := r.parseTemplate(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	r.tmpl = tmpl
+
+	return r, nil
+}
+
+// NewFromString creates a new Renderer with the given template text,
+// rather than a template file.
+func NewFromString(text string, characterLimit int) (*Renderer, error) {
+	r := newRenderer(characterLimit)
+
+	tmpl, err := template.New("post").Funcs(r.funcMap()).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	r.tmpl = tmpl
+
+	return r, nil
+}
+
+// newRenderer returns a Renderer with the default settings and no
+// template.
+func newRenderer(characterLimit int) *Renderer {
+	return &Renderer{
+		characterLimit: characterLimit,
+		limitMode:      LimitWarn,
+		escapeMentions: true,
+		now:            time.Now,
+	}
+}
+
+// parseTemplate reads and parses a template file with the custom functions.
+func (r *Renderer) parseTemplate(templatePath string) (*template.Template, error) {
 	// Read template file
 	tmplContent, err := os.ReadFile(templatePath)
 	if err != nil {
@@ -35,18 +127,62 @@ func New(templatePath string, characterLimit int) (*Renderer, error) {
 	}
 
 	// Parse template with custom functions
-	tmpl, err := template.New("post").Funcs(template.FuncMap{
-		"truncate":       truncate,
-		"htmltomarkdown": htmlToMarkdown,
-	}).Parse(string(tmplContent))
+	tmpl, err := template.New("post").Funcs(r.funcMap()).Parse(string(tmplContent))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse template: %w", err)
+		return nil, fmt.Errorf("failed to parse template %s: %w", templatePath, err)
 	}
 
-	return &Renderer{
-		tmpl:           tmpl,
-		characterLimit: characterLimit,
-	}, nil
+	return tmpl, nil
+}
+
+// AddTemplateRule adds a rule that renders entries with the template at
+// templatePath instead of the default one. The rule matches entries with
+// the given category and from the given feed; an empty value matches
+// anything. The feed matches the feed's title, link, or feed link.
+// Matching ignores case, and the first matching rule wins.
+func (r *Renderer) AddTemplateRule(category, feed, templatePath string) error {
+	if category == "" && feed == "" {
+		return fmt.Errorf("template rule for %s needs a category or feed to match", templatePath)
+	}
+
+	tmpl, err := r.parseTemplate(templatePath)
+	if err != nil {
+		return err
+	}
+
+	r.rules = append(r.rules, templateRule{category: category, feed: feed, tmpl: tmpl})
+	return nil
+}
+
+// templateFor returns the template of the first rule matching item, or
+// the default template.
+func (r *Renderer) templateFor(item *gofeed.Item) *template.Template {
+	for _, rule := range r.rules {
+		if rule.matches(item, r.feed) {
+			return rule.tmpl
+		}
+	}
+	return r.tmpl
+}
+
+// matches reports whether the rule applies to item from feed.
+func (rule templateRule) matches(item *gofeed.Item, feed *gofeed.Feed) bool {
+	if rule.category != "" && !slices.ContainsFunc(item.Categories, func(category string) bool {
+		return strings.EqualFold(category, rule.category)
+	}) {
+		return false
+	}
+
+	if rule.feed != "" {
+		if feed == nil {
+			return false
+		}
+		if !strings.EqualFold(feed.Title, rule.feed) && !strings.EqualFold(feed.Link, rule.feed) && !strings.EqualFold(feed.FeedLink, rule.feed) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // truncate truncates a string to the specified maximum length.
@@ -97,40 +233,173 @@ func htmlToMarkdown(html string) string {
 	return markdown
 }
 
+// CharacterLimit returns the configured character limit for posts.
+func (r *Renderer) CharacterLimit() int {
+	return r.characterLimit
+}
+
+// SetContentWarning sets the content warning posts are published with, which
+// counts toward the character limit. Content warning rules and the content
+// warning template take precedence.
+func (r *Renderer) SetContentWarning(contentWarning string) {
+	r.contentWarning = contentWarning
+}
+
+// SetTimezone sets the timezone date functions format dates in. By default
+// dates keep the zone they were parsed with.
+func (r *Renderer) SetTimezone(location *time.Location) {
+	r.location = location
+}
+
 // SetFeed sets the feed metadata for use in templates.
 func (r *Renderer) SetFeed(feed *gofeed.Feed) {
 	r.feed = feed
 }
 
+// SetVars sets the variables templates can use as .Vars, like
+// {{.Vars.handle}}.
+func (r *Renderer) SetVars(vars map[string]string) {
+	r.vars = vars
+}
+
+// AddURLRewrite adds a rule that rewrites links on the from host (and its
+// subdomains) to the to host. The to value may include a scheme, as in
+// "https://yewtu.be", otherwise the original scheme is kept.
+func (r *Renderer) AddURLRewrite(from, to string) error {
+	rule := urlRewrite{from: strings.ToLower(strings.TrimPrefix(from, "www."))}
+
+	if strings.Contains(to, "://") {
+		toURL, err := url.Parse(to)
+		if err != nil {
+			return fmt.Errorf("invalid rewrite target %q: %w", to, err)
+		}
+		rule.scheme = toURL.Scheme
+		rule.host = toURL.Host
+	} else {
+		rule.host = strings.TrimSuffix(to, "/")
+	}
+
+	if rule.from == "" || rule.host == "" {
+		return fmt.Errorf("invalid rewrite rule: %q -> %q", from, to)
+	}
+
+	r.urlRewrites = append(r.urlRewrites, rule)
+	return nil
+}
+
+// rewriteURLs applies the configured URL rewrite rules to every link in text.
+func (r *Renderer) rewriteURLs(text string) string {
+	if len(r.urlRewrites) == 0 {
+		return text
+	}
+
+	return urlPattern.ReplaceAllStringFunc(text, func(link string) string {
+		u, err := url.Parse(link)
+		if err != nil {
+			return link
+		}
+
+		host := strings.ToLower(u.Hostname())
+		for _, rule := range r.urlRewrites {
+			if host != rule.from && !strings.HasSuffix(host, "."+rule.from) {
+				continue
+			}
+			if rule.scheme != "" {
+				u.Scheme = rule.scheme
+			}
+			u.Host = rule.host
+			logrus.Debugf("Rewrote URL %s -> %s", link, u.String())
+			return u.String()
+		}
+
+		return link
+	})
+}
+
 // Render renders the template with the given entry data.
 func (r *Renderer) Render(entryJSON []byte) (string, error) {
+	return r.RenderArticle(entryJSON, "", "")
+}
+
+// RenderArticle renders the template with the given entry data and the
+// content and lead image extracted from the entry's linked article.
+func (r *Renderer) RenderArticle(entryJSON []byte, fullContent, leadImage string) (string, error) {
 	// Unmarshal entry JSON into gofeed.Item
 	var item gofeed.Item
 	if err := json.Unmarshal(entryJSON, &item); err != nil {
 		return "", fmt.Errorf("failed to unmarshal entry: %w", err)
 	}
 
-	// Create template data
-	data := TemplateData{
-		Item: &item,
-		Feed: r.feed,
+	data := r.escapeItem(&Item{Item: &item, FullContent: fullContent, LeadImage: leadImage})
+	archiveLine := r.archiveLine(data)
+	rendered, err := r.execute(data)
+	if err != nil {
+		return "", err
 	}
 
-	// Execute template
-	var buf bytes.Buffer
-	if err := r.tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+	// Check character limit, counting like Mastodon does. Short links
+	// count the same as the links they replace, so they're made last.
+	contentWarning, err := r.contentWarningFor(&item)
+	if err != nil {
+		return "", err
 	}
+	if length := PostLength(appendLine(rendered, archiveLine), contentWarning); length > r.characterLimit {
+		switch r.limitMode {
+		case LimitTruncate:
+			if rendered, err = r.shorten(data, archiveLine, contentWarning); err != nil {
+				return "", err
+			}
+		case LimitFail:
+			return "", fmt.Errorf("%w: %d > %d", ErrOverLimit, length, r.characterLimit)
+		default:
+			logrus.Warnf("Rendered post exceeds character limit: %d > %d", length, r.characterLimit)
+		}
+	}
+	return appendLine(r.shortenLinks(rendered), archiveLine), nil
+}
 
-	rendered := buf.String()
+// SetFinalLinks sets whether rendered posts are final, to be published, so
+// their links are shortened and an archive link is appended, if those are
+// set up. Both make requests to other services, which can create short
+// links and snapshots, so previews and dry runs leave them out.
+func (r *Renderer) SetFinalLinks(final bool) {
+	r.finalLinks = final
+}
 
-	// Check character limit and warn if exceeded
-	runeCount := utf8.RuneCountInString(rendered)
-	if runeCount > r.characterLimit {
-		logrus.Warnf("Rendered post exceeds character limit: %d > %d", runeCount, r.characterLimit)
+// FinalizeLinks shortens the links in text and appends the archive link of
+// the entry's link, like rendering with final links does, for a post
+// rendered without them, whatever SetFinalLinks was set to.
+func (r *Renderer) FinalizeLinks(text string, entryJSON []byte) (string, error) {
+	var item gofeed.Item
+	if err := json.Unmarshal(entryJSON, &item); err != nil {
+		return "", fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+
+	final := r.finalLinks
+	r.finalLinks = true
+	defer func() { r.finalLinks = final }()
+	return appendLine(r.shortenLinks(text), r.archiveLine(&Item{Item: &item})), nil
+}
+
+// execute renders the template for item, with links cleaned and
+// rewritten.
+func (r *Renderer) execute(item *Item) (string, error) {
+	var buf bytes.Buffer
+	data := TemplateData{Item: item, Feed: r.feed, Vars: r.vars}
+	if err := r.templateFor(item.Item).Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
+	return r.rewriteURLs(r.cleanLinks(buf.String())), nil
+}
 
-	return rendered, nil
+// executeInline executes a one-line template from config, like
+// cw_template, for item, trimming surrounding whitespace from the result.
+func (r *Renderer) executeInline(tmpl *template.Template, item *gofeed.Item) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, TemplateData{Item: r.escapeItem(&Item{Item: item}), Feed: r.feed, Vars: r.vars}); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
 }
 
 // GetDefaultTemplate returns a simple default template.
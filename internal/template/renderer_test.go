@@ -260,6 +260,21 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestNewFromString(t *testing.T) {
+	renderer, err := NewFromString(`{{truncate .Item.Title 8}} {{.Item.Link}}`, 500)
+	if err != nil {
+		t.Fatalf("NewFromString() error = %v", err)
+	}
+	itemJSON, _ := json.Marshal(&gofeed.Item{Title: "A long title", Link: "https://example.com/a"})
+	if result, err := renderer.Render(itemJSON); err != nil || result != "A lon... https://example.com/a" {
+		t.Errorf("Render() = %q, %v", result, err)
+	}
+
+	if _, err := NewFromString("{{.Invalid{{}}", 500); err == nil {
+		t.Error("Expected error for invalid template syntax")
+	}
+}
+
 func TestRender(t *testing.T) {
 	t.Run("renders simple template", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -349,6 +364,32 @@ Description: Test Description`
 		}
 	})
 
+	t.Run("renders extracted article", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		tmplPath := filepath.Join(tmpDir, "template.txt")
+		err := os.WriteFile(tmplPath, []byte("{{.Item.Title}}: {{.Item.FullContent}} {{.Item.LeadImage}}"), 0o644)
+		if err != nil {
+			t.Fatalf("Failed to create test template: %v", err)
+		}
+
+		renderer, err := New(tmplPath, 500)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Stub"})
+
+		result, err := renderer.RenderArticle(itemJSON, "The whole story", "https://example.com/lead.jpg")
+		if err != nil {
+			t.Fatalf("RenderArticle() error = %v", err)
+		}
+
+		expected := "Stub: The whole story https://example.com/lead.jpg"
+		if result != expected {
+			t.Errorf("RenderArticle() = %q, want %q", result, expected)
+		}
+	})
+
 	// Note: Testing truncate function with numeric literals in templates is
 	// challenging due to html/template's strict type checking. The truncate
 	// function itself is thoroughly tested in TestTruncate.
@@ -372,6 +413,30 @@ Description: Test Description`
 		}
 	})
 
+	t.Run("renders config variables", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		tmplPath := filepath.Join(tmpDir, "template.txt")
+		err := os.WriteFile(tmplPath, []byte("{{.Item.Title}} by {{.Vars.handle}} #{{.Vars.campaign}}"), 0o644)
+		if err != nil {
+			t.Fatalf("Failed to create test template: %v", err)
+		}
+
+		renderer, err := New(tmplPath, 500)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		renderer.SetVars(map[string]string{"handle": "@me@example.social", "campaign": "Launch"})
+
+		itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Test"})
+		result, err := renderer.Render(itemJSON)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if want := "Test by @me@example.social #Launch"; result != want {
+			t.Errorf("Render() = %q, want %q", result, want)
+		}
+	})
+
 	t.Run("character limit warning", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		tmplPath := filepath.Join(tmpDir, "template.txt")
@@ -403,6 +468,142 @@ Description: Test Description`
 	})
 }
 
+func TestURLRewrites(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  string
+		to    string
+		input string
+		want  string
+	}{
+		{
+			name:  "rewrites matching host",
+			from:  "youtube.com",
+			to:    "https://yewtu.be",
+			input: "Watch https://youtube.com/watch?v=abc123",
+			want:  "Watch https://yewtu.be/watch?v=abc123",
+		},
+		{
+			name:  "rewrites subdomains",
+			from:  "youtube.com",
+			to:    "https://yewtu.be",
+			input: "https://www.youtube.com/watch?v=abc123",
+			want:  "https://yewtu.be/watch?v=abc123",
+		},
+		{
+			name:  "keeps scheme when target has none",
+			from:  "twitter.com",
+			to:    "nitter.example",
+			input: "http://twitter.com/someone/status/1",
+			want:  "http://nitter.example/someone/status/1",
+		},
+		{
+			name:  "leaves other hosts alone",
+			from:  "youtube.com",
+			to:    "https://yewtu.be",
+			input: "https://notyoutube.com/watch https://example.com/",
+			want:  "https://notyoutube.com/watch https://example.com/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			tmplPath := filepath.Join(tmpDir, "template.txt")
+			if err := os.WriteFile(tmplPath, []byte("{{.Item.Link}}"), 0o644); err != nil {
+				t.Fatalf("Failed to create test template: %v", err)
+			}
+
+			renderer, err := New(tmplPath, 500)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := renderer.AddURLRewrite(tt.from, tt.to); err != nil {
+				t.Fatalf("AddURLRewrite() error = %v", err)
+			}
+
+			itemJSON, _ := json.Marshal(&gofeed.Item{Link: tt.input})
+			result, err := renderer.Render(itemJSON)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			if result != tt.want {
+				t.Errorf("Render() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test template: %v", err)
+		}
+		return path
+	}
+
+	renderer, err := New(write("default.txt", "post: {{.Item.Title}}"), 500)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	renderer.SetFeed(&gofeed.Feed{Title: "My Blog", FeedLink: "https://example.com/feed.xml"})
+
+	if err := renderer.AddTemplateRule("podcast", "", write("podcast.txt", "episode: {{.Item.Title}}")); err != nil {
+		t.Fatalf("AddTemplateRule() error = %v", err)
+	}
+	if err := renderer.AddTemplateRule("", "https://example.com/feed.xml", write("feed.txt", "blog: {{.Item.Title}}")); err != nil {
+		t.Fatalf("AddTemplateRule() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		item gofeed.Item
+		want string
+	}{
+		{"first matching rule wins", gofeed.Item{Title: "Ep 1", Categories: []string{"Podcast"}}, "episode: Ep 1"},
+		{"matches feed", gofeed.Item{Title: "Post", Categories: []string{"news"}}, "blog: Post"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			itemJSON, _ := json.Marshal(&tt.item)
+			result, err := renderer.Render(itemJSON)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Render() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+
+	t.Run("falls back to the default template", func(t *testing.T) {
+		renderer.SetFeed(&gofeed.Feed{Title: "Other"})
+		itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Post"})
+		result, err := renderer.Render(itemJSON)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if result != "post: Post" {
+			t.Errorf("Render() = %q, want %q", result, "post: Post")
+		}
+	})
+
+	t.Run("rejects rules without a match", func(t *testing.T) {
+		if err := renderer.AddTemplateRule("", "", write("any.txt", "x")); err == nil {
+			t.Error("Expected error for rule without category or feed")
+		}
+	})
+
+	t.Run("rejects missing template", func(t *testing.T) {
+		if err := renderer.AddTemplateRule("podcast", "", filepath.Join(tmpDir, "missing.txt")); err == nil {
+			t.Error("Expected error for missing template file")
+		}
+	})
+}
+
 func TestGetDefaultTemplate(t *testing.T) {
 	t.Run("returns non-empty string", func(t *testing.T) {
 		tmpl := GetDefaultTemplate()
@@ -0,0 +1,141 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// shortenerTimeout bounds a request to the link shortener.
+const shortenerTimeout = 15 * time.Second
+
+// maxShortenerResponse is the most of a shortener's response that is read.
+const maxShortenerResponse = 64 * 1024
+
+// linkShortener shortens links through a URL shortener's API.
+type linkShortener struct {
+	apiURL  string
+	field   string
+	headers map[string]string
+	host    string
+}
+
+// SetLinkShortener has every link in rendered posts replaced by a short
+// link from a URL shortener's API. apiURL is requested with GET, with
+// {url} in it replaced by the escaped link, as in
+// https://is.gd/create.php?format=simple&url={url}. If field is empty, the
+// response is the short link as text; otherwise the response is JSON and
+// field is the dotted path of the short link in it, like shorturl or
+// data.link. Headers, like an API key, are sent with each request. Links
+// are only shortened once they're final; see SetFinalLinks.
+func (r *Renderer) SetLinkShortener(apiURL, field string, headers map[string]string) error {
+	if !strings.Contains(apiURL, "{url}") {
+		return fmt.Errorf("link shortener URL %q has no {url} for the link", apiURL)
+	}
+	u, err := url.Parse(strings.ReplaceAll(apiURL, "{url}", ""))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid link shortener URL %q", apiURL)
+	}
+	r.shortener = &linkShortener{
+		apiURL:  apiURL,
+		field:   field,
+		headers: headers,
+		host:    strings.ToLower(u.Hostname()),
+	}
+	return nil
+}
+
+// shortenLinks replaces every link in text with its short link, if a link
+// shortener is set and links are final. Short links are remembered, and
+// links that fail to shorten are kept as they are.
+func (r *Renderer) shortenLinks(text string) string {
+	if r.shortener == nil || !r.finalLinks {
+		return text
+	}
+
+	return urlPattern.ReplaceAllStringFunc(text, func(link string) string {
+		if u, err := url.Parse(link); err != nil || strings.EqualFold(u.Hostname(), r.shortener.host) {
+			return link
+		}
+		if short, ok := r.shortened[link]; ok {
+			return short
+		}
+
+		short, err := r.shortener.shorten(link)
+		if err != nil {
+			logrus.Warnf("Failed to shorten %s: %v", link, err)
+			short = link
+		} else {
+			logrus.Debugf("Shortened URL %s -> %s", link, short)
+		}
+		if r.shortened == nil {
+			r.shortened = make(map[string]string)
+		}
+		r.shortened[link] = short
+		return short
+	})
+}
+
+// shorten requests the short link for link from the shortener's API.
+func (s *linkShortener) shorten(link string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.ReplaceAll(s.apiURL, "{url}", url.QueryEscape(link)), nil)
+	if err != nil {
+		return "", err
+	}
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	client := &http.Client{Timeout: shortenerTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxShortenerResponse))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("shortener returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	short := strings.TrimSpace(string(body))
+	if s.field != "" {
+		if short, err = jsonField(body, s.field); err != nil {
+			return "", err
+		}
+	}
+	if u, err := url.Parse(short); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("shortener returned %q, which isn't a link", truncate(short, 100))
+	}
+	return short, nil
+}
+
+// jsonField returns the string at a dotted path, like data.link, in a JSON
+// object.
+func jsonField(body []byte, path string) (string, error) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", fmt.Errorf("invalid JSON response: %w", err)
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("response has no %s", path)
+		}
+		value = object[key]
+	}
+	text, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("response has no %s", path)
+	}
+	return text, nil
+}
@@ -0,0 +1,149 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// newLinkRenderer returns a renderer for a template with the item's title
+// and link, rendering final links.
+func newLinkRenderer(t *testing.T) *Renderer {
+	t.Helper()
+	tmplPath := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(tmplPath, []byte("{{.Item.Title}} {{.Item.Link}}"), 0o644); err != nil {
+		t.Fatalf("Failed to create test template: %v", err)
+	}
+	renderer, err := New(tmplPath, 500)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	renderer.SetFinalLinks(true)
+	return renderer
+}
+
+// renderLink renders an item with link.
+func renderLink(t *testing.T, renderer *Renderer, link string) (string, error) {
+	t.Helper()
+	itemJSON, _ := json.Marshal(&gofeed.Item{Title: "Post", Link: link})
+	return renderer.Render(itemJSON)
+}
+
+func TestShortenLinks(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		link := r.URL.Query().Get("url")
+		switch {
+		case r.Header.Get("X-Api-Key") != "secret":
+			http.Error(w, "bad key", http.StatusForbidden)
+		case strings.Contains(link, "broken"):
+			fmt.Fprint(w, "Error: invalid URL")
+		case r.URL.Path == "/json":
+			fmt.Fprintf(w, `{"data": {"link": "https://sho.rt/%d"}}`, requests)
+		default:
+			fmt.Fprintf(w, "https://sho.rt/%d\n", requests)
+		}
+	}))
+	defer server.Close()
+	headers := map[string]string{"X-Api-Key": "secret"}
+
+	t.Run("shortens links once", func(t *testing.T) {
+		renderer := newLinkRenderer(t)
+		if err := renderer.SetLinkShortener(server.URL+"/?url={url}", "", headers); err != nil {
+			t.Fatalf("SetLinkShortener() error = %v", err)
+		}
+		requests = 0
+		for i := 0; i < 2; i++ {
+			got, err := renderLink(t, renderer, "https://example.com/post?a=1&b=2")
+			if err != nil || got != "Post https://sho.rt/1" {
+				t.Errorf("Render() = %q, %v", got, err)
+			}
+		}
+		if requests != 1 {
+			t.Errorf("shortener requests = %d, want 1", requests)
+		}
+	})
+
+	t.Run("leaves links alone until they're final", func(t *testing.T) {
+		renderer := newLinkRenderer(t)
+		if err := renderer.SetLinkShortener(server.URL+"/?url={url}", "", headers); err != nil {
+			t.Fatalf("SetLinkShortener() error = %v", err)
+		}
+		renderer.SetFinalLinks(false)
+		requests = 0
+		got, err := renderLink(t, renderer, "https://example.com/post")
+		if err != nil || got != "Post https://example.com/post" || requests != 0 {
+			t.Errorf("Render() = %q, %v with %d requests; want the link as it is", got, err, requests)
+		}
+
+		got, err = renderer.FinalizeLinks(got, []byte(`{"link": "https://example.com/post"}`))
+		if err != nil || got != "Post https://sho.rt/1" {
+			t.Errorf("FinalizeLinks() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("reads the link from JSON", func(t *testing.T) {
+		renderer := newLinkRenderer(t)
+		if err := renderer.SetLinkShortener(server.URL+"/json?url={url}", "data.link", headers); err != nil {
+			t.Fatalf("SetLinkShortener() error = %v", err)
+		}
+		requests = 0
+		if got, err := renderLink(t, renderer, "https://example.com/post"); err != nil || got != "Post https://sho.rt/1" {
+			t.Errorf("Render() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("keeps links that fail to shorten", func(t *testing.T) {
+		renderer := newLinkRenderer(t)
+		if err := renderer.SetLinkShortener(server.URL+"/?url={url}", "", nil); err != nil {
+			t.Fatalf("SetLinkShortener() error = %v", err)
+		}
+		if got, err := renderLink(t, renderer, "https://example.com/post"); err != nil || got != "Post https://example.com/post" {
+			t.Errorf("Render() = %q, %v", got, err)
+		}
+
+		if err := renderer.SetLinkShortener(server.URL+"/?url={url}", "", headers); err != nil {
+			t.Fatalf("SetLinkShortener() error = %v", err)
+		}
+		if got, err := renderLink(t, renderer, "https://example.com/broken"); err != nil || got != "Post https://example.com/broken" {
+			t.Errorf("Render() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("requires a place for the link", func(t *testing.T) {
+		renderer := newLinkRenderer(t)
+		if err := renderer.SetLinkShortener(server.URL+"/?format=simple", "", nil); err == nil {
+			t.Error("SetLinkShortener() without {url} succeeded")
+		}
+	})
+}
+
+func TestJSONField(t *testing.T) {
+	body := []byte(`{"shorturl": "https://sho.rt/a", "data": {"link": "https://sho.rt/b", "id": 7}}`)
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "shorturl", want: "https://sho.rt/a"},
+		{path: "data.link", want: "https://sho.rt/b"},
+		{path: "data.id", wantErr: true},
+		{path: "shorturl.link", wantErr: true},
+		{path: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := jsonField(body, tt.path)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("jsonField(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordPattern matches a word with the whitespace before it.
+var wordPattern = regexp.MustCompile(`\s*\S+`)
+
+// SplitThread splits text that's longer than limit into parts for posting as
+// a thread. Parts break at whitespace where possible and end with a " (i/N)"
+// marker; each part, marker included, fits within limit as counted by
+// CountCharacters. Text that already fits is returned as a single part
+// without a marker.
+func SplitThread(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if CountCharacters(text) <= limit || limit <= 0 {
+		return []string{text}
+	}
+
+	// The marker length depends on the number of parts, so grow the
+	// estimate until the split agrees with it
+	total := 2
+	for {
+		budget := limit - utf8.RuneCountInString(marker(total, total))
+		if budget < 1 {
+			return []string{text}
+		}
+
+		chunks := splitWords(text, budget)
+		if len(chunks) <= total {
+			parts := make([]string, len(chunks))
+			for i, chunk := range chunks {
+				parts[i] = chunk + marker(i+1, len(chunks))
+			}
+			return parts
+		}
+		total = len(chunks)
+	}
+}
+
+// marker returns the thread position marker for part i of n.
+func marker(i, n int) string {
+	return fmt.Sprintf(" (%d/%d)", i, n)
+}
+
+// splitWords breaks text into chunks that count as at most budget
+// characters, breaking at whitespace. Words longer than budget are cut.
+func splitWords(text string, budget int) []string {
+	var chunks []string
+	chunk := ""
+
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		if chunk != "" && CountCharacters(chunk+word) <= budget {
+			chunk += word
+			continue
+		}
+
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		chunk = strings.TrimLeftFunc(word, unicode.IsSpace)
+
+		// Cut words that don't fit on their own
+		for CountCharacters(chunk) > budget {
+			runes := []rune(chunk)
+			chunks = append(chunks, string(runes[:budget]))
+			chunk = string(runes[budget:])
+		}
+	}
+
+	if chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
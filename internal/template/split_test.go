@@ -0,0 +1,79 @@
+package template
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitThread(t *testing.T) {
+	t.Run("short text is a single part", func(t *testing.T) {
+		parts := SplitThread("  Hello world  ", 500)
+		if len(parts) != 1 || parts[0] != "Hello world" {
+			t.Errorf("SplitThread() = %q, want [\"Hello world\"]", parts)
+		}
+	})
+
+	t.Run("splits at word boundaries with markers", func(t *testing.T) {
+		text := "one two three four five six seven eight nine ten"
+		parts := SplitThread(text, 25)
+
+		if len(parts) < 2 {
+			t.Fatalf("SplitThread() = %q, want several parts", parts)
+		}
+		var words []string
+		for i, part := range parts {
+			if n := utf8.RuneCountInString(part); n > 25 {
+				t.Errorf("part %d is %d runes, over the limit: %q", i, n, part)
+			}
+			suffix := marker(i+1, len(parts))
+			if !strings.HasSuffix(part, suffix) {
+				t.Errorf("part %d = %q, want suffix %q", i, part, suffix)
+			}
+			words = append(words, strings.Fields(strings.TrimSuffix(part, suffix))...)
+		}
+		if strings.Join(words, " ") != text {
+			t.Errorf("rejoined text = %q, want %q", strings.Join(words, " "), text)
+		}
+	})
+
+	t.Run("cuts words longer than the limit", func(t *testing.T) {
+		text := strings.Repeat("x", 50)
+		parts := SplitThread(text, 20)
+
+		joined := ""
+		for i, part := range parts {
+			if n := utf8.RuneCountInString(part); n > 20 {
+				t.Errorf("part %d is %d runes, over the limit", i, n)
+			}
+			joined += strings.TrimSuffix(part, marker(i+1, len(parts)))
+		}
+		if joined != text {
+			t.Errorf("rejoined text = %q, want %q", joined, text)
+		}
+	})
+
+	t.Run("counts URLs as 23 characters", func(t *testing.T) {
+		link := "https://example.com/" + strings.Repeat("a", 80)
+		text := "See " + link + " for details"
+		parts := SplitThread(text, 40)
+
+		if len(parts) != 1 || parts[0] != text {
+			t.Errorf("SplitThread() = %q, want the text unsplit", parts)
+		}
+	})
+
+	t.Run("marker width grows with part count", func(t *testing.T) {
+		text := strings.Repeat("word ", 60)
+		parts := SplitThread(text, 20)
+
+		if len(parts) < 10 {
+			t.Fatalf("expected at least 10 parts, got %d", len(parts))
+		}
+		for i, part := range parts {
+			if n := utf8.RuneCountInString(part); n > 20 {
+				t.Errorf("part %d is %d runes, over the limit: %q", i, n, part)
+			}
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package template
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// abbreviations end with a period without ending a sentence.
+var abbreviations = map[string]bool{
+	"e.g.": true, "i.e.": true, "etc.": true, "vs.": true, "cf.": true,
+	"mr.": true, "mrs.": true, "ms.": true, "dr.": true, "prof.": true,
+	"st.": true, "jr.": true, "sr.": true, "inc.": true, "no.": true,
+}
+
+// summarize returns the first n sentences of text, after converting any
+// HTML to plain text, like {{summarize 2 .Item.Description}}. Unlike
+// truncate, it doesn't cut a sentence short.
+func summarize(n int, text string) string {
+	text = stripHTML(text)
+	if n <= 0 {
+		return ""
+	}
+	end := 0
+	for i := 0; i < n; i++ {
+		next := sentenceEnd(text, end)
+		if next < 0 {
+			return text
+		}
+		end = next
+	}
+	return strings.TrimSpace(text[:end])
+}
+
+// sentenceEnd returns the index just past the sentence of text starting
+// at start, including closing quotes and brackets, or -1 if it runs to the
+// end of the text. A sentence ends at a paragraph break, or at ., !, ?,
+// or … followed by a space, unless the period ends an abbreviation or an
+// initial.
+func sentenceEnd(text string, start int) int {
+	for i := start; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+
+		if r == '\n' && strings.HasPrefix(text[i:], "\n") {
+			if strings.TrimSpace(text[start:i]) != "" {
+				return i
+			}
+			continue
+		}
+		if !strings.ContainsRune(".!?…", r) {
+			continue
+		}
+
+		end := i
+		for end < len(text) {
+			next, size := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune(`.!?…"')]”’`, next) {
+				break
+			}
+			end += size
+		}
+		if end == len(text) {
+			return -1
+		}
+		if next, _ := utf8.DecodeRuneInString(text[end:]); !unicode.IsSpace(next) {
+			continue
+		}
+		if r == '.' && !endsSentence(text[start:i]) {
+			continue
+		}
+		return end
+	}
+	return -1
+}
+
+// endsSentence reports whether a period at the end of text ends the
+// sentence, rather than an abbreviation like "e.g." or an initial.
+func endsSentence(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false
+	}
+	word := strings.ToLower(strings.TrimLeft(fields[len(fields)-1], `"'([“‘`))
+	if abbreviations[word] {
+		return false
+	}
+	letters := strings.TrimSuffix(word, ".")
+	return utf8.RuneCountInString(letters) != 1 || !unicode.IsLetter([]rune(letters)[0])
+}
+
+// summarizeWords returns the first n words of text, after converting any
+// HTML to plain text, with an ellipsis if words were left out, like
+// {{summarizeWords 30 .Item.Description}}. Unlike truncate, it doesn't cut
+// a word short.
+func summarizeWords(n int, text string) string {
+	words := strings.Fields(stripHTML(text))
+	if n <= 0 {
+		return ""
+	}
+	if len(words) <= n {
+		return strings.Join(words, " ")
+	}
+	return strings.TrimRight(strings.Join(words[:n], " "), ",;:") + "…"
+}
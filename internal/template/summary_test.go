@@ -0,0 +1,53 @@
+package template
+
+import "testing"
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		text string
+		want string
+	}{
+		{"first sentence", 1, "One thing. Another thing. A third.", "One thing."},
+		{"two sentences", 2, "One thing! Another thing? A third.", "One thing! Another thing?"},
+		{"fewer sentences than n", 3, "Only one.", "Only one."},
+		{"no final punctuation", 2, "Just a phrase", "Just a phrase"},
+		{"strips HTML", 1, "<p>First <b>bold</b> claim. Second.</p>", "First bold claim."},
+		{"abbreviations", 1, "Use tools, e.g. grep and sed. Then rest.", "Use tools, e.g. grep and sed."},
+		{"initials", 1, "Written by J. R. Smith in 2024. More.", "Written by J. R. Smith in 2024."},
+		{"numbers", 1, "Version 1.2 is out. Upgrade.", "Version 1.2 is out."},
+		{"closing quotes", 1, `He said "stop." Then left.`, `He said "stop."`},
+		{"curly quotes", 1, "He said “stop.” Then left.", "He said “stop.”"},
+		{"ellipsis", 1, "Wait… what happened?", "Wait…"},
+		{"paragraph break", 1, "<p>A heading</p><p>The body. More.</p>", "A heading"},
+		{"zero", 0, "Anything.", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarize(tt.n, tt.text); got != tt.want {
+				t.Errorf("summarize(%d, %q) = %q, want %q", tt.n, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeWords(t *testing.T) {
+	tests := []struct {
+		n    int
+		text string
+		want string
+	}{
+		{3, "The quick brown fox jumps", "The quick brown…"},
+		{3, "One, two, three, four", "One, two, three…"},
+		{5, "<p>Short <i>text</i></p>", "Short text"},
+		{0, "Anything", ""},
+	}
+
+	for _, tt := range tests {
+		if got := summarizeWords(tt.n, tt.text); got != tt.want {
+			t.Errorf("summarizeWords(%d, %q) = %q, want %q", tt.n, tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Registry holds named project directories so one binary can operate
+// several independent feed-to-mastodon projects.
+type Registry struct {
+	path       string
+	Workspaces map[string]string `yaml:"workspaces"`
+}
+
+// DefaultPath returns the default location of the workspace registry.
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "feed-to-mastodon", "workspaces.yaml"), nil
+}
+
+// ProfileDir returns the directory of a named profile, a project directory
+// kept in the user config directory rather than registered as a workspace.
+func ProfileDir(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid profile name: %q", name)
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "feed-to-mastodon", name), nil
+}
+
+// Load reads the workspace registry from path.
+// A missing registry file results in an empty registry.
+func Load(path string) (*Registry, error) {
+	r := &Registry{
+		path:       path,
+		Workspaces: make(map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace registry: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace registry: %w", err)
+	}
+	if r.Workspaces == nil {
+		r.Workspaces = make(map[string]string)
+	}
+
+	return r, nil
+}
+
+// Save writes the workspace registry back to its file.
+func (r *Registry) Save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspace registry: %w", err)
+	}
+
+	if err := os.WriteFile(r.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write workspace registry: %w", err)
+	}
+
+	return nil
+}
+
+// Add registers a workspace name for a project directory.
+// The directory is stored as an absolute path and must exist.
+func (r *Registry) Add(name, dir string) error {
+	if name == "" {
+		return fmt.Errorf("workspace name is required")
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory path: %w", err)
+	}
+
+	info, err := os.Stat(absDir)
+	if err != nil {
+		return fmt.Errorf("workspace directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", absDir)
+	}
+
+	r.Workspaces[name] = absDir
+	return nil
+}
+
+// Remove unregisters a workspace. The project directory is left untouched.
+func (r *Registry) Remove(name string) error {
+	if _, ok := r.Workspaces[name]; !ok {
+		return fmt.Errorf("unknown workspace: %s", name)
+	}
+	delete(r.Workspaces, name)
+	return nil
+}
+
+// Get returns the project directory for a workspace name.
+func (r *Registry) Get(name string) (string, error) {
+	dir, ok := r.Workspaces[name]
+	if !ok {
+		return "", fmt.Errorf("unknown workspace: %s (see 'workspaces list')", name)
+	}
+	return dir, nil
+}
+
+// Names returns the registered workspace names in sorted order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.Workspaces))
+	for name := range r.Workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
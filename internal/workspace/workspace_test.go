@@ -0,0 +1,115 @@
+package workspace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("missing file gives empty registry", func(t *testing.T) {
+		r, err := Load(filepath.Join(t.TempDir(), "workspaces.yaml"))
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if len(r.Names()) != 0 {
+			t.Errorf("Expected empty registry, got %v", r.Names())
+		}
+	})
+
+	t.Run("round trips through Save", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		path := filepath.Join(tmpDir, "config", "workspaces.yaml")
+
+		r, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if err := r.Add("blogbot", tmpDir); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := r.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+
+		r2, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		dir, err := r2.Get("blogbot")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if dir != tmpDir {
+			t.Errorf("Get() = %s, want %s", dir, tmpDir)
+		}
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("add rejects missing directory", func(t *testing.T) {
+		r, _ := Load(filepath.Join(t.TempDir(), "workspaces.yaml"))
+		if err := r.Add("missing", "/nonexistent/project"); err == nil {
+			t.Error("Expected error for missing directory")
+		}
+	})
+
+	t.Run("add stores absolute path", func(t *testing.T) {
+		r, _ := Load(filepath.Join(t.TempDir(), "workspaces.yaml"))
+		if err := r.Add("here", "."); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		dir, _ := r.Get("here")
+		if !filepath.IsAbs(dir) {
+			t.Errorf("Expected absolute path, got %s", dir)
+		}
+	})
+
+	t.Run("get unknown workspace errors", func(t *testing.T) {
+		r, _ := Load(filepath.Join(t.TempDir(), "workspaces.yaml"))
+		if _, err := r.Get("nope"); err == nil {
+			t.Error("Expected error for unknown workspace")
+		}
+	})
+
+	t.Run("remove and names", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		r, _ := Load(filepath.Join(tmpDir, "workspaces.yaml"))
+		_ = r.Add("b", tmpDir)
+		_ = r.Add("a", tmpDir)
+
+		names := r.Names()
+		if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+			t.Errorf("Names() = %v, want [a b]", names)
+		}
+
+		if err := r.Remove("a"); err != nil {
+			t.Fatalf("Remove() error = %v", err)
+		}
+		if err := r.Remove("a"); err == nil {
+			t.Error("Expected error removing unknown workspace")
+		}
+		if len(r.Names()) != 1 {
+			t.Errorf("Expected 1 workspace after remove, got %v", r.Names())
+		}
+	})
+}
+
+func TestProfileDir(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+
+	dir, err := ProfileDir("blogbot")
+	if err != nil {
+		t.Fatalf("ProfileDir() error = %v", err)
+	}
+	if filepath.Base(dir) != "blogbot" || filepath.Base(filepath.Dir(dir)) != "feed-to-mastodon" {
+		t.Errorf("ProfileDir() = %s, want .../feed-to-mastodon/blogbot", dir)
+	}
+
+	for _, name := range []string{"", ".", "..", "../blogbot", "blog/bot"} {
+		if _, err := ProfileDir(name); err == nil {
+			t.Errorf("ProfileDir(%q) succeeded, want invalid profile name", name)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package feedtomastodon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/database"
+	"github.com/lorchard/feed-to-mastodon/internal/feed"
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/lorchard/feed-to-mastodon/internal/template"
+	"github.com/mmcdole/gofeed"
+)
+
+// Defaults for the options of the parts created here.
+const (
+	DefaultCharacterLimit = 500
+	DefaultRetryBackoff   = 15 * time.Minute
+	DefaultMaxAttempts    = 5
+)
+
+// maxRetryDelay caps the backoff before retrying a failed entry.
+const maxRetryDelay = 24 * time.Hour
+
+// FetcherOptions configure the Fetcher made by NewFetcher.
+type FetcherOptions struct {
+	// Timeout bounds each request. Zero means no timeout.
+	Timeout time.Duration
+	// UserAgent replaces the default user agent, feed-to-mastodon.
+	UserAgent string
+	// Headers are added to each request, e.g. for an API key.
+	Headers map[string]string
+	// Username and Password, if Username is set, are sent with HTTP basic
+	// authentication, for private feeds.
+	Username string
+	Password string
+	// Retries is how many times a request that fails with a network
+	// error or a server error is retried, waiting RetryBackoff and then
+	// twice as long each time.
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// fetcher is the Fetcher the command uses.
+type fetcher struct {
+	fetcher *feed.Fetcher
+	err     error
+}
+
+// NewFetcher creates a Fetcher for RSS, Atom, and JSON feeds.
+func NewFetcher(opts FetcherOptions) Fetcher {
+	f := feed.New()
+	err := f.SetHTTPOptions(feed.HTTPOptions{
+		Timeout:   opts.Timeout,
+		UserAgent: opts.UserAgent,
+		Headers:   opts.Headers,
+		Username:  opts.Username,
+		Password:  opts.Password,
+	})
+	f.SetRetries(opts.Retries, opts.RetryBackoff)
+	return &fetcher{fetcher: f, err: err}
+}
+
+// Fetch fetches and parses the feed at feedURL.
+func (f *fetcher) Fetch(ctx context.Context, feedURL string) (*gofeed.Feed, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.fetcher.FetchContext(ctx, feedURL)
+}
+
+// StoreOptions configure the Store opened by OpenStore.
+type StoreOptions struct {
+	// Path is the SQLite database file, created if it doesn't exist. A
+	// database used by the feed-to-mastodon command can be shared.
+	Path string
+	// BusyTimeout is how long to wait for another connection's lock.
+	// Defaults to 5s.
+	BusyTimeout time.Duration
+	// RetryBackoff is how long to wait before retrying an entry that
+	// failed to post, doubling after each further failure. Defaults to
+	// DefaultRetryBackoff.
+	RetryBackoff time.Duration
+	// MaxAttempts is how many times to try posting an entry before giving
+	// up on it. Defaults to DefaultMaxAttempts; negative never gives up.
+	MaxAttempts int
+}
+
+// SQLiteStore is the Store the command uses, kept in a SQLite database.
+type SQLiteStore struct {
+	db      *database.DB
+	backoff time.Duration
+	max     int
+}
+
+// OpenStore opens the SQLite database at opts.Path as a Store. Close it
+// when done.
+func OpenStore(opts StoreOptions) (*SQLiteStore, error) {
+	if opts.Path == "" {
+		return nil, errors.New("store path is required")
+	}
+	if opts.BusyTimeout <= 0 {
+		opts.BusyTimeout = database.DefaultBusyTimeout
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+
+	db, err := database.NewWithOptions(opts.Path, database.Options{BusyTimeout: opts.BusyTimeout})
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db, backoff: opts.RetryBackoff, max: opts.MaxAttempts}, nil
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// SaveItems saves the items of a fetched feed.
+func (s *SQLiteStore) SaveItems(ctx context.Context, f *gofeed.Feed) (int, error) {
+	return feed.New().SaveEntriesToDBContext(ctx, f, s.db)
+}
+
+// Unposted returns up to limit entries waiting to be posted, oldest first.
+// Entries waiting to retry after a failure are left out until it's time.
+func (s *SQLiteStore) Unposted(ctx context.Context, limit int) ([]*Entry, error) {
+	stored, err := s.db.GetUnpostedEntriesContext(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(stored))
+	for _, entry := range stored {
+		var item gofeed.Item
+		if err := json.Unmarshal(entry.EntryData, &item); err != nil {
+			return nil, fmt.Errorf("invalid entry %s: %w", entry.ID, err)
+		}
+		entries = append(entries, &Entry{ID: entry.ID, Item: &item, FetchedAt: entry.FetchedAt.Time})
+	}
+	return entries, nil
+}
+
+// MarkPosted records that entry was posted as status with content.
+func (s *SQLiteStore) MarkPosted(ctx context.Context, entry *Entry, status *Status, content string) error {
+	if status == nil {
+		status = &Status{}
+	}
+	if err := s.db.MarkAsPosted(entry.ID, status.ID, status.URL); err != nil {
+		return err
+	}
+	return s.db.SetPostedContent(entry.ID, content)
+}
+
+// MarkFailed records a failed attempt to post entry, to be retried after
+// the backoff or given up on after the most attempts.
+func (s *SQLiteStore) MarkFailed(ctx context.Context, entry *Entry, postErr error) error {
+	stored, err := s.db.GetEntry(entry.ID)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return fmt.Errorf("entry not found: %s", entry.ID)
+	}
+
+	failures := stored.FailureCount + 1
+	var retryAt *time.Time
+	if s.max < 0 || failures < s.max {
+		delay := s.backoff
+		for i := 1; i < failures && delay < maxRetryDelay; i++ {
+			delay *= 2
+		}
+		next := time.Now().Add(min(delay, maxRetryDelay))
+		retryAt = &next
+	}
+	return s.db.RecordFailure(entry.ID, postErr.Error(), retryAt)
+}
+
+// RendererOptions configure the Renderer made by NewRenderer, which uses
+// the command's template language and functions.
+type RendererOptions struct {
+	// Template is the template text. One of Template and TemplateFile is
+	// required.
+	Template string
+	// TemplateFile is a template file to read instead.
+	TemplateFile string
+	// CharacterLimit is the most characters in a post, counted like
+	// Mastodon does. Longer posts are logged with a warning. Defaults to
+	// DefaultCharacterLimit.
+	CharacterLimit int
+	// Vars are available to templates as .Vars.
+	Vars map[string]string
+	// Feed, if set, is available to templates as .Feed.
+	Feed *gofeed.Feed
+}
+
+// renderer is the Renderer the command uses.
+type renderer struct {
+	renderer *template.Renderer
+}
+
+// NewRenderer creates a Renderer for a template.
+func NewRenderer(opts RendererOptions) (Renderer, error) {
+	if opts.CharacterLimit <= 0 {
+		opts.CharacterLimit = DefaultCharacterLimit
+	}
+
+	var r *template.Renderer
+	var err error
+	switch {
+	case opts.Template != "":
+		r, err = template.NewFromString(opts.Template, opts.CharacterLimit)
+	case opts.TemplateFile != "":
+		r, err = template.New(opts.TemplateFile, opts.CharacterLimit)
+	default:
+		return nil, errors.New("template or template file is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.SetVars(opts.Vars)
+	if opts.Feed != nil {
+		r.SetFeed(opts.Feed)
+	}
+	return &renderer{renderer: r}, nil
+}
+
+// Render renders the post of entry.
+func (r *renderer) Render(entry *Entry) (string, error) {
+	entryJSON, err := json.Marshal(entry.Item)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode entry %s: %w", entry.ID, err)
+	}
+	return r.renderer.Render(entryJSON)
+}
+
+// PosterOptions configure the Poster made by NewPoster.
+type PosterOptions struct {
+	// Server is the Mastodon server's URL. Required.
+	Server string
+	// AccessToken is a token for the account to post as, with the
+	// write:statuses scope. Required.
+	AccessToken string
+	// Visibility is public (the default), unlisted, private, or direct.
+	Visibility string
+	// ContentWarning, if set, is posted with every status.
+	ContentWarning string
+	// CharacterLimit is the server's character limit. Longer posts are
+	// split into a thread if SplitLongPosts is set. Defaults to
+	// DefaultCharacterLimit.
+	CharacterLimit int
+	// SplitLongPosts splits posts over the character limit into a thread.
+	SplitLongPosts bool
+}
+
+// poster is the Poster the command uses.
+type poster struct {
+	poster *mastodon.Poster
+	limit  int
+}
+
+// NewPoster creates a Poster for a Mastodon account.
+func NewPoster(opts PosterOptions) (Poster, error) {
+	if opts.Server == "" || opts.AccessToken == "" {
+		return nil, errors.New("server and access token are required")
+	}
+	if opts.Visibility == "" {
+		opts.Visibility = "public"
+	}
+	if opts.CharacterLimit <= 0 {
+		opts.CharacterLimit = DefaultCharacterLimit
+	}
+
+	p, err := mastodon.New(opts.Server, opts.AccessToken, opts.Visibility, opts.ContentWarning)
+	if err != nil {
+		return nil, err
+	}
+	p.SetSplitLongPosts(opts.SplitLongPosts)
+	return &poster{poster: p, limit: opts.CharacterLimit}, nil
+}
+
+// Post publishes content as the status of entry.
+func (p *poster) Post(ctx context.Context, entry *Entry, content string) (*Status, error) {
+	entryJSON, err := json.Marshal(entry.Item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode entry %s: %w", entry.ID, err)
+	}
+	stored := &database.Entry{ID: entry.ID, EntryData: entryJSON}
+	if err := p.poster.PostContentContext(ctx, stored, content, p.limit, false); err != nil {
+		return nil, err
+	}
+	return &Status{ID: stored.StatusID.String, URL: stored.StatusURL.String}, nil
+}
@@ -0,0 +1,247 @@
+// Package feedtomastodon lets other Go programs fetch feeds and post their
+// entries to Mastodon without running the feed-to-mastodon command.
+//
+// A Pipeline connects four parts, each an interface so it can be replaced:
+// a Fetcher reads a feed, a Store keeps its entries and what was posted, a
+// Renderer turns an entry into the text of a post, and a Poster publishes
+// it. NewFetcher, OpenStore, NewRenderer, and NewPoster create the ones
+// the command uses, configured by options structs:
+//
+//	store, err := feedtomastodon.OpenStore(feedtomastodon.StoreOptions{Path: "feed.db"})
+//	...
+//	renderer, err := feedtomastodon.NewRenderer(feedtomastodon.RendererOptions{
+//		Template: "{{.Item.Title}}\n\n{{.Item.Link}}",
+//	})
+//	...
+//	poster, err := feedtomastodon.NewPoster(feedtomastodon.PosterOptions{
+//		Server:      "https://mastodon.example",
+//		AccessToken: token,
+//	})
+//	...
+//	pipeline, err := feedtomastodon.New(feedtomastodon.Options{
+//		FeedURL:  "https://example.com/feed.xml",
+//		Fetcher:  feedtomastodon.NewFetcher(feedtomastodon.FetcherOptions{}),
+//		Store:    store,
+//		Renderer: renderer,
+//		Poster:   poster,
+//	})
+//	...
+//	if _, err := pipeline.Fetch(ctx); err != nil { ... }
+//	result, err := pipeline.Post(ctx, feedtomastodon.PostOptions{Limit: 5})
+//
+// Pipelines keep no global state, so a program can run one per feed or per
+// account, like a service posting for many users. The rest of the
+// command's features, like filters, threads, media, and cross-posting, are
+// configured through its config file and aren't part of this API yet.
+package feedtomastodon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lorchard/feed-to-mastodon/internal/mastodon"
+	"github.com/mmcdole/gofeed"
+)
+
+// Errors a Poster returns that stop posting, since every entry after them
+// would fail too. The entry being posted isn't counted as failed.
+var (
+	// ErrUnauthorized means the server rejected the access token.
+	ErrUnauthorized = mastodon.ErrUnauthorized
+	// ErrServerUnavailable means the server is down or overloaded.
+	ErrServerUnavailable = mastodon.ErrServerUnavailable
+)
+
+// Entry is a feed item kept in a Store.
+type Entry struct {
+	// ID identifies the entry in the store, from the item's GUID or link.
+	ID string
+	// Item is the feed item.
+	Item *gofeed.Item
+	// FetchedAt is when the entry was first fetched.
+	FetchedAt time.Time
+}
+
+// Status is a post published by a Poster.
+type Status struct {
+	// ID is the status's ID on the server.
+	ID string
+	// URL is the status's public URL.
+	URL string
+}
+
+// Fetcher fetches the current items of a feed.
+type Fetcher interface {
+	Fetch(ctx context.Context, feedURL string) (*gofeed.Feed, error)
+}
+
+// Store keeps the entries of a feed and records which were posted.
+type Store interface {
+	// SaveItems saves the items of a fetched feed, leaving entries that
+	// are already stored alone. Returns how many entries were new.
+	SaveItems(ctx context.Context, feed *gofeed.Feed) (int, error)
+	// Unposted returns up to limit entries (0 = all) waiting to be posted,
+	// oldest first.
+	Unposted(ctx context.Context, limit int) ([]*Entry, error)
+	// MarkPosted records that entry was posted as status with content.
+	MarkPosted(ctx context.Context, entry *Entry, status *Status, content string) error
+	// MarkFailed records a failed attempt to post entry.
+	MarkFailed(ctx context.Context, entry *Entry, postErr error) error
+}
+
+// Renderer renders the text of an entry's post.
+type Renderer interface {
+	Render(entry *Entry) (string, error)
+}
+
+// Poster publishes the post of an entry.
+type Poster interface {
+	Post(ctx context.Context, entry *Entry, content string) (*Status, error)
+}
+
+// Options are the parts of a Pipeline. All of them are required.
+type Options struct {
+	// FeedURL is the feed to fetch.
+	FeedURL string
+	// Fetcher fetches the feed.
+	Fetcher Fetcher
+	// Store keeps the feed's entries.
+	Store Store
+	// Renderer renders posts.
+	Renderer Renderer
+	// Poster publishes posts.
+	Poster Poster
+}
+
+// Pipeline fetches a feed into a store and posts its new entries.
+type Pipeline struct {
+	feedURL  string
+	fetcher  Fetcher
+	store    Store
+	renderer Renderer
+	poster   Poster
+}
+
+// New creates a Pipeline from its parts.
+func New(opts Options) (*Pipeline, error) {
+	switch {
+	case opts.FeedURL == "":
+		return nil, errors.New("feed URL is required")
+	case opts.Fetcher == nil:
+		return nil, errors.New("fetcher is required")
+	case opts.Store == nil:
+		return nil, errors.New("store is required")
+	case opts.Renderer == nil:
+		return nil, errors.New("renderer is required")
+	case opts.Poster == nil:
+		return nil, errors.New("poster is required")
+	}
+	return &Pipeline{
+		feedURL:  opts.FeedURL,
+		fetcher:  opts.Fetcher,
+		store:    opts.Store,
+		renderer: opts.Renderer,
+		poster:   opts.Poster,
+	}, nil
+}
+
+// FetchResult describes a fetch.
+type FetchResult struct {
+	// Items is the number of items in the feed.
+	Items int
+	// New is the number of entries that weren't stored before.
+	New int
+}
+
+// Fetch fetches the feed and saves its items to the store.
+func (p *Pipeline) Fetch(ctx context.Context) (*FetchResult, error) {
+	feed, err := p.fetcher.Fetch(ctx, p.feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	saved, err := p.store.SaveItems(ctx, feed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save entries: %w", err)
+	}
+	return &FetchResult{Items: len(feed.Items), New: saved}, nil
+}
+
+// PostOptions control a run of Pipeline.Post.
+type PostOptions struct {
+	// Limit is the most entries to post, or 0 for all of them.
+	Limit int
+	// DryRun renders the posts without publishing them or changing the
+	// store.
+	DryRun bool
+}
+
+// Post is the outcome of posting one entry.
+type Post struct {
+	Entry *Entry
+	// Content is the rendered post.
+	Content string
+	// Status is the published status, or nil if posting failed or in a
+	// dry run.
+	Status *Status
+	// Err is why rendering or posting failed.
+	Err error
+}
+
+// PostResult describes a run of Pipeline.Post.
+type PostResult struct {
+	// Posts are the entries that were posted or tried, in order.
+	Posts []Post
+	// Posted and Failed count the entries that were posted and that
+	// failed. In a dry run, Posted counts the entries that would be.
+	Posted int
+	Failed int
+}
+
+// Post renders and publishes entries waiting in the store, recording each
+// as posted or failed. Entries that fail don't stop the run, but
+// ErrUnauthorized, ErrServerUnavailable, and ctx being done do; the result
+// then holds the entries handled until then.
+func (p *Pipeline) Post(ctx context.Context, opts PostOptions) (*PostResult, error) {
+	entries, err := p.store.Unposted(ctx, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unposted entries: %w", err)
+	}
+
+	result := &PostResult{}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		post := Post{Entry: entry}
+		post.Content, post.Err = p.renderer.Render(entry)
+		if post.Err == nil && !opts.DryRun {
+			post.Status, post.Err = p.poster.Post(ctx, entry, post.Content)
+			if errors.Is(post.Err, ErrUnauthorized) || errors.Is(post.Err, ErrServerUnavailable) || (post.Err != nil && ctx.Err() != nil) {
+				return result, post.Err
+			}
+		}
+		result.Posts = append(result.Posts, post)
+
+		if post.Err != nil {
+			result.Failed++
+			if opts.DryRun {
+				continue
+			}
+			if err := p.store.MarkFailed(ctx, entry, post.Err); err != nil {
+				return result, fmt.Errorf("failed to record failure of entry %s: %w", entry.ID, err)
+			}
+			continue
+		}
+		result.Posted++
+		if opts.DryRun {
+			continue
+		}
+		if err := p.store.MarkPosted(ctx, entry, post.Status, post.Content); err != nil {
+			return result, fmt.Errorf("failed to mark entry %s as posted: %w", entry.ID, err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,341 @@
+package feedtomastodon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// fakeStore is a Store kept in memory.
+type fakeStore struct {
+	entries []*Entry
+	posted  map[string]string
+	failed  map[string]error
+}
+
+func newFakeStore(ids ...string) *fakeStore {
+	s := &fakeStore{posted: make(map[string]string), failed: make(map[string]error)}
+	for _, id := range ids {
+		s.entries = append(s.entries, &Entry{ID: id, Item: &gofeed.Item{Title: "Entry " + id}})
+	}
+	return s
+}
+
+func (s *fakeStore) SaveItems(ctx context.Context, feed *gofeed.Feed) (int, error) {
+	for _, item := range feed.Items {
+		s.entries = append(s.entries, &Entry{ID: item.GUID, Item: item})
+	}
+	return len(feed.Items), nil
+}
+
+func (s *fakeStore) Unposted(ctx context.Context, limit int) ([]*Entry, error) {
+	var entries []*Entry
+	for _, entry := range s.entries {
+		if _, ok := s.posted[entry.ID]; ok {
+			continue
+		}
+		if limit > 0 && len(entries) == limit {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *fakeStore) MarkPosted(ctx context.Context, entry *Entry, status *Status, content string) error {
+	s.posted[entry.ID] = content
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(ctx context.Context, entry *Entry, postErr error) error {
+	s.failed[entry.ID] = postErr
+	return nil
+}
+
+type fakeFetcher struct {
+	feed *gofeed.Feed
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, feedURL string) (*gofeed.Feed, error) {
+	return f.feed, nil
+}
+
+type fakeRenderer struct{}
+
+func (fakeRenderer) Render(entry *Entry) (string, error) {
+	if entry.Item.Title == "" {
+		return "", errors.New("no title")
+	}
+	return entry.Item.Title, nil
+}
+
+// fakePoster fails posts of entries with an error set for their ID.
+type fakePoster struct {
+	errs  map[string]error
+	posts []string
+}
+
+func (p *fakePoster) Post(ctx context.Context, entry *Entry, content string) (*Status, error) {
+	if err := p.errs[entry.ID]; err != nil {
+		return nil, err
+	}
+	p.posts = append(p.posts, content)
+	return &Status{ID: entry.ID, URL: "https://mastodon.example/@feed/" + entry.ID}, nil
+}
+
+func newTestPipeline(t *testing.T, store *fakeStore, poster *fakePoster) *Pipeline {
+	t.Helper()
+	pipeline, err := New(Options{
+		FeedURL:  "https://example.com/feed.xml",
+		Fetcher:  &fakeFetcher{},
+		Store:    store,
+		Renderer: fakeRenderer{},
+		Poster:   poster,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return pipeline
+}
+
+func TestNew(t *testing.T) {
+	t.Run("requires every part", func(t *testing.T) {
+		_, err := New(Options{FeedURL: "https://example.com/feed.xml", Fetcher: &fakeFetcher{}, Store: newFakeStore()})
+		if err == nil || !strings.Contains(err.Error(), "renderer") {
+			t.Errorf("New() error = %v, want renderer is required", err)
+		}
+	})
+
+	t.Run("requires a feed URL", func(t *testing.T) {
+		_, err := New(Options{Fetcher: &fakeFetcher{}, Store: newFakeStore(), Renderer: fakeRenderer{}, Poster: &fakePoster{}})
+		if err == nil || !strings.Contains(err.Error(), "feed URL") {
+			t.Errorf("New() error = %v, want feed URL is required", err)
+		}
+	})
+}
+
+func TestPipelineFetch(t *testing.T) {
+	store := newFakeStore()
+	pipeline := newTestPipeline(t, store, &fakePoster{})
+	pipeline.fetcher = &fakeFetcher{feed: &gofeed.Feed{Items: []*gofeed.Item{{GUID: "a"}, {GUID: "b"}}}}
+
+	result, err := pipeline.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if result.Items != 2 || result.New != 2 {
+		t.Errorf("Fetch() = %+v, want 2 items, 2 new", result)
+	}
+	if len(store.entries) != 2 {
+		t.Errorf("stored %d entries, want 2", len(store.entries))
+	}
+}
+
+func TestPipelinePost(t *testing.T) {
+	t.Run("posts entries and records them", func(t *testing.T) {
+		store := newFakeStore("1", "2", "3")
+		poster := &fakePoster{}
+		result, err := newTestPipeline(t, store, poster).Post(context.Background(), PostOptions{Limit: 2})
+		if err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+		if result.Posted != 2 || result.Failed != 0 || len(result.Posts) != 2 {
+			t.Errorf("Post() = %+v, want 2 posted", result)
+		}
+		if result.Posts[0].Status == nil || result.Posts[0].Status.ID != "1" {
+			t.Errorf("Posts[0].Status = %+v, want status 1", result.Posts[0].Status)
+		}
+		if store.posted["1"] != "Entry 1" || store.posted["2"] != "Entry 2" {
+			t.Errorf("posted = %v, want entries 1 and 2", store.posted)
+		}
+	})
+
+	t.Run("records failures and keeps going", func(t *testing.T) {
+		store := newFakeStore("1", "2", "3")
+		store.entries[2].Item.Title = ""
+		poster := &fakePoster{errs: map[string]error{"1": errors.New("too long")}}
+		result, err := newTestPipeline(t, store, poster).Post(context.Background(), PostOptions{})
+		if err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+		if result.Posted != 1 || result.Failed != 2 {
+			t.Errorf("Post() = %+v, want 1 posted, 2 failed", result)
+		}
+		if store.failed["1"] == nil || store.failed["3"] == nil {
+			t.Errorf("failed = %v, want entries 1 and 3", store.failed)
+		}
+		if _, ok := store.posted["2"]; !ok {
+			t.Error("entry 2 wasn't marked as posted")
+		}
+	})
+
+	t.Run("dry run leaves the store alone", func(t *testing.T) {
+		store := newFakeStore("1", "2")
+		poster := &fakePoster{}
+		result, err := newTestPipeline(t, store, poster).Post(context.Background(), PostOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+		if result.Posted != 2 || result.Posts[1].Content != "Entry 2" {
+			t.Errorf("Post() = %+v, want 2 rendered", result)
+		}
+		if len(poster.posts) != 0 || len(store.posted) != 0 {
+			t.Errorf("dry run posted %v and recorded %v", poster.posts, store.posted)
+		}
+	})
+
+	t.Run("stops when the token is rejected", func(t *testing.T) {
+		store := newFakeStore("1", "2", "3")
+		poster := &fakePoster{errs: map[string]error{"2": fmt.Errorf("posting: %w", ErrUnauthorized)}}
+		result, err := newTestPipeline(t, store, poster).Post(context.Background(), PostOptions{})
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("Post() error = %v, want ErrUnauthorized", err)
+		}
+		if result.Posted != 1 || result.Failed != 0 {
+			t.Errorf("Post() = %+v, want 1 posted", result)
+		}
+		if len(store.failed) != 0 {
+			t.Errorf("failed = %v, want the rejected entry not counted", store.failed)
+		}
+	})
+}
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Test Feed</title>
+<item><guid>https://example.com/1</guid><title>First post</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 00:00:00 GMT</pubDate></item>
+<item><guid>https://example.com/2</guid><title>Second post</title><link>https://example.com/2</link><pubDate>Tue, 02 Jan 2024 00:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+func TestDefaults(t *testing.T) {
+	feedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, testFeed)
+	}))
+	defer feedServer.Close()
+
+	var mu sync.Mutex
+	var statuses []string
+	mastodonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/statuses" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm() error = %v", err)
+		}
+		mu.Lock()
+		statuses = append(statuses, r.Form.Get("status"))
+		id := 110 + len(statuses)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"%d","url":"https://mastodon.example/@feed/%d"}`, id, id)
+	}))
+	defer mastodonServer.Close()
+
+	store, err := OpenStore(StoreOptions{Path: filepath.Join(t.TempDir(), "feed.db")})
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+
+	renderer, err := NewRenderer(RendererOptions{
+		Template: "{{.Vars.prefix}} {{.Item.Title}}\n\n{{.Item.Link}}",
+		Vars:     map[string]string{"prefix": "New:"},
+	})
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+	poster, err := NewPoster(PosterOptions{Server: mastodonServer.URL, AccessToken: "token"})
+	if err != nil {
+		t.Fatalf("NewPoster() error = %v", err)
+	}
+	pipeline, err := New(Options{
+		FeedURL:  feedServer.URL,
+		Fetcher:  NewFetcher(FetcherOptions{}),
+		Store:    store,
+		Renderer: renderer,
+		Poster:   poster,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+	fetched, err := pipeline.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if fetched.Items != 2 || fetched.New != 2 {
+		t.Errorf("Fetch() = %+v, want 2 items, 2 new", fetched)
+	}
+
+	result, err := pipeline.Post(ctx, PostOptions{})
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if result.Posted != 2 || result.Failed != 0 {
+		t.Fatalf("Post() = %+v, want 2 posted", result)
+	}
+	if want := "New: First post\n\nhttps://example.com/1"; len(statuses) != 2 || statuses[0] != want {
+		t.Errorf("statuses = %q, want %q first", statuses, want)
+	}
+	if status := result.Posts[0].Status; status == nil || status.ID != "111" || status.URL != "https://mastodon.example/@feed/111" {
+		t.Errorf("Posts[0].Status = %+v, want status 111", status)
+	}
+
+	// Posted entries aren't posted again, and fetching again adds nothing
+	fetched, err = pipeline.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if fetched.New != 0 {
+		t.Errorf("second Fetch() found %d new, want 0", fetched.New)
+	}
+	result, err = pipeline.Post(ctx, PostOptions{})
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if len(result.Posts) != 0 {
+		t.Errorf("second Post() posted %d entries, want 0", len(result.Posts))
+	}
+}
+
+func TestSQLiteStoreMarkFailed(t *testing.T) {
+	store, err := OpenStore(StoreOptions{Path: filepath.Join(t.TempDir(), "feed.db"), MaxAttempts: 2})
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	feed := &gofeed.Feed{Items: []*gofeed.Item{{GUID: "a", Title: "A", Link: "https://example.com/a"}}}
+	if _, err := store.SaveItems(ctx, feed); err != nil {
+		t.Fatalf("SaveItems() error = %v", err)
+	}
+	entries, err := store.Unposted(ctx, 0)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Unposted() = %d entries, %v; want 1", len(entries), err)
+	}
+	if entries[0].Item.Title != "A" {
+		t.Errorf("Item.Title = %q, want A", entries[0].Item.Title)
+	}
+
+	if err := store.MarkFailed(ctx, entries[0], errors.New("boom")); err != nil {
+		t.Fatalf("MarkFailed() error = %v", err)
+	}
+	entries, err = store.Unposted(ctx, 0)
+	if err != nil {
+		t.Fatalf("Unposted() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Unposted() = %d entries, want the failed entry waiting to retry", len(entries))
+	}
+}